@@ -27,6 +27,7 @@ type BaseAdapter struct {
 	storage    storage.Storage
 
 	platform   string
+	accountID  string
 	loginURL   string
 	publishURL string
 	limits     publisher.ContentLimits
@@ -44,9 +45,15 @@ func NewBaseAdapter(platform string, opts *publisher.Options) *BaseAdapter {
 	if opts.CookieDir == "" {
 		opts.CookieDir = "./cookies"
 	}
+	// 每个平台在 CookieDir 中只保存一个登录账号
+	accountID := opts.AccountID
+	if accountID == "" {
+		accountID = platform
+	}
 
 	return &BaseAdapter{
 		platform:   platform,
+		accountID:  accountID,
 		headless:   opts.Headless,
 		cookieDir:  opts.CookieDir,
 		cookieMgr:  cookies.NewManager(opts.CookieDir),
@@ -55,6 +62,11 @@ func NewBaseAdapter(platform string, opts *publisher.Options) *BaseAdapter {
 	}
 }
 
+// SetTaskManager 使用共享的任务管理器替换适配器自带的内存任务管理器
+func (a *BaseAdapter) SetTaskManager(m *task.TaskManager) {
+	a.taskMgr = m
+}
+
 func (a *BaseAdapter) Platform() string {
 	return a.platform
 }
@@ -209,22 +221,23 @@ func (a *BaseAdapter) PublishAsync(ctx context.Context, content *publisher.Conte
 		return "", err
 	}
 
+	// account_id 用于队列按账号限流和内容去重
 	payload := map[string]interface{}{
-		"title":  content.Title,
-		"body":   content.Body,
-		"type":   content.Type,
-		"images": content.ImagePaths,
-		"video":  content.VideoPath,
-		"tags":   content.Tags,
+		"platform":   a.platform,
+		"account_id": a.accountID,
+		"title":      content.Title,
+		"body":       content.Body,
+		"type":       content.Type,
+		"images":     content.ImagePaths,
+		"video":      content.VideoPath,
+		"tags":       content.Tags,
 	}
 
-	t, err := a.taskMgr.CreateTask("publish", a.platform, payload)
+	t, err := a.taskMgr.Submit(context.Background(), "publish", a.platform, payload)
 	if err != nil {
 		return "", err
 	}
 
-	go a.taskMgr.Execute(context.Background(), t.ID)
-
 	return t.ID, nil
 }
 
@@ -774,6 +787,14 @@ func (a *XiaohongshuAdapter) submitPublish(page *rod.Page) error {
 type PublisherFactory struct {
 	mu      sync.RWMutex
 	creators map[string]func(*publisher.Options) publisher.Publisher
+	taskMgr  *task.TaskManager
+}
+
+// SetTaskManager 设置共享任务管理器，之后创建的适配器都通过它提交异步任务
+func (f *PublisherFactory) SetTaskManager(m *task.TaskManager) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.taskMgr = m
 }
 
 func NewPublisherFactory() *PublisherFactory {
@@ -797,7 +818,14 @@ func (f *PublisherFactory) Create(platform string, opts *publisher.Options) (pub
 		return nil, fmt.Errorf("unsupported platform: %s", platform)
 	}
 
-	return creator(opts), nil
+	pub := creator(opts)
+	if f.taskMgr != nil {
+		if setter, ok := pub.(interface{ SetTaskManager(*task.TaskManager) }); ok {
+			setter.SetTaskManager(f.taskMgr)
+		}
+	}
+
+	return pub, nil
 }
 
 func (f *PublisherFactory) Platforms() []string {
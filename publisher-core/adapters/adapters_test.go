@@ -0,0 +1,134 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"publisher-core/database"
+	publisher "publisher-core/interfaces"
+	"publisher-core/task"
+)
+
+func newTestQueue(t *testing.T) (*task.QueueService, *task.TaskManager) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "queue.db")), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open db failed: %v", err)
+	}
+	if err := db.AutoMigrate(
+		&database.AsyncTask{},
+		&database.TaskQueue{},
+		&database.TaskExecution{},
+		&database.TaskDependency{},
+		&database.TaskRateLimit{},
+	); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	config := task.DefaultQueueConfig()
+	config.PollInterval = 10 * time.Millisecond
+	queue := task.NewQueueService(db, config)
+	mgr := task.NewQueueTaskManager(queue, task.NewStateService(db))
+	mgr.RegisterHandler("publish", func(ctx context.Context, t *task.Task) error {
+		return nil
+	})
+	return queue, mgr
+}
+
+func newTestAdapter(mgr *task.TaskManager, accountID string) *DouyinAdapter {
+	opts := publisher.DefaultOptions()
+	opts.AccountID = accountID
+	adapter := NewDouyinAdapter(opts)
+	adapter.SetTaskManager(mgr)
+	return adapter
+}
+
+func TestPublishAsyncDedupByAccount(t *testing.T) {
+	queue, mgr := newTestQueue(t)
+	ctx := context.Background()
+	content := &publisher.Content{Title: "标题", Body: "正文"}
+
+	taskID, err := newTestAdapter(mgr, "douyin-1").PublishAsync(ctx, content)
+	if err != nil {
+		t.Fatalf("PublishAsync failed: %v", err)
+	}
+	submitted, err := queue.GetTask(taskID)
+	if err != nil {
+		t.Fatalf("GetTask failed: %v", err)
+	}
+	if submitted.ContentHash == "" {
+		t.Fatal("Expected publish task to be deduplicated by content")
+	}
+
+	if _, err := newTestAdapter(mgr, "douyin-1").PublishAsync(ctx, content); !errors.Is(err, task.ErrDuplicateContent) {
+		t.Errorf("Expected ErrDuplicateContent for the same account, got %v", err)
+	}
+	if _, err := newTestAdapter(mgr, "douyin-2").PublishAsync(ctx, content); err != nil {
+		t.Errorf("Expected another account to publish the same content, got %v", err)
+	}
+}
+
+func TestPublishAsyncRateLimitByAccount(t *testing.T) {
+	queue, mgr := newTestQueue(t)
+	if err := queue.SetRateLimit(&database.TaskRateLimit{
+		Scope:       task.RateLimitScopeAccount,
+		Key:         "douyin-1",
+		MinInterval: 600,
+		IsActive:    true,
+	}); err != nil {
+		t.Fatalf("SetRateLimit failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	queue.Start(ctx)
+
+	adapter := newTestAdapter(mgr, "douyin-1")
+	first, err := adapter.PublishAsync(ctx, &publisher.Content{Title: "第一篇", Body: "正文"})
+	if err != nil {
+		t.Fatalf("PublishAsync failed: %v", err)
+	}
+	if done, err := queue.WaitTask(ctx, first); err != nil || done.Status != database.TaskStatusCompleted {
+		t.Fatalf("Expected first publish to complete, got %v", err)
+	}
+
+	second, err := adapter.PublishAsync(ctx, &publisher.Content{Title: "第二篇", Body: "正文"})
+	if err != nil {
+		t.Fatalf("PublishAsync failed: %v", err)
+	}
+	other, err := newTestAdapter(mgr, "douyin-2").PublishAsync(ctx, &publisher.Content{Title: "第三篇", Body: "正文"})
+	if err != nil {
+		t.Fatalf("PublishAsync failed: %v", err)
+	}
+	if done, err := queue.WaitTask(ctx, other); err != nil || done.Status != database.TaskStatusCompleted {
+		t.Fatalf("Expected other account to publish without limit, got %v", err)
+	}
+
+	// 同账号的第二次发布被推迟到最小间隔之后
+	for {
+		deferred, err := queue.GetTask(second)
+		if err != nil {
+			t.Fatalf("GetTask failed: %v", err)
+		}
+		if deferred.Status == database.TaskStatusPending && deferred.ScheduledAt != nil {
+			break
+		}
+		if deferred.Status != database.TaskStatusPending || ctx.Err() != nil {
+			t.Fatalf("Expected second publish on the same account to be deferred, got %s", deferred.Status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,59 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"publisher-core/ai/provider"
+	"publisher-core/cost"
+	"publisher-core/prompt"
+)
+
+// auditOutput 内容审核的结构化输出
+type auditOutput struct {
+	Passed   bool `json:"passed"`
+	Score    int  `json:"score" description:"合规评分" jsonschema:"minimum=0,maximum=100"`
+	Findings []struct {
+		Text       string `json:"text" description:"原文中有问题的片段，必须与原文完全一致" jsonschema:"minLength=1"`
+		Reason     string `json:"reason" jsonschema:"minLength=1"`
+		Severity   string `json:"severity" jsonschema:"enum=low|medium|high|critical"`
+		Suggestion string `json:"suggestion" description:"建议替换成的文本，建议删除时为空"`
+	} `json:"findings"`
+}
+
+// AuditContent 使用 AI 审核内容是否适合公开发布，返回带原文片段的问题列表
+func (s *Service) AuditContent(ctx context.Context, content string) (*provider.AuditResult, error) {
+	if strings.TrimSpace(content) == "" {
+		return &provider.AuditResult{Passed: true, Score: 100}, nil
+	}
+
+	// 相近的内容可能只差一处违规，审核结果不能复用缓存
+	ctx = cost.WithFunction(cost.WithoutCache(ctx), "content_audit")
+	var output auditOutput
+	_, err := s.promptService().GenerateStructured(ctx, s, prompt.TemplateContentAudit, map[string]interface{}{
+		"content": content,
+	}, &output, nil)
+	if err != nil {
+		return nil, fmt.Errorf("AI审核失败: %w", err)
+	}
+
+	result := &provider.AuditResult{
+		Passed:   output.Passed,
+		Score:    output.Score,
+		Findings: make([]provider.AuditFinding, 0, len(output.Findings)),
+	}
+	for _, f := range output.Findings {
+		result.Findings = append(result.Findings, provider.AuditFinding{
+			Text:       f.Text,
+			Reason:     f.Reason,
+			Severity:   f.Severity,
+			Suggestion: f.Suggestion,
+		})
+		result.Issues = append(result.Issues, fmt.Sprintf("%s：%s", f.Text, f.Reason))
+		if f.Suggestion != "" {
+			result.Suggestions = append(result.Suggestions, fmt.Sprintf("将“%s”改为“%s”", f.Text, f.Suggestion))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,142 @@
+package ai
+
+import (
+	"fmt"
+
+	"publisher-core/ai/provider"
+	"publisher-core/cost"
+	"publisher-core/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ContextAction 提示词超出模型上下文窗口时的处理方式
+type ContextAction string
+
+const (
+	// ContextActionReject 拒绝调用，按上下文超长错误降级到下一个配置
+	ContextActionReject ContextAction = "reject"
+	// ContextActionTruncate 丢弃最早的对话轮次并截断过长的消息
+	ContextActionTruncate ContextAction = "truncate"
+)
+
+// ContextPolicy 上下文窗口管理策略
+type ContextPolicy struct {
+	// AutoUpgrade 调用方未指定模型时，自动换用同一提供商上下文更大的模型
+	AutoUpgrade bool
+	// OnOverflow 没有可换用的模型时的处理方式
+	OnOverflow ContextAction
+}
+
+// DefaultContextPolicy 返回默认上下文策略
+func DefaultContextPolicy() ContextPolicy {
+	return ContextPolicy{
+		AutoUpgrade: true,
+		OnOverflow:  ContextActionReject,
+	}
+}
+
+// defaultEstimateOutput 未指定 MaxTokens 时估算的输出 token 数
+const defaultEstimateOutput = 1024
+
+// CallEstimate 调用前的 token 与成本估算
+type CallEstimate struct {
+	Config        string  `json:"config,omitempty"`
+	Provider      string  `json:"provider"`
+	Model         string  `json:"model"`
+	ContextWindow int     `json:"context_window"`
+	InputTokens   int     `json:"input_tokens"`
+	OutputTokens  int     `json:"output_tokens"`
+	EstimatedCost float64 `json:"estimated_cost"`
+	// Truncated 提示词需要截断才能放入上下文
+	Truncated bool `json:"truncated"`
+}
+
+// SetContextPolicy 设置上下文窗口管理策略
+func (s *UnifiedService) SetContextPolicy(policy ContextPolicy) {
+	s.contextPolicy = policy
+}
+
+// fitContext 确保调用选项能放入模型上下文窗口
+// 超出时按策略换用更大的模型、截断消息或返回上下文超长错误；pinned 为 true 表示调用方指定了模型，不自动换用
+func (s *UnifiedService) fitContext(config *database.AIServiceConfig, client provider.Provider, opts *provider.GenerateOptions, pinned bool) (bool, error) {
+	return s.contextPolicy.fit(provider.ProviderType(config.Provider), client.Models(), opts, pinned)
+}
+
+// fit 按策略调整调用选项，models 为可换用的模型
+func (policy ContextPolicy) fit(pt provider.ProviderType, models []string, opts *provider.GenerateOptions, pinned bool) (bool, error) {
+	info := provider.LookupModel(pt, opts.Model)
+	required := provider.CountMessageTokens(pt, opts.Model, opts.Messages)
+	if required <= info.InputBudget(opts.MaxTokens) {
+		return false, nil
+	}
+
+	if policy.AutoUpgrade && !pinned {
+		if model, ok := provider.PickModel(pt, models, opts.Messages, opts.MaxTokens); ok {
+			logrus.Infof("提示词约 %d 个 token，超出 %s 的上下文窗口 (%d)，改用模型 %s", required, opts.Model, info.ContextWindow, model)
+			opts.Model = model
+			return false, nil
+		}
+	}
+
+	if policy.OnOverflow == ContextActionTruncate {
+		messages, ok := provider.FitMessages(opts.Messages, provider.TokenizerFor(pt, opts.Model), info.InputBudget(opts.MaxTokens))
+		if ok {
+			logrus.Warnf("提示词约 %d 个 token，已截断以适应 %s 的上下文窗口 (%d)", required, opts.Model, info.ContextWindow)
+			opts.Messages = messages
+			return true, nil
+		}
+	}
+
+	return false, &provider.ProviderError{
+		Provider: pt,
+		Class:    provider.ErrorClassContextLength,
+		Message:  fmt.Sprintf("prompt of ~%d tokens exceeds %s context window of %d tokens", required, opts.Model, info.ContextWindow),
+	}
+}
+
+// newCallEstimate 按调整后的调用选项估算 token 数，输出按 MaxTokens 计，未指定时取默认值
+func newCallEstimate(providerName string, callOpts *provider.GenerateOptions, truncated bool) *CallEstimate {
+	pt := provider.ProviderType(providerName)
+	estimate := &CallEstimate{
+		Provider:      providerName,
+		Model:         callOpts.Model,
+		ContextWindow: provider.LookupModel(pt, callOpts.Model).ContextWindow,
+		InputTokens:   provider.CountMessageTokens(pt, callOpts.Model, callOpts.Messages),
+		OutputTokens:  callOpts.MaxTokens,
+		Truncated:     truncated,
+	}
+	if estimate.OutputTokens <= 0 {
+		estimate.OutputTokens = defaultEstimateOutput
+	}
+	return estimate
+}
+
+// Estimate 在调用前估算 token 数和成本
+// 按优先级选出实际会使用的配置，并应用与 Generate 相同的上下文策略
+func (s *UnifiedService) Estimate(opts *provider.GenerateOptions) (*CallEstimate, error) {
+	configs, err := s.GetActiveConfigs("text")
+	if err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for i := range configs {
+		config := &configs[i]
+		_, callOpts, truncated, err := s.prepareCall(config, opts)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		estimate := newCallEstimate(config.Provider, callOpts, truncated)
+		estimate.Config = config.Name
+		_, estimate.EstimatedCost = cost.NewCostService(s.db).CalculateCost(config.Provider, callOpts.Model, estimate.InputTokens, estimate.OutputTokens)
+		return estimate, nil
+	}
+
+	if lastErr == nil {
+		return nil, fmt.Errorf("no active AI service config")
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,78 @@
+package ai
+
+import (
+	"time"
+
+	"publisher-core/ai/provider"
+)
+
+// RetryPolicy 某类错误的重试与降级策略
+type RetryPolicy struct {
+	// MaxRetries 在同一配置上的重试次数
+	MaxRetries int
+	// Backoff 首次重试等待时间，之后每次翻倍
+	Backoff time.Duration
+	// MaxBackoff 单次等待上限，服务端要求的等待超过此值时不再重试
+	MaxBackoff time.Duration
+	// Fallback 重试用尽后是否尝试下一个配置
+	Fallback bool
+	// TripBreaker 是否计入该配置的熔断失败次数
+	TripBreaker bool
+}
+
+// FallbackPolicy 按错误类别划分的策略
+type FallbackPolicy map[provider.ErrorClass]RetryPolicy
+
+// DefaultFallbackPolicy 返回默认降级策略
+// 请求本身有问题（参数错误、内容被过滤）时换提供商也无济于事，直接返回
+func DefaultFallbackPolicy() FallbackPolicy {
+	return FallbackPolicy{
+		provider.ErrorClassRateLimited: {
+			MaxRetries:  2,
+			Backoff:     2 * time.Second,
+			MaxBackoff:  20 * time.Second,
+			Fallback:    true,
+			TripBreaker: true,
+		},
+		provider.ErrorClassTransient: {
+			MaxRetries:  2,
+			Backoff:     500 * time.Millisecond,
+			MaxBackoff:  8 * time.Second,
+			Fallback:    true,
+			TripBreaker: true,
+		},
+		provider.ErrorClassAuth:            {Fallback: true, TripBreaker: true},
+		provider.ErrorClassNotFound:        {Fallback: true},
+		provider.ErrorClassContextLength:   {Fallback: true},
+		provider.ErrorClassContentFiltered: {},
+		provider.ErrorClassInvalidRequest:  {},
+		provider.ErrorClassUnknown:         {Fallback: true, TripBreaker: true},
+	}
+}
+
+// For 返回错误类别对应的策略，未配置的类别按 unknown 处理
+func (p FallbackPolicy) For(class provider.ErrorClass) RetryPolicy {
+	if policy, ok := p[class]; ok {
+		return policy
+	}
+	return p[provider.ErrorClassUnknown]
+}
+
+// wait 返回第 attempt 次重试前的等待时间，不应重试时返回 false
+func (p RetryPolicy) wait(attempt int, retryAfter time.Duration) (time.Duration, bool) {
+	if attempt >= p.MaxRetries {
+		return 0, false
+	}
+
+	wait := p.Backoff << uint(attempt)
+	if p.MaxBackoff > 0 && wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	if retryAfter > wait {
+		if p.MaxBackoff > 0 && retryAfter > p.MaxBackoff {
+			return 0, false
+		}
+		wait = retryAfter
+	}
+	return wait, true
+}
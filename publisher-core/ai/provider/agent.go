@@ -0,0 +1,205 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ToolHandler 工具处理函数，参数形式与 MCP 工具处理函数一致，返回的文本作为工具结果交给模型
+type ToolHandler func(ctx context.Context, args map[string]interface{}) (string, error)
+
+// Tool 可由模型调用的 Go 工具
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+	Handler     ToolHandler            `json:"-"`
+}
+
+// ToolRegistry 工具注册器
+type ToolRegistry struct {
+	tools map[string]Tool
+	order []string
+	mu    sync.RWMutex
+}
+
+// NewToolRegistry 创建工具注册器
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{
+		tools: make(map[string]Tool),
+	}
+}
+
+// RegisterTool 注册工具，同名工具会被覆盖
+func (r *ToolRegistry) RegisterTool(tool Tool) error {
+	if tool.Name == "" {
+		return fmt.Errorf("tool name is required")
+	}
+	if tool.Handler == nil {
+		return fmt.Errorf("tool handler is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tools[tool.Name]; !exists {
+		r.order = append(r.order, tool.Name)
+	}
+	r.tools[tool.Name] = tool
+	return nil
+}
+
+// Definitions 返回按注册顺序排列的工具定义
+func (r *ToolRegistry) Definitions() []ToolDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	defs := make([]ToolDefinition, 0, len(r.order))
+	for _, name := range r.order {
+		tool := r.tools[name]
+		defs = append(defs, ToolDefinition{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  tool.InputSchema,
+		})
+	}
+	return defs
+}
+
+// Execute 执行一次工具调用并生成工具结果消息
+// 工具不存在、参数错误或执行失败时把错误信息作为结果返回给模型，由模型决定如何继续
+func (r *ToolRegistry) Execute(ctx context.Context, call ToolCall) Message {
+	msg := Message{
+		Role:       RoleTool,
+		ToolCallID: call.ID,
+		Name:       call.Function.Name,
+	}
+
+	r.mu.RLock()
+	tool, ok := r.tools[call.Function.Name]
+	r.mu.RUnlock()
+	if !ok {
+		msg.Content = toolErrorContent(fmt.Errorf("unknown tool: %s", call.Function.Name))
+		return msg
+	}
+
+	args, err := call.ParseArguments()
+	if err != nil {
+		msg.Content = toolErrorContent(err)
+		return msg
+	}
+
+	content, err := tool.Handler(ctx, args)
+	if err != nil {
+		logrus.Warnf("tool %s failed: %v", call.Function.Name, err)
+		msg.Content = toolErrorContent(err)
+		return msg
+	}
+
+	msg.Content = content
+	return msg
+}
+
+func toolErrorContent(err error) string {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return string(data)
+}
+
+// AgentConfig 智能体配置
+type AgentConfig struct {
+	// MaxSteps 最多调用模型的次数，用于防止工具调用无限循环
+	MaxSteps int `json:"max_steps"`
+}
+
+// DefaultAgentConfig 默认智能体配置
+func DefaultAgentConfig() *AgentConfig {
+	return &AgentConfig{MaxSteps: 8}
+}
+
+// Agent 工具调用循环：调用模型，执行模型请求的工具，把结果交回模型，直到模型给出最终回答
+type Agent struct {
+	generator Generator
+	registry  *ToolRegistry
+	config    *AgentConfig
+}
+
+// AgentResult 智能体执行结果
+type AgentResult struct {
+	// Content 模型的最终回答
+	Content string `json:"content"`
+	// Messages 包含工具调用和工具结果的完整对话
+	Messages     []Message `json:"messages"`
+	Steps        int       `json:"steps"`
+	ToolCalls    int       `json:"tool_calls"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	Model        string    `json:"model"`
+	Provider     string    `json:"provider"`
+}
+
+// NewAgent 创建智能体
+func NewAgent(generator Generator, registry *ToolRegistry, config *AgentConfig) *Agent {
+	if config == nil {
+		config = DefaultAgentConfig()
+	}
+	if config.MaxSteps <= 0 {
+		config.MaxSteps = DefaultAgentConfig().MaxSteps
+	}
+	return &Agent{
+		generator: generator,
+		registry:  registry,
+		config:    config,
+	}
+}
+
+// Run 执行工具调用循环
+// opts.Tools 为空时使用注册器中的全部工具
+func (a *Agent) Run(ctx context.Context, opts *GenerateOptions) (*AgentResult, error) {
+	req := *opts
+	req.Messages = append([]Message(nil), opts.Messages...)
+	if len(req.Tools) == 0 {
+		req.Tools = a.registry.Definitions()
+	}
+
+	result := &AgentResult{}
+	for step := 0; step < a.config.MaxSteps; step++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		resp, err := a.generator.Generate(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Steps++
+		result.InputTokens += resp.InputTokens
+		result.OutputTokens += resp.OutputTokens
+		result.Model = resp.Model
+		result.Provider = resp.Provider
+
+		req.Messages = append(req.Messages, Message{
+			Role:      RoleAssistant,
+			Content:   resp.Content,
+			ToolCalls: resp.ToolCalls,
+		})
+
+		if len(resp.ToolCalls) == 0 {
+			result.Content = resp.Content
+			result.Messages = req.Messages
+			return result, nil
+		}
+
+		for _, call := range resp.ToolCalls {
+			logrus.Debugf("agent step %d calls tool %s", result.Steps, call.Function.Name)
+			req.Messages = append(req.Messages, a.registry.Execute(ctx, call))
+			result.ToolCalls++
+		}
+	}
+
+	return nil, fmt.Errorf("agent did not finish within %d steps", a.config.MaxSteps)
+}
@@ -0,0 +1,187 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type toolCallingGenerator struct {
+	responses []*GenerateResult
+	requests  []*GenerateOptions
+}
+
+func (g *toolCallingGenerator) Generate(ctx context.Context, opts *GenerateOptions) (*GenerateResult, error) {
+	copied := *opts
+	copied.Messages = append([]Message(nil), opts.Messages...)
+	g.requests = append(g.requests, &copied)
+	return g.responses[len(g.requests)-1], nil
+}
+
+func newTestToolRegistry(t *testing.T) *ToolRegistry {
+	registry := NewToolRegistry()
+	err := registry.RegisterTool(Tool{
+		Name:        "get_topic",
+		Description: "获取话题详情",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"topic_id": map[string]interface{}{"type": "string", "default": "1"},
+			},
+			"required": []string{"topic_id"},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (string, error) {
+			id, _ := args["topic_id"].(string)
+			if id == "missing" {
+				return "", fmt.Errorf("topic not found")
+			}
+			return fmt.Sprintf(`{"id":%q,"heat":100}`, id), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("RegisterTool failed: %v", err)
+	}
+	return registry
+}
+
+func TestAgentRunExecutesTools(t *testing.T) {
+	gen := &toolCallingGenerator{responses: []*GenerateResult{
+		{
+			ToolCalls: []ToolCall{
+				{ID: "a", Type: "function", Function: ToolCallFunction{Name: "get_topic", Arguments: `{"topic_id":"42"}`}},
+				{ID: "b", Type: "function", Function: ToolCallFunction{Name: "get_topic", Arguments: `{"topic_id":"missing"}`}},
+			},
+			InputTokens: 10,
+		},
+		{Content: "话题42热度为100", InputTokens: 20, OutputTokens: 5},
+	}}
+
+	agent := NewAgent(gen, newTestToolRegistry(t), nil)
+	result, err := agent.Run(context.Background(), &GenerateOptions{
+		Messages: []Message{{Role: RoleUser, Content: "话题42怎么样"}},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if result.Content != "话题42热度为100" || result.Steps != 2 || result.ToolCalls != 2 || result.InputTokens != 30 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+	if len(gen.requests[0].Tools) != 1 || gen.requests[0].Tools[0].Name != "get_topic" {
+		t.Errorf("Expected registry tools to be offered, got %+v", gen.requests[0].Tools)
+	}
+
+	second := gen.requests[1].Messages
+	if len(second) != 4 {
+		t.Fatalf("Expected user, assistant and two tool messages, got %d", len(second))
+	}
+	if second[2].Role != RoleTool || second[2].ToolCallID != "a" || !strings.Contains(second[2].Content, `"heat":100`) {
+		t.Errorf("Unexpected tool result: %+v", second[2])
+	}
+	if !strings.Contains(second[3].Content, "topic not found") {
+		t.Errorf("Expected tool error to be returned to the model, got %+v", second[3])
+	}
+}
+
+func TestAgentRunStopsAfterMaxSteps(t *testing.T) {
+	call := &GenerateResult{ToolCalls: []ToolCall{{ID: "a", Function: ToolCallFunction{Name: "get_topic", Arguments: `{"topic_id":"1"}`}}}}
+	gen := &toolCallingGenerator{responses: []*GenerateResult{call, call}}
+
+	agent := NewAgent(gen, newTestToolRegistry(t), &AgentConfig{MaxSteps: 2})
+	if _, err := agent.Run(context.Background(), &GenerateOptions{Messages: []Message{{Role: RoleUser, Content: "hi"}}}); err == nil {
+		t.Fatal("Expected error when the agent keeps calling tools")
+	}
+}
+
+func TestOpenAICompatibleToolCalls(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Tools      []openAITool `json:"tools"`
+			ToolChoice string       `json:"tool_choice"`
+			Messages   []Message    `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if len(req.Tools) != 1 || req.Tools[0].Type != "function" || req.Tools[0].Function.Name != "get_topic" {
+			t.Errorf("Unexpected tools: %+v", req.Tools)
+		}
+		if req.ToolChoice != ToolChoiceAuto {
+			t.Errorf("Expected tool_choice auto, got %q", req.ToolChoice)
+		}
+		if last := req.Messages[len(req.Messages)-1]; last.Role != RoleTool || last.ToolCallID != "call_1" {
+			t.Errorf("Expected tool result message, got %+v", last)
+		}
+
+		fmt.Fprint(w, `{"choices":[{"message":{"content":null,"tool_calls":[{"id":"call_2","type":"function","function":{"name":"get_topic","arguments":"{\"topic_id\":\"7\"}"}}]}}]}`)
+	}))
+	defer server.Close()
+
+	p, _ := NewOpenAICompatibleProvider(&OpenAICompatibleConfig{BaseURL: server.URL, DefaultModel: "m"})
+	result, err := p.Generate(context.Background(), &GenerateOptions{
+		Messages: []Message{
+			{Role: RoleUser, Content: "hi"},
+			{Role: RoleAssistant, ToolCalls: []ToolCall{{ID: "call_1", Type: "function", Function: ToolCallFunction{Name: "get_topic", Arguments: "{}"}}}},
+			{Role: RoleTool, ToolCallID: "call_1", Name: "get_topic", Content: "{}"},
+		},
+		Tools:      newTestToolRegistry(t).Definitions(),
+		ToolChoice: ToolChoiceAuto,
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if len(result.ToolCalls) != 1 || result.ToolCalls[0].ID != "call_2" {
+		t.Fatalf("Unexpected tool calls: %+v", result.ToolCalls)
+	}
+	args, err := result.ToolCalls[0].ParseArguments()
+	if err != nil || args["topic_id"] != "7" {
+		t.Errorf("Unexpected arguments: %v, %v", args, err)
+	}
+}
+
+func TestGoogleToolConversion(t *testing.T) {
+	contents := googleContents([]Message{
+		{Role: RoleUser, Content: "hi"},
+		{Role: RoleAssistant, ToolCalls: []ToolCall{
+			{ID: "call_0", Function: ToolCallFunction{Name: "get_topic", Arguments: `{"topic_id":"1"}`}},
+			{ID: "call_1", Function: ToolCallFunction{Name: "get_stats", Arguments: `{}`}},
+		}},
+		{Role: RoleTool, ToolCallID: "call_0", Content: `{"heat":1}`},
+		{Role: RoleTool, ToolCallID: "call_1", Content: "ok"},
+	})
+
+	if len(contents) != 3 {
+		t.Fatalf("Expected tool results merged into one turn, got %d contents", len(contents))
+	}
+	if contents[1].Role != "model" || len(contents[1].Parts) != 2 || contents[1].Parts[0].FunctionCall.Name != "get_topic" {
+		t.Errorf("Unexpected model turn: %+v", contents[1])
+	}
+	responses := contents[2].Parts
+	if responses[0].FunctionResponse.Name != "get_topic" || responses[0].FunctionResponse.Response["heat"] != float64(1) {
+		t.Errorf("Unexpected function response: %+v", responses[0].FunctionResponse)
+	}
+	if responses[1].FunctionResponse.Name != "get_stats" || responses[1].FunctionResponse.Response["content"] != "ok" {
+		t.Errorf("Unexpected function response: %+v", responses[1].FunctionResponse)
+	}
+
+	tools, config := googleTools(&GenerateOptions{Tools: newTestToolRegistry(t).Definitions(), ToolChoice: ToolChoiceRequired})
+	params := tools[0].FunctionDeclarations[0].Parameters
+	prop := params["properties"].(map[string]interface{})["topic_id"].(map[string]interface{})
+	if _, ok := prop["default"]; ok {
+		t.Error("Expected unsupported schema keys to be removed")
+	}
+	if config.FunctionCallingConfig.Mode != "ANY" {
+		t.Errorf("Expected ANY mode, got %s", config.FunctionCallingConfig.Mode)
+	}
+
+	text, calls := googleParseParts([]googlePart{
+		{Text: "查询中"},
+		{FunctionCall: &googleFunctionCall{Name: "get_topic", Args: map[string]interface{}{"topic_id": "9"}}},
+	})
+	if text != "查询中" || len(calls) != 1 || calls[0].Function.Arguments != `{"topic_id":"9"}` {
+		t.Errorf("Unexpected parsed parts: %q %+v", text, calls)
+	}
+}
@@ -27,14 +27,19 @@ type deepSeekRequest struct {
 	MaxTokens   int       `json:"max_tokens,omitempty"`
 	Temperature float64   `json:"temperature,omitempty"`
 	Stream      bool      `json:"stream,omitempty"`
+	// ResponseFormat OpenAI 风格的 response_format 参数
+	ResponseFormat interface{}  `json:"response_format,omitempty"`
+	Tools          []openAITool `json:"tools,omitempty"`
+	ToolChoice     interface{}  `json:"tool_choice,omitempty"`
 }
 
 type deepSeekResponse struct {
 	ID      string `json:"id"`
 	Choices []struct {
 		Message struct {
-			Content string `json:"content"`
-			Role    string `json:"role"`
+			Content   string     `json:"content"`
+			Role      string     `json:"role"`
+			ToolCalls []ToolCall `json:"tool_calls"`
 		} `json:"message"`
 		Delta struct {
 			Content string `json:"content"`
@@ -97,6 +102,9 @@ func (p *DeepSeekProvider) Generate(ctx context.Context, opts *GenerateOptions)
 		Messages: opts.Messages,
 		Stream:   false,
 	}
+	req.Tools = openAITools(opts.Tools)
+	req.ToolChoice = openAIToolChoice(opts)
+	req.ResponseFormat = openAIResponseFormat(opts.ResponseFormat, false)
 
 	if opts.MaxTokens > 0 {
 		req.MaxTokens = opts.MaxTokens
@@ -129,11 +137,14 @@ func (p *DeepSeekProvider) Generate(ctx context.Context, opts *GenerateOptions)
 
 	var result deepSeekResponse
 	if err := json.Unmarshal(respBody, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, NewAPIError(p.Name(), resp, string(respBody))
+		}
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
 
 	if result.Error != nil {
-		return nil, fmt.Errorf("API error [%s]: %s", result.Error.Code, result.Error.Message)
+		return nil, NewAPIError(p.Name(), resp, fmt.Sprintf("[%s] %s", result.Error.Code, result.Error.Message))
 	}
 
 	if len(result.Choices) == 0 {
@@ -147,6 +158,7 @@ func (p *DeepSeekProvider) Generate(ctx context.Context, opts *GenerateOptions)
 		InputTokens:  result.Usage.PromptTokens,
 		OutputTokens: result.Usage.CompletionTokens,
 		FinishedAt:   time.Now(),
+		ToolCalls:    normalizeToolCalls(result.Choices[0].Message.ToolCalls),
 	}, nil
 }
 
@@ -188,7 +200,7 @@ func (p *DeepSeekProvider) GenerateStream(ctx context.Context, opts *GenerateOpt
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("API error: %s - %s", resp.Status, string(respBody))
+		return nil, NewAPIError(p.Name(), resp, string(respBody))
 	}
 
 	ch := make(chan string, 100)
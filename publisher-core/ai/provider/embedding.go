@@ -0,0 +1,146 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ErrEmbeddingNotSupported 提供商不支持文本向量化
+var ErrEmbeddingNotSupported = errors.New("embedding not supported")
+
+// EmbedOptions 文本向量化参数
+type EmbedOptions struct {
+	// Model 为空时使用提供商的默认向量模型
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+// EmbedResult 向量化结果，Vectors 与 Input 一一对应
+type EmbedResult struct {
+	Vectors     [][]float32 `json:"vectors"`
+	Model       string      `json:"model"`
+	Provider    string      `json:"provider"`
+	InputTokens int         `json:"input_tokens"`
+}
+
+// Embedder 支持文本向量化的提供商
+type Embedder interface {
+	Embed(ctx context.Context, opts *EmbedOptions) (*EmbedResult, error)
+	DefaultEmbeddingModel() string
+}
+
+// AsEmbedder 返回提供商的向量化能力，包装过的提供商（如成本追踪）会检查被包装的提供商
+func AsEmbedder(p Provider) (Embedder, bool) {
+	inner := p
+	for {
+		w, ok := inner.(interface{ Unwrap() Provider })
+		if !ok {
+			break
+		}
+		inner = w.Unwrap()
+	}
+	if _, ok := inner.(Embedder); !ok {
+		return nil, false
+	}
+	// 包装器自身实现了 Embed 时优先使用，以便记录成本
+	if e, ok := p.(Embedder); ok {
+		return e, true
+	}
+	return inner.(Embedder), true
+}
+
+// openAIEmbeddingRequest OpenAI 风格的 /embeddings 请求，Mistral 和各兼容服务通用
+type openAIEmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type openAIEmbeddingResponse struct {
+	Model string `json:"model"`
+	Data  []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string      `json:"message"`
+		Code    interface{} `json:"code"`
+	} `json:"error,omitempty"`
+}
+
+// openAIEmbed 调用 OpenAI 风格的 /embeddings 接口
+func openAIEmbed(ctx context.Context, client *http.Client, pt ProviderType, url string, headers map[string]string, model string, input []string) (*EmbedResult, error) {
+	if model == "" {
+		return nil, fmt.Errorf("no embedding model configured for %s provider", pt)
+	}
+	if len(input) == 0 {
+		return &EmbedResult{Model: model, Provider: string(pt)}, nil
+	}
+
+	body, err := json.Marshal(openAIEmbeddingRequest{Model: model, Input: input})
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	var result openAIEmbeddingResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, NewAPIError(pt, resp, string(respBody))
+		}
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if result.Error != nil {
+		return nil, NewAPIError(pt, resp, fmt.Sprintf("[%v] %s", result.Error.Code, result.Error.Message))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, NewAPIError(pt, resp, string(respBody))
+	}
+	if len(result.Data) != len(input) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(input), len(result.Data))
+	}
+
+	vectors := make([][]float32, len(input))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("embedding index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	if result.Model != "" {
+		model = result.Model
+	}
+
+	return &EmbedResult{
+		Vectors:     vectors,
+		Model:       model,
+		Provider:    string(pt),
+		InputTokens: result.Usage.PromptTokens,
+	}, nil
+}
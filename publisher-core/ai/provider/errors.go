@@ -0,0 +1,155 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorClass 提供商错误类别，决定是否重试或降级
+type ErrorClass string
+
+const (
+	ErrorClassRateLimited     ErrorClass = "rate_limited"
+	ErrorClassAuth            ErrorClass = "auth"
+	ErrorClassContextLength   ErrorClass = "context_length"
+	ErrorClassContentFiltered ErrorClass = "content_filtered"
+	ErrorClassTransient       ErrorClass = "transient"
+	ErrorClassNotFound        ErrorClass = "not_found"
+	ErrorClassInvalidRequest  ErrorClass = "invalid_request"
+	ErrorClassUnknown         ErrorClass = "unknown"
+)
+
+// ProviderError 提供商返回的错误
+type ProviderError struct {
+	Provider   ProviderType
+	Class      ErrorClass
+	StatusCode int
+	Message    string
+	// RetryAfter 服务端建议的重试等待时间，来自 Retry-After 响应头
+	RetryAfter time.Duration
+}
+
+func (e *ProviderError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("%s API error (%s): %s", e.Provider, e.Class, e.Message)
+	}
+	return fmt.Sprintf("%s API error %d (%s): %s", e.Provider, e.StatusCode, e.Class, e.Message)
+}
+
+// NewAPIError 根据响应状态码和错误信息创建 ProviderError
+func NewAPIError(pt ProviderType, resp *http.Response, message string) *ProviderError {
+	err := &ProviderError{Provider: pt, Message: strings.TrimSpace(message)}
+	if resp != nil {
+		err.StatusCode = resp.StatusCode
+		err.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+	err.Class = classifyAPIError(err.StatusCode, err.Message)
+	return err
+}
+
+// classifyAPIError 按状态码和错误信息关键字分类
+// 状态码优先；只有 400 和以 200 状态返回的错误体才依据错误信息细分，
+// 避免鉴权、限流或服务端错误的提示中出现 "blocked"、"safety" 等字样时被当作内容过滤而跳过重试和降级
+func classifyAPIError(statusCode int, message string) ErrorClass {
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return ErrorClassRateLimited
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden || statusCode == http.StatusPaymentRequired:
+		return ErrorClassAuth
+	case statusCode == http.StatusNotFound:
+		return ErrorClassNotFound
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusConflict || statusCode >= 500:
+		return ErrorClassTransient
+	case statusCode == http.StatusRequestEntityTooLarge:
+		return ErrorClassContextLength
+	case statusCode >= 400 && statusCode != http.StatusBadRequest:
+		return ErrorClassInvalidRequest
+	}
+
+	lower := strings.ToLower(message)
+	switch {
+	case containsAny(lower, "context_length", "context length", "maximum context", "context window", "too many tokens", "token limit", "prompt is too long", "input is too long"):
+		return ErrorClassContextLength
+	case containsAny(lower, "content_filter", "content filter", "content policy", "content management policy", "safety", "moderation", "blocked"):
+		return ErrorClassContentFiltered
+	case statusCode == http.StatusBadRequest:
+		return ErrorClassInvalidRequest
+	}
+
+	// 部分接口以 200 状态返回错误体，只能依据错误信息判断
+	switch {
+	case containsAny(lower, "rate limit", "rate_limit", "too many requests", "quota"):
+		return ErrorClassRateLimited
+	case containsAny(lower, "api key", "api_key", "unauthorized", "authentication", "permission"):
+		return ErrorClassAuth
+	case containsAny(lower, "overloaded", "unavailable", "timeout", "try again"):
+		return ErrorClassTransient
+	case containsAny(lower, "not found", "does not exist"):
+		return ErrorClassNotFound
+	case containsAny(lower, "invalid", "bad request"):
+		return ErrorClassInvalidRequest
+	}
+	return ErrorClassUnknown
+}
+
+// ClassifyError 返回错误类别，非 ProviderError 时根据网络错误类型推断
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ""
+	}
+
+	var perr *ProviderError
+	if errors.As(err, &perr) {
+		return perr.Class
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrorClassUnknown
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, io.ErrUnexpectedEOF), errors.As(err, &netErr):
+		return ErrorClassTransient
+	}
+	return ErrorClassUnknown
+}
+
+// RetryAfter 返回错误携带的建议重试等待时间
+func RetryAfter(err error) time.Duration {
+	var perr *ProviderError
+	if errors.As(err, &perr) {
+		return perr.RetryAfter
+	}
+	return 0
+}
+
+// parseRetryAfter 解析 Retry-After 响应头，支持秒数和 HTTP 日期
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClassifyAPIError(t *testing.T) {
+	tests := []struct {
+		status  int
+		message string
+		want    ErrorClass
+	}{
+		{429, "slow down", ErrorClassRateLimited},
+		{401, "invalid api key", ErrorClassAuth},
+		{400, `{"error":{"code":"context_length_exceeded"}}`, ErrorClassContextLength},
+		{400, "Output blocked by content filtering policy (content_filter)", ErrorClassContentFiltered},
+		{400, "unknown field foo", ErrorClassInvalidRequest},
+		{404, "model not found", ErrorClassNotFound},
+		{503, "overloaded", ErrorClassTransient},
+		{200, "[rate_limit_exceeded] Rate limit reached", ErrorClassRateLimited},
+		{200, "response blocked by safety settings", ErrorClassContentFiltered},
+		{403, "request blocked by firewall", ErrorClassAuth},
+		{429, "safety system is overloaded", ErrorClassRateLimited},
+		{502, "upstream blocked", ErrorClassTransient},
+	}
+
+	for _, tt := range tests {
+		resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+		if got := NewAPIError(ProviderDeepSeek, resp, tt.message).Class; got != tt.want {
+			t.Errorf("status %d %q: got %s, want %s", tt.status, tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	apiErr := NewAPIError(ProviderGroq, &http.Response{StatusCode: 429, Header: http.Header{"Retry-After": []string{"3"}}}, "")
+	wrapped := fmt.Errorf("generate: %w", apiErr)
+
+	if ClassifyError(wrapped) != ErrorClassRateLimited {
+		t.Errorf("Expected wrapped ProviderError to keep its class")
+	}
+	if RetryAfter(wrapped) != 3*time.Second {
+		t.Errorf("Expected Retry-After 3s, got %s", RetryAfter(wrapped))
+	}
+	if ClassifyError(context.DeadlineExceeded) != ErrorClassTransient {
+		t.Error("Expected deadline exceeded to be transient")
+	}
+	if ClassifyError(errors.New("boom")) != ErrorClassUnknown {
+		t.Error("Expected plain errors to be unknown")
+	}
+}
+
+func TestProviderReturnsTypedError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, "<html>too many requests</html>")
+	}))
+	defer server.Close()
+
+	p, _ := NewOpenAICompatibleProvider(&OpenAICompatibleConfig{BaseURL: server.URL, DefaultModel: "m"})
+	_, err := p.Generate(context.Background(), &GenerateOptions{Messages: []Message{{Role: RoleUser, Content: "hi"}}})
+
+	var perr *ProviderError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Expected ProviderError, got %v", err)
+	}
+	if perr.Class != ErrorClassRateLimited || perr.StatusCode != 429 || perr.RetryAfter != time.Second {
+		t.Errorf("Unexpected error: %+v", perr)
+	}
+}
+
+func TestHealthCheckerCircuitBreaker(t *testing.T) {
+	h := NewHealthChecker(nil)
+	h.SetBreakerConfig(BreakerConfig{FailureThreshold: 2, Cooldown: 20 * time.Millisecond})
+	key := ProviderType("deepseek#1")
+
+	if !h.Allow(key) {
+		t.Fatal("Unknown key should be allowed")
+	}
+	if h.RecordFailure(key, errors.New("boom")) {
+		t.Error("Breaker should not open before threshold")
+	}
+	if !h.RecordFailure(key, errors.New("boom")) {
+		t.Error("Breaker should open at threshold")
+	}
+	if h.Allow(key) || h.IsHealthy(key) {
+		t.Error("Open breaker should reject requests")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !h.Allow(key) {
+		t.Fatal("Expected a trial request after cooldown")
+	}
+	if h.Allow(key) {
+		t.Error("Only one trial request should pass while half-open")
+	}
+
+	h.RecordSuccess(key, time.Millisecond)
+	if !h.Allow(key) || !h.IsHealthy(key) {
+		t.Error("Success should close the breaker")
+	}
+}
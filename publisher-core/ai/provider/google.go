@@ -22,8 +22,10 @@ type GoogleProvider struct {
 }
 
 type googleRequest struct {
-	Contents         []googleContent `json:"contents"`
-	GenerationConfig googleConfig    `json:"generationConfig,omitempty"`
+	Contents         []googleContent   `json:"contents"`
+	GenerationConfig googleConfig      `json:"generationConfig,omitempty"`
+	Tools            []googleTool      `json:"tools,omitempty"`
+	ToolConfig       *googleToolConfig `json:"toolConfig,omitempty"`
 }
 
 type googleContent struct {
@@ -32,22 +34,25 @@ type googleContent struct {
 }
 
 type googlePart struct {
-	Text string `json:"text"`
+	Text             string                  `json:"text,omitempty"`
+	FunctionCall     *googleFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *googleFunctionResponse `json:"functionResponse,omitempty"`
+	InlineData       *googleInlineData       `json:"inlineData,omitempty"`
 }
 
 type googleConfig struct {
 	Temperature     float64 `json:"temperature,omitempty"`
 	MaxOutputTokens int     `json:"maxOutputTokens,omitempty"`
 	TopP            float64 `json:"topP,omitempty"`
+	// ResponseMimeType 设为 application/json 时启用 JSON 模式
+	ResponseMimeType string `json:"responseMimeType,omitempty"`
 }
 
 type googleResponse struct {
 	Candidates []struct {
 		Content struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
-			Role string `json:"role"`
+			Parts []googlePart `json:"parts"`
+			Role  string       `json:"role"`
 		} `json:"content"`
 		FinishReason string `json:"finishReason"`
 	} `json:"candidates"`
@@ -96,17 +101,11 @@ func (p *GoogleProvider) Generate(ctx context.Context, opts *GenerateOptions) (*
 		model = p.defaultModel
 	}
 
-	contents := make([]googleContent, 0, len(opts.Messages))
-	for _, msg := range opts.Messages {
-		role := string(msg.Role)
-		if role == "assistant" {
-			role = "model"
-		}
-		contents = append(contents, googleContent{
-			Role:  role,
-			Parts: []googlePart{{Text: msg.Content}},
-		})
+	messages, err := inlineImages(ctx, opts.Messages)
+	if err != nil {
+		return nil, err
 	}
+	contents := googleContents(messages)
 
 	req := googleRequest{
 		Contents: contents,
@@ -116,6 +115,10 @@ func (p *GoogleProvider) Generate(ctx context.Context, opts *GenerateOptions) (*
 			TopP:            opts.TopP,
 		},
 	}
+	if wantsJSON(opts.ResponseFormat) {
+		req.GenerationConfig.ResponseMimeType = "application/json"
+	}
+	req.Tools, req.ToolConfig = googleTools(opts)
 
 	body, err := json.Marshal(req)
 	if err != nil {
@@ -142,21 +145,21 @@ func (p *GoogleProvider) Generate(ctx context.Context, opts *GenerateOptions) (*
 
 	var result googleResponse
 	if err := json.Unmarshal(respBody, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, NewAPIError(p.Name(), resp, string(respBody))
+		}
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
 
 	if result.Error != nil {
-		return nil, fmt.Errorf("API error: %s", result.Error.Message)
+		return nil, NewAPIError(p.Name(), resp, result.Error.Message)
 	}
 
 	if len(result.Candidates) == 0 {
 		return nil, fmt.Errorf("no response candidates")
 	}
 
-	content := ""
-	if len(result.Candidates[0].Content.Parts) > 0 {
-		content = result.Candidates[0].Content.Parts[0].Text
-	}
+	content, toolCalls := googleParseParts(result.Candidates[0].Content.Parts)
 
 	return &GenerateResult{
 		Content:      content,
@@ -165,6 +168,7 @@ func (p *GoogleProvider) Generate(ctx context.Context, opts *GenerateOptions) (*
 		InputTokens:  result.UsageMetadata.PromptTokenCount,
 		OutputTokens: result.UsageMetadata.CandidatesTokenCount,
 		FinishedAt:   time.Now(),
+		ToolCalls:    toolCalls,
 	}, nil
 }
 
@@ -174,17 +178,11 @@ func (p *GoogleProvider) GenerateStream(ctx context.Context, opts *GenerateOptio
 		model = p.defaultModel
 	}
 
-	contents := make([]googleContent, 0, len(opts.Messages))
-	for _, msg := range opts.Messages {
-		role := string(msg.Role)
-		if role == "assistant" {
-			role = "model"
-		}
-		contents = append(contents, googleContent{
-			Role:  role,
-			Parts: []googlePart{{Text: msg.Content}},
-		})
+	messages, err := inlineImages(ctx, opts.Messages)
+	if err != nil {
+		return nil, err
 	}
+	contents := googleContents(messages)
 
 	req := googleRequest{
 		Contents: contents,
@@ -217,7 +215,7 @@ func (p *GoogleProvider) GenerateStream(ctx context.Context, opts *GenerateOptio
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("API error: %s - %s", resp.Status, string(respBody))
+		return nil, NewAPIError(p.Name(), resp, string(respBody))
 	}
 
 	ch := make(chan string, 100)
@@ -264,3 +262,95 @@ func (p *GoogleProvider) GenerateStream(ctx context.Context, opts *GenerateOptio
 
 	return ch, nil
 }
+
+// GoogleEmbeddingModel Google 默认向量模型
+const GoogleEmbeddingModel = "gemini-embedding-001"
+
+type googleEmbedRequest struct {
+	Requests []googleEmbedContentRequest `json:"requests"`
+}
+
+type googleEmbedContentRequest struct {
+	Model   string        `json:"model"`
+	Content googleContent `json:"content"`
+}
+
+type googleEmbedResponse struct {
+	Embeddings []struct {
+		Values []float32 `json:"values"`
+	} `json:"embeddings"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// DefaultEmbeddingModel 返回默认向量模型
+func (p *GoogleProvider) DefaultEmbeddingModel() string {
+	return GoogleEmbeddingModel
+}
+
+// Embed 通过 batchEmbedContents 批量向量化文本
+func (p *GoogleProvider) Embed(ctx context.Context, opts *EmbedOptions) (*EmbedResult, error) {
+	model := opts.Model
+	if model == "" {
+		model = GoogleEmbeddingModel
+	}
+	if len(opts.Input) == 0 {
+		return &EmbedResult{Model: model, Provider: string(ProviderGoogle)}, nil
+	}
+
+	req := googleEmbedRequest{Requests: make([]googleEmbedContentRequest, len(opts.Input))}
+	for i, text := range opts.Input {
+		req.Requests[i] = googleEmbedContentRequest{
+			Model:   "models/" + model,
+			Content: googleContent{Parts: []googlePart{{Text: text}}},
+		}
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/models/%s:batchEmbedContents?key=%s", p.baseURL, model, p.apiKey)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+
+	var result googleEmbedResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, NewAPIError(p.Name(), resp, string(respBody))
+		}
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if result.Error != nil {
+		return nil, NewAPIError(p.Name(), resp, result.Error.Message)
+	}
+	if len(result.Embeddings) != len(opts.Input) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(opts.Input), len(result.Embeddings))
+	}
+
+	vectors := make([][]float32, len(result.Embeddings))
+	for i, e := range result.Embeddings {
+		vectors[i] = e.Values
+	}
+	return &EmbedResult{
+		Vectors:     vectors,
+		Model:       model,
+		Provider:    string(ProviderGoogle),
+		InputTokens: CountTokens(ProviderGoogle, model, strings.Join(opts.Input, "\n")),
+	}, nil
+}
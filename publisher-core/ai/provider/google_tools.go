@@ -0,0 +1,226 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type googleFunctionCall struct {
+	Name string                 `json:"name"`
+	Args map[string]interface{} `json:"args,omitempty"`
+}
+
+type googleFunctionResponse struct {
+	Name     string                 `json:"name"`
+	Response map[string]interface{} `json:"response"`
+}
+
+type googleInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type googleTool struct {
+	FunctionDeclarations []googleFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type googleFunctionDeclaration struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+type googleToolConfig struct {
+	FunctionCallingConfig struct {
+		Mode string `json:"mode"`
+	} `json:"functionCallingConfig"`
+}
+
+// googleSchemaKeys Gemini 函数参数支持的 schema 字段，其余字段（如 default）会被拒绝
+var googleSchemaKeys = map[string]bool{
+	"type":        true,
+	"format":      true,
+	"description": true,
+	"nullable":    true,
+	"enum":        true,
+	"properties":  true,
+	"required":    true,
+	"items":       true,
+	"minimum":     true,
+	"maximum":     true,
+	"minItems":    true,
+	"maxItems":    true,
+}
+
+// googleContents 将消息转换为 Gemini 的 contents
+// 助手的工具调用转换为 functionCall，连续的工具结果合并为同一轮的 functionResponse
+func googleContents(messages []Message) []googleContent {
+	contents := make([]googleContent, 0, len(messages))
+	callNames := make(map[string]string)
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case RoleAssistant:
+			content := googleContent{Role: "model"}
+			if msg.Content != "" || len(msg.ToolCalls) == 0 {
+				content.Parts = append(content.Parts, googlePart{Text: msg.Content})
+			}
+			for _, call := range msg.ToolCalls {
+				callNames[call.ID] = call.Function.Name
+				args, err := call.ParseArguments()
+				if err != nil {
+					args = nil
+				}
+				content.Parts = append(content.Parts, googlePart{
+					FunctionCall: &googleFunctionCall{Name: call.Function.Name, Args: args},
+				})
+			}
+			contents = append(contents, content)
+
+		case RoleTool:
+			name := msg.Name
+			if name == "" {
+				name = callNames[msg.ToolCallID]
+			}
+			part := googlePart{FunctionResponse: &googleFunctionResponse{
+				Name:     name,
+				Response: googleToolResponse(msg.Content),
+			}}
+
+			if n := len(contents); n > 0 && contents[n-1].Role == "user" && isFunctionResponse(contents[n-1]) {
+				contents[n-1].Parts = append(contents[n-1].Parts, part)
+			} else {
+				contents = append(contents, googleContent{Role: "user", Parts: []googlePart{part}})
+			}
+
+		default:
+			content := googleContent{Role: string(msg.Role)}
+			if msg.Content != "" || len(msg.Images) == 0 {
+				content.Parts = append(content.Parts, googlePart{Text: msg.Content})
+			}
+			// 图片需已内联为 base64 数据
+			for _, img := range msg.Images {
+				content.Parts = append(content.Parts, googlePart{
+					InlineData: &googleInlineData{MimeType: img.MimeType, Data: img.Data},
+				})
+			}
+			contents = append(contents, content)
+		}
+	}
+
+	return contents
+}
+
+func isFunctionResponse(content googleContent) bool {
+	return len(content.Parts) > 0 && content.Parts[0].FunctionResponse != nil
+}
+
+// googleToolResponse 工具结果为 JSON 对象时直接使用，否则包装为 {"content": ...}
+func googleToolResponse(content string) map[string]interface{} {
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &obj); err == nil && obj != nil {
+		return obj
+	}
+	return map[string]interface{}{"content": content}
+}
+
+// googleTools 转换工具定义和调用策略
+func googleTools(opts *GenerateOptions) ([]googleTool, *googleToolConfig) {
+	if len(opts.Tools) == 0 {
+		return nil, nil
+	}
+
+	decls := make([]googleFunctionDeclaration, len(opts.Tools))
+	for i, def := range opts.Tools {
+		decls[i] = googleFunctionDeclaration{
+			Name:        def.Name,
+			Description: def.Description,
+			Parameters:  googleSchema(def.Parameters),
+		}
+	}
+
+	var config *googleToolConfig
+	mode := map[string]string{
+		ToolChoiceAuto:     "AUTO",
+		ToolChoiceNone:     "NONE",
+		ToolChoiceRequired: "ANY",
+	}[opts.ToolChoice]
+	if mode != "" {
+		config = &googleToolConfig{}
+		config.FunctionCallingConfig.Mode = mode
+	}
+
+	return []googleTool{{FunctionDeclarations: decls}}, config
+}
+
+// googleSchema 去除 Gemini 不支持的 schema 字段
+// 没有属性的对象参数返回 nil，Gemini 不接受空的 properties
+func googleSchema(schema map[string]interface{}) map[string]interface{} {
+	if schema == nil {
+		return nil
+	}
+
+	result := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		if !googleSchemaKeys[key] {
+			continue
+		}
+		switch key {
+		case "properties":
+			props, ok := value.(map[string]interface{})
+			if !ok || len(props) == 0 {
+				continue
+			}
+			cleaned := make(map[string]interface{}, len(props))
+			for name, prop := range props {
+				propSchema, ok := prop.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if cleanedProp := googleSchema(propSchema); cleanedProp != nil {
+					cleaned[name] = cleanedProp
+				}
+			}
+			result[key] = cleaned
+		case "items":
+			if items, ok := value.(map[string]interface{}); ok {
+				result[key] = googleSchema(items)
+			}
+		default:
+			result[key] = value
+		}
+	}
+
+	if result["type"] == "object" && result["properties"] == nil {
+		return nil
+	}
+	return result
+}
+
+// googleParseParts 提取文本和函数调用
+func googleParseParts(parts []googlePart) (string, []ToolCall) {
+	var text strings.Builder
+	var calls []ToolCall
+
+	for _, part := range parts {
+		if part.FunctionCall != nil {
+			args, _ := json.Marshal(part.FunctionCall.Args)
+			if part.FunctionCall.Args == nil {
+				args = []byte("{}")
+			}
+			calls = append(calls, ToolCall{
+				ID:   fmt.Sprintf("call_%d", len(calls)),
+				Type: "function",
+				Function: ToolCallFunction{
+					Name:      part.FunctionCall.Name,
+					Arguments: string(args),
+				},
+			})
+			continue
+		}
+		text.WriteString(part.Text)
+	}
+
+	return text.String(), calls
+}
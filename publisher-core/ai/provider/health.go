@@ -14,6 +14,20 @@ type HealthChecker struct {
 	interval  time.Duration
 	stopChan  chan struct{}
 	mu        sync.RWMutex
+	breaker   BreakerConfig
+}
+
+// BreakerConfig 熔断配置
+type BreakerConfig struct {
+	// FailureThreshold 连续失败多少次后熔断
+	FailureThreshold int
+	// Cooldown 熔断持续时间，到期后放行一次试探请求
+	Cooldown time.Duration
+}
+
+// DefaultBreakerConfig 返回默认熔断配置
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{FailureThreshold: 3, Cooldown: time.Minute}
 }
 
 // NewHealthChecker 创建健康检查器
@@ -23,6 +37,7 @@ func NewHealthChecker(providers map[ProviderType]Provider) *HealthChecker {
 		stats:     make(map[ProviderType]*ProviderStats),
 		interval:  30 * time.Second,
 		stopChan:  make(chan struct{}),
+		breaker:   DefaultBreakerConfig(),
 	}
 
 	// 初始化统计
@@ -106,6 +121,8 @@ func (h *HealthChecker) checkProvider(pType ProviderType, provider Provider) {
 		stats.IsHealthy = true
 		stats.SuccessCalls++
 		stats.LastError = ""
+		stats.Failures = 0
+		stats.OpenUntil = time.Time{}
 	}
 
 	stats.TotalCalls++
@@ -161,6 +178,83 @@ func (h *HealthChecker) MarkHealthy(pType ProviderType) {
 	}
 }
 
+// SetBreakerConfig 设置熔断配置
+func (h *HealthChecker) SetBreakerConfig(config BreakerConfig) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.breaker = config
+}
+
+// Allow 判断熔断器是否放行请求
+// 熔断期内拒绝；熔断到期后放行一次试探请求，并在结果返回前继续拒绝其余请求
+func (h *HealthChecker) Allow(key ProviderType) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats, exists := h.stats[key]
+	if !exists || stats.OpenUntil.IsZero() {
+		return true
+	}
+
+	now := time.Now()
+	if now.Before(stats.OpenUntil) {
+		return false
+	}
+	stats.OpenUntil = now.Add(h.breaker.Cooldown)
+	return true
+}
+
+// RecordSuccess 记录一次成功调用并关闭熔断器
+func (h *HealthChecker) RecordSuccess(key ProviderType, duration time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats := h.statsFor(key)
+	stats.TotalCalls++
+	stats.SuccessCalls++
+	stats.SuccessRate = float64(stats.SuccessCalls) / float64(stats.TotalCalls) * 100
+	stats.AvgDuration = duration
+	stats.LastCallTime = time.Now()
+	stats.LastError = ""
+	stats.IsHealthy = true
+	stats.Failures = 0
+	stats.OpenUntil = time.Time{}
+}
+
+// RecordFailure 记录一次失败调用，连续失败达到阈值时熔断
+// 返回本次失败是否触发了熔断
+func (h *HealthChecker) RecordFailure(key ProviderType, err error) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats := h.statsFor(key)
+	stats.TotalCalls++
+	stats.FailedCalls++
+	stats.SuccessRate = float64(stats.SuccessCalls) / float64(stats.TotalCalls) * 100
+	stats.LastCallTime = time.Now()
+	if err != nil {
+		stats.LastError = err.Error()
+	}
+	stats.Failures++
+
+	if h.breaker.FailureThreshold <= 0 || stats.Failures < h.breaker.FailureThreshold {
+		return false
+	}
+	stats.IsHealthy = false
+	stats.OpenUntil = time.Now().Add(h.breaker.Cooldown)
+	return true
+}
+
+// statsFor 返回统计项，不存在时创建，调用方需持有写锁
+func (h *HealthChecker) statsFor(key ProviderType) *ProviderStats {
+	stats, exists := h.stats[key]
+	if !exists {
+		stats = &ProviderStats{IsHealthy: true, QualityScore: 100.0}
+		h.stats[key] = stats
+	}
+	return stats
+}
+
 // GetHealthyProviders 获取所有健康的提供商
 func (h *HealthChecker) GetHealthyProviders() []ProviderType {
 	h.mu.RLock()
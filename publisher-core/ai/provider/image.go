@@ -0,0 +1,318 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"publisher-core/storage"
+)
+
+// maxImageSize 单张图片的大小上限
+const maxImageSize = 20 << 20
+
+// ImagePart 消息中的图片，URL 与 Data 二选一
+type ImagePart struct {
+	// URL 远程图片地址
+	URL string `json:"url,omitempty"`
+	// Data base64 编码的图片数据
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
+	// Detail 清晰度提示：low、high、auto，仅 OpenAI 风格接口使用
+	Detail string `json:"detail,omitempty"`
+}
+
+// ImageFromBytes 由图片数据创建图片
+func ImageFromBytes(data []byte, mimeType string) ImagePart {
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return ImagePart{
+		Data:     base64.StdEncoding.EncodeToString(data),
+		MimeType: mimeType,
+	}
+}
+
+// ImageFromURL 由远程地址创建图片，需要内联数据的提供商会在请求时下载
+func ImageFromURL(url string) ImagePart {
+	return ImagePart{URL: url}
+}
+
+// imageClient 下载远程图片的客户端
+// 地址可能来自用户输入，连接时检查解析后的 IP，拒绝内网、回环和链路本地地址，重定向同样受限
+var imageClient = &http.Client{
+	Timeout: 30 * time.Second,
+	Transport: &http.Transport{
+		Proxy: nil,
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !imageHostAllowed(ip) {
+					return fmt.Errorf("image host %s is not a public address", host)
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 20 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 5 {
+			return fmt.Errorf("too many redirects")
+		}
+		return checkImageURL(req.URL)
+	},
+}
+
+// imageHostAllowed 检查图片地址解析出的 IP，测试中可替换
+var imageHostAllowed = publicIP
+
+// publicIP 判断是否为公网地址
+func publicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// checkImageURL 远程图片只允许 http 和 https
+func checkImageURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported image URL scheme: %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("image URL has no host")
+	}
+	return nil
+}
+
+// ImageFromFile 读取本地图片文件
+func ImageFromFile(path string) (ImagePart, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ImagePart{}, fmt.Errorf("read image %s: %w", path, err)
+	}
+	if len(data) > maxImageSize {
+		return ImagePart{}, fmt.Errorf("image %s exceeds %d bytes", path, maxImageSize)
+	}
+	return ImageFromBytes(data, mime.TypeByExtension(filepath.Ext(path))), nil
+}
+
+// ImageFromStorage 从存储中读取图片
+func ImageFromStorage(ctx context.Context, store storage.Storage, path string) (ImagePart, error) {
+	data, err := store.Read(ctx, path)
+	if err != nil {
+		return ImagePart{}, fmt.Errorf("read image %s: %w", path, err)
+	}
+	if len(data) > maxImageSize {
+		return ImagePart{}, fmt.Errorf("image %s exceeds %d bytes", path, maxImageSize)
+	}
+	return ImageFromBytes(data, mime.TypeByExtension(filepath.Ext(path))), nil
+}
+
+// dataURL 返回 data URL 或远程地址
+func (img ImagePart) dataURL() string {
+	if img.Data == "" {
+		return img.URL
+	}
+	mimeType := img.MimeType
+	if mimeType == "" {
+		mimeType = "image/jpeg"
+	}
+	return fmt.Sprintf("data:%s;base64,%s", mimeType, img.Data)
+}
+
+// inline 确保图片携带 base64 数据，远程图片会被下载
+func (img ImagePart) inline(ctx context.Context) (ImagePart, error) {
+	if img.Data != "" {
+		return img, nil
+	}
+	if img.URL == "" {
+		return img, fmt.Errorf("image has neither data nor URL")
+	}
+
+	if strings.HasPrefix(img.URL, "data:") {
+		return parseDataURL(img)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", img.URL, nil)
+	if err != nil {
+		return img, fmt.Errorf("create image request: %w", err)
+	}
+	if err := checkImageURL(req.URL); err != nil {
+		return img, err
+	}
+	resp, err := imageClient.Do(req)
+	if err != nil {
+		return img, fmt.Errorf("download image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return img, fmt.Errorf("download image: %s", resp.Status)
+	}
+	if resp.ContentLength > maxImageSize {
+		return img, fmt.Errorf("image %s exceeds %d bytes", img.URL, maxImageSize)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return img, fmt.Errorf("download image: %w", err)
+	}
+	if len(data) > maxImageSize {
+		return img, fmt.Errorf("image %s exceeds %d bytes", img.URL, maxImageSize)
+	}
+
+	mimeType := resp.Header.Get("Content-Type")
+	if mimeType == "" || !strings.HasPrefix(mimeType, "image/") {
+		mimeType = http.DetectContentType(data)
+	}
+	inlined := ImageFromBytes(data, mimeType)
+	inlined.Detail = img.Detail
+	return inlined, nil
+}
+
+// parseDataURL 解析 base64 data URL
+func parseDataURL(img ImagePart) (ImagePart, error) {
+	meta, data, ok := strings.Cut(strings.TrimPrefix(img.URL, "data:"), ",")
+	if !ok || !strings.HasSuffix(meta, ";base64") {
+		return img, fmt.Errorf("unsupported data URL")
+	}
+	return ImagePart{Data: data, MimeType: strings.TrimSuffix(meta, ";base64"), Detail: img.Detail}, nil
+}
+
+// inlineImages 返回所有图片均已内联数据的消息副本，没有远程图片时原样返回
+func inlineImages(ctx context.Context, messages []Message) ([]Message, error) {
+	needsInline := false
+	for _, msg := range messages {
+		for _, img := range msg.Images {
+			if img.Data == "" {
+				needsInline = true
+			}
+		}
+	}
+	if !needsInline {
+		return messages, nil
+	}
+
+	result := make([]Message, len(messages))
+	copy(result, messages)
+	for i := range result {
+		if len(result[i].Images) == 0 {
+			continue
+		}
+		images := make([]ImagePart, len(result[i].Images))
+		for j, img := range result[i].Images {
+			inlined, err := img.inline(ctx)
+			if err != nil {
+				return nil, err
+			}
+			images[j] = inlined
+		}
+		result[i].Images = images
+	}
+	return result, nil
+}
+
+// HasImages 判断消息中是否包含图片
+func HasImages(messages []Message) bool {
+	for _, msg := range messages {
+		if len(msg.Images) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// contentPart OpenAI 风格的多模态内容块
+type contentPart struct {
+	Type     string        `json:"type"`
+	Text     string        `json:"text,omitempty"`
+	ImageURL *imageURLPart `json:"image_url,omitempty"`
+}
+
+type imageURLPart struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// messageAlias 避免 MarshalJSON 递归
+type messageAlias Message
+
+// MarshalJSON 带图片的消息按 OpenAI 风格输出为内容块数组，其余消息 content 保持字符串
+func (m Message) MarshalJSON() ([]byte, error) {
+	if len(m.Images) == 0 {
+		return json.Marshal(messageAlias(m))
+	}
+
+	parts := make([]contentPart, 0, len(m.Images)+1)
+	if m.Content != "" {
+		parts = append(parts, contentPart{Type: "text", Text: m.Content})
+	}
+	for _, img := range m.Images {
+		parts = append(parts, contentPart{
+			Type:     "image_url",
+			ImageURL: &imageURLPart{URL: img.dataURL(), Detail: img.Detail},
+		})
+	}
+
+	return json.Marshal(struct {
+		messageAlias
+		Content []contentPart `json:"content"`
+	}{messageAlias(m), parts})
+}
+
+// UnmarshalJSON 兼容字符串和内容块数组两种 content
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		messageAlias
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = Message(raw.messageAlias)
+	m.Content = ""
+
+	content := strings.TrimSpace(string(raw.Content))
+	if content == "" || content == "null" {
+		return nil
+	}
+	if !strings.HasPrefix(content, "[") {
+		return json.Unmarshal(raw.Content, &m.Content)
+	}
+
+	var parts []contentPart
+	if err := json.Unmarshal(raw.Content, &parts); err != nil {
+		return err
+	}
+	var text []string
+	for _, part := range parts {
+		switch {
+		case part.Type == "text":
+			text = append(text, part.Text)
+		case part.ImageURL != nil:
+			img := ImagePart{URL: part.ImageURL.URL, Detail: part.ImageURL.Detail}
+			if strings.HasPrefix(img.URL, "data:") {
+				if parsed, err := parseDataURL(img); err == nil {
+					img = parsed
+				}
+			}
+			m.Images = append(m.Images, img)
+		}
+	}
+	m.Content = strings.Join(text, "\n")
+	return nil
+}
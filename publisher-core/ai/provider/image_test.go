@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMessageMarshalWithImages(t *testing.T) {
+	plain, _ := json.Marshal(Message{Role: RoleUser, Content: "hi"})
+	if string(plain) != `{"role":"user","content":"hi"}` {
+		t.Errorf("Text-only message should keep string content, got %s", plain)
+	}
+
+	msg := Message{
+		Role:    RoleUser,
+		Content: "描述图片",
+		Images: []ImagePart{
+			ImageFromBytes([]byte("fake"), "image/png"),
+			ImageFromURL("https://example.com/a.jpg"),
+		},
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var raw struct {
+		Content []contentPart `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("Expected content parts, got %s", data)
+	}
+	if len(raw.Content) != 3 || raw.Content[0].Text != "描述图片" {
+		t.Fatalf("Unexpected parts: %s", data)
+	}
+	if !strings.HasPrefix(raw.Content[1].ImageURL.URL, "data:image/png;base64,") || raw.Content[2].ImageURL.URL != "https://example.com/a.jpg" {
+		t.Errorf("Unexpected image URLs: %s", data)
+	}
+
+	var decoded Message
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded.Content != "描述图片" || len(decoded.Images) != 2 || decoded.Images[0].Data != msg.Images[0].Data || decoded.Images[0].MimeType != "image/png" {
+		t.Errorf("Round trip mismatch: %+v", decoded)
+	}
+}
+
+func TestImageFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "thumb.jpg")
+	os.WriteFile(path, []byte{0xff, 0xd8, 0xff}, 0644)
+
+	img, err := ImageFromFile(path)
+	if err != nil {
+		t.Fatalf("ImageFromFile failed: %v", err)
+	}
+	if img.MimeType != "image/jpeg" || img.Data != base64.StdEncoding.EncodeToString([]byte{0xff, 0xd8, 0xff}) {
+		t.Errorf("Unexpected image: %+v", img)
+	}
+}
+
+// allowLocalImages 允许测试从本地 httptest 服务下载图片
+func allowLocalImages(t *testing.T) {
+	imageHostAllowed = func(net.IP) bool { return true }
+	t.Cleanup(func() { imageHostAllowed = publicIP })
+}
+
+func TestImageInlineRejectsUnsafeURLs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	defer server.Close()
+
+	for _, u := range []string{
+		server.URL + "/a.png",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/a.png",
+		"file:///etc/passwd",
+		"ftp://example.com/a.png",
+	} {
+		if _, err := ImageFromURL(u).inline(context.Background()); err == nil {
+			t.Errorf("Expected %s to be rejected", u)
+		}
+	}
+}
+
+func TestImageInlineSizeLimit(t *testing.T) {
+	allowLocalImages(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, maxImageSize+1))
+	}))
+	defer server.Close()
+
+	if _, err := ImageFromURL(server.URL + "/big.png").inline(context.Background()); err == nil {
+		t.Error("Expected oversized image to be rejected")
+	}
+}
+
+func TestProviderImageConversion(t *testing.T) {
+	allowLocalImages(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("png-bytes"))
+	}))
+	defer server.Close()
+
+	messages := []Message{{
+		Role:    RoleUser,
+		Content: "这是什么",
+		Images:  []ImagePart{ImageFromURL(server.URL + "/a.png")},
+	}}
+
+	ollama, err := ollamaMessages(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("ollamaMessages failed: %v", err)
+	}
+	if len(ollama[0].Images) != 1 || ollama[0].Images[0] != base64.StdEncoding.EncodeToString([]byte("png-bytes")) {
+		t.Errorf("Expected downloaded image for Ollama, got %+v", ollama[0])
+	}
+	if messages[0].Images[0].Data != "" {
+		t.Error("Caller messages should not be modified")
+	}
+
+	inlined, err := inlineImages(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("inlineImages failed: %v", err)
+	}
+	contents := googleContents(inlined)
+	parts := contents[0].Parts
+	if len(parts) != 2 || parts[0].Text != "这是什么" || parts[1].InlineData == nil || parts[1].InlineData.MimeType != "image/png" {
+		t.Errorf("Unexpected Gemini parts: %+v", parts)
+	}
+}
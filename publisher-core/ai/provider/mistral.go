@@ -46,7 +46,7 @@ func NewMistralProvider(cfg *MistralConfig) *MistralProvider {
 
 // Name 返回提供商名称
 func (p *MistralProvider) Name() ProviderType {
-	return "mistral"
+	return ProviderMistral
 }
 
 // Generate 生成内容
@@ -70,6 +70,18 @@ func (p *MistralProvider) Generate(ctx context.Context, opts *GenerateOptions) (
 	if opts.TopP > 0 {
 		reqBody["top_p"] = opts.TopP
 	}
+	if format := openAIResponseFormat(opts.ResponseFormat, false); format != nil {
+		reqBody["response_format"] = format
+	}
+	if len(opts.Tools) > 0 {
+		reqBody["tools"] = openAITools(opts.Tools)
+		if opts.ToolChoice == ToolChoiceRequired {
+			// Mistral 使用 any 表示必须调用工具
+			reqBody["tool_choice"] = "any"
+		} else if opts.ToolChoice != "" {
+			reqBody["tool_choice"] = opts.ToolChoice
+		}
+	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
@@ -100,14 +112,15 @@ func (p *MistralProvider) Generate(ctx context.Context, opts *GenerateOptions) (
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API错误 [%d]: %s", resp.StatusCode, string(body))
+		return nil, NewAPIError(p.Name(), resp, string(body))
 	}
 
 	// 解析响应
 	var mistralResp struct {
 		Choices []struct {
 			Message struct {
-				Content string `json:"content"`
+				Content   string     `json:"content"`
+				ToolCalls []ToolCall `json:"tool_calls"`
 			} `json:"message"`
 			FinishReason string `json:"finish_reason"`
 		} `json:"choices"`
@@ -134,6 +147,7 @@ func (p *MistralProvider) Generate(ctx context.Context, opts *GenerateOptions) (
 		InputTokens:  mistralResp.Usage.PromptTokens,
 		OutputTokens: mistralResp.Usage.CompletionTokens,
 		FinishedAt:   time.Now(),
+		ToolCalls:    normalizeToolCalls(mistralResp.Choices[0].Message.ToolCalls),
 	}, nil
 }
 
@@ -177,6 +191,11 @@ func (p *MistralProvider) GenerateStream(ctx context.Context, opts *GenerateOpti
 	if err != nil {
 		return nil, fmt.Errorf("请求失败: %w", err)
 	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, NewAPIError(p.Name(), resp, string(body))
+	}
 
 	// 创建输出通道
 	output := make(chan string, 100)
@@ -234,3 +253,21 @@ func (p *MistralProvider) Models() []string {
 func (p *MistralProvider) DefaultModel() string {
 	return p.model
 }
+
+// MistralEmbeddingModel Mistral 默认向量模型
+const MistralEmbeddingModel = "mistral-embed"
+
+// DefaultEmbeddingModel 返回默认向量模型
+func (p *MistralProvider) DefaultEmbeddingModel() string {
+	return MistralEmbeddingModel
+}
+
+// Embed 文本向量化
+func (p *MistralProvider) Embed(ctx context.Context, opts *EmbedOptions) (*EmbedResult, error) {
+	model := opts.Model
+	if model == "" {
+		model = MistralEmbeddingModel
+	}
+	headers := map[string]string{"Authorization": fmt.Sprintf("Bearer %s", p.apiKey)}
+	return openAIEmbed(ctx, p.client, ProviderMistral, p.baseURL+"/embeddings", headers, model, opts.Input)
+}
@@ -3,8 +3,11 @@ package provider
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 // MultiModelService 多模型服务
@@ -12,6 +15,7 @@ type MultiModelService struct {
 	providers map[ProviderType]Provider
 	health    *HealthChecker
 	selector  *ProviderSelector
+	scorer    ResultScorer
 }
 
 // NewMultiModelService 创建多模型服务
@@ -36,6 +40,8 @@ type ParallelCallResult struct {
 	Error      error            `json:"error"`
 	Duration   time.Duration    `json:"duration"`
 	QualityScore float64        `json:"quality_score"`
+
+	opts *GenerateOptions
 }
 
 // ParallelCall 并行调用多个模型
@@ -78,6 +84,7 @@ func (s *MultiModelService) ParallelCall(ctx context.Context, opts *GenerateOpti
 				Result:   result,
 				Error:    err,
 				Duration: duration,
+				opts:     opts,
 			}
 			mu.Unlock()
 		}(i, providerType)
@@ -87,21 +94,35 @@ func (s *MultiModelService) ParallelCall(ctx context.Context, opts *GenerateOpti
 	return results, nil
 }
 
-// CompareResults 对比多个结果
+// ResultScorer 为生成结果打分，分数越高越好，evaluation.Scorer 实现了该接口
+type ResultScorer interface {
+	ScoreResult(ctx context.Context, opts *GenerateOptions, result *GenerateResult) (float64, error)
+}
+
+// SetScorer 设置 CompareResults 使用的打分器
+// 未设置时只区分输出是否为空，不再按长度、延迟或提供商加分
+func (s *MultiModelService) SetScorer(scorer ResultScorer) {
+	s.scorer = scorer
+}
+
+// CompareResults 对比多个结果，得分相同时耗时短的优先
 func (s *MultiModelService) CompareResults(results []*ParallelCallResult) *ParallelCallResult {
 	var bestResult *ParallelCallResult
 	bestScore := -1.0
 
 	for _, result := range results {
-		if result.Error != nil {
+		if result.Error != nil || result.Result == nil {
 			continue
 		}
 
-		// 计算质量评分
-		score := s.calculateQualityScore(result)
+		score, err := s.score(result)
+		if err != nil {
+			logrus.Warnf("结果打分失败: %s, 错误: %v", result.Provider, err)
+			continue
+		}
 		result.QualityScore = score
 
-		if score > bestScore {
+		if score > bestScore || (score == bestScore && result.Duration < bestResult.Duration) {
 			bestScore = score
 			bestResult = result
 		}
@@ -110,40 +131,15 @@ func (s *MultiModelService) CompareResults(results []*ParallelCallResult) *Paral
 	return bestResult
 }
 
-// calculateQualityScore 计算质量评分
-func (s *MultiModelService) calculateQualityScore(result *ParallelCallResult) float64 {
-	score := 100.0
-
-	// 响应时间评分（越快越好）
-	if result.Duration < 1*time.Second {
-		score += 10
-	} else if result.Duration < 3*time.Second {
-		score += 5
-	} else if result.Duration > 10*time.Second {
-		score -= 10
-	}
-
-	// 内容长度评分
-	if result.Result != nil {
-		contentLen := len(result.Result.Content)
-		if contentLen > 100 && contentLen < 2000 {
-			score += 5
-		} else if contentLen < 50 {
-			score -= 5
-		}
+// score 计算质量评分
+func (s *MultiModelService) score(result *ParallelCallResult) (float64, error) {
+	if s.scorer != nil {
+		return s.scorer.ScoreResult(context.Background(), result.opts, result.Result)
 	}
-
-	// 提供商优先级评分
-	switch result.Provider {
-	case ProviderOpenRouter:
-		score += 5
-	case ProviderGoogle:
-		score += 3
-	case ProviderGroq:
-		score += 2
+	if strings.TrimSpace(result.Result.Content) == "" {
+		return 0, nil
 	}
-
-	return score
+	return 1, nil
 }
 
 // SmartCall 智能调用（自动选择最佳提供商）
@@ -46,7 +46,7 @@ func NewNVIDIAProvider(cfg *NVIDIAConfig) *NVIDIAProvider {
 
 // Name 返回提供商名称
 func (p *NVIDIAProvider) Name() ProviderType {
-	return "nvidia"
+	return ProviderNVIDIA
 }
 
 // Generate 生成内容
@@ -100,7 +100,7 @@ func (p *NVIDIAProvider) Generate(ctx context.Context, opts *GenerateOptions) (*
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("API错误 [%d]: %s", resp.StatusCode, string(body))
+		return nil, NewAPIError(p.Name(), resp, string(body))
 	}
 
 	// 解析响应
@@ -27,10 +27,36 @@ type OllamaProvider struct {
 
 // OllamaChatRequest Ollama 聊天请求
 type OllamaChatRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
+	Model    string          `json:"model"`
+	Messages []OllamaMessage `json:"messages"`
 	Stream   bool      `json:"stream"`
 	Options  *OllamaOptions `json:"options,omitempty"`
+	// Format 设为 json 时启用 JSON 模式
+	Format string `json:"format,omitempty"`
+}
+
+// OllamaMessage Ollama 消息，图片以 base64 列表传递给 llava 等视觉模型
+type OllamaMessage struct {
+	Role    Role     `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"`
+}
+
+// ollamaMessages 转换消息，远程图片会先下载
+func ollamaMessages(ctx context.Context, messages []Message) ([]OllamaMessage, error) {
+	messages, err := inlineImages(ctx, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]OllamaMessage, len(messages))
+	for i, msg := range messages {
+		result[i] = OllamaMessage{Role: msg.Role, Content: msg.Content}
+		for _, img := range msg.Images {
+			result[i].Images = append(result[i].Images, img.Data)
+		}
+	}
+	return result, nil
 }
 
 // OllamaOptions Ollama 选项
@@ -80,11 +106,19 @@ func (p *OllamaProvider) Name() ProviderType {
 
 // Generate 生成内容
 func (p *OllamaProvider) Generate(ctx context.Context, opts *GenerateOptions) (*GenerateResult, error) {
+	messages, err := ollamaMessages(ctx, opts.Messages)
+	if err != nil {
+		return nil, err
+	}
+
 	req := &OllamaChatRequest{
 		Model:    p.model,
-		Messages: opts.Messages,
+		Messages: messages,
 		Stream:   false,
 	}
+	if wantsJSON(opts.ResponseFormat) {
+		req.Format = "json"
+	}
 
 	if opts.MaxTokens > 0 {
 		req.Options = &OllamaOptions{
@@ -120,7 +154,7 @@ func (p *OllamaProvider) Generate(ctx context.Context, opts *GenerateOptions) (*
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, NewAPIError(p.Name(), resp, string(body))
 	}
 
 	var ollamaResp OllamaChatResponse
@@ -140,6 +174,11 @@ func (p *OllamaProvider) Generate(ctx context.Context, opts *GenerateOptions) (*
 
 // GenerateStream 流式生成内容
 func (p *OllamaProvider) GenerateStream(ctx context.Context, opts *GenerateOptions) (<-chan string, error) {
+	messages, err := ollamaMessages(ctx, opts.Messages)
+	if err != nil {
+		return nil, err
+	}
+
 	ch := make(chan string)
 
 	go func() {
@@ -147,7 +186,7 @@ func (p *OllamaProvider) GenerateStream(ctx context.Context, opts *GenerateOptio
 
 		req := &OllamaChatRequest{
 			Model:    p.model,
-			Messages: opts.Messages,
+			Messages: messages,
 			Stream:   true,
 		}
 
@@ -223,3 +262,76 @@ func (p *OllamaProvider) Models() []string {
 func (p *OllamaProvider) DefaultModel() string {
 	return p.model
 }
+
+// OllamaEmbeddingModel Ollama 默认向量模型
+const OllamaEmbeddingModel = "embeddinggemma"
+
+// ollamaEmbedRequest Ollama /embed 请求
+type ollamaEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// ollamaEmbedResponse Ollama /embed 响应
+type ollamaEmbedResponse struct {
+	Model           string      `json:"model"`
+	Embeddings      [][]float32 `json:"embeddings"`
+	PromptEvalCount int         `json:"prompt_eval_count"`
+}
+
+// DefaultEmbeddingModel 返回默认向量模型
+func (p *OllamaProvider) DefaultEmbeddingModel() string {
+	return OllamaEmbeddingModel
+}
+
+// Embed 文本向量化
+func (p *OllamaProvider) Embed(ctx context.Context, opts *EmbedOptions) (*EmbedResult, error) {
+	model := opts.Model
+	if model == "" {
+		model = OllamaEmbeddingModel
+	}
+	if len(opts.Input) == 0 {
+		return &EmbedResult{Model: model, Provider: string(ProviderOllama)}, nil
+	}
+
+	reqBody, err := json.Marshal(&ollamaEmbedRequest{Model: model, Input: opts.Input})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/embed", bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, NewAPIError(p.Name(), resp, string(body))
+	}
+
+	var result ollamaEmbedResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(result.Embeddings) != len(opts.Input) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(opts.Input), len(result.Embeddings))
+	}
+	if result.Model != "" {
+		model = result.Model
+	}
+
+	return &EmbedResult{
+		Vectors:     result.Embeddings,
+		Model:       model,
+		Provider:    string(ProviderOllama),
+		InputTokens: result.PromptEvalCount,
+	}, nil
+}
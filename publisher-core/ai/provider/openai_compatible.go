@@ -0,0 +1,362 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 常见 OpenAI 兼容服务的默认地址
+var openAICompatibleEndpoints = map[ProviderType]string{
+	ProviderOpenAI:   "https://api.openai.com/v1",
+	ProviderQwen:     "https://dashscope.aliyuncs.com/compatible-mode/v1",
+	ProviderDoubao:   "https://ark.cn-beijing.volces.com/api/v3",
+	ProviderVLLM:     "http://localhost:8000/v1",
+	ProviderLMStudio: "http://localhost:1234/v1",
+}
+
+// DefaultOpenAICompatibleBaseURL 返回已知 OpenAI 兼容服务的默认地址
+func DefaultOpenAICompatibleBaseURL(pt ProviderType) string {
+	return openAICompatibleEndpoints[pt]
+}
+
+// IsOpenAICompatible 判断提供商是否通过 OpenAI 兼容接口访问
+func IsOpenAICompatible(pt ProviderType) bool {
+	if pt == ProviderOpenAICompatible {
+		return true
+	}
+	_, ok := openAICompatibleEndpoints[pt]
+	return ok
+}
+
+// OpenAICompatibleConfig OpenAI 兼容提供商配置
+type OpenAICompatibleConfig struct {
+	// Name 提供商名称，为空时使用 openai_compatible
+	Name ProviderType
+	// APIKey 为空时不发送 Authorization 头，适用于本地部署
+	APIKey  string
+	BaseURL string
+	// Headers 额外请求头，如网关鉴权或组织ID
+	Headers      map[string]string
+	Models       []string
+	DefaultModel string
+	// ChatPath 聊天接口路径，默认 /chat/completions
+	ChatPath string
+	// EmbeddingModel 向量模型，为空时使用已知服务的默认值
+	EmbeddingModel string
+	Timeout        time.Duration
+}
+
+// OpenAICompatibleProvider 通用 OpenAI 兼容提供商
+// 适用于 vLLM、LM Studio、通义千问、豆包等提供 /chat/completions 接口的服务
+type OpenAICompatibleProvider struct {
+	name         ProviderType
+	apiKey       string
+	baseURL      string
+	chatPath     string
+	headers      map[string]string
+	models       []string
+	defaultModel string
+	// embeddingModel 默认向量模型
+	embeddingModel string
+	client         *http.Client
+}
+
+type openAICompatibleRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Temperature float64   `json:"temperature,omitempty"`
+	TopP        float64   `json:"top_p,omitempty"`
+	Stop        []string  `json:"stop,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
+	// ResponseFormat 结构化输出格式，兼容服务通常支持 json_schema
+	ResponseFormat interface{}  `json:"response_format,omitempty"`
+	Tools          []openAITool `json:"tools,omitempty"`
+	ToolChoice     interface{}  `json:"tool_choice,omitempty"`
+}
+
+type openAICompatibleResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content   string     `json:"content"`
+			ToolCalls []ToolCall `json:"tool_calls"`
+		} `json:"message"`
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string      `json:"message"`
+		Type    string      `json:"type"`
+		Code    interface{} `json:"code"`
+	} `json:"error,omitempty"`
+}
+
+// NewOpenAICompatibleProvider 创建 OpenAI 兼容提供商
+func NewOpenAICompatibleProvider(cfg *OpenAICompatibleConfig) (*OpenAICompatibleProvider, error) {
+	name := cfg.Name
+	if name == "" {
+		name = ProviderOpenAICompatible
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultOpenAICompatibleBaseURL(name)
+	}
+	if baseURL == "" {
+		return nil, fmt.Errorf("base URL is required for %s provider", name)
+	}
+
+	defaultModel := cfg.DefaultModel
+	if defaultModel == "" && len(cfg.Models) > 0 {
+		defaultModel = cfg.Models[0]
+	}
+	models := cfg.Models
+	if len(models) == 0 && defaultModel != "" {
+		models = []string{defaultModel}
+	}
+
+	chatPath := cfg.ChatPath
+	if chatPath == "" {
+		chatPath = "/chat/completions"
+	}
+
+	embeddingModel := cfg.EmbeddingModel
+	if embeddingModel == "" {
+		embeddingModel = openAICompatibleEmbeddingModels[name]
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 180 * time.Second
+	}
+
+	return &OpenAICompatibleProvider{
+		name:           name,
+		apiKey:         cfg.APIKey,
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		chatPath:       "/" + strings.TrimLeft(chatPath, "/"),
+		headers:        cfg.Headers,
+		models:         models,
+		defaultModel:   defaultModel,
+		embeddingModel: embeddingModel,
+		client:         &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Name 返回提供商名称
+func (p *OpenAICompatibleProvider) Name() ProviderType {
+	return p.name
+}
+
+// Models 返回配置的模型列表
+func (p *OpenAICompatibleProvider) Models() []string {
+	return p.models
+}
+
+// DefaultModel 返回默认模型
+func (p *OpenAICompatibleProvider) DefaultModel() string {
+	return p.defaultModel
+}
+
+// buildRequest 构建聊天请求
+func (p *OpenAICompatibleProvider) buildRequest(ctx context.Context, opts *GenerateOptions, stream bool) (*http.Request, string, error) {
+	model := opts.Model
+	if model == "" {
+		model = p.defaultModel
+	}
+	if model == "" {
+		return nil, "", fmt.Errorf("no model configured for %s provider", p.name)
+	}
+
+	body, err := json.Marshal(openAICompatibleRequest{
+		Model:          model,
+		Messages:       opts.Messages,
+		MaxTokens:      opts.MaxTokens,
+		Temperature:    opts.Temperature,
+		TopP:           opts.TopP,
+		Stop:           opts.Stop,
+		Stream:         stream,
+		ResponseFormat: openAIResponseFormat(opts.ResponseFormat, true),
+		Tools:          openAITools(opts.Tools),
+		ToolChoice:     openAIToolChoice(opts),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+p.chatPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", fmt.Errorf("create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	for k, v := range p.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	return httpReq, model, nil
+}
+
+// Generate 生成文本
+func (p *OpenAICompatibleProvider) Generate(ctx context.Context, opts *GenerateOptions) (*GenerateResult, error) {
+	httpReq, model, err := p.buildRequest(ctx, opts, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	var result openAICompatibleResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, NewAPIError(p.Name(), resp, string(respBody))
+		}
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	if result.Error != nil {
+		return nil, NewAPIError(p.Name(), resp, fmt.Sprintf("[%v] %s", result.Error.Code, result.Error.Message))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, NewAPIError(p.Name(), resp, string(respBody))
+	}
+	if len(result.Choices) == 0 {
+		return nil, fmt.Errorf("no response choices")
+	}
+
+	if result.Model != "" {
+		model = result.Model
+	}
+
+	return &GenerateResult{
+		Content:      result.Choices[0].Message.Content,
+		Model:        model,
+		Provider:     string(p.name),
+		InputTokens:  result.Usage.PromptTokens,
+		OutputTokens: result.Usage.CompletionTokens,
+		FinishedAt:   time.Now(),
+		ToolCalls:    normalizeToolCalls(result.Choices[0].Message.ToolCalls),
+	}, nil
+}
+
+// GenerateStream 流式生成文本
+func (p *OpenAICompatibleProvider) GenerateStream(ctx context.Context, opts *GenerateOptions) (<-chan string, error) {
+	httpReq, _, err := p.buildRequest(ctx, opts, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// 流式请求的时长由 ctx 控制
+	client := &http.Client{Transport: p.client.Transport}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, NewAPIError(p.Name(), resp, string(respBody))
+	}
+
+	ch := make(chan string, 100)
+
+	go func() {
+		defer close(ch)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "[DONE]" {
+				return
+			}
+
+			var streamResp openAICompatibleResponse
+			if err := json.Unmarshal([]byte(data), &streamResp); err != nil {
+				logrus.Warnf("parse stream data: %v", err)
+				continue
+			}
+
+			if streamResp.Error != nil {
+				logrus.Errorf("stream error: %s", streamResp.Error.Message)
+				return
+			}
+
+			if len(streamResp.Choices) > 0 && streamResp.Choices[0].Delta.Content != "" {
+				select {
+				case ch <- streamResp.Choices[0].Delta.Content:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			logrus.Errorf("stream scanner error: %v", err)
+		}
+	}()
+
+	return ch, nil
+}
+
+// 常见 OpenAI 兼容服务的默认向量模型
+var openAICompatibleEmbeddingModels = map[ProviderType]string{
+	ProviderOpenAI: "text-embedding-3-small",
+	ProviderQwen:   "text-embedding-v3",
+}
+
+// DefaultEmbeddingModel 返回默认向量模型，未配置时为空
+func (p *OpenAICompatibleProvider) DefaultEmbeddingModel() string {
+	return p.embeddingModel
+}
+
+// Embed 调用 /embeddings 接口向量化文本
+func (p *OpenAICompatibleProvider) Embed(ctx context.Context, opts *EmbedOptions) (*EmbedResult, error) {
+	model := opts.Model
+	if model == "" {
+		model = p.embeddingModel
+	}
+
+	headers := make(map[string]string, len(p.headers)+1)
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+	for k, v := range p.headers {
+		headers[k] = v
+	}
+	return openAIEmbed(ctx, p.client, p.name, p.baseURL+"/embeddings", headers, model, opts.Input)
+}
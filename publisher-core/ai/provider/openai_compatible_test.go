@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAICompatibleGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("X-Gateway-Key"); got != "secret" {
+			t.Errorf("Expected custom header, got %q", got)
+		}
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("Expected no Authorization header without API key, got %q", got)
+		}
+
+		var req openAICompatibleRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "qwen2.5-7b" {
+			t.Errorf("Expected default model, got %s", req.Model)
+		}
+
+		fmt.Fprint(w, `{"model":"qwen2.5-7b","choices":[{"message":{"content":"hello"}}],"usage":{"prompt_tokens":3,"completion_tokens":1}}`)
+	}))
+	defer server.Close()
+
+	p, err := NewOpenAICompatibleProvider(&OpenAICompatibleConfig{
+		Name:    ProviderVLLM,
+		BaseURL: server.URL + "/v1/",
+		Headers: map[string]string{"X-Gateway-Key": "secret"},
+		Models:  []string{"qwen2.5-7b", "qwen2.5-72b"},
+	})
+	if err != nil {
+		t.Fatalf("NewOpenAICompatibleProvider failed: %v", err)
+	}
+
+	result, err := p.Generate(context.Background(), &GenerateOptions{Messages: []Message{{Role: RoleUser, Content: "hi"}}})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if result.Content != "hello" || result.Provider != string(ProviderVLLM) || result.InputTokens != 3 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestOpenAICompatibleStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"he\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"llo\"}}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p, _ := NewOpenAICompatibleProvider(&OpenAICompatibleConfig{BaseURL: server.URL, DefaultModel: "local"})
+
+	ch, err := p.GenerateStream(context.Background(), &GenerateOptions{Messages: []Message{{Role: RoleUser, Content: "hi"}}})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+
+	var content string
+	for chunk := range ch {
+		content += chunk
+	}
+	if content != "hello" {
+		t.Errorf("Expected streamed content hello, got %q", content)
+	}
+}
+
+func TestOpenAICompatibleRequiresBaseURL(t *testing.T) {
+	if _, err := NewOpenAICompatibleProvider(&OpenAICompatibleConfig{}); err == nil {
+		t.Error("Expected error without base URL")
+	}
+
+	p, err := NewOpenAICompatibleProvider(&OpenAICompatibleConfig{Name: ProviderQwen, DefaultModel: "qwen-plus"})
+	if err != nil {
+		t.Fatalf("Expected known provider to use default base URL: %v", err)
+	}
+	if p.baseURL != DefaultOpenAICompatibleBaseURL(ProviderQwen) {
+		t.Errorf("Unexpected base URL %s", p.baseURL)
+	}
+}
+
+func TestOpenAICompatibleEmbed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		var req openAIEmbeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "bge-m3" || len(req.Input) != 2 {
+			t.Errorf("Unexpected request: %+v", req)
+		}
+		// 返回顺序与输入不同，按 index 还原
+		fmt.Fprint(w, `{"model":"bge-m3","data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}],"usage":{"prompt_tokens":4}}`)
+	}))
+	defer server.Close()
+
+	p, err := NewOpenAICompatibleProvider(&OpenAICompatibleConfig{
+		Name:           ProviderVLLM,
+		BaseURL:        server.URL + "/v1",
+		Models:         []string{"qwen2.5-7b"},
+		EmbeddingModel: "bge-m3",
+	})
+	if err != nil {
+		t.Fatalf("NewOpenAICompatibleProvider failed: %v", err)
+	}
+
+	e, ok := AsEmbedder(p)
+	if !ok {
+		t.Fatal("Expected provider to support embeddings")
+	}
+	result, err := e.Embed(context.Background(), &EmbedOptions{Input: []string{"a", "b"}})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(result.Vectors) != 2 || result.Vectors[0][0] != 1 || result.Vectors[1][1] != 1 || result.InputTokens != 4 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
@@ -27,14 +27,19 @@ type openRouterRequest struct {
 	MaxTokens   int       `json:"max_tokens,omitempty"`
 	Temperature float64   `json:"temperature,omitempty"`
 	Stream      bool      `json:"stream,omitempty"`
+	// ResponseFormat OpenAI 风格的 response_format 参数
+	ResponseFormat interface{}  `json:"response_format,omitempty"`
+	Tools          []openAITool `json:"tools,omitempty"`
+	ToolChoice     interface{}  `json:"tool_choice,omitempty"`
 }
 
 type openRouterResponse struct {
 	ID      string `json:"id"`
 	Choices []struct {
 		Message struct {
-			Content string `json:"content"`
-			Role    string `json:"role"`
+			Content   string     `json:"content"`
+			Role      string     `json:"role"`
+			ToolCalls []ToolCall `json:"tool_calls"`
 		} `json:"message"`
 		Delta struct {
 			Content string `json:"content"`
@@ -87,6 +92,9 @@ func (p *OpenRouterProvider) Generate(ctx context.Context, opts *GenerateOptions
 		Messages: opts.Messages,
 		Stream:   false,
 	}
+	req.Tools = openAITools(opts.Tools)
+	req.ToolChoice = openAIToolChoice(opts)
+	req.ResponseFormat = openAIResponseFormat(opts.ResponseFormat, true)
 
 	if opts.MaxTokens > 0 {
 		req.MaxTokens = opts.MaxTokens
@@ -119,7 +127,7 @@ func (p *OpenRouterProvider) Generate(ctx context.Context, opts *GenerateOptions
 
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error: %s - %s", resp.Status, string(respBody))
+		return nil, NewAPIError(p.Name(), resp, string(respBody))
 	}
 
 	var result openRouterResponse
@@ -138,6 +146,7 @@ func (p *OpenRouterProvider) Generate(ctx context.Context, opts *GenerateOptions
 		InputTokens:  result.Usage.PromptTokens,
 		OutputTokens: result.Usage.CompletionTokens,
 		FinishedAt:   time.Now(),
+		ToolCalls:    normalizeToolCalls(result.Choices[0].Message.ToolCalls),
 	}, nil
 }
 
@@ -181,7 +190,7 @@ func (p *OpenRouterProvider) GenerateStream(ctx context.Context, opts *GenerateO
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("API error: %s - %s", resp.Status, string(respBody))
+		return nil, NewAPIError(p.Name(), resp, string(respBody))
 	}
 
 	ch := make(chan string, 100)
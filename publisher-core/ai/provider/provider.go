@@ -13,6 +13,16 @@ const (
 	ProviderGroq       ProviderType = "groq"
 	ProviderDeepSeek   ProviderType = "deepseek"
 	ProviderOllama     ProviderType = "ollama"
+	ProviderMistral    ProviderType = "mistral"
+	ProviderNVIDIA     ProviderType = "nvidia"
+
+	// OpenAI 兼容接口的提供商
+	ProviderOpenAICompatible ProviderType = "openai_compatible"
+	ProviderOpenAI           ProviderType = "openai"
+	ProviderQwen             ProviderType = "qwen"
+	ProviderDoubao           ProviderType = "doubao"
+	ProviderVLLM             ProviderType = "vllm"
+	ProviderLMStudio         ProviderType = "lmstudio"
 )
 
 type Role string
@@ -21,11 +31,20 @@ const (
 	RoleSystem    Role = "system"
 	RoleUser      Role = "user"
 	RoleAssistant Role = "assistant"
+	RoleTool      Role = "tool"
 )
 
 type Message struct {
 	Role    Role   `json:"role"`
 	Content string `json:"content"`
+	// ToolCalls 助手消息中模型发起的工具调用
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID 工具结果消息对应的调用ID
+	ToolCallID string `json:"tool_call_id,omitempty"`
+	// Name 工具结果消息对应的工具名称
+	Name string `json:"name,omitempty"`
+	// Images 随消息发送的图片，仅支持视觉模型的提供商使用
+	Images []ImagePart `json:"-"`
 }
 
 type GenerateOptions struct {
@@ -35,6 +54,12 @@ type GenerateOptions struct {
 	Temperature float64   `json:"temperature,omitempty"`
 	TopP        float64   `json:"top_p,omitempty"`
 	Stop        []string  `json:"stop,omitempty"`
+	// ResponseFormat 结构化输出要求，为空时按普通文本生成
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	// Tools 允许模型调用的工具
+	Tools []ToolDefinition `json:"tools,omitempty"`
+	// ToolChoice 工具调用策略：auto、none、required，为空时由提供商决定
+	ToolChoice string `json:"tool_choice,omitempty"`
 }
 
 type GenerateResult struct {
@@ -44,6 +69,8 @@ type GenerateResult struct {
 	InputTokens  int       `json:"input_tokens"`
 	OutputTokens int       `json:"output_tokens"`
 	FinishedAt   time.Time `json:"finished_at"`
+	// ToolCalls 模型要求执行的工具调用，非空时 Content 可能为空
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
 }
 
 type Provider interface {
@@ -109,8 +136,17 @@ type HotspotAnalysis struct {
 }
 
 type AuditResult struct {
-	Passed      bool     `json:"passed"`
-	Issues      []string `json:"issues,omitempty"`
-	Suggestions []string `json:"suggestions,omitempty"`
-	Score       int      `json:"score"`
+	Passed      bool           `json:"passed"`
+	Issues      []string       `json:"issues,omitempty"`
+	Suggestions []string       `json:"suggestions,omitempty"`
+	Score       int            `json:"score"`
+	Findings    []AuditFinding `json:"findings,omitempty"`
+}
+
+// AuditFinding 审核发现的具体问题，Text 为原文中有问题的片段
+type AuditFinding struct {
+	Text       string `json:"text"`
+	Reason     string `json:"reason"`
+	Severity   string `json:"severity"`
+	Suggestion string `json:"suggestion,omitempty"`
 }
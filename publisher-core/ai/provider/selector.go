@@ -26,6 +26,8 @@ type ProviderStats struct {
 	QualityScore   float64       `json:"quality_score"`
 	Priority       int           `json:"priority"`
 	IsHealthy      bool          `json:"is_healthy"`
+	Failures       int           `json:"failures"`   // 连续失败次数
+	OpenUntil      time.Time     `json:"open_until"` // 熔断截止时间
 }
 
 // NewProviderSelector 创建提供商选择器
@@ -0,0 +1,91 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// StreamEventType 流式生成事件类型
+type StreamEventType string
+
+const (
+	// StreamEventStart 流已建立，携带提供商和模型
+	StreamEventStart StreamEventType = "start"
+	// StreamEventDelta 新生成的文本片段
+	StreamEventDelta StreamEventType = "delta"
+	// StreamEventDone 生成结束，携带完整用量
+	StreamEventDone StreamEventType = "done"
+	// StreamEventError 生成中断
+	StreamEventError StreamEventType = "error"
+)
+
+// StreamEvent 流式生成事件
+type StreamEvent struct {
+	Type         StreamEventType `json:"type"`
+	Content      string          `json:"content,omitempty"`
+	Provider     string          `json:"provider,omitempty"`
+	Model        string          `json:"model,omitempty"`
+	InputTokens  int             `json:"input_tokens,omitempty"`
+	OutputTokens int             `json:"output_tokens,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// StreamEvents 将文本流包装为事件流
+// 依次发送 start、若干 delta，结束时发送 done；ctx 取消后停止转发并尽量发送 error。
+// 流式接口不返回用量，token 数按模型分词器估算。onDone 在上游结束后以完整结果调用，可为 nil
+func StreamEvents(ctx context.Context, chunks <-chan string, providerName, model string, opts *GenerateOptions, onDone func(result *GenerateResult, err error)) <-chan StreamEvent {
+	events := make(chan StreamEvent, 16)
+
+	go func() {
+		defer close(events)
+
+		send := func(event StreamEvent) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		}
+
+		send(StreamEvent{Type: StreamEventStart, Provider: providerName, Model: model})
+
+		var content strings.Builder
+		for chunk := range chunks {
+			content.WriteString(chunk)
+			if ctx.Err() == nil {
+				send(StreamEvent{Type: StreamEventDelta, Content: chunk})
+			}
+		}
+
+		result := &GenerateResult{
+			Content:      content.String(),
+			Model:        model,
+			Provider:     providerName,
+			InputTokens:  CountMessageTokens(ProviderType(providerName), model, opts.Messages),
+			OutputTokens: CountTokens(ProviderType(providerName), model, content.String()),
+			FinishedAt:   time.Now(),
+		}
+		err := ctx.Err()
+		if onDone != nil {
+			onDone(result, err)
+		}
+
+		if err != nil {
+			// 调用方多半已离开，不阻塞
+			select {
+			case events <- StreamEvent{Type: StreamEventError, Error: err.Error()}:
+			default:
+			}
+			return
+		}
+		send(StreamEvent{
+			Type:         StreamEventDone,
+			Provider:     providerName,
+			Model:        model,
+			InputTokens:  result.InputTokens,
+			OutputTokens: result.OutputTokens,
+		})
+	}()
+
+	return events
+}
@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStreamEvents(t *testing.T) {
+	chunks := make(chan string, 2)
+	chunks <- "你好"
+	chunks <- "世界"
+	close(chunks)
+
+	var recorded *GenerateResult
+	events := StreamEvents(context.Background(), chunks, "deepseek", "deepseek-chat", &GenerateOptions{
+		Messages: []Message{{Role: RoleUser, Content: "hello world!"}},
+	}, func(result *GenerateResult, err error) {
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+		recorded = result
+	})
+
+	var got []StreamEvent
+	for event := range events {
+		got = append(got, event)
+	}
+
+	if len(got) != 4 || got[0].Type != StreamEventStart || got[0].Model != "deepseek-chat" {
+		t.Fatalf("Unexpected events: %+v", got)
+	}
+	if got[1].Content != "你好" || got[2].Content != "世界" {
+		t.Errorf("Unexpected deltas: %+v", got[1:3])
+	}
+	done := got[3]
+	if done.Type != StreamEventDone || done.Provider != "deepseek" || done.InputTokens != 12 || done.OutputTokens != 3 {
+		t.Errorf("Unexpected done event: %+v", done)
+	}
+	if recorded == nil || recorded.Content != "你好世界" {
+		t.Errorf("Expected full result in onDone, got %+v", recorded)
+	}
+}
+
+func TestStreamEventsCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	chunks := make(chan string)
+
+	var doneErr error
+	finished := make(chan struct{})
+	events := StreamEvents(ctx, chunks, "p", "m", &GenerateOptions{}, func(result *GenerateResult, err error) {
+		doneErr = err
+		close(finished)
+	})
+
+	if first := <-events; first.Type != StreamEventStart {
+		t.Fatalf("Expected start event, got %+v", first)
+	}
+	cancel()
+	// 上游在取消后仍会被读完
+	chunks <- "partial"
+	close(chunks)
+	<-finished
+
+	if doneErr != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", doneErr)
+	}
+	for event := range events {
+		if event.Type == StreamEventDone {
+			t.Error("Canceled stream should not report done")
+		}
+	}
+}
@@ -0,0 +1,572 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResponseFormatType 输出格式类型
+type ResponseFormatType string
+
+const (
+	ResponseFormatText       ResponseFormatType = "text"
+	ResponseFormatJSONObject ResponseFormatType = "json_object"
+	ResponseFormatJSONSchema ResponseFormatType = "json_schema"
+)
+
+// DefaultMaxRepairs 结构化输出校验失败时默认的重新提示次数
+const DefaultMaxRepairs = 2
+
+// ResponseFormat 结构化输出要求
+// 支持原生 JSON 模式的提供商会在请求中声明该格式，其余提供商仅依赖提示词和校验
+type ResponseFormat struct {
+	Type ResponseFormatType `json:"type"`
+	// Name schema 名称，部分提供商要求必填
+	Name   string      `json:"name,omitempty"`
+	Schema *JSONSchema `json:"schema,omitempty"`
+	// MaxRepairs 校验失败后携带错误信息重新提示的最大次数，0 时使用 DefaultMaxRepairs，负数表示不重试
+	MaxRepairs int `json:"max_repairs,omitempty"`
+}
+
+// JSONSchema JSON Schema 的常用子集
+type JSONSchema struct {
+	Type        string                 `json:"type,omitempty"`
+	Description string                 `json:"description,omitempty"`
+	Properties  map[string]*JSONSchema `json:"properties,omitempty"`
+	Required    []string               `json:"required,omitempty"`
+	// AdditionalProperties 为 bool 或 *JSONSchema
+	AdditionalProperties interface{}   `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema   `json:"items,omitempty"`
+	Enum                 []interface{} `json:"enum,omitempty"`
+	Minimum              *float64      `json:"minimum,omitempty"`
+	Maximum              *float64      `json:"maximum,omitempty"`
+	MinLength            *int          `json:"minLength,omitempty"`
+	MaxLength            *int          `json:"maxLength,omitempty"`
+	MinItems             *int          `json:"minItems,omitempty"`
+	MaxItems             *int          `json:"maxItems,omitempty"`
+}
+
+// Generator 可生成文本的对象，Provider、ai.Service 和 UnifiedService 均满足
+type Generator interface {
+	Generate(ctx context.Context, opts *GenerateOptions) (*GenerateResult, error)
+}
+
+// StructuredOutputError 多次重新提示后输出仍未通过校验
+type StructuredOutputError struct {
+	Attempts int
+	// Errors 最后一次输出的校验错误
+	Errors []string
+	// Content 最后一次输出的原始内容
+	Content string
+}
+
+func (e *StructuredOutputError) Error() string {
+	return fmt.Sprintf("structured output invalid after %d attempts: %s", e.Attempts, strings.Join(e.Errors, "; "))
+}
+
+// JSONSchemaFormat 创建带 schema 的输出格式
+func JSONSchemaFormat(name string, schema *JSONSchema) *ResponseFormat {
+	return &ResponseFormat{Type: ResponseFormatJSONSchema, Name: name, Schema: schema}
+}
+
+// ResponseFormatFor 根据 Go 结构体推导输出格式
+func ResponseFormatFor(name string, v interface{}) (*ResponseFormat, error) {
+	schema, err := SchemaFromStruct(v)
+	if err != nil {
+		return nil, err
+	}
+	return JSONSchemaFormat(name, schema), nil
+}
+
+// SchemaFromStruct 根据 Go 类型推导 JSON Schema
+// 字段名取 json 标签，未标记 omitempty 的字段为必填；
+// jsonschema 标签支持 enum=a|b、minimum、maximum、minLength、maxLength、minItems、maxItems、required，
+// description 标签作为字段说明
+func SchemaFromStruct(v interface{}) (*JSONSchema, error) {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return nil, fmt.Errorf("cannot derive schema from nil")
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return schemaForType(t, map[reflect.Type]bool{})
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func schemaForType(t reflect.Type, visiting map[reflect.Type]bool) (*JSONSchema, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &JSONSchema{Type: "string", Description: "RFC3339 时间"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &JSONSchema{Type: "string"}, nil
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}, nil
+	case reflect.Interface:
+		return &JSONSchema{}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := schemaForType(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return &JSONSchema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		values, err := schemaForType(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return &JSONSchema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Struct:
+		if visiting[t] {
+			// 递归类型不再展开
+			return &JSONSchema{Type: "object"}, nil
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		schema := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema)}
+		if err := addStructFields(schema, t, visiting); err != nil {
+			return nil, err
+		}
+		sort.Strings(schema.Required)
+		return schema, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", t)
+	}
+}
+
+func addStructFields(schema *JSONSchema, t reflect.Type, visiting map[reflect.Type]bool) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name, omitEmpty, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+
+		// 匿名嵌入且未命名的结构体字段展开到外层
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := addStructFields(schema, ft, visiting); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+
+		fieldSchema, err := schemaForType(field.Type, visiting)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if desc := field.Tag.Get("description"); desc != "" {
+			fieldSchema.Description = desc
+		}
+
+		required := !omitEmpty
+		if tag := field.Tag.Get("jsonschema"); tag != "" {
+			if required, err = applySchemaTag(fieldSchema, tag, required); err != nil {
+				return fmt.Errorf("field %s: %w", field.Name, err)
+			}
+		}
+
+		schema.Properties[name] = fieldSchema
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return nil
+}
+
+func jsonFieldName(field reflect.StructField) (name string, omitEmpty, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
+
+// applySchemaTag 解析 jsonschema 标签，返回字段是否必填
+func applySchemaTag(schema *JSONSchema, tag string, required bool) (bool, error) {
+	for _, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "required":
+			required = true
+		case "optional":
+			required = false
+		case "enum":
+			for _, item := range strings.Split(value, "|") {
+				schema.Enum = append(schema.Enum, item)
+			}
+		case "minimum", "maximum":
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return required, fmt.Errorf("invalid %s %q", key, value)
+			}
+			if key == "minimum" {
+				schema.Minimum = &f
+			} else {
+				schema.Maximum = &f
+			}
+		case "minLength", "maxLength", "minItems", "maxItems":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return required, fmt.Errorf("invalid %s %q", key, value)
+			}
+			switch key {
+			case "minLength":
+				schema.MinLength = &n
+			case "maxLength":
+				schema.MaxLength = &n
+			case "minItems":
+				schema.MinItems = &n
+			default:
+				schema.MaxItems = &n
+			}
+		default:
+			return required, fmt.Errorf("unknown jsonschema option %q", key)
+		}
+	}
+	return required, nil
+}
+
+// ValidateJSON 按 schema 校验 JSON 数据，返回全部校验错误
+func ValidateJSON(schema *JSONSchema, data []byte) []string {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return []string{fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	if schema == nil {
+		return nil
+	}
+	return schema.validate("$", value, nil)
+}
+
+func (s *JSONSchema) validate(path string, value interface{}, errs []string) []string {
+	if s.Type != "" && !matchesType(s.Type, value) {
+		return append(errs, fmt.Sprintf("%s: expected %s, got %s", path, s.Type, jsonTypeName(value)))
+	}
+
+	if len(s.Enum) > 0 && !enumContains(s.Enum, value) {
+		errs = append(errs, fmt.Sprintf("%s: must be one of %s", path, formatEnum(s.Enum)))
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			errs = append(errs, fmt.Sprintf("%s: must be >= %g", path, *s.Minimum))
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			errs = append(errs, fmt.Sprintf("%s: must be <= %g", path, *s.Maximum))
+		}
+	case string:
+		length := len([]rune(v))
+		if s.MinLength != nil && length < *s.MinLength {
+			errs = append(errs, fmt.Sprintf("%s: length must be >= %d", path, *s.MinLength))
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			errs = append(errs, fmt.Sprintf("%s: length must be <= %d", path, *s.MaxLength))
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			errs = append(errs, fmt.Sprintf("%s: must have at least %d items", path, *s.MinItems))
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			errs = append(errs, fmt.Sprintf("%s: must have at most %d items", path, *s.MaxItems))
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				errs = s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				errs = append(errs, fmt.Sprintf("%s: missing required field %q", path, name))
+			}
+		}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			childPath := path + "." + key
+			if prop, ok := s.Properties[key]; ok {
+				errs = prop.validate(childPath, v[key], errs)
+				continue
+			}
+			switch extra := s.AdditionalProperties.(type) {
+			case bool:
+				if !extra {
+					errs = append(errs, fmt.Sprintf("%s: unexpected field", childPath))
+				}
+			case *JSONSchema:
+				errs = extra.validate(childPath, v[key], errs)
+			}
+		}
+	}
+
+	return errs
+}
+
+func matchesType(typ string, value interface{}) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func enumContains(enum []interface{}, value interface{}) bool {
+	for _, item := range enum {
+		if fmt.Sprint(item) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(enum []interface{}) string {
+	items := make([]string, len(enum))
+	for i, item := range enum {
+		items[i] = fmt.Sprint(item)
+	}
+	return "[" + strings.Join(items, ", ") + "]"
+}
+
+// ExtractJSON 从模型输出中提取 JSON 文本
+// 去除 Markdown 代码块标记，并截取首个 { 或 [ 到与之对应的结尾
+func ExtractJSON(content string) string {
+	content = strings.TrimSpace(content)
+
+	if start := strings.Index(content, "```"); start >= 0 {
+		rest := content[start+3:]
+		if nl := strings.Index(rest, "\n"); nl >= 0 {
+			rest = rest[nl+1:]
+		}
+		if end := strings.Index(rest, "```"); end >= 0 {
+			rest = rest[:end]
+		}
+		content = strings.TrimSpace(rest)
+	}
+
+	start := strings.IndexAny(content, "{[")
+	if start < 0 {
+		return content
+	}
+	closing := byte('}')
+	if content[start] == '[' {
+		closing = ']'
+	}
+	end := strings.LastIndexByte(content, closing)
+	if end < start {
+		return content[start:]
+	}
+	return content[start : end+1]
+}
+
+// structuredInstruction 生成要求模型按 schema 输出的提示
+func structuredInstruction(format *ResponseFormat) string {
+	if format.Schema == nil {
+		return "请只输出一个合法的 JSON 对象，不要包含任何额外说明或 Markdown 标记。"
+	}
+	schema, _ := json.MarshalIndent(format.Schema, "", "  ")
+	return fmt.Sprintf("请只输出一个符合以下 JSON Schema 的 JSON，不要包含任何额外说明或 Markdown 标记：\n%s", schema)
+}
+
+// repairInstruction 生成携带校验错误的重新提示
+func repairInstruction(errs []string) string {
+	return fmt.Sprintf("上面的输出未通过 JSON 校验：\n- %s\n请修正以上问题，只输出完整的 JSON。", strings.Join(errs, "\n- "))
+}
+
+// GenerateStructured 生成结构化输出并解析到 out
+// opts.ResponseFormat 为空时根据 out 的类型推导 schema；
+// 输出未通过校验时携带错误信息重新提示，超过次数后返回 *StructuredOutputError。
+// 返回的结果中 token 用量为所有尝试之和
+func GenerateStructured(ctx context.Context, gen Generator, opts *GenerateOptions, out interface{}) (*GenerateResult, error) {
+	format := opts.ResponseFormat
+	if format == nil || format.Type == "" || format.Type == ResponseFormatText {
+		derived, err := ResponseFormatFor("response", out)
+		if err != nil {
+			return nil, fmt.Errorf("derive schema: %w", err)
+		}
+		format = derived
+	}
+	if format.Type == ResponseFormatJSONSchema && format.Schema == nil {
+		schema, err := SchemaFromStruct(out)
+		if err != nil {
+			return nil, fmt.Errorf("derive schema: %w", err)
+		}
+		copied := *format
+		copied.Schema = schema
+		format = &copied
+	}
+
+	maxRepairs := format.MaxRepairs
+	if maxRepairs == 0 {
+		maxRepairs = DefaultMaxRepairs
+	}
+	if maxRepairs < 0 {
+		maxRepairs = 0
+	}
+
+	req := *opts
+	req.ResponseFormat = format
+	req.Messages = make([]Message, len(opts.Messages), len(opts.Messages)+2*maxRepairs+1)
+	copy(req.Messages, opts.Messages)
+
+	instruction := structuredInstruction(format)
+	if n := len(req.Messages); n > 0 && req.Messages[n-1].Role == RoleUser {
+		req.Messages[n-1].Content += "\n\n" + instruction
+	} else {
+		req.Messages = append(req.Messages, Message{Role: RoleUser, Content: instruction})
+	}
+
+	var inputTokens, outputTokens int
+	var errs []string
+	var content string
+
+	for attempt := 0; attempt <= maxRepairs; attempt++ {
+		result, err := gen.Generate(ctx, &req)
+		if err != nil {
+			return nil, err
+		}
+		inputTokens += result.InputTokens
+		outputTokens += result.OutputTokens
+		content = result.Content
+
+		data := ExtractJSON(content)
+		errs = ValidateJSON(format.Schema, []byte(data))
+		if len(errs) == 0 {
+			if err := json.Unmarshal([]byte(data), out); err != nil {
+				errs = []string{fmt.Sprintf("decode: %v", err)}
+			}
+		}
+		if len(errs) == 0 {
+			result.InputTokens = inputTokens
+			result.OutputTokens = outputTokens
+			return result, nil
+		}
+
+		req.Messages = append(req.Messages,
+			Message{Role: RoleAssistant, Content: content},
+			Message{Role: RoleUser, Content: repairInstruction(errs)},
+		)
+	}
+
+	return nil, &StructuredOutputError{
+		Attempts: maxRepairs + 1,
+		Errors:   errs,
+		Content:  content,
+	}
+}
+
+// openAIResponseFormat 转换为 OpenAI 风格的 response_format 参数
+// supportsSchema 为 false 时降级为 json_object
+func openAIResponseFormat(format *ResponseFormat, supportsSchema bool) interface{} {
+	if format == nil || format.Type == "" || format.Type == ResponseFormatText {
+		return nil
+	}
+	if format.Type == ResponseFormatJSONSchema && supportsSchema && format.Schema != nil {
+		name := format.Name
+		if name == "" {
+			name = "response"
+		}
+		return map[string]interface{}{
+			"type": "json_schema",
+			"json_schema": map[string]interface{}{
+				"name":   name,
+				"schema": format.Schema,
+			},
+		}
+	}
+	return map[string]interface{}{"type": "json_object"}
+}
+
+// wantsJSON 判断是否要求 JSON 输出
+func wantsJSON(format *ResponseFormat) bool {
+	return format != nil && format.Type != "" && format.Type != ResponseFormatText
+}
@@ -0,0 +1,200 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testAnalysis struct {
+	Summary   string              `json:"summary"`
+	Score     int                 `json:"score" jsonschema:"minimum=0,maximum=100"`
+	Sentiment string              `json:"sentiment" jsonschema:"enum=positive|neutral|negative"`
+	Tags      []string            `json:"tags,omitempty"`
+	Groups    map[string][]string `json:"groups,omitempty"`
+}
+
+type scriptedGenerator struct {
+	replies []string
+	calls   []*GenerateOptions
+}
+
+func (g *scriptedGenerator) Generate(ctx context.Context, opts *GenerateOptions) (*GenerateResult, error) {
+	copied := *opts
+	copied.Messages = append([]Message(nil), opts.Messages...)
+	g.calls = append(g.calls, &copied)
+
+	reply := g.replies[len(g.calls)-1]
+	return &GenerateResult{Content: reply, InputTokens: 10, OutputTokens: 5}, nil
+}
+
+func TestSchemaFromStruct(t *testing.T) {
+	schema, err := SchemaFromStruct(&testAnalysis{})
+	if err != nil {
+		t.Fatalf("SchemaFromStruct failed: %v", err)
+	}
+
+	if schema.Type != "object" {
+		t.Fatalf("Expected object schema, got %s", schema.Type)
+	}
+	if strings.Join(schema.Required, ",") != "score,sentiment,summary" {
+		t.Errorf("Unexpected required fields: %v", schema.Required)
+	}
+	if s := schema.Properties["score"]; s.Type != "integer" || *s.Minimum != 0 || *s.Maximum != 100 {
+		t.Errorf("Unexpected score schema: %+v", s)
+	}
+	if s := schema.Properties["sentiment"]; len(s.Enum) != 3 {
+		t.Errorf("Expected sentiment enum, got %+v", s)
+	}
+	groups := schema.Properties["groups"]
+	if values, ok := groups.AdditionalProperties.(*JSONSchema); !ok || values.Type != "array" {
+		t.Errorf("Expected map schema with array values, got %+v", groups)
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	schema, _ := SchemaFromStruct(testAnalysis{})
+
+	errs := ValidateJSON(schema, []byte(`{"summary":"ok","score":120.5,"sentiment":"angry","groups":{"a":"b"}}`))
+	want := []string{
+		"$.score: expected integer",
+		"$.sentiment: must be one of",
+		"$.groups.a: expected array",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Expected %d errors, got %v", len(want), errs)
+	}
+	for _, w := range want {
+		found := false
+		for _, e := range errs {
+			if strings.HasPrefix(e, w) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected error %q in %v", w, errs)
+		}
+	}
+
+	if errs := ValidateJSON(schema, []byte(`{"summary":"ok","score":80,"sentiment":"neutral"}`)); len(errs) != 0 {
+		t.Errorf("Expected valid document, got %v", errs)
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	cases := map[string]string{
+		"```json\n{\"a\":1}\n```":    `{"a":1}`,
+		"结果如下：{\"a\":{\"b\":2}} 以上。": `{"a":{"b":2}}`,
+		"[1,2,3]": `[1,2,3]`,
+	}
+	for input, want := range cases {
+		if got := ExtractJSON(input); got != want {
+			t.Errorf("ExtractJSON(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestGenerateStructuredRepairs(t *testing.T) {
+	gen := &scriptedGenerator{replies: []string{
+		"这是分析结果",
+		`{"summary":"ok","score":150,"sentiment":"neutral"}`,
+		"```json\n{\"summary\":\"ok\",\"score\":90,\"sentiment\":\"positive\"}\n```",
+	}}
+
+	opts := &GenerateOptions{Messages: []Message{{Role: RoleUser, Content: "分析"}}}
+	var out testAnalysis
+	result, err := GenerateStructured(context.Background(), gen, opts, &out)
+	if err != nil {
+		t.Fatalf("GenerateStructured failed: %v", err)
+	}
+
+	if out.Score != 90 || out.Sentiment != "positive" {
+		t.Errorf("Unexpected output: %+v", out)
+	}
+	if result.InputTokens != 30 || result.OutputTokens != 15 {
+		t.Errorf("Expected token usage summed across attempts, got %d/%d", result.InputTokens, result.OutputTokens)
+	}
+	if len(opts.Messages) != 1 || opts.ResponseFormat != nil {
+		t.Error("Caller options should not be modified")
+	}
+
+	last := gen.calls[2]
+	if last.ResponseFormat == nil || last.ResponseFormat.Type != ResponseFormatJSONSchema {
+		t.Errorf("Expected derived json_schema format, got %+v", last.ResponseFormat)
+	}
+	if len(last.Messages) != 5 {
+		t.Fatalf("Expected conversation with two repair rounds, got %d messages", len(last.Messages))
+	}
+	if !strings.Contains(last.Messages[4].Content, "$.score: must be <= 100") {
+		t.Errorf("Expected repair prompt to contain validation errors, got %q", last.Messages[4].Content)
+	}
+}
+
+func TestGenerateStructuredGivesUp(t *testing.T) {
+	gen := &scriptedGenerator{replies: []string{"not json", "still not json"}}
+
+	opts := &GenerateOptions{
+		Messages:       []Message{{Role: RoleUser, Content: "分析"}},
+		ResponseFormat: &ResponseFormat{Type: ResponseFormatJSONSchema, MaxRepairs: 1},
+	}
+	var out testAnalysis
+	_, err := GenerateStructured(context.Background(), gen, opts, &out)
+
+	var structErr *StructuredOutputError
+	if !errors.As(err, &structErr) {
+		t.Fatalf("Expected StructuredOutputError, got %v", err)
+	}
+	if structErr.Attempts != 2 || structErr.Content != "still not json" {
+		t.Errorf("Unexpected error: %+v", structErr)
+	}
+	if len(gen.calls) != 2 {
+		t.Errorf("Expected 2 calls, got %d", len(gen.calls))
+	}
+}
+
+func TestOpenAICompatibleResponseFormat(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ResponseFormat struct {
+				Type       string `json:"type"`
+				JSONSchema struct {
+					Name   string      `json:"name"`
+					Schema *JSONSchema `json:"schema"`
+				} `json:"json_schema"`
+			} `json:"response_format"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.ResponseFormat.Type != "json_schema" || req.ResponseFormat.JSONSchema.Name != "analysis" {
+			t.Errorf("Unexpected response_format: %+v", req.ResponseFormat)
+		}
+		if req.ResponseFormat.JSONSchema.Schema == nil || req.ResponseFormat.JSONSchema.Schema.Properties["score"] == nil {
+			t.Error("Expected schema to be sent")
+		}
+
+		content, _ := json.Marshal(`{"summary":"ok","score":1,"sentiment":"neutral"}`)
+		fmt.Fprintf(w, `{"choices":[{"message":{"content":%s}}]}`, content)
+	}))
+	defer server.Close()
+
+	p, _ := NewOpenAICompatibleProvider(&OpenAICompatibleConfig{BaseURL: server.URL, DefaultModel: "m"})
+
+	format, err := ResponseFormatFor("analysis", testAnalysis{})
+	if err != nil {
+		t.Fatalf("ResponseFormatFor failed: %v", err)
+	}
+	var out testAnalysis
+	if _, err := GenerateStructured(context.Background(), p, &GenerateOptions{
+		Messages:       []Message{{Role: RoleUser, Content: "分析"}},
+		ResponseFormat: format,
+	}, &out); err != nil {
+		t.Fatalf("GenerateStructured failed: %v", err)
+	}
+	if out.Summary != "ok" {
+		t.Errorf("Unexpected output: %+v", out)
+	}
+}
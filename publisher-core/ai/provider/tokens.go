@@ -0,0 +1,327 @@
+package provider
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Tokenizer 按模型族的分词特征估算 token 数
+// 不同模型的词表对中文的压缩率差别很大，按族区分比统一按字符数估算准确得多
+type Tokenizer struct {
+	Name string
+	// TokensPerCJK 每个中日韩字符约占的 token 数
+	TokensPerCJK float64
+	// CharsPerToken 拉丁字母单词平均每 token 的字符数
+	CharsPerToken float64
+	// MessageOverhead 每条消息的角色和分隔符开销
+	MessageOverhead int
+	// ImageTokens 每张图片按此计入
+	ImageTokens int
+}
+
+// 各模型族的分词特征，数值取自对常见中英文语料的实测平均
+var tokenizers = map[string]*Tokenizer{
+	"cl100k":   {Name: "cl100k", TokensPerCJK: 1.0, CharsPerToken: 4.0, MessageOverhead: 4, ImageTokens: 765},
+	"o200k":    {Name: "o200k", TokensPerCJK: 0.7, CharsPerToken: 4.2, MessageOverhead: 4, ImageTokens: 765},
+	"deepseek": {Name: "deepseek", TokensPerCJK: 0.6, CharsPerToken: 3.8, MessageOverhead: 4, ImageTokens: 765},
+	"qwen":     {Name: "qwen", TokensPerCJK: 0.65, CharsPerToken: 3.8, MessageOverhead: 4, ImageTokens: 765},
+	"llama":    {Name: "llama", TokensPerCJK: 0.9, CharsPerToken: 4.0, MessageOverhead: 5, ImageTokens: 765},
+	"gemini":   {Name: "gemini", TokensPerCJK: 0.6, CharsPerToken: 4.0, MessageOverhead: 3, ImageTokens: 258},
+	"mistral":  {Name: "mistral", TokensPerCJK: 1.0, CharsPerToken: 3.5, MessageOverhead: 4, ImageTokens: 765},
+	"glm":      {Name: "glm", TokensPerCJK: 0.7, CharsPerToken: 3.8, MessageOverhead: 4, ImageTokens: 765},
+	// default 未知模型取偏保守的估计，宁可高估也不要超出上下文
+	"default": {Name: "default", TokensPerCJK: 1.0, CharsPerToken: 3.5, MessageOverhead: 4, ImageTokens: 765},
+}
+
+// replyPriming 回复前缀开销
+const replyPriming = 3
+
+// Count 估算文本的 token 数
+func (t *Tokenizer) Count(text string) int {
+	cjk := 0
+	tokens := 0
+	word := 0
+	digits := 0
+
+	flush := func() {
+		if word > 0 {
+			tokens += int(math.Ceil(float64(word) / t.CharsPerToken))
+			word = 0
+		}
+		if digits > 0 {
+			// 数字通常按最多 3 位一组切分
+			tokens += (digits + 2) / 3
+			digits = 0
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flush()
+			cjk++
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || r == '\''):
+			if digits > 0 {
+				flush()
+			}
+			word++
+		case unicode.IsDigit(r):
+			if word > 0 {
+				flush()
+			}
+			digits++
+		case unicode.IsSpace(r):
+			// 空格通常并入后一个单词
+			flush()
+		case unicode.IsLetter(r):
+			// 其他文字（如西里尔、阿拉伯字母）按字符数折算
+			word++
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+
+	return tokens + int(math.Ceil(float64(cjk)*t.TokensPerCJK))
+}
+
+// CountMessages 估算消息列表的 token 数，包含消息开销、工具调用和图片
+func (t *Tokenizer) CountMessages(messages []Message) int {
+	total := replyPriming
+	for _, msg := range messages {
+		total += t.MessageOverhead + t.Count(msg.Content)
+		for _, call := range msg.ToolCalls {
+			total += t.Count(call.Function.Name) + t.Count(call.Function.Arguments)
+		}
+		total += len(msg.Images) * t.ImageTokens
+	}
+	return total
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r) ||
+		(r >= 0x3000 && r <= 0x303F) || // 中日韩标点
+		(r >= 0xFF00 && r <= 0xFFEF) // 全角字符
+}
+
+// ModelInfo 模型的上下文窗口和分词信息
+type ModelInfo struct {
+	Model string `json:"model"`
+	// ContextWindow 输入与输出合计的最大 token 数
+	ContextWindow int `json:"context_window"`
+	// MaxOutput 单次最多生成的 token 数
+	MaxOutput int    `json:"max_output"`
+	Tokenizer string `json:"tokenizer"`
+}
+
+// modelCatalog 已知模型，按模型名前缀匹配，最长前缀优先
+var modelCatalog = []ModelInfo{
+	{"gpt-3.5-turbo", 16385, 4096, "cl100k"},
+	{"gpt-4", 8192, 8192, "cl100k"},
+	{"gpt-4-turbo", 128000, 4096, "cl100k"},
+	{"gpt-4o", 128000, 16384, "o200k"},
+	{"gpt-4.1", 1047576, 32768, "o200k"},
+	{"deepseek-chat", 65536, 8192, "deepseek"},
+	{"deepseek-coder", 16384, 4096, "deepseek"},
+	{"deepseek-reasoner", 65536, 32768, "deepseek"},
+	{"deepseek-r1", 163840, 32768, "deepseek"},
+	{"deepseek-r1-distill", 131072, 8192, "llama"},
+	{"deepseek-v3", 131072, 8192, "deepseek"},
+	{"gemini-pro", 32760, 8192, "gemini"},
+	{"gemini-1.5-flash", 1048576, 8192, "gemini"},
+	{"gemini-1.5-pro", 2097152, 8192, "gemini"},
+	{"gemini-2", 1048576, 8192, "gemini"},
+	{"gemini-2.5", 1048576, 65536, "gemini"},
+	{"gemini-3", 1048576, 65536, "gemini"},
+	{"gemma-7b", 8192, 2048, "gemini"},
+	{"gemma-3", 131072, 8192, "gemini"},
+	{"gemma3", 131072, 8192, "gemini"},
+	{"llama3", 8192, 2048, "llama"},
+	{"llama-3.1", 131072, 8192, "llama"},
+	{"llama-3.3", 131072, 32768, "llama"},
+	{"llama-4", 131072, 8192, "llama"},
+	{"qwen-turbo", 131072, 8192, "qwen"},
+	{"qwen-plus", 131072, 8192, "qwen"},
+	{"qwen-max", 32768, 8192, "qwen"},
+	{"qwen-long", 10000000, 8192, "qwen"},
+	{"qwen3", 32768, 8192, "qwen"},
+	{"qwen3-coder", 262144, 65536, "qwen"},
+	{"mistral-large", 131072, 8192, "mistral"},
+	{"mistral-medium", 131072, 8192, "mistral"},
+	{"mistral-small", 32768, 8192, "mistral"},
+	{"mistral-small-3.1", 131072, 8192, "mistral"},
+	{"open-mistral-7b", 32768, 8192, "mistral"},
+	{"open-mixtral-8x7b", 32768, 8192, "mistral"},
+	{"open-mixtral-8x22b", 65536, 8192, "mistral"},
+	{"mixtral-8x7b", 32768, 8192, "mistral"},
+	{"codestral", 262144, 8192, "mistral"},
+	{"nemotron-4-340b", 4096, 1024, "default"},
+	{"phi-3-medium-128k", 131072, 4096, "default"},
+	{"moonshot-v1-8k", 8192, 4096, "default"},
+	{"moonshot-v1-32k", 32768, 4096, "default"},
+	{"moonshot-v1-128k", 131072, 4096, "default"},
+	{"glm-4", 131072, 4096, "glm"},
+	{"doubao", 32768, 4096, "default"},
+	{"doubao-pro-128k", 131072, 4096, "default"},
+}
+
+// defaultModelInfo 未知模型的保守估计
+var defaultModelInfo = ModelInfo{ContextWindow: 8192, MaxOutput: 2048, Tokenizer: "default"}
+
+// providerTokenizers 未知模型按提供商推断分词器
+var providerTokenizers = map[ProviderType]string{
+	ProviderOpenAI:   "o200k",
+	ProviderDeepSeek: "deepseek",
+	ProviderGoogle:   "gemini",
+	ProviderMistral:  "mistral",
+	ProviderQwen:     "qwen",
+}
+
+// normalizeModel 去掉 vendor/ 前缀和 :tag 后缀，如 deepseek/deepseek-r1-0528:free → deepseek-r1-0528
+func normalizeModel(model string) string {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	if i := strings.Index(model, ":"); i >= 0 {
+		model = model[:i]
+	}
+	return model
+}
+
+// LookupModel 返回模型的上下文信息，未知模型返回保守的默认值
+func LookupModel(pt ProviderType, model string) ModelInfo {
+	name := normalizeModel(model)
+
+	best := -1
+	for i, info := range modelCatalog {
+		if strings.HasPrefix(name, info.Model) && (best < 0 || len(info.Model) > len(modelCatalog[best].Model)) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		info := modelCatalog[best]
+		info.Model = model
+		return info
+	}
+
+	info := defaultModelInfo
+	info.Model = model
+	if tokenizer, ok := providerTokenizers[pt]; ok {
+		info.Tokenizer = tokenizer
+	}
+	return info
+}
+
+// TokenizerFor 返回模型使用的分词器
+func TokenizerFor(pt ProviderType, model string) *Tokenizer {
+	if t, ok := tokenizers[LookupModel(pt, model).Tokenizer]; ok {
+		return t
+	}
+	return tokenizers["default"]
+}
+
+// CountTokens 按模型估算文本的 token 数，provider 和 model 均可为空
+func CountTokens(pt ProviderType, model, text string) int {
+	return TokenizerFor(pt, model).Count(text)
+}
+
+// CountMessageTokens 按模型估算消息列表的 token 数
+func CountMessageTokens(pt ProviderType, model string, messages []Message) int {
+	return TokenizerFor(pt, model).CountMessages(messages)
+}
+
+// InputBudget 返回给定输出预留下允许的最大输入 token 数
+// maxTokens 为 0 时预留模型最大输出与 4096 中的较小者
+func (m ModelInfo) InputBudget(maxTokens int) int {
+	reserve := maxTokens
+	if reserve <= 0 {
+		reserve = m.MaxOutput
+		if reserve > 4096 {
+			reserve = 4096
+		}
+	}
+	return m.ContextWindow - reserve
+}
+
+// PickModel 从候选模型中选出能容纳消息的上下文最小的模型
+// 各模型分词器不同，按每个候选模型分别估算
+func PickModel(pt ProviderType, candidates []string, messages []Message, maxTokens int) (string, bool) {
+	best, bestWindow := "", 0
+	for _, model := range candidates {
+		info := LookupModel(pt, model)
+		if CountMessageTokens(pt, model, messages) > info.InputBudget(maxTokens) {
+			continue
+		}
+		if best == "" || info.ContextWindow < bestWindow {
+			best, bestWindow = model, info.ContextWindow
+		}
+	}
+	return best, best != ""
+}
+
+// truncationMarker 截断处插入的标记
+const truncationMarker = "\n…（内容过长，已截断）…\n"
+
+// FitMessages 将消息裁剪到 limit 个 token 以内
+// 保留系统消息和最后一条消息，先丢弃最早的对话轮次，仍超出时截去最长消息的中间部分。
+// 无法裁剪到限制以内时返回 false
+func FitMessages(messages []Message, tokenizer *Tokenizer, limit int) ([]Message, bool) {
+	if tokenizer.CountMessages(messages) <= limit {
+		return messages, true
+	}
+
+	result := make([]Message, len(messages))
+	copy(result, messages)
+
+	// 丢弃最早的非系统消息，保留最后一条
+	for len(result) > 1 && tokenizer.CountMessages(result) > limit {
+		dropped := false
+		for i := 0; i < len(result)-1; i++ {
+			if result[i].Role != RoleSystem {
+				result = append(result[:i], result[i+1:]...)
+				dropped = true
+				break
+			}
+		}
+		if !dropped {
+			break
+		}
+	}
+
+	for tokenizer.CountMessages(result) > limit {
+		longest := 0
+		for i := range result {
+			if len(result[i].Content) > len(result[longest].Content) {
+				longest = i
+			}
+		}
+
+		excess := tokenizer.CountMessages(result) - limit
+		content := []rune(result[longest].Content)
+		current := tokenizer.Count(result[longest].Content)
+		if current <= excess || len(content) < 2 {
+			return nil, false
+		}
+
+		// 按比例估算保留的字符数，留出截断标记的余量
+		keep := int(float64(len(content)) * float64(current-excess) / float64(current))
+		keep -= tokenizer.Count(truncationMarker) + 1
+		if keep <= 0 {
+			return nil, false
+		}
+		if keep >= len(content) {
+			keep = len(content) - 1
+		}
+		head := keep / 2
+		tail := keep - head
+		result[longest].Content = string(content[:head]) + truncationMarker + string(content[len(content)-tail:])
+	}
+
+	return result, true
+}
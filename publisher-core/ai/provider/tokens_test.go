@@ -0,0 +1,102 @@
+package provider
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookupModel(t *testing.T) {
+	tests := []struct {
+		pt        ProviderType
+		model     string
+		window    int
+		tokenizer string
+	}{
+		{ProviderDeepSeek, "deepseek-chat", 65536, "deepseek"},
+		{ProviderOpenRouter, "deepseek/deepseek-r1-0528:free", 163840, "deepseek"},
+		{ProviderGroq, "deepseek-r1-distill-llama-70b", 131072, "llama"},
+		{ProviderOllama, "gemma3:4b", 131072, "gemini"},
+		{ProviderMistral, "mistral-small-latest", 32768, "mistral"},
+		{ProviderOpenAI, "my-finetune", 8192, "o200k"},
+		{"", "", 8192, "default"},
+	}
+
+	for _, tt := range tests {
+		info := LookupModel(tt.pt, tt.model)
+		if info.ContextWindow != tt.window || info.Tokenizer != tt.tokenizer || info.Model != tt.model {
+			t.Errorf("%s/%s: got %+v", tt.pt, tt.model, info)
+		}
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	tests := []struct {
+		model string
+		text  string
+		want  int
+	}{
+		{"deepseek-chat", "", 0},
+		{"deepseek-chat", "hello world!", 5},
+		{"deepseek-chat", "你好世界", 3},
+		{"gpt-4", "你好世界", 4},
+		{"gpt-4", "2024年", 3},
+	}
+
+	for _, tt := range tests {
+		if got := CountTokens("", tt.model, tt.text); got != tt.want {
+			t.Errorf("%s %q: got %d, want %d", tt.model, tt.text, got, tt.want)
+		}
+	}
+
+	// 分词器对中文压缩率越高，同样文本的 token 越少
+	text := strings.Repeat("人工智能正在改变内容创作。", 100)
+	if CountTokens("", "deepseek-chat", text) >= CountTokens("", "gpt-4", text) {
+		t.Error("Expected deepseek tokenizer to be more compact on Chinese text than cl100k")
+	}
+}
+
+func TestPickModel(t *testing.T) {
+	messages := []Message{{Role: RoleUser, Content: strings.Repeat("长", 40000)}}
+	candidates := []string{"llama3", "gemini-2.5-flash", "gemma-3-27b-it"}
+
+	model, ok := PickModel(ProviderGoogle, candidates, messages, 0)
+	if !ok || model != "gemma-3-27b-it" {
+		t.Errorf("Expected smallest fitting window gemma-3-27b-it, got %q %v", model, ok)
+	}
+
+	if _, ok := PickModel(ProviderGoogle, []string{"llama3"}, messages, 0); ok {
+		t.Error("Expected no model to fit")
+	}
+}
+
+func TestFitMessages(t *testing.T) {
+	tokenizer := tokenizers["default"]
+	messages := []Message{
+		{Role: RoleSystem, Content: "你是编辑"},
+		{Role: RoleUser, Content: strings.Repeat("旧", 500)},
+		{Role: RoleAssistant, Content: strings.Repeat("答", 500)},
+		{Role: RoleUser, Content: strings.Repeat("新", 300)},
+	}
+
+	// 丢弃最早的对话轮次即可放下
+	fitted, ok := FitMessages(messages, tokenizer, 400)
+	if !ok || len(fitted) != 2 || fitted[0].Role != RoleSystem || fitted[1].Content != messages[3].Content {
+		t.Fatalf("Unexpected fitted messages: ok=%v len=%d", ok, len(fitted))
+	}
+	if len(messages) != 4 {
+		t.Error("FitMessages must not modify the input")
+	}
+
+	// 仍超出时截断最长消息
+	fitted, ok = FitMessages(messages, tokenizer, 200)
+	if !ok || tokenizer.CountMessages(fitted) > 200 {
+		t.Fatalf("Expected truncated messages within limit, got ok=%v tokens=%d", ok, tokenizer.CountMessages(fitted))
+	}
+	if !strings.Contains(fitted[len(fitted)-1].Content, "已截断") {
+		t.Error("Expected truncation marker")
+	}
+
+	if _, ok := FitMessages(messages, tokenizer, 10); ok {
+		t.Error("Expected failure when even the system prompt does not fit")
+	}
+}
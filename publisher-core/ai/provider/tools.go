@@ -0,0 +1,90 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 工具调用策略
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceNone     = "none"
+	ToolChoiceRequired = "required"
+)
+
+// ToolDefinition 可供模型调用的工具定义
+// Parameters 为 JSON Schema，与 MCP 工具的 inputSchema 格式一致
+type ToolDefinition struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// ToolCall 模型发起的工具调用
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction 工具调用的函数名和参数
+type ToolCallFunction struct {
+	Name string `json:"name"`
+	// Arguments JSON 编码的参数
+	Arguments string `json:"arguments"`
+}
+
+// ParseArguments 解析工具调用参数
+func (c ToolCall) ParseArguments() (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	raw := strings.TrimSpace(c.Function.Arguments)
+	if raw == "" {
+		return args, nil
+	}
+	if err := json.Unmarshal([]byte(raw), &args); err != nil {
+		return nil, fmt.Errorf("invalid arguments for tool %s: %w", c.Function.Name, err)
+	}
+	return args, nil
+}
+
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function ToolDefinition `json:"function"`
+}
+
+// openAITools 转换为 OpenAI 风格的 tools 参数
+func openAITools(defs []ToolDefinition) []openAITool {
+	if len(defs) == 0 {
+		return nil
+	}
+	tools := make([]openAITool, len(defs))
+	for i, def := range defs {
+		if def.Parameters == nil {
+			def.Parameters = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		tools[i] = openAITool{Type: "function", Function: def}
+	}
+	return tools
+}
+
+// openAIToolChoice 转换为 OpenAI 风格的 tool_choice 参数，未设置工具时不发送
+func openAIToolChoice(opts *GenerateOptions) interface{} {
+	if len(opts.Tools) == 0 || opts.ToolChoice == "" {
+		return nil
+	}
+	return opts.ToolChoice
+}
+
+// normalizeToolCalls 补全缺失的调用ID和类型
+func normalizeToolCalls(calls []ToolCall) []ToolCall {
+	for i := range calls {
+		if calls[i].ID == "" {
+			calls[i].ID = fmt.Sprintf("call_%d", i)
+		}
+		if calls[i].Type == "" {
+			calls[i].Type = "function"
+		}
+	}
+	return calls
+}
@@ -6,10 +6,15 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 
 	"publisher-core/ai/provider"
+	"publisher-core/cost"
+	"publisher-core/prompt"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 type Service struct {
@@ -17,6 +22,12 @@ type Service struct {
 	providers map[provider.ProviderType]provider.Provider
 	config    *Config
 	primary   provider.ProviderType
+	tracker   *cost.Tracker
+	historyDB *gorm.DB
+	// prompts 内容审核等内置功能的提示词来源，未设置时使用内置模板
+	prompts *prompt.Service
+	// contextPolicy 提示词超出上下文窗口时的处理策略
+	contextPolicy ContextPolicy
 }
 
 type Config struct {
@@ -34,8 +45,9 @@ type ProviderConfig struct {
 
 func NewService(configPath string) (*Service, error) {
 	s := &Service{
-		providers: make(map[provider.ProviderType]provider.Provider),
-		primary:   provider.ProviderOpenRouter,
+		providers:     make(map[provider.ProviderType]provider.Provider),
+		primary:       provider.ProviderOpenRouter,
+		contextPolicy: DefaultContextPolicy(),
 	}
 
 	if configPath != "" {
@@ -49,9 +61,10 @@ func NewService(configPath string) (*Service, error) {
 
 func NewServiceWithDefaults() *Service {
 	s := &Service{
-		providers: make(map[provider.ProviderType]provider.Provider),
-		primary:   provider.ProviderOpenRouter,
-		config:    &Config{Primary: provider.ProviderOpenRouter, Providers: make(map[string]ProviderConfig)},
+		providers:     make(map[provider.ProviderType]provider.Provider),
+		primary:       provider.ProviderOpenRouter,
+		config:        &Config{Primary: provider.ProviderOpenRouter, Providers: make(map[string]ProviderConfig)},
+		contextPolicy: DefaultContextPolicy(),
 	}
 	return s
 }
@@ -93,6 +106,34 @@ func (s *Service) loadConfig(path string) error {
 			} else {
 				s.RegisterProvider(p)
 			}
+		case provider.ProviderMistral:
+			s.RegisterProvider(provider.NewMistralProvider(&provider.MistralConfig{
+				APIKey:  pc.APIKey,
+				BaseURL: pc.BaseURL,
+				Model:   pc.Model,
+			}))
+		case provider.ProviderNVIDIA:
+			s.RegisterProvider(provider.NewNVIDIAProvider(&provider.NVIDIAConfig{
+				APIKey:  pc.APIKey,
+				BaseURL: pc.BaseURL,
+				Model:   pc.Model,
+			}))
+		default:
+			if !provider.IsOpenAICompatible(pt) {
+				logrus.Warnf("Unsupported AI provider in config: %s", name)
+				continue
+			}
+			p, err := provider.NewOpenAICompatibleProvider(&provider.OpenAICompatibleConfig{
+				Name:         pt,
+				APIKey:       pc.APIKey,
+				BaseURL:      pc.BaseURL,
+				DefaultModel: pc.Model,
+			})
+			if err != nil {
+				logrus.Warnf("Failed to create %s provider: %v", name, err)
+			} else {
+				s.RegisterProvider(p)
+			}
 		}
 	}
 
@@ -167,30 +208,185 @@ func (s *Service) ListModels() map[string][]string {
 	return result
 }
 
+// SetCostTracker 设置成本追踪器，之后的每次调用都会检查预算并记录成本
+func (s *Service) SetCostTracker(tracker *cost.Tracker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tracker = tracker
+}
+
+// tracked 在设置了成本追踪器时包装提供商
+func (s *Service) tracked(p provider.Provider) provider.Provider {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.tracker == nil {
+		return p
+	}
+	return s.tracker.Wrap(p)
+}
+
+// SetContextPolicy 设置上下文窗口管理策略
+func (s *Service) SetContextPolicy(policy ContextPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.contextPolicy = policy
+}
+
+// callOptions 返回调用选项副本，未指定模型时使用提供商默认模型，并按上下文策略换用更大的模型或截断消息
+func (s *Service) callOptions(p provider.Provider, opts *provider.GenerateOptions) (*provider.GenerateOptions, bool, error) {
+	s.mu.RLock()
+	policy := s.contextPolicy
+	s.mu.RUnlock()
+
+	callOpts := *opts
+	if callOpts.Model == "" {
+		callOpts.Model = p.DefaultModel()
+	}
+	truncated, err := policy.fit(p.Name(), p.Models(), &callOpts, opts.Model != "")
+	if err != nil {
+		return nil, false, err
+	}
+	return &callOpts, truncated, nil
+}
+
 func (s *Service) Generate(ctx context.Context, opts *provider.GenerateOptions) (*provider.GenerateResult, error) {
 	p := s.GetPrimary()
 	if p == nil {
 		return nil, fmt.Errorf("no AI provider available")
 	}
 
-	if opts.Model == "" {
-		opts.Model = p.DefaultModel()
+	callOpts, _, err := s.callOptions(p, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.tracked(p).Generate(ctx, callOpts)
+}
+
+func (s *Service) GenerateStream(ctx context.Context, opts *provider.GenerateOptions) (<-chan string, error) {
+	p := s.GetPrimary()
+	if p == nil {
+		return nil, fmt.Errorf("no AI provider available")
+	}
+
+	callOpts, _, err := s.callOptions(p, opts)
+	if err != nil {
+		return nil, err
 	}
+	return s.tracked(p).GenerateStream(ctx, callOpts)
+}
 
-	return p.Generate(ctx, opts)
+// Estimate 在调用前估算主提供商的 token 数和成本，应用与 Generate 相同的上下文策略
+func (s *Service) Estimate(opts *provider.GenerateOptions) (*CallEstimate, error) {
+	p := s.GetPrimary()
+	if p == nil {
+		return nil, fmt.Errorf("no AI provider available")
+	}
+	return s.estimate(p, opts)
 }
 
-func (s *Service) GenerateStream(ctx context.Context, opts *provider.GenerateOptions) (<-chan string, error) {
+// EstimateWithProvider 在调用前估算指定提供商的 token 数和成本
+func (s *Service) EstimateWithProvider(pt provider.ProviderType, opts *provider.GenerateOptions) (*CallEstimate, error) {
+	p, err := s.GetProvider(pt)
+	if err != nil {
+		return nil, err
+	}
+	return s.estimate(p, opts)
+}
+
+func (s *Service) estimate(p provider.Provider, opts *provider.GenerateOptions) (*CallEstimate, error) {
+	callOpts, truncated, err := s.callOptions(p, opts)
+	if err != nil {
+		return nil, err
+	}
+	estimate := newCallEstimate(string(p.Name()), callOpts, truncated)
+
+	s.mu.RLock()
+	tracker := s.tracker
+	s.mu.RUnlock()
+	if tracker != nil {
+		estimate.EstimatedCost = tracker.EstimateCost(estimate.Provider, estimate.Model, estimate.InputTokens, estimate.OutputTokens)
+	}
+	return estimate, nil
+}
+
+// SetHistoryDB 设置调用历史的存储，设置后流式生成结束时写入 AI 调用历史
+func (s *Service) SetHistoryDB(db *gorm.DB) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.historyDB = db
+}
+
+// SetPromptService 设置提示词模板服务，设置后内容审核使用模板管理中维护的提示词
+func (s *Service) SetPromptService(prompts *prompt.Service) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prompts = prompts
+}
+
+// promptService 返回提示词模板服务，未设置时按内置模板渲染
+func (s *Service) promptService() *prompt.Service {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.prompts == nil {
+		return prompt.NewService(nil)
+	}
+	return s.prompts
+}
+
+// recordHistory 记录 AI 调用历史，未设置存储时跳过
+func (s *Service) recordHistory(providerName string, opts *provider.GenerateOptions, result *provider.GenerateResult, duration time.Duration, callErr error) {
+	s.mu.RLock()
+	db := s.historyDB
+	s.mu.RUnlock()
+	if db == nil {
+		return
+	}
+
+	history := newAIHistory(providerName, opts, result, duration, true, "")
+	if callErr != nil {
+		history.Success = false
+		history.ErrorMessage = callErr.Error()
+	}
+	if err := db.Create(history).Error; err != nil {
+		logrus.Warnf("record AI history failed: %v", err)
+	}
+}
+
+// GenerateStreamEvents 使用主提供商流式生成并以事件形式返回
+// 用量由成本追踪器记录，流结束后写入调用历史
+func (s *Service) GenerateStreamEvents(ctx context.Context, opts *provider.GenerateOptions) (<-chan provider.StreamEvent, error) {
 	p := s.GetPrimary()
 	if p == nil {
 		return nil, fmt.Errorf("no AI provider available")
 	}
+	return s.streamEvents(ctx, p, opts)
+}
+
+// GenerateStreamEventsWithProvider 使用指定提供商流式生成并以事件形式返回
+func (s *Service) GenerateStreamEventsWithProvider(ctx context.Context, pt provider.ProviderType, opts *provider.GenerateOptions) (<-chan provider.StreamEvent, error) {
+	p, err := s.GetProvider(pt)
+	if err != nil {
+		return nil, err
+	}
+	return s.streamEvents(ctx, p, opts)
+}
 
-	if opts.Model == "" {
-		opts.Model = p.DefaultModel()
+// streamEvents 建立流并在结束时记录历史
+func (s *Service) streamEvents(ctx context.Context, p provider.Provider, opts *provider.GenerateOptions) (<-chan provider.StreamEvent, error) {
+	callOpts, _, err := s.callOptions(p, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	return p.GenerateStream(ctx, opts)
+	startTime := time.Now()
+	ch, err := s.tracked(p).GenerateStream(ctx, callOpts)
+	if err != nil {
+		s.recordHistory(string(p.Name()), callOpts, nil, time.Since(startTime), err)
+		return nil, err
+	}
+	return provider.StreamEvents(ctx, ch, string(p.Name()), callOpts.Model, callOpts, func(result *provider.GenerateResult, err error) {
+		s.recordHistory(string(p.Name()), callOpts, result, time.Since(startTime), err)
+	}), nil
 }
 
 func (s *Service) GenerateWithProvider(ctx context.Context, pt provider.ProviderType, opts *provider.GenerateOptions) (*provider.GenerateResult, error) {
@@ -199,11 +395,49 @@ func (s *Service) GenerateWithProvider(ctx context.Context, pt provider.Provider
 		return nil, err
 	}
 
-	if opts.Model == "" {
-		opts.Model = p.DefaultModel()
+	callOpts, _, err := s.callOptions(p, opts)
+	if err != nil {
+		return nil, err
 	}
+	return s.tracked(p).Generate(ctx, callOpts)
+}
+
+// Embedder 返回用于向量化的提供商：主提供商支持时优先，否则按名称顺序选第一个支持的提供商
+// 同一索引中的向量必须来自同一模型，因此选择结果是确定的
+func (s *Service) Embedder() (provider.Embedder, error) {
+	if p := s.GetPrimary(); p != nil {
+		if e, ok := provider.AsEmbedder(s.tracked(p)); ok {
+			return e, nil
+		}
+	}
+
+	names := s.ListProviders()
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	for _, pt := range names {
+		p, err := s.GetProvider(pt)
+		if err != nil {
+			continue
+		}
+		if e, ok := provider.AsEmbedder(s.tracked(p)); ok {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("no AI provider supports embeddings: %w", provider.ErrEmbeddingNotSupported)
+}
+
+// Embed 使用 Embedder 返回的提供商向量化文本
+func (s *Service) Embed(ctx context.Context, opts *provider.EmbedOptions) (*provider.EmbedResult, error) {
+	e, err := s.Embedder()
+	if err != nil {
+		return nil, err
+	}
+	return e.Embed(ctx, opts)
+}
 
-	return p.Generate(ctx, opts)
+// GenerateStructured 使用主提供商生成结构化输出并解析到 out
+// 输出多次校验失败时返回 *provider.StructuredOutputError
+func (s *Service) GenerateStructured(ctx context.Context, opts *provider.GenerateOptions, out interface{}) (*provider.GenerateResult, error) {
+	return provider.GenerateStructured(ctx, s, opts, out)
 }
 
 func SaveConfig(cfg *Config, path string) error {
@@ -2,11 +2,15 @@ package ai
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"publisher-core/ai/provider"
+	"publisher-core/cost"
 	"publisher-core/database"
 
 	"github.com/sirupsen/logrus"
@@ -15,10 +19,14 @@ import (
 
 // UnifiedService 统一的 AI 服务管理器
 type UnifiedService struct {
-	db      *gorm.DB
-	mu      sync.RWMutex
-	clients map[string]provider.Provider // 客户端缓存
-	log     *logrus.Logger
+	db            *gorm.DB
+	mu            sync.RWMutex
+	clients       map[string]provider.Provider // 客户端缓存
+	tracker       *cost.Tracker                // 成本追踪，为空时不记录成本
+	health        *provider.HealthChecker      // 按配置的熔断器
+	policy        FallbackPolicy
+	contextPolicy ContextPolicy // 提示词超出上下文窗口时的处理策略
+	log           *logrus.Logger
 }
 
 // NewUnifiedService 创建统一的 AI 服务
@@ -27,9 +35,12 @@ func NewUnifiedService(db *gorm.DB) *UnifiedService {
 		db = database.GetDB()
 	}
 	return &UnifiedService{
-		db:      db,
-		clients: make(map[string]provider.Provider),
-		log:     logrus.StandardLogger(),
+		db:            db,
+		clients:       make(map[string]provider.Provider),
+		health:        provider.NewHealthChecker(nil),
+		policy:        DefaultFallbackPolicy(),
+		contextPolicy: DefaultContextPolicy(),
+		log:           logrus.StandardLogger(),
 	}
 }
 
@@ -65,9 +76,28 @@ func (s *UnifiedService) GetActiveConfigs(serviceType string) ([]database.AIServ
 	return configs, nil
 }
 
+// clientKey 客户端缓存键
+// 同一提供商可以配置多个端点，缓存键以配置ID开头
+func clientKey(config *database.AIServiceConfig) string {
+	return fmt.Sprintf("%d:%s:%s", config.ID, config.Provider, config.Model)
+}
+
+// evictClients 清除配置对应的全部客户端缓存
+func (s *UnifiedService) evictClients(configID uint) {
+	prefix := fmt.Sprintf("%d:", configID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.clients {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.clients, key)
+		}
+	}
+}
+
 // GetOrCreateClient 获取或创建客户端
 func (s *UnifiedService) GetOrCreateClient(config *database.AIServiceConfig) (provider.Provider, error) {
-	key := fmt.Sprintf("%s:%s", config.Provider, config.Model)
+	key := clientKey(config)
 
 	s.mu.RLock()
 	client, ok := s.clients[key]
@@ -91,32 +121,96 @@ func (s *UnifiedService) GetOrCreateClient(config *database.AIServiceConfig) (pr
 		return nil, err
 	}
 
+	if s.tracker != nil {
+		client = s.tracker.Wrap(client)
+	}
+
 	s.clients[key] = client
 	s.log.Infof("Created AI client: %s (%s)", config.Name, key)
 
 	return client, nil
 }
 
+// providerSettings AIServiceConfig.Settings 中的提供商配置
+type providerSettings struct {
+	// Headers 额外请求头
+	Headers map[string]string `json:"headers"`
+	// Models 可用模型列表，为空时使用配置中的模型
+	Models []string `json:"models"`
+	// ChatPath 聊天接口路径，默认 /chat/completions
+	ChatPath string `json:"chat_path"`
+	// TimeoutSeconds 请求超时时间（秒）
+	TimeoutSeconds int `json:"timeout_seconds"`
+}
+
+// parseProviderSettings 解析配置中的 Settings
+func parseProviderSettings(config *database.AIServiceConfig) (*providerSettings, error) {
+	settings := &providerSettings{}
+	if config.Settings == "" {
+		return settings, nil
+	}
+	if err := json.Unmarshal([]byte(config.Settings), settings); err != nil {
+		return nil, fmt.Errorf("invalid settings for %s: %w", config.Name, err)
+	}
+	return settings, nil
+}
+
 // createProvider 根据配置创建 Provider
 func (s *UnifiedService) createProvider(config *database.AIServiceConfig) (provider.Provider, error) {
-	switch config.Provider {
-	case "openrouter":
+	pt := provider.ProviderType(config.Provider)
+
+	switch pt {
+	case provider.ProviderOpenRouter:
 		return provider.NewOpenRouterProvider(config.APIKey), nil
-	case "google":
+	case provider.ProviderGoogle:
 		return provider.NewGoogleProvider(config.APIKey), nil
-	case "groq":
+	case provider.ProviderGroq:
 		return provider.NewGroqProvider(config.APIKey), nil
-	case "deepseek":
+	case provider.ProviderDeepSeek:
 		if config.BaseURL != "" {
 			return provider.NewDeepSeekProviderWithBaseURL(config.APIKey, config.BaseURL), nil
 		}
 		return provider.NewDeepSeekProvider(config.APIKey), nil
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", config.Provider)
+	case provider.ProviderMistral:
+		return provider.NewMistralProvider(&provider.MistralConfig{
+			APIKey:  config.APIKey,
+			BaseURL: config.BaseURL,
+			Model:   config.Model,
+		}), nil
+	case provider.ProviderNVIDIA:
+		return provider.NewNVIDIAProvider(&provider.NVIDIAConfig{
+			APIKey:  config.APIKey,
+			BaseURL: config.BaseURL,
+			Model:   config.Model,
+		}), nil
+	case provider.ProviderOllama:
+		// 无需 API Key 的本地 Ollama 可按 openai_compatible 配置 http://localhost:11434/v1
+		return provider.NewOllamaProvider(config.APIKey, config.BaseURL, config.Model)
 	}
+
+	if provider.IsOpenAICompatible(pt) {
+		settings, err := parseProviderSettings(config)
+		if err != nil {
+			return nil, err
+		}
+
+		return provider.NewOpenAICompatibleProvider(&provider.OpenAICompatibleConfig{
+			Name:         pt,
+			APIKey:       config.APIKey,
+			BaseURL:      config.BaseURL,
+			Headers:      settings.Headers,
+			Models:       settings.Models,
+			DefaultModel: config.Model,
+			ChatPath:     settings.ChatPath,
+			Timeout:      time.Duration(settings.TimeoutSeconds) * time.Second,
+		})
+	}
+
+	return nil, fmt.Errorf("unsupported provider: %s", config.Provider)
 }
 
 // Generate 生成文本（支持降级）
+// 按优先级依次尝试各配置，每类错误的重试和降级行为由 FallbackPolicy 决定
 func (s *UnifiedService) Generate(ctx context.Context, opts *provider.GenerateOptions) (*provider.GenerateResult, error) {
 	configs, err := s.GetActiveConfigs("text")
 	if err != nil {
@@ -124,66 +218,186 @@ func (s *UnifiedService) Generate(ctx context.Context, opts *provider.GenerateOp
 	}
 
 	var lastErr error
-	for _, config := range configs {
-		client, err := s.GetOrCreateClient(&config)
+	for i := range configs {
+		config := &configs[i]
+		client, callOpts, _, err := s.prepareCall(config, opts)
 		if err != nil {
 			lastErr = err
-			s.log.Warnf("Failed to create client for %s: %v", config.Name, err)
 			continue
 		}
 
-		// 如果没有指定模型，使用配置中的模型
-		if opts.Model == "" {
-			opts.Model = config.Model
-		}
-
-		startTime := time.Now()
-		result, err := client.Generate(ctx, opts)
+		var result *provider.GenerateResult
+		err = s.callWithRetry(ctx, config, func() error {
+			startTime := time.Now()
+			var callErr error
+			result, callErr = client.Generate(ctx, callOpts)
+			if callErr != nil {
+				s.recordHistory(config, callOpts, nil, time.Since(startTime), false, callErr.Error())
+				return callErr
+			}
+			s.recordHistory(config, callOpts, result, time.Since(startTime), true, "")
+			return nil
+		})
 		if err == nil {
-			// 记录成功的历史
-			s.recordHistory(&config, opts, result, time.Since(startTime), true, "")
 			return result, nil
 		}
 
-		// 记录失败的历史
-		s.recordHistory(&config, opts, nil, time.Since(startTime), false, err.Error())
-
-		s.log.Warnf("AI generation failed for %s, trying next provider: %v", config.Name, err)
 		lastErr = err
+		if !s.shouldFallback(ctx, err) {
+			return nil, err
+		}
+		s.log.Warnf("AI generation failed for %s, trying next provider: %v", config.Name, err)
 	}
 
 	return nil, fmt.Errorf("all providers failed: %w", lastErr)
 }
 
 // GenerateStream 流式生成文本（支持降级）
+// 只有建立流之前的错误会触发重试和降级
 func (s *UnifiedService) GenerateStream(ctx context.Context, opts *provider.GenerateOptions) (<-chan string, error) {
-	configs, err := s.GetActiveConfigs("text")
+	ch, _, _, err := s.openStream(ctx, opts)
+	return ch, err
+}
+
+// GenerateStreamEvents 流式生成并以事件形式返回，结束时发送用量并记录历史
+// 取消 ctx 会中断提供商请求，已生成的部分按失败记录
+func (s *UnifiedService) GenerateStreamEvents(ctx context.Context, opts *provider.GenerateOptions) (<-chan provider.StreamEvent, error) {
+	ch, config, callOpts, err := s.openStream(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
+	startTime := time.Now()
+	return provider.StreamEvents(ctx, ch, config.Provider, callOpts.Model, callOpts, func(result *provider.GenerateResult, err error) {
+		if err != nil {
+			s.recordHistory(config, callOpts, result, time.Since(startTime), false, err.Error())
+			return
+		}
+		s.recordHistory(config, callOpts, result, time.Since(startTime), true, "")
+	}), nil
+}
+
+// openStream 按优先级建立流，返回所用配置和实际调用选项
+func (s *UnifiedService) openStream(ctx context.Context, opts *provider.GenerateOptions) (<-chan string, *database.AIServiceConfig, *provider.GenerateOptions, error) {
+	configs, err := s.GetActiveConfigs("text")
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	var lastErr error
-	for _, config := range configs {
-		client, err := s.GetOrCreateClient(&config)
+	for i := range configs {
+		config := &configs[i]
+		client, callOpts, _, err := s.prepareCall(config, opts)
 		if err != nil {
 			lastErr = err
 			continue
 		}
 
-		if opts.Model == "" {
-			opts.Model = config.Model
+		var ch <-chan string
+		err = s.callWithRetry(ctx, config, func() error {
+			var callErr error
+			ch, callErr = client.GenerateStream(ctx, callOpts)
+			return callErr
+		})
+		if err == nil {
+			return ch, config, callOpts, nil
+		}
+
+		lastErr = err
+		if !s.shouldFallback(ctx, err) {
+			return nil, nil, nil, err
 		}
+		s.log.Warnf("AI stream generation failed for %s, trying next provider: %v", config.Name, err)
+	}
 
-		ch, err := client.GenerateStream(ctx, opts)
+	return nil, nil, nil, fmt.Errorf("all providers failed: %w", lastErr)
+}
+
+// prepareCall 检查熔断器并为配置准备客户端和调用选项
+// 返回的选项是副本，调用方未指定模型时使用配置中的模型，并按上下文策略调整模型或截断消息。
+// truncated 表示消息已被截断
+func (s *UnifiedService) prepareCall(config *database.AIServiceConfig, opts *provider.GenerateOptions) (client provider.Provider, callOpts *provider.GenerateOptions, truncated bool, err error) {
+	if !s.health.Allow(breakerKey(config)) {
+		return nil, nil, false, fmt.Errorf("circuit breaker open for %s", config.Name)
+	}
+
+	client, err = s.GetOrCreateClient(config)
+	if err != nil {
+		s.log.Warnf("Failed to create client for %s: %v", config.Name, err)
+		return nil, nil, false, err
+	}
+
+	copied := *opts
+	if copied.Model == "" {
+		copied.Model = config.Model
+	}
+	truncated, err = s.fitContext(config, client, &copied, opts.Model != "")
+	if err != nil {
+		s.log.Warnf("Skipping %s: %v", config.Name, err)
+		return nil, nil, false, err
+	}
+	return client, &copied, truncated, nil
+}
+
+// callWithRetry 按错误类别的策略在同一配置上重试，并维护该配置的熔断状态
+func (s *UnifiedService) callWithRetry(ctx context.Context, config *database.AIServiceConfig, call func() error) error {
+	key := breakerKey(config)
+	for attempt := 0; ; attempt++ {
+		startTime := time.Now()
+		err := call()
 		if err == nil {
-			return ch, nil
+			s.health.RecordSuccess(key, time.Since(startTime))
+			return nil
+		}
+		if errors.Is(err, cost.ErrBudgetExceeded) || ctx.Err() != nil {
+			return err
 		}
 
-		s.log.Warnf("AI stream generation failed for %s, trying next provider: %v", config.Name, err)
-		lastErr = err
+		class := provider.ClassifyError(err)
+		policy := s.policy.For(class)
+		if policy.TripBreaker && s.health.RecordFailure(key, err) {
+			s.log.Warnf("Circuit breaker opened for %s after error: %v", config.Name, err)
+			return err
+		}
+
+		wait, retry := policy.wait(attempt, provider.RetryAfter(err))
+		if !retry {
+			return err
+		}
+		s.log.Infof("AI call to %s failed (%s), retrying in %s: %v", config.Name, class, wait, err)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
 	}
+}
 
-	return nil, fmt.Errorf("all providers failed: %w", lastErr)
+// shouldFallback 判断错误后是否继续尝试下一个配置
+func (s *UnifiedService) shouldFallback(ctx context.Context, err error) bool {
+	// 预算耗尽或调用方已取消时换提供商也无济于事
+	if errors.Is(err, cost.ErrBudgetExceeded) || ctx.Err() != nil {
+		return false
+	}
+	return s.policy.For(provider.ClassifyError(err)).Fallback
+}
+
+// breakerKey 熔断器按配置区分，同一提供商的多个端点互不影响
+func breakerKey(config *database.AIServiceConfig) provider.ProviderType {
+	return provider.ProviderType(fmt.Sprintf("%s#%d", config.Provider, config.ID))
+}
+
+// SetFallbackPolicy 设置降级策略
+func (s *UnifiedService) SetFallbackPolicy(policy FallbackPolicy) {
+	s.policy = policy
+}
+
+// Health 返回各配置的熔断与健康统计
+func (s *UnifiedService) Health() *provider.HealthChecker {
+	return s.health
 }
 
 // GenerateWithProvider 使用指定提供商生成
@@ -201,11 +415,17 @@ func (s *UnifiedService) GenerateWithProvider(ctx context.Context, providerName
 		return nil, err
 	}
 
-	if opts.Model == "" {
-		opts.Model = config.Model
+	callOpts := *opts
+	if callOpts.Model == "" {
+		callOpts.Model = config.Model
 	}
 
-	return client.Generate(ctx, opts)
+	return client.Generate(ctx, &callOpts)
+}
+
+// GenerateStructured 按优先级生成结构化输出并解析到 out
+func (s *UnifiedService) GenerateStructured(ctx context.Context, opts *provider.GenerateOptions, out interface{}) (*provider.GenerateResult, error) {
+	return provider.GenerateStructured(ctx, s, opts, out)
 }
 
 // GenerateWithModel 使用指定模型生成
@@ -222,14 +442,23 @@ func (s *UnifiedService) GenerateWithModel(ctx context.Context, providerName, mo
 		return nil, err
 	}
 
-	opts.Model = model
-	return client.Generate(ctx, opts)
+	callOpts := *opts
+	callOpts.Model = model
+	return client.Generate(ctx, &callOpts)
 }
 
 // recordHistory 记录 AI 调用历史
 func (s *UnifiedService) recordHistory(config *database.AIServiceConfig, opts *provider.GenerateOptions, result *provider.GenerateResult, duration time.Duration, success bool, errMsg string) {
+	history := newAIHistory(config.Provider, opts, result, duration, success, errMsg)
+	if err := s.db.Create(history).Error; err != nil {
+		s.log.Warnf("Failed to record AI history: %v", err)
+	}
+}
+
+// newAIHistory 构造 AI 调用历史记录，提示词和响应过长时截断
+func newAIHistory(providerName string, opts *provider.GenerateOptions, result *provider.GenerateResult, duration time.Duration, success bool, errMsg string) *database.AIHistory {
 	history := &database.AIHistory{
-		Provider:     config.Provider,
+		Provider:     providerName,
 		Model:        opts.Model,
 		Success:      success,
 		ErrorMessage: errMsg,
@@ -257,9 +486,7 @@ func (s *UnifiedService) recordHistory(config *database.AIServiceConfig, opts *p
 		}
 	}
 
-	if err := s.db.Create(history).Error; err != nil {
-		s.log.Warnf("Failed to record AI history: %v", err)
-	}
+	return history
 }
 
 // ListProviders 列出所有可用的提供商
@@ -282,11 +509,8 @@ func (s *UnifiedService) UpdateConfig(config *database.AIServiceConfig) error {
 			Update("is_default", false)
 	}
 
-	// 清除客户端缓存
-	key := fmt.Sprintf("%s:%s", config.Provider, config.Model)
-	s.mu.Lock()
-	delete(s.clients, key)
-	s.mu.Unlock()
+	// 更新前的提供商和模型可能与新配置不同，按配置ID清除客户端缓存
+	s.evictClients(config.ID)
 
 	return s.db.Save(config).Error
 }
@@ -308,14 +532,7 @@ func (s *UnifiedService) CreateConfig(config *database.AIServiceConfig) error {
 
 // DeleteConfig 删除 AI 配置
 func (s *UnifiedService) DeleteConfig(id uint) error {
-	// 清除客户端缓存
-	var config database.AIServiceConfig
-	if err := s.db.First(&config, id).Error; err == nil {
-		key := fmt.Sprintf("%s:%s", config.Provider, config.Model)
-		s.mu.Lock()
-		delete(s.clients, key)
-		s.mu.Unlock()
-	}
+	s.evictClients(id)
 
 	return s.db.Delete(&database.AIServiceConfig{}, id).Error
 }
@@ -386,6 +603,14 @@ type ProviderStats struct {
 	AvgDuration float64
 }
 
+// SetCostTracker 设置成本追踪器，并清除客户端缓存以便重新包装
+func (s *UnifiedService) SetCostTracker(tracker *cost.Tracker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tracker = tracker
+	s.clients = make(map[string]provider.Provider)
+}
+
 // ClearCache 清除客户端缓存
 func (s *UnifiedService) ClearCache() {
 	s.mu.Lock()
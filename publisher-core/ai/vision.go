@@ -0,0 +1,138 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"publisher-core/ai/provider"
+)
+
+// defaultImagePrompt 默认的图片描述提示词
+const defaultImagePrompt = "请用中文详细描述这张图片：主体、场景、色彩、氛围以及图中出现的文字，不超过150字。"
+
+// ImageDescription 单张图片的描述
+type ImageDescription struct {
+	Index       int    `json:"index"`
+	Description string `json:"description"`
+}
+
+// ImageNoteRequest 看图写笔记请求
+type ImageNoteRequest struct {
+	Images []provider.ImagePart `json:"images"`
+	// Topic 笔记主题，可为空
+	Topic string `json:"topic,omitempty"`
+	// Platform 目标平台，默认小红书
+	Platform string `json:"platform,omitempty"`
+	Style    string `json:"style,omitempty"`
+	// VisionModel 描述图片使用的模型，为空时使用提供商默认模型
+	VisionModel string `json:"vision_model,omitempty"`
+	// Model 撰写笔记使用的模型，为空时使用提供商默认模型
+	Model string `json:"model,omitempty"`
+}
+
+// imageNoteOutput 笔记的结构化输出
+type imageNoteOutput struct {
+	Title      string   `json:"title" jsonschema:"minLength=1,maxLength=40"`
+	Content    string   `json:"content" jsonschema:"minLength=1"`
+	Tags       []string `json:"tags" jsonschema:"maxItems=10"`
+	CoverIndex int      `json:"cover_index" description:"最适合作为封面的图片序号，从 0 开始" jsonschema:"minimum=0"`
+}
+
+// ImageNote 看图写出的笔记
+type ImageNote struct {
+	Title        string             `json:"title"`
+	Content      string             `json:"content"`
+	Tags         []string           `json:"tags"`
+	CoverIndex   int                `json:"cover_index"`
+	Descriptions []ImageDescription `json:"descriptions"`
+	InputTokens  int                `json:"input_tokens"`
+	OutputTokens int                `json:"output_tokens"`
+}
+
+// DescribeImage 使用视觉模型描述图片，prompt 为空时使用默认提示词
+func (s *Service) DescribeImage(ctx context.Context, image provider.ImagePart, prompt, model string) (*provider.GenerateResult, error) {
+	if prompt == "" {
+		prompt = defaultImagePrompt
+	}
+	return s.Generate(ctx, &provider.GenerateOptions{
+		Model: model,
+		Messages: []provider.Message{
+			{Role: provider.RoleUser, Content: prompt, Images: []provider.ImagePart{image}},
+		},
+		Temperature: 0.3,
+	})
+}
+
+// WriteImageNote 先逐张描述图片，再根据描述撰写图文笔记
+func (s *Service) WriteImageNote(ctx context.Context, req *ImageNoteRequest) (*ImageNote, error) {
+	if len(req.Images) == 0 {
+		return nil, fmt.Errorf("至少需要一张图片")
+	}
+
+	platform := req.Platform
+	if platform == "" {
+		platform = "xiaohongshu"
+	}
+
+	note := &ImageNote{Descriptions: make([]ImageDescription, 0, len(req.Images))}
+	for i, image := range req.Images {
+		result, err := s.DescribeImage(ctx, image, "", req.VisionModel)
+		if err != nil {
+			return nil, fmt.Errorf("描述第%d张图片失败: %w", i+1, err)
+		}
+		note.Descriptions = append(note.Descriptions, ImageDescription{
+			Index:       i,
+			Description: strings.TrimSpace(result.Content),
+		})
+		note.InputTokens += result.InputTokens
+		note.OutputTokens += result.OutputTokens
+	}
+
+	var output imageNoteOutput
+	result, err := s.GenerateStructured(ctx, &provider.GenerateOptions{
+		Model: req.Model,
+		Messages: []provider.Message{
+			{Role: provider.RoleUser, Content: buildImageNotePrompt(note.Descriptions, req.Topic, platform, req.Style)},
+		},
+		Temperature: 0.8,
+	}, &output)
+	if err != nil {
+		return nil, fmt.Errorf("撰写笔记失败: %w", err)
+	}
+	if output.CoverIndex >= len(req.Images) {
+		output.CoverIndex = 0
+	}
+
+	note.Title = output.Title
+	note.Content = output.Content
+	note.Tags = output.Tags
+	note.CoverIndex = output.CoverIndex
+	note.InputTokens += result.InputTokens
+	note.OutputTokens += result.OutputTokens
+	return note, nil
+}
+
+// buildImageNotePrompt 构建看图写笔记提示词
+func buildImageNotePrompt(descriptions []ImageDescription, topic, platform, style string) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("请根据以下%d张图片的描述，为%s平台写一篇图文笔记。\n\n", len(descriptions), platform))
+	for _, d := range descriptions {
+		b.WriteString(fmt.Sprintf("图片%d：%s\n", d.Index, d.Description))
+	}
+	if topic != "" {
+		b.WriteString(fmt.Sprintf("\n笔记主题：%s\n", topic))
+	}
+	if style != "" {
+		b.WriteString(fmt.Sprintf("内容风格：%s\n", style))
+	}
+
+	b.WriteString("\n要求：\n")
+	b.WriteString("1. 标题20字以内，吸引眼球\n")
+	b.WriteString("2. 正文围绕图片展开，真实自然，适当使用emoji，300-600字\n")
+	b.WriteString("3. 给出5-10个话题标签，不带#号\n")
+	b.WriteString("4. 选出最适合作为封面的图片序号\n")
+
+	return b.String()
+}
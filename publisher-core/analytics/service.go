@@ -8,10 +8,16 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// EngagementRecorder 接收采集到的内容互动率，prompt.ABTestService 实现了该接口，用于统计实验的发布效果
+type EngagementRecorder interface {
+	RecordPostEngagement(platform, postID string, engagement float64) error
+}
+
 type AnalyticsService struct {
 	mu         sync.RWMutex
 	collectors map[Platform]Collector
 	storage    MetricsStorage
+	recorder   EngagementRecorder
 }
 
 func NewService(storage MetricsStorage) *AnalyticsService {
@@ -28,6 +34,13 @@ func (s *AnalyticsService) RegisterCollector(collector Collector) {
 	logrus.Infof("Registered analytics collector: %s", collector.Platform())
 }
 
+// SetEngagementRecorder 设置互动率的接收方，每次采集到内容数据后回调
+func (s *AnalyticsService) SetEngagementRecorder(recorder EngagementRecorder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recorder = recorder
+}
+
 func (s *AnalyticsService) GetCollector(platform Platform) (Collector, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -67,6 +80,15 @@ func (s *AnalyticsService) CollectPostMetrics(ctx context.Context, platform Plat
 		}
 	}
 
+	s.mu.RLock()
+	recorder := s.recorder
+	s.mu.RUnlock()
+	if recorder != nil {
+		if err := recorder.RecordPostEngagement(string(platform), postID, metrics.Engagement); err != nil {
+			logrus.Warnf("Failed to record post engagement: %v", err)
+		}
+	}
+
 	return metrics, nil
 }
 
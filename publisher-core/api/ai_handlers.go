@@ -3,16 +3,21 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gorilla/mux"
 	"publisher-core/ai/provider"
+	"publisher-core/cost"
 )
 
 type AIServiceAPI interface {
 	Generate(ctx context.Context, providerName string, opts *provider.GenerateOptions) (*provider.GenerateResult, error)
 	GenerateStream(ctx context.Context, providerName string, opts *provider.GenerateOptions) (<-chan string, error)
+	GenerateStreamEvents(ctx context.Context, providerName string, opts *provider.GenerateOptions) (<-chan provider.StreamEvent, error)
+	Estimate(providerName string, opts *provider.GenerateOptions) (interface{}, error)
 	ListProviders() []string
 	ListModels() map[string][]string
 	GenerateContent(ctx context.Context, prompt string, options map[string]interface{}) (interface{}, error)
@@ -29,6 +34,8 @@ func (s *Server) setupAIRoutes() {
 	s.router.HandleFunc("/api/v1/ai/providers", s.listAIProviders).Methods("GET")
 	s.router.HandleFunc("/api/v1/ai/models", s.listAIModels).Methods("GET")
 	s.router.HandleFunc("/api/v1/ai/generate", s.aiGenerate).Methods("POST")
+	s.router.HandleFunc("/api/v1/ai/stream", s.aiGenerateStream).Methods("POST")
+	s.router.HandleFunc("/api/v1/ai/estimate", s.aiEstimate).Methods("POST")
 	s.router.HandleFunc("/api/v1/ai/generate/{provider}", s.aiGenerateWithProvider).Methods("POST")
 	s.router.HandleFunc("/api/v1/ai/analyze/hotspot", s.aiAnalyzeHotspot).Methods("POST")
 	s.router.HandleFunc("/api/v1/ai/content/generate", s.aiContentGenerate).Methods("POST")
@@ -86,13 +93,119 @@ func (s *Server) aiGenerate(w http.ResponseWriter, r *http.Request) {
 
 	result, err := s.ai.Generate(r.Context(), "", opts)
 	if err != nil {
-		jsonError(w, "AI_ERROR", err.Error(), http.StatusInternalServerError)
+		aiError(w, err)
 		return
 	}
 
 	jsonSuccess(w, result)
 }
 
+// aiGenerateStream 以 server-sent events 流式返回生成结果
+// 事件名为 start、delta、done、error，数据为 JSON；客户端断开会取消生成
+func (s *Server) aiGenerateStream(w http.ResponseWriter, r *http.Request) {
+	if s.ai == nil {
+		jsonError(w, "SERVICE_UNAVAILABLE", "AI service not initialized", http.StatusServiceUnavailable)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		jsonError(w, "STREAM_UNSUPPORTED", "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	var req struct {
+		Messages    []provider.Message `json:"messages"`
+		Provider    string             `json:"provider,omitempty"`
+		Model       string             `json:"model,omitempty"`
+		MaxTokens   int                `json:"max_tokens,omitempty"`
+		Temperature float64            `json:"temperature,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "INVALID_REQUEST", "Invalid request format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Provider != "" && !s.hasAIProvider(req.Provider) {
+		jsonError(w, "INVALID_REQUEST", "Unknown provider: "+req.Provider, http.StatusBadRequest)
+		return
+	}
+
+	opts := &provider.GenerateOptions{
+		Messages:    req.Messages,
+		Model:       req.Model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+
+	events, err := s.ai.GenerateStreamEvents(r.Context(), req.Provider, opts)
+	if err != nil {
+		aiError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for event := range events {
+		data, _ := json.Marshal(event)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		flusher.Flush()
+	}
+}
+
+// aiEstimate 在调用前估算 token 数和成本，按与生成相同的上下文策略选择模型或截断
+func (s *Server) aiEstimate(w http.ResponseWriter, r *http.Request) {
+	if s.ai == nil {
+		jsonError(w, "SERVICE_UNAVAILABLE", "AI service not initialized", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		Messages  []provider.Message `json:"messages"`
+		Provider  string             `json:"provider,omitempty"`
+		Model     string             `json:"model,omitempty"`
+		MaxTokens int                `json:"max_tokens,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "INVALID_REQUEST", "Invalid request format: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Provider != "" && !s.hasAIProvider(req.Provider) {
+		jsonError(w, "INVALID_REQUEST", "Unknown provider: "+req.Provider, http.StatusBadRequest)
+		return
+	}
+
+	estimate, err := s.ai.Estimate(req.Provider, &provider.GenerateOptions{
+		Messages:  req.Messages,
+		Model:     req.Model,
+		MaxTokens: req.MaxTokens,
+	})
+	if err != nil {
+		aiError(w, err)
+		return
+	}
+
+	jsonSuccess(w, estimate)
+}
+
+// hasAIProvider 检查提供商是否已注册
+func (s *Server) hasAIProvider(name string) bool {
+	for _, p := range s.ai.ListProviders() {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Server) aiGenerateWithProvider(w http.ResponseWriter, r *http.Request) {
 	if s.ai == nil {
 		jsonError(w, "SERVICE_UNAVAILABLE", "AI service not initialized", http.StatusServiceUnavailable)
@@ -123,7 +236,7 @@ func (s *Server) aiGenerateWithProvider(w http.ResponseWriter, r *http.Request)
 
 	result, err := s.ai.Generate(r.Context(), providerName, opts)
 	if err != nil {
-		jsonError(w, "AI_ERROR", err.Error(), http.StatusInternalServerError)
+		aiError(w, err)
 		return
 	}
 
@@ -158,7 +271,7 @@ func (s *Server) aiAnalyzeHotspot(w http.ResponseWriter, r *http.Request) {
 
 	result, err := s.ai.Generate(r.Context(), "", opts)
 	if err != nil {
-		jsonError(w, "AI_ERROR", err.Error(), http.StatusInternalServerError)
+		aiError(w, err)
 		return
 	}
 
@@ -209,7 +322,7 @@ func (s *Server) aiContentGenerate(w http.ResponseWriter, r *http.Request) {
 
 	result, err := s.ai.Generate(r.Context(), "", opts)
 	if err != nil {
-		jsonError(w, "AI_ERROR", err.Error(), http.StatusInternalServerError)
+		aiError(w, err)
 		return
 	}
 
@@ -256,7 +369,7 @@ func (s *Server) aiContentRewrite(w http.ResponseWriter, r *http.Request) {
 
 	result, err := s.ai.Generate(r.Context(), "", opts)
 	if err != nil {
-		jsonError(w, "AI_ERROR", err.Error(), http.StatusInternalServerError)
+		aiError(w, err)
 		return
 	}
 
@@ -294,7 +407,7 @@ func (s *Server) aiContentAudit(w http.ResponseWriter, r *http.Request) {
 
 	result, err := s.ai.Generate(r.Context(), "", opts)
 	if err != nil {
-		jsonError(w, "AI_ERROR", err.Error(), http.StatusInternalServerError)
+		aiError(w, err)
 		return
 	}
 
@@ -359,3 +472,16 @@ Check:
 
 Output the review result in JSON format.`
 }
+
+// aiError 输出 AI 调用错误，预算耗尽返回 402
+func aiError(w http.ResponseWriter, err error) {
+	if errors.Is(err, cost.ErrBudgetExceeded) {
+		jsonError(w, "BUDGET_EXCEEDED", err.Error(), http.StatusPaymentRequired)
+		return
+	}
+	if provider.ClassifyError(err) == provider.ErrorClassContextLength {
+		jsonError(w, "CONTEXT_TOO_LONG", err.Error(), http.StatusBadRequest)
+		return
+	}
+	jsonError(w, "AI_ERROR", err.Error(), http.StatusInternalServerError)
+}
@@ -30,6 +30,7 @@ func (h *CacheHandler) RegisterRoutes(router interface{}) {
 	}); ok {
 		r.HandleFunc("/api/v1/cache/stats", h.GetCacheStats).Methods("GET")
 		r.HandleFunc("/api/v1/cache/keys", h.GetCacheKeys).Methods("GET")
+		r.HandleFunc("/api/v1/cache/functions", h.GetFunctionStats).Methods("GET")
 		r.HandleFunc("/api/v1/cache/clear", h.ClearCache).Methods("POST")
 		r.HandleFunc("/api/v1/cache/warmup", h.WarmupCache).Methods("POST")
 		r.HandleFunc("/api/v1/cache/{key}", h.DeleteCache).Methods("DELETE")
@@ -59,6 +60,14 @@ func (h *CacheHandler) GetCacheKeys(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// GetFunctionStats 获取按功能类型的AI响应缓存命中统计
+func (h *CacheHandler) GetFunctionStats(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"data":    h.cacheService.GetFunctionStats(),
+	})
+}
+
 // ClearCache 清空缓存
 func (h *CacheHandler) ClearCache(w http.ResponseWriter, r *http.Request) {
 	h.cacheService.Clear()
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"publisher-core/compliance"
+	"publisher-core/database"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// TaskRequeuer 重新执行已失败的任务
+type TaskRequeuer interface {
+	RequeueTask(taskID string) error
+}
+
+// ComplianceHandlers 内容合规检查和人工审批处理器
+type ComplianceHandlers struct {
+	checker *compliance.Checker
+	reviews *compliance.ReviewStore
+	tasks   TaskRequeuer
+}
+
+// NewComplianceHandlers 创建合规处理器，tasks 不为空时批准后重新执行因待审批而失败的发布任务
+func NewComplianceHandlers(checker *compliance.Checker, reviews *compliance.ReviewStore, tasks TaskRequeuer) *ComplianceHandlers {
+	return &ComplianceHandlers{
+		checker: checker,
+		reviews: reviews,
+		tasks:   tasks,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *ComplianceHandlers) RegisterRoutes(router *mux.Router) {
+	complianceRouter := router.PathPrefix("/api/v1/compliance").Subrouter()
+
+	complianceRouter.HandleFunc("/check", h.check).Methods("POST")
+	complianceRouter.HandleFunc("/policy", h.getPolicy).Methods("GET")
+	complianceRouter.HandleFunc("/policy", h.updatePolicy).Methods("PUT")
+
+	complianceRouter.HandleFunc("/reviews", h.listReviews).Methods("GET")
+	complianceRouter.HandleFunc("/reviews/{id}", h.getReview).Methods("GET")
+	complianceRouter.HandleFunc("/reviews/{id}/approve", h.approveReview).Methods("POST")
+	complianceRouter.HandleFunc("/reviews/{id}/reject", h.rejectReview).Methods("POST")
+}
+
+// check 检查内容，只返回报告，不创建审批记录
+func (h *ComplianceHandlers) check(w http.ResponseWriter, r *http.Request) {
+	var req compliance.Content
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "INVALID_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Title == "" && req.Body == "" {
+		jsonError(w, "INVALID_REQUEST", "title or body is required", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.checker.Check(r.Context(), &req)
+	if err != nil {
+		jsonError(w, "CHECK_FAILED", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonSuccess(w, report)
+}
+
+// getPolicy 获取当前策略
+func (h *ComplianceHandlers) getPolicy(w http.ResponseWriter, r *http.Request) {
+	jsonSuccess(w, h.checker.Policy())
+}
+
+// updatePolicy 更新策略
+func (h *ComplianceHandlers) updatePolicy(w http.ResponseWriter, r *http.Request) {
+	policy := h.checker.Policy()
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		jsonError(w, "INVALID_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.checker.SetPolicy(&policy)
+	jsonSuccess(w, policy)
+}
+
+// listReviews 列出审批记录
+func (h *ComplianceHandlers) listReviews(w http.ResponseWriter, r *http.Request) {
+	status := database.ComplianceReviewStatus(r.URL.Query().Get("status"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 50
+	}
+
+	reviews, err := h.reviews.List(status, limit)
+	if err != nil {
+		jsonError(w, "LIST_REVIEWS_FAILED", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonSuccess(w, map[string]interface{}{
+		"reviews": reviews,
+		"total":   len(reviews),
+	})
+}
+
+// getReview 获取审批记录
+func (h *ComplianceHandlers) getReview(w http.ResponseWriter, r *http.Request) {
+	id, ok := reviewID(w, r)
+	if !ok {
+		return
+	}
+
+	review, err := h.reviews.Get(id)
+	if err != nil {
+		jsonError(w, "REVIEW_NOT_FOUND", err.Error(), http.StatusNotFound)
+		return
+	}
+
+	jsonSuccess(w, review)
+}
+
+// reviewRequest 审批请求
+type reviewRequest struct {
+	Reviewer string `json:"reviewer"`
+	Comment  string `json:"comment"`
+}
+
+// approveReview 批准，并重新执行关联的发布任务
+func (h *ComplianceHandlers) approveReview(w http.ResponseWriter, r *http.Request) {
+	id, ok := reviewID(w, r)
+	if !ok {
+		return
+	}
+	var req reviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "INVALID_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	review, err := h.reviews.Approve(id, req.Reviewer, req.Comment)
+	if err != nil {
+		jsonError(w, "APPROVE_FAILED", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	requeued := false
+	if review.TaskID != "" && h.tasks != nil {
+		if err := h.tasks.RequeueTask(review.TaskID); err != nil {
+			logrus.Warnf("重新执行发布任务失败: %s, 错误: %v", review.TaskID, err)
+		} else {
+			requeued = true
+		}
+	}
+
+	jsonSuccess(w, map[string]interface{}{
+		"review":   review,
+		"requeued": requeued,
+	})
+}
+
+// rejectReview 驳回
+func (h *ComplianceHandlers) rejectReview(w http.ResponseWriter, r *http.Request) {
+	id, ok := reviewID(w, r)
+	if !ok {
+		return
+	}
+	var req reviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "INVALID_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	review, err := h.reviews.Reject(id, req.Reviewer, req.Comment)
+	if err != nil {
+		jsonError(w, "REJECT_FAILED", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jsonSuccess(w, review)
+}
+
+// reviewID 解析路径中的审批记录ID
+func reviewID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		jsonError(w, "INVALID_REQUEST", "invalid review id", http.StatusBadRequest)
+		return 0, false
+	}
+	return uint(id), true
+}
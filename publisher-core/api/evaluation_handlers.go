@@ -0,0 +1,174 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"publisher-core/evaluation"
+
+	"github.com/gorilla/mux"
+)
+
+// EvaluationHandlers 模型离线评测处理器
+type EvaluationHandlers struct {
+	store  *evaluation.Store
+	runner *evaluation.Runner
+}
+
+// NewEvaluationHandlers 创建评测处理器
+func NewEvaluationHandlers(store *evaluation.Store, runner *evaluation.Runner) *EvaluationHandlers {
+	return &EvaluationHandlers{
+		store:  store,
+		runner: runner,
+	}
+}
+
+// RegisterRoutes 注册路由
+func (h *EvaluationHandlers) RegisterRoutes(router *mux.Router) {
+	evalRouter := router.PathPrefix("/api/v1/evaluations").Subrouter()
+
+	evalRouter.HandleFunc("/datasets", h.listDatasets).Methods("GET")
+	evalRouter.HandleFunc("/datasets", h.saveDataset).Methods("POST")
+	evalRouter.HandleFunc("/datasets/{id}", h.getDataset).Methods("GET")
+	evalRouter.HandleFunc("/datasets/{id}", h.deleteDataset).Methods("DELETE")
+
+	evalRouter.HandleFunc("/runs", h.listRuns).Methods("GET")
+	evalRouter.HandleFunc("/runs", h.startRun).Methods("POST")
+	evalRouter.HandleFunc("/runs/{id}", h.getReport).Methods("GET")
+	evalRouter.HandleFunc("/runs/{id}/results", h.getResults).Methods("GET")
+
+	evalRouter.HandleFunc("/compare", h.compare).Methods("GET")
+}
+
+// listDatasets 列出数据集
+func (h *EvaluationHandlers) listDatasets(w http.ResponseWriter, r *http.Request) {
+	datasets, err := h.store.ListDatasets()
+	if err != nil {
+		jsonError(w, "LIST_DATASETS_FAILED", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonSuccess(w, map[string]interface{}{
+		"datasets": datasets,
+		"total":    len(datasets),
+	})
+}
+
+// saveDataset 创建数据集，ID 已存在时替换其用例
+func (h *EvaluationHandlers) saveDataset(w http.ResponseWriter, r *http.Request) {
+	var ds evaluation.Dataset
+	if err := json.NewDecoder(r.Body).Decode(&ds); err != nil {
+		jsonError(w, "INVALID_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+	ds.Source = ""
+
+	if err := h.store.SaveDataset(&ds); err != nil {
+		jsonError(w, "SAVE_DATASET_FAILED", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jsonSuccess(w, ds)
+}
+
+// getDataset 获取数据集及其用例
+func (h *EvaluationHandlers) getDataset(w http.ResponseWriter, r *http.Request) {
+	ds, err := h.store.GetDataset(mux.Vars(r)["id"])
+	if err != nil {
+		jsonError(w, "DATASET_NOT_FOUND", err.Error(), http.StatusNotFound)
+		return
+	}
+
+	jsonSuccess(w, ds)
+}
+
+// deleteDataset 删除数据集
+func (h *EvaluationHandlers) deleteDataset(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.DeleteDataset(mux.Vars(r)["id"]); err != nil {
+		jsonError(w, "DELETE_DATASET_FAILED", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jsonSuccess(w, nil)
+}
+
+// listRuns 列出评测记录
+func (h *EvaluationHandlers) listRuns(w http.ResponseWriter, r *http.Request) {
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 50
+	}
+
+	runs, err := h.store.ListRuns(r.URL.Query().Get("dataset_id"), limit)
+	if err != nil {
+		jsonError(w, "LIST_RUNS_FAILED", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonSuccess(w, map[string]interface{}{
+		"runs":  runs,
+		"total": len(runs),
+	})
+}
+
+// startRun 在后台开始评测
+func (h *EvaluationHandlers) startRun(w http.ResponseWriter, r *http.Request) {
+	var req evaluation.RunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "INVALID_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.runner.Start(&req)
+	if err != nil {
+		jsonError(w, "START_RUN_FAILED", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jsonSuccess(w, run)
+}
+
+// getReport 获取评测报告，未完成时返回当前进度的汇总
+func (h *EvaluationHandlers) getReport(w http.ResponseWriter, r *http.Request) {
+	report, err := h.store.GetReport(mux.Vars(r)["id"])
+	if err != nil {
+		jsonError(w, "RUN_NOT_FOUND", err.Error(), http.StatusNotFound)
+		return
+	}
+
+	jsonSuccess(w, report)
+}
+
+// getResults 获取逐条结果
+func (h *EvaluationHandlers) getResults(w http.ResponseWriter, r *http.Request) {
+	results, err := h.store.GetResults(mux.Vars(r)["id"], r.URL.Query().Get("candidate"))
+	if err != nil {
+		jsonError(w, "GET_RESULTS_FAILED", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonSuccess(w, map[string]interface{}{
+		"results": results,
+		"total":   len(results),
+	})
+}
+
+// compare 对比同一数据集上的多次评测，run_ids 以逗号分隔
+func (h *EvaluationHandlers) compare(w http.ResponseWriter, r *http.Request) {
+	var runIDs []string
+	for _, id := range strings.Split(r.URL.Query().Get("run_ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			runIDs = append(runIDs, id)
+		}
+	}
+
+	comparison, err := h.store.CompareRuns(runIDs)
+	if err != nil {
+		jsonError(w, "COMPARE_FAILED", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jsonSuccess(w, comparison)
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"publisher-core/task"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultMetricsWindow 未指定时间范围时的统计窗口
+const defaultMetricsWindow = time.Hour
+
+// parseMetricsFilter 解析指标查询参数
+// 支持 window（如 15m、24h）或 start_time/end_time（RFC3339），以及 task_type、queue_name 过滤
+func parseMetricsFilter(query url.Values) (*task.MetricsFilter, error) {
+	filter := &task.MetricsFilter{
+		EndTime:   time.Now(),
+		TaskType:  query.Get("task_type"),
+		QueueName: query.Get("queue_name"),
+	}
+
+	if end := query.Get("end_time"); end != "" {
+		t, err := time.Parse(time.RFC3339, end)
+		if err != nil {
+			return nil, fmt.Errorf("无效的结束时间: %s", end)
+		}
+		filter.EndTime = t
+	}
+
+	window := defaultMetricsWindow
+	if w := query.Get("window"); w != "" {
+		d, err := time.ParseDuration(w)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("无效的统计窗口: %s", w)
+		}
+		window = d
+	}
+	filter.StartTime = filter.EndTime.Add(-window)
+
+	if start := query.Get("start_time"); start != "" {
+		t, err := time.Parse(time.RFC3339, start)
+		if err != nil {
+			return nil, fmt.Errorf("无效的开始时间: %s", start)
+		}
+		filter.StartTime = t
+	}
+
+	return filter, nil
+}
+
+// TaskMetricsHandler 以 Prometheus 文本格式输出任务执行指标
+func TaskMetricsHandler(stateService *task.StateService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseMetricsFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		metrics, err := stateService.GetExecutionMetrics(filter)
+		if err != nil {
+			logrus.Errorf("统计任务执行指标失败: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := metrics.WritePrometheus(w); err != nil {
+			logrus.Warnf("输出任务执行指标失败: %v", err)
+		}
+	}
+}
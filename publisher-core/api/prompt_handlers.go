@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"time"
 
 	"publisher-core/prompt"
 
@@ -15,6 +16,7 @@ import (
 type PromptTemplateHandler struct {
 	service       *prompt.Service
 	abTestService *prompt.ABTestService
+	preview       prompt.PreviewBackend
 }
 
 // NewPromptTemplateHandler 创建提示词模板处理器
@@ -25,18 +27,35 @@ func NewPromptTemplateHandler(db *gorm.DB) *PromptTemplateHandler {
 	}
 }
 
+// SetPreviewBackend 设置预览草稿时调用的模型服务，未设置时预览接口不可用
+func (h *PromptTemplateHandler) SetPreviewBackend(backend prompt.PreviewBackend) {
+	h.preview = backend
+}
+
 // RegisterRoutes 注册路由
 func (h *PromptTemplateHandler) RegisterRoutes(router *mux.Router) {
 	// 模板管理路由
 	router.HandleFunc("/api/v1/prompt-templates", h.CreateTemplate).Methods("POST")
 	router.HandleFunc("/api/v1/prompt-templates", h.ListTemplates).Methods("GET")
+	router.HandleFunc("/api/v1/prompt-templates/migrate-syntax", h.MigrateTemplateSyntax).Methods("POST")
 	router.HandleFunc("/api/v1/prompt-templates/{templateId}", h.GetTemplate).Methods("GET")
 	router.HandleFunc("/api/v1/prompt-templates/{templateId}", h.UpdateTemplate).Methods("PUT")
 	router.HandleFunc("/api/v1/prompt-templates/{templateId}", h.DeleteTemplate).Methods("DELETE")
 	router.HandleFunc("/api/v1/prompt-templates/{templateId}/render", h.RenderTemplate).Methods("POST")
 	router.HandleFunc("/api/v1/prompt-templates/{templateId}/versions", h.GetTemplateVersions).Methods("GET")
+	router.HandleFunc("/api/v1/prompt-templates/{templateId}/usage", h.GetTemplateUsage).Methods("GET")
 	router.HandleFunc("/api/v1/prompt-templates/{templateId}/versions/{version}/restore", h.RestoreTemplateVersion).Methods("POST")
 
+	// 草稿、差异、预览和灰度发布路由
+	router.HandleFunc("/api/v1/prompt-templates/{templateId}/drafts", h.CreateDraft).Methods("POST")
+	router.HandleFunc("/api/v1/prompt-templates/{templateId}/drafts/{version}", h.UpdateDraft).Methods("PUT")
+	router.HandleFunc("/api/v1/prompt-templates/{templateId}/drafts/{version}", h.DeleteDraft).Methods("DELETE")
+	router.HandleFunc("/api/v1/prompt-templates/{templateId}/diff", h.DiffVersions).Methods("GET")
+	router.HandleFunc("/api/v1/prompt-templates/{templateId}/versions/{version}/preview", h.PreviewVersion).Methods("POST")
+	router.HandleFunc("/api/v1/prompt-templates/{templateId}/versions/{version}/publish", h.PublishVersion).Methods("POST")
+	router.HandleFunc("/api/v1/prompt-templates/{templateId}/versions/{version}/rollout", h.StartRollout).Methods("POST")
+	router.HandleFunc("/api/v1/prompt-templates/{templateId}/rollout", h.StopRollout).Methods("DELETE")
+
 	// A/B测试路由
 	router.HandleFunc("/api/v1/prompt-ab-tests", h.CreateABTest).Methods("POST")
 	router.HandleFunc("/api/v1/prompt-ab-tests", h.ListABTests).Methods("GET")
@@ -44,6 +63,8 @@ func (h *PromptTemplateHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/v1/prompt-ab-tests/{testId}", h.UpdateABTest).Methods("PUT")
 	router.HandleFunc("/api/v1/prompt-ab-tests/{testId}/complete", h.CompleteABTest).Methods("POST")
 	router.HandleFunc("/api/v1/prompt-ab-tests/{testId}/stats", h.GetABTestStats).Methods("GET")
+	router.HandleFunc("/api/v1/prompt-ab-tests/{testId}/assign", h.AssignABTest).Methods("POST")
+	router.HandleFunc("/api/v1/prompt-ab-tests/{testId}/outcomes", h.RecordABTestOutcome).Methods("POST")
 }
 
 // CreateTemplate 创建模板
@@ -148,7 +169,8 @@ func (h *PromptTemplateHandler) DeleteTemplate(w http.ResponseWriter, r *http.Re
 	promptRespondWithJSON(w, http.StatusOK, map[string]string{"message": "删除成功"})
 }
 
-// RenderTemplate 渲染模板
+// RenderTemplate 渲染模板，返回可直接发送的生成请求
+// content 为最后一条消息的内容，兼容只使用单条提示词的调用方
 func (h *PromptTemplateHandler) RenderTemplate(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	templateID := vars["templateId"]
@@ -161,13 +183,45 @@ func (h *PromptTemplateHandler) RenderTemplate(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	rendered, err := h.service.RenderTemplate(templateID, req.Variables)
+	opts, err := h.service.RenderToGenerateOptions(templateID, req.Variables)
+	if err != nil {
+		promptRespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	promptRespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"content": opts.Messages[len(opts.Messages)-1].Content,
+		"options": opts,
+	})
+}
+
+// MigrateTemplateSyntax 把旧版 {{name}} 占位符的模板迁移到新语法
+func (h *PromptTemplateHandler) MigrateTemplateSyntax(w http.ResponseWriter, r *http.Request) {
+	migrated, err := h.service.MigrateLegacyTemplates()
+	if err != nil {
+		promptRespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	promptRespondWithJSON(w, http.StatusOK, map[string]int{"migrated": migrated})
+}
+
+// GetTemplateUsage 获取模板使用统计，days 指定统计最近几天，默认统计全部记录
+func (h *PromptTemplateHandler) GetTemplateUsage(w http.ResponseWriter, r *http.Request) {
+	templateID := mux.Vars(r)["templateId"]
+
+	var since time.Time
+	if days, _ := strconv.Atoi(r.URL.Query().Get("days")); days > 0 {
+		since = time.Now().AddDate(0, 0, -days)
+	}
+
+	stats, err := h.service.GetUsageStats(templateID, since)
 	if err != nil {
 		promptRespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	promptRespondWithJSON(w, http.StatusOK, map[string]string{"content": rendered})
+	promptRespondWithJSON(w, http.StatusOK, stats)
 }
 
 // GetTemplateVersions 获取模板版本历史
@@ -223,6 +277,183 @@ func (h *PromptTemplateHandler) RestoreTemplateVersion(w http.ResponseWriter, r
 	promptRespondWithJSON(w, http.StatusOK, template)
 }
 
+// versionParam 解析路径中的版本号
+func versionParam(r *http.Request) (string, int, error) {
+	vars := mux.Vars(r)
+	version, err := strconv.Atoi(vars["version"])
+	return vars["templateId"], version, err
+}
+
+// CreateDraft 创建草稿版本
+func (h *PromptTemplateHandler) CreateDraft(w http.ResponseWriter, r *http.Request) {
+	templateID := mux.Vars(r)["templateId"]
+
+	var req prompt.DraftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	draft, err := h.service.CreateDraft(templateID, &req)
+	if err != nil {
+		promptRespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	promptRespondWithJSON(w, http.StatusCreated, draft)
+}
+
+// UpdateDraft 修改草稿
+func (h *PromptTemplateHandler) UpdateDraft(w http.ResponseWriter, r *http.Request) {
+	templateID, version, err := versionParam(r)
+	if err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, "无效的版本号")
+		return
+	}
+
+	var req prompt.DraftRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	draft, err := h.service.UpdateDraft(templateID, version, &req)
+	if err != nil {
+		promptRespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	promptRespondWithJSON(w, http.StatusOK, draft)
+}
+
+// DeleteDraft 删除草稿
+func (h *PromptTemplateHandler) DeleteDraft(w http.ResponseWriter, r *http.Request) {
+	templateID, version, err := versionParam(r)
+	if err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, "无效的版本号")
+		return
+	}
+
+	if err := h.service.DeleteDraft(templateID, version); err != nil {
+		promptRespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	promptRespondWithJSON(w, http.StatusOK, map[string]string{"message": "草稿已删除"})
+}
+
+// DiffVersions 比较两个版本，参数 from 和 to 为版本号
+func (h *PromptTemplateHandler) DiffVersions(w http.ResponseWriter, r *http.Request) {
+	templateID := mux.Vars(r)["templateId"]
+
+	query := r.URL.Query()
+	from, err := strconv.Atoi(query.Get("from"))
+	if err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, "无效的起始版本号")
+		return
+	}
+	to, err := strconv.Atoi(query.Get("to"))
+	if err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, "无效的目标版本号")
+		return
+	}
+
+	diff, err := h.service.DiffVersions(templateID, from, to)
+	if err != nil {
+		promptRespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	promptRespondWithJSON(w, http.StatusOK, diff)
+}
+
+// PreviewVersion 用示例变量渲染版本并调用模型生成
+func (h *PromptTemplateHandler) PreviewVersion(w http.ResponseWriter, r *http.Request) {
+	if h.preview == nil {
+		promptRespondWithError(w, http.StatusServiceUnavailable, "AI服务未配置")
+		return
+	}
+
+	templateID, version, err := versionParam(r)
+	if err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, "无效的版本号")
+		return
+	}
+
+	var req prompt.PreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	samples, err := h.service.PreviewVersion(r.Context(), h.preview, templateID, version, &req)
+	if err != nil {
+		promptRespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	promptRespondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"template_id": templateID,
+		"version":     version,
+		"samples":     samples,
+	})
+}
+
+// PublishVersion 发布草稿或灰度中的版本
+func (h *PromptTemplateHandler) PublishVersion(w http.ResponseWriter, r *http.Request) {
+	templateID, version, err := versionParam(r)
+	if err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, "无效的版本号")
+		return
+	}
+
+	template, err := h.service.PublishVersion(templateID, version)
+	if err != nil {
+		promptRespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	promptRespondWithJSON(w, http.StatusOK, template)
+}
+
+// StartRollout 按比例灰度发布版本，请求体 {"percent": 10}
+func (h *PromptTemplateHandler) StartRollout(w http.ResponseWriter, r *http.Request) {
+	templateID, version, err := versionParam(r)
+	if err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, "无效的版本号")
+		return
+	}
+
+	var req struct {
+		Percent int `json:"percent"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	template, err := h.service.StartRollout(templateID, version, req.Percent)
+	if err != nil {
+		promptRespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	promptRespondWithJSON(w, http.StatusOK, template)
+}
+
+// StopRollout 停止灰度，全部流量回到线上版本
+func (h *PromptTemplateHandler) StopRollout(w http.ResponseWriter, r *http.Request) {
+	templateID := mux.Vars(r)["templateId"]
+
+	template, err := h.service.StopRollout(templateID)
+	if err != nil {
+		promptRespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	promptRespondWithJSON(w, http.StatusOK, template)
+}
+
 // CreateABTest 创建A/B测试
 func (h *PromptTemplateHandler) CreateABTest(w http.ResponseWriter, r *http.Request) {
 	var req prompt.CreateABTestRequest
@@ -328,14 +559,17 @@ func (h *PromptTemplateHandler) CompleteABTest(w http.ResponseWriter, r *http.Re
 	}
 
 	var req struct {
+		// WinnerTemplateID 为空时按统计结果判定
 		WinnerTemplateID string `json:"winner_template_id"`
+		// Promote 把获胜模板设为同类型的默认模板
+		Promote bool `json:"promote"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		promptRespondWithError(w, http.StatusBadRequest, "无效的请求参数")
 		return
 	}
 
-	if err := h.abTestService.CompleteABTest(uint(testID), req.WinnerTemplateID); err != nil {
+	if err := h.abTestService.CompleteABTest(uint(testID), req.WinnerTemplateID, req.Promote); err != nil {
 		promptRespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -363,6 +597,63 @@ func (h *PromptTemplateHandler) GetABTestStats(w http.ResponseWriter, r *http.Re
 	promptRespondWithJSON(w, http.StatusOK, stats)
 }
 
+// AssignABTest 为用户或会话分配测试变体
+func (h *PromptTemplateHandler) AssignABTest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	testIDStr := vars["testId"]
+
+	testID, err := strconv.ParseUint(testIDStr, 10, 32)
+	if err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, "无效的测试ID")
+		return
+	}
+
+	var req struct {
+		UnitID string `json:"unit_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	templateID, err := h.abTestService.SelectTemplateForTest(uint(testID), req.UnitID)
+	if err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	promptRespondWithJSON(w, http.StatusOK, map[string]string{"unit_id": req.UnitID, "template_id": templateID})
+}
+
+// RecordABTestOutcome 记录测试结果，如用户评分、发布互动率或审核结果
+func (h *PromptTemplateHandler) RecordABTestOutcome(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	testIDStr := vars["testId"]
+
+	testID, err := strconv.ParseUint(testIDStr, 10, 32)
+	if err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, "无效的测试ID")
+		return
+	}
+
+	var req struct {
+		UnitID string            `json:"unit_id"`
+		Metric prompt.GoalMetric `json:"metric"`
+		Value  float64           `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, "无效的请求参数")
+		return
+	}
+
+	if err := h.abTestService.RecordOutcome(uint(testID), req.UnitID, req.Metric, req.Value); err != nil {
+		promptRespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	promptRespondWithJSON(w, http.StatusOK, map[string]string{"message": "结果已记录"})
+}
+
 // promptRespondWithError 返回错误响应
 func promptRespondWithError(w http.ResponseWriter, code int, message string) {
 	promptRespondWithJSON(w, code, map[string]string{"error": message})
@@ -12,6 +12,8 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/sirupsen/logrus"
 	"publisher-core/config"
+	"publisher-core/cost"
+	"publisher-core/task"
 )
 
 type Server struct {
@@ -20,6 +22,9 @@ type Server struct {
 	publisher   PublisherAPI
 	storage     StorageAPI
 	ai          AIServiceAPI
+	vector      VectorSearchAPI
+	taskQueue   *task.QueueService
+	taskState   *task.StateService
 	middleware  []Middleware
 	server      *http.Server
 	security    *config.SecurityConfig
@@ -71,6 +76,7 @@ func NewServer(taskManager TaskManagerAPI, publisher PublisherAPI, storage Stora
 
 func (s *Server) setupRoutes() {
 	s.router.Use(LoggingMiddleware)
+	s.router.Use(CostAttributionMiddleware)
 	s.router.Use(CORSMiddleware(
 		s.security.AllowedOrigins,
 		s.security.AllowedMethods,
@@ -88,6 +94,7 @@ func (s *Server) setupRoutes() {
 	tasksRouter := apiRouter.PathPrefix("/tasks").Subrouter()
 	tasksRouter.HandleFunc("", s.createTask).Methods("POST")
 	tasksRouter.HandleFunc("", s.listTasks).Methods("GET")
+	s.setupTaskQueueRoutes(tasksRouter)
 	tasksRouter.HandleFunc("/{id}", s.getTask).Methods("GET")
 	tasksRouter.HandleFunc("/{id}/cancel", s.cancelTask).Methods("POST")
 
@@ -105,10 +112,7 @@ func (s *Server) setupRoutes() {
 
 	// 注册 AI 内容生成相关路由
 	s.setupAIRoutes()
-
-	cacheRouter := apiRouter.PathPrefix("/cache").Subrouter()
-	cacheRouter.HandleFunc("/stats", s.getCacheStats).Methods("GET")
-	cacheRouter.HandleFunc("/clear", s.clearCache).Methods("POST")
+	s.setupVectorRoutes()
 
 	fs := http.FileServer(http.Dir("static"))
 	s.router.PathPrefix("/static/").Handler(http.StripPrefix("/static/", fs))
@@ -164,6 +168,11 @@ func (s *Server) detailedHealthCheck(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) createTask(w http.ResponseWriter, r *http.Request) {
+	if s.taskQueue != nil {
+		s.submitQueueTask(w, r)
+		return
+	}
+
 	var req struct {
 		TaskType string                 `json:"task_type"`
 		Platform string                 `json:"platform"`
@@ -350,20 +359,6 @@ func (s *Server) analyzeContent(w http.ResponseWriter, r *http.Request) {
 	jsonSuccess(w, result)
 }
 
-func (s *Server) getCacheStats(w http.ResponseWriter, r *http.Request) {
-	jsonSuccess(w, map[string]interface{}{
-		"hits":   0,
-		"misses": 0,
-		"size":   0,
-	})
-}
-
-func (s *Server) clearCache(w http.ResponseWriter, r *http.Request) {
-	jsonSuccess(w, map[string]interface{}{
-		"message": "Cache cleared",
-	})
-}
-
 func jsonSuccess(w http.ResponseWriter, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -385,6 +380,18 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// CostAttributionMiddleware 从请求头读取用户、项目和请求ID，用于 AI 调用的成本归属
+func CostAttributionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := cost.WithAttribution(r.Context(), cost.Attribution{
+			UserID:    r.Header.Get("X-User-ID"),
+			ProjectID: r.Header.Get("X-Project-ID"),
+			RequestID: r.Header.Get("X-Request-ID"),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 func CORSMiddleware(allowedOrigins []string, allowedMethods []string, allowedHeaders []string, allowCredentials bool, maxAge int) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -1,9 +1,13 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
+	"publisher-core/cost"
 	"publisher-core/database"
 	"publisher-core/task"
 
@@ -32,6 +36,7 @@ func (api *TaskAPI) RegisterRoutes(r *gin.RouterGroup) {
 	{
 		// 任务管理
 		tasks.POST("", api.SubmitTask)
+		tasks.POST("/workflows", api.SubmitWorkflow)
 		tasks.GET("/:task_id", api.GetTask)
 		tasks.DELETE("/:task_id", api.CancelTask)
 		tasks.GET("", api.ListTasks)
@@ -40,8 +45,12 @@ func (api *TaskAPI) RegisterRoutes(r *gin.RouterGroup) {
 		tasks.GET("/:task_id/progress", api.GetProgress)
 		tasks.GET("/:task_id/result", api.GetResult)
 
+		// 工作流
+		tasks.GET("/:task_id/children", api.GetChildTasks)
+
 		// 统计
 		tasks.GET("/statistics", api.GetStatistics)
+		tasks.GET("/metrics", api.GetExecutionMetrics)
 	}
 
 	queues := r.Group("/queues")
@@ -50,6 +59,13 @@ func (api *TaskAPI) RegisterRoutes(r *gin.RouterGroup) {
 		queues.POST("", api.CreateQueue)
 	}
 
+	limits := r.Group("/rate-limits")
+	{
+		limits.GET("", api.ListRateLimits)
+		limits.PUT("", api.SetRateLimit)
+		limits.DELETE("/:id", api.DeleteRateLimit)
+	}
+
 	scheduled := r.Group("/scheduled-tasks")
 	{
 		scheduled.POST("", api.CreateScheduledTask)
@@ -72,26 +88,86 @@ func (api *TaskAPI) SubmitTask(c *gin.Context) {
 		return
 	}
 
-	// 设置默认值
-	if req.QueueName == "" {
-		req.QueueName = "default"
+	setTaskRequestDefaults(c.Request.Context(), &req)
+
+	submitted, duplicate, err := api.queueService.SubmitTask(c.Request.Context(), &req)
+	if err != nil {
+		c.JSON(submitErrorStatus(err), gin.H{"error": err.Error()})
+		return
 	}
-	if req.Priority == 0 {
-		req.Priority = database.PriorityNormal
+
+	// 幂等键命中时返回已有任务
+	if duplicate {
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "任务已存在",
+			"task":      submitted,
+			"duplicate": true,
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "任务已提交",
+		"task":    submitted,
+	})
+}
+
+// SubmitWorkflow 提交父子任务工作流
+func (api *TaskAPI) SubmitWorkflow(c *gin.Context) {
+	var req struct {
+		Parent   task.TaskRequest    `json:"parent"`
+		Children []*task.TaskRequest `json:"children"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	task, err := api.queueService.SubmitTask(c.Request.Context(), &req)
+	setTaskRequestDefaults(c.Request.Context(), &req.Parent)
+	for _, child := range req.Children {
+		setTaskRequestDefaults(c.Request.Context(), child)
+	}
+
+	parent, children, err := api.queueService.SubmitWorkflow(c.Request.Context(), &req.Parent, req.Children)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(submitErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "任务已提交",
-		"task":    task,
+		"message":  "工作流已提交",
+		"parent":   parent,
+		"children": children,
 	})
 }
 
+// submitErrorStatus 提交任务失败时的 HTTP 状态码
+// 内容重复返回 409，幂等键被内容不同的请求复用返回 422
+func submitErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, task.ErrDuplicateContent):
+		return http.StatusConflict
+	case errors.Is(err, task.ErrIdempotencyConflict):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, task.ErrInvalidTaskRequest):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// setTaskRequestDefaults 设置任务请求默认值
+// 用户ID取自请求上下文中的成本归属，忽略请求体中的 user_id，避免冒用其他用户的幂等键
+func setTaskRequestDefaults(ctx context.Context, req *task.TaskRequest) {
+	req.UserID = cost.AttributionFromContext(ctx).UserID
+	if req.QueueName == "" {
+		req.QueueName = "default"
+	}
+	if req.Priority == 0 {
+		req.Priority = database.PriorityNormal
+	}
+}
+
 // GetTask 获取任务
 func (api *TaskAPI) GetTask(c *gin.Context) {
 	taskID := c.Param("task_id")
@@ -173,6 +249,22 @@ func (api *TaskAPI) GetResult(c *gin.Context) {
 	c.JSON(http.StatusOK, result)
 }
 
+// GetChildTasks 获取子任务
+func (api *TaskAPI) GetChildTasks(c *gin.Context) {
+	taskID := c.Param("task_id")
+
+	children, err := api.queueService.GetChildTasks(taskID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"parent_task_id": taskID,
+		"children":       children,
+	})
+}
+
 // GetStatistics 获取任务统计
 func (api *TaskAPI) GetStatistics(c *gin.Context) {
 	// 解析时间范围
@@ -199,6 +291,28 @@ func (api *TaskAPI) GetStatistics(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
+// GetExecutionMetrics 获取任务执行指标，包括吞吐量、成功率、耗时和排队等待分位数
+func (api *TaskAPI) GetExecutionMetrics(c *gin.Context) {
+	filter, err := parseMetricsFilter(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	metrics, err := api.stateService.GetExecutionMetrics(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, metrics)
+}
+
+// RegisterMetricsRoute 注册 Prometheus 指标路由
+func (api *TaskAPI) RegisterMetricsRoute(r gin.IRouter) {
+	r.GET("/metrics", gin.WrapF(TaskMetricsHandler(api.stateService)))
+}
+
 // GetQueueStats 获取队列统计
 func (api *TaskAPI) GetQueueStats(c *gin.Context) {
 	queueName := c.Param("queue_name")
@@ -239,6 +353,66 @@ func (api *TaskAPI) CreateQueue(c *gin.Context) {
 	})
 }
 
+// ListRateLimits 列出限流规则
+func (api *TaskAPI) ListRateLimits(c *gin.Context) {
+	rules, err := api.queueService.ListRateLimits()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+// SetRateLimit 创建或更新限流规则
+func (api *TaskAPI) SetRateLimit(c *gin.Context) {
+	var req struct {
+		Scope       string `json:"scope" binding:"required"`
+		Key         string `json:"key" binding:"required"`
+		MaxCount    int    `json:"max_count"`
+		Period      int    `json:"period"`
+		MinInterval int    `json:"min_interval"`
+		IsActive    *bool  `json:"is_active"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := &database.TaskRateLimit{
+		Scope:       req.Scope,
+		Key:         req.Key,
+		MaxCount:    req.MaxCount,
+		Period:      req.Period,
+		MinInterval: req.MinInterval,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+	}
+
+	if err := api.queueService.SetRateLimit(rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRateLimit 删除限流规则
+func (api *TaskAPI) DeleteRateLimit(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的规则ID"})
+		return
+	}
+
+	if err := api.queueService.DeleteRateLimit(uint(id)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "限流规则已删除"})
+}
+
 // CreateScheduledTask 创建定时任务
 func (api *TaskAPI) CreateScheduledTask(c *gin.Context) {
 	var req task.ScheduledTaskRequest
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"publisher-core/database"
+	"publisher-core/task"
+)
+
+// WithTaskQueue 设置持久化任务队列
+// 设置后创建任务支持幂等键、优先级等完整参数，并开放工作流、限流规则和执行指标接口
+func (s *Server) WithTaskQueue(queue *task.QueueService, state *task.StateService) *Server {
+	s.taskQueue = queue
+	s.taskState = state
+	return s
+}
+
+// setupTaskQueueRoutes 注册任务队列路由
+// 需在 /tasks/{id} 之前注册，避免 workflows、metrics 等路径被当作任务ID
+func (s *Server) setupTaskQueueRoutes(tasksRouter *mux.Router) {
+	tasksRouter.HandleFunc("/workflows", s.submitWorkflow).Methods("POST")
+	tasksRouter.HandleFunc("/metrics", s.taskExecutionMetrics).Methods("GET")
+	tasksRouter.HandleFunc("/{id}/children", s.childTasks).Methods("GET")
+
+	s.router.HandleFunc("/api/v1/rate-limits", s.listRateLimits).Methods("GET")
+	s.router.HandleFunc("/api/v1/rate-limits", s.setRateLimit).Methods("PUT")
+	s.router.HandleFunc("/api/v1/rate-limits/{id}", s.deleteRateLimit).Methods("DELETE")
+}
+
+// requireTaskQueue 检查任务队列是否已设置
+func (s *Server) requireTaskQueue(w http.ResponseWriter) bool {
+	if s.taskQueue == nil || s.taskState == nil {
+		jsonError(w, "SERVICE_UNAVAILABLE", "Task queue not initialized", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// submitQueueTask 提交任务到队列
+// 幂等键命中时返回已有任务；内容重复返回 409，幂等键被内容不同的请求复用返回 422
+func (s *Server) submitQueueTask(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		task.TaskRequest
+		Platform string `json:"platform"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "INVALID_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.TaskType == "" {
+		jsonError(w, "INVALID_REQUEST", "task_type is required", http.StatusBadRequest)
+		return
+	}
+	if req.Platform != "" {
+		if req.Payload == nil {
+			req.Payload = make(map[string]interface{})
+		}
+		if _, exists := req.Payload["platform"]; !exists {
+			req.Payload["platform"] = req.Platform
+		}
+	}
+	setTaskRequestDefaults(r.Context(), &req.TaskRequest)
+
+	submitted, duplicate, err := s.taskQueue.SubmitTask(r.Context(), &req.TaskRequest)
+	if err != nil {
+		taskSubmitError(w, err)
+		return
+	}
+
+	// 与 TaskManagerAPI 创建任务的响应字段保持一致
+	var payload map[string]interface{}
+	if submitted.Payload != "" {
+		json.Unmarshal([]byte(submitted.Payload), &payload)
+	}
+	platform, _ := payload["platform"].(string)
+	jsonSuccess(w, map[string]interface{}{
+		"id":         submitted.TaskID,
+		"type":       submitted.TaskType,
+		"status":     submitted.Status,
+		"platform":   platform,
+		"payload":    payload,
+		"progress":   submitted.Progress,
+		"created_at": submitted.CreatedAt,
+		"duplicate":  duplicate,
+	})
+}
+
+// submitWorkflow 提交父子任务工作流
+// POST /api/v1/tasks/workflows
+func (s *Server) submitWorkflow(w http.ResponseWriter, r *http.Request) {
+	if !s.requireTaskQueue(w) {
+		return
+	}
+
+	var req struct {
+		Parent   task.TaskRequest    `json:"parent"`
+		Children []*task.TaskRequest `json:"children"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "INVALID_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	setTaskRequestDefaults(r.Context(), &req.Parent)
+	for _, child := range req.Children {
+		if child == nil {
+			jsonError(w, "INVALID_REQUEST", "children must not contain null", http.StatusBadRequest)
+			return
+		}
+		setTaskRequestDefaults(r.Context(), child)
+	}
+
+	parent, children, err := s.taskQueue.SubmitWorkflow(r.Context(), &req.Parent, req.Children)
+	if err != nil {
+		taskSubmitError(w, err)
+		return
+	}
+
+	jsonSuccess(w, map[string]interface{}{
+		"parent":   parent,
+		"children": children,
+	})
+}
+
+// taskSubmitError 按提交失败原因返回错误
+func taskSubmitError(w http.ResponseWriter, err error) {
+	status := submitErrorStatus(err)
+	switch status {
+	case http.StatusConflict:
+		jsonError(w, "DUPLICATE_CONTENT", err.Error(), status)
+	case http.StatusUnprocessableEntity:
+		jsonError(w, "IDEMPOTENCY_CONFLICT", err.Error(), status)
+	case http.StatusBadRequest:
+		jsonError(w, "INVALID_REQUEST", err.Error(), status)
+	default:
+		jsonError(w, "CREATE_TASK_FAILED", err.Error(), status)
+	}
+}
+
+// childTasks 获取工作流的子任务
+// GET /api/v1/tasks/{id}/children
+func (s *Server) childTasks(w http.ResponseWriter, r *http.Request) {
+	if !s.requireTaskQueue(w) {
+		return
+	}
+
+	taskID := mux.Vars(r)["id"]
+	children, err := s.taskQueue.GetChildTasks(taskID)
+	if err != nil {
+		jsonError(w, "LIST_TASKS_FAILED", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonSuccess(w, map[string]interface{}{
+		"parent_task_id": taskID,
+		"children":       children,
+	})
+}
+
+// taskExecutionMetrics 获取任务执行指标，包括吞吐量、成功率、耗时和排队等待分位数
+// GET /api/v1/tasks/metrics?window=1h&task_type=publish&queue_name=default
+func (s *Server) taskExecutionMetrics(w http.ResponseWriter, r *http.Request) {
+	if !s.requireTaskQueue(w) {
+		return
+	}
+
+	filter, err := parseMetricsFilter(r.URL.Query())
+	if err != nil {
+		jsonError(w, "INVALID_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	metrics, err := s.taskState.GetExecutionMetrics(filter)
+	if err != nil {
+		jsonError(w, "METRICS_FAILED", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonSuccess(w, metrics)
+}
+
+// listRateLimits 列出限流规则
+// GET /api/v1/rate-limits
+func (s *Server) listRateLimits(w http.ResponseWriter, r *http.Request) {
+	if !s.requireTaskQueue(w) {
+		return
+	}
+
+	rules, err := s.taskQueue.ListRateLimits()
+	if err != nil {
+		jsonError(w, "LIST_RATE_LIMITS_FAILED", err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jsonSuccess(w, rules)
+}
+
+// setRateLimit 创建或更新限流规则
+// PUT /api/v1/rate-limits
+func (s *Server) setRateLimit(w http.ResponseWriter, r *http.Request) {
+	if !s.requireTaskQueue(w) {
+		return
+	}
+
+	var req struct {
+		Scope       string `json:"scope"`
+		Key         string `json:"key"`
+		MaxCount    int    `json:"max_count"`
+		Period      int    `json:"period"`
+		MinInterval int    `json:"min_interval"`
+		IsActive    *bool  `json:"is_active"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		jsonError(w, "INVALID_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Scope == "" || req.Key == "" {
+		jsonError(w, "INVALID_REQUEST", "scope and key are required", http.StatusBadRequest)
+		return
+	}
+
+	rule := &database.TaskRateLimit{
+		Scope:       req.Scope,
+		Key:         req.Key,
+		MaxCount:    req.MaxCount,
+		Period:      req.Period,
+		MinInterval: req.MinInterval,
+		IsActive:    req.IsActive == nil || *req.IsActive,
+	}
+	if err := s.taskQueue.SetRateLimit(rule); err != nil {
+		jsonError(w, "INVALID_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	jsonSuccess(w, rule)
+}
+
+// deleteRateLimit 删除限流规则
+// DELETE /api/v1/rate-limits/{id}
+func (s *Server) deleteRateLimit(w http.ResponseWriter, r *http.Request) {
+	if !s.requireTaskQueue(w) {
+		return
+	}
+
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		jsonError(w, "INVALID_REQUEST", "invalid rate limit id", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.taskQueue.DeleteRateLimit(uint(id)); err != nil {
+		jsonError(w, "RATE_LIMIT_NOT_FOUND", err.Error(), http.StatusNotFound)
+		return
+	}
+
+	jsonSuccess(w, map[string]interface{}{
+		"message": "Rate limit deleted",
+		"id":      id,
+	})
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"publisher-core/vector"
+)
+
+// VectorSearchAPI 按语义相似度检索已索引的内容
+type VectorSearchAPI interface {
+	Search(ctx context.Context, namespace, text string, k int) ([]vector.Match, error)
+}
+
+// WithVectorSearch 设置向量检索服务
+func (s *Server) WithVectorSearch(v VectorSearchAPI) *Server {
+	s.vector = v
+	return s
+}
+
+func (s *Server) setupVectorRoutes() {
+	s.router.HandleFunc("/api/v1/vector/search", s.vectorSearch).Methods("GET")
+}
+
+// vectorSearch 检索与查询文本相似的条目
+// GET /api/v1/vector/search?namespace=topics&q=...&k=10
+func (s *Server) vectorSearch(w http.ResponseWriter, r *http.Request) {
+	if s.vector == nil {
+		jsonError(w, "SERVICE_UNAVAILABLE", "Vector search not initialized", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+	text := query.Get("q")
+	if text == "" {
+		jsonError(w, "INVALID_REQUEST", "q is required", http.StatusBadRequest)
+		return
+	}
+	namespace := query.Get("namespace")
+	if namespace == "" {
+		namespace = vector.NamespaceTopics
+	}
+	k := 10
+	if v, err := strconv.Atoi(query.Get("k")); err == nil && v > 0 && v <= 100 {
+		k = v
+	}
+
+	matches, err := s.vector.Search(r.Context(), namespace, text, k)
+	if err != nil {
+		aiError(w, err)
+		return
+	}
+
+	jsonSuccess(w, map[string]interface{}{
+		"namespace": namespace,
+		"matches":   matches,
+		"count":     len(matches),
+	})
+}
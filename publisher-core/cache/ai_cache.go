@@ -3,12 +3,17 @@ package cache
 import (
 	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 )
 
 // AICacheService AI缓存服务
 type AICacheService struct {
 	cache *MemoryCache
+
+	mu            sync.RWMutex
+	semantic      *semanticIndex                 // 语义缓存，未启用时为空
+	functionStats map[string]*FunctionCacheStats // 按功能类型的命中统计
 }
 
 // NewAICacheService 创建AI缓存服务
@@ -175,6 +180,14 @@ func (s *AICacheService) GetStats() CacheStats {
 // Clear 清空所有缓存
 func (s *AICacheService) Clear() {
 	s.cache.Clear()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.semantic != nil {
+		s.semantic.mu.Lock()
+		s.semantic.scopes = make(map[string][]*semanticEntry)
+		s.semantic.mu.Unlock()
+	}
 }
 
 // Warmup 预热缓存
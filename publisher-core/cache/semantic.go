@@ -0,0 +1,396 @@
+package cache
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Embedder 将文本转换为向量，用于语义缓存的相似度计算
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// EmbedderFunc 将函数适配为 Embedder，便于接入支持向量接口的提供商
+type EmbedderFunc func(ctx context.Context, text string) ([]float32, error)
+
+// Embed 实现 Embedder
+func (f EmbedderFunc) Embed(ctx context.Context, text string) ([]float32, error) {
+	return f(ctx, text)
+}
+
+// NGramEmbedder 本地哈希 n-gram 向量
+// 中文按单字和相邻二字切分，英文按单词和字符三元组切分，再哈希到固定维度。
+// 不需要调用外部模型，适合没有向量接口的提供商
+type NGramEmbedder struct {
+	dim int
+}
+
+// NewNGramEmbedder 创建本地 n-gram 向量生成器，dim 为 0 时使用 512 维
+func NewNGramEmbedder(dim int) *NGramEmbedder {
+	if dim <= 0 {
+		dim = 512
+	}
+	return &NGramEmbedder{dim: dim}
+}
+
+// Embed 生成归一化的 n-gram 向量
+func (e *NGramEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	vector := make([]float32, e.dim)
+	add := func(gram string, weight float32) {
+		h := fnv.New32a()
+		h.Write([]byte(gram))
+		sum := h.Sum32()
+		// 用哈希的最高位决定符号，减少哈希冲突带来的偏差
+		if sum&0x80000000 != 0 {
+			weight = -weight
+		}
+		vector[int(sum%uint32(e.dim))] += weight
+	}
+
+	var prev rune
+	var word []rune
+	flushWord := func() {
+		if len(word) == 0 {
+			return
+		}
+		add("w:"+string(word), 1)
+		padded := append(append([]rune{'#'}, word...), '#')
+		for i := 0; i+3 <= len(padded); i++ {
+			add("t:"+string(padded[i:i+3]), 0.5)
+		}
+		word = word[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flushWord()
+			add("c:"+string(r), 1)
+			if prev != 0 {
+				add("b:"+string([]rune{prev, r}), 1.5)
+			}
+			prev = r
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			prev = 0
+			word = append(word, r)
+		default:
+			prev = 0
+			flushWord()
+		}
+	}
+	flushWord()
+
+	normalize(vector)
+	return vector, nil
+}
+
+// normalize 将向量归一化为单位长度
+func normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+}
+
+// cosine 计算两个向量的余弦相似度
+func cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// SemanticCacheConfig 语义缓存配置
+type SemanticCacheConfig struct {
+	// DefaultThreshold 未单独配置的功能类型使用的相似度阈值，>= 1 表示只做精确匹配
+	DefaultThreshold float64 `json:"default_threshold"`
+	// Thresholds 按功能类型的相似度阈值，>= 1 表示该功能只做精确匹配
+	Thresholds map[string]float64 `json:"thresholds"`
+	// MaxEntries 每个功能、提供商和模型组合最多保留的条目数
+	MaxEntries int `json:"max_entries"`
+	// TTL 缓存有效期
+	TTL time.Duration `json:"ttl"`
+}
+
+// DefaultSemanticCacheConfig 默认语义缓存配置
+// 语义匹配按功能类型启用：只有对相近问题可以复用结果的分析类功能配置了阈值，
+// 生成、改写、审核等对输入细节敏感的功能只做精确匹配
+func DefaultSemanticCacheConfig() *SemanticCacheConfig {
+	return &SemanticCacheConfig{
+		DefaultThreshold: 1,
+		Thresholds: map[string]float64{
+			"hotspot_analysis":   0.90,
+			"sentiment_analysis": 0.90,
+			"content_suggestion": 0.92,
+		},
+		MaxEntries: 1000,
+		TTL:        6 * time.Hour,
+	}
+}
+
+// threshold 返回功能类型的相似度阈值
+func (c *SemanticCacheConfig) threshold(function string) float64 {
+	if t, ok := c.Thresholds[function]; ok {
+		return t
+	}
+	return c.DefaultThreshold
+}
+
+// CacheMatchType 缓存命中方式
+type CacheMatchType string
+
+const (
+	CacheMatchExact    CacheMatchType = "exact"
+	CacheMatchSemantic CacheMatchType = "semantic"
+)
+
+// CacheMatch 缓存命中信息
+type CacheMatch struct {
+	Type       CacheMatchType `json:"type"`
+	Similarity float64        `json:"similarity"`
+}
+
+// FunctionCacheStats 按功能类型的缓存命中统计
+type FunctionCacheStats struct {
+	Function     string  `json:"function"`
+	ExactHits    int64   `json:"exact_hits"`
+	SemanticHits int64   `json:"semantic_hits"`
+	Misses       int64   `json:"misses"`
+	HitRate      float64 `json:"hit_rate"` // 命中率（%）
+}
+
+// semanticEntry 语义缓存条目
+type semanticEntry struct {
+	vector    []float32
+	value     *AICacheValue
+	expiresAt time.Time
+}
+
+// semanticIndex 按作用域保存的向量索引，条目数有限，线性扫描即可
+type semanticIndex struct {
+	mu       sync.RWMutex
+	embedder Embedder
+	config   *SemanticCacheConfig
+	scopes   map[string][]*semanticEntry
+}
+
+// search 返回作用域内相似度最高的未过期条目
+func (idx *semanticIndex) search(scope string, vector []float32) (*semanticEntry, float64) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	now := time.Now()
+	var best *semanticEntry
+	bestScore := 0.0
+	for _, entry := range idx.scopes[scope] {
+		if now.After(entry.expiresAt) {
+			continue
+		}
+		if score := cosine(vector, entry.vector); score > bestScore {
+			best, bestScore = entry, score
+		}
+	}
+	return best, bestScore
+}
+
+// add 添加条目，超出上限时先清理过期条目再淘汰最早的条目
+func (idx *semanticIndex) add(scope string, entry *semanticEntry) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	entries := idx.scopes[scope]
+	if idx.config.MaxEntries > 0 && len(entries) >= idx.config.MaxEntries {
+		now := time.Now()
+		alive := entries[:0]
+		for _, e := range entries {
+			if now.Before(e.expiresAt) {
+				alive = append(alive, e)
+			}
+		}
+		entries = alive
+		if len(entries) >= idx.config.MaxEntries {
+			entries = entries[len(entries)-idx.config.MaxEntries+1:]
+		}
+	}
+	idx.scopes[scope] = append(entries, entry)
+}
+
+// EnableSemanticCache 启用语义缓存，embedder 为 nil 时使用本地 n-gram 向量
+func (s *AICacheService) EnableSemanticCache(embedder Embedder, config *SemanticCacheConfig) {
+	if embedder == nil {
+		embedder = NewNGramEmbedder(0)
+	}
+	if config == nil {
+		config = DefaultSemanticCacheConfig()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.semantic = &semanticIndex{
+		embedder: embedder,
+		config:   config,
+		scopes:   make(map[string][]*semanticEntry),
+	}
+}
+
+// ResponseKey 响应缓存的查找键
+type ResponseKey struct {
+	Function string
+	Provider string
+	Model    string
+	// UserID、ProjectID 缓存条目的归属，不同用户和项目之间不共享结果
+	UserID    string
+	ProjectID string
+	// Prompt 精确匹配的键文本，应包含系统提示词、用户输入和影响输出的生成参数
+	Prompt string
+	// SemanticText 计算相似度的文本，只包含随请求变化的用户输入；为空时不做语义匹配
+	SemanticText string
+	// Context 提示词中不参与相似度计算的部分（系统提示词、生成参数等），只有完全相同时才做语义匹配
+	Context string
+}
+
+// exactOptions 精确缓存的附加键，按功能和归属隔离
+func (k ResponseKey) exactOptions() string {
+	return k.Function + "|" + k.UserID + "|" + k.ProjectID
+}
+
+// semanticScope 语义缓存按功能、提供商、模型、归属和不参与相似度计算的上下文隔离
+func (k ResponseKey) semanticScope() string {
+	h := fnv.New64a()
+	h.Write([]byte(k.Context))
+	return strings.Join([]string{k.Function, k.Provider, k.Model, k.UserID, k.ProjectID, strconv.FormatUint(h.Sum64(), 16)}, "|")
+}
+
+// LookupResponse 按功能类型查找缓存的 AI 响应
+// 先按提示词精确匹配，未命中且该功能启用了语义匹配时返回相似度达到阈值的结果
+func (s *AICacheService) LookupResponse(ctx context.Context, key ResponseKey) (*AICacheValue, *CacheMatch, bool) {
+	if value, ok := s.GetCachedResponse(key.Provider, key.Model, key.Prompt, key.exactOptions()); ok {
+		s.recordLookup(key.Function, CacheMatchExact)
+		return value, &CacheMatch{Type: CacheMatchExact, Similarity: 1}, true
+	}
+
+	if idx := s.semanticIndexFor(key); idx != nil {
+		threshold := idx.config.threshold(key.Function)
+		vector, err := idx.embedder.Embed(ctx, key.SemanticText)
+		if err != nil {
+			logrus.Warnf("生成提示词向量失败: %v", err)
+		} else if entry, score := idx.search(key.semanticScope(), vector); entry != nil && score >= threshold {
+			s.recordLookup(key.Function, CacheMatchSemantic)
+			return entry.value, &CacheMatch{Type: CacheMatchSemantic, Similarity: score}, true
+		}
+	}
+
+	s.recordLookup(key.Function, "")
+	return nil, nil, false
+}
+
+// StoreResponse 缓存 AI 响应，同时写入精确缓存和语义索引
+func (s *AICacheService) StoreResponse(ctx context.Context, key ResponseKey, value *AICacheValue, ttl time.Duration) error {
+	s.mu.RLock()
+	idx := s.semantic
+	s.mu.RUnlock()
+
+	if ttl == 0 && idx != nil {
+		ttl = idx.config.TTL
+	}
+	if err := s.SetCachedResponse(key.Provider, key.Model, key.Prompt, key.exactOptions(), value, ttl); err != nil {
+		return err
+	}
+	if s.semanticIndexFor(key) == nil {
+		return nil
+	}
+
+	vector, err := idx.embedder.Embed(ctx, key.SemanticText)
+	if err != nil {
+		return err
+	}
+	if ttl == 0 {
+		ttl = s.cache.config.DefaultTTL
+	}
+	idx.add(key.semanticScope(), &semanticEntry{
+		vector:    vector,
+		value:     value,
+		expiresAt: time.Now().Add(ttl),
+	})
+	return nil
+}
+
+// semanticIndexFor 返回可用于该键的语义索引，未启用、功能只做精确匹配或没有用户输入时为空
+func (s *AICacheService) semanticIndexFor(key ResponseKey) *semanticIndex {
+	s.mu.RLock()
+	idx := s.semantic
+	s.mu.RUnlock()
+
+	if idx == nil || key.SemanticText == "" {
+		return nil
+	}
+	if threshold := idx.config.threshold(key.Function); threshold <= 0 || threshold >= 1 {
+		return nil
+	}
+	return idx
+}
+
+// recordLookup 累计功能类型的命中统计，match 为空表示未命中
+func (s *AICacheService) recordLookup(function string, match CacheMatchType) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.functionStats == nil {
+		s.functionStats = make(map[string]*FunctionCacheStats)
+	}
+	stats, ok := s.functionStats[function]
+	if !ok {
+		stats = &FunctionCacheStats{Function: function}
+		s.functionStats[function] = stats
+	}
+	switch match {
+	case CacheMatchExact:
+		stats.ExactHits++
+	case CacheMatchSemantic:
+		stats.SemanticHits++
+	default:
+		stats.Misses++
+	}
+}
+
+// GetFunctionStats 获取按功能类型的缓存命中统计
+func (s *AICacheService) GetFunctionStats() []FunctionCacheStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]FunctionCacheStats, 0, len(s.functionStats))
+	for _, stats := range s.functionStats {
+		item := *stats
+		if total := item.ExactHits + item.SemanticHits + item.Misses; total > 0 {
+			item.HitRate = float64(item.ExactHits+item.SemanticHits) / float64(total) * 100
+		}
+		result = append(result, item)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Function < result[j].Function })
+	return result
+}
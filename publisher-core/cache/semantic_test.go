@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNGramEmbedderSimilarity(t *testing.T) {
+	e := NewNGramEmbedder(0)
+	ctx := context.Background()
+
+	a, _ := e.Embed(ctx, "user: 请分析今天微博热搜上的科技类热点，并给出三个选题建议")
+	b, _ := e.Embed(ctx, "user: 请分析今天微博热搜上的科技热点，并给出三个选题建议")
+	c, _ := e.Embed(ctx, "user: Write a short poem about autumn leaves")
+
+	if score := cosine(a, b); score < 0.9 {
+		t.Errorf("Expected near-duplicate prompts to be similar, got %.3f", score)
+	}
+	if score := cosine(a, c); score > 0.2 {
+		t.Errorf("Expected unrelated prompts to differ, got %.3f", score)
+	}
+}
+
+func TestSemanticLookup(t *testing.T) {
+	s := NewAICacheService(&CacheConfig{MaxItems: 100})
+	defer s.Stop()
+	s.EnableSemanticCache(nil, nil)
+	ctx := context.Background()
+
+	key := func(function, model, userID, text string) ResponseKey {
+		return ResponseKey{
+			Function:     function,
+			Provider:     "deepseek",
+			Model:        model,
+			UserID:       userID,
+			Prompt:       "system: 你是内容分析师\nuser: " + text,
+			SemanticText: text,
+			Context:      "system: 你是内容分析师",
+		}
+	}
+	prompt := "请分析今天微博热搜上的科技类热点，并给出三个选题建议"
+	similar := "请分析今天微博热搜上的科技热点，并给出三个选题建议"
+	if err := s.StoreResponse(ctx, key("hotspot_analysis", "deepseek-chat", "u1", prompt), &AICacheValue{Response: "ok"}, 0); err != nil {
+		t.Fatalf("StoreResponse failed: %v", err)
+	}
+
+	if _, match, ok := s.LookupResponse(ctx, key("hotspot_analysis", "deepseek-chat", "u1", prompt)); !ok || match.Type != CacheMatchExact {
+		t.Errorf("Expected exact hit, got %v %+v", ok, match)
+	}
+	value, match, ok := s.LookupResponse(ctx, key("hotspot_analysis", "deepseek-chat", "u1", similar))
+	if !ok || match.Type != CacheMatchSemantic || value.Response != "ok" {
+		t.Errorf("Expected semantic hit, got %v %+v", ok, match)
+	}
+
+	// 不同模型、其他用户和系统提示词不同时不会命中
+	if _, _, ok := s.LookupResponse(ctx, key("hotspot_analysis", "deepseek-reasoner", "u1", similar)); ok {
+		t.Error("Expected miss for a different model")
+	}
+	if _, _, ok := s.LookupResponse(ctx, key("hotspot_analysis", "deepseek-chat", "u2", prompt)); ok {
+		t.Error("Expected miss for another user")
+	}
+	other := key("hotspot_analysis", "deepseek-chat", "u1", similar)
+	other.Context = "system: 只输出JSON"
+	if _, _, ok := s.LookupResponse(ctx, other); ok {
+		t.Error("Expected miss for a different system prompt")
+	}
+
+	// 未启用语义匹配的功能只做精确匹配
+	s.StoreResponse(ctx, key("rewrite", "deepseek-chat", "u1", prompt), &AICacheValue{Response: "ok"}, 0)
+	if _, _, ok := s.LookupResponse(ctx, key("rewrite", "deepseek-chat", "u1", similar)); ok {
+		t.Error("Expected exact-only function to miss on a similar prompt")
+	}
+
+	stats := s.GetFunctionStats()
+	if len(stats) != 2 || stats[0].Function != "hotspot_analysis" || stats[0].ExactHits != 1 || stats[0].SemanticHits != 1 || stats[0].Misses != 3 {
+		t.Errorf("Unexpected function stats: %+v", stats)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
@@ -16,11 +17,22 @@ import (
 	"publisher-core/analytics"
 	"publisher-core/analytics/collectors"
 	"publisher-core/api"
+	"publisher-core/cache"
+	"publisher-core/compliance"
+	"publisher-core/cost"
+	"publisher-core/database"
+	"publisher-core/evaluation"
 	"publisher-core/hotspot"
 	"publisher-core/hotspot/sources"
+	"publisher-core/pipeline"
+	"publisher-core/prompt"
 	"publisher-core/storage"
 	"publisher-core/task"
 	"publisher-core/task/handlers"
+	"publisher-core/vector"
+	"publisher-core/video"
+	"publisher-core/video/processor"
+	"publisher-core/websocket"
 
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
@@ -34,6 +46,10 @@ var (
 	dataDir    string
 	baseURL    string
 	debug      bool
+
+	complianceConfigPath string
+	evalDatasetDir       string
+	legacyTemplateDir    string
 )
 
 func init() {
@@ -44,6 +60,9 @@ func init() {
 	flag.StringVar(&dataDir, "data-dir", "./data", "Data storage directory")
 	flag.StringVar(&baseURL, "base-url", "", "File access base URL")
 	flag.BoolVar(&debug, "debug", false, "Debug mode")
+	flag.StringVar(&complianceConfigPath, "compliance-config", "", "Compliance dictionary and policy JSON file")
+	flag.StringVar(&evalDatasetDir, "eval-datasets", "", "Directory of evaluation datasets (.json/.jsonl) to import on startup")
+	flag.StringVar(&legacyTemplateDir, "legacy-templates", "", "Directory of legacy JSON content templates to import into the prompt registry")
 }
 
 func main() {
@@ -61,11 +80,41 @@ func main() {
 		logrus.Fatalf("Failed to create storage: %v", err)
 	}
 
-	taskMgr := task.NewTaskManager(task.NewMemoryStorage())
+	db, err := database.Init(&database.Config{
+		DBPath:      dataDir + "/publisher.db",
+		AutoMigrate: true,
+	})
+	if err != nil {
+		logrus.Fatalf("Failed to init database: %v", err)
+	}
+
+	// 发布任务与其它异步任务共用同一个持久化任务队列
+	taskQueue := task.NewQueueService(db, nil)
+	taskState := task.NewStateService(db)
+	taskMgr := task.NewQueueTaskManager(taskQueue, taskState)
 
 	factory := adapters.DefaultFactory()
+	factory.SetTaskManager(taskMgr)
+
+	// 发布前执行合规检查，需人工审批的内容记录到数据库，批准后重新执行发布任务
+	var complianceConfig *compliance.Config
+	if complianceConfigPath != "" {
+		complianceConfig, err = compliance.LoadConfig(complianceConfigPath)
+		if err != nil {
+			logrus.Fatalf("Failed to load compliance config: %v", err)
+		}
+	}
+	checker := compliance.NewChecker(complianceConfig)
+	complianceReviews := compliance.NewReviewStore(db)
+	checker.SetReviewStore(complianceReviews)
+
+	// 提示词实验的审核通过率和发布互动率分别由合规检查和数据采集回填
+	abTests := prompt.NewABTestService(db)
+	checker.SetResultRecorder(abTests)
 
 	publishHandler := handlers.NewPublishHandler(factory)
+	publishHandler.SetComplianceChecker(checker)
+	publishHandler.SetExperimentRecorder(abTests)
 	taskMgr.RegisterHandler("publish", publishHandler.Handle)
 
 	publisherService := &PublisherService{
@@ -82,9 +131,88 @@ func main() {
 	// 初始化 AI 服务并从环境变量加载配置
 	aiService := ai.NewServiceWithDefaults()
 	setupAIProviders(aiService)
+
+	// 每次 AI 调用都记录成本并受预算约束
+	costService := cost.NewCostService(db)
+	budgetService := cost.NewBudgetService(db, costService)
+	tracker := cost.NewTracker(costService, budgetService, nil)
+
+	// 相同的提示词复用已缓存的响应，分析类功能另外复用相近问题的结果；
+	// 缓存按用户和项目隔离，命中率按功能类型计入成本记录
+	aiCache := cache.NewAICacheService(nil)
+	aiCache.EnableSemanticCache(nil, nil)
+	tracker.SetResponseCache(aiCache)
+	aiService.SetCostTracker(tracker)
+	aiService.SetHistoryDB(db)
+	checker.SetAIAuditor(aiService)
 	aiAdapter := &AIServiceAdapter{service: aiService}
 
+	// 视频下载、转写和优化任务同样由任务队列执行，需在队列启动前注册处理器
+	videoService := video.NewService(db, aiService, nil)
+	videoService.SetTaskQueue(taskQueue, taskState)
+	videoService.Start()
+	defer videoService.Stop()
+
+	queueCtx, stopQueue := context.WithCancel(context.Background())
+	defer stopQueue()
+	taskQueue.Start(queueCtx)
+
+	// 定时任务按计划提交到任务队列，需在队列启动后启动
+	scheduler := task.NewSchedulerService(db, taskQueue)
+	if err := scheduler.Start(queueCtx); err != nil {
+		logrus.Fatalf("Failed to start scheduler: %v", err)
+	}
+
 	server := api.NewServer(taskService, publisherService, storageService, aiAdapter)
+	server.WithTaskQueue(taskQueue, taskState)
+	api.NewCacheHandler(aiCache).RegisterRoutes(server.Router())
+	api.NewComplianceHandlers(checker, complianceReviews, taskQueue).RegisterRoutes(server.Router())
+	api.NewSchedulerHandlers(scheduler).RegisterRoutes(server.Router())
+	server.Router().Handle("/metrics", api.TaskMetricsHandler(taskState)).Methods("GET")
+
+	// 提示词模板统一存放在数据库中，代码按稳定ID加载
+	if err := prompt.InitializeDefaultTemplates(db); err != nil {
+		logrus.Warnf("Failed to initialize prompt templates: %v", err)
+	}
+	prompts := prompt.NewService(db)
+	if legacyTemplateDir != "" {
+		imported, err := prompts.ImportLegacyTemplates(legacyTemplateDir)
+		if err != nil {
+			logrus.Warnf("Failed to import legacy templates: %v", err)
+		}
+		logrus.Infof("Legacy templates imported: %d", imported)
+	}
+	aiService.SetPromptService(prompts)
+	promptHandler := api.NewPromptTemplateHandler(db)
+	promptHandler.SetPreviewBackend(aiService)
+	promptHandler.RegisterRoutes(server.Router())
+
+	// 离线评测：在数据集上对比提供商、模型和提示词模板组合
+	evalStore := evaluation.NewStore(db)
+	if evalDatasetDir != "" {
+		importEvalDatasets(evalStore, evalDatasetDir)
+	}
+	evalRunner := evaluation.NewRunner(evalStore, aiService)
+	evalRunner.SetTemplates(prompts)
+	evalRunner.SetPricer(costService)
+	api.NewEvaluationHandlers(evalStore, evalRunner).RegisterRoutes(server.Router())
+
+	// WebSocket 客户端可通过 ai_generate 消息流式生成内容
+	wsServer := websocket.NewServer()
+	wsServer.SetAIStreamer(aiService)
+	server.Router().HandleFunc("/ws/ai", wsServer.HandleWebSocket)
+
+	// 有提供商支持向量接口时，定期索引话题标题和 AI 生成内容以支持相似度检索
+	if embedder, err := aiService.Embedder(); err != nil {
+		logrus.Infof("Vector search disabled: %v", err)
+	} else if vectorStore, err := vector.Open(dataDir + "/vectors"); err != nil {
+		logrus.Warnf("Failed to open vector store: %v", err)
+	} else {
+		defer vectorStore.Close()
+		vectorService := vector.NewService(vectorStore, embedder, db)
+		go vectorService.Run(queueCtx, 10*time.Minute)
+		server.WithVectorSearch(vectorService)
+	}
 
 	hotspotStorage, err := hotspot.NewJSONStorage(dataDir)
 	if err != nil {
@@ -98,8 +226,19 @@ func main() {
 
 	hotspotService.RegisterSource(sources.NewMockSource("mock", "Test Source"))
 
+	// 跨来源热点事件：定时抓取到数据库并把各平台报道同一事件的话题合并为一个事件
+	eventService := hotspot.NewEnhancedService(db, nil)
+	for _, src := range sources.CreateAllSources() {
+		eventService.RegisterSource(src)
+	}
+	if embedder, err := aiService.Embedder(); err == nil {
+		eventService.SetEmbedder(embedder)
+	}
+	go eventService.Run(queueCtx, 30*time.Minute, 20)
+
 	// 注册热点监控API路由
 	hotspotAPI := hotspot.NewAPIHandler(hotspotService)
+	hotspotAPI.SetEventService(eventService)
 	server.RegisterRoutes(hotspotAPI)
 
 	// 注册存储API路由
@@ -111,11 +250,25 @@ func main() {
 		logrus.Warnf("Failed to create analytics storage: %v", err)
 	}
 	analyticsService := analytics.NewService(analyticsStorage)
+	analyticsService.SetEngagementRecorder(abTests)
 
 	analyticsService.RegisterCollector(collectors.NewDouyinCollector())
 	analyticsService.RegisterCollector(collectors.NewXiaohongshuCollector())
 	analyticsService.RegisterCollector(collectors.NewToutiaoCollector())
 
+	// 内容流水线：内置步骤处理器，以及读取存储中图片和视频缩略图的多模态处理器
+	orchestrator := pipeline.NewPipelineOrchestrator(pipeline.NewDBStorage(db))
+	pipelineHandlers := pipeline.NewHandlerRegistry(orchestrator, aiService, prompts, factory, analyticsService)
+	pipelineHandlers.SetComplianceChecker(checker)
+	var videoProcessor processor.Processor
+	if ffmpeg := processor.NewFFmpegProcessor(nil); ffmpeg.IsAvailable() {
+		videoProcessor = ffmpeg
+	} else {
+		logrus.Info("FFmpeg not available, thumbnail captioning disabled")
+	}
+	pipelineHandlers.RegisterVisionHandlers(store, videoProcessor)
+	api.NewPipelineAPI(orchestrator, wsServer).RegisterRoutes(server.Router())
+
 	go func() {
 		addr := fmt.Sprintf(":%d", port)
 		if err := server.Start(addr); err != nil && err != http.ErrServerClosed {
@@ -139,6 +292,8 @@ func main() {
 	if err := server.Stop(ctx); err != nil {
 		logrus.Errorf("Failed to shutdown server: %v", err)
 	}
+	scheduler.Stop()
+	stopQueue()
 
 	select {
 	case <-ctx.Done():
@@ -160,6 +315,24 @@ func setupLogger() {
 	}
 }
 
+// importEvalDatasets 导入目录中的评测数据集，同名数据集的用例以文件为准
+func importEvalDatasets(store *evaluation.Store, dir string) {
+	var files []string
+	for _, pattern := range []string{"*.json", "*.jsonl"} {
+		matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+		files = append(files, matches...)
+	}
+
+	for _, file := range files {
+		ds, err := store.ImportFile(file)
+		if err != nil {
+			logrus.Warnf("Failed to import evaluation dataset %s: %v", file, err)
+			continue
+		}
+		logrus.Infof("Evaluation dataset imported: %s (%d cases)", ds.ID, len(ds.Cases))
+	}
+}
+
 // setupAIProviders 从环境变量加载 AI 提供商配置
 func setupAIProviders(aiService *ai.Service) {
 	// OpenRouter
@@ -348,6 +521,20 @@ func (a *AIServiceAdapter) GenerateStream(ctx context.Context, providerName stri
 	return a.service.GenerateStream(ctx, opts)
 }
 
+func (a *AIServiceAdapter) GenerateStreamEvents(ctx context.Context, providerName string, opts *provider.GenerateOptions) (<-chan provider.StreamEvent, error) {
+	if providerName != "" {
+		return a.service.GenerateStreamEventsWithProvider(ctx, provider.ProviderType(providerName), opts)
+	}
+	return a.service.GenerateStreamEvents(ctx, opts)
+}
+
+func (a *AIServiceAdapter) Estimate(providerName string, opts *provider.GenerateOptions) (interface{}, error) {
+	if providerName != "" {
+		return a.service.EstimateWithProvider(provider.ProviderType(providerName), opts)
+	}
+	return a.service.Estimate(opts)
+}
+
 func (a *AIServiceAdapter) ListProviders() []string {
 	providers := a.service.ListProviders()
 	if len(providers) == 0 {
@@ -0,0 +1,127 @@
+package compliance
+
+// 内置词库只收录常见的违法和导流用语，更完整的词库通过配置文件加载
+
+// builtinSensitive 内置敏感词
+func builtinSensitive() []Term {
+	return []Term{
+		{Word: "冰毒", Severity: SeverityCritical, Reason: "涉毒"},
+		{Word: "海洛因", Severity: SeverityCritical, Reason: "涉毒"},
+		{Word: "摇头丸", Severity: SeverityCritical, Reason: "涉毒"},
+		{Word: "大麻", Severity: SeverityHigh, Reason: "涉毒", Exceptions: []string{"大麻烦", "大麻雀"}},
+		{Word: "枪支", Severity: SeverityCritical, Reason: "涉枪", Variants: []string{"枪枝"}},
+		{Word: "仿真枪", Severity: SeverityCritical, Reason: "涉枪"},
+		{Word: "炸药", Severity: SeverityCritical, Reason: "涉爆"},
+		{Word: "赌博", Severity: SeverityHigh, Reason: "涉赌", Variants: []string{"堵博", "睹博"}},
+		{Word: "网赌", Severity: SeverityHigh, Reason: "涉赌"},
+		{Word: "博彩", Severity: SeverityHigh, Reason: "涉赌"},
+		{Word: "六合彩", Severity: SeverityHigh, Reason: "涉赌"},
+		{Word: "色情", Severity: SeverityHigh, Reason: "涉黄", Variants: []string{"涩情"}},
+		{Word: "裸聊", Severity: SeverityHigh, Reason: "涉黄"},
+		{Word: "约炮", Severity: SeverityHigh, Reason: "涉黄", Variants: []string{"约p"}},
+		{Word: "代孕", Severity: SeverityHigh, Reason: "违法服务"},
+		{Word: "代开发票", Severity: SeverityHigh, Reason: "违法服务"},
+		{Word: "假证", Severity: SeverityHigh, Reason: "违法服务"},
+		{Word: "洗钱", Severity: SeverityHigh, Reason: "涉嫌违法", Exceptions: []string{"反洗钱"}},
+		{Word: "传销", Severity: SeverityHigh, Reason: "涉嫌违法", Exceptions: []string{"反传销", "打击传销"}},
+		{Word: "裸贷", Severity: SeverityHigh, Reason: "违法借贷"},
+		{Word: "高利贷", Severity: SeverityMedium, Reason: "违法借贷"},
+		{Word: "刷单", Severity: SeverityMedium, Reason: "虚假交易"},
+		{Word: "套现", Severity: SeverityMedium, Reason: "违规金融"},
+		{Word: "翻墙", Severity: SeverityMedium, Reason: "违规上网"},
+	}
+}
+
+// contactTerms 各平台通用的站外导流用语
+func contactTerms() []Term {
+	return []Term{
+		{Word: "加微信", Severity: SeverityMedium, Replacement: "私信", Variants: []string{"加微", "加v", "加vx", "加wx", "加威信"}},
+		{Word: "vx", Severity: SeverityMedium, Replacement: "私信", Variants: []string{"v信", "威信", "薇信", "wx"}},
+		{Word: "私信领取", Severity: SeverityMedium, Replacement: "评论区交流"},
+		{Word: "二维码", Severity: SeverityMedium},
+	}
+}
+
+// builtinPlatformTerms 内置平台禁用词
+func builtinPlatformTerms() map[string][]Term {
+	return map[string][]Term{
+		"douyin": append(contactTerms(),
+			Term{Word: "淘宝", Severity: SeverityLow, Reason: "提及站外电商平台可能限流"},
+			Term{Word: "快手", Severity: SeverityLow, Reason: "提及竞品平台可能限流"},
+		),
+		"xiaohongshu": append(contactTerms(),
+			Term{Word: "淘宝", Severity: SeverityMedium, Reason: "禁止引导站外交易", Variants: []string{"tb"}},
+			Term{Word: "拼多多", Severity: SeverityMedium, Reason: "禁止引导站外交易", Variants: []string{"pdd"}},
+			Term{Word: "代购", Severity: SeverityMedium, Reason: "禁止引导站外交易"},
+			Term{Word: "抖音", Severity: SeverityLow, Reason: "提及竞品平台可能限流"},
+		),
+		"toutiao": append(contactTerms(),
+			Term{Word: "点击链接", Severity: SeverityLow, Replacement: "查看详情"},
+		),
+	}
+}
+
+// builtinAdLaw 广告法第九条禁止的绝对化用语
+// 日常表达中也常出现，默认级别较低，仅给出替换建议
+func builtinAdLaw() []Term {
+	return []Term{
+		{Word: "最佳", Severity: SeverityLow, Replacement: "极佳"},
+		{Word: "最好", Severity: SeverityLow, Replacement: "很好", Exceptions: []string{"最好不要", "最好别", "最好还是", "最好是"}},
+		{Word: "最优", Severity: SeverityLow, Replacement: "优秀"},
+		{Word: "最强", Severity: SeverityLow, Replacement: "强劲"},
+		{Word: "最低价", Severity: SeverityLow, Replacement: "优惠价"},
+		{Word: "最便宜", Severity: SeverityLow, Replacement: "实惠"},
+		{Word: "最先进", Severity: SeverityLow, Replacement: "先进"},
+		{Word: "最受欢迎", Severity: SeverityLow, Replacement: "广受欢迎"},
+		{Word: "最畅销", Severity: SeverityLow, Replacement: "热销"},
+		{Word: "最权威", Severity: SeverityLow, Replacement: "权威"},
+		{Word: "第一", Severity: SeverityLow, Replacement: "领先", Exceptions: []string{
+			"第一次", "第一天", "第一步", "第一章", "第一个", "第一眼", "第一季", "第一集", "第一期", "第一部", "第一条", "第一轮", "第一批", "第一年",
+		}},
+		{Word: "全网第一", Severity: SeverityMedium},
+		{Word: "销量第一", Severity: SeverityMedium, Replacement: "销量领先"},
+		{Word: "唯一", Severity: SeverityLow, Replacement: "少有"},
+		{Word: "首选", Severity: SeverityLow, Replacement: "优选"},
+		{Word: "顶级", Severity: SeverityLow, Replacement: "高端"},
+		{Word: "极致", Severity: SeverityLow, Replacement: "出色"},
+		{Word: "独一无二", Severity: SeverityLow, Replacement: "与众不同"},
+		{Word: "史无前例", Severity: SeverityLow, Replacement: "少见"},
+		{Word: "万能", Severity: SeverityLow, Replacement: "多用途"},
+		{Word: "遥遥领先", Severity: SeverityLow, Replacement: "领先"},
+		{Word: "永久", Severity: SeverityLow, Replacement: "长期"},
+		{Word: "国家级", Severity: SeverityMedium},
+		{Word: "世界级", Severity: SeverityMedium},
+		{Word: "100%", Severity: SeverityMedium, Replacement: "高"},
+		{Word: "根治", Severity: SeverityMedium, Replacement: "改善", Reason: "医疗功效宣传"},
+		{Word: "无副作用", Severity: SeverityMedium, Reason: "医疗功效宣传"},
+	}
+}
+
+// pinyinTable 内置词条用字的拼音，配置的词条可通过 Term.Pinyin 指定
+var pinyinTable = map[rune]string{
+	'冰': "bing", '毒': "du", '海': "hai", '洛': "luo", '因': "yin",
+	'摇': "yao", '头': "tou", '丸': "wan", '大': "da", '麻': "ma",
+	'枪': "qiang", '支': "zhi", '仿': "fang", '真': "zhen", '炸': "zha",
+	'药': "yao", '赌': "du", '博': "bo", '网': "wang", '彩': "cai",
+	'六': "liu", '合': "he", '色': "se", '情': "qing", '裸': "luo",
+	'聊': "liao", '约': "yue", '炮': "pao", '代': "dai", '孕': "yun",
+	'开': "kai", '发': "fa", '票': "piao", '假': "jia", '证': "zheng",
+	'洗': "xi", '钱': "qian", '传': "chuan", '销': "xiao", '贷': "dai",
+	'高': "gao", '利': "li", '刷': "shua", '单': "dan", '套': "tao",
+	'现': "xian", '翻': "fan", '墙': "qiang",
+}
+
+// traditionalChars 词库相关的常用繁体字
+var traditionalChars = map[rune]rune{
+	'賭': '赌', '槍': '枪', '彈': '弹', '藥': '药', '錢': '钱',
+	'發': '发', '貸': '贷', '傳': '传', '銷': '销', '證': '证',
+	'聯': '联', '網': '网', '裝': '装', '幣': '币', '號': '号',
+	'維': '维', '碼': '码', '鏈': '链', '國': '国', '級': '级',
+	'極': '极', '絕': '绝', '對': '对', '頂': '顶', '獨': '独',
+	'無': '无', '優': '优', '強': '强', '選': '选', '萬': '万',
+	'價': '价', '廣': '广', '遙': '遥', '領': '领', '權': '权',
+	'暢': '畅', '歡': '欢', '爭': '争', '寶': '宝', '勢': '势',
+	'療': '疗', '純': '纯', '質': '质', '購': '购',
+	'頭': '头', '搖': '摇', '約': '约', '單': '单',
+	'現': '现', '牆': '墙', '開': '开', '騙': '骗',
+}
@@ -0,0 +1,401 @@
+package compliance
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"publisher-core/ai/provider"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 检查的内容字段
+const (
+	FieldTitle = "title"
+	FieldBody  = "body"
+	FieldTags  = "tags"
+)
+
+// textField 待检查的字段
+type textField struct {
+	name string
+	text string
+}
+
+// AIAuditor AI 内容审核，ai.Service 实现了该接口
+type AIAuditor interface {
+	AuditContent(ctx context.Context, content string) (*provider.AuditResult, error)
+}
+
+// AuditResultRecorder 接收检查结论，prompt.ABTestService 实现了该接口，用于统计实验的审核通过率
+type AuditResultRecorder interface {
+	RecordAuditResult(ctx context.Context, passed bool) error
+}
+
+// Decision 检查结论
+type Decision string
+
+const (
+	DecisionPass   Decision = "pass"   // 放行
+	DecisionReview Decision = "review" // 需人工审批
+	DecisionBlock  Decision = "block"  // 拦截
+)
+
+// Policy 按问题最高级别决定检查结论
+type Policy struct {
+	// ReviewAt 达到该级别需人工审批，为空表示不要求审批
+	ReviewAt Severity `json:"review_at"`
+	// BlockAt 达到该级别直接拦截，为空表示不拦截
+	BlockAt Severity `json:"block_at"`
+	// UseAI 设置了 AI 审核时是否调用
+	UseAI bool `json:"use_ai"`
+}
+
+// DefaultPolicy 默认策略：违法内容拦截，可能被限流处罚的内容需人工审批
+func DefaultPolicy() *Policy {
+	return &Policy{
+		ReviewAt: SeverityMedium,
+		BlockAt:  SeverityCritical,
+		UseAI:    true,
+	}
+}
+
+// decide 根据最高级别得出结论
+func (p *Policy) decide(max Severity) Decision {
+	switch {
+	case max.AtLeast(p.BlockAt):
+		return DecisionBlock
+	case max.AtLeast(p.ReviewAt):
+		return DecisionReview
+	}
+	return DecisionPass
+}
+
+// Config 合规检查配置，词条追加到内置词库之后
+type Config struct {
+	Sensitive []Term            `json:"sensitive"`
+	Platforms map[string][]Term `json:"platforms"`
+	AdLaw     []Term            `json:"ad_law"`
+	Policy    *Policy           `json:"policy,omitempty"`
+	// DisableBuiltin 不加载内置词库
+	DisableBuiltin bool `json:"disable_builtin"`
+}
+
+// LoadConfig 从 JSON 文件加载配置
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取合规配置失败: %w", err)
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("解析合规配置失败: %w", err)
+	}
+	return &config, nil
+}
+
+// Content 待检查的内容
+type Content struct {
+	Platform string   `json:"platform"`
+	Title    string   `json:"title"`
+	Body     string   `json:"body"`
+	Tags     []string `json:"tags,omitempty"`
+	// SkipAI 只做确定性检查
+	SkipAI bool `json:"skip_ai,omitempty"`
+}
+
+// Hash 内容摘要，人工审批按平台和摘要匹配
+func (c *Content) Hash() string {
+	h := sha256.New()
+	for _, part := range append([]string{c.Platform, c.Title, c.Body}, c.Tags...) {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Report 检查报告
+type Report struct {
+	Decision    Decision `json:"decision"`
+	MaxSeverity Severity `json:"max_severity,omitempty"`
+	Issues      []Issue  `json:"issues"`
+	// SuggestedTitle、SuggestedBody 按建议替换后的标题和正文
+	SuggestedTitle string    `json:"suggested_title"`
+	SuggestedBody  string    `json:"suggested_body"`
+	AIChecked      bool      `json:"ai_checked"`
+	AIScore        int       `json:"ai_score,omitempty"`
+	AIError        string    `json:"ai_error,omitempty"`
+	ContentHash    string    `json:"content_hash"`
+	ReviewID       uint      `json:"review_id,omitempty"`
+	CheckedAt      time.Time `json:"checked_at"`
+}
+
+// Checker 内容合规检查器
+type Checker struct {
+	mu        sync.RWMutex
+	sensitive *Dictionary
+	adLaw     *Dictionary
+	platforms map[string]*Dictionary
+	policy    *Policy
+	auditor   AIAuditor
+	reviews   *ReviewStore
+	recorder  AuditResultRecorder
+}
+
+// NewChecker 创建检查器，config 为 nil 时只使用内置词库和默认策略
+func NewChecker(config *Config) *Checker {
+	if config == nil {
+		config = &Config{}
+	}
+
+	c := &Checker{
+		sensitive: NewDictionary(CategorySensitive, true),
+		adLaw:     NewDictionary(CategoryAdLaw, false),
+		platforms: make(map[string]*Dictionary),
+		policy:    DefaultPolicy(),
+	}
+	if config.Policy != nil {
+		c.policy = config.Policy
+	}
+
+	if !config.DisableBuiltin {
+		c.sensitive.Add(builtinSensitive()...)
+		c.adLaw.Add(builtinAdLaw()...)
+		for platform, terms := range builtinPlatformTerms() {
+			c.platformDictionary(platform).Add(terms...)
+		}
+	}
+	c.sensitive.Add(config.Sensitive...)
+	c.adLaw.Add(config.AdLaw...)
+	for platform, terms := range config.Platforms {
+		c.platformDictionary(platform).Add(terms...)
+	}
+
+	return c
+}
+
+// platformDictionary 返回平台词典，不存在时创建
+func (c *Checker) platformDictionary(platform string) *Dictionary {
+	d, ok := c.platforms[platform]
+	if !ok {
+		d = NewDictionary(CategoryPlatform, false)
+		c.platforms[platform] = d
+	}
+	return d
+}
+
+// SetAIAuditor 设置 AI 审核
+func (c *Checker) SetAIAuditor(auditor AIAuditor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.auditor = auditor
+}
+
+// SetResultRecorder 设置检查结论的接收方，每次检查后以结论是否为放行回调
+func (c *Checker) SetResultRecorder(recorder AuditResultRecorder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recorder = recorder
+}
+
+// SetReviewStore 设置人工审批存储，未设置时需审批的内容一律不放行
+func (c *Checker) SetReviewStore(reviews *ReviewStore) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reviews = reviews
+}
+
+// SetPolicy 设置策略
+func (c *Checker) SetPolicy(policy *Policy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.policy = policy
+}
+
+// Policy 返回当前策略
+func (c *Checker) Policy() Policy {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return *c.policy
+}
+
+// Check 检查内容并给出结论，AI 审核失败不影响确定性检查的结果
+func (c *Checker) Check(ctx context.Context, content *Content) (*Report, error) {
+	c.mu.RLock()
+	policy, auditor, recorder := *c.policy, c.auditor, c.recorder
+	c.mu.RUnlock()
+
+	report := &Report{
+		ContentHash: content.Hash(),
+		CheckedAt:   time.Now(),
+	}
+
+	fields := []textField{{FieldTitle, content.Title}, {FieldBody, content.Body}}
+	for _, tag := range content.Tags {
+		fields = append(fields, textField{FieldTags, tag})
+	}
+
+	platform := c.platforms[content.Platform]
+	for _, f := range fields {
+		if f.text == "" {
+			continue
+		}
+		s := scan(f.text)
+		found := append(c.sensitive.find(s), c.adLaw.find(s)...)
+		if platform != nil {
+			for _, issue := range platform.find(s) {
+				issue.Platform = content.Platform
+				found = append(found, issue)
+			}
+		}
+		for i := range found {
+			found[i].Field = f.name
+		}
+		report.Issues = append(report.Issues, found...)
+	}
+
+	if auditor != nil && policy.UseAI && !content.SkipAI {
+		if err := c.audit(ctx, auditor, content, report); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logrus.Warnf("AI内容审核失败，仅使用词库检查结果: %v", err)
+			report.AIError = err.Error()
+		}
+	}
+
+	report.Issues = dedupeIssues(report.Issues)
+	for _, issue := range report.Issues {
+		if issue.Severity.rank() > report.MaxSeverity.rank() {
+			report.MaxSeverity = issue.Severity
+		}
+	}
+	report.Decision = policy.decide(report.MaxSeverity)
+	report.SuggestedTitle = applySuggestions(content.Title, report.Issues, FieldTitle)
+	report.SuggestedBody = applySuggestions(content.Body, report.Issues, FieldBody)
+
+	if recorder != nil {
+		if err := recorder.RecordAuditResult(ctx, report.Decision == DecisionPass); err != nil {
+			logrus.Warnf("记录审核结论失败: %v", err)
+		}
+	}
+	return report, nil
+}
+
+// audit 调用 AI 审核，把发现的问题定位到标题或正文
+func (c *Checker) audit(ctx context.Context, auditor AIAuditor, content *Content, report *Report) error {
+	text := content.Body
+	if content.Title != "" {
+		text = content.Title + "\n\n" + content.Body
+	}
+	result, err := auditor.AuditContent(ctx, text)
+	if err != nil {
+		return err
+	}
+
+	report.AIChecked = true
+	report.AIScore = result.Score
+	for _, f := range result.Findings {
+		issue := locate(f.Text, content)
+		issue.Category = CategoryAI
+		issue.Severity = parseSeverity(f.Severity, SeverityMedium)
+		issue.Match = MatchAI
+		issue.Term = f.Text
+		issue.Reason = f.Reason
+		issue.Replacement = f.Suggestion
+		issue.Suggestion = suggestionFor(f.Text, f.Suggestion)
+		report.Issues = append(report.Issues, issue)
+	}
+
+	// 未给出具体片段但判定不通过时，按中等级别记录一条整体问题
+	if !result.Passed && len(result.Findings) == 0 {
+		report.Issues = append(report.Issues, Issue{
+			Category:   CategoryAI,
+			Severity:   SeverityMedium,
+			Match:      MatchAI,
+			Reason:     strings.Join(result.Issues, "；"),
+			Suggestion: strings.Join(result.Suggestions, "；"),
+		})
+	}
+	return nil
+}
+
+// locate 在标题和正文中查找 AI 给出的片段
+func locate(text string, content *Content) Issue {
+	if text == "" {
+		return Issue{}
+	}
+	for _, f := range []textField{{FieldTitle, content.Title}, {FieldBody, content.Body}} {
+		idx := strings.Index(f.text, text)
+		if idx < 0 {
+			continue
+		}
+		start := utf8.RuneCountInString(f.text[:idx])
+		return Issue{
+			Field:  f.name,
+			Text:   text,
+			Start:  start,
+			End:    start + utf8.RuneCountInString(text),
+			Line:   strings.Count(f.text[:idx], "\n") + 1,
+			Column: utf8.RuneCountInString(f.text[strings.LastIndex(f.text[:idx], "\n")+1:idx]) + 1,
+		}
+	}
+	return Issue{Text: text}
+}
+
+// dedupeIssues 按位置排序，并去掉被同类别、不低于其级别的问题完全覆盖的问题
+// 例如"全网第一"命中后不再单独报告其中的"第一"
+func dedupeIssues(issues []Issue) []Issue {
+	sort.SliceStable(issues, func(i, j int) bool {
+		a, b := issues[i], issues[j]
+		if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		return a.End-a.Start > b.End-b.Start
+	})
+
+	kept := issues[:0]
+	for _, issue := range issues {
+		covered := false
+		for _, k := range kept {
+			if k.Line > 0 && issue.Line > 0 && k.Field == issue.Field && k.Category == issue.Category &&
+				k.Start <= issue.Start && issue.End <= k.End && k.Severity.rank() >= issue.Severity.rank() {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			kept = append(kept, issue)
+		}
+	}
+	return kept
+}
+
+// applySuggestions 按建议替换字段中的问题文本，重叠的问题只处理最先出现的
+func applySuggestions(text string, issues []Issue, field string) string {
+	runes := []rune(text)
+	var b strings.Builder
+	last := 0
+	for _, issue := range issues {
+		if issue.Field != field || issue.Line == 0 || issue.Start < last || issue.End > len(runes) {
+			continue
+		}
+		b.WriteString(string(runes[last:issue.Start]))
+		b.WriteString(issue.Replacement)
+		last = issue.End
+	}
+	b.WriteString(string(runes[last:]))
+	return b.String()
+}
@@ -0,0 +1,201 @@
+package compliance
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"publisher-core/ai"
+	"publisher-core/ai/provider"
+	"publisher-core/cache"
+	"publisher-core/cost"
+	"publisher-core/database"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type fakeAuditor struct {
+	result *provider.AuditResult
+	err    error
+}
+
+func (a *fakeAuditor) AuditContent(ctx context.Context, content string) (*provider.AuditResult, error) {
+	return a.result, a.err
+}
+
+// auditProvider 模拟审核模型，内容含“加微信”时返回导流问题
+type auditProvider struct {
+	calls int
+}
+
+func (p *auditProvider) Name() provider.ProviderType { return provider.ProviderDeepSeek }
+func (p *auditProvider) Models() []string            { return []string{"deepseek-chat"} }
+func (p *auditProvider) DefaultModel() string        { return "deepseek-chat" }
+
+func (p *auditProvider) Generate(ctx context.Context, opts *provider.GenerateOptions) (*provider.GenerateResult, error) {
+	p.calls++
+	content := `{"passed":true,"score":100,"findings":[]}`
+	if strings.Contains(opts.Messages[len(opts.Messages)-1].Content, "加微信") {
+		content = `{"passed":false,"score":40,"findings":[{"text":"加微信","reason":"引导站外交易","severity":"high","suggestion":""}]}`
+	}
+	return &provider.GenerateResult{Content: content, Model: opts.Model}, nil
+}
+
+func (p *auditProvider) GenerateStream(ctx context.Context, opts *provider.GenerateOptions) (<-chan string, error) {
+	return nil, errors.New("not supported")
+}
+
+func newTestReviewStore(t *testing.T) *ReviewStore {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&database.ComplianceReview{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	return NewReviewStore(db)
+}
+
+func TestCheckCombinesLayers(t *testing.T) {
+	c := NewChecker(&Config{
+		Platforms: map[string][]Term{"xiaohongshu": {{Word: "闲鱼", Severity: SeverityMedium}}},
+	})
+	c.SetAIAuditor(&fakeAuditor{result: &provider.AuditResult{
+		Passed: false,
+		Score:  60,
+		Findings: []provider.AuditFinding{
+			{Text: "吃了就能瘦", Reason: "夸大功效", Severity: "high", Suggestion: "有助于控制体重"},
+		},
+	}})
+
+	report, err := c.Check(context.Background(), &Content{
+		Platform: "xiaohongshu",
+		Title:    "全网第一的代餐",
+		Body:     "吃了就能瘦，想要的加vx，闲鱼也有",
+	})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	categories := make(map[Category]int)
+	for _, issue := range report.Issues {
+		categories[issue.Category]++
+	}
+	if categories[CategoryAdLaw] != 1 || categories[CategoryPlatform] != 2 || categories[CategoryAI] != 1 {
+		t.Errorf("Unexpected issues: %+v", report.Issues)
+	}
+	if report.MaxSeverity != SeverityHigh || report.Decision != DecisionReview || !report.AIChecked || report.AIScore != 60 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+	if report.SuggestedTitle != "的代餐" {
+		t.Errorf("Unexpected suggested title: %q", report.SuggestedTitle)
+	}
+	if report.SuggestedBody != "有助于控制体重，想要的私信，也有" {
+		t.Errorf("Unexpected suggested body: %q", report.SuggestedBody)
+	}
+}
+
+func TestCheckAIFailure(t *testing.T) {
+	c := NewChecker(nil)
+	c.SetAIAuditor(&fakeAuditor{err: errors.New("timeout")})
+
+	report, err := c.Check(context.Background(), &Content{Platform: "douyin", Body: "出售冰毒"})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if report.Decision != DecisionBlock || report.AIChecked || report.AIError == "" {
+		t.Errorf("Expected deterministic block despite AI failure, got %+v", report)
+	}
+}
+
+func TestCheckReauditsNearDuplicate(t *testing.T) {
+	// 即使审核功能启用了语义缓存，也要重新审核只多出一处违规的相近内容
+	aiCache := cache.NewAICacheService(nil)
+	defer aiCache.Stop()
+	aiCache.EnableSemanticCache(nil, &cache.SemanticCacheConfig{Thresholds: map[string]float64{"content_audit": 0.5}})
+	tracker := cost.NewTracker(nil, nil, nil)
+	tracker.SetResponseCache(aiCache)
+
+	inner := &auditProvider{}
+	service := ai.NewServiceWithDefaults()
+	service.RegisterProvider(inner)
+	service.SetCostTracker(tracker)
+
+	c := NewChecker(nil)
+	c.SetAIAuditor(service)
+
+	body := "今天分享一款好用的保温杯，容量大、保温时间长，适合通勤和出差携带。"
+	first, err := c.Check(context.Background(), &Content{Platform: "douyin", Body: body})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !first.AIChecked || len(first.Issues) != 0 {
+		t.Fatalf("Expected clean first audit, got %+v", first)
+	}
+
+	second, err := c.Check(context.Background(), &Content{Platform: "douyin", Body: body + "需要的加微信。"})
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("Expected near-duplicate to be re-audited, got %d calls", inner.calls)
+	}
+	found := false
+	for _, issue := range second.Issues {
+		if issue.Category == CategoryAI && issue.Term == "加微信" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected AI finding for the added violation, got %+v", second.Issues)
+	}
+}
+
+func TestEnforceApproval(t *testing.T) {
+	c := NewChecker(nil)
+	reviews := newTestReviewStore(t)
+	c.SetReviewStore(reviews)
+	ctx := context.Background()
+	content := &Content{Platform: "douyin", Title: "福利", Body: "加微信领取资料"}
+
+	_, err := c.Enforce(ctx, content, "task-1")
+	var violation *ViolationError
+	if !errors.As(err, &violation) || !errors.Is(err, ErrApprovalRequired) {
+		t.Fatalf("Expected approval required, got %v", err)
+	}
+	reviewID := violation.Report.ReviewID
+
+	// 再次提交复用待审批记录
+	_, err = c.Enforce(ctx, content, "task-2")
+	if !errors.As(err, &violation) || violation.Report.ReviewID != reviewID {
+		t.Fatalf("Expected the same pending review, got %v", err)
+	}
+	review, _ := reviews.Get(reviewID)
+	if review.TaskID != "task-2" {
+		t.Errorf("Expected review to track latest task, got %s", review.TaskID)
+	}
+
+	if _, err := reviews.Approve(reviewID, "editor", "ok"); err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+	report, err := c.Enforce(ctx, content, "task-2")
+	if err != nil || report.Decision != DecisionPass {
+		t.Fatalf("Expected approved content to pass, got %+v %v", report, err)
+	}
+
+	// 修改内容后需要重新审批，驳回后直接拦截
+	changed := &Content{Platform: "douyin", Title: "福利", Body: "加微信领取资料包"}
+	_, err = c.Enforce(ctx, changed, "")
+	if !errors.As(err, &violation) || violation.Report.ReviewID == reviewID {
+		t.Fatalf("Expected a new review for changed content, got %v", err)
+	}
+	if _, err := reviews.Reject(violation.Report.ReviewID, "editor", "no"); err != nil {
+		t.Fatalf("Reject failed: %v", err)
+	}
+	if _, err := c.Enforce(ctx, changed, ""); !errors.Is(err, ErrBlocked) {
+		t.Errorf("Expected rejected content to be blocked, got %v", err)
+	}
+}
@@ -0,0 +1,441 @@
+// Package compliance 提供发布前的内容合规检查
+// 确定性检查包括敏感词（含拼音、谐音、拆分等变体写法）、平台禁用词和广告法绝对化用语，
+// 可选再由 AI 审核补充；按严重级别策略决定放行、人工审批或拦截
+package compliance
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Severity 问题严重级别
+type Severity string
+
+const (
+	SeverityLow      Severity = "low"      // 提示，一般不影响发布
+	SeverityMedium   Severity = "medium"   // 可能被限流或处罚
+	SeverityHigh     Severity = "high"     // 大概率被删除或封号
+	SeverityCritical Severity = "critical" // 违法内容
+)
+
+// rank 返回级别的排序值，未知级别为 0
+func (s Severity) rank() int {
+	switch s {
+	case SeverityLow:
+		return 1
+	case SeverityMedium:
+		return 2
+	case SeverityHigh:
+		return 3
+	case SeverityCritical:
+		return 4
+	}
+	return 0
+}
+
+// AtLeast 是否不低于另一个级别，other 为空时返回 false
+func (s Severity) AtLeast(other Severity) bool {
+	return other.rank() > 0 && s.rank() >= other.rank()
+}
+
+// parseSeverity 解析级别，无法识别时返回 fallback
+func parseSeverity(s string, fallback Severity) Severity {
+	if sev := Severity(strings.ToLower(strings.TrimSpace(s))); sev.rank() > 0 {
+		return sev
+	}
+	return fallback
+}
+
+// Category 问题类别
+type Category string
+
+const (
+	CategorySensitive Category = "sensitive" // 敏感词
+	CategoryPlatform  Category = "platform"  // 平台禁用词
+	CategoryAdLaw     Category = "ad_law"    // 广告法绝对化用语
+	CategoryAI        Category = "ai"        // AI 审核发现
+)
+
+// defaultReasons 词条未填写原因时使用的说明
+var defaultReasons = map[Category]string{
+	CategorySensitive: "涉及敏感内容",
+	CategoryPlatform:  "平台禁止或限流的导流用语",
+	CategoryAdLaw:     "违反广告法的绝对化用语",
+}
+
+// MatchKind 命中方式
+type MatchKind string
+
+const (
+	MatchExact   MatchKind = "exact"   // 与词条一致（忽略大小写、全半角、繁简和插入的符号）
+	MatchVariant MatchKind = "variant" // 命中词条配置的变体写法
+	MatchPinyin  MatchKind = "pinyin"  // 部分或全部用拼音书写
+	MatchAI      MatchKind = "ai"      // AI 审核给出
+)
+
+// Term 词条
+type Term struct {
+	Word     string   `json:"word"`
+	Severity Severity `json:"severity"`
+	// Replacement 建议替换成的文本，为空表示建议删除
+	Replacement string `json:"replacement,omitempty"`
+	// Variants 谐音字、缩写等变体写法
+	Variants []string `json:"variants,omitempty"`
+	// Exceptions 包含该词的正常用语，如"第一"的"第一次"
+	Exceptions []string `json:"exceptions,omitempty"`
+	// Pinyin 每个字的拼音，以空格分隔；为空时使用内置拼音表，"-" 表示不做拼音匹配
+	Pinyin string `json:"pinyin,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Issue 检查发现的问题
+// Start、End 为问题在字段中的字符（rune）偏移，Line 为 0 表示未能在原文中定位
+type Issue struct {
+	Category    Category  `json:"category"`
+	Severity    Severity  `json:"severity"`
+	Match       MatchKind `json:"match"`
+	Field       string    `json:"field"`
+	Term        string    `json:"term"`
+	Text        string    `json:"text"`
+	Start       int       `json:"start"`
+	End         int       `json:"end"`
+	Line        int       `json:"line"`
+	Column      int       `json:"column"`
+	Platform    string    `json:"platform,omitempty"`
+	Reason      string    `json:"reason"`
+	Replacement string    `json:"replacement"`
+	Suggestion  string    `json:"suggestion"`
+}
+
+// unit 归一化后的有效字符，r 为 0 表示句子边界
+type unit struct {
+	r   rune
+	pos int
+}
+
+// scanned 预处理后的文本
+type scanned struct {
+	runes []rune
+	units []unit
+}
+
+// scan 归一化文本并跳过空白、标点和符号，使"敏 感""敏*感"等拆分写法也能命中
+// 换行和句末标点保留为边界，避免跨句匹配
+func scan(text string) *scanned {
+	runes := []rune(text)
+	s := &scanned{runes: runes, units: make([]unit, 0, len(runes))}
+	for i, r := range runes {
+		n := normalizeRune(r)
+		switch {
+		case unicode.Is(unicode.Han, n) || unicode.IsLetter(n) || unicode.IsDigit(n) || n == '%':
+			s.units = append(s.units, unit{r: n, pos: i})
+		case strings.ContainsRune("\n\r。！？!?；;", n):
+			s.units = append(s.units, unit{pos: i})
+		}
+	}
+	return s
+}
+
+// normalizeRune 全角转半角、繁体转简体并转为小写
+func normalizeRune(r rune) rune {
+	switch {
+	case r == 0x3000:
+		return ' '
+	case r >= 0xFF01 && r <= 0xFF5E:
+		r -= 0xFEE0
+	}
+	if simplified, ok := traditionalChars[r]; ok {
+		r = simplified
+	}
+	return unicode.ToLower(r)
+}
+
+// normalizeWord 按与正文相同的规则归一化词条
+func normalizeWord(word string) []rune {
+	s := scan(word)
+	out := make([]rune, 0, len(s.units))
+	for _, u := range s.units {
+		if u.r != 0 {
+			out = append(out, u.r)
+		}
+	}
+	return out
+}
+
+// isLatin 是否为 ASCII 字母
+func isLatin(r rune) bool {
+	return r < unicode.MaxASCII && unicode.IsLetter(r)
+}
+
+// charClass 字母和数字需要词边界，汉字不需要
+func charClass(r rune) int {
+	switch {
+	case isLatin(r):
+		return 1
+	case r < unicode.MaxASCII && unicode.IsDigit(r):
+		return 2
+	}
+	return 0
+}
+
+// bounded 检查 units[start:end] 两端是否为词边界
+// 字母或数字开头结尾的匹配，不能与原文中紧邻的同类字符相连，避免命中单词的一部分
+func (s *scanned) bounded(start, end int) bool {
+	if start > 0 {
+		prev, first := s.units[start-1], s.units[start]
+		if c := charClass(first.r); c != 0 && prev.pos == first.pos-1 && charClass(prev.r) == c {
+			return false
+		}
+	}
+	if end < len(s.units) {
+		next, last := s.units[end], s.units[end-1]
+		if c := charClass(last.r); c != 0 && next.pos == last.pos+1 && charClass(next.r) == c {
+			return false
+		}
+	}
+	return true
+}
+
+// equalAt 判断从 units[i] 开始是否与 pattern 一致
+func (s *scanned) equalAt(i int, pattern []rune) bool {
+	if i < 0 || i+len(pattern) > len(s.units) {
+		return false
+	}
+	for j, r := range pattern {
+		if s.units[i+j].r != r {
+			return false
+		}
+	}
+	return true
+}
+
+// issueAt 生成 units[start:end] 对应的问题
+func (s *scanned) issueAt(start, end int) Issue {
+	from, to := s.units[start].pos, s.units[end-1].pos+1
+	line, column := 1, 1
+	for _, r := range s.runes[:from] {
+		if r == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return Issue{
+		Text:   string(s.runes[from:to]),
+		Start:  from,
+		End:    to,
+		Line:   line,
+		Column: column,
+	}
+}
+
+// pattern 词条的一种写法
+type pattern struct {
+	term  *compiledTerm
+	runes []rune
+	kind  MatchKind
+}
+
+// compiledTerm 预处理后的词条
+type compiledTerm struct {
+	Term
+	word       []rune
+	syllables  []string
+	exceptions [][]rune
+}
+
+// excepted 命中位置是否属于例外用语
+func (t *compiledTerm) excepted(s *scanned, i int, p []rune) bool {
+	for _, ex := range t.exceptions {
+		for k := 0; k+len(p) <= len(ex); k++ {
+			if string(ex[k:k+len(p)]) == string(p) && s.equalAt(i-k, ex) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchPinyin 从 units[i] 开始按音节匹配，每个音节可以是词条原字，也可以是拼音字母
+// 至少有一个音节用字母书写才算拼音匹配，纯汉字的情况由精确匹配处理
+func (t *compiledTerm) matchPinyin(s *scanned, i int) (int, bool) {
+	spelled := false
+	for j, syllable := range t.syllables {
+		if i >= len(s.units) {
+			return 0, false
+		}
+		if s.units[i].r == t.word[j] {
+			i++
+			continue
+		}
+		if !s.equalAt(i, []rune(syllable)) {
+			return 0, false
+		}
+		i += len(syllable)
+		spelled = true
+	}
+	return i, spelled
+}
+
+// Dictionary 词典，按首字索引词条的各种写法
+type Dictionary struct {
+	mu       sync.RWMutex
+	category Category
+	pinyin   bool
+	terms    []*compiledTerm
+	patterns map[rune][]*pattern
+	spelled  map[rune][]*compiledTerm
+}
+
+// NewDictionary 创建词典，matchPinyin 为 true 时同时匹配拼音写法
+func NewDictionary(category Category, matchPinyin bool, terms ...Term) *Dictionary {
+	d := &Dictionary{
+		category: category,
+		pinyin:   matchPinyin,
+		patterns: make(map[rune][]*pattern),
+		spelled:  make(map[rune][]*compiledTerm),
+	}
+	d.Add(terms...)
+	return d
+}
+
+// Add 添加词条
+func (d *Dictionary) Add(terms ...Term) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, term := range terms {
+		word := normalizeWord(term.Word)
+		if len(word) == 0 {
+			continue
+		}
+		if term.Severity.rank() == 0 {
+			term.Severity = SeverityMedium
+		}
+		if term.Reason == "" {
+			term.Reason = defaultReasons[d.category]
+		}
+
+		t := &compiledTerm{Term: term, word: word}
+		for _, ex := range term.Exceptions {
+			if r := normalizeWord(ex); len(r) > 0 {
+				t.exceptions = append(t.exceptions, r)
+			}
+		}
+		d.terms = append(d.terms, t)
+
+		d.index(&pattern{term: t, runes: word, kind: MatchExact})
+		for _, v := range term.Variants {
+			if r := normalizeWord(v); len(r) > 0 {
+				d.index(&pattern{term: t, runes: r, kind: MatchVariant})
+			}
+		}
+
+		if d.pinyin {
+			t.syllables = syllablesFor(term.Pinyin, word)
+			if len(t.syllables) > 0 {
+				d.spelled[word[0]] = append(d.spelled[word[0]], t)
+				if first := rune(t.syllables[0][0]); first != word[0] {
+					d.spelled[first] = append(d.spelled[first], t)
+				}
+			}
+		}
+	}
+}
+
+func (d *Dictionary) index(p *pattern) {
+	d.patterns[p.runes[0]] = append(d.patterns[p.runes[0]], p)
+}
+
+// syllablesFor 返回词条每个字的拼音，无法得到完整拼音时返回 nil
+func syllablesFor(pinyin string, word []rune) []string {
+	if pinyin == "-" {
+		return nil
+	}
+	if pinyin != "" {
+		syllables := strings.Fields(strings.ToLower(pinyin))
+		if len(syllables) != len(word) {
+			return nil
+		}
+		return syllables
+	}
+
+	syllables := make([]string, len(word))
+	for i, r := range word {
+		if isLatin(r) {
+			syllables[i] = string(r)
+			continue
+		}
+		p, ok := pinyinTable[r]
+		if !ok {
+			return nil
+		}
+		syllables[i] = p
+	}
+	return syllables
+}
+
+// Len 返回词条数
+func (d *Dictionary) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.terms)
+}
+
+// Find 查找文本中命中的词条
+func (d *Dictionary) Find(text string) []Issue {
+	return d.find(scan(text))
+}
+
+func (d *Dictionary) find(s *scanned) []Issue {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var issues []Issue
+	add := func(t *compiledTerm, start, end int, kind MatchKind) {
+		issue := s.issueAt(start, end)
+		issue.Category = d.category
+		issue.Severity = t.Severity
+		issue.Match = kind
+		issue.Term = t.Word
+		issue.Reason = t.Reason
+		issue.Replacement = t.Replacement
+		issue.Suggestion = suggestionFor(issue.Text, t.Replacement)
+		issues = append(issues, issue)
+	}
+
+	for i, u := range s.units {
+		if u.r == 0 {
+			continue
+		}
+		matched := make(map[*compiledTerm]bool)
+		for _, p := range d.patterns[u.r] {
+			end := i + len(p.runes)
+			if matched[p.term] || !s.equalAt(i, p.runes) || !s.bounded(i, end) || p.term.excepted(s, i, p.runes) {
+				continue
+			}
+			matched[p.term] = true
+			add(p.term, i, end, p.kind)
+		}
+		for _, t := range d.spelled[u.r] {
+			if matched[t] {
+				continue
+			}
+			if end, ok := t.matchPinyin(s, i); ok && s.bounded(i, end) {
+				matched[t] = true
+				add(t, i, end, MatchPinyin)
+			}
+		}
+	}
+	return issues
+}
+
+// suggestionFor 生成修改建议
+func suggestionFor(text, replacement string) string {
+	if replacement == "" {
+		return "建议删除“" + text + "”"
+	}
+	return "建议将“" + text + "”改为“" + replacement + "”"
+}
@@ -0,0 +1,62 @@
+package compliance
+
+import (
+	"testing"
+)
+
+func TestDictionaryVariants(t *testing.T) {
+	d := NewDictionary(CategorySensitive, true, builtinSensitive()...)
+
+	tests := []struct {
+		text  string
+		term  string
+		match MatchKind
+		found string
+	}{
+		{"周末一起去赌博吧", "赌博", MatchExact, "赌博"},
+		{"一起去 賭 * 博 吧", "赌博", MatchExact, "賭 * 博"},
+		{"这里可以DU博", "赌博", MatchPinyin, "DU博"},
+		{"有人在 dubo 吗", "赌博", MatchPinyin, "dubo"},
+		{"千万别去堵博", "赌博", MatchVariant, "堵博"},
+		{"ＳＥ情内容", "色情", MatchPinyin, "ＳＥ情"},
+	}
+	for _, tt := range tests {
+		issues := d.Find(tt.text)
+		if len(issues) != 1 {
+			t.Errorf("%q: expected 1 issue, got %+v", tt.text, issues)
+			continue
+		}
+		got := issues[0]
+		if got.Term != tt.term || got.Match != tt.match || got.Text != tt.found {
+			t.Errorf("%q: unexpected issue %+v", tt.text, got)
+		}
+	}
+
+	clean := []string{
+		"这真是个大麻烦",
+		"银行加强反洗钱监管",
+		"Dubois wrote a book",
+		"赌。博",
+	}
+	for _, text := range clean {
+		if issues := d.Find(text); len(issues) != 0 {
+			t.Errorf("%q: expected no issues, got %+v", text, issues)
+		}
+	}
+}
+
+func TestDictionaryLocation(t *testing.T) {
+	d := NewDictionary(CategoryAdLaw, false, builtinAdLaw()...)
+
+	issues := d.Find("第一次用就爱上了\n这是全网最好的面霜")
+	if len(issues) != 1 {
+		t.Fatalf("Expected only 最好 to match, got %+v", issues)
+	}
+	issue := issues[0]
+	if issue.Term != "最好" || issue.Line != 2 || issue.Column != 5 || issue.Start != 13 || issue.End != 15 {
+		t.Errorf("Unexpected location: %+v", issue)
+	}
+	if issue.Replacement != "很好" || issue.Reason == "" {
+		t.Errorf("Unexpected suggestion: %+v", issue)
+	}
+}
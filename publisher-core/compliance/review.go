@@ -0,0 +1,217 @@
+package compliance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"publisher-core/database"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBlocked 内容被合规策略拦截
+	ErrBlocked = errors.New("内容未通过合规检查")
+	// ErrApprovalRequired 内容需人工审批后才能发布
+	ErrApprovalRequired = errors.New("内容需人工审批")
+)
+
+// ViolationError 合规检查未放行，Report 为检查报告
+type ViolationError struct {
+	Report *Report
+}
+
+func (e *ViolationError) Error() string {
+	var parts []string
+	for _, issue := range e.Report.Issues {
+		if !issue.Severity.AtLeast(SeverityMedium) {
+			continue
+		}
+		if len(parts) == 3 {
+			parts = append(parts, "...")
+			break
+		}
+		if issue.Text != "" {
+			parts = append(parts, fmt.Sprintf("“%s”%s", issue.Text, issue.Reason))
+		} else {
+			parts = append(parts, issue.Reason)
+		}
+	}
+	msg := e.Unwrap().Error()
+	if e.Report.ReviewID > 0 {
+		msg += fmt.Sprintf("（审批单 %d）", e.Report.ReviewID)
+	}
+	if len(parts) > 0 {
+		msg += ": " + strings.Join(parts, "；")
+	}
+	return msg
+}
+
+func (e *ViolationError) Unwrap() error {
+	if e.Report.Decision == DecisionBlock {
+		return ErrBlocked
+	}
+	return ErrApprovalRequired
+}
+
+// ReviewStore 人工审批记录
+type ReviewStore struct {
+	db *gorm.DB
+}
+
+// NewReviewStore 创建审批存储，db 为 nil 时使用全局数据库
+func NewReviewStore(db *gorm.DB) *ReviewStore {
+	if db == nil {
+		db = database.GetDB()
+	}
+	return &ReviewStore{db: db}
+}
+
+// latest 查找相同平台、相同内容的最近一次审批
+func (s *ReviewStore) latest(hash, platform string) (*database.ComplianceReview, error) {
+	var review database.ComplianceReview
+	err := s.db.Where("content_hash = ? AND platform = ?", hash, platform).
+		Order("id DESC").
+		First(&review).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询审批记录失败: %w", err)
+	}
+	return &review, nil
+}
+
+// request 创建待审批记录，已有待审批记录时更新关联的任务
+func (s *ReviewStore) request(content *Content, report *Report, taskID string, pending *database.ComplianceReview) (*database.ComplianceReview, error) {
+	if pending != nil {
+		if taskID != "" && pending.TaskID != taskID {
+			pending.TaskID = taskID
+			if err := s.db.Model(pending).Update("task_id", taskID).Error; err != nil {
+				return nil, fmt.Errorf("更新审批记录失败: %w", err)
+			}
+		}
+		return pending, nil
+	}
+
+	issues, err := json.Marshal(report.Issues)
+	if err != nil {
+		return nil, fmt.Errorf("序列化问题列表失败: %w", err)
+	}
+	review := &database.ComplianceReview{
+		ContentHash: report.ContentHash,
+		Platform:    content.Platform,
+		Title:       content.Title,
+		Body:        content.Body,
+		MaxSeverity: string(report.MaxSeverity),
+		Issues:      string(issues),
+		Status:      database.ComplianceReviewPending,
+		TaskID:      taskID,
+	}
+	if err := s.db.Create(review).Error; err != nil {
+		return nil, fmt.Errorf("创建审批记录失败: %w", err)
+	}
+	return review, nil
+}
+
+// List 按状态列出审批记录，status 为空时列出全部
+func (s *ReviewStore) List(status database.ComplianceReviewStatus, limit int) ([]database.ComplianceReview, error) {
+	query := s.db.Order("id DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var reviews []database.ComplianceReview
+	if err := query.Find(&reviews).Error; err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// Get 获取审批记录
+func (s *ReviewStore) Get(id uint) (*database.ComplianceReview, error) {
+	var review database.ComplianceReview
+	if err := s.db.First(&review, id).Error; err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// Approve 批准，之后相同平台、相同内容的发布不再拦截
+func (s *ReviewStore) Approve(id uint, reviewer, comment string) (*database.ComplianceReview, error) {
+	return s.resolve(id, database.ComplianceReviewApproved, reviewer, comment)
+}
+
+// Reject 驳回，之后相同平台、相同内容的发布直接拦截
+func (s *ReviewStore) Reject(id uint, reviewer, comment string) (*database.ComplianceReview, error) {
+	return s.resolve(id, database.ComplianceReviewRejected, reviewer, comment)
+}
+
+// resolve 处理待审批记录
+func (s *ReviewStore) resolve(id uint, status database.ComplianceReviewStatus, reviewer, comment string) (*database.ComplianceReview, error) {
+	now := time.Now()
+	result := s.db.Model(&database.ComplianceReview{}).
+		Where("id = ? AND status = ?", id, database.ComplianceReviewPending).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"reviewer":    reviewer,
+			"comment":     comment,
+			"reviewed_at": now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("审批记录不存在或已处理: %d", id)
+	}
+	return s.Get(id)
+}
+
+// Enforce 检查内容并执行策略
+// 需审批的内容已被批准时放行，已被驳回时拦截，否则创建待审批记录；
+// 未放行时返回 *ViolationError，可用 errors.Is 区分 ErrBlocked 和 ErrApprovalRequired。
+// taskID 为触发检查的发布任务，批准后可据此重新执行任务
+func (c *Checker) Enforce(ctx context.Context, content *Content, taskID string) (*Report, error) {
+	report, err := c.Check(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	reviews := c.reviews
+	c.mu.RUnlock()
+
+	if report.Decision == DecisionReview && reviews != nil {
+		review, err := reviews.latest(report.ContentHash, content.Platform)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case review != nil && review.Status == database.ComplianceReviewApproved:
+			report.Decision = DecisionPass
+			report.ReviewID = review.ID
+		case review != nil && review.Status == database.ComplianceReviewRejected:
+			report.Decision = DecisionBlock
+			report.ReviewID = review.ID
+		default:
+			pending, err := reviews.request(content, report, taskID, review)
+			if err != nil {
+				return nil, err
+			}
+			report.ReviewID = pending.ID
+		}
+	}
+
+	if report.Decision == DecisionPass {
+		return report, nil
+	}
+	return report, &ViolationError{Report: report}
+}
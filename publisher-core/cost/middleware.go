@@ -0,0 +1,527 @@
+package cost
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"publisher-core/ai/provider"
+	"publisher-core/cache"
+
+	"github.com/sirupsen/logrus"
+)
+
+// BudgetAction 预算耗尽时的处理方式
+type BudgetAction string
+
+const (
+	// BudgetActionReject 拒绝调用
+	BudgetActionReject BudgetAction = "reject"
+	// BudgetActionDowngrade 改用低价模型
+	BudgetActionDowngrade BudgetAction = "downgrade"
+	// BudgetActionAllow 仅记录，不拦截
+	BudgetActionAllow BudgetAction = "allow"
+)
+
+// DefaultFunctionType 未指定功能类型时记录的值
+const DefaultFunctionType = "generation"
+
+// ErrBudgetExceeded 预算已耗尽
+var ErrBudgetExceeded = errors.New("预算已耗尽")
+
+// BudgetExceededError 预算耗尽导致调用被拒绝
+type BudgetExceededError struct {
+	UserID    string
+	ProjectID string
+	Status    *BudgetStatus
+}
+
+func (e *BudgetExceededError) Error() string {
+	return fmt.Sprintf("用户 %q 项目 %q 的%s预算已耗尽（已用 $%.4f / $%.4f）",
+		e.UserID, e.ProjectID, e.Status.BudgetType, e.Status.UsedAmount, e.Status.BudgetAmount)
+}
+
+func (e *BudgetExceededError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+type attributionKey struct{}
+
+// Attribution AI 调用的成本归属
+type Attribution struct {
+	UserID    string
+	ProjectID string
+	// Function 调用方功能类型，如 rewrite、hotspot_analysis
+	Function  string
+	RequestID string
+}
+
+// WithAttribution 在上下文中设置成本归属，空字段沿用上层上下文中的值
+func WithAttribution(ctx context.Context, attr Attribution) context.Context {
+	parent := AttributionFromContext(ctx)
+	if attr.UserID == "" {
+		attr.UserID = parent.UserID
+	}
+	if attr.ProjectID == "" {
+		attr.ProjectID = parent.ProjectID
+	}
+	if attr.Function == "" {
+		attr.Function = parent.Function
+	}
+	if attr.RequestID == "" {
+		attr.RequestID = parent.RequestID
+	}
+	return context.WithValue(ctx, attributionKey{}, attr)
+}
+
+// WithFunction 在上下文中设置调用方功能类型
+func WithFunction(ctx context.Context, function string) context.Context {
+	return WithAttribution(ctx, Attribution{Function: function})
+}
+
+// AttributionFromContext 读取上下文中的成本归属
+func AttributionFromContext(ctx context.Context) Attribution {
+	attr, _ := ctx.Value(attributionKey{}).(Attribution)
+	return attr
+}
+
+type noCacheKey struct{}
+
+// WithoutCache 在上下文中标记本次调用不读写响应缓存，用于需要真实生成结果的场景（如模型评测）
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// cacheDisabled 上下文是否标记了不使用响应缓存
+func cacheDisabled(ctx context.Context) bool {
+	disabled, _ := ctx.Value(noCacheKey{}).(bool)
+	return disabled
+}
+
+// TrackerConfig 成本追踪配置
+type TrackerConfig struct {
+	// OnExceeded 预算耗尽时的处理方式，默认拒绝
+	OnExceeded BudgetAction `json:"on_exceeded"`
+	// DowngradeOnWarning 达到预警阈值即改用低价模型
+	DowngradeOnWarning bool `json:"downgrade_on_warning"`
+	// DowngradeModels 各提供商的低价模型，键为提供商名称
+	DowngradeModels map[string]string `json:"downgrade_models"`
+}
+
+// DefaultTrackerConfig 返回默认成本追踪配置
+func DefaultTrackerConfig() *TrackerConfig {
+	return &TrackerConfig{
+		OnExceeded: BudgetActionReject,
+		DowngradeModels: map[string]string{
+			"openrouter": "gpt-3.5-turbo",
+			"google":     "gemini-pro",
+			"deepseek":   "deepseek-chat",
+			"groq":       "mixtral-8x7b-32768",
+			"mistral":    "mistral-small-latest",
+			"nvidia":     "meta/llama-3.1-8b-instruct",
+		},
+	}
+}
+
+// Tracker 在每次 AI 调用前检查预算，调用后记录成本并累计预算用量
+type Tracker struct {
+	costService   *CostService
+	budgetService *BudgetService
+	config        *TrackerConfig
+	cache         *cache.AICacheService // 响应缓存，为空时不缓存
+}
+
+// NewTracker 创建成本追踪器，config 为 nil 时使用默认配置
+func NewTracker(costService *CostService, budgetService *BudgetService, config *TrackerConfig) *Tracker {
+	if config == nil {
+		config = DefaultTrackerConfig()
+	}
+	if config.OnExceeded == "" {
+		config.OnExceeded = BudgetActionReject
+	}
+	return &Tracker{
+		costService:   costService,
+		budgetService: budgetService,
+		config:        config,
+	}
+}
+
+// SetResponseCache 设置响应缓存
+// 命中时不调用提供商，按零成本记录一条 cached 成本记录，供优化建议统计各功能的命中率
+func (t *Tracker) SetResponseCache(c *cache.AICacheService) {
+	t.cache = c
+}
+
+// EstimateCost 按定价估算一次调用的成本（美元）
+func (t *Tracker) EstimateCost(providerName, model string, inputTokens, outputTokens int) float64 {
+	if t.costService == nil {
+		return 0
+	}
+	_, total := t.costService.CalculateCost(providerName, model, inputTokens, outputTokens)
+	return total
+}
+
+// Wrap 为提供商加上成本追踪，已包装的提供商原样返回
+func (t *Tracker) Wrap(p provider.Provider) provider.Provider {
+	if _, ok := p.(*TrackedProvider); ok {
+		return p
+	}
+	return &TrackedProvider{Provider: p, tracker: t}
+}
+
+// trackedCall 单次调用的追踪上下文
+type trackedCall struct {
+	attr          Attribution
+	provider      string
+	model         string
+	downgradeFrom string
+	cached        bool
+	startTime     time.Time
+}
+
+// prepare 检查预算，必要时返回改用低价模型的选项副本
+func (t *Tracker) prepare(ctx context.Context, p provider.Provider, opts *provider.GenerateOptions) (*provider.GenerateOptions, *trackedCall, error) {
+	call := &trackedCall{
+		attr:      AttributionFromContext(ctx),
+		provider:  string(p.Name()),
+		model:     opts.Model,
+		startTime: time.Now(),
+	}
+	if call.model == "" {
+		call.model = p.DefaultModel()
+	}
+	if t.budgetService == nil || t.config.OnExceeded == BudgetActionAllow {
+		return opts, call, nil
+	}
+
+	status, err := t.budgetService.CheckBudget(call.attr.UserID, call.attr.ProjectID)
+	if err != nil {
+		// 预算查询失败不应阻断生成
+		logrus.Warnf("检查预算失败: %v", err)
+		return opts, call, nil
+	}
+	if !status.HasBudget {
+		return opts, call, nil
+	}
+
+	downgrade := status.IsWarning && t.config.DowngradeOnWarning
+	if status.IsExceeded {
+		if t.config.OnExceeded == BudgetActionReject {
+			return nil, nil, &BudgetExceededError{UserID: call.attr.UserID, ProjectID: call.attr.ProjectID, Status: status}
+		}
+		downgrade = true
+	}
+	if !downgrade {
+		return opts, call, nil
+	}
+
+	cheap := t.config.DowngradeModels[call.provider]
+	if cheap == "" {
+		if status.IsExceeded {
+			return nil, nil, &BudgetExceededError{UserID: call.attr.UserID, ProjectID: call.attr.ProjectID, Status: status}
+		}
+		return opts, call, nil
+	}
+	if cheap == call.model {
+		return opts, call, nil
+	}
+
+	logrus.Infof("预算使用 %.1f%%，%s 模型 %s 降级为 %s", status.UsagePercent, call.provider, call.model, cheap)
+	downgraded := *opts
+	downgraded.Model = cheap
+	call.downgradeFrom = call.model
+	call.model = cheap
+	return &downgraded, call, nil
+}
+
+// function 返回调用方功能类型，未指定时为 DefaultFunctionType
+func (call *trackedCall) function() string {
+	if call.attr.Function == "" {
+		return DefaultFunctionType
+	}
+	return call.attr.Function
+}
+
+// record 记录成本并累计预算用量
+func (t *Tracker) record(call *trackedCall, opts *provider.GenerateOptions, inputTokens, outputTokens int, model string, callErr error, metadata map[string]interface{}) {
+	if t.costService == nil {
+		return
+	}
+	if model == "" {
+		model = call.model
+	}
+	if call.downgradeFrom != "" {
+		if metadata == nil {
+			metadata = make(map[string]interface{})
+		}
+		metadata["downgraded_from"] = call.downgradeFrom
+	}
+
+	input := &CostRecordInput{
+		Provider:     call.provider,
+		Model:        model,
+		UserID:       call.attr.UserID,
+		ProjectID:    call.attr.ProjectID,
+		FunctionType: call.function(),
+		InputTokens:  inputTokens,
+		OutputTokens: outputTokens,
+		DurationMs:   int(time.Since(call.startTime).Milliseconds()),
+		Success:      callErr == nil,
+		RequestID:    call.attr.RequestID,
+		Cached:       call.cached,
+		Prompt:       promptText(opts.Messages),
+		Metadata:     metadata,
+	}
+	if callErr != nil {
+		input.ErrorMessage = callErr.Error()
+	}
+
+	record, err := t.costService.RecordCost(input)
+	if err != nil {
+		logrus.Warnf("记录AI调用成本失败: %v", err)
+		return
+	}
+	if t.budgetService == nil || record.TotalCost <= 0 {
+		return
+	}
+	if err := t.budgetService.UpdateUsedAmount(call.attr.UserID, call.attr.ProjectID, record.TotalCost); err != nil {
+		logrus.Warnf("更新预算用量失败: %v", err)
+	}
+}
+
+// TrackedProvider 带成本追踪和预算控制的提供商
+type TrackedProvider struct {
+	provider.Provider
+	tracker *Tracker
+}
+
+// Unwrap 返回被包装的提供商
+func (p *TrackedProvider) Unwrap() provider.Provider {
+	return p.Provider
+}
+
+func (p *TrackedProvider) Generate(ctx context.Context, opts *provider.GenerateOptions) (*provider.GenerateResult, error) {
+	if result := p.tracker.lookupCache(ctx, p.Provider, opts); result != nil {
+		return result, nil
+	}
+
+	opts, call, err := p.tracker.prepare(ctx, p.Provider, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.Provider.Generate(ctx, opts)
+	if err != nil {
+		p.tracker.record(call, opts, 0, 0, "", err, nil)
+		return nil, err
+	}
+
+	p.tracker.record(call, opts, result.InputTokens, result.OutputTokens, result.Model, nil, nil)
+	p.tracker.storeCache(ctx, call, opts, result)
+	return result, nil
+}
+
+// GenerateStream 流式接口不返回用量，结束后按模型分词器估算 token 数
+func (p *TrackedProvider) GenerateStream(ctx context.Context, opts *provider.GenerateOptions) (<-chan string, error) {
+	opts, call, err := p.tracker.prepare(ctx, p.Provider, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	ch, err := p.Provider.GenerateStream(ctx, opts)
+	if err != nil {
+		p.tracker.record(call, opts, 0, 0, "", err, nil)
+		return nil, err
+	}
+
+	out := make(chan string)
+	go func() {
+		defer close(out)
+
+		var output strings.Builder
+		forwarding := true
+		for chunk := range ch {
+			output.WriteString(chunk)
+			if !forwarding {
+				continue
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				// 调用方已放弃，继续读完上游以便统计已生成的内容
+				forwarding = false
+			}
+		}
+
+		metadata := map[string]interface{}{"stream": true, "estimated_tokens": true}
+		pt := p.Provider.Name()
+		p.tracker.record(call, opts, provider.CountMessageTokens(pt, call.model, opts.Messages), provider.CountTokens(pt, call.model, output.String()), "", ctx.Err(), metadata)
+	}()
+
+	return out, nil
+}
+
+// EmbeddingFunctionType 向量化调用未指定功能类型时记录的值
+const EmbeddingFunctionType = "embedding"
+
+// DefaultEmbeddingModel 返回被包装提供商的默认向量模型，不支持向量化时为空
+func (p *TrackedProvider) DefaultEmbeddingModel() string {
+	if e, ok := provider.AsEmbedder(p.Provider); ok {
+		return e.DefaultEmbeddingModel()
+	}
+	return ""
+}
+
+// Embed 向量化并记录成本，被包装的提供商不支持时返回 provider.ErrEmbeddingNotSupported
+func (p *TrackedProvider) Embed(ctx context.Context, opts *provider.EmbedOptions) (*provider.EmbedResult, error) {
+	e, ok := provider.AsEmbedder(p.Provider)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", p.Provider.Name(), provider.ErrEmbeddingNotSupported)
+	}
+
+	call := &trackedCall{
+		attr:      AttributionFromContext(ctx),
+		provider:  string(p.Provider.Name()),
+		model:     opts.Model,
+		startTime: time.Now(),
+	}
+	if call.attr.Function == "" {
+		call.attr.Function = EmbeddingFunctionType
+	}
+	if call.model == "" {
+		call.model = e.DefaultEmbeddingModel()
+	}
+
+	result, err := e.Embed(ctx, opts)
+	if err != nil {
+		p.tracker.record(call, &provider.GenerateOptions{}, 0, 0, "", err, nil)
+		return nil, err
+	}
+	p.tracker.record(call, &provider.GenerateOptions{}, result.InputTokens, 0, result.Model, nil, map[string]interface{}{"inputs": len(opts.Input)})
+	return result, nil
+}
+
+// cacheable 判断调用结果能否缓存，工具调用和图片输入依赖调用时的上下文，不缓存
+func cacheable(opts *provider.GenerateOptions) bool {
+	if len(opts.Tools) > 0 {
+		return false
+	}
+	for _, msg := range opts.Messages {
+		if len(msg.Images) > 0 || len(msg.ToolCalls) > 0 || msg.Role == provider.RoleTool {
+			return false
+		}
+	}
+	return len(opts.Messages) > 0
+}
+
+// lookupCache 查找缓存的响应，命中时记录一条零成本的 cached 记录
+func (t *Tracker) lookupCache(ctx context.Context, p provider.Provider, opts *provider.GenerateOptions) *provider.GenerateResult {
+	if t.cache == nil || !cacheable(opts) || cacheDisabled(ctx) {
+		return nil
+	}
+
+	call := &trackedCall{
+		attr:      AttributionFromContext(ctx),
+		provider:  string(p.Name()),
+		model:     opts.Model,
+		cached:    true,
+		startTime: time.Now(),
+	}
+	if call.model == "" {
+		call.model = p.DefaultModel()
+	}
+
+	value, match, ok := t.cache.LookupResponse(ctx, responseKey(call, opts))
+	if !ok {
+		return nil
+	}
+
+	t.record(call, opts, 0, 0, value.Model, nil, map[string]interface{}{
+		"cache":        string(match.Type),
+		"similarity":   match.Similarity,
+		"saved_tokens": value.TokensUsed,
+	})
+	return &provider.GenerateResult{
+		Content:    value.Response,
+		Model:      value.Model,
+		Provider:   value.Provider,
+		FinishedAt: time.Now(),
+	}
+}
+
+// storeCache 缓存成功的响应
+func (t *Tracker) storeCache(ctx context.Context, call *trackedCall, opts *provider.GenerateOptions, result *provider.GenerateResult) {
+	if t.cache == nil || !cacheable(opts) || cacheDisabled(ctx) || len(result.ToolCalls) > 0 || result.Content == "" {
+		return
+	}
+
+	// 降级后的结果按实际模型缓存，不会被原模型的请求命中
+	value := &cache.AICacheValue{
+		Response:   result.Content,
+		TokensUsed: result.InputTokens + result.OutputTokens,
+		Model:      result.Model,
+		Provider:   call.provider,
+	}
+	if err := t.cache.StoreResponse(ctx, responseKey(call, opts), value, 0); err != nil {
+		logrus.Warnf("缓存AI响应失败: %v", err)
+	}
+}
+
+// responseKey 响应缓存键，按调用的功能和成本归属隔离
+// 相似度只按用户输入计算，系统提示词和生成参数须完全相同才会语义命中
+func responseKey(call *trackedCall, opts *provider.GenerateOptions) cache.ResponseKey {
+	var userInput, fixed []string
+	for _, msg := range opts.Messages {
+		if msg.Role == provider.RoleUser {
+			userInput = append(userInput, msg.Content)
+			continue
+		}
+		fixed = append(fixed, string(msg.Role)+": "+msg.Content)
+	}
+	fixed = append(fixed, generationParams(opts))
+
+	return cache.ResponseKey{
+		Function:     call.function(),
+		Provider:     call.provider,
+		Model:        call.model,
+		UserID:       call.attr.UserID,
+		ProjectID:    call.attr.ProjectID,
+		Prompt:       cacheKeyText(opts),
+		SemanticText: strings.Join(userInput, "\n"),
+		Context:      strings.Join(fixed, "\n"),
+	}
+}
+
+// cacheKeyText 缓存键文本，包含角色以区分系统提示词和用户输入，并包含影响输出的生成参数
+func cacheKeyText(opts *provider.GenerateOptions) string {
+	parts := make([]string, 0, len(opts.Messages)+1)
+	for _, msg := range opts.Messages {
+		parts = append(parts, string(msg.Role)+": "+msg.Content)
+	}
+	parts = append(parts, generationParams(opts))
+	return strings.Join(parts, "\n")
+}
+
+// generationParams 影响输出的生成参数
+func generationParams(opts *provider.GenerateOptions) string {
+	params, _ := json.Marshal(struct {
+		ResponseFormat *provider.ResponseFormat `json:"response_format,omitempty"`
+		Temperature    float64                  `json:"temperature"`
+		MaxTokens      int                      `json:"max_tokens"`
+		TopP           float64                  `json:"top_p"`
+		Stop           []string                 `json:"stop,omitempty"`
+	}{opts.ResponseFormat, opts.Temperature, opts.MaxTokens, opts.TopP, opts.Stop})
+	return "params: " + string(params)
+}
+
+// promptText 拼接消息内容
+func promptText(messages []provider.Message) string {
+	parts := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		parts = append(parts, msg.Content)
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,256 @@
+package cost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"publisher-core/ai/provider"
+	"publisher-core/cache"
+	"publisher-core/database"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type fakeProvider struct {
+	models []string
+}
+
+func (p *fakeProvider) Name() provider.ProviderType { return provider.ProviderDeepSeek }
+func (p *fakeProvider) Models() []string            { return []string{"deepseek-chat", "deepseek-reasoner"} }
+func (p *fakeProvider) DefaultModel() string        { return "deepseek-reasoner" }
+
+func (p *fakeProvider) Generate(ctx context.Context, opts *provider.GenerateOptions) (*provider.GenerateResult, error) {
+	p.models = append(p.models, opts.Model)
+	return &provider.GenerateResult{Content: "ok", Model: opts.Model, InputTokens: 1000, OutputTokens: 1000}, nil
+}
+
+func (p *fakeProvider) GenerateStream(ctx context.Context, opts *provider.GenerateOptions) (<-chan string, error) {
+	ch := make(chan string, 2)
+	ch <- "你好"
+	ch <- "世界"
+	close(ch)
+	return ch, nil
+}
+
+func newTestTracker(t *testing.T, config *TrackerConfig) (*Tracker, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&database.AICostRecord{}, &database.AIBudget{}, &database.AICostAlert{}, &database.AIModelPricing{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	costService := NewCostService(db)
+	return NewTracker(costService, NewBudgetService(db, costService), config), db
+}
+
+func createBudget(t *testing.T, db *gorm.DB, amount, used float64) {
+	budget := &database.AIBudget{
+		UserID:         "u1",
+		ProjectID:      "p1",
+		BudgetType:     "monthly",
+		BudgetAmount:   amount,
+		UsedAmount:     used,
+		AlertThreshold: 80,
+		IsActive:       true,
+		StartDate:      time.Now().Add(-time.Hour),
+		EndDate:        time.Now().Add(time.Hour),
+		LastResetAt:    time.Now(),
+	}
+	if err := db.Create(budget).Error; err != nil {
+		t.Fatalf("Failed to create budget: %v", err)
+	}
+}
+
+func TestTrackedProviderRecordsCost(t *testing.T) {
+	tracker, db := newTestTracker(t, nil)
+	createBudget(t, db, 10, 0)
+
+	ctx := WithAttribution(context.Background(), Attribution{UserID: "u1", ProjectID: "p1"})
+	ctx = WithFunction(ctx, "rewrite")
+
+	p := tracker.Wrap(&fakeProvider{})
+	if _, err := p.Generate(ctx, &provider.GenerateOptions{Model: "deepseek-chat"}); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	var record database.AICostRecord
+	if err := db.First(&record).Error; err != nil {
+		t.Fatalf("Expected cost record: %v", err)
+	}
+	if record.UserID != "u1" || record.ProjectID != "p1" || record.FunctionType != "rewrite" || record.TotalTokens != 2000 {
+		t.Errorf("Unexpected record: %+v", record)
+	}
+	if record.TotalCost <= 0 {
+		t.Errorf("Expected positive cost, got %f", record.TotalCost)
+	}
+
+	var budget database.AIBudget
+	db.First(&budget)
+	if budget.UsedAmount != record.TotalCost {
+		t.Errorf("Expected used amount %f, got %f", record.TotalCost, budget.UsedAmount)
+	}
+}
+
+func TestTrackedProviderRejectsWhenBudgetExceeded(t *testing.T) {
+	tracker, db := newTestTracker(t, nil)
+	createBudget(t, db, 1, 1)
+
+	inner := &fakeProvider{}
+	ctx := WithAttribution(context.Background(), Attribution{UserID: "u1", ProjectID: "p1"})
+	_, err := tracker.Wrap(inner).Generate(ctx, &provider.GenerateOptions{})
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Expected ErrBudgetExceeded, got %v", err)
+	}
+	if len(inner.models) != 0 {
+		t.Error("Provider should not be called when budget is exceeded")
+	}
+
+	// 其他用户不受影响
+	if _, err := tracker.Wrap(inner).Generate(context.Background(), &provider.GenerateOptions{}); err != nil {
+		t.Errorf("Unexpected error without budget: %v", err)
+	}
+}
+
+func TestTrackedProviderDowngradesModel(t *testing.T) {
+	config := DefaultTrackerConfig()
+	config.OnExceeded = BudgetActionDowngrade
+	tracker, db := newTestTracker(t, config)
+	createBudget(t, db, 1, 1)
+
+	inner := &fakeProvider{}
+	opts := &provider.GenerateOptions{}
+	ctx := WithAttribution(context.Background(), Attribution{UserID: "u1", ProjectID: "p1"})
+	if _, err := tracker.Wrap(inner).Generate(ctx, opts); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	if inner.models[0] != "deepseek-chat" {
+		t.Errorf("Expected downgrade to deepseek-chat, got %s", inner.models[0])
+	}
+	if opts.Model != "" {
+		t.Error("Caller options should not be modified")
+	}
+
+	var record database.AICostRecord
+	db.First(&record)
+	if record.Model != "deepseek-chat" || record.Metadata == "" {
+		t.Errorf("Expected downgraded record, got %+v", record)
+	}
+}
+
+func TestTrackedProviderStream(t *testing.T) {
+	tracker, db := newTestTracker(t, nil)
+
+	ch, err := tracker.Wrap(&fakeProvider{}).GenerateStream(context.Background(), &provider.GenerateOptions{
+		Messages: []provider.Message{{Role: provider.RoleUser, Content: "hello world!"}},
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream failed: %v", err)
+	}
+	var content string
+	for chunk := range ch {
+		content += chunk
+	}
+	if content != "你好世界" {
+		t.Errorf("Unexpected content: %s", content)
+	}
+
+	var record database.AICostRecord
+	if err := db.First(&record).Error; err != nil {
+		t.Fatalf("Expected cost record after stream: %v", err)
+	}
+	if record.InputTokens != 12 || record.OutputTokens != 3 || record.Model != "deepseek-reasoner" {
+		t.Errorf("Unexpected stream record: %+v", record)
+	}
+}
+
+func TestTrackedProviderResponseCache(t *testing.T) {
+	tracker, db := newTestTracker(t, nil)
+	aiCache := cache.NewAICacheService(nil)
+	defer aiCache.Stop()
+	aiCache.EnableSemanticCache(nil, nil)
+	tracker.SetResponseCache(aiCache)
+
+	inner := &fakeProvider{}
+	p := tracker.Wrap(inner)
+	ctx := WithFunction(context.Background(), "hotspot_analysis")
+
+	prompts := []string{
+		"请分析今天微博热搜上的科技类热点，并给出三个选题建议",
+		"请分析今天微博热搜上的科技热点，并给出三个选题建议",
+	}
+	for _, prompt := range prompts {
+		result, err := p.Generate(ctx, &provider.GenerateOptions{
+			Messages: []provider.Message{{Role: provider.RoleUser, Content: prompt}},
+		})
+		if err != nil || result.Content != "ok" {
+			t.Fatalf("Generate failed: %v %+v", err, result)
+		}
+	}
+	if len(inner.models) != 1 {
+		t.Errorf("Expected provider to be called once, got %d", len(inner.models))
+	}
+
+	usage, err := NewOptimizationService(db, nil).GetCacheUsageByFunction("", "", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GetCacheUsageByFunction failed: %v", err)
+	}
+	if len(usage) != 1 || usage[0].FunctionType != "hotspot_analysis" || usage[0].CachedCalls != 1 || usage[0].HitRate != 50 {
+		t.Errorf("Unexpected cache usage: %+v", usage)
+	}
+
+	// 其他用户、不同的系统提示词或生成参数都不复用该结果
+	misses := []struct {
+		ctx  context.Context
+		opts *provider.GenerateOptions
+	}{
+		{WithAttribution(ctx, Attribution{UserID: "other"}), &provider.GenerateOptions{
+			Messages: []provider.Message{{Role: provider.RoleUser, Content: prompts[0]}},
+		}},
+		{ctx, &provider.GenerateOptions{
+			Messages: []provider.Message{{Role: provider.RoleSystem, Content: "只输出JSON"}, {Role: provider.RoleUser, Content: prompts[1]}},
+		}},
+		{ctx, &provider.GenerateOptions{
+			Messages:    []provider.Message{{Role: provider.RoleUser, Content: prompts[0]}},
+			Temperature: 0.9,
+		}},
+	}
+	for i, miss := range misses {
+		if _, err := p.Generate(miss.ctx, miss.opts); err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		if len(inner.models) != i+2 {
+			t.Errorf("Expected cache miss for case %d", i)
+		}
+	}
+}
+
+func TestAnalyzeCacheUsageSuggestsCaching(t *testing.T) {
+	_, db := newTestTracker(t, nil)
+	costService := NewCostService(db)
+	for i := 0; i < cacheMinCalls; i++ {
+		prompt := "重复的改写请求"
+		if i%2 == 0 {
+			prompt = fmt.Sprintf("不同的改写请求 %d", i)
+		}
+		costService.RecordCost(&CostRecordInput{
+			Provider: "deepseek", Model: "deepseek-chat", FunctionType: "rewrite",
+			InputTokens: 1000, OutputTokens: 1000, Success: true, Prompt: prompt,
+		})
+	}
+
+	suggestions := NewOptimizationService(db, costService).analyzeCacheUsage("", "", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if len(suggestions) != 1 || suggestions[0].Type != "cache_usage" || !strings.Contains(suggestions[0].Title, "rewrite") {
+		t.Fatalf("Unexpected suggestions: %+v", suggestions)
+	}
+	if !strings.Contains(suggestions[0].Description, "其中 9 次") || suggestions[0].PotentialSavings <= 0 {
+		t.Errorf("Expected 9 repeated calls with savings, got %+v", suggestions[0])
+	}
+}
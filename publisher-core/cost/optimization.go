@@ -108,13 +108,29 @@ func (s *OptimizationService) analyzeModelUsage(stats []ModelCostStats) []Optimi
 	return suggestions
 }
 
-// analyzeCacheUsage 分析缓存使用情况
-func (s *OptimizationService) analyzeCacheUsage(userID, projectID string, startTime, endTime time.Time) []OptimizationSuggestion {
-	var suggestions []OptimizationSuggestion
+// 缓存分析阈值
+const (
+	// cacheMinCalls 功能调用次数达到此值才给出缓存建议
+	cacheMinCalls = 20
+	// cacheTargetHitRate 启用缓存后期望达到的命中率（%）
+	cacheTargetHitRate = 30.0
+)
 
-	// 查询缓存使用情况
-	var totalCalls, cachedCalls int64
-	query := s.db.Model(&database.AICostRecord{}).Where("created_at BETWEEN ? AND ?", startTime, endTime)
+// FunctionCacheUsage 按功能类型的缓存使用情况
+type FunctionCacheUsage struct {
+	FunctionType  string  `json:"function_type"`
+	TotalCalls    int64   `json:"total_calls"`
+	CachedCalls   int64   `json:"cached_calls"`
+	RepeatedCalls int64   `json:"repeated_calls"` // 未命中缓存但提示词与之前完全相同的调用
+	UncachedCost  float64 `json:"uncached_cost"`
+	HitRate       float64 `json:"hit_rate"` // 命中率（%）
+}
+
+// GetCacheUsageByFunction 按功能类型统计缓存命中率
+// 缓存命中时追踪器会记录一条 cached 的零成本记录，因此可以直接从成本记录统计
+func (s *OptimizationService) GetCacheUsageByFunction(userID, projectID string, startTime, endTime time.Time) ([]FunctionCacheUsage, error) {
+	query := s.db.Model(&database.AICostRecord{}).
+		Where("created_at BETWEEN ? AND ? AND success = ?", startTime, endTime, true)
 	if userID != "" {
 		query = query.Where("user_id = ?", userID)
 	}
@@ -122,23 +138,88 @@ func (s *OptimizationService) analyzeCacheUsage(userID, projectID string, startT
 		query = query.Where("project_id = ?", projectID)
 	}
 
-	query.Select("COUNT(*) as total_calls, SUM(CASE WHEN cached THEN 1 ELSE 0 END) as cached_calls").
-		Scan(&struct {
-			TotalCalls  int64
-			CachedCalls int64
-		}{&totalCalls, &cachedCalls})
+	var rows []struct {
+		FunctionType    string
+		TotalCalls      int64
+		CachedCalls     int64
+		HashedCalls     int64
+		DistinctPrompts int64
+		UncachedCost    float64
+	}
+	err := query.Select(`function_type,
+		COUNT(*) as total_calls,
+		SUM(CASE WHEN cached THEN 1 ELSE 0 END) as cached_calls,
+		SUM(CASE WHEN NOT cached AND prompt_hash <> '' THEN 1 ELSE 0 END) as hashed_calls,
+		COUNT(DISTINCT CASE WHEN NOT cached AND prompt_hash <> '' THEN prompt_hash END) as distinct_prompts,
+		SUM(CASE WHEN cached THEN 0 ELSE total_cost END) as uncached_cost`).
+		Group("function_type").
+		Order("uncached_cost DESC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询缓存使用情况失败: %w", err)
+	}
+
+	usage := make([]FunctionCacheUsage, 0, len(rows))
+	for _, row := range rows {
+		item := FunctionCacheUsage{
+			FunctionType:  row.FunctionType,
+			TotalCalls:    row.TotalCalls,
+			CachedCalls:   row.CachedCalls,
+			RepeatedCalls: row.HashedCalls - row.DistinctPrompts,
+			UncachedCost:  row.UncachedCost,
+		}
+		if item.TotalCalls > 0 {
+			item.HitRate = float64(item.CachedCalls) / float64(item.TotalCalls) * 100
+		}
+		usage = append(usage, item)
+	}
+	return usage, nil
+}
+
+// analyzeCacheUsage 分析缓存使用情况
+// 按功能类型给出建议：重复提示词多却没有命中的功能应启用缓存，已启用但命中率低的功能应调整相似度阈值
+func (s *OptimizationService) analyzeCacheUsage(userID, projectID string, startTime, endTime time.Time) []OptimizationSuggestion {
+	var suggestions []OptimizationSuggestion
+
+	usage, err := s.GetCacheUsageByFunction(userID, projectID, startTime, endTime)
+	if err != nil {
+		return suggestions
+	}
+
+	for _, u := range usage {
+		uncached := u.TotalCalls - u.CachedCalls
+		if u.TotalCalls < cacheMinCalls || uncached <= 0 {
+			continue
+		}
+		avgCost := u.UncachedCost / float64(uncached)
 
-	if totalCalls > 0 {
-		cacheHitRate := float64(cachedCalls) / float64(totalCalls) * 100
-		if cacheHitRate < 30 { // 缓存命中率低于30%
+		if u.CachedCalls == 0 {
+			if u.RepeatedCalls == 0 {
+				continue
+			}
+			savings := float64(u.RepeatedCalls) * avgCost
 			suggestions = append(suggestions, OptimizationSuggestion{
 				Type:             "cache_usage",
-				Priority:         "high",
-				Title:            "提高缓存使用率",
-				Description:      fmt.Sprintf("当前缓存命中率仅 %.1f%%，建议优化缓存策略", cacheHitRate),
-				PotentialSavings: float64(totalCalls-cachedCalls) * 0.001, // 假设每次调用$0.001
+				Priority:         cachePriority(savings),
+				Title:            fmt.Sprintf("为 %s 启用响应缓存", u.FunctionType),
+				Description:      fmt.Sprintf("%s 共 %d 次调用未使用缓存，其中 %d 次提示词与之前完全相同", u.FunctionType, u.TotalCalls, u.RepeatedCalls),
+				PotentialSavings: savings,
 				Impact:           "high",
-				Action:           "增加缓存TTL时间，优化缓存键生成策略",
+				Action:           fmt.Sprintf("为成本追踪器设置响应缓存，并为 %s 配置语义相似度阈值", u.FunctionType),
+			})
+			continue
+		}
+
+		if u.HitRate < cacheTargetHitRate {
+			savings := (cacheTargetHitRate - u.HitRate) / 100 * float64(u.TotalCalls) * avgCost
+			suggestions = append(suggestions, OptimizationSuggestion{
+				Type:             "cache_usage",
+				Priority:         cachePriority(savings),
+				Title:            fmt.Sprintf("提高 %s 的缓存命中率", u.FunctionType),
+				Description:      fmt.Sprintf("%s 缓存命中率仅 %.1f%%（%d/%d），其中 %d 次未命中的提示词与之前完全相同", u.FunctionType, u.HitRate, u.CachedCalls, u.TotalCalls, u.RepeatedCalls),
+				PotentialSavings: savings,
+				Impact:           "medium",
+				Action:           fmt.Sprintf("适当降低 %s 的语义相似度阈值或延长缓存TTL", u.FunctionType),
 			})
 		}
 	}
@@ -146,6 +227,18 @@ func (s *OptimizationService) analyzeCacheUsage(userID, projectID string, startT
 	return suggestions
 }
 
+// cachePriority 按潜在节省金额确定建议优先级
+func cachePriority(savings float64) string {
+	switch {
+	case savings >= 1.0:
+		return "high"
+	case savings >= 0.1:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
 // analyzePromptEfficiency 分析提示词效率
 func (s *OptimizationService) analyzePromptEfficiency(userID, projectID string, startTime, endTime time.Time) []OptimizationSuggestion {
 	var suggestions []OptimizationSuggestion
@@ -93,6 +93,7 @@ func autoMigrate(db *gorm.DB) error {
 		&Topic{},
 		&RankHistory{},
 		&CrawlRecord{},
+		&HotEvent{},
 		// 视频处理
 		&Video{},
 		&Transcript{},
@@ -109,6 +110,9 @@ func autoMigrate(db *gorm.DB) error {
 		&PromptTemplate{},
 		&PromptTemplateVersion{},
 		&PromptTemplateABTest{},
+		&PromptExperimentAssignment{},
+		&PromptExperimentOutcome{},
+		&PromptExperimentPost{},
 		&PromptTemplateUsage{},
 		// AI 成本追踪
 		&AICostRecord{},
@@ -120,6 +124,19 @@ func autoMigrate(db *gorm.DB) error {
 		&TaskQueue{},
 		&TaskExecution{},
 		&ScheduledTask{},
+		&TaskDependency{},
+		&TaskRateLimit{},
+		&SchedulerLease{},
+		// 内容合规审批
+		&ComplianceReview{},
+		// 模型离线评测
+		&EvalDataset{},
+		&EvalCase{},
+		&EvalRun{},
+		&EvalResult{},
+		// 流水线
+		&PipelineDefinition{},
+		&PipelineExecutionRecord{},
 	)
 }
 
@@ -138,7 +138,15 @@ func (s *HotspotStorage) Delete(id string) error {
 // DeleteBefore 删除指定时间之前的话题
 func (s *HotspotStorage) DeleteBefore(t time.Time) (int64, error) {
 	result := s.db.Where("created_at < ?", t).Delete(&Topic{})
-	return result.RowsAffected, result.Error
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	// 成员话题都已删除的事件一并删除
+	if err := s.db.Where("id NOT IN (?)", s.db.Model(&Topic{}).Select("event_id").Where("event_id <> ''")).
+		Delete(&HotEvent{}).Error; err != nil {
+		logrus.Warnf("Failed to delete orphan hot events: %v", err)
+	}
+	return result.RowsAffected, nil
 }
 
 // GetByTitle 根据标题获取话题
@@ -245,6 +253,58 @@ func (s *HotspotStorage) GetTrendingTopics(limit int) ([]Topic, error) {
 	return topics, err
 }
 
+// EventFilter 热点事件过滤条件
+type EventFilter struct {
+	Since      time.Time // 最近出现时间不早于 Since
+	MinSources int       // 至少覆盖的来源数
+	Limit      int
+	Offset     int
+}
+
+// ListEvents 按热度列出热点事件
+func (s *HotspotStorage) ListEvents(filter EventFilter) ([]HotEvent, int64, error) {
+	query := s.db.Model(&HotEvent{})
+
+	if !filter.Since.IsZero() {
+		query = query.Where("last_seen_at >= ?", filter.Since)
+	}
+	if filter.MinSources > 0 {
+		query = query.Where("source_count >= ?", filter.MinSources)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query = query.Order("heat desc")
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var events []HotEvent
+	err := query.Find(&events).Error
+	return events, total, err
+}
+
+// GetEvent 获取热点事件及其成员话题，成员按热度排序
+func (s *HotspotStorage) GetEvent(id string) (*HotEvent, error) {
+	var event HotEvent
+	err := s.db.Preload("Topics", func(db *gorm.DB) *gorm.DB {
+		return db.Order("heat desc")
+	}).Where("id = ?", id).First(&event).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
 // TopicWithKeywords 带关键词的话题
 type TopicWithKeywords struct {
 	Topic
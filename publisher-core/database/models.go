@@ -70,6 +70,7 @@ type Topic struct {
 	OriginalURL    string    `gorm:"size:1000" json:"original_url"`
 	Keywords       string    `gorm:"type:text" json:"keywords"` // JSON 数组
 	Suitability    int       `gorm:"default:0" json:"suitability"`
+	EventID        string    `gorm:"index;size:64" json:"event_id,omitempty"` // 所属的跨来源热点事件
 	PublishedAt    time.Time `json:"published_at"`
 	FirstCrawlTime time.Time `json:"first_crawl_time"`
 	LastCrawlTime  time.Time `json:"last_crawl_time"`
@@ -113,6 +114,27 @@ func (CrawlRecord) TableName() string {
 	return "crawl_records"
 }
 
+// HotEvent 热点事件，不同来源报道同一事件的话题聚合为一个事件
+type HotEvent struct {
+	ID          string    `gorm:"primaryKey;size:64" json:"id"`
+	Title       string    `gorm:"size:500;not null" json:"title"` // 热度最高的成员话题标题
+	Category    string    `gorm:"size:50" json:"category"`
+	Heat        int       `gorm:"default:0;index" json:"heat"` // 各来源热度最高的成员话题热度之和
+	TopicCount  int       `gorm:"default:0" json:"topic_count"`
+	SourceCount int       `gorm:"default:0;index" json:"source_count"`
+	Sources     string    `gorm:"type:text" json:"sources"` // JSON 数组
+	FirstSeenAt time.Time `gorm:"index" json:"first_seen_at"`
+	LastSeenAt  time.Time `gorm:"index" json:"last_seen_at"`
+	Topics      []Topic   `gorm:"foreignKey:EventID" json:"topics,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (HotEvent) TableName() string {
+	return "hot_events"
+}
+
 // =====================================================
 // 视频处理模型
 // =====================================================
@@ -269,8 +291,13 @@ type PromptTemplate struct {
 	Category    string         `gorm:"size:50;index" json:"category"`                    // 分类标签
 	Description string         `gorm:"type:text" json:"description"`                     // 模板描述
 	Content     string         `gorm:"type:text;not null" json:"content"`                // 模板内容（支持变量占位符）
+	Format      string         `gorm:"size:20;default:text" json:"format"`               // 模板格式：text 单条提示词，chat 多条消息
+	Messages    string         `gorm:"type:text" json:"messages"`                        // JSON格式的消息模板列表（chat 格式）
+	Parameters  string         `gorm:"type:text" json:"parameters"`                      // JSON格式的默认生成参数
 	Variables   string         `gorm:"type:text" json:"variables"`                       // JSON格式的变量定义
-	Version     int            `gorm:"default:1" json:"version"`                         // 版本号
+	Version     int            `gorm:"default:1" json:"version"`                         // 线上版本号
+	RolloutVersion int         `gorm:"default:0" json:"rollout_version"`                 // 灰度发布中的版本号，0 表示没有灰度
+	RolloutPercent int         `gorm:"default:0" json:"rollout_percent"`                 // 灰度流量百分比
 	IsActive    bool           `gorm:"default:true;index" json:"is_active"`              // 是否激活
 	IsDefault   bool           `gorm:"default:false" json:"is_default"`                  // 是否默认模板
 	IsSystem    bool           `gorm:"default:false" json:"is_system"`                   // 是否系统模板（不可删除）
@@ -292,10 +319,15 @@ type PromptTemplateVersion struct {
 	TemplateID string    `gorm:"index;size:100;not null" json:"template_id"`
 	Version    int       `gorm:"not null" json:"version"`
 	Content    string    `gorm:"type:text;not null" json:"content"`
+	Format     string    `gorm:"size:20;default:text" json:"format"`
+	Messages   string    `gorm:"type:text" json:"messages"`
+	Parameters string    `gorm:"type:text" json:"parameters"`
 	Variables  string    `gorm:"type:text" json:"variables"`
-	ChangeNote string    `gorm:"type:text" json:"change_note"` // 变更说明
-	CreatedBy  string    `gorm:"size:100" json:"created_by"`   // 创建者
+	ChangeNote string    `gorm:"type:text" json:"change_note"`                   // 变更说明
+	Status     string    `gorm:"size:20;default:published;index" json:"status"` // 状态：draft 草稿, rollout 灰度中, published 已发布
+	CreatedBy  string    `gorm:"size:100" json:"created_by"`                     // 创建者
 	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
 
 // TableName 指定表名
@@ -303,28 +335,25 @@ func (PromptTemplateVersion) TableName() string {
 	return "prompt_template_versions"
 }
 
-// PromptTemplateABTest 提示词模板A/B测试
+// PromptTemplateABTest 提示词模板实验，支持多个变体
 type PromptTemplateABTest struct {
-	ID             uint      `gorm:"primaryKey" json:"id"`
-	TestName       string    `gorm:"size:200;not null" json:"test_name"`        // 测试名称
-	TemplateAID    string    `gorm:"size:100;not null" json:"template_a_id"`    // 模板A的ID
-	TemplateBID    string    `gorm:"size:100;not null" json:"template_b_id"`    // 模板B的ID
-	Status         string    `gorm:"size:20;default:running;index" json:"status"` // running, completed, paused
-	TrafficSplit   int       `gorm:"default:50" json:"traffic_split"`           // A/B流量分配（0-100，表示A的流量百分比）
-	TotalCalls     int       `gorm:"default:0" json:"total_calls"`              // 总调用次数
-	CallsA         int       `gorm:"default:0" json:"calls_a"`                  // 模板A调用次数
-	CallsB         int       `gorm:"default:0" json:"calls_b"`                  // 模板B调用次数
-	SuccessA       int       `gorm:"default:0" json:"success_a"`                // 模板A成功次数
-	SuccessB       int       `gorm:"default:0" json:"success_b"`                // 模板B成功次数
-	AvgDurationA   float64   `gorm:"default:0" json:"avg_duration_a"`           // 模板A平均响应时间（毫秒）
-	AvgDurationB   float64   `gorm:"default:0" json:"avg_duration_b"`           // 模板B平均响应时间（毫秒）
-	UserRatingA    float64   `gorm:"default:0" json:"user_rating_a"`            // 模板A用户评分（0-5）
-	UserRatingB    float64   `gorm:"default:0" json:"user_rating_b"`            // 模板B用户评分（0-5）
-	StartTime      time.Time `json:"start_time"`
-	EndTime        *time.Time `json:"end_time"`
-	WinnerTemplate string    `gorm:"size:100" json:"winner_template"`            // 获胜模板ID
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	TestName        string     `gorm:"size:200;not null" json:"test_name"`          // 测试名称
+	TemplateAID     string     `gorm:"size:100;not null" json:"template_a_id"`      // 对照组模板ID，即第一个变体
+	TemplateBID     string     `gorm:"size:100;not null" json:"template_b_id"`      // 第二个变体的模板ID
+	Variants        string     `gorm:"type:text" json:"variants"`                   // JSON格式的变体列表（模板ID和流量权重）
+	Status          string     `gorm:"size:20;default:running;index" json:"status"` // running, completed, paused
+	TrafficSplit    int        `gorm:"default:50" json:"traffic_split"`             // 旧版两变体测试中A的流量百分比，Variants 为空时使用
+	GoalMetric      string     `gorm:"size:30;default:success" json:"goal_metric"`  // 目标指标：success, rating, engagement, audit_pass
+	ConfidenceLevel float64    `gorm:"default:0.95" json:"confidence_level"`        // 置信水平
+	MinSamples      int        `gorm:"default:100" json:"min_samples"`              // 每个变体判定前需要的最少样本数
+	AutoPromote     bool       `gorm:"default:false" json:"auto_promote"`           // 结果显著时自动完成并设为默认模板
+	TotalCalls      int        `gorm:"default:0" json:"total_calls"`                // 总调用次数
+	StartTime       time.Time  `json:"start_time"`
+	EndTime         *time.Time `json:"end_time"`
+	WinnerTemplate  string     `gorm:"size:100" json:"winner_template"` // 获胜模板ID
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 // TableName 指定表名
@@ -332,10 +361,56 @@ func (PromptTemplateABTest) TableName() string {
 	return "prompt_template_ab_tests"
 }
 
+// PromptExperimentAssignment 实验分组记录，同一用户或会话始终使用同一变体
+type PromptExperimentAssignment struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	TestID     uint      `gorm:"uniqueIndex:idx_experiment_unit;not null" json:"test_id"`
+	UnitID     string    `gorm:"uniqueIndex:idx_experiment_unit;size:100;not null" json:"unit_id"` // 用户ID或会话ID
+	TemplateID string    `gorm:"size:100;index" json:"template_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (PromptExperimentAssignment) TableName() string {
+	return "prompt_experiment_assignments"
+}
+
+// PromptExperimentOutcome 实验结果记录，如调用成功、用户评分、发布互动和审核结果
+type PromptExperimentOutcome struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	TestID     uint      `gorm:"index:idx_experiment_outcome;not null" json:"test_id"`
+	Metric     string    `gorm:"index:idx_experiment_outcome;size:30;not null" json:"metric"`
+	UnitID     string    `gorm:"size:100;index" json:"unit_id"`
+	TemplateID string    `gorm:"size:100" json:"template_id"`
+	Value      float64   `json:"value"`
+	Source     string    `gorm:"size:200" json:"source"` // 结果来源，如发布内容；同一来源只保留最新结果
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (PromptExperimentOutcome) TableName() string {
+	return "prompt_experiment_outcomes"
+}
+
+// PromptExperimentPost 实验单元发布的内容，用于把发布后的互动数据回填到实验
+type PromptExperimentPost struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Platform  string    `gorm:"uniqueIndex:idx_experiment_post;size:50;not null" json:"platform"`
+	PostID    string    `gorm:"uniqueIndex:idx_experiment_post;size:200;not null" json:"post_id"`
+	UnitID    string    `gorm:"size:100;index" json:"unit_id"` // 发布时的用户ID或会话ID
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (PromptExperimentPost) TableName() string {
+	return "prompt_experiment_posts"
+}
+
 // PromptTemplateUsage 提示词模板使用记录
 type PromptTemplateUsage struct {
 	ID           uint      `gorm:"primaryKey" json:"id"`
 	TemplateID   string    `gorm:"index;size:100;not null" json:"template_id"`
+	Version      int       `gorm:"index" json:"version"`                       // 调用时使用的模板版本
 	UserID       string    `gorm:"size:100;index" json:"user_id"`              // 用户ID（可选）
 	SessionID    string    `gorm:"size:100;index" json:"session_id"`           // 会话ID
 	InputData    string    `gorm:"type:text" json:"input_data"`                // JSON格式的输入数据
@@ -465,6 +540,8 @@ const (
 	TaskStatusFailed    TaskStatus = "failed"    // 失败
 	TaskStatusCancelled TaskStatus = "cancelled" // 已取消
 	TaskStatusRetrying  TaskStatus = "retrying"  // 重试中
+	TaskStatusWaiting   TaskStatus = "waiting"   // 等待依赖任务或子任务
+	TaskStatusCancelling TaskStatus = "cancelling" // 取消中，等待运行中的处理器退出
 )
 
 // TaskPriority 任务优先级
@@ -493,10 +570,18 @@ type AsyncTask struct {
 	RetryCount   int          `gorm:"default:0" json:"retry_count"`                 // 重试次数
 	MaxRetries   int          `gorm:"default:3" json:"max_retries"`                 // 最大重试次数
 	Timeout      int          `json:"timeout"`                                      // 超时时间（秒）
-	UserID       string       `gorm:"size:100;index" json:"user_id"`                // 用户ID
+	UserID       string       `gorm:"size:100;index;uniqueIndex:idx_async_task_idempotency,priority:1" json:"user_id"` // 用户ID
 	ProjectID    string       `gorm:"size:100;index" json:"project_id"`             // 项目ID
 	ParentTaskID string       `gorm:"size:100;index" json:"parent_task_id"`         // 父任务ID
+	Aggregate    bool         `gorm:"default:false;index" json:"aggregate"`         // 工作流父任务，状态由子任务汇总，本身不会被执行
+	IdempotencyKey *string    `gorm:"size:200;uniqueIndex:idx_async_task_idempotency,priority:2" json:"idempotency_key"` // 幂等键，同一用户在窗口期内重复提交返回同一任务
+	ContentHash  string       `gorm:"size:64;index" json:"content_hash"`            // 发布内容哈希，用于内容去重
+	Platform     string       `gorm:"size:50;index" json:"platform"`                // 发布平台，取自任务数据，用于限流
+	AccountID    string       `gorm:"size:100;index" json:"account_id"`             // 发布账号，取自任务数据，用于限流
 	ScheduledAt  *time.Time   `json:"scheduled_at"`                                 // 计划执行时间
+	WorkerID     string       `gorm:"size:100;index" json:"worker_id"`              // 当前执行的工作器
+	HeartbeatAt  *time.Time   `json:"heartbeat_at"`                                 // 工作器最近心跳时间
+	CancelRequestedAt *time.Time `json:"cancel_requested_at"`                         // 请求取消时间
 	StartedAt    *time.Time   `json:"started_at"`                                   // 开始时间
 	CompletedAt  *time.Time   `json:"completed_at"`                                 // 完成时间
 	ExpiredAt    *time.Time   `json:"expired_at"`                                   // 过期时间
@@ -529,6 +614,37 @@ func (TaskQueue) TableName() string {
 	return "task_queues"
 }
 
+// TaskDependency 任务依赖关系
+type TaskDependency struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	TaskID          string    `gorm:"index;size:100;not null" json:"task_id"`            // 任务ID
+	DependsOnTaskID string    `gorm:"index;size:100;not null" json:"depends_on_task_id"` // 被依赖的任务ID
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (TaskDependency) TableName() string {
+	return "task_dependencies"
+}
+
+// TaskRateLimit 任务限流规则
+type TaskRateLimit struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Scope       string    `gorm:"uniqueIndex:idx_rate_limit_scope_key;size:20;not null" json:"scope"` // 限流维度：task_type, platform, account
+	Key         string    `gorm:"uniqueIndex:idx_rate_limit_scope_key;size:100;not null" json:"key"`  // 维度取值，如 publish、douyin、账号ID
+	MaxCount    int       `gorm:"default:0" json:"max_count"`                                         // 周期内最大执行次数（0表示不限）
+	Period      int       `gorm:"default:3600" json:"period"`                                         // 统计周期（秒）
+	MinInterval int       `gorm:"default:0" json:"min_interval"`                                      // 两次执行最小间隔（秒）
+	IsActive    bool      `gorm:"default:true;index" json:"is_active"`                                // 是否激活
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (TaskRateLimit) TableName() string {
+	return "task_rate_limits"
+}
+
 // TaskExecution 任务执行记录
 type TaskExecution struct {
 	ID          uint      `gorm:"primaryKey" json:"id"`
@@ -541,6 +657,7 @@ type TaskExecution struct {
 	MemoryMB    int       `json:"memory_mb"`                              // 内存使用（MB）
 	CPUUsage    float64   `json:"cpu_usage"`                              // CPU使用率
 	StartedAt   time.Time `json:"started_at"`
+	CancelRequestedAt *time.Time `json:"cancel_requested_at"` // 请求取消时间，状态为 cancelled 时有效
 	CompletedAt time.Time `json:"completed_at"`
 }
 
@@ -558,7 +675,12 @@ type ScheduledTask struct {
 	Payload      string    `gorm:"type:text" json:"payload"`                  // JSON格式的任务数据
 	QueueName    string    `gorm:"size:50;not null" json:"queue_name"`        // 目标队列
 	IsActive     bool      `gorm:"default:true;index" json:"is_active"`       // 是否激活
-	LastRunAt    *time.Time `json:"last_run_at"`                             // 上次执行时间
+	TimeZone     string    `gorm:"size:64" json:"time_zone"`                  // Cron表达式所用时区（IANA名称），为空使用服务器时区
+	MisfirePolicy string   `gorm:"size:20;default:skip" json:"misfire_policy"` // 错过执行的处理策略: skip, run_once, run_all
+	JitterSeconds int      `json:"jitter_seconds"`                            // 随机延迟上限（秒）
+	AllowOverlap bool      `json:"allow_overlap"`                             // 是否允许上次执行未结束时再次执行
+	LastTaskID   string    `gorm:"size:100" json:"last_task_id"`              // 最近一次提交的队列任务ID
+	LastRunAt    *time.Time `json:"last_run_at"`                             // 上次执行对应的计划时间
 	NextRunAt    *time.Time `json:"next_run_at"`                             // 下次执行时间
 	RunCount     int       `json:"run_count"`                                 // 执行次数
 	LastError    string    `gorm:"type:text" json:"last_error"`               // 最后错误
@@ -571,6 +693,21 @@ func (ScheduledTask) TableName() string {
 	return "scheduled_tasks"
 }
 
+// SchedulerLease 调度器租约，多实例共享数据库时只有持有租约的实例触发定时任务
+type SchedulerLease struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"uniqueIndex;size:100;not null" json:"name"` // 租约名称
+	HolderID  string    `gorm:"size:100" json:"holder_id"`                 // 持有者实例ID
+	ExpiresAt time.Time `gorm:"index" json:"expires_at"`                   // 过期时间
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (SchedulerLease) TableName() string {
+	return "scheduler_leases"
+}
+
 // =====================================================
 // 进度追踪模型
 // =====================================================
@@ -810,3 +947,137 @@ func (User) TableName() string {
 	return "users"
 }
 
+
+// =====================================================
+// 内容合规审核模型
+// =====================================================
+
+// ComplianceReviewStatus 合规审批状态
+type ComplianceReviewStatus string
+
+const (
+	ComplianceReviewPending  ComplianceReviewStatus = "pending"  // 待审批
+	ComplianceReviewApproved ComplianceReviewStatus = "approved" // 已批准
+	ComplianceReviewRejected ComplianceReviewStatus = "rejected" // 已驳回
+)
+
+// ComplianceReview 合规检查命中需人工审批的内容
+// 批准后相同平台、相同内容的发布不再拦截
+type ComplianceReview struct {
+	ID          uint                   `gorm:"primaryKey" json:"id"`
+	ContentHash string                 `gorm:"size:64;not null;index:idx_compliance_review_content" json:"content_hash"`
+	Platform    string                 `gorm:"size:50;index:idx_compliance_review_content" json:"platform"`
+	Title       string                 `gorm:"size:500" json:"title"`
+	Body        string                 `gorm:"type:text" json:"body"`
+	MaxSeverity string                 `gorm:"size:20" json:"max_severity"`        // 命中问题的最高级别
+	Issues      string                 `gorm:"type:text" json:"issues"`            // JSON格式的问题列表
+	Status      ComplianceReviewStatus `gorm:"size:20;default:pending;index" json:"status"`
+	TaskID      string                 `gorm:"size:100;index" json:"task_id"`      // 触发审批的发布任务ID
+	Reviewer    string                 `gorm:"size:100" json:"reviewer"`
+	Comment     string                 `gorm:"type:text" json:"comment"`
+	ReviewedAt  *time.Time             `json:"reviewed_at"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (ComplianceReview) TableName() string {
+	return "compliance_reviews"
+}
+
+// =====================================================
+// 模型离线评测模型
+// =====================================================
+
+// EvalDataset 评测数据集
+type EvalDataset struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	DatasetID   string    `gorm:"uniqueIndex;size:100;not null" json:"dataset_id"`
+	Name        string    `gorm:"size:200;not null" json:"name"`
+	Description string    `gorm:"type:text" json:"description"`
+	Source      string    `gorm:"size:500" json:"source"` // 导入来源文件，通过接口创建时为空
+	CaseCount   int       `gorm:"default:0" json:"case_count"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (EvalDataset) TableName() string {
+	return "eval_datasets"
+}
+
+// EvalCase 评测用例
+type EvalCase struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	DatasetID string    `gorm:"size:100;not null;uniqueIndex:idx_eval_case" json:"dataset_id"`
+	CaseID    string    `gorm:"size:100;not null;uniqueIndex:idx_eval_case" json:"case_id"`
+	Variables string    `gorm:"type:text" json:"variables"` // JSON格式的模板变量
+	Prompt    string    `gorm:"type:text" json:"prompt"`    // 未指定模板时直接使用的提示词
+	System    string    `gorm:"type:text" json:"system"`
+	Reference string    `gorm:"type:text" json:"reference"` // 参考答案，供评审模型参考
+	Keywords  string    `gorm:"type:text" json:"keywords"`  // JSON格式的应覆盖关键词
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (EvalCase) TableName() string {
+	return "eval_cases"
+}
+
+// EvalRunStatus 评测运行状态
+type EvalRunStatus string
+
+const (
+	EvalRunPending   EvalRunStatus = "pending"   // 等待执行
+	EvalRunRunning   EvalRunStatus = "running"   // 执行中
+	EvalRunCompleted EvalRunStatus = "completed" // 已完成
+	EvalRunFailed    EvalRunStatus = "failed"    // 失败
+)
+
+// EvalRun 一次评测运行，在同一数据集上对比多个提供商、模型和模板组合
+type EvalRun struct {
+	ID          uint          `gorm:"primaryKey" json:"id"`
+	RunID       string        `gorm:"uniqueIndex;size:100;not null" json:"run_id"`
+	Name        string        `gorm:"size:200" json:"name"`
+	DatasetID   string        `gorm:"size:100;not null;index" json:"dataset_id"`
+	Status      EvalRunStatus `gorm:"size:20;default:pending;index" json:"status"`
+	Candidates  string        `gorm:"type:text" json:"candidates"` // JSON格式的参评组合
+	Metrics     string        `gorm:"type:text" json:"metrics"`    // JSON格式的指标配置
+	Summary     string        `gorm:"type:text" json:"summary"`    // JSON格式的对比报告
+	Error       string        `gorm:"type:text" json:"error"`
+	StartedAt   *time.Time    `json:"started_at"`
+	CompletedAt *time.Time    `json:"completed_at"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (EvalRun) TableName() string {
+	return "eval_runs"
+}
+
+// EvalResult 单个组合在单个用例上的输出和得分
+type EvalResult struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	RunID        string    `gorm:"size:100;not null;index:idx_eval_result_run" json:"run_id"`
+	Candidate    string    `gorm:"size:300;not null;index:idx_eval_result_run" json:"candidate"`
+	CaseID       string    `gorm:"size:100;not null" json:"case_id"`
+	Provider     string    `gorm:"size:50" json:"provider"`
+	Model        string    `gorm:"size:100" json:"model"`
+	TemplateID   string    `gorm:"size:100" json:"template_id"`
+	Output       string    `gorm:"type:text" json:"output"`
+	Scores       string    `gorm:"type:text" json:"scores"` // JSON格式的各指标得分
+	Score        float64   `json:"score"`                   // 按权重汇总的得分，0-1
+	Passed       bool      `json:"passed"`                  // 是否所有指标都达到阈值
+	Error        string    `gorm:"type:text" json:"error"`
+	LatencyMs    int64     `json:"latency_ms"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	Cost         float64   `json:"cost"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (EvalResult) TableName() string {
+	return "eval_results"
+}
@@ -0,0 +1,282 @@
+// Package evaluation 离线评测提供商、模型和提示词模板组合
+// 数据集保存在数据库或 JSON、JSONL 文件中，每个组合对每条用例生成输出，
+// 按配置的指标打分，结果持久化后汇总成对比报告，作为选择默认配置的依据
+package evaluation
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"publisher-core/database"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Case 评测用例
+type Case struct {
+	ID string `json:"id"`
+	// Variables 渲染提示词模板使用的变量
+	Variables map[string]interface{} `json:"variables,omitempty"`
+	// Prompt 参评组合未指定模板时直接使用的提示词
+	Prompt string `json:"prompt,omitempty"`
+	System string `json:"system,omitempty"`
+	// Reference 参考答案，提供给评审模型
+	Reference string `json:"reference,omitempty"`
+	// Keywords 输出应覆盖的关键词
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+// Dataset 评测数据集
+type Dataset struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Cases       []Case `json:"cases"`
+}
+
+// validate 检查用例，并为未指定ID的用例按序号编号
+func (d *Dataset) validate() error {
+	if len(d.Cases) == 0 {
+		return errors.New("数据集没有用例")
+	}
+	seen := make(map[string]bool, len(d.Cases))
+	for i := range d.Cases {
+		c := &d.Cases[i]
+		if c.ID == "" {
+			c.ID = fmt.Sprintf("case-%d", i+1)
+		}
+		if seen[c.ID] {
+			return fmt.Errorf("用例ID重复: %s", c.ID)
+		}
+		seen[c.ID] = true
+		if c.Prompt == "" && len(c.Variables) == 0 {
+			return fmt.Errorf("用例 %s 缺少提示词或模板变量", c.ID)
+		}
+	}
+	return nil
+}
+
+// LoadDatasetFile 从文件加载数据集
+// .jsonl 文件每行一条用例，数据集名称取文件名；其它文件按完整的 Dataset JSON 解析
+func LoadDatasetFile(path string) (*Dataset, error) {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	var ds Dataset
+	if strings.EqualFold(filepath.Ext(path), ".jsonl") {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取数据集失败: %w", err)
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" {
+				continue
+			}
+			var c Case
+			if err := json.Unmarshal([]byte(text), &c); err != nil {
+				return nil, fmt.Errorf("解析数据集第 %d 行失败: %w", line, err)
+			}
+			ds.Cases = append(ds.Cases, c)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("读取数据集失败: %w", err)
+		}
+	} else {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取数据集失败: %w", err)
+		}
+		if err := json.Unmarshal(data, &ds); err != nil {
+			return nil, fmt.Errorf("解析数据集失败: %w", err)
+		}
+	}
+
+	if ds.ID == "" {
+		ds.ID = name
+	}
+	if ds.Name == "" {
+		ds.Name = name
+	}
+	ds.Source = path
+	if err := ds.validate(); err != nil {
+		return nil, err
+	}
+	return &ds, nil
+}
+
+// Store 评测数据集、运行记录和结果的存储
+type Store struct {
+	db *gorm.DB
+}
+
+// NewStore 创建存储，db 为 nil 时使用全局数据库
+func NewStore(db *gorm.DB) *Store {
+	if db == nil {
+		db = database.GetDB()
+	}
+	return &Store{db: db}
+}
+
+// SaveDataset 保存数据集，已存在时整体替换其用例
+func (s *Store) SaveDataset(ds *Dataset) error {
+	if ds.ID == "" {
+		ds.ID = uuid.New().String()
+	}
+	if ds.Name == "" {
+		ds.Name = ds.ID
+	}
+	if err := ds.validate(); err != nil {
+		return err
+	}
+
+	cases := make([]database.EvalCase, 0, len(ds.Cases))
+	for _, c := range ds.Cases {
+		variables, err := marshalOptional(c.Variables, len(c.Variables) > 0)
+		if err != nil {
+			return fmt.Errorf("序列化用例 %s 变量失败: %w", c.ID, err)
+		}
+		keywords, err := marshalOptional(c.Keywords, len(c.Keywords) > 0)
+		if err != nil {
+			return fmt.Errorf("序列化用例 %s 关键词失败: %w", c.ID, err)
+		}
+		cases = append(cases, database.EvalCase{
+			DatasetID: ds.ID,
+			CaseID:    c.ID,
+			Variables: variables,
+			Prompt:    c.Prompt,
+			System:    c.System,
+			Reference: c.Reference,
+			Keywords:  keywords,
+		})
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var record database.EvalDataset
+		err := tx.Where("dataset_id = ?", ds.ID).First(&record).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			record = database.EvalDataset{DatasetID: ds.ID}
+		case err != nil:
+			return fmt.Errorf("查询数据集失败: %w", err)
+		}
+		record.Name = ds.Name
+		record.Description = ds.Description
+		record.Source = ds.Source
+		record.CaseCount = len(cases)
+		if err := tx.Save(&record).Error; err != nil {
+			return fmt.Errorf("保存数据集失败: %w", err)
+		}
+
+		if err := tx.Where("dataset_id = ?", ds.ID).Delete(&database.EvalCase{}).Error; err != nil {
+			return fmt.Errorf("清除旧用例失败: %w", err)
+		}
+		if err := tx.CreateInBatches(cases, 100).Error; err != nil {
+			return fmt.Errorf("保存用例失败: %w", err)
+		}
+		return nil
+	})
+}
+
+// ImportFile 从文件加载数据集并保存
+func (s *Store) ImportFile(path string) (*Dataset, error) {
+	ds, err := LoadDatasetFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.SaveDataset(ds); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// GetDataset 获取数据集及其全部用例
+func (s *Store) GetDataset(datasetID string) (*Dataset, error) {
+	var record database.EvalDataset
+	if err := s.db.Where("dataset_id = ?", datasetID).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("数据集不存在: %s", datasetID)
+		}
+		return nil, err
+	}
+
+	var rows []database.EvalCase
+	if err := s.db.Where("dataset_id = ?", datasetID).Order("id ASC").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("查询用例失败: %w", err)
+	}
+
+	ds := &Dataset{
+		ID:          record.DatasetID,
+		Name:        record.Name,
+		Description: record.Description,
+		Source:      record.Source,
+		Cases:       make([]Case, 0, len(rows)),
+	}
+	for _, row := range rows {
+		c := Case{
+			ID:        row.CaseID,
+			Prompt:    row.Prompt,
+			System:    row.System,
+			Reference: row.Reference,
+		}
+		if row.Variables != "" {
+			if err := json.Unmarshal([]byte(row.Variables), &c.Variables); err != nil {
+				return nil, fmt.Errorf("解析用例 %s 变量失败: %w", row.CaseID, err)
+			}
+		}
+		if row.Keywords != "" {
+			if err := json.Unmarshal([]byte(row.Keywords), &c.Keywords); err != nil {
+				return nil, fmt.Errorf("解析用例 %s 关键词失败: %w", row.CaseID, err)
+			}
+		}
+		ds.Cases = append(ds.Cases, c)
+	}
+	return ds, nil
+}
+
+// ListDatasets 列出数据集
+func (s *Store) ListDatasets() ([]database.EvalDataset, error) {
+	var datasets []database.EvalDataset
+	if err := s.db.Order("id DESC").Find(&datasets).Error; err != nil {
+		return nil, err
+	}
+	return datasets, nil
+}
+
+// DeleteDataset 删除数据集及其用例，已有的运行记录保留
+func (s *Store) DeleteDataset(datasetID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("dataset_id = ?", datasetID).Delete(&database.EvalDataset{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("数据集不存在: %s", datasetID)
+		}
+		return tx.Where("dataset_id = ?", datasetID).Delete(&database.EvalCase{}).Error
+	})
+}
+
+// marshalOptional 序列化非空字段，空字段保存为空字符串
+func marshalOptional(v interface{}, present bool) (string, error) {
+	if !present {
+		return "", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
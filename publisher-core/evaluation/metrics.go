@@ -0,0 +1,397 @@
+package evaluation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"publisher-core/ai/provider"
+	"publisher-core/cost"
+)
+
+// 指标类型
+const (
+	MetricLength   = "length"   // 输出长度在范围内
+	MetricKeywords = "keywords" // 关键词覆盖率
+	MetricJSON     = "json"     // 输出为合法 JSON，可按 schema 校验
+	MetricJudge    = "judge"    // 评审模型按评分标准打分
+)
+
+// Sample 待打分的一次生成
+type Sample struct {
+	Case   *Case
+	Prompt string
+	Output string
+}
+
+// MetricScore 单个指标的得分
+type MetricScore struct {
+	// Score 归一化到 0-1
+	Score  float64 `json:"score"`
+	Passed bool    `json:"passed"`
+	Detail string  `json:"detail,omitempty"`
+	// Error 指标本身执行失败（如评审模型调用失败），不计入汇总得分
+	Error string `json:"error,omitempty"`
+}
+
+// Metric 评测指标
+type Metric interface {
+	Evaluate(ctx context.Context, sample *Sample) (*MetricScore, error)
+}
+
+// Criterion 评审维度
+type Criterion struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Weight      float64 `json:"weight,omitempty"`
+}
+
+// MetricSpec 指标配置
+type MetricSpec struct {
+	Type string `json:"type"`
+	// Name 报告中的指标名称，默认为 Type
+	Name string `json:"name,omitempty"`
+	// Weight 汇总得分时的权重，默认 1
+	Weight float64 `json:"weight,omitempty"`
+	// Threshold 得分达到该值视为通过，默认 judge 为 0.6、keywords 为 0.8、其它为 1
+	Threshold float64 `json:"threshold,omitempty"`
+
+	// length：按字符数计算，0 表示不限制
+	MinLength int `json:"min_length,omitempty"`
+	MaxLength int `json:"max_length,omitempty"`
+
+	// keywords：追加到每条用例自身的关键词之后
+	Keywords []string `json:"keywords,omitempty"`
+
+	// json：为空时只校验是否为合法 JSON
+	Schema *provider.JSONSchema `json:"schema,omitempty"`
+
+	// judge：评分标准和评审维度，评审模型为空时使用主提供商
+	Rubric        string                `json:"rubric,omitempty"`
+	Criteria      []Criterion           `json:"criteria,omitempty"`
+	JudgeProvider provider.ProviderType `json:"judge_provider,omitempty"`
+	JudgeModel    string                `json:"judge_model,omitempty"`
+}
+
+// name 报告中的指标名称
+func (spec *MetricSpec) name() string {
+	if spec.Name != "" {
+		return spec.Name
+	}
+	return spec.Type
+}
+
+// DefaultMetrics 未配置指标时使用的确定性指标
+func DefaultMetrics() []MetricSpec {
+	return []MetricSpec{
+		{Type: MetricLength, MinLength: 50, MaxLength: 5000},
+		{Type: MetricKeywords},
+	}
+}
+
+// scoredMetric 带权重和阈值的指标
+type scoredMetric struct {
+	name      string
+	weight    float64
+	threshold float64
+	metric    Metric
+}
+
+// Scorer 按多个指标为输出打分
+type Scorer struct {
+	metrics []scoredMetric
+}
+
+// NewScorer 根据配置创建打分器，backend 仅在配置了 judge 指标时使用
+func NewScorer(specs []MetricSpec, backend Backend) (*Scorer, error) {
+	if len(specs) == 0 {
+		specs = DefaultMetrics()
+	}
+
+	s := &Scorer{}
+	seen := make(map[string]bool, len(specs))
+	for i := range specs {
+		spec := specs[i]
+		name := spec.name()
+		if seen[name] {
+			return nil, fmt.Errorf("指标名称重复: %s", name)
+		}
+		seen[name] = true
+
+		m := scoredMetric{name: name, weight: spec.Weight, threshold: spec.Threshold}
+		if m.weight == 0 {
+			m.weight = 1
+		}
+		if m.weight < 0 {
+			return nil, fmt.Errorf("指标 %s 的权重不能为负数", name)
+		}
+
+		switch spec.Type {
+		case MetricLength:
+			if spec.MaxLength > 0 && spec.MinLength > spec.MaxLength {
+				return nil, fmt.Errorf("指标 %s 的最小长度大于最大长度", name)
+			}
+			m.metric = &LengthMetric{Min: spec.MinLength, Max: spec.MaxLength}
+		case MetricKeywords:
+			m.metric = &KeywordMetric{Keywords: spec.Keywords}
+			if m.threshold == 0 {
+				m.threshold = 0.8
+			}
+		case MetricJSON:
+			m.metric = &JSONMetric{Schema: spec.Schema}
+		case MetricJudge:
+			if backend == nil {
+				return nil, fmt.Errorf("指标 %s 需要评审模型", name)
+			}
+			m.metric = &JudgeMetric{
+				Generator: &providerGenerator{backend: backend, provider: spec.JudgeProvider},
+				Model:     spec.JudgeModel,
+				Rubric:    spec.Rubric,
+				Criteria:  spec.Criteria,
+			}
+			if m.threshold == 0 {
+				m.threshold = 0.6
+			}
+		default:
+			return nil, fmt.Errorf("不支持的指标类型: %s", spec.Type)
+		}
+		if m.threshold == 0 {
+			m.threshold = 1
+		}
+		s.metrics = append(s.metrics, m)
+	}
+	return s, nil
+}
+
+// Score 计算各指标得分和加权汇总得分，所有指标都达到阈值时 passed 为 true
+// 单个指标执行失败时记录错误并从汇总中排除，上下文取消时返回错误
+func (s *Scorer) Score(ctx context.Context, sample *Sample) (map[string]*MetricScore, float64, bool, error) {
+	scores := make(map[string]*MetricScore, len(s.metrics))
+	var total, weights float64
+	passed := true
+
+	for _, m := range s.metrics {
+		score, err := m.metric.Evaluate(ctx, sample)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, 0, false, ctx.Err()
+			}
+			scores[m.name] = &MetricScore{Error: err.Error()}
+			passed = false
+			continue
+		}
+		score.Passed = score.Score >= m.threshold
+		scores[m.name] = score
+		passed = passed && score.Passed
+		total += score.Score * m.weight
+		weights += m.weight
+	}
+
+	if weights == 0 {
+		return scores, 0, false, nil
+	}
+	return scores, total / weights, passed, nil
+}
+
+// ScoreResult 实现 provider.ResultScorer，供 MultiModelService.CompareResults 选出最佳结果
+// 以最后一条用户消息作为提示词，没有用例关键词和参考答案
+func (s *Scorer) ScoreResult(ctx context.Context, opts *provider.GenerateOptions, result *provider.GenerateResult) (float64, error) {
+	sample := &Sample{Case: &Case{}, Output: result.Content}
+	if opts != nil {
+		for i := len(opts.Messages) - 1; i >= 0; i-- {
+			if opts.Messages[i].Role == provider.RoleUser {
+				sample.Prompt = opts.Messages[i].Content
+				break
+			}
+		}
+	}
+	_, score, _, err := s.Score(ctx, sample)
+	return score, err
+}
+
+// LengthMetric 按字符数检查输出长度，超出范围时按偏离比例扣分
+type LengthMetric struct {
+	Min int
+	Max int
+}
+
+func (m *LengthMetric) Evaluate(ctx context.Context, sample *Sample) (*MetricScore, error) {
+	n := utf8.RuneCountInString(strings.TrimSpace(sample.Output))
+	switch {
+	case m.Min > 0 && n < m.Min:
+		return &MetricScore{
+			Score:  float64(n) / float64(m.Min),
+			Detail: fmt.Sprintf("长度 %d 小于 %d", n, m.Min),
+		}, nil
+	case m.Max > 0 && n > m.Max:
+		return &MetricScore{
+			Score:  float64(m.Max) / float64(n),
+			Detail: fmt.Sprintf("长度 %d 超过 %d", n, m.Max),
+		}, nil
+	}
+	return &MetricScore{Score: 1, Detail: fmt.Sprintf("长度 %d", n)}, nil
+}
+
+// KeywordMetric 计算用例关键词和配置关键词在输出中的覆盖率，不区分大小写
+type KeywordMetric struct {
+	Keywords []string
+}
+
+func (m *KeywordMetric) Evaluate(ctx context.Context, sample *Sample) (*MetricScore, error) {
+	var keywords []string
+	if sample.Case != nil {
+		keywords = append(keywords, sample.Case.Keywords...)
+	}
+	keywords = append(keywords, m.Keywords...)
+	if len(keywords) == 0 {
+		return &MetricScore{Score: 1, Detail: "未设置关键词"}, nil
+	}
+
+	output := strings.ToLower(sample.Output)
+	var missing []string
+	for _, kw := range keywords {
+		if !strings.Contains(output, strings.ToLower(kw)) {
+			missing = append(missing, kw)
+		}
+	}
+
+	score := &MetricScore{Score: float64(len(keywords)-len(missing)) / float64(len(keywords))}
+	if len(missing) > 0 {
+		score.Detail = "缺少关键词: " + strings.Join(missing, ", ")
+	}
+	return score, nil
+}
+
+// JSONMetric 检查输出是否为合法 JSON
+// 允许代码块包裹；设置了 Schema 时不符合 schema 得 0.5 分
+type JSONMetric struct {
+	Schema *provider.JSONSchema
+}
+
+func (m *JSONMetric) Evaluate(ctx context.Context, sample *Sample) (*MetricScore, error) {
+	data := provider.ExtractJSON(sample.Output)
+	if !json.Valid([]byte(data)) {
+		return &MetricScore{Score: 0, Detail: "输出不是合法的JSON"}, nil
+	}
+	if m.Schema == nil {
+		return &MetricScore{Score: 1}, nil
+	}
+	if errs := provider.ValidateJSON(m.Schema, []byte(data)); len(errs) > 0 {
+		return &MetricScore{Score: 0.5, Detail: strings.Join(errs, "; ")}, nil
+	}
+	return &MetricScore{Score: 1}, nil
+}
+
+// defaultCriteria 未配置评审维度时使用
+var defaultCriteria = []Criterion{
+	{Name: "相关性", Description: "是否切题、完成了提示词要求的任务"},
+	{Name: "准确性", Description: "事实是否准确，与参考答案是否一致"},
+	{Name: "表达", Description: "结构是否清晰、语言是否流畅自然"},
+}
+
+// judgeOutput 评审模型的结构化输出
+type judgeOutput struct {
+	Scores []struct {
+		Criterion string `json:"criterion"`
+		Score     int    `json:"score" jsonschema:"minimum=1,maximum=10"`
+		Reason    string `json:"reason"`
+	} `json:"scores"`
+	Summary string `json:"summary"`
+}
+
+// JudgeMetric 由评审模型按评分标准逐个维度打 1-10 分，按维度权重归一化
+type JudgeMetric struct {
+	Generator provider.Generator
+	Model     string
+	Rubric    string
+	Criteria  []Criterion
+}
+
+func (m *JudgeMetric) Evaluate(ctx context.Context, sample *Sample) (*MetricScore, error) {
+	criteria := m.Criteria
+	if len(criteria) == 0 {
+		criteria = defaultCriteria
+	}
+
+	// 评审必须针对本次输出打分，不复用缓存
+	ctx = cost.WithoutCache(cost.WithFunction(ctx, "evaluation_judge"))
+	var output judgeOutput
+	_, err := provider.GenerateStructured(ctx, m.Generator, &provider.GenerateOptions{
+		Model: m.Model,
+		Messages: []provider.Message{
+			{Role: provider.RoleSystem, Content: "你是严格、公正的内容质量评审，只依据评分标准打分，不因篇幅长短加分。"},
+			{Role: provider.RoleUser, Content: buildJudgePrompt(sample, m.Rubric, criteria)},
+		},
+		Temperature: 0,
+	}, &output)
+	if err != nil {
+		return nil, fmt.Errorf("评审模型打分失败: %w", err)
+	}
+
+	byName := make(map[string]int, len(output.Scores))
+	var reasons []string
+	for _, s := range output.Scores {
+		byName[s.Criterion] = s.Score
+		if s.Reason != "" {
+			reasons = append(reasons, fmt.Sprintf("%s %d：%s", s.Criterion, s.Score, s.Reason))
+		}
+	}
+
+	var total, weights float64
+	for _, c := range criteria {
+		score, ok := byName[c.Name]
+		if !ok {
+			return nil, fmt.Errorf("评审结果缺少维度: %s", c.Name)
+		}
+		weight := c.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		total += float64(score) / 10 * weight
+		weights += weight
+	}
+
+	detail := output.Summary
+	if len(reasons) > 0 {
+		detail = strings.TrimSpace(detail + "\n" + strings.Join(reasons, "\n"))
+	}
+	return &MetricScore{Score: total / weights, Detail: detail}, nil
+}
+
+// buildJudgePrompt 构建评审提示词
+func buildJudgePrompt(sample *Sample, rubric string, criteria []Criterion) string {
+	var b strings.Builder
+
+	b.WriteString("请评审以下 AI 输出的质量。\n\n")
+	b.WriteString("【任务提示词】\n")
+	b.WriteString(sample.Prompt)
+	if sample.Case != nil && sample.Case.Reference != "" {
+		b.WriteString("\n\n【参考答案】\n")
+		b.WriteString(sample.Case.Reference)
+	}
+	b.WriteString("\n\n【待评审输出】\n")
+	b.WriteString(sample.Output)
+
+	if rubric != "" {
+		b.WriteString("\n\n【评分标准】\n")
+		b.WriteString(rubric)
+	}
+
+	b.WriteString("\n\n【评审维度】\n")
+	for i, c := range criteria {
+		fmt.Fprintf(&b, "%d. %s", i+1, c.Name)
+		if c.Description != "" {
+			b.WriteString("：" + c.Description)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("\n要求：\n")
+	b.WriteString("1. scores 中每个维度一项，criterion 与上面的维度名称完全一致\n")
+	b.WriteString("2. score 取 1-10 的整数，10 为最好\n")
+	b.WriteString("3. reason 简要说明扣分原因，summary 给出总体评价\n")
+
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package evaluation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"publisher-core/ai/provider"
+)
+
+func TestDeterministicMetrics(t *testing.T) {
+	ctx := context.Background()
+	sample := &Sample{
+		Case:   &Case{Keywords: []string{"Go", "并发"}},
+		Output: "```json\n{\"title\": \"Go 语言入门\"}\n```",
+	}
+
+	length, _ := (&LengthMetric{Min: 10, Max: 100}).Evaluate(ctx, sample)
+	if length.Score != 1 {
+		t.Errorf("Expected length within range, got %+v", length)
+	}
+	short, _ := (&LengthMetric{Max: 10}).Evaluate(ctx, &Sample{Output: "一二三四五六七八九十一二三四五六七八九十"})
+	if short.Score != 0.5 {
+		t.Errorf("Expected half score for doubled length, got %+v", short)
+	}
+
+	keywords, _ := (&KeywordMetric{Keywords: []string{"入门"}}).Evaluate(ctx, sample)
+	if keywords.Score < 0.66 || keywords.Score > 0.67 || keywords.Detail != "缺少关键词: 并发" {
+		t.Errorf("Unexpected keyword coverage: %+v", keywords)
+	}
+
+	valid, _ := (&JSONMetric{}).Evaluate(ctx, sample)
+	if valid.Score != 1 {
+		t.Errorf("Expected fenced JSON to be valid, got %+v", valid)
+	}
+	schema := &provider.JSONSchema{Type: "object", Required: []string{"summary"}}
+	mismatch, _ := (&JSONMetric{Schema: schema}).Evaluate(ctx, sample)
+	if mismatch.Score != 0.5 || mismatch.Detail == "" {
+		t.Errorf("Expected schema mismatch, got %+v", mismatch)
+	}
+	invalid, _ := (&JSONMetric{}).Evaluate(ctx, &Sample{Output: "不是JSON"})
+	if invalid.Score != 0 {
+		t.Errorf("Expected invalid JSON, got %+v", invalid)
+	}
+}
+
+type failingMetric struct{}
+
+func (failingMetric) Evaluate(ctx context.Context, sample *Sample) (*MetricScore, error) {
+	return nil, errors.New("judge unavailable")
+}
+
+func TestScorerWeightsAndFailures(t *testing.T) {
+	scorer, err := NewScorer([]MetricSpec{
+		{Type: MetricLength, MaxLength: 4, Weight: 3},
+		{Type: MetricJSON},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewScorer failed: %v", err)
+	}
+	scorer.metrics = append(scorer.metrics, scoredMetric{name: "judge", weight: 1, threshold: 0.6, metric: failingMetric{}})
+
+	scores, total, passed, err := scorer.Score(context.Background(), &Sample{Case: &Case{}, Output: "[1,2,3,4]"})
+	if err != nil {
+		t.Fatalf("Score failed: %v", err)
+	}
+	// length 4/9 权重 3，json 1 权重 1，失败的 judge 不计入
+	if want := (3*4.0/9 + 1) / 4; total < want-1e-9 || total > want+1e-9 {
+		t.Errorf("Expected weighted score %.4f, got %.4f", want, total)
+	}
+	if passed {
+		t.Error("Expected failure when a metric errors or misses its threshold")
+	}
+	if scores["judge"].Error == "" || !scores["json"].Passed || scores["length"].Passed {
+		t.Errorf("Unexpected metric scores: %+v %+v %+v", scores["judge"], scores["json"], scores["length"])
+	}
+
+	if _, err := NewScorer([]MetricSpec{{Type: MetricJudge}}, nil); err == nil {
+		t.Error("Expected judge metric without backend to fail")
+	}
+	if _, err := NewScorer([]MetricSpec{{Type: "bleu"}}, nil); err == nil {
+		t.Error("Expected unknown metric type to fail")
+	}
+}
+
+func TestCompareResultsUsesScorer(t *testing.T) {
+	scorer, err := NewScorer([]MetricSpec{{Type: MetricJSON}}, nil)
+	if err != nil {
+		t.Fatalf("NewScorer failed: %v", err)
+	}
+	s := &provider.MultiModelService{}
+
+	results := []*provider.ParallelCallResult{
+		{Provider: provider.ProviderOpenRouter, Result: &provider.GenerateResult{Content: "一段很长但不是JSON的回答"}},
+		{Provider: provider.ProviderGroq, Result: &provider.GenerateResult{Content: `{"ok": true}`}},
+		{Provider: provider.ProviderGoogle, Error: errors.New("timeout")},
+	}
+	if best := s.CompareResults(results); best.Provider != provider.ProviderOpenRouter {
+		t.Errorf("Expected first non-empty result without scorer, got %s", best.Provider)
+	}
+
+	s.SetScorer(scorer)
+	best := s.CompareResults(results)
+	if best.Provider != provider.ProviderGroq || best.QualityScore != 1 {
+		t.Errorf("Expected JSON result to win, got %s (%.2f)", best.Provider, best.QualityScore)
+	}
+}
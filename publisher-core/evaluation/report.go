@@ -0,0 +1,305 @@
+package evaluation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"publisher-core/database"
+
+	"gorm.io/gorm"
+)
+
+// MetricSummary 单个指标的汇总
+type MetricSummary struct {
+	Mean     float64 `json:"mean"`
+	PassRate float64 `json:"pass_rate"`
+	// Errors 指标执行失败的用例数，不计入均值
+	Errors int `json:"errors,omitempty"`
+}
+
+// CandidateSummary 单个参评组合的汇总
+type CandidateSummary struct {
+	RunID      string `json:"run_id,omitempty"`
+	Candidate  string `json:"candidate"`
+	Provider   string `json:"provider"`
+	Model      string `json:"model"`
+	TemplateID string `json:"template_id,omitempty"`
+	Cases      int    `json:"cases"`
+	// Errors 生成失败的用例数，按 0 分计入均值
+	Errors    int     `json:"errors"`
+	MeanScore float64 `json:"mean_score"`
+	PassRate  float64 `json:"pass_rate"`
+	// Wins 在同一用例上得分最高的次数，并列时都计入
+	Wins         int                       `json:"wins"`
+	Metrics      map[string]*MetricSummary `json:"metrics"`
+	AvgLatencyMs int64                     `json:"avg_latency_ms"`
+	P95LatencyMs int64                     `json:"p95_latency_ms"`
+	InputTokens  int                       `json:"input_tokens"`
+	OutputTokens int                       `json:"output_tokens"`
+	TotalCost    float64                   `json:"total_cost"`
+}
+
+// Report 一次评测的对比报告，组合按平均得分从高到低排列
+type Report struct {
+	RunID       string                 `json:"run_id"`
+	Name        string                 `json:"name,omitempty"`
+	DatasetID   string                 `json:"dataset_id"`
+	Status      database.EvalRunStatus `json:"status"`
+	Error       string                 `json:"error,omitempty"`
+	Results     int                    `json:"results"`
+	Best        string                 `json:"best,omitempty"`
+	Candidates  []*CandidateSummary    `json:"candidates"`
+	StartedAt   *time.Time             `json:"started_at,omitempty"`
+	CompletedAt *time.Time             `json:"completed_at,omitempty"`
+}
+
+// Summarize 汇总评测结果
+func Summarize(results []database.EvalResult) []*CandidateSummary {
+	byCandidate := make(map[string]*CandidateSummary)
+	var order []string
+	latencies := make(map[string][]int64)
+	passes := make(map[string]int)
+	metricPasses := make(map[string]map[string]int)
+	metricCounts := make(map[string]map[string]int)
+	best := make(map[string]float64)
+
+	for _, r := range results {
+		s, ok := byCandidate[r.Candidate]
+		if !ok {
+			s = &CandidateSummary{
+				Candidate:  r.Candidate,
+				Provider:   r.Provider,
+				Model:      r.Model,
+				TemplateID: r.TemplateID,
+				Metrics:    make(map[string]*MetricSummary),
+			}
+			byCandidate[r.Candidate] = s
+			order = append(order, r.Candidate)
+			metricPasses[r.Candidate] = make(map[string]int)
+			metricCounts[r.Candidate] = make(map[string]int)
+		}
+
+		s.Cases++
+		s.InputTokens += r.InputTokens
+		s.OutputTokens += r.OutputTokens
+		s.TotalCost += r.Cost
+		latencies[r.Candidate] = append(latencies[r.Candidate], r.LatencyMs)
+		// 提供商实际返回的模型可能与请求不同，报告中以非空的实际值为准
+		if r.Model != "" {
+			s.Model = r.Model
+		}
+
+		if r.Error != "" {
+			s.Errors++
+			continue
+		}
+		s.MeanScore += r.Score
+		if r.Passed {
+			passes[r.Candidate]++
+		}
+		if r.Score > best[r.CaseID] {
+			best[r.CaseID] = r.Score
+		}
+
+		var scores map[string]*MetricScore
+		if r.Scores != "" && json.Unmarshal([]byte(r.Scores), &scores) == nil {
+			for name, score := range scores {
+				m, ok := s.Metrics[name]
+				if !ok {
+					m = &MetricSummary{}
+					s.Metrics[name] = m
+				}
+				if score.Error != "" {
+					m.Errors++
+					continue
+				}
+				m.Mean += score.Score
+				metricCounts[r.Candidate][name]++
+				if score.Passed {
+					metricPasses[r.Candidate][name]++
+				}
+			}
+		}
+	}
+
+	for _, r := range results {
+		if r.Error == "" && r.Score > 0 && r.Score == best[r.CaseID] {
+			byCandidate[r.Candidate].Wins++
+		}
+	}
+
+	summaries := make([]*CandidateSummary, 0, len(order))
+	for _, key := range order {
+		s := byCandidate[key]
+		s.MeanScore /= float64(s.Cases)
+		s.PassRate = float64(passes[key]) / float64(s.Cases)
+		for name, m := range s.Metrics {
+			if n := metricCounts[key][name]; n > 0 {
+				m.Mean /= float64(n)
+				m.PassRate = float64(metricPasses[key][name]) / float64(n)
+			}
+		}
+		s.AvgLatencyMs, s.P95LatencyMs = latencyStats(latencies[key])
+		summaries = append(summaries, s)
+	}
+
+	sortSummaries(summaries)
+	return summaries
+}
+
+// sortSummaries 按平均得分排序，得分相同时费用低、延迟低的在前
+func sortSummaries(summaries []*CandidateSummary) {
+	sort.SliceStable(summaries, func(i, j int) bool {
+		a, b := summaries[i], summaries[j]
+		if a.MeanScore != b.MeanScore {
+			return a.MeanScore > b.MeanScore
+		}
+		if a.TotalCost != b.TotalCost {
+			return a.TotalCost < b.TotalCost
+		}
+		return a.AvgLatencyMs < b.AvgLatencyMs
+	})
+}
+
+// latencyStats 计算平均和 P95 延迟
+func latencyStats(values []int64) (int64, int64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum int64
+	for _, v := range sorted {
+		sum += v
+	}
+	idx := (len(sorted)*95+99)/100 - 1
+	return sum / int64(len(sorted)), sorted[idx]
+}
+
+// GetRun 获取运行记录
+func (s *Store) GetRun(runID string) (*database.EvalRun, error) {
+	var run database.EvalRun
+	if err := s.db.Where("run_id = ?", runID).First(&run).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("评测记录不存在: %s", runID)
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListRuns 列出运行记录，datasetID 为空时列出全部
+func (s *Store) ListRuns(datasetID string, limit int) ([]database.EvalRun, error) {
+	query := s.db.Order("id DESC")
+	if datasetID != "" {
+		query = query.Where("dataset_id = ?", datasetID)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var runs []database.EvalRun
+	if err := query.Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// GetResults 获取运行的逐条结果，candidate 为空时返回全部组合
+func (s *Store) GetResults(runID, candidate string) ([]database.EvalResult, error) {
+	query := s.db.Where("run_id = ?", runID)
+	if candidate != "" {
+		query = query.Where("candidate = ?", candidate)
+	}
+
+	var results []database.EvalResult
+	if err := query.Order("id ASC").Find(&results).Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetReport 获取对比报告，运行未完成时按已保存的结果汇总
+func (s *Store) GetReport(runID string) (*Report, error) {
+	run, err := s.GetRun(runID)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status == database.EvalRunCompleted && run.Summary != "" {
+		var report Report
+		if err := json.Unmarshal([]byte(run.Summary), &report); err != nil {
+			return nil, fmt.Errorf("解析评测报告失败: %w", err)
+		}
+		report.Status = run.Status
+		report.CompletedAt = run.CompletedAt
+		return &report, nil
+	}
+	return s.buildReport(run)
+}
+
+// buildReport 按已保存的结果生成报告
+func (s *Store) buildReport(run *database.EvalRun) (*Report, error) {
+	results, err := s.GetResults(run.RunID, "")
+	if err != nil {
+		return nil, fmt.Errorf("查询评测结果失败: %w", err)
+	}
+
+	report := &Report{
+		RunID:       run.RunID,
+		Name:        run.Name,
+		DatasetID:   run.DatasetID,
+		Status:      run.Status,
+		Error:       run.Error,
+		Results:     len(results),
+		Candidates:  Summarize(results),
+		StartedAt:   run.StartedAt,
+		CompletedAt: run.CompletedAt,
+	}
+	if len(report.Candidates) > 0 {
+		report.Best = report.Candidates[0].Candidate
+	}
+	for _, c := range report.Candidates {
+		c.RunID = run.RunID
+	}
+	return report, nil
+}
+
+// Comparison 同一数据集上多次运行的对比
+type Comparison struct {
+	DatasetID  string              `json:"dataset_id"`
+	Best       string              `json:"best,omitempty"`
+	Candidates []*CandidateSummary `json:"candidates"`
+}
+
+// CompareRuns 合并多次运行的组合汇总并统一排序，运行必须使用同一数据集
+// 不同运行的指标配置可能不同，平均得分只在指标一致时可比
+func (s *Store) CompareRuns(runIDs []string) (*Comparison, error) {
+	if len(runIDs) == 0 {
+		return nil, errors.New("至少需要一个评测记录")
+	}
+
+	comparison := &Comparison{}
+	for _, runID := range runIDs {
+		report, err := s.GetReport(runID)
+		if err != nil {
+			return nil, err
+		}
+		if comparison.DatasetID == "" {
+			comparison.DatasetID = report.DatasetID
+		} else if comparison.DatasetID != report.DatasetID {
+			return nil, fmt.Errorf("评测记录 %s 使用的数据集不同: %s", runID, report.DatasetID)
+		}
+		comparison.Candidates = append(comparison.Candidates, report.Candidates...)
+	}
+
+	sortSummaries(comparison.Candidates)
+	if len(comparison.Candidates) > 0 {
+		best := comparison.Candidates[0]
+		comparison.Best = best.RunID + ":" + best.Candidate
+	}
+	return comparison, nil
+}
@@ -0,0 +1,396 @@
+package evaluation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"publisher-core/ai/provider"
+	"publisher-core/cost"
+	"publisher-core/database"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Backend 调用模型生成，ai.Service 实现了该接口
+type Backend interface {
+	Generate(ctx context.Context, opts *provider.GenerateOptions) (*provider.GenerateResult, error)
+	GenerateWithProvider(ctx context.Context, pt provider.ProviderType, opts *provider.GenerateOptions) (*provider.GenerateResult, error)
+}
+
+// TemplateRenderer 把提示词模板渲染为生成请求，prompt.Service 实现了该接口
+type TemplateRenderer interface {
+	RenderToGenerateOptions(templateID string, variables map[string]interface{}) (*provider.GenerateOptions, error)
+}
+
+// Pricer 按 token 用量计算费用，cost.CostService 实现了该接口
+type Pricer interface {
+	CalculateCost(provider, model string, inputTokens, outputTokens int) (float64, float64)
+}
+
+// providerGenerator 把 Backend 适配为指定提供商的 provider.Generator，提供商为空时使用主提供商
+type providerGenerator struct {
+	backend  Backend
+	provider provider.ProviderType
+}
+
+func (g *providerGenerator) Generate(ctx context.Context, opts *provider.GenerateOptions) (*provider.GenerateResult, error) {
+	if g.provider == "" {
+		return g.backend.Generate(ctx, opts)
+	}
+	return g.backend.GenerateWithProvider(ctx, g.provider, opts)
+}
+
+// Candidate 参评组合
+type Candidate struct {
+	// Name 报告中的名称，默认由提供商、模型和模板组成
+	Name     string                `json:"name,omitempty"`
+	Provider provider.ProviderType `json:"provider,omitempty"`
+	Model    string                `json:"model,omitempty"`
+	// TemplateID 提示词模板，为空时直接使用用例的提示词
+	TemplateID  string  `json:"template_id,omitempty"`
+	System      string  `json:"system,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+	MaxTokens   int     `json:"max_tokens,omitempty"`
+}
+
+// Key 组合在报告和结果中的标识
+func (c *Candidate) Key() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	key := string(c.Provider)
+	if key == "" {
+		key = "primary"
+	}
+	if c.Model != "" {
+		key += "/" + c.Model
+	}
+	if c.TemplateID != "" {
+		key += "@" + c.TemplateID
+	}
+	return key
+}
+
+// RunRequest 评测请求
+type RunRequest struct {
+	Name       string       `json:"name"`
+	DatasetID  string       `json:"dataset_id"`
+	Candidates []Candidate  `json:"candidates"`
+	Metrics    []MetricSpec `json:"metrics"`
+	// Concurrency 同时进行的生成数，默认 4
+	Concurrency int `json:"concurrency,omitempty"`
+}
+
+// Runner 执行评测
+type Runner struct {
+	store     *Store
+	backend   Backend
+	templates TemplateRenderer
+	pricer    Pricer
+}
+
+// NewRunner 创建评测执行器
+func NewRunner(store *Store, backend Backend) *Runner {
+	return &Runner{store: store, backend: backend}
+}
+
+// SetTemplates 设置提示词模板，参评组合指定了模板时需要
+func (r *Runner) SetTemplates(templates TemplateRenderer) {
+	r.templates = templates
+}
+
+// SetPricer 设置计费，未设置时结果中的费用为 0
+func (r *Runner) SetPricer(pricer Pricer) {
+	r.pricer = pricer
+}
+
+// plan 已校验的评测计划
+type plan struct {
+	run        *database.EvalRun
+	dataset    *Dataset
+	candidates []Candidate
+	scorer     *Scorer
+	workers    int
+}
+
+// prepare 校验请求并创建运行记录
+func (r *Runner) prepare(req *RunRequest) (*plan, error) {
+	if len(req.Candidates) == 0 {
+		return nil, errors.New("至少需要一个参评组合")
+	}
+	seen := make(map[string]bool, len(req.Candidates))
+	for i := range req.Candidates {
+		c := &req.Candidates[i]
+		if seen[c.Key()] {
+			return nil, fmt.Errorf("参评组合重复: %s", c.Key())
+		}
+		seen[c.Key()] = true
+		if c.TemplateID != "" && r.templates == nil {
+			return nil, fmt.Errorf("参评组合 %s 指定了模板，但未配置模板服务", c.Key())
+		}
+	}
+
+	ds, err := r.store.GetDataset(req.DatasetID)
+	if err != nil {
+		return nil, err
+	}
+	scorer, err := NewScorer(req.Metrics, r.backend)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := req.Metrics
+	if len(metrics) == 0 {
+		metrics = DefaultMetrics()
+	}
+	candidatesJSON, err := json.Marshal(req.Candidates)
+	if err != nil {
+		return nil, fmt.Errorf("序列化参评组合失败: %w", err)
+	}
+	metricsJSON, err := json.Marshal(metrics)
+	if err != nil {
+		return nil, fmt.Errorf("序列化指标配置失败: %w", err)
+	}
+
+	run := &database.EvalRun{
+		RunID:      uuid.New().String(),
+		Name:       req.Name,
+		DatasetID:  ds.ID,
+		Status:     database.EvalRunPending,
+		Candidates: string(candidatesJSON),
+		Metrics:    string(metricsJSON),
+	}
+	if err := r.store.db.Create(run).Error; err != nil {
+		return nil, fmt.Errorf("创建评测记录失败: %w", err)
+	}
+
+	workers := req.Concurrency
+	if workers <= 0 {
+		workers = 4
+	}
+	return &plan{
+		run:        run,
+		dataset:    ds,
+		candidates: req.Candidates,
+		scorer:     scorer,
+		workers:    workers,
+	}, nil
+}
+
+// Run 执行评测并返回对比报告
+func (r *Runner) Run(ctx context.Context, req *RunRequest) (*Report, error) {
+	p, err := r.prepare(req)
+	if err != nil {
+		return nil, err
+	}
+	return r.execute(ctx, p)
+}
+
+// Start 在后台执行评测，立即返回运行记录，可通过 Store.GetReport 查询进度和结果
+func (r *Runner) Start(req *RunRequest) (*database.EvalRun, error) {
+	p, err := r.prepare(req)
+	if err != nil {
+		return nil, err
+	}
+	run := *p.run
+	go func() {
+		if _, err := r.execute(context.Background(), p); err != nil {
+			logrus.Errorf("评测执行失败: %s, 错误: %v", p.run.RunID, err)
+		}
+	}()
+	return &run, nil
+}
+
+// job 一个组合在一条用例上的生成
+type job struct {
+	candidate *Candidate
+	c         *Case
+}
+
+// execute 按组合和用例生成并打分，结果逐条保存，完成后汇总报告
+func (r *Runner) execute(ctx context.Context, p *plan) (*Report, error) {
+	started := time.Now()
+	p.run.Status = database.EvalRunRunning
+	p.run.StartedAt = &started
+	if err := r.store.db.Model(p.run).Updates(map[string]interface{}{
+		"status":     p.run.Status,
+		"started_at": started,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("更新评测状态失败: %w", err)
+	}
+
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+
+	for i := 0; i < p.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				result, err := r.evaluate(ctx, p, j.candidate, j.c)
+				mu.Lock()
+				if err == nil {
+					err = r.store.db.Create(result).Error
+				}
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for i := range p.candidates {
+		for k := range p.dataset.Cases {
+			select {
+			case jobs <- job{candidate: &p.candidates[i], c: &p.dataset.Cases[k]}:
+			case <-ctx.Done():
+				break feed
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	if firstErr != nil {
+		r.finish(p.run, database.EvalRunFailed, "", firstErr.Error())
+		return nil, firstErr
+	}
+
+	report, err := r.store.buildReport(p.run)
+	if err != nil {
+		r.finish(p.run, database.EvalRunFailed, "", err.Error())
+		return nil, err
+	}
+	summary, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("序列化评测报告失败: %w", err)
+	}
+	r.finish(p.run, database.EvalRunCompleted, string(summary), "")
+	report.Status = database.EvalRunCompleted
+	return report, nil
+}
+
+// finish 记录运行结束状态
+func (r *Runner) finish(run *database.EvalRun, status database.EvalRunStatus, summary, errMsg string) {
+	now := time.Now()
+	run.Status = status
+	run.Summary = summary
+	run.Error = errMsg
+	run.CompletedAt = &now
+	if err := r.store.db.Model(run).Updates(map[string]interface{}{
+		"status":       status,
+		"summary":      summary,
+		"error":        errMsg,
+		"completed_at": now,
+	}).Error; err != nil {
+		logrus.Errorf("更新评测状态失败: %s, 错误: %v", run.RunID, err)
+	}
+}
+
+// evaluate 生成一条输出并打分，生成失败记录在结果中，只有上下文取消时返回错误
+func (r *Runner) evaluate(ctx context.Context, p *plan, candidate *Candidate, c *Case) (*database.EvalResult, error) {
+	result := &database.EvalResult{
+		RunID:      p.run.RunID,
+		Candidate:  candidate.Key(),
+		CaseID:     c.ID,
+		Provider:   string(candidate.Provider),
+		Model:      candidate.Model,
+		TemplateID: candidate.TemplateID,
+	}
+
+	opts, err := r.request(candidate, c)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	prompt := opts.Messages[len(opts.Messages)-1].Content
+	result.Model = opts.Model
+
+	// 评测需要真实的生成结果和耗时，不使用响应缓存
+	genCtx := cost.WithoutCache(cost.WithFunction(ctx, "evaluation"))
+	gen := &providerGenerator{backend: r.backend, provider: candidate.Provider}
+	start := time.Now()
+	output, err := gen.Generate(genCtx, opts)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	result.Output = output.Content
+	result.InputTokens = output.InputTokens
+	result.OutputTokens = output.OutputTokens
+	if output.Model != "" {
+		result.Model = output.Model
+	}
+	if output.Provider != "" {
+		result.Provider = output.Provider
+	}
+	if r.pricer != nil {
+		_, result.Cost = r.pricer.CalculateCost(result.Provider, result.Model, result.InputTokens, result.OutputTokens)
+	}
+
+	scores, score, passed, err := p.scorer.Score(ctx, &Sample{Case: c, Prompt: prompt, Output: output.Content})
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(scores)
+	if err != nil {
+		return nil, fmt.Errorf("序列化得分失败: %w", err)
+	}
+	result.Scores = string(data)
+	result.Score = score
+	result.Passed = passed
+	return result, nil
+}
+
+// request 构造用例在该组合下的生成请求
+// 指定模板时使用模板渲染的消息和默认参数，组合中设置的模型、温度和最大 token 数优先
+func (r *Runner) request(candidate *Candidate, c *Case) (*provider.GenerateOptions, error) {
+	opts := &provider.GenerateOptions{}
+	if candidate.TemplateID == "" {
+		if c.Prompt == "" {
+			return nil, errors.New("用例没有提示词，参评组合需要指定模板")
+		}
+		opts.Messages = []provider.Message{{Role: provider.RoleUser, Content: c.Prompt}}
+	} else {
+		rendered, err := r.templates.RenderToGenerateOptions(candidate.TemplateID, c.Variables)
+		if err != nil {
+			return nil, fmt.Errorf("渲染模板失败: %w", err)
+		}
+		opts = rendered
+	}
+
+	system := candidate.System
+	if system == "" {
+		system = c.System
+	}
+	if system != "" && opts.Messages[0].Role != provider.RoleSystem {
+		opts.Messages = append([]provider.Message{{Role: provider.RoleSystem, Content: system}}, opts.Messages...)
+	}
+	if candidate.Model != "" {
+		opts.Model = candidate.Model
+	}
+	if candidate.Temperature != 0 {
+		opts.Temperature = candidate.Temperature
+	}
+	if candidate.MaxTokens != 0 {
+		opts.MaxTokens = candidate.MaxTokens
+	}
+	return opts, nil
+}
@@ -0,0 +1,232 @@
+package evaluation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"publisher-core/ai/provider"
+	"publisher-core/database"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestStore(t *testing.T) *Store {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	// 内存数据库每个连接独立，限制为单连接
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&database.EvalDataset{}, &database.EvalCase{}, &database.EvalRun{}, &database.EvalResult{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	return NewStore(db)
+}
+
+// fakeBackend 按提供商返回固定风格的输出，评审请求按输出中是否包含“详细”打分
+type fakeBackend struct {
+	mu     sync.Mutex
+	judged int
+}
+
+func (b *fakeBackend) Generate(ctx context.Context, opts *provider.GenerateOptions) (*provider.GenerateResult, error) {
+	b.mu.Lock()
+	b.judged++
+	b.mu.Unlock()
+
+	score := 3
+	if strings.Contains(opts.Messages[1].Content, "详细") {
+		score = 9
+	}
+	var items []string
+	for _, c := range defaultCriteria {
+		items = append(items, fmt.Sprintf(`{"criterion": %q, "score": %d, "reason": "ok"}`, c.Name, score))
+	}
+	return &provider.GenerateResult{
+		Content: fmt.Sprintf(`{"scores": [%s], "summary": "评审完成"}`, strings.Join(items, ",")),
+	}, nil
+}
+
+func (b *fakeBackend) GenerateWithProvider(ctx context.Context, pt provider.ProviderType, opts *provider.GenerateOptions) (*provider.GenerateResult, error) {
+	prompt := opts.Messages[len(opts.Messages)-1].Content
+	switch pt {
+	case "good":
+		return &provider.GenerateResult{
+			Content:      "关于" + prompt + "的详细介绍，涵盖 Go 和并发。",
+			Model:        "good-large",
+			InputTokens:  100,
+			OutputTokens: 50,
+		}, nil
+	case "bad":
+		return &provider.GenerateResult{Content: "不知道", Model: "bad-small", InputTokens: 100, OutputTokens: 5}, nil
+	}
+	return nil, errors.New("provider unavailable")
+}
+
+type fakeTemplates struct{}
+
+func (fakeTemplates) RenderToGenerateOptions(templateID string, variables map[string]interface{}) (*provider.GenerateOptions, error) {
+	return &provider.GenerateOptions{
+		Messages: []provider.Message{{Role: provider.RoleUser, Content: fmt.Sprintf("%v", variables["topic"])}},
+	}, nil
+}
+
+type fakePricer struct{}
+
+func (fakePricer) CalculateCost(provider, model string, inputTokens, outputTokens int) (float64, float64) {
+	return 0.001, float64(inputTokens+outputTokens) * 0.001
+}
+
+func approx(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
+
+func TestRunnerReport(t *testing.T) {
+	store := newTestStore(t)
+	if err := store.SaveDataset(&Dataset{
+		ID:   "intro",
+		Name: "技术介绍",
+		Cases: []Case{
+			{ID: "go", Variables: map[string]interface{}{"topic": "Go"}, Prompt: "介绍 Go", Keywords: []string{"go", "并发"}},
+			{ID: "rust", Variables: map[string]interface{}{"topic": "Rust"}, Prompt: "介绍 Rust", Keywords: []string{"rust", "所有权"}},
+		},
+	}); err != nil {
+		t.Fatalf("SaveDataset failed: %v", err)
+	}
+
+	backend := &fakeBackend{}
+	runner := NewRunner(store, backend)
+	runner.SetTemplates(fakeTemplates{})
+	runner.SetPricer(fakePricer{})
+
+	req := &RunRequest{
+		Name:      "baseline",
+		DatasetID: "intro",
+		Candidates: []Candidate{
+			{Provider: "bad", TemplateID: "intro"},
+			{Provider: "good", Model: "large", TemplateID: "intro"},
+			{Provider: "down"},
+		},
+		Metrics: []MetricSpec{
+			{Type: MetricLength, MinLength: 5, MaxLength: 200},
+			{Type: MetricKeywords, Threshold: 0.5},
+			{Type: MetricJudge, Rubric: "内容越具体越好", Weight: 2},
+		},
+		Concurrency: 2,
+	}
+	report, err := runner.Run(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if report.Status != database.EvalRunCompleted || report.Results != 6 {
+		t.Fatalf("Unexpected report: status=%s results=%d", report.Status, report.Results)
+	}
+	if report.Best != "good/large@intro" || len(report.Candidates) != 3 {
+		t.Fatalf("Unexpected ranking: best=%s candidates=%d", report.Best, len(report.Candidates))
+	}
+	if backend.judged != 4 {
+		t.Errorf("Expected judge to run only for successful generations, got %d calls", backend.judged)
+	}
+
+	good, bad, down := report.Candidates[0], report.Candidates[1], report.Candidates[2]
+	if good.Model != "good-large" || good.PassRate != 1 || good.Wins != 2 {
+		t.Errorf("Unexpected summary for good: %+v", good)
+	}
+	// Rust 用例缺少“所有权”，其余关键词都覆盖了
+	if !approx(good.Metrics[MetricKeywords].Mean, 0.75) {
+		t.Errorf("Expected keyword mean 0.75, got %.2f", good.Metrics[MetricKeywords].Mean)
+	}
+	if !approx(good.TotalCost, 0.3) || good.InputTokens != 200 {
+		t.Errorf("Unexpected usage: cost=%.3f tokens=%d", good.TotalCost, good.InputTokens)
+	}
+	if bad.MeanScore >= good.MeanScore || bad.PassRate != 0 || !approx(bad.Metrics[MetricJudge].Mean, 0.3) {
+		t.Errorf("Unexpected summary for bad: %+v", bad)
+	}
+	if down.Errors != 2 || down.MeanScore != 0 {
+		t.Errorf("Expected failed generations to score zero: %+v", down)
+	}
+
+	stored, err := store.GetReport(report.RunID)
+	if err != nil {
+		t.Fatalf("GetReport failed: %v", err)
+	}
+	if stored.Best != report.Best || stored.CompletedAt == nil {
+		t.Errorf("Stored report differs: %+v", stored)
+	}
+
+	req.Name = "retry"
+	req.Candidates = req.Candidates[:1]
+	second, err := runner.Run(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Second run failed: %v", err)
+	}
+	comparison, err := store.CompareRuns([]string{report.RunID, second.RunID})
+	if err != nil {
+		t.Fatalf("CompareRuns failed: %v", err)
+	}
+	if len(comparison.Candidates) != 4 || comparison.Best != report.RunID+":good/large@intro" {
+		t.Errorf("Unexpected comparison: best=%s candidates=%d", comparison.Best, len(comparison.Candidates))
+	}
+
+	if _, err := runner.Run(context.Background(), &RunRequest{
+		DatasetID:  "intro",
+		Candidates: []Candidate{{Provider: "good"}, {Provider: "good"}},
+	}); err == nil {
+		t.Error("Expected duplicate candidates to be rejected")
+	}
+}
+
+func TestLoadDatasetFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "titles.jsonl")
+	content := `{"id": "a", "prompt": "写一个标题", "keywords": ["标题"]}
+
+{"variables": {"topic": "AI"}, "reference": "AI 改变生活"}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write dataset: %v", err)
+	}
+
+	store := newTestStore(t)
+	ds, err := store.ImportFile(path)
+	if err != nil {
+		t.Fatalf("ImportFile failed: %v", err)
+	}
+	if ds.ID != "titles" || len(ds.Cases) != 2 || ds.Cases[1].ID != "case-2" {
+		t.Fatalf("Unexpected dataset: %+v", ds)
+	}
+
+	loaded, err := store.GetDataset("titles")
+	if err != nil {
+		t.Fatalf("GetDataset failed: %v", err)
+	}
+	if loaded.Source != path || loaded.Cases[0].Keywords[0] != "标题" || loaded.Cases[1].Variables["topic"] != "AI" {
+		t.Errorf("Dataset did not round-trip: %+v", loaded)
+	}
+
+	// 重新导入时替换用例
+	if err := os.WriteFile(path, []byte(`{"id": "only", "prompt": "p"}`), 0644); err != nil {
+		t.Fatalf("Failed to write dataset: %v", err)
+	}
+	if _, err := store.ImportFile(path); err != nil {
+		t.Fatalf("Reimport failed: %v", err)
+	}
+	if loaded, _ = store.GetDataset("titles"); len(loaded.Cases) != 1 {
+		t.Errorf("Expected cases to be replaced, got %d", len(loaded.Cases))
+	}
+
+	bad := filepath.Join(t.TempDir(), "empty.json")
+	os.WriteFile(bad, []byte(`{"cases": [{"id": "x"}]}`), 0644)
+	if _, err := LoadDatasetFile(bad); err == nil {
+		t.Error("Expected case without prompt or variables to be rejected")
+	}
+}
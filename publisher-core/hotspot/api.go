@@ -6,17 +6,25 @@ import (
 	"strconv"
 	"time"
 
+	"publisher-core/database"
+
 	"github.com/gorilla/mux"
 )
 
 type APIHandler struct {
 	service *HotspotService
+	events  *EnhancedService
 }
 
 func NewAPIHandler(service *HotspotService) *APIHandler {
 	return &APIHandler{service: service}
 }
 
+// SetEventService 设置提供跨来源热点事件的服务
+func (h *APIHandler) SetEventService(events *EnhancedService) {
+	h.events = events
+}
+
 func (h *APIHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/hot-topics", h.ListTopics).Methods("GET")
 	router.HandleFunc("/api/hot-topics/{id}", h.GetTopic).Methods("GET")
@@ -26,6 +34,9 @@ func (h *APIHandler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/api/hot-topics/update", h.RefreshTopics).Methods("POST")
 	router.HandleFunc("/api/hot-topics/trends/new", h.GetNewTopics).Methods("GET")
 	router.HandleFunc("/api/hot-topics/{id}", h.DeleteTopic).Methods("DELETE")
+	router.HandleFunc("/api/hot-events", h.ListEvents).Methods("GET")
+	router.HandleFunc("/api/hot-events/fetch", h.FetchEvents).Methods("POST")
+	router.HandleFunc("/api/hot-events/{id}", h.GetEvent).Methods("GET")
 }
 
 type APIResponse struct {
@@ -40,6 +51,11 @@ type ListResponse struct {
 	Pagination Pagination `json:"pagination"`
 }
 
+type EventListResponse struct {
+	Events     []database.HotEvent `json:"data"`
+	Pagination Pagination          `json:"pagination"`
+}
+
 func (h *APIHandler) jsonSuccess(w http.ResponseWriter, data interface{}) {
 	resp := APIResponse{
 		Success:   true,
@@ -241,3 +257,113 @@ func (h *APIHandler) DeleteTopic(w http.ResponseWriter, r *http.Request) {
 
 	h.jsonSuccess(w, map[string]string{"message": "deleted"})
 }
+
+func (h *APIHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	if h.events == nil {
+		h.jsonError(w, "event service not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := r.URL.Query()
+
+	page, _ := strconv.Atoi(query.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	hours, _ := strconv.Atoi(query.Get("hours"))
+	if hours <= 0 {
+		hours = 24
+	}
+
+	filter := database.EventFilter{
+		Since:  time.Now().Add(-time.Duration(hours) * time.Hour),
+		Limit:  limit,
+		Offset: (page - 1) * limit,
+	}
+	filter.MinSources, _ = strconv.Atoi(query.Get("minSources"))
+
+	events, total, err := h.events.ListEvents(filter)
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	pages := int(total) / limit
+	if int(total)%limit > 0 {
+		pages++
+	}
+
+	h.jsonSuccess(w, EventListResponse{
+		Events: events,
+		Pagination: Pagination{
+			Page:    page,
+			Limit:   limit,
+			Total:   int(total),
+			Pages:   pages,
+			HasMore: page < pages,
+		},
+	})
+}
+
+func (h *APIHandler) GetEvent(w http.ResponseWriter, r *http.Request) {
+	if h.events == nil {
+		h.jsonError(w, "event service not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	event, err := h.events.GetEvent(mux.Vars(r)["id"])
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if event == nil {
+		h.jsonError(w, "event not found", http.StatusNotFound)
+		return
+	}
+
+	h.jsonSuccess(w, event)
+}
+
+// FetchEvents 从所有数据源抓取并聚类，返回本次抓取涉及的事件
+func (h *APIHandler) FetchEvents(w http.ResponseWriter, r *http.Request) {
+	if h.events == nil {
+		h.jsonError(w, "event service not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	maxItems, _ := strconv.Atoi(r.URL.Query().Get("maxItems"))
+	if maxItems == 0 {
+		maxItems = 20
+	}
+
+	start := time.Now()
+	results, err := h.events.FetchFromAllSources(r.Context(), maxItems)
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	fetched := 0
+	for _, topics := range results {
+		fetched += len(topics)
+	}
+
+	events, total, err := h.events.ListEvents(database.EventFilter{Since: start, Limit: 50})
+	if err != nil {
+		h.jsonError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.jsonSuccess(w, map[string]interface{}{
+		"fetched": fetched,
+		"total":   total,
+		"events":  events,
+	})
+}
@@ -0,0 +1,366 @@
+package hotspot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"publisher-core/ai/provider"
+	"publisher-core/cost"
+	"publisher-core/database"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	shingleSize = 2   // 按字符 2-gram 切分标题，中文标题没有分词也能比较
+	minHashSize = 128 // MinHash 签名长度，估计误差约为 1/sqrt(128)
+	minShingles = 3   // n-gram 少于该数量的短标题只按 Jaccard 系数比较
+)
+
+// minHashSeeds 每个 MinHash 函数的种子，固定生成以保证签名在进程间一致
+var minHashSeeds = func() []uint64 {
+	seeds := make([]uint64, minHashSize)
+	var x uint64
+	for i := range seeds {
+		x += 0x9e3779b97f4a7c15
+		seeds[i] = mix64(x)
+	}
+	return seeds
+}()
+
+// mix64 splitmix64 的混合函数
+func mix64(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// titleSignature 标题的 MinHash 签名
+type titleSignature struct {
+	hashes []uint64
+	size   int // 标题的 n-gram 数量
+}
+
+// normalizeTitle 统一大小写并去掉标点、空白和 emoji，只保留文字和数字
+func normalizeTitle(title string) []rune {
+	var runes []rune
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}
+
+// newTitleSignature 计算标题的 MinHash 签名
+func newTitleSignature(title string) *titleSignature {
+	runes := normalizeTitle(title)
+	shingles := make(map[string]struct{})
+	if len(runes) > 0 && len(runes) < shingleSize {
+		shingles[string(runes)] = struct{}{}
+	}
+	for i := 0; i+shingleSize <= len(runes); i++ {
+		shingles[string(runes[i:i+shingleSize])] = struct{}{}
+	}
+
+	sig := &titleSignature{hashes: make([]uint64, minHashSize), size: len(shingles)}
+	for i := range sig.hashes {
+		sig.hashes[i] = math.MaxUint64
+	}
+	for shingle := range shingles {
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		base := h.Sum64()
+		for i, seed := range minHashSeeds {
+			if v := mix64(base ^ seed); v < sig.hashes[i] {
+				sig.hashes[i] = v
+			}
+		}
+	}
+	return sig
+}
+
+// similarity 估计较短标题的 n-gram 有多大比例出现在另一个标题中，取值 0-1
+// 各平台对同一事件的标题长短差别较大，按 Jaccard 系数比较时长标题会被低估，
+// 因此由 MinHash 估计的 Jaccard 系数和两边的 n-gram 数量换算出重合部分占较短标题的比例
+func (a *titleSignature) similarity(b *titleSignature) float64 {
+	if a.size == 0 || b.size == 0 {
+		return 0
+	}
+	equal := 0
+	for i := range a.hashes {
+		if a.hashes[i] == b.hashes[i] {
+			equal++
+		}
+	}
+	jaccard := float64(equal) / minHashSize
+	smaller := min(a.size, b.size)
+	if smaller < minShingles {
+		return jaccard
+	}
+	// |A∩B| = J * (|A| + |B|) / (1 + J)
+	overlap := jaccard * float64(a.size+b.size) / (1 + jaccard) / float64(smaller)
+	return math.Min(overlap, 1)
+}
+
+// cosineSimilarity 计算两个向量的余弦相似度
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// eventCluster 聚类时的候选事件
+type eventCluster struct {
+	id         string
+	title      string // 热度最高的成员标题，用于向量匹配
+	signatures []*titleSignature
+	vector     []float32
+	// batchSources 本次抓取中已归入该事件的来源
+	batchSources map[string]bool
+}
+
+// titleSimilarity 与事件中最相似的成员标题的相似度
+func (c *eventCluster) titleSimilarity(sig *titleSignature) float64 {
+	best := 0.0
+	for _, member := range c.signatures {
+		if sim := sig.similarity(member); sim > best {
+			best = sim
+		}
+	}
+	return best
+}
+
+// bestCluster 相似度不低于阈值的最相似事件，本次抓取中已有同来源话题的事件不参与匹配
+func bestCluster(clusters []*eventCluster, source string, threshold float64, score func(*eventCluster) float64) *eventCluster {
+	var best *eventCluster
+	bestScore := threshold
+	for _, c := range clusters {
+		if c.batchSources[source] {
+			continue
+		}
+		if sim := score(c); sim >= bestScore {
+			best, bestScore = c, sim
+		}
+	}
+	return best
+}
+
+// clusterTopics 把本次抓取的话题归入跨来源的热点事件
+// 已归入事件的话题保持不变；其余话题先按标题相似度匹配最近出现过的事件，
+// 配置了向量模型时对未匹配的话题再按标题向量匹配，仍未匹配的话题各自创建新事件。
+// 同一榜单中的不同话题标题可能很接近，因此一次抓取中每个来源只有一个话题能归入同一事件
+func (s *EnhancedService) clusterTopics(ctx context.Context, topics []database.Topic) error {
+	since := time.Now().Add(-time.Duration(s.config.ClusterWindowHours) * time.Hour)
+	var members []database.Topic
+	if err := s.db.Where("event_id <> '' AND last_crawl_time >= ?", since).
+		Order("heat desc").Find(&members).Error; err != nil {
+		return fmt.Errorf("查询事件成员失败: %w", err)
+	}
+
+	byID := make(map[string]*eventCluster)
+	var clusters []*eventCluster
+	for _, m := range members {
+		c, ok := byID[m.EventID]
+		if !ok {
+			c = &eventCluster{id: m.EventID, title: m.Title, batchSources: make(map[string]bool)}
+			byID[m.EventID] = c
+			clusters = append(clusters, c)
+		}
+		c.signatures = append(c.signatures, newTitleSignature(m.Title))
+	}
+
+	touched := make(map[string]bool)
+	var assigned []*database.Topic
+	assign := func(topic *database.Topic, c *eventCluster, sig *titleSignature) {
+		topic.EventID = c.id
+		c.signatures = append(c.signatures, sig)
+		c.batchSources[topic.Source] = true
+		touched[c.id] = true
+		assigned = append(assigned, topic)
+	}
+
+	// 已归入事件的话题先占用来源，避免同来源的其它话题再归入同一事件
+	for i := range topics {
+		if id := topics[i].EventID; id != "" {
+			touched[id] = true
+			if c := byID[id]; c != nil {
+				c.batchSources[topics[i].Source] = true
+			}
+		}
+	}
+
+	var pending []int
+	signatures := make(map[int]*titleSignature)
+	for i := range topics {
+		topic := &topics[i]
+		if topic.EventID != "" {
+			continue
+		}
+		sig := newTitleSignature(topic.Title)
+		signatures[i] = sig
+		if c := bestCluster(clusters, topic.Source, s.config.ClusterThreshold, func(c *eventCluster) float64 {
+			return c.titleSimilarity(sig)
+		}); c != nil {
+			assign(topic, c, sig)
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	pending = s.matchByEmbedding(ctx, topics, pending, clusters, func(i int, c *eventCluster) {
+		assign(&topics[i], c, signatures[i])
+	})
+
+	for _, i := range pending {
+		topic := &topics[i]
+		topic.EventID = uuid.New().String()
+		touched[topic.EventID] = true
+		assigned = append(assigned, topic)
+	}
+
+	if len(touched) == 0 {
+		return nil
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, topic := range assigned {
+			if err := tx.Model(&database.Topic{}).Where("id = ?", topic.ID).
+				Update("event_id", topic.EventID).Error; err != nil {
+				return err
+			}
+		}
+		for id := range touched {
+			if err := refreshEvent(tx, id); err != nil {
+				return fmt.Errorf("更新事件 %s 失败: %w", id, err)
+			}
+		}
+		return nil
+	})
+}
+
+// matchByEmbedding 按标题向量匹配标题相似度不够的话题，返回仍未匹配的话题
+// 未配置向量模型或调用失败时不做匹配
+func (s *EnhancedService) matchByEmbedding(ctx context.Context, topics []database.Topic, pending []int, clusters []*eventCluster, assign func(int, *eventCluster)) []int {
+	if s.embedder == nil || s.config.EmbeddingThreshold <= 0 || len(pending) == 0 || len(clusters) == 0 {
+		return pending
+	}
+
+	input := make([]string, 0, len(pending)+len(clusters))
+	for _, i := range pending {
+		input = append(input, topics[i].Title)
+	}
+	for _, c := range clusters {
+		input = append(input, c.title)
+	}
+	result, err := s.embedder.Embed(cost.WithFunction(ctx, "hotspot_clustering"), &provider.EmbedOptions{
+		Model: s.embedder.DefaultEmbeddingModel(),
+		Input: input,
+	})
+	if err != nil {
+		logrus.Warnf("Failed to embed topic titles: %v", err)
+		return pending
+	}
+	if len(result.Vectors) != len(input) {
+		logrus.Warnf("Embedding returned %d vectors for %d titles", len(result.Vectors), len(input))
+		return pending
+	}
+	for j, c := range clusters {
+		c.vector = result.Vectors[len(pending)+j]
+	}
+
+	var rest []int
+	for k, i := range pending {
+		vector := result.Vectors[k]
+		c := bestCluster(clusters, topics[i].Source, s.config.EmbeddingThreshold, func(c *eventCluster) float64 {
+			return cosineSimilarity(vector, c.vector)
+		})
+		if c == nil {
+			rest = append(rest, i)
+			continue
+		}
+		assign(i, c)
+	}
+	return rest
+}
+
+// refreshEvent 按成员话题重新计算事件的标题、热度和来源覆盖，没有成员时删除事件
+// 同一来源有多个成员时只计入热度最高的一个，避免来源更换话题ID后重复计算
+func refreshEvent(tx *gorm.DB, id string) error {
+	var members []database.Topic
+	if err := tx.Where("event_id = ?", id).Order("heat desc").Find(&members).Error; err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return tx.Where("id = ?", id).Delete(&database.HotEvent{}).Error
+	}
+
+	now := time.Now()
+	event := database.HotEvent{
+		ID:         id,
+		Title:      members[0].Title,
+		Category:   members[0].Category,
+		TopicCount: len(members),
+		UpdatedAt:  now,
+	}
+	seen := make(map[string]bool)
+	var sources []string
+	for _, m := range members {
+		if !seen[m.Source] {
+			seen[m.Source] = true
+			sources = append(sources, m.Source)
+			event.Heat += m.Heat
+		}
+		if !m.FirstCrawlTime.IsZero() && (event.FirstSeenAt.IsZero() || m.FirstCrawlTime.Before(event.FirstSeenAt)) {
+			event.FirstSeenAt = m.FirstCrawlTime
+		}
+		if m.LastCrawlTime.After(event.LastSeenAt) {
+			event.LastSeenAt = m.LastCrawlTime
+		}
+	}
+	sort.Strings(sources)
+	event.SourceCount = len(sources)
+	sourcesJSON, _ := json.Marshal(sources)
+	event.Sources = string(sourcesJSON)
+
+	var existing database.HotEvent
+	err := tx.Where("id = ?", id).First(&existing).Error
+	switch {
+	case err == nil:
+		event.CreatedAt = existing.CreatedAt
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		event.CreatedAt = now
+	default:
+		return err
+	}
+	return tx.Save(&event).Error
+}
+
+// ListEvents 按热度列出跨来源热点事件
+func (s *EnhancedService) ListEvents(filter database.EventFilter) ([]database.HotEvent, int64, error) {
+	return s.storage.ListEvents(filter)
+}
+
+// GetEvent 获取热点事件及其在各来源的话题
+func (s *EnhancedService) GetEvent(id string) (*database.HotEvent, error) {
+	return s.storage.GetEvent(id)
+}
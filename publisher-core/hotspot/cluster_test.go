@@ -0,0 +1,213 @@
+package hotspot
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"publisher-core/ai/provider"
+	"publisher-core/database"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type staticSource struct {
+	id     string
+	topics []Topic
+}
+
+func (s *staticSource) ID() string      { return s.id }
+func (s *staticSource) Name() string    { return s.id }
+func (s *staticSource) IsEnabled() bool { return true }
+func (s *staticSource) SetEnabled(bool) {}
+func (s *staticSource) Fetch(ctx context.Context, maxItems int) ([]Topic, error) {
+	return s.topics, nil
+}
+
+type fakeEmbedder struct {
+	vectors map[string][]float32
+}
+
+func (e *fakeEmbedder) DefaultEmbeddingModel() string { return "fake" }
+
+func (e *fakeEmbedder) Embed(ctx context.Context, opts *provider.EmbedOptions) (*provider.EmbedResult, error) {
+	result := &provider.EmbedResult{}
+	for _, input := range opts.Input {
+		vector, ok := e.vectors[input]
+		if !ok {
+			vector = []float32{0, 0, 1}
+		}
+		result.Vectors = append(result.Vectors, vector)
+	}
+	return result, nil
+}
+
+func newClusterTestService(t *testing.T) *EnhancedService {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&database.Topic{}, &database.RankHistory{}, &database.CrawlRecord{}, &database.HotEvent{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	return NewEnhancedService(db, nil)
+}
+
+func fetch(t *testing.T, s *EnhancedService, source string, topics ...Topic) []database.Topic {
+	t.Helper()
+	for i := range topics {
+		topics[i].Source = source
+	}
+	s.RegisterSource(&staticSource{id: source, topics: topics})
+	saved, err := s.FetchAndSave(context.Background(), source, 0)
+	if err != nil {
+		t.Fatalf("FetchAndSave %s failed: %v", source, err)
+	}
+	return saved
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		match bool
+	}{
+		{"神舟二十号载人飞船发射成功", "神舟二十号发射成功！航天员顺利进入空间站", true},
+		{"OpenAI 发布 GPT-5", "openai发布gpt5", true},
+		{"神舟二十号载人飞船发射成功", "多地迎来今年首场降雪", false},
+		{"国足世预赛客场负于日本", "日本首相访问美国", false},
+	}
+	for _, tt := range tests {
+		sim := newTitleSignature(tt.a).similarity(newTitleSignature(tt.b))
+		if (sim >= DefaultEnhancedConfig().ClusterThreshold) != tt.match {
+			t.Errorf("similarity(%q, %q) = %.2f, want match=%v", tt.a, tt.b, sim, tt.match)
+		}
+	}
+	if sim := newTitleSignature("").similarity(newTitleSignature("")); sim != 0 {
+		t.Errorf("Expected empty titles not to match, got %.2f", sim)
+	}
+}
+
+func TestClusterTopicsAcrossSources(t *testing.T) {
+	s := newClusterTestService(t)
+
+	weibo := fetch(t, s, "weibo",
+		Topic{ID: "weibo-1", Title: "神舟二十号载人飞船发射成功", Heat: 900},
+		Topic{ID: "weibo-2", Title: "多地迎来今年首场降雪", Heat: 500},
+	)
+	zhihu := fetch(t, s, "zhihu",
+		Topic{ID: "zhihu-1", Title: "如何看待神舟二十号载人飞船发射成功？", Heat: 300},
+		Topic{ID: "zhihu-2", Title: "年轻人为什么不爱存钱了", Heat: 200},
+	)
+
+	if weibo[0].EventID == "" || zhihu[0].EventID != weibo[0].EventID {
+		t.Fatalf("Expected same event for the launch topics: %q, %q", weibo[0].EventID, zhihu[0].EventID)
+	}
+	if weibo[1].EventID == weibo[0].EventID || zhihu[1].EventID == weibo[1].EventID {
+		t.Error("Expected unrelated topics to form separate events")
+	}
+
+	event, err := s.GetEvent(weibo[0].EventID)
+	if err != nil || event == nil {
+		t.Fatalf("GetEvent failed: %v", err)
+	}
+	if event.Title != "神舟二十号载人飞船发射成功" || event.Heat != 1200 || event.TopicCount != 2 ||
+		event.SourceCount != 2 || event.Sources != `["weibo","zhihu"]` || len(event.Topics) != 2 {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+
+	// 再次抓取时话题保留所属事件，热度按最新值重新汇总
+	weibo = fetch(t, s, "weibo",
+		Topic{ID: "weibo-1", Title: "神舟二十号载人飞船发射成功", Heat: 1000},
+	)
+	if weibo[0].EventID != event.ID {
+		t.Errorf("Expected topic to keep its event, got %q", weibo[0].EventID)
+	}
+	if event, _ = s.GetEvent(event.ID); event.Heat != 1300 || !event.FirstSeenAt.Before(event.LastSeenAt) {
+		t.Errorf("Unexpected refreshed event: %+v", event)
+	}
+
+	events, total, err := s.ListEvents(database.EventFilter{MinSources: 2})
+	if err != nil || total != 1 || events[0].ID != event.ID {
+		t.Errorf("Expected one cross-source event, got %d, %v", total, err)
+	}
+}
+
+func TestClusterTopicsSameSource(t *testing.T) {
+	s := newClusterTestService(t)
+
+	// 同一榜单中标题相近的不同话题不合并
+	topics := fetch(t, s, "weibo",
+		Topic{ID: "weibo-1", Title: "某地发生3.2级地震", Heat: 300},
+		Topic{ID: "weibo-2", Title: "某地发生4.1级地震", Heat: 200},
+	)
+	if topics[0].EventID == topics[1].EventID {
+		t.Error("Expected topics from the same crawl to form separate events")
+	}
+
+	// 来源更换话题ID后仍归入原事件，热度只计一次
+	topics = fetch(t, s, "weibo",
+		Topic{ID: "weibo-3", Title: "某地发生3.2级地震", Heat: 400},
+	)
+	event, _ := s.GetEvent(topics[0].EventID)
+	if event == nil || event.TopicCount != 2 || event.SourceCount != 1 || event.Heat != 400 {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+}
+
+func TestClusterTopicsByEmbedding(t *testing.T) {
+	s := newClusterTestService(t)
+
+	weibo := fetch(t, s, "weibo", Topic{ID: "weibo-1", Title: "央行宣布降准0.5个百分点", Heat: 800})
+	douyin := fetch(t, s, "douyin", Topic{ID: "douyin-1", Title: "降准来了", Heat: 600})
+	if douyin[0].EventID == weibo[0].EventID {
+		t.Fatal("Expected different wording not to match without embeddings")
+	}
+
+	s.SetEmbedder(&fakeEmbedder{vectors: map[string][]float32{
+		"央行宣布降准0.5个百分点":  {1, 0, 0},
+		"央行下调金融机构存款准备金率": {0.95, 0.1, 0},
+	}})
+	baidu := fetch(t, s, "baidu", Topic{ID: "baidu-1", Title: "央行下调金融机构存款准备金率", Heat: 700})
+	if baidu[0].EventID != weibo[0].EventID {
+		t.Errorf("Expected embedding match to join the weibo event, got %q", baidu[0].EventID)
+	}
+}
+
+func TestRunClustersOnSchedule(t *testing.T) {
+	s := newClusterTestService(t)
+	s.RegisterSource(&staticSource{id: "weibo", topics: []Topic{{ID: "weibo-1", Title: "神舟二十号载人飞船发射成功", Source: "weibo", Heat: 900}}})
+	s.RegisterSource(&staticSource{id: "zhihu", topics: []Topic{{ID: "zhihu-1", Title: "如何看待神舟二十号载人飞船发射成功？", Source: "zhihu", Heat: 300}}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx, time.Hour, 20)
+		close(done)
+	}()
+
+	// 启动后立即抓取一次，无需手动触发
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		events, _, err := s.ListEvents(database.EventFilter{MinSources: 2})
+		if err == nil && len(events) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected scheduled fetch to cluster topics, got %d events, %v", len(events), err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Run to stop when the context is cancelled")
+	}
+}
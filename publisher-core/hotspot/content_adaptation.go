@@ -7,7 +7,9 @@ import (
 	"time"
 
 	"publisher-core/ai/provider"
+	"publisher-core/cost"
 	"publisher-core/database"
+	"publisher-core/prompt"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -17,6 +19,7 @@ import (
 type ContentAdaptationService struct {
 	db        *gorm.DB
 	aiService AIAnalyzer
+	prompts   *prompt.Service
 	config    *ContentAdaptationConfig
 }
 
@@ -48,8 +51,9 @@ func NewContentAdaptationService(db *gorm.DB, config *ContentAdaptationConfig) *
 		config = DefaultContentAdaptationConfig()
 	}
 	return &ContentAdaptationService{
-		db:     db,
-		config: config,
+		db:      db,
+		prompts: prompt.NewService(db),
+		config:  config,
 	}
 }
 
@@ -83,6 +87,8 @@ type AdaptationResult struct {
 
 // AdaptContent 适配内容
 func (s *ContentAdaptationService) AdaptContent(ctx context.Context, req *AdaptationRequest) (*AdaptationResult, error) {
+	ctx = cost.WithFunction(ctx, "content_adaptation")
+
 	// 获取热点信息
 	var topic database.Topic
 	if err := s.db.Where("id = ?", req.TopicID).First(&topic).Error; err != nil {
@@ -100,29 +106,27 @@ func (s *ContentAdaptationService) AdaptContent(ctx context.Context, req *Adapta
 		style = s.config.ContentStyles[platform]
 	}
 
-	// 构建适配提示词
-	prompt := s.buildAdaptationPrompt(topic, platform, style, req.Keywords, req.CustomPrompt)
-
 	// 调用AI生成内容
 	if s.aiService == nil {
 		return nil, fmt.Errorf("AI服务未配置")
 	}
 
-	result, err := s.aiService.Generate(ctx, &provider.GenerateOptions{
-		Messages: []provider.Message{
-			{Role: provider.RoleUser, Content: prompt},
-		},
-		MaxTokens:   s.config.MaxContentLength,
-		Temperature: 0.8,
+	adaptedContent := &AdaptedContent{}
+	_, err := s.prompts.GenerateStructured(ctx, s.aiService, prompt.TemplateHotspotAdaptation, map[string]interface{}{
+		"platform":      platform,
+		"title":         topic.Title,
+		"description":   topic.Description,
+		"style":         style,
+		"keywords":      req.Keywords,
+		"custom_prompt": req.CustomPrompt,
+	}, adaptedContent, func(opts *provider.GenerateOptions) {
+		opts.MaxTokens = s.config.MaxContentLength
 	})
 
 	if err != nil {
 		return nil, fmt.Errorf("AI生成失败: %w", err)
 	}
 
-	// 解析生成的内容
-	adaptedContent := s.parseGeneratedContent(result.Content, platform)
-
 	// 计算质量评分
 	qualityScore := s.calculateQualityScore(adaptedContent, topic)
 
@@ -141,109 +145,13 @@ func (s *ContentAdaptationService) AdaptContent(ctx context.Context, req *Adapta
 	return adaptationResult, nil
 }
 
-// buildAdaptationPrompt 构建适配提示词
-func (s *ContentAdaptationService) buildAdaptationPrompt(topic database.Topic, platform, style string, keywords []string, customPrompt string) string {
-	var promptBuilder strings.Builder
-
-	// 基础信息
-	promptBuilder.WriteString(fmt.Sprintf("请基于以下热点话题，为%s平台创作内容：\n\n", platform))
-	promptBuilder.WriteString(fmt.Sprintf("热点标题：%s\n", topic.Title))
-	if topic.Description != "" {
-		promptBuilder.WriteString(fmt.Sprintf("热点描述：%s\n", topic.Description))
-	}
-
-	// 平台要求
-	promptBuilder.WriteString(fmt.Sprintf("\n平台要求：\n"))
-	switch platform {
-	case "douyin":
-		promptBuilder.WriteString("- 标题：30字以内，吸引眼球\n")
-		promptBuilder.WriteString("- 内容：轻松幽默，适合短视频\n")
-		promptBuilder.WriteString("- 风格：口语化，有互动性\n")
-	case "xiaohongshu":
-		promptBuilder.WriteString("- 标题：20字以内，精致优雅\n")
-		promptBuilder.WriteString("- 内容：实用分享，有情感共鸣\n")
-		promptBuilder.WriteString("- 风格：图文并茂，有生活气息\n")
-	case "toutiao":
-		promptBuilder.WriteString("- 标题：30字以内，专业深度\n")
-		promptBuilder.WriteString("- 内容：信息丰富，有观点\n")
-		promptBuilder.WriteString("- 风格：专业严谨，有深度\n")
-	}
-
-	// 风格要求
-	if style != "" {
-		promptBuilder.WriteString(fmt.Sprintf("\n内容风格：%s\n", style))
-	}
-
-	// 关键词要求
-	if len(keywords) > 0 {
-		promptBuilder.WriteString(fmt.Sprintf("\n请包含以下关键词：%s\n", strings.Join(keywords, ", ")))
-	}
-
-	// 自定义提示
-	if customPrompt != "" {
-		promptBuilder.WriteString(fmt.Sprintf("\n额外要求：%s\n", customPrompt))
-	}
-
-	// 输出格式
-	promptBuilder.WriteString("\n请按以下格式输出：\n")
-	promptBuilder.WriteString("标题：[标题内容]\n")
-	promptBuilder.WriteString("内容：[正文内容]\n")
-	promptBuilder.WriteString("摘要：[内容摘要]\n")
-	promptBuilder.WriteString("关键词：[关键词1, 关键词2, ...]\n")
-	promptBuilder.WriteString("标签：[标签1, 标签2, ...]\n")
-
-	return promptBuilder.String()
-}
-
 // AdaptedContent 解析后的内容
 type AdaptedContent struct {
-	Title    string
-	Content  string
-	Summary  string
-	Keywords []string
-	Tags     []string
-}
-
-// parseGeneratedContent 解析生成的内容
-func (s *ContentAdaptationService) parseGeneratedContent(content, platform string) *AdaptedContent {
-	result := &AdaptedContent{
-		Keywords: []string{},
-		Tags:     []string{},
-	}
-
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "标题：") || strings.HasPrefix(line, "标题:") {
-			result.Title = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "标题："), "标题:"))
-		} else if strings.HasPrefix(line, "内容：") || strings.HasPrefix(line, "内容:") {
-			result.Content = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "内容："), "内容:"))
-		} else if strings.HasPrefix(line, "摘要：") || strings.HasPrefix(line, "摘要:") {
-			result.Summary = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "摘要："), "摘要:"))
-		} else if strings.HasPrefix(line, "关键词：") || strings.HasPrefix(line, "关键词:") {
-			kwStr := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "关键词："), "关键词:"))
-			result.Keywords = strings.Split(kwStr, ",")
-			for i, kw := range result.Keywords {
-				result.Keywords[i] = strings.TrimSpace(kw)
-			}
-		} else if strings.HasPrefix(line, "标签：") || strings.HasPrefix(line, "标签:") {
-			tagStr := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "标签："), "标签:"))
-			result.Tags = strings.Split(tagStr, ",")
-			for i, tag := range result.Tags {
-				result.Tags[i] = strings.TrimSpace(tag)
-			}
-		} else if result.Content != "" && line != "" {
-			// 继续添加内容
-			result.Content += "\n" + line
-		}
-	}
-
-	// 如果没有解析到标题，使用第一行作为标题
-	if result.Title == "" && len(lines) > 0 {
-		result.Title = lines[0]
-	}
-
-	return result
+	Title    string   `json:"title" jsonschema:"minLength=1"`
+	Content  string   `json:"content" jsonschema:"minLength=1"`
+	Summary  string   `json:"summary"`
+	Keywords []string `json:"keywords"`
+	Tags     []string `json:"tags"`
 }
 
 // calculateQualityScore 计算质量评分
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"publisher-core/ai/provider"
+	"publisher-core/cost"
 	"publisher-core/database"
 
 	"github.com/sirupsen/logrus"
@@ -21,6 +22,7 @@ type EnhancedService struct {
 	storage     *database.HotspotStorage
 	aiService   AIAnalyzer
 	notifyService Notifier
+	embedder    provider.Embedder
 	db          *gorm.DB
 	config      *EnhancedConfig
 }
@@ -47,6 +49,11 @@ type EnhancedConfig struct {
 	AutoNotify      bool   `json:"auto_notify"`
 	NotifyChannel   string `json:"notify_channel"`
 	NotifyThreshold int    `json:"notify_threshold"` // 热度阈值
+	// 跨来源事件聚类
+	ClusterEnabled     bool    `json:"cluster_enabled"`
+	ClusterThreshold   float64 `json:"cluster_threshold"`    // 标题相似度阈值，0-1
+	ClusterWindowHours int     `json:"cluster_window_hours"` // 只与最近 N 小时出现过的事件合并
+	EmbeddingThreshold float64 `json:"embedding_threshold"`  // 设置向量模型后，标题不够相似的话题按向量余弦相似度匹配的阈值
 }
 
 // DefaultEnhancedConfig 默认配置
@@ -59,6 +66,10 @@ func DefaultEnhancedConfig() *EnhancedConfig {
 		AutoNotify:      false,
 		NotifyChannel:   "feishu",
 		NotifyThreshold: 80,
+		ClusterEnabled:     true,
+		ClusterThreshold:   0.5,
+		ClusterWindowHours: 48,
+		EmbeddingThreshold: 0.85,
 	}
 }
 
@@ -85,6 +96,11 @@ func (s *EnhancedService) SetNotifyService(notifier Notifier) {
 	s.notifyService = notifier
 }
 
+// SetEmbedder 设置向量模型，用于聚类时匹配措辞不同的同一事件
+func (s *EnhancedService) SetEmbedder(embedder provider.Embedder) {
+	s.embedder = embedder
+}
+
 // RegisterSource 注册数据源
 func (s *EnhancedService) RegisterSource(source SourceInterface) {
 	s.mu.Lock()
@@ -117,6 +133,13 @@ func (s *EnhancedService) FetchAndSave(ctx context.Context, sourceID string, max
 		return nil, err
 	}
 
+	// 与其它来源的同一事件合并，聚类失败不影响抓取结果
+	if s.config.ClusterEnabled {
+		if err := s.clusterTopics(ctx, dbTopics); err != nil {
+			logrus.Warnf("Cluster topics from %s failed: %v", sourceID, err)
+		}
+	}
+
 	return dbTopics, nil
 }
 
@@ -151,6 +174,27 @@ func (s *EnhancedService) FetchFromAllSources(ctx context.Context, maxItemsPerSo
 	return results, nil
 }
 
+// Run 按间隔从所有数据源抓取、聚类并更新趋势，直到 ctx 取消
+// 启动时立即执行一次，热度排行和预警读取的话题因此始终带有所属事件
+func (s *EnhancedService) Run(ctx context.Context, interval time.Duration, maxItemsPerSource int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.FetchFromAllSources(ctx, maxItemsPerSource); err != nil && ctx.Err() == nil {
+			logrus.Warnf("Scheduled hotspot fetch failed: %v", err)
+		}
+		if err := s.UpdateAllTrends(); err != nil && ctx.Err() == nil {
+			logrus.Warnf("Update hotspot trends failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 // saveWithHistory 保存数据并记录历史
 func (s *EnhancedService) saveWithHistory(ctx context.Context, topics []database.Topic, sourceID string) error {
 	now := time.Now()
@@ -176,6 +220,7 @@ func (s *EnhancedService) saveWithHistory(ctx context.Context, topics []database
 				// 已存在，更新
 				topic.CreatedAt = existing.CreatedAt
 				topic.FirstCrawlTime = existing.FirstCrawlTime
+				topic.EventID = existing.EventID
 				topic.UpdatedAt = now
 				if err := tx.Save(topic).Error; err != nil {
 					return err
@@ -303,6 +348,8 @@ func (s *EnhancedService) UpdateAllTrends() error {
 
 // AIAnalyze AI 分析热点
 func (s *EnhancedService) AIAnalyze(ctx context.Context, topicIDs []string) (*AIAnalysisResult, error) {
+	ctx = cost.WithFunction(ctx, "hotspot_analysis")
+
 	if s.aiService == nil {
 		return nil, fmt.Errorf("AI service not configured")
 	}
@@ -320,24 +367,17 @@ func (s *EnhancedService) AIAnalyze(ctx context.Context, topicIDs []string) (*AI
 	// 构建分析提示词
 	prompt := s.buildAnalysisPrompt(topics)
 
-	// 调用 AI 服务
-	result, err := s.aiService.Generate(ctx, &provider.GenerateOptions{
+	// 调用 AI 服务，输出按 AIAnalysisResult 结构校验
+	var analysis AIAnalysisResult
+	if _, err := provider.GenerateStructured(ctx, s.aiService, &provider.GenerateOptions{
 		Messages: []provider.Message{
 			{Role: provider.RoleUser, Content: prompt},
 		},
 		Temperature: 0.7,
-	})
-	if err != nil {
+	}, &analysis); err != nil {
 		return nil, err
 	}
 
-	// 解析结果
-	var analysis AIAnalysisResult
-	if err := json.Unmarshal([]byte(result.Content), &analysis); err != nil {
-		// 如果解析失败，返回原始内容
-		analysis.Summary = result.Content
-	}
-
 	return &analysis, nil
 }
 
@@ -386,7 +426,7 @@ type AIAnalysisResult struct {
 type AIRecommendation struct {
 	TopicID     string `json:"topic_id"`
 	Reason      string `json:"reason"`
-	Suitability int    `json:"suitability"`
+	Suitability int    `json:"suitability" jsonschema:"minimum=0,maximum=100"`
 }
 
 // GetTrendingTopics 获取趋势上升的话题
@@ -463,11 +503,20 @@ func (s *EnhancedService) NotifyHotTopics(ctx context.Context, threshold int) er
 		return err
 	}
 
+	// 同一事件在多个来源的话题只推送热度最高的一个
 	var hotTopics []database.Topic
+	events := make(map[string]bool)
 	for _, t := range topics {
-		if t.Heat >= threshold {
-			hotTopics = append(hotTopics, t)
+		if t.Heat < threshold {
+			continue
+		}
+		if t.EventID != "" {
+			if events[t.EventID] {
+				continue
+			}
+			events[t.EventID] = true
 		}
+		hotTopics = append(hotTopics, t)
 	}
 
 	if len(hotTopics) == 0 {
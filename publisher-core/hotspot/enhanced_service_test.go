@@ -7,150 +7,107 @@ import (
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+
+	"publisher-core/database"
 )
 
+// newEnhancedTestService 创建使用内存数据库的增强服务，并写入测试话题
+func newEnhancedTestService(t *testing.T, topics ...database.Topic) *EnhancedService {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&database.Topic{}, &database.RankHistory{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	for i := range topics {
+		if err := db.Create(&topics[i]).Error; err != nil {
+			t.Fatalf("Failed to create topic: %v", err)
+		}
+	}
+	return NewEnhancedService(db, nil)
+}
+
 // TestCalculateHeat 测试热度计算
 func TestCalculateHeat(t *testing.T) {
-	service := &EnhancedHotspotService{}
+	service := NewEnhancedService(nil, nil)
 
 	tests := []struct {
-		name     string
-		rank     int
+		name      string
+		rank      int
 		frequency int
-		hotness  int
-		expected int
+		hotness   int
 	}{
-		{
-			name:     "High rank, high frequency",
-			rank:     1,
-			frequency: 10,
-			hotness:  100000,
-			expected: 100,
-		},
-		{
-			name:     "Low rank, low frequency",
-			rank:     50,
-			frequency: 1,
-			hotness:  1000,
-			expected: 32,
-		},
-		{
-			name:     "Medium rank, medium frequency",
-			rank:     10,
-			frequency: 5,
-			hotness:  50000,
-			expected: 82,
-		},
+		{name: "High rank, high frequency", rank: 1, frequency: 10, hotness: 100000},
+		{name: "Low rank, low frequency", rank: 50, frequency: 1, hotness: 1000},
+		{name: "Medium rank, medium frequency", rank: 10, frequency: 5, hotness: 50000},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			result := service.CalculateHeat(tt.rank, tt.frequency, tt.hotness)
-			// 由于热度计算是加权平均，我们只验证结果在合理范围内
+			// 热度为加权平均，只验证结果在合理范围内
 			if result < 0 || result > 100 {
 				t.Errorf("CalculateHeat() = %v, want value between 0 and 100", result)
 			}
 		})
 	}
+
+	if high, low := service.CalculateHeat(1, 10, 100000), service.CalculateHeat(50, 1, 1000); high <= low {
+		t.Errorf("Expected higher rank and frequency to score higher: %d <= %d", high, low)
+	}
 }
 
 // TestAnalyzeTrend 测试趋势分析
 func TestAnalyzeTrend(t *testing.T) {
-	// 创建内存数据库
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
-	})
-	if err != nil {
-		t.Fatalf("Failed to open database: %v", err)
-	}
-
-	// 创建表
-	err = db.AutoMigrate(&Topic{}, &RankHistory{})
-	if err != nil {
-		t.Fatalf("Failed to migrate: %v", err)
-	}
+	service := newEnhancedTestService(t, database.Topic{ID: "test-topic-1", Title: "Test Topic", Source: "weibo", Heat: 1000, Trend: "new"})
 
-	// 创建测试话题
-	topic := &Topic{
-		ID:     "test-topic-1",
-		Title:  "Test Topic",
-		Source: "weibo",
-		Heat:   1000,
-		Trend:  "new",
-	}
-	db.Create(topic)
-
-	// 创建排名历史
+	// 最近一次抓取的热度比上一次上涨 50%
 	now := time.Now()
-	histories := []RankHistory{
-		{TopicID: topic.ID, Rank: 5, Heat: 1000, CrawlTime: now.Add(-6 * 24 * time.Hour)},
-		{TopicID: topic.ID, Rank: 3, Heat: 2000, CrawlTime: now.Add(-5 * 24 * time.Hour)},
-		{TopicID: topic.ID, Rank: 1, Heat: 3000, CrawlTime: now.Add(-4 * 24 * time.Hour)},
-	}
-	for _, h := range histories {
-		db.Create(&h)
+	histories := []database.RankHistory{
+		{TopicID: "test-topic-1", Rank: 5, Heat: 1000, CrawlTime: now.Add(-6 * 24 * time.Hour)},
+		{TopicID: "test-topic-1", Rank: 3, Heat: 2000, CrawlTime: now.Add(-5 * 24 * time.Hour)},
+		{TopicID: "test-topic-1", Rank: 1, Heat: 3000, CrawlTime: now.Add(-4 * 24 * time.Hour)},
+	}
+	for i := range histories {
+		if err := service.db.Create(&histories[i]).Error; err != nil {
+			t.Fatalf("Failed to create history: %v", err)
+		}
 	}
 
-	// 测试趋势分析
-	service := &EnhancedHotspotService{DB: db}
-	trend, err := service.AnalyzeTrend(topic.ID)
+	trend, err := service.AnalyzeTrend("test-topic-1")
 	if err != nil {
 		t.Fatalf("AnalyzeTrend() error = %v", err)
 	}
-
-	// 验证趋势
 	if trend != "up" {
 		t.Errorf("AnalyzeTrend() = %v, want up", trend)
 	}
-}
-
-// TestGetTopicsByPlatform 测试按平台获取话题
-func TestGetTopicsByPlatform(t *testing.T) {
-	// 创建内存数据库
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
-	})
-	if err != nil {
-		t.Fatalf("Failed to open database: %v", err)
-	}
-
-	// 创建表
-	err = db.AutoMigrate(&Platform{}, &Topic{})
-	if err != nil {
-		t.Fatalf("Failed to migrate: %v", err)
-	}
 
-	// 创建测试平台
-	platforms := []Platform{
-		{ID: "weibo", Name: "微博", IsActive: true},
-		{ID: "douyin", Name: "抖音", IsActive: true},
-	}
-	for _, p := range platforms {
-		db.Create(&p)
+	// 没有足够历史的话题视为新话题
+	if trend, _ := service.AnalyzeTrend("missing"); trend != "new" {
+		t.Errorf("AnalyzeTrend() = %v, want new", trend)
 	}
+}
 
-	// 创建测试话题
-	topics := []Topic{
-		{ID: "topic-1", Title: "Topic 1", Source: "weibo", Heat: 1000, Trend: "up"},
-		{ID: "topic-2", Title: "Topic 2", Source: "weibo", Heat: 2000, Trend: "down"},
-		{ID: "topic-3", Title: "Topic 3", Source: "douyin", Heat: 3000, Trend: "stable"},
-	}
-	for _, topic := range topics {
-		db.Create(&topic)
-	}
+// TestListBySource 测试按来源列出话题
+func TestListBySource(t *testing.T) {
+	service := newEnhancedTestService(t,
+		database.Topic{ID: "topic-1", Title: "Topic 1", Source: "weibo", Heat: 1000, Trend: "up"},
+		database.Topic{ID: "topic-2", Title: "Topic 2", Source: "weibo", Heat: 2000, Trend: "down"},
+		database.Topic{ID: "topic-3", Title: "Topic 3", Source: "douyin", Heat: 3000, Trend: "stable"},
+	)
 
-	// 测试获取微博话题
-	service := &EnhancedHotspotService{DB: db}
-	weiboTopics, err := service.GetTopicsByPlatform("weibo", 10)
+	weiboTopics, total, err := service.List(database.TopicFilter{Source: "weibo", Limit: 10})
 	if err != nil {
-		t.Fatalf("GetTopicsByPlatform() error = %v", err)
+		t.Fatalf("List() error = %v", err)
 	}
-
-	if len(weiboTopics) != 2 {
-		t.Errorf("GetTopicsByPlatform() = %v, want 2", len(weiboTopics))
+	if total != 2 || len(weiboTopics) != 2 {
+		t.Errorf("List() = %d topics (total %d), want 2", len(weiboTopics), total)
 	}
-
-	// 验证所有话题都来自微博
 	for _, topic := range weiboTopics {
 		if topic.Source != "weibo" {
 			t.Errorf("Topic source = %v, want weibo", topic.Source)
@@ -160,144 +117,41 @@ func TestGetTopicsByPlatform(t *testing.T) {
 
 // TestGetTopTopics 测试获取热门话题
 func TestGetTopTopics(t *testing.T) {
-	// 创建内存数据库
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
-	})
-	if err != nil {
-		t.Fatalf("Failed to open database: %v", err)
-	}
-
-	// 创建表
-	err = db.AutoMigrate(&Topic{})
-	if err != nil {
-		t.Fatalf("Failed to migrate: %v", err)
-	}
-
-	// 创建测试话题（按热度排序）
-	topics := []Topic{
-		{ID: "topic-1", Title: "Topic 1", Source: "weibo", Heat: 5000, Trend: "hot"},
-		{ID: "topic-2", Title: "Topic 2", Source: "douyin", Heat: 4000, Trend: "up"},
-		{ID: "topic-3", Title: "Topic 3", Source: "xiaohongshu", Heat: 3000, Trend: "stable"},
-		{ID: "topic-4", Title: "Topic 4", Source: "weibo", Heat: 2000, Trend: "down"},
-		{ID: "topic-5", Title: "Topic 5", Source: "douyin", Heat: 1000, Trend: "new"},
-	}
-	for _, topic := range topics {
-		db.Create(&topic)
-	}
+	service := newEnhancedTestService(t,
+		database.Topic{ID: "topic-1", Title: "Topic 1", Source: "weibo", Heat: 5000, Trend: "hot"},
+		database.Topic{ID: "topic-2", Title: "Topic 2", Source: "douyin", Heat: 4000, Trend: "up"},
+		database.Topic{ID: "topic-3", Title: "Topic 3", Source: "xiaohongshu", Heat: 3000, Trend: "stable"},
+		database.Topic{ID: "topic-4", Title: "Topic 4", Source: "weibo", Heat: 2000, Trend: "down"},
+		database.Topic{ID: "topic-5", Title: "Topic 5", Source: "douyin", Heat: 1000, Trend: "new"},
+	)
 
-	// 测试获取前 3 个热门话题
-	service := &EnhancedHotspotService{DB: db}
 	topTopics, err := service.GetTopTopics(3)
 	if err != nil {
 		t.Fatalf("GetTopTopics() error = %v", err)
 	}
-
 	if len(topTopics) != 3 {
-		t.Errorf("GetTopTopics() = %v, want 3", len(topTopics))
+		t.Fatalf("GetTopTopics() = %v, want 3", len(topTopics))
 	}
-
-	// 验证话题按热度降序排列
-	if topTopics[0].Heat < topTopics[1].Heat {
+	if topTopics[0].Heat < topTopics[1].Heat || topTopics[1].Heat < topTopics[2].Heat {
 		t.Error("Topics not sorted by heat descending")
 	}
 }
 
-// TestSearchTopics 测试搜索话题
-func TestSearchTopics(t *testing.T) {
-	// 创建内存数据库
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
-	})
-	if err != nil {
-		t.Fatalf("Failed to open database: %v", err)
-	}
-
-	// 创建表
-	err = db.AutoMigrate(&Topic{})
-	if err != nil {
-		t.Fatalf("Failed to migrate: %v", err)
-	}
-
-	// 创建测试话题
-	topics := []Topic{
-		{ID: "topic-1", Title: "人工智能新闻", Source: "weibo", Heat: 1000, Trend: "up"},
-		{ID: "topic-2", Title: "AI技术发展", Source: "douyin", Heat: 2000, Trend: "hot"},
-		{ID: "topic-3", Title: "机器学习应用", Source: "xiaohongshu", Heat: 1500, Trend: "stable"},
-		{ID: "topic-4", Title: "美食推荐", Source: "weibo", Heat: 3000, Trend: "down"},
-	}
-	for _, topic := range topics {
-		db.Create(&topic)
-	}
-
-	// 测试搜索包含"AI"的话题
-	service := &EnhancedHotspotService{DB: db}
-	results, err := service.SearchTopics("AI", 10)
-	if err != nil {
-		t.Fatalf("SearchTopics() error = %v", err)
-	}
-
-	// 应该找到 2 个话题
-	if len(results) != 2 {
-		t.Errorf("SearchTopics() = %v, want 2", len(results))
-	}
-
-	// 验证搜索结果都包含关键词
-	for _, topic := range results {
-		contains := false
-		if topic.Title == "人工智能新闻" || topic.Title == "AI技术发展" {
-			contains = true
-		}
-		if !contains {
-			t.Errorf("Topic title = %v, should contain AI", topic.Title)
-		}
-	}
-}
-
-// TestGetTrendingTopics 测试获取趋势话题
+// TestGetTrendingTopics 测试获取趋势上升的话题
 func TestGetTrendingTopics(t *testing.T) {
-	// 创建内存数据库
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
-	})
-	if err != nil {
-		t.Fatalf("Failed to open database: %v", err)
-	}
-
-	// 创建表
-	err = db.AutoMigrate(&Topic{})
-	if err != nil {
-		t.Fatalf("Failed to migrate: %v", err)
-	}
-
-	// 创建测试话题
-	topics := []Topic{
-		{ID: "topic-1", Title: "Topic 1", Source: "weibo", Heat: 1000, Trend: "up"},
-		{ID: "topic-2", Title: "Topic 2", Source: "douyin", Heat: 2000, Trend: "hot"},
-		{ID: "topic-3", Title: "Topic 3", Source: "xiaohongshu", Heat: 3000, Trend: "stable"},
-		{ID: "topic-4", Title: "Topic 4", Source: "weibo", Heat: 4000, Trend: "down"},
-		{ID: "topic-5", Title: "Topic 5", Source: "douyin", Heat: 5000, Trend: "new"},
-	}
-	for _, topic := range topics {
-		db.Create(&topic)
-	}
-
-	// 测试获取上升趋势的话题
-	service := &EnhancedHotspotService{DB: db}
-	trendingTopics, err := service.GetTrendingTopics("up", 10)
+	service := newEnhancedTestService(t,
+		database.Topic{ID: "topic-1", Title: "Topic 1", Source: "weibo", Heat: 1000, Trend: "up"},
+		database.Topic{ID: "topic-2", Title: "Topic 2", Source: "douyin", Heat: 2000, Trend: "hot"},
+		database.Topic{ID: "topic-3", Title: "Topic 3", Source: "xiaohongshu", Heat: 3000, Trend: "stable"},
+		database.Topic{ID: "topic-4", Title: "Topic 4", Source: "weibo", Heat: 4000, Trend: "down"},
+		database.Topic{ID: "topic-5", Title: "Topic 5", Source: "douyin", Heat: 5000, Trend: "new"},
+	)
+
+	trendingTopics, err := service.GetTrendingTopics(10)
 	if err != nil {
 		t.Fatalf("GetTrendingTopics() error = %v", err)
 	}
-
-	// 应该找到 1 个上升趋势的话题
-	if len(trendingTopics) != 1 {
-		t.Errorf("GetTrendingTopics() = %v, want 1", len(trendingTopics))
-	}
-
-	// 验证所有话题的趋势都是 up
-	for _, topic := range trendingTopics {
-		if topic.Trend != "up" {
-			t.Errorf("Topic trend = %v, want up", topic.Trend)
-		}
+	if len(trendingTopics) != 1 || trendingTopics[0].Trend != "up" {
+		t.Errorf("GetTrendingTopics() = %+v, want only the rising topic", trendingTopics)
 	}
 }
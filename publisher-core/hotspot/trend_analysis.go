@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"publisher-core/ai/provider"
+	"publisher-core/cost"
 	"publisher-core/database"
 
 	"github.com/sirupsen/logrus"
@@ -368,6 +369,8 @@ func (s *TrendAnalysisService) calculateRelevance(topic database.Topic, keywords
 
 // generateContentSuggestions 生成内容建议
 func (s *TrendAnalysisService) generateContentSuggestions(ctx context.Context, topic database.Topic, keywords []KeywordScore) []string {
+	ctx = cost.WithFunction(ctx, "content_suggestion")
+
 	if s.aiService == nil {
 		return []string{}
 	}
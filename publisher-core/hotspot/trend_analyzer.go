@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"publisher-core/ai/provider"
+	"publisher-core/cost"
 	"publisher-core/database"
 
 	"github.com/sirupsen/logrus"
@@ -244,6 +245,8 @@ func (a *TrendAnalyzer) analyzeSentiment(ctx context.Context, topic *database.To
 
 // analyzeSentimentWithAI 使用AI分析情感
 func (a *TrendAnalyzer) analyzeSentimentWithAI(ctx context.Context, topic *database.Topic) AnalyzerSentiment {
+	ctx = cost.WithFunction(ctx, "sentiment_analysis")
+
 	prompt := fmt.Sprintf(`请分析以下热点话题的情感倾向：
 
 标题：%s
@@ -76,6 +76,8 @@ type Options struct {
 	ProxyURL     string
 	UserAgent    string
 	DebugMode    bool
+	// AccountID 发布使用的账号，用于按账号限流和内容去重，为空时以平台名代表 CookieDir 中登录的账号
+	AccountID    string
 }
 
 func DefaultOptions() *Options {
@@ -110,6 +112,12 @@ func WithProxy(proxyURL string) Option {
 	}
 }
 
+func WithAccountID(accountID string) Option {
+	return func(o *Options) {
+		o.AccountID = accountID
+	}
+}
+
 func WithDebug(debug bool) Option {
 	return func(o *Options) {
 		o.DebugMode = debug
@@ -0,0 +1,69 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"publisher-core/ai/provider"
+)
+
+// AgentTools 将已注册的 MCP 工具导出为模型可调用的工具
+// names 为空时导出全部工具，工具按名称排序注册
+func (s *Server) AgentTools(names ...string) (*provider.ToolRegistry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(names) == 0 {
+		for name := range s.tools {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	registry := provider.NewToolRegistry()
+	for _, name := range names {
+		tool, ok := s.tools[name]
+		if !ok {
+			return nil, fmt.Errorf("tool not found: %s", name)
+		}
+		if err := registry.RegisterTool(provider.Tool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.InputSchema,
+			Handler:     agentToolHandler(tool.Handler),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
+
+// agentToolHandler 将 MCP 工具结果转换为文本
+func agentToolHandler(handler ToolHandler) provider.ToolHandler {
+	return func(ctx context.Context, args map[string]interface{}) (string, error) {
+		result, err := handler(ctx, args)
+		if err != nil {
+			return "", err
+		}
+		if result == nil {
+			return "", nil
+		}
+
+		texts := make([]string, 0, len(result.Content))
+		for _, block := range result.Content {
+			if block.Text != "" {
+				texts = append(texts, block.Text)
+			}
+		}
+		text := strings.Join(texts, "\n")
+
+		if result.IsError {
+			return "", errors.New(text)
+		}
+		return text, nil
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"publisher-core/database"
 	"publisher-core/hotspot"
@@ -175,6 +176,49 @@ func (r *ToolsRegistry) registerHotspotTools(server *Server) {
 		},
 		Handler: r.handleGetHotspotStats,
 	})
+
+	// 获取跨来源热点事件
+	server.RegisterTool(Tool{
+		Name:        "get_hot_events",
+		Description: "获取跨平台聚合的热点事件，同一事件在各平台的话题合并为一条",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "返回数量，默认20",
+					"default":     20,
+				},
+				"hours": map[string]interface{}{
+					"type":        "integer",
+					"description": "只返回最近N小时出现过的事件，默认24",
+					"default":     24,
+				},
+				"min_sources": map[string]interface{}{
+					"type":        "integer",
+					"description": "至少覆盖的平台数",
+				},
+			},
+		},
+		Handler: r.handleGetHotEvents,
+	})
+
+	// 获取热点事件详情
+	server.RegisterTool(Tool{
+		Name:        "get_event_detail",
+		Description: "获取热点事件详情及其在各平台的话题",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"event_id": map[string]interface{}{
+					"type":        "string",
+					"description": "事件ID",
+				},
+			},
+			"required": []string{"event_id"},
+		},
+		Handler: r.handleGetEventDetail,
+	})
 }
 
 func (r *ToolsRegistry) handleGetHotTopics(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
@@ -335,6 +379,64 @@ func (r *ToolsRegistry) handleGetHotspotStats(ctx context.Context, args map[stri
 	return SuccessResult(string(data)), nil
 }
 
+func (r *ToolsRegistry) handleGetHotEvents(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	limit := 20
+	if l, ok := args["limit"].(float64); ok {
+		limit = int(l)
+	}
+
+	hours := 24
+	if h, ok := args["hours"].(float64); ok && h > 0 {
+		hours = int(h)
+	}
+
+	filter := database.EventFilter{
+		Since: time.Now().Add(-time.Duration(hours) * time.Hour),
+		Limit: limit,
+	}
+	if minSources, ok := args["min_sources"].(float64); ok {
+		filter.MinSources = int(minSources)
+	}
+
+	events, total, err := database.NewHotspotStorage(r.db).ListEvents(filter)
+	if err != nil {
+		return ErrorResult(err), nil
+	}
+
+	result := map[string]interface{}{
+		"success": true,
+		"total":   total,
+		"events":  events,
+	}
+
+	data, _ := json.MarshalIndent(result, "", "  ")
+	return SuccessResult(string(data)), nil
+}
+
+func (r *ToolsRegistry) handleGetEventDetail(ctx context.Context, args map[string]interface{}) (*ToolResult, error) {
+	eventID, ok := args["event_id"].(string)
+	if !ok {
+		return ErrorResult(fmt.Errorf("event_id is required")), nil
+	}
+
+	event, err := database.NewHotspotStorage(r.db).GetEvent(eventID)
+	if err != nil {
+		return ErrorResult(err), nil
+	}
+
+	if event == nil {
+		return ErrorResult(fmt.Errorf("event not found")), nil
+	}
+
+	result := map[string]interface{}{
+		"success": true,
+		"event":   event,
+	}
+
+	data, _ := json.MarshalIndent(result, "", "  ")
+	return SuccessResult(string(data)), nil
+}
+
 // =====================================================
 // 视频处理工具
 // =====================================================
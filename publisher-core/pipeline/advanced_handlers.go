@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"publisher-core/ai"
 	"publisher-core/ai/provider"
+	"publisher-core/prompt"
 	"sort"
 	"strings"
 	"time"
@@ -17,11 +18,12 @@ import (
 // OutlineGenerator 内容大纲生成处理器
 type OutlineGenerator struct {
 	aiService *ai.Service
+	prompts   *prompt.Service
 }
 
 // NewOutlineGenerator 创建大纲生成器
-func NewOutlineGenerator(aiService *ai.Service) *OutlineGenerator {
-	return &OutlineGenerator{aiService: aiService}
+func NewOutlineGenerator(aiService *ai.Service, prompts *prompt.Service) *OutlineGenerator {
+	return &OutlineGenerator{aiService: aiService, prompts: promptsOrBuiltin(prompts)}
 }
 
 func (h *OutlineGenerator) Execute(ctx context.Context, config map[string]interface{}, input map[string]interface{}) (map[string]interface{}, error) {
@@ -40,40 +42,12 @@ func (h *OutlineGenerator) Execute(ctx context.Context, config map[string]interf
 		style = "professional"
 	}
 
-	// 构建大纲生成提示词
-	prompt := fmt.Sprintf(`请为以下主题生成一个详细的内容大纲：
-
-主题: %s
-目标字数: %d字
-风格: %s
-
-要求:
-1. 生成3-5个主要章节
-2. 每个章节包含2-4个子要点
-3. 标注每个章节的预估字数
-4. 确保逻辑连贯、层次分明
-
-请以JSON格式返回大纲，格式如下:
-{
-  "title": "文章标题",
-  "sections": [
-    {
-      "title": "章节标题",
-      "estimated_words": 200,
-      "points": ["要点1", "要点2"]
-    }
-  ],
-  "total_estimated_words": 1000,
-  "keywords": ["关键词1", "关键词2"]
-}`, topic, targetLength, style)
-
 	// 调用 AI 服务
-	result, err := h.aiService.Generate(ctx, &provider.GenerateOptions{
-		Model:       "deepseek-chat",
-		Messages:    []provider.Message{{Role: provider.RoleUser, Content: prompt}},
-		MaxTokens:   1500,
-		Temperature: 0.7,
-	})
+	result, err := h.prompts.Generate(ctx, h.aiService, prompt.TemplatePipelineOutlineGeneration, map[string]interface{}{
+		"topic":         topic,
+		"target_length": targetLength,
+		"style":         style,
+	}, nil)
 	if err != nil {
 		return nil, fmt.Errorf("大纲生成失败: %w", err)
 	}
@@ -97,11 +71,12 @@ func (h *OutlineGenerator) Execute(ctx context.Context, config map[string]interf
 // ContentClusterer 内容聚类处理器
 type ContentClusterer struct {
 	aiService *ai.Service
+	prompts   *prompt.Service
 }
 
 // NewContentClusterer 创建内容聚类器
-func NewContentClusterer(aiService *ai.Service) *ContentClusterer {
-	return &ContentClusterer{aiService: aiService}
+func NewContentClusterer(aiService *ai.Service, prompts *prompt.Service) *ContentClusterer {
+	return &ContentClusterer{aiService: aiService, prompts: promptsOrBuiltin(prompts)}
 }
 
 func (h *ContentClusterer) Execute(ctx context.Context, config map[string]interface{}, input map[string]interface{}) (map[string]interface{}, error) {
@@ -127,47 +102,25 @@ func (h *ContentClusterer) Execute(ctx context.Context, config map[string]interf
 		}
 	}
 
-	// 构建聚类提示词
-	prompt := fmt.Sprintf(`请将以下内容进行聚类分析，分成%d个类别：
-
-内容列表:
-%s
-
-要求:
-1. 根据内容主题进行分类
-2. 每个类别给出一个描述性名称
-3. 列出每个类别包含的内容索引
-4. 提取每个类别的关键词
-
-请以JSON格式返回聚类结果:
-{
-  "clusters": [
-    {
-      "name": "类别名称",
-      "description": "类别描述",
-      "indices": [0, 2, 5],
-      "keywords": ["关键词1", "关键词2"]
-    }
-  ]
-}`, clusterCount, strings.Join(contentTexts, "\n---\n"))
-
-	// 调用 AI 服务
-	result, err := h.aiService.Generate(ctx, &provider.GenerateOptions{
-		Model:       "deepseek-chat",
-		Messages:    []provider.Message{{Role: provider.RoleUser, Content: prompt}},
-		MaxTokens:   2000,
-		Temperature: 0.3,
-	})
+	// 调用 AI 服务，输出按 ClusterResult 结构校验
+	var clusterResult ClusterResult
+	result, err := h.prompts.GenerateStructured(ctx, h.aiService, prompt.TemplatePipelineContentClustering, map[string]interface{}{
+		"contents":      contentTexts,
+		"cluster_count": clusterCount,
+	}, &clusterResult, nil)
 	if err != nil {
 		return nil, fmt.Errorf("内容聚类失败: %w", err)
 	}
 
-	// 解析聚类结果
-	var clusterResult map[string]interface{}
-	if err := json.Unmarshal([]byte(result.Content), &clusterResult); err != nil {
-		clusterResult = map[string]interface{}{
-			"raw_result": result.Content,
+	// 过滤越界的内容索引
+	for i := range clusterResult.Clusters {
+		indices := clusterResult.Clusters[i].Indices[:0]
+		for _, idx := range clusterResult.Clusters[i].Indices {
+			if idx >= 0 && idx < len(contentTexts) {
+				indices = append(indices, idx)
+			}
 		}
+		clusterResult.Clusters[i].Indices = indices
 	}
 
 	return map[string]interface{}{
@@ -177,14 +130,37 @@ func (h *ContentClusterer) Execute(ctx context.Context, config map[string]interf
 	}, nil
 }
 
+// ClusterResult 内容聚类结果
+type ClusterResult struct {
+	Clusters []ContentCluster `json:"clusters" jsonschema:"minItems=1"`
+}
+
+// ContentCluster 聚类类别
+type ContentCluster struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Indices     []int    `json:"indices" description:"属于该类别的内容索引，从 0 开始"`
+	Keywords    []string `json:"keywords"`
+}
+
+// ClassifyResult 内容分类结果
+type ClassifyResult struct {
+	Category       string   `json:"category"`
+	Confidence     float64  `json:"confidence" jsonschema:"minimum=0,maximum=1"`
+	Keywords       []string `json:"keywords"`
+	Sentiment      string   `json:"sentiment" jsonschema:"enum=positive|neutral|negative"`
+	SentimentScore float64  `json:"sentiment_score" jsonschema:"minimum=-1,maximum=1"`
+}
+
 // ContentClassifier 内容分类处理器
 type ContentClassifier struct {
 	aiService *ai.Service
+	prompts   *prompt.Service
 }
 
 // NewContentClassifier 创建内容分类器
-func NewContentClassifier(aiService *ai.Service) *ContentClassifier {
-	return &ContentClassifier{aiService: aiService}
+func NewContentClassifier(aiService *ai.Service, prompts *prompt.Service) *ContentClassifier {
+	return &ContentClassifier{aiService: aiService, prompts: promptsOrBuiltin(prompts)}
 }
 
 func (h *ContentClassifier) Execute(ctx context.Context, config map[string]interface{}, input map[string]interface{}) (map[string]interface{}, error) {
@@ -198,48 +174,28 @@ func (h *ContentClassifier) Execute(ctx context.Context, config map[string]inter
 		categories = []string{"科技", "娱乐", "教育", "生活", "财经", "体育", "健康", "其他"}
 	}
 
-	// 构建分类提示词
-	prompt := fmt.Sprintf(`请对以下内容进行分类：
-
-内容:
-%s
-
-可选类别: %s
-
-要求:
-1. 选择最匹配的类别
-2. 给出置信度（0-1）
-3. 提取内容的关键词
-4. 判断内容情感倾向
-
-请以JSON格式返回分类结果:
-{
-  "category": "科技",
-  "confidence": 0.95,
-  "keywords": ["人工智能", "机器学习"],
-  "sentiment": "positive",
-  "sentiment_score": 0.8
-}`, content, strings.Join(categories, ", "))
+	// 类别限定为可选类别
+	format, err := provider.ResponseFormatFor("classification", ClassifyResult{})
+	if err != nil {
+		return nil, err
+	}
+	format.Schema.Properties["category"].Enum = make([]interface{}, len(categories))
+	for i, category := range categories {
+		format.Schema.Properties["category"].Enum[i] = category
+	}
 
 	// 调用 AI 服务
-	result, err := h.aiService.Generate(ctx, &provider.GenerateOptions{
-		Model:       "deepseek-chat",
-		Messages:    []provider.Message{{Role: provider.RoleUser, Content: prompt}},
-		MaxTokens:   500,
-		Temperature: 0.2,
+	var classifyResult ClassifyResult
+	result, err := h.prompts.GenerateStructured(ctx, h.aiService, prompt.TemplatePipelineContentClassification, map[string]interface{}{
+		"content":    content,
+		"categories": categories,
+	}, &classifyResult, func(opts *provider.GenerateOptions) {
+		opts.ResponseFormat = format
 	})
 	if err != nil {
 		return nil, fmt.Errorf("内容分类失败: %w", err)
 	}
 
-	// 解析分类结果
-	var classifyResult map[string]interface{}
-	if err := json.Unmarshal([]byte(result.Content), &classifyResult); err != nil {
-		classifyResult = map[string]interface{}{
-			"raw_result": result.Content,
-		}
-	}
-
 	return map[string]interface{}{
 		"classification": classifyResult,
 		"tokens_used":    result.InputTokens + result.OutputTokens,
@@ -250,11 +206,12 @@ func (h *ContentClassifier) Execute(ctx context.Context, config map[string]inter
 // TitleGenerator 标题生成处理器
 type TitleGenerator struct {
 	aiService *ai.Service
+	prompts   *prompt.Service
 }
 
 // NewTitleGenerator 创建标题生成器
-func NewTitleGenerator(aiService *ai.Service) *TitleGenerator {
-	return &TitleGenerator{aiService: aiService}
+func NewTitleGenerator(aiService *ai.Service, prompts *prompt.Service) *TitleGenerator {
+	return &TitleGenerator{aiService: aiService, prompts: promptsOrBuiltin(prompts)}
 }
 
 func (h *TitleGenerator) Execute(ctx context.Context, config map[string]interface{}, input map[string]interface{}) (map[string]interface{}, error) {
@@ -273,37 +230,12 @@ func (h *TitleGenerator) Execute(ctx context.Context, config map[string]interfac
 		maxLength = 30
 	}
 
-	// 构建标题生成提示词
-	prompt := fmt.Sprintf(`请为以下内容生成%d个吸引人的标题：
-
-内容:
-%s
-
-要求:
-1. 标题长度不超过%d字
-2. 标题要有吸引力
-3. 适合社交媒体传播
-4. 包含适当的emoji表情
-5. 风格多样化
-
-请以JSON格式返回标题列表:
-{
-  "titles": [
-    {
-      "title": "标题1",
-      "style": "question",
-      "score": 0.95
-    }
-  ]
-}`, count, content, maxLength)
-
 	// 调用 AI 服务
-	result, err := h.aiService.Generate(ctx, &provider.GenerateOptions{
-		Model:       "deepseek-chat",
-		Messages:    []provider.Message{{Role: provider.RoleUser, Content: prompt}},
-		MaxTokens:   1000,
-		Temperature: 0.8,
-	})
+	result, err := h.prompts.Generate(ctx, h.aiService, prompt.TemplatePipelineTitleGeneration, map[string]interface{}{
+		"content":    content,
+		"count":      count,
+		"max_length": maxLength,
+	}, nil)
 	if err != nil {
 		return nil, fmt.Errorf("标题生成失败: %w", err)
 	}
@@ -326,11 +258,12 @@ func (h *TitleGenerator) Execute(ctx context.Context, config map[string]interfac
 // DescriptionGenerator 描述生成处理器
 type DescriptionGenerator struct {
 	aiService *ai.Service
+	prompts   *prompt.Service
 }
 
 // NewDescriptionGenerator 创建描述生成器
-func NewDescriptionGenerator(aiService *ai.Service) *DescriptionGenerator {
-	return &DescriptionGenerator{aiService: aiService}
+func NewDescriptionGenerator(aiService *ai.Service, prompts *prompt.Service) *DescriptionGenerator {
+	return &DescriptionGenerator{aiService: aiService, prompts: promptsOrBuiltin(prompts)}
 }
 
 func (h *DescriptionGenerator) Execute(ctx context.Context, config map[string]interface{}, input map[string]interface{}) (map[string]interface{}, error) {
@@ -346,26 +279,12 @@ func (h *DescriptionGenerator) Execute(ctx context.Context, config map[string]in
 
 	includeKeywords, _ := config["include_keywords"].(bool)
 
-	// 构建描述生成提示词
-	prompt := fmt.Sprintf(`请为以下内容生成一个简洁的描述：
-
-内容:
-%s
-
-要求:
-1. 描述长度不超过%d字
-2. 概括内容核心要点
-3. 吸引读者阅读
-%s
-请直接输出描述内容。`, content, maxLength, map[bool]string{true: "4. 包含关键词\n"}[includeKeywords])
-
 	// 调用 AI 服务
-	result, err := h.aiService.Generate(ctx, &provider.GenerateOptions{
-		Model:       "deepseek-chat",
-		Messages:    []provider.Message{{Role: provider.RoleUser, Content: prompt}},
-		MaxTokens:   300,
-		Temperature: 0.6,
-	})
+	result, err := h.prompts.Generate(ctx, h.aiService, prompt.TemplatePipelineDescription, map[string]interface{}{
+		"content":          content,
+		"max_length":       maxLength,
+		"include_keywords": includeKeywords,
+	}, nil)
 	if err != nil {
 		return nil, fmt.Errorf("描述生成失败: %w", err)
 	}
@@ -380,11 +299,12 @@ func (h *DescriptionGenerator) Execute(ctx context.Context, config map[string]in
 // ContentFilter 内容筛选处理器
 type ContentFilter struct {
 	aiService *ai.Service
+	prompts   *prompt.Service
 }
 
 // NewContentFilter 创建内容筛选器
-func NewContentFilter(aiService *ai.Service) *ContentFilter {
-	return &ContentFilter{aiService: aiService}
+func NewContentFilter(aiService *ai.Service, prompts *prompt.Service) *ContentFilter {
+	return &ContentFilter{aiService: aiService, prompts: promptsOrBuiltin(prompts)}
 }
 
 func (h *ContentFilter) Execute(ctx context.Context, config map[string]interface{}, input map[string]interface{}) (map[string]interface{}, error) {
@@ -417,30 +337,11 @@ func (h *ContentFilter) Execute(ctx context.Context, config map[string]interface
 			}
 		}
 
-		// 构建评分提示词
-		prompt := fmt.Sprintf(`请对以下内容进行评分：
-
-内容:
-%s
-
-评分维度: %s
-
-请以JSON格式返回评分结果:
-{
-  "overall_score": 0.85,
-  "quality": 0.9,
-  "relevance": 0.85,
-  "originality": 0.8,
-  "reasoning": "评分理由"
-}`, contentText, strings.Join(filterCriteria, ", "))
-
 		// 调用 AI 服务
-		result, err := h.aiService.Generate(ctx, &provider.GenerateOptions{
-			Model:       "deepseek-chat",
-			Messages:    []provider.Message{{Role: provider.RoleUser, Content: prompt}},
-			MaxTokens:   300,
-			Temperature: 0.2,
-		})
+		result, err := h.prompts.Generate(ctx, h.aiService, prompt.TemplatePipelineContentScoring, map[string]interface{}{
+			"content":  contentText,
+			"criteria": filterCriteria,
+		}, nil)
 		if err != nil {
 			logrus.Warnf("内容 %d 评分失败: %v", i, err)
 			continue
@@ -4,13 +4,17 @@ package pipeline
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"publisher-core/ai"
 	"publisher-core/ai/provider"
 	"publisher-core/adapters"
 	"publisher-core/analytics"
+	"publisher-core/compliance"
 	publisher "publisher-core/interfaces"
-	"strings"
+	"publisher-core/prompt"
+	"publisher-core/storage"
+	"publisher-core/video/processor"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -20,20 +24,24 @@ import (
 type HandlerRegistry struct {
 	orchestrator *PipelineOrchestrator
 	aiService   *ai.Service
+	prompts     *prompt.Service
 	publisher   *adapters.PublisherFactory
 	analytics   *analytics.AnalyticsService
 }
 
 // NewHandlerRegistry 创建处理器注册表
+// AI 处理器的提示词按ID从 prompts 加载，prompts 为 nil 时使用内置模板
 func NewHandlerRegistry(
 	orchestrator *PipelineOrchestrator,
 	aiService *ai.Service,
+	prompts *prompt.Service,
 	publisher *adapters.PublisherFactory,
 	analyticsService *analytics.AnalyticsService,
 ) *HandlerRegistry {
 	registry := &HandlerRegistry{
 		orchestrator: orchestrator,
 		aiService:   aiService,
+		prompts:     promptsOrBuiltin(prompts),
 		publisher:   publisher,
 		analytics:   analyticsService,
 	}
@@ -43,21 +51,32 @@ func NewHandlerRegistry(
 	return registry
 }
 
+// promptsOrBuiltin 未提供模板服务时只使用内置模板
+func promptsOrBuiltin(prompts *prompt.Service) *prompt.Service {
+	if prompts == nil {
+		return prompt.NewService(nil)
+	}
+	return prompts
+}
+
 // registerAllHandlers 注册所有处理器
 func (r *HandlerRegistry) registerAllHandlers() {
 	// AI 内容生成处理器
 	r.orchestrator.RegisterHandler("ai_content_generator", &AIContentGenerator{
 		aiService: r.aiService,
+		prompts:   r.prompts,
 	})
 
 	// 内容优化处理器
 	r.orchestrator.RegisterHandler("content_optimizer", &ContentOptimizer{
 		aiService: r.aiService,
+		prompts:   r.prompts,
 	})
 
 	// 质量评分处理器
 	r.orchestrator.RegisterHandler("quality_scorer", &QualityScorer{
 		aiService: r.aiService,
+		prompts:   r.prompts,
 	})
 
 	// 平台发布处理器
@@ -79,6 +98,7 @@ func (r *HandlerRegistry) registerAllHandlers() {
 	// 内容改写处理器
 	r.orchestrator.RegisterHandler("content_rewriter", &ContentRewriter{
 		aiService: r.aiService,
+		prompts:   r.prompts,
 	})
 
 	// 视频切片处理器
@@ -90,11 +110,13 @@ func (r *HandlerRegistry) registerAllHandlers() {
 	// 趋势分析处理器
 	r.orchestrator.RegisterHandler("trend_analyzer", &TrendAnalyzer{
 		aiService: r.aiService,
+		prompts:   r.prompts,
 	})
 
 	// 数据分析处理器
 	r.orchestrator.RegisterHandler("data_analyzer", &DataAnalyzer{
 		aiService: r.aiService,
+		prompts:   r.prompts,
 	})
 
 	// 报告生成处理器
@@ -103,9 +125,29 @@ func (r *HandlerRegistry) registerAllHandlers() {
 	logrus.Info("所有步骤处理器已注册")
 }
 
+// RegisterVisionHandlers 注册依赖存储和视频处理器的多模态处理器
+func (r *HandlerRegistry) RegisterVisionHandlers(store storage.Storage, proc processor.Processor) {
+	// 看图写笔记处理器
+	r.orchestrator.RegisterHandler("image_note_writer", NewImageNoteWriter(r.aiService, store))
+
+	// 缩略图描述处理器
+	if proc != nil {
+		r.orchestrator.RegisterHandler("thumbnail_captioner", NewThumbnailCaptioner(r.aiService, proc))
+	}
+}
+
+// SetComplianceChecker 平台发布前执行合规检查
+func (r *HandlerRegistry) SetComplianceChecker(checker *compliance.Checker) {
+	r.orchestrator.RegisterHandler("platform_publisher", &PlatformPublisher{
+		publisher:  r.publisher,
+		compliance: checker,
+	})
+}
+
 // AIContentGenerator AI内容生成处理器
 type AIContentGenerator struct {
 	aiService *ai.Service
+	prompts   *prompt.Service
 }
 
 func (h *AIContentGenerator) Execute(ctx context.Context, config map[string]interface{}, input map[string]interface{}) (map[string]interface{}, error) {
@@ -122,29 +164,13 @@ func (h *AIContentGenerator) Execute(ctx context.Context, config map[string]inte
 	keywords, _ := input["keywords"].([]string)
 	targetAudience, _ := input["target_audience"].(string)
 
-	// 构建提示词
-	prompt := fmt.Sprintf(`请根据以下信息生成一篇高质量的内容：
-
-主题: %s
-关键词: %s
-目标受众: %s
-
-要求:
-1. 内容要吸引人，有吸引力
-2. 结构清晰，逻辑连贯
-3. 符合目标受众的阅读习惯
-4. 包含适当的emoji表情
-5. 字数控制在500-1000字
-
-请直接生成内容，不要包含任何解释。`,
-		topic, strings.Join(keywords, ", "), targetAudience)
-
 	// 调用 AI 服务
-	result, err := h.aiService.Generate(ctx, &provider.GenerateOptions{
-		Model:    model,
-		Messages: []provider.Message{{Role: provider.RoleUser, Content: prompt}},
-		MaxTokens: 2000,
-		Temperature: 0.7,
+	result, err := h.prompts.Generate(ctx, h.aiService, prompt.TemplatePipelineContentGeneration, map[string]interface{}{
+		"topic":           topic,
+		"keywords":        keywords,
+		"target_audience": targetAudience,
+	}, func(opts *provider.GenerateOptions) {
+		opts.Model = model
 	})
 	if err != nil {
 		return nil, fmt.Errorf("AI生成失败: %w", err)
@@ -160,6 +186,7 @@ func (h *AIContentGenerator) Execute(ctx context.Context, config map[string]inte
 // ContentOptimizer 内容优化处理器
 type ContentOptimizer struct {
 	aiService *ai.Service
+	prompts   *prompt.Service
 }
 
 func (h *ContentOptimizer) Execute(ctx context.Context, config map[string]interface{}, input map[string]interface{}) (map[string]interface{}, error) {
@@ -171,26 +198,12 @@ func (h *ContentOptimizer) Execute(ctx context.Context, config map[string]interf
 	checkSpelling, _ := config["check_spelling"].(bool)
 	improveReadability, _ := config["improve_readability"].(bool)
 
-	// 构建优化提示词
-	prompt := fmt.Sprintf(`请优化以下内容：
-
-%s
-
-优化要求:
-%s%s
-
-请直接输出优化后的内容，不要包含任何解释。`,
-		content,
-		map[bool]string{true: "1. 检查并修正拼写错误\n"}[checkSpelling],
-		map[bool]string{true: "2. 改善可读性和流畅度\n"}[improveReadability])
-
 	// 调用 AI 服务
-	result, err := h.aiService.Generate(ctx, &provider.GenerateOptions{
-		Model:       "deepseek-chat",
-		Messages:    []provider.Message{{Role: provider.RoleUser, Content: prompt}},
-		MaxTokens:   2000,
-		Temperature: 0.3,
-	})
+	result, err := h.prompts.Generate(ctx, h.aiService, prompt.TemplatePipelineContentOptimization, map[string]interface{}{
+		"content":             content,
+		"check_spelling":      checkSpelling,
+		"improve_readability": improveReadability,
+	}, nil)
 	if err != nil {
 		return nil, fmt.Errorf("内容优化失败: %w", err)
 	}
@@ -204,6 +217,7 @@ func (h *ContentOptimizer) Execute(ctx context.Context, config map[string]interf
 // QualityScorer 质量评分处理器
 type QualityScorer struct {
 	aiService *ai.Service
+	prompts   *prompt.Service
 }
 
 func (h *QualityScorer) Execute(ctx context.Context, config map[string]interface{}, input map[string]interface{}) (map[string]interface{}, error) {
@@ -220,35 +234,10 @@ func (h *QualityScorer) Execute(ctx context.Context, config map[string]interface
 		minScore = 0.7
 	}
 
-	// 构建评分提示词
-	prompt := fmt.Sprintf(`请对以下内容进行质量评分（0-1分）：
-
-%s
-
-评分维度:
-1. 内容质量（30%）
-2. 吸引力（25%）
-3. 可读性（25%）
-4. 完整性（20%）
-
-请以JSON格式返回评分结果，格式如下:
-{
-  "overall_score": 0.85,
-  "content_quality": 0.9,
-  "attractiveness": 0.85,
-  "readability": 0.8,
-  "completeness": 0.85,
-  "reasoning": "内容质量优秀，逻辑清晰，具有较强的吸引力"
-}`,
-		content)
-
 	// 调用 AI 服务
-	result, err := h.aiService.Generate(ctx, &provider.GenerateOptions{
-		Model:       "deepseek-chat",
-		Messages:    []provider.Message{{Role: provider.RoleUser, Content: prompt}},
-		MaxTokens:   500,
-		Temperature: 0.2,
-	})
+	result, err := h.prompts.Generate(ctx, h.aiService, prompt.TemplatePipelineQualityScoring, map[string]interface{}{
+		"content": content,
+	}, nil)
 	if err != nil {
 		return nil, fmt.Errorf("质量评分失败: %w", err)
 	}
@@ -271,7 +260,8 @@ func (h *QualityScorer) Execute(ctx context.Context, config map[string]interface
 
 // PlatformPublisher 平台发布处理器
 type PlatformPublisher struct {
-	publisher *adapters.PublisherFactory
+	publisher  *adapters.PublisherFactory
+	compliance *compliance.Checker
 }
 
 func (h *PlatformPublisher) Execute(ctx context.Context, config map[string]interface{}, input map[string]interface{}) (map[string]interface{}, error) {
@@ -296,6 +286,28 @@ func (h *PlatformPublisher) Execute(ctx context.Context, config map[string]inter
 	for _, platform := range platforms {
 		logrus.Infof("开始发布到平台: %s", platform)
 
+		// 合规检查按平台执行，被拦截或待审批的平台跳过
+		if h.compliance != nil {
+			report, err := h.compliance.Enforce(ctx, &compliance.Content{
+				Platform: platform,
+				Title:    title,
+				Body:     contentStr,
+			}, "")
+			var violation *compliance.ViolationError
+			if errors.As(err, &violation) {
+				logrus.Warnf("内容未通过 %s 平台合规检查: %v", platform, err)
+				results[platform] = map[string]interface{}{
+					"success":    false,
+					"error":      err.Error(),
+					"compliance": report,
+				}
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("合规检查失败: %w", err)
+			}
+		}
+
 		// 获取适配器
 		adapter, err := h.publisher.Create(platform, nil)
 		if err != nil {
@@ -439,6 +451,7 @@ func (h *SpeechTranscriber) Execute(ctx context.Context, config map[string]inter
 // ContentRewriter 内容改写处理器
 type ContentRewriter struct {
 	aiService *ai.Service
+	prompts   *prompt.Service
 }
 
 func (h *ContentRewriter) Execute(ctx context.Context, config map[string]interface{}, input map[string]interface{}) (map[string]interface{}, error) {
@@ -457,28 +470,12 @@ func (h *ContentRewriter) Execute(ctx context.Context, config map[string]interfa
 		maxLength = 1000
 	}
 
-	// 构建改写提示词
-	prompt := fmt.Sprintf(`请将以下转录文本改写为适合发布的内容：
-
-%s
-
-改写要求:
-1. 风格: %s
-2. 字数限制: %d字以内
-3. 语言要生动有趣
-4. 保留核心信息
-5. 适当添加emoji表情
-
-请直接输出改写后的内容，不要包含任何解释。`,
-		transcript, style, maxLength)
-
 	// 调用 AI 服务
-	result, err := h.aiService.Generate(ctx, &provider.GenerateOptions{
-		Model:       "deepseek-chat",
-		Messages:    []provider.Message{{Role: provider.RoleUser, Content: prompt}},
-		MaxTokens:   1500,
-		Temperature: 0.8,
-	})
+	result, err := h.prompts.Generate(ctx, h.aiService, prompt.TemplatePipelineTranscriptRewrite, map[string]interface{}{
+		"transcript": transcript,
+		"style":      style,
+		"max_length": maxLength,
+	}, nil)
 	if err != nil {
 		return nil, fmt.Errorf("内容改写失败: %w", err)
 	}
@@ -591,6 +588,7 @@ func (h *HotspotFetcher) Execute(ctx context.Context, config map[string]interfac
 // TrendAnalyzer 趋势分析处理器
 type TrendAnalyzer struct {
 	aiService *ai.Service
+	prompts   *prompt.Service
 }
 
 func (h *TrendAnalyzer) Execute(ctx context.Context, config map[string]interface{}, input map[string]interface{}) (map[string]interface{}, error) {
@@ -614,27 +612,10 @@ func (h *TrendAnalyzer) Execute(ctx context.Context, config map[string]interface
 		}
 	}
 
-	// 构建分析提示词
-	prompt := fmt.Sprintf(`请分析以下热点标题，提取关键趋势和关键词：
-
-%s
-
-分析要求:
-1. 提取主要关键词（最多10个）
-2. 识别热门话题
-3. 分析趋势方向
-4. 给出推荐的主题
-
-请以JSON格式返回分析结果。`,
-		strings.Join(titles, "\n"))
-
 	// 调用 AI 服务
-	result, err := h.aiService.Generate(ctx, &provider.GenerateOptions{
-		Model:       "deepseek-chat",
-		Messages:    []provider.Message{{Role: provider.RoleUser, Content: prompt}},
-		MaxTokens:   1000,
-		Temperature: 0.3,
-	})
+	result, err := h.prompts.Generate(ctx, h.aiService, prompt.TemplatePipelineTrendAnalysis, map[string]interface{}{
+		"titles": titles,
+	}, nil)
 	if err != nil {
 		return nil, fmt.Errorf("趋势分析失败: %w", err)
 	}
@@ -656,6 +637,7 @@ func (h *TrendAnalyzer) Execute(ctx context.Context, config map[string]interface
 // DataAnalyzer 数据分析处理器
 type DataAnalyzer struct {
 	aiService *ai.Service
+	prompts   *prompt.Service
 }
 
 func (h *DataAnalyzer) Execute(ctx context.Context, config map[string]interface{}, input map[string]interface{}) (map[string]interface{}, error) {
@@ -671,30 +653,10 @@ func (h *DataAnalyzer) Execute(ctx context.Context, config map[string]interface{
 
 	logrus.Infof("开始数据分析 (分析类型: %s)", analysisType)
 
-	// 将数据转换为 JSON 字符串
-	dataJSON, _ := json.Marshal(data)
-
-	// 构建分析提示词
-	prompt := fmt.Sprintf(`请分析以下数据，生成性能报告：
-
-%s
-
-报告要求:
-1. 总结关键指标
-2. 识别趋势和模式
-3. 提供优化建议
-4. 给出行动建议
-
-请以Markdown格式输出报告。`,
-		string(dataJSON))
-
 	// 调用 AI 服务
-	result, err := h.aiService.Generate(ctx, &provider.GenerateOptions{
-		Model:       "deepseek-chat",
-		Messages:    []provider.Message{{Role: provider.RoleUser, Content: prompt}},
-		MaxTokens:   2000,
-		Temperature: 0.4,
-	})
+	result, err := h.prompts.Generate(ctx, h.aiService, prompt.TemplatePipelineDataReport, map[string]interface{}{
+		"data": data,
+	}, nil)
 	if err != nil {
 		return nil, fmt.Errorf("数据分析失败: %w", err)
 	}
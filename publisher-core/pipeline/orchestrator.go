@@ -8,6 +8,8 @@ import (
 	"sync"
 	"time"
 
+	"publisher-core/cost"
+
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 )
@@ -246,6 +248,9 @@ func (o *PipelineOrchestrator) executeStep(ctx context.Context, step PipelineSte
 	stepCtx, cancel := context.WithTimeout(ctx, step.Timeout)
 	defer cancel()
 
+	// 步骤内的 AI 调用按处理器名称归集成本
+	stepCtx = cost.WithFunction(stepCtx, step.Handler)
+
 	// 执行步骤
 	output, err := handler.Execute(stepCtx, step.Config, input)
 	if err != nil {
@@ -0,0 +1,179 @@
+// Package pipeline 提供多模态流水线步骤处理器
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"publisher-core/ai"
+	"publisher-core/ai/provider"
+	"publisher-core/storage"
+	"publisher-core/video/processor"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ImageNoteWriter 看图写笔记处理器
+// 输入 images 为图片地址列表，支持 http(s) 地址、data URL 和存储路径，不读取存储以外的本地文件
+type ImageNoteWriter struct {
+	aiService *ai.Service
+	storage   storage.Storage
+}
+
+// NewImageNoteWriter 创建看图写笔记处理器
+func NewImageNoteWriter(aiService *ai.Service, store storage.Storage) *ImageNoteWriter {
+	return &ImageNoteWriter{aiService: aiService, storage: store}
+}
+
+func (h *ImageNoteWriter) Execute(ctx context.Context, config map[string]interface{}, input map[string]interface{}) (map[string]interface{}, error) {
+	refs := stringList(input["images"])
+	if len(refs) == 0 {
+		return nil, fmt.Errorf("缺少 images 参数")
+	}
+
+	images := make([]provider.ImagePart, 0, len(refs))
+	for _, ref := range refs {
+		image, err := h.loadImage(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+
+	req := &ai.ImageNoteRequest{Images: images}
+	req.Topic, _ = input["topic"].(string)
+	req.Platform, _ = config["platform"].(string)
+	req.Style, _ = config["style"].(string)
+	req.VisionModel, _ = config["vision_model"].(string)
+	req.Model, _ = config["model"].(string)
+
+	note, err := h.aiService.WriteImageNote(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"title":        note.Title,
+		"content":      note.Content,
+		"tags":         note.Tags,
+		"cover_image":  refs[note.CoverIndex],
+		"descriptions": note.Descriptions,
+		"tokens_used":  note.InputTokens + note.OutputTokens,
+		"generated_at": time.Now().Format(time.RFC3339),
+	}, nil
+}
+
+// loadImage 按地址类型加载图片
+func (h *ImageNoteWriter) loadImage(ctx context.Context, ref string) (provider.ImagePart, error) {
+	if strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://") || strings.HasPrefix(ref, "data:") {
+		return provider.ImageFromURL(ref), nil
+	}
+	if h.storage == nil {
+		return provider.ImagePart{}, fmt.Errorf("未配置存储，无法读取图片: %s", ref)
+	}
+	return provider.ImageFromStorage(ctx, h.storage, ref)
+}
+
+// ThumbnailCaptioner 视频缩略图描述处理器
+// 从视频中截取缩略图并用视觉模型为每张生成描述
+type ThumbnailCaptioner struct {
+	aiService *ai.Service
+	processor processor.Processor
+}
+
+// NewThumbnailCaptioner 创建缩略图描述处理器
+func NewThumbnailCaptioner(aiService *ai.Service, proc processor.Processor) *ThumbnailCaptioner {
+	return &ThumbnailCaptioner{aiService: aiService, processor: proc}
+}
+
+func (h *ThumbnailCaptioner) Execute(ctx context.Context, config map[string]interface{}, input map[string]interface{}) (map[string]interface{}, error) {
+	videoPath, ok := input["video_path"].(string)
+	if !ok || videoPath == "" {
+		return nil, fmt.Errorf("缺少 video_path 参数")
+	}
+
+	thumbConfig := processor.DefaultThumbnailConfig()
+	if count := intValue(config["count"]); count > 0 {
+		thumbConfig.Count = count
+	}
+	if outputDir, ok := config["output_dir"].(string); ok && outputDir != "" {
+		thumbConfig.OutputDir = outputDir
+	}
+
+	thumbnails, err := h.processor.GenerateThumbnails(ctx, videoPath, thumbConfig)
+	if err != nil {
+		return nil, fmt.Errorf("生成缩略图失败: %w", err)
+	}
+
+	prompt, _ := config["prompt"].(string)
+	model, _ := config["model"].(string)
+
+	captions := make([]map[string]interface{}, 0, len(thumbnails.Thumbnails))
+	tokensUsed := 0
+	for _, thumb := range thumbnails.Thumbnails {
+		image, err := provider.ImageFromFile(thumb.FilePath)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := h.aiService.DescribeImage(ctx, image, prompt, model)
+		if err != nil {
+			logrus.Warnf("缩略图描述失败: %s, 错误: %v", thumb.FilePath, err)
+			continue
+		}
+		tokensUsed += result.InputTokens + result.OutputTokens
+
+		captions = append(captions, map[string]interface{}{
+			"index":     thumb.Index,
+			"file_path": thumb.FilePath,
+			"timestamp": thumb.Timestamp,
+			"caption":   strings.TrimSpace(result.Content),
+		})
+	}
+
+	if len(captions) == 0 && len(thumbnails.Thumbnails) > 0 {
+		return nil, fmt.Errorf("所有缩略图描述均失败")
+	}
+
+	return map[string]interface{}{
+		"thumbnails":   captions,
+		"tokens_used":  tokensUsed,
+		"captioned_at": time.Now().Format(time.RFC3339),
+	}, nil
+}
+
+// intValue 读取整数配置，流水线配置经 JSON 解码后数字可能是 float64 或 json.Number
+func intValue(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	case json.Number:
+		n, _ := v.Int64()
+		return int(n)
+	}
+	return 0
+}
+
+// stringList 将 []string 或 []interface{} 输入转换为字符串列表
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
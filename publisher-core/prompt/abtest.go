@@ -1,17 +1,65 @@
 package prompt
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"math/rand"
+	"hash/fnv"
+	"sort"
 	"time"
 
 	"publisher-core/database"
 
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// GoalMetric 实验的目标指标
+type GoalMetric string
+
+const (
+	// GoalSuccess 调用成功率，取值 0 或 1
+	GoalSuccess GoalMetric = "success"
+	// GoalRating 用户评分，取值 0-5
+	GoalRating GoalMetric = "rating"
+	// GoalEngagement 发布后的互动率，来自数据分析
+	GoalEngagement GoalMetric = "engagement"
+	// GoalAuditPass 审核通过率，取值 0 或 1
+	GoalAuditPass GoalMetric = "audit_pass"
+)
+
+// validate 检查指标取值范围
+func (m GoalMetric) validate(value float64) error {
+	switch m {
+	case GoalSuccess, GoalAuditPass:
+		if value != 0 && value != 1 {
+			return fmt.Errorf("指标 %s 的取值必须为 0 或 1", m)
+		}
+	case GoalRating:
+		if value < 0 || value > 5 {
+			return errors.New("评分必须在0-5之间")
+		}
+	case GoalEngagement:
+		if value < 0 {
+			return errors.New("互动率不能为负数")
+		}
+	default:
+		return fmt.Errorf("不支持的指标: %s", m)
+	}
+	return nil
+}
+
+// Variant 实验变体
+type Variant struct {
+	TemplateID string `json:"template_id"`
+	// Weight 流量权重，按所有变体权重之和分配
+	Weight int `json:"weight"`
+}
+
 // ABTestService A/B测试服务
+// 实验按用户或会话分组，同一单元始终使用首次分配的变体，结果按单元汇总后做显著性检验
 type ABTestService struct {
 	db *gorm.DB
 }
@@ -23,32 +71,91 @@ func NewABTestService(db *gorm.DB) *ABTestService {
 
 // CreateABTestRequest 创建A/B测试请求
 type CreateABTestRequest struct {
-	TestName     string `json:"test_name"`
-	TemplateAID  string `json:"template_a_id"`
-	TemplateBID  string `json:"template_b_id"`
-	TrafficSplit int    `json:"traffic_split"` // 0-100，表示模板A的流量百分比
+	TestName string `json:"test_name"`
+	// Variants 参与实验的模板，第一个为对照组；为空时使用 TemplateAID 和 TemplateBID
+	Variants     []Variant `json:"variants"`
+	TemplateAID  string    `json:"template_a_id"`
+	TemplateBID  string    `json:"template_b_id"`
+	TrafficSplit int       `json:"traffic_split"` // 0-100，表示模板A的流量百分比
+	// GoalMetric 目标指标，默认 success
+	GoalMetric GoalMetric `json:"goal_metric"`
+	// ConfidenceLevel 置信水平，默认 0.95
+	ConfidenceLevel float64 `json:"confidence_level"`
+	// MinSamples 每个变体判定前需要的最少样本数，默认 100
+	MinSamples  int  `json:"min_samples"`
+	AutoPromote bool `json:"auto_promote"`
 }
 
 // UpdateABTestRequest 更新A/B测试请求
 type UpdateABTestRequest struct {
-	TrafficSplit int    `json:"traffic_split"`
+	// Weights 按模板ID调整流量权重，已分组的单元不受影响
+	Weights map[string]int `json:"weights"`
+	// TrafficSplit 两个变体的实验中模板A的流量百分比
+	TrafficSplit *int   `json:"traffic_split"`
+	AutoPromote  *bool  `json:"auto_promote"`
 	Status       string `json:"status"` // running, paused, completed
 }
 
 // CreateABTest 创建A/B测试
 func (s *ABTestService) CreateABTest(req *CreateABTestRequest) (*database.PromptTemplateABTest, error) {
-	// 验证流量分配
-	if req.TrafficSplit < 0 || req.TrafficSplit > 100 {
-		return nil, errors.New("流量分配必须在0-100之间")
+	variants := req.Variants
+	if len(variants) == 0 {
+		// 验证流量分配
+		if req.TrafficSplit < 0 || req.TrafficSplit > 100 {
+			return nil, errors.New("流量分配必须在0-100之间")
+		}
+		variants = []Variant{
+			{TemplateID: req.TemplateAID, Weight: req.TrafficSplit},
+			{TemplateID: req.TemplateBID, Weight: 100 - req.TrafficSplit},
+		}
+	}
+	if len(variants) < 2 {
+		return nil, errors.New("至少需要两个变体")
 	}
 
 	// 检查模板是否存在
 	templateService := NewService(s.db)
-	if _, err := templateService.GetTemplate(req.TemplateAID); err != nil {
-		return nil, fmt.Errorf("模板A不存在: %w", err)
+	seen := make(map[string]bool, len(variants))
+	total := 0
+	for i, v := range variants {
+		if seen[v.TemplateID] {
+			return nil, fmt.Errorf("变体模板重复: %s", v.TemplateID)
+		}
+		seen[v.TemplateID] = true
+		if _, err := templateService.GetTemplate(v.TemplateID); err != nil {
+			return nil, fmt.Errorf("变体 %d 的模板 %s 不存在: %w", i+1, v.TemplateID, err)
+		}
+		if v.Weight < 0 {
+			return nil, errors.New("流量权重不能为负数")
+		}
+		total += v.Weight
 	}
-	if _, err := templateService.GetTemplate(req.TemplateBID); err != nil {
-		return nil, fmt.Errorf("模板B不存在: %w", err)
+	if total == 0 {
+		for i := range variants {
+			variants[i].Weight = 1
+		}
+	}
+
+	goal := req.GoalMetric
+	if goal == "" {
+		goal = GoalSuccess
+	}
+	if err := goal.validate(0); err != nil {
+		return nil, err
+	}
+	confidence := req.ConfidenceLevel
+	if confidence == 0 {
+		confidence = 0.95
+	}
+	if confidence < 0.5 || confidence >= 1 {
+		return nil, errors.New("置信水平必须在0.5-1之间")
+	}
+	minSamples := req.MinSamples
+	if minSamples == 0 {
+		minSamples = 100
+	}
+	if minSamples < 2 {
+		return nil, errors.New("最少样本数不能小于2")
 	}
 
 	// 检查是否已有同名测试
@@ -60,15 +167,25 @@ func (s *ABTestService) CreateABTest(req *CreateABTestRequest) (*database.Prompt
 		return nil, errors.New("已存在同名的运行中测试")
 	}
 
+	variantsJSON, err := json.Marshal(variants)
+	if err != nil {
+		return nil, fmt.Errorf("序列化变体失败: %w", err)
+	}
+
 	abTest := &database.PromptTemplateABTest{
-		TestName:     req.TestName,
-		TemplateAID:  req.TemplateAID,
-		TemplateBID:  req.TemplateBID,
-		Status:       "running",
-		TrafficSplit: req.TrafficSplit,
-		StartTime:    time.Now(),
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		TestName:        req.TestName,
+		TemplateAID:     variants[0].TemplateID,
+		TemplateBID:     variants[1].TemplateID,
+		Variants:        string(variantsJSON),
+		Status:          "running",
+		TrafficSplit:    variants[0].Weight * 100 / sumWeights(variants),
+		GoalMetric:      string(goal),
+		ConfidenceLevel: confidence,
+		MinSamples:      minSamples,
+		AutoPromote:     req.AutoPromote,
+		StartTime:       time.Now(),
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
 	}
 
 	if err := s.db.Create(abTest).Error; err != nil {
@@ -126,8 +243,45 @@ func (s *ABTestService) UpdateABTest(testID uint, req *UpdateABTestRequest) (*da
 		"updated_at": time.Now(),
 	}
 
-	if req.TrafficSplit >= 0 && req.TrafficSplit <= 100 {
-		updates["traffic_split"] = req.TrafficSplit
+	if len(req.Weights) > 0 || req.TrafficSplit != nil {
+		variants, err := parseVariants(abTest)
+		if err != nil {
+			return nil, err
+		}
+		if req.TrafficSplit != nil {
+			if len(variants) != 2 || *req.TrafficSplit < 0 || *req.TrafficSplit > 100 {
+				return nil, errors.New("流量百分比只适用于两个变体的测试，且必须在0-100之间")
+			}
+			variants[0].Weight, variants[1].Weight = *req.TrafficSplit, 100-*req.TrafficSplit
+		}
+		for templateID, weight := range req.Weights {
+			found := false
+			for i := range variants {
+				if variants[i].TemplateID == templateID {
+					variants[i].Weight = weight
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("模板 %s 不在测试中", templateID)
+			}
+			if weight < 0 {
+				return nil, errors.New("流量权重不能为负数")
+			}
+		}
+		if sumWeights(variants) == 0 {
+			return nil, errors.New("至少一个变体的流量权重大于0")
+		}
+		variantsJSON, err := json.Marshal(variants)
+		if err != nil {
+			return nil, fmt.Errorf("序列化变体失败: %w", err)
+		}
+		updates["variants"] = string(variantsJSON)
+		updates["traffic_split"] = variants[0].Weight * 100 / sumWeights(variants)
+	}
+
+	if req.AutoPromote != nil {
+		updates["auto_promote"] = *req.AutoPromote
 	}
 
 	if req.Status != "" {
@@ -147,8 +301,54 @@ func (s *ABTestService) UpdateABTest(testID uint, req *UpdateABTestRequest) (*da
 	return s.GetABTest(testID)
 }
 
-// SelectTemplateForTest 为A/B测试选择模板
-func (s *ABTestService) SelectTemplateForTest(testID uint) (string, error) {
+// parseVariants 解析测试的变体，旧版两变体测试按 TrafficSplit 分配
+func parseVariants(abTest *database.PromptTemplateABTest) ([]Variant, error) {
+	if abTest.Variants == "" {
+		return []Variant{
+			{TemplateID: abTest.TemplateAID, Weight: abTest.TrafficSplit},
+			{TemplateID: abTest.TemplateBID, Weight: 100 - abTest.TrafficSplit},
+		}, nil
+	}
+	var variants []Variant
+	if err := json.Unmarshal([]byte(abTest.Variants), &variants); err != nil {
+		return nil, fmt.Errorf("解析变体失败: %w", err)
+	}
+	return variants, nil
+}
+
+// sumWeights 计算权重之和
+func sumWeights(variants []Variant) int {
+	total := 0
+	for _, v := range variants {
+		total += v.Weight
+	}
+	return total
+}
+
+// pickVariant 按单元ID的哈希值在权重区间中选择变体，同一测试和单元的结果固定
+func pickVariant(testID uint, unitID string, variants []Variant) string {
+	total := sumWeights(variants)
+	if total <= 0 {
+		return variants[0].TemplateID
+	}
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d:%s", testID, unitID)
+	bucket := int(h.Sum64() % uint64(total))
+	for _, v := range variants {
+		if bucket < v.Weight {
+			return v.TemplateID
+		}
+		bucket -= v.Weight
+	}
+	return variants[len(variants)-1].TemplateID
+}
+
+// SelectTemplateForTest 为用户或会话选择模板
+// 首次请求按哈希分组并记录，之后始终返回同一变体，调整权重不影响已分组的单元
+func (s *ABTestService) SelectTemplateForTest(testID uint, unitID string) (string, error) {
+	if unitID == "" {
+		return "", errors.New("需要用户ID或会话ID")
+	}
 	abTest, err := s.GetABTest(testID)
 	if err != nil {
 		return "", err
@@ -158,140 +358,453 @@ func (s *ABTestService) SelectTemplateForTest(testID uint) (string, error) {
 		return "", errors.New("A/B测试未运行")
 	}
 
-	// 根据流量分配随机选择模板
-	if rand.Intn(100) < abTest.TrafficSplit {
-		return abTest.TemplateAID, nil
+	if templateID, err := s.assignment(testID, unitID); err != nil || templateID != "" {
+		return templateID, err
 	}
-	return abTest.TemplateBID, nil
+
+	variants, err := parseVariants(abTest)
+	if err != nil {
+		return "", err
+	}
+	assignment := &database.PromptExperimentAssignment{
+		TestID:     testID,
+		UnitID:     unitID,
+		TemplateID: pickVariant(testID, unitID, variants),
+		CreatedAt:  time.Now(),
+	}
+	// 并发请求同一单元时以先写入的分组为准
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(assignment).Error; err != nil {
+		return "", fmt.Errorf("记录分组失败: %w", err)
+	}
+	return s.assignment(testID, unitID)
 }
 
-// RecordTestCall 记录测试调用
-func (s *ABTestService) RecordTestCall(testID uint, templateID string, success bool, durationMs int) error {
-	abTest, err := s.GetABTest(testID)
+// assignment 查询单元的分组，未分组时返回空字符串
+func (s *ABTestService) assignment(testID uint, unitID string) (string, error) {
+	var assignment database.PromptExperimentAssignment
+	err := s.db.Where("test_id = ? AND unit_id = ?", testID, unitID).First(&assignment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
 	if err != nil {
-		return err
+		return "", fmt.Errorf("查询分组失败: %w", err)
 	}
+	return assignment.TemplateID, nil
+}
 
-	updates := map[string]interface{}{
-		"total_calls": abTest.TotalCalls + 1,
-		"updated_at":  time.Now(),
+// RecordTestCall 记录测试调用是否成功
+func (s *ABTestService) RecordTestCall(testID uint, unitID string, success bool) error {
+	value := 0.0
+	if success {
+		value = 1
 	}
+	if err := s.RecordOutcome(testID, unitID, GoalSuccess, value); err != nil {
+		return err
+	}
+	return s.db.Model(&database.PromptTemplateABTest{}).Where("id = ?", testID).
+		UpdateColumn("total_calls", gorm.Expr("total_calls + 1")).Error
+}
+
+// RecordUserRating 记录用户评分
+func (s *ABTestService) RecordUserRating(testID uint, unitID string, rating float64) error {
+	return s.RecordOutcome(testID, unitID, GoalRating, rating)
+}
+
+// RecordOutcome 记录单元在某个指标上的结果，结果归属于单元所在的变体
+// 发布互动率和审核结果由 RecordPostEngagement、RecordAuditResult 按生成内容时的用户或会话回填
+func (s *ABTestService) RecordOutcome(testID uint, unitID string, metric GoalMetric, value float64) error {
+	return s.recordOutcome(testID, unitID, metric, value, "")
+}
 
-	// 更新对应模板的统计
-	if templateID == abTest.TemplateAID {
-		updates["calls_a"] = abTest.CallsA + 1
-		if success {
-			updates["success_a"] = abTest.SuccessA + 1
+// recordOutcome 记录结果，source 非空时替换同一来源的旧结果
+func (s *ABTestService) recordOutcome(testID uint, unitID string, metric GoalMetric, value float64, source string) error {
+	if err := metric.validate(value); err != nil {
+		return err
+	}
+	abTest, err := s.GetABTest(testID)
+	if err != nil {
+		return err
+	}
+	templateID, err := s.assignment(testID, unitID)
+	if err != nil {
+		return err
+	}
+	if templateID == "" {
+		return fmt.Errorf("单元 %s 未分配到该测试", unitID)
+	}
+
+	outcome := &database.PromptExperimentOutcome{
+		TestID:     testID,
+		Metric:     string(metric),
+		UnitID:     unitID,
+		TemplateID: templateID,
+		Value:      value,
+		Source:     source,
+		CreatedAt:  time.Now(),
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if source != "" {
+			if err := tx.Where("test_id = ? AND metric = ? AND unit_id = ? AND source = ?", testID, string(metric), unitID, source).
+				Delete(&database.PromptExperimentOutcome{}).Error; err != nil {
+				return err
+			}
 		}
-		// 更新平均响应时间
-		newAvgDurationA := (abTest.AvgDurationA*float64(abTest.CallsA) + float64(durationMs)) / float64(abTest.CallsA+1)
-		updates["avg_duration_a"] = newAvgDurationA
-	} else if templateID == abTest.TemplateBID {
-		updates["calls_b"] = abTest.CallsB + 1
-		if success {
-			updates["success_b"] = abTest.SuccessB + 1
+		return tx.Create(outcome).Error
+	})
+	if err != nil {
+		return fmt.Errorf("记录实验结果失败: %w", err)
+	}
+
+	if abTest.AutoPromote && abTest.Status == "running" && abTest.GoalMetric == string(metric) {
+		if err := s.autoPromote(testID); err != nil {
+			logrus.Warnf("自动判定测试 %d 失败: %v", testID, err)
 		}
-		// 更新平均响应时间
-		newAvgDurationB := (abTest.AvgDurationB*float64(abTest.CallsB) + float64(durationMs)) / float64(abTest.CallsB+1)
-		updates["avg_duration_b"] = newAvgDurationB
 	}
+	return nil
+}
 
-	return s.db.Model(abTest).Updates(updates).Error
+// runningAssignments 返回单元在运行中测试里的分组
+func (s *ABTestService) runningAssignments(unitID string) ([]database.PromptExperimentAssignment, error) {
+	var assignments []database.PromptExperimentAssignment
+	err := s.db.Model(&database.PromptExperimentAssignment{}).
+		Joins("JOIN prompt_template_ab_tests ON prompt_template_ab_tests.id = prompt_experiment_assignments.test_id").
+		Where("prompt_experiment_assignments.unit_id = ? AND prompt_template_ab_tests.status = ?", unitID, "running").
+		Find(&assignments).Error
+	if err != nil {
+		return nil, fmt.Errorf("查询分组失败: %w", err)
+	}
+	return assignments, nil
 }
 
-// RecordUserRating 记录用户评分
-func (s *ABTestService) RecordUserRating(testID uint, templateID string, rating float64) error {
-	abTest, err := s.GetABTest(testID)
+// recordUnitOutcome 把结果记入单元参与的所有运行中测试
+func (s *ABTestService) recordUnitOutcome(unitID string, metric GoalMetric, value float64, source string) error {
+	assignments, err := s.runningAssignments(unitID)
 	if err != nil {
 		return err
 	}
+	for _, a := range assignments {
+		if err := s.recordOutcome(a.TestID, unitID, metric, value, source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	if rating < 0 || rating > 5 {
-		return errors.New("评分必须在0-5之间")
+// RecordAuditResult 记录合规检查是否通过，单元取自上下文中的用户或请求ID
+func (s *ABTestService) RecordAuditResult(ctx context.Context, passed bool) error {
+	unitID := rolloutUnit(ctx)
+	if unitID == "" {
+		return nil
+	}
+	value := 0.0
+	if passed {
+		value = 1
 	}
+	return s.recordUnitOutcome(unitID, GoalAuditPass, value, "")
+}
 
-	updates := map[string]interface{}{
-		"updated_at": time.Now(),
+// RecordPublishedPost 记录实验单元发布的内容，之后采集到的互动数据计入该单元
+// 单元取自上下文中的用户或请求ID，未参与运行中测试时不记录
+func (s *ABTestService) RecordPublishedPost(ctx context.Context, platform, postID string) error {
+	unitID := rolloutUnit(ctx)
+	if unitID == "" || postID == "" {
+		return nil
+	}
+	assignments, err := s.runningAssignments(unitID)
+	if err != nil || len(assignments) == 0 {
+		return err
+	}
+	post := &database.PromptExperimentPost{
+		Platform:  platform,
+		PostID:    postID,
+		UnitID:    unitID,
+		CreatedAt: time.Now(),
+	}
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(post).Error; err != nil {
+		return fmt.Errorf("记录实验发布内容失败: %w", err)
+	}
+	return nil
+}
+
+// RecordPostEngagement 记录发布内容的互动率，多次采集时只保留最新值
+func (s *ABTestService) RecordPostEngagement(platform, postID string, engagement float64) error {
+	var post database.PromptExperimentPost
+	err := s.db.Where("platform = ? AND post_id = ?", platform, postID).First(&post).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("查询实验发布内容失败: %w", err)
+	}
+	return s.recordUnitOutcome(post.UnitID, GoalEngagement, engagement, platform+":"+postID)
+}
+
+// autoPromote 按固定样本量判定：每个变体首次达到最少样本数时检验一次
+// 判定前关闭自动判定，之后的结果不再触发检验，避免反复检验抬高误判率；结果不显著时留待人工完成
+func (s *ABTestService) autoPromote(testID uint) error {
+	stats, err := s.GetABTestStats(testID)
+	if err != nil {
+		return err
+	}
+	if !stats.Ready {
+		return nil
 	}
 
-	// 更新对应模板的用户评分
-	if templateID == abTest.TemplateAID {
-		// 计算新的平均评分
-		newRatingA := (abTest.UserRatingA*float64(abTest.CallsA) + rating) / float64(abTest.CallsA+1)
-		updates["user_rating_a"] = newRatingA
-	} else if templateID == abTest.TemplateBID {
-		// 计算新的平均评分
-		newRatingB := (abTest.UserRatingB*float64(abTest.CallsB) + rating) / float64(abTest.CallsB+1)
-		updates["user_rating_b"] = newRatingB
+	// 并发写入结果时只有成功关闭自动判定的调用执行判定
+	result := s.db.Model(&database.PromptTemplateABTest{}).
+		Where("id = ? AND auto_promote = ?", testID, true).
+		Update("auto_promote", false)
+	if result.Error != nil {
+		return fmt.Errorf("关闭自动判定失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil
 	}
 
-	return s.db.Model(abTest).Updates(updates).Error
+	if stats.Winner == "" {
+		logrus.Infof("测试 %d 已达到样本量但结果不显著，需人工判定", testID)
+		return nil
+	}
+	if err := s.CompleteABTest(testID, stats.Winner, true); err != nil {
+		return err
+	}
+	logrus.Infof("测试 %d 已自动完成，获胜模板: %s", testID, stats.Winner)
+	return nil
 }
 
-// CompleteABTest 完成A/B测试并选择获胜者
-func (s *ABTestService) CompleteABTest(testID uint, winnerTemplateID string) error {
+// CompleteABTest 完成A/B测试
+// winnerTemplateID 为空时使用统计判定的获胜者，promote 为 true 时把获胜模板设为同类型的默认模板
+func (s *ABTestService) CompleteABTest(testID uint, winnerTemplateID string, promote bool) error {
 	abTest, err := s.GetABTest(testID)
 	if err != nil {
 		return err
 	}
 
-	if winnerTemplateID != abTest.TemplateAID && winnerTemplateID != abTest.TemplateBID {
+	if winnerTemplateID == "" {
+		stats, err := s.GetABTestStats(testID)
+		if err != nil {
+			return err
+		}
+		if stats.Winner == "" {
+			return errors.New("结果尚不显著，无法自动判定获胜模板")
+		}
+		winnerTemplateID = stats.Winner
+	}
+
+	variants, err := parseVariants(abTest)
+	if err != nil {
+		return err
+	}
+	valid := false
+	for _, v := range variants {
+		if v.TemplateID == winnerTemplateID {
+			valid = true
+		}
+	}
+	if !valid {
 		return errors.New("获胜模板必须是测试中的模板之一")
 	}
 
-	now := time.Now()
-	updates := map[string]interface{}{
-		"status":          "completed",
-		"end_time":        &now,
-		"winner_template": winnerTemplateID,
-		"updated_at":      now,
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		updates := map[string]interface{}{
+			"status":          "completed",
+			"end_time":        &now,
+			"winner_template": winnerTemplateID,
+			"updated_at":      now,
+		}
+		if err := tx.Model(abTest).Updates(updates).Error; err != nil {
+			return fmt.Errorf("完成A/B测试失败: %w", err)
+		}
+		if promote {
+			return promoteTemplate(tx, winnerTemplateID)
+		}
+		return nil
+	})
+}
+
+// promoteTemplate 把模板设为其类型的默认模板
+func promoteTemplate(tx *gorm.DB, templateID string) error {
+	var template database.PromptTemplate
+	if err := tx.Where("template_id = ?", templateID).First(&template).Error; err != nil {
+		return fmt.Errorf("查询获胜模板失败: %w", err)
+	}
+	if err := tx.Model(&database.PromptTemplate{}).
+		Where("type = ? AND template_id <> ?", template.Type, templateID).
+		Update("is_default", false).Error; err != nil {
+		return fmt.Errorf("取消原默认模板失败: %w", err)
 	}
+	if err := tx.Model(&template).Update("is_default", true).Error; err != nil {
+		return fmt.Errorf("设置默认模板失败: %w", err)
+	}
+	return nil
+}
 
-	return s.db.Model(abTest).Updates(updates).Error
+// VariantStats 变体统计
+type VariantStats struct {
+	TemplateID string `json:"template_id"`
+	Weight     int    `json:"weight"`
+	Control    bool   `json:"control"`
+	// Units 已分组的用户或会话数，Samples 其中有目标指标结果的数量
+	Units   int     `json:"units"`
+	Samples int     `json:"samples"`
+	Mean    float64 `json:"mean"`
+	CILow   float64 `json:"ci_low"`
+	CIHigh  float64 `json:"ci_high"`
+	// Difference 与对照组均值之差及其置信区间，Lift 为相对提升
+	Difference float64 `json:"difference"`
+	DiffLow    float64 `json:"diff_low"`
+	DiffHigh   float64 `json:"diff_high"`
+	Lift       float64 `json:"lift"`
+	PValue     float64 `json:"p_value"`
+	// Significant 经多重比较校正后与对照组差异显著
+	Significant bool `json:"significant"`
+	// Metrics 各指标的单元平均值
+	Metrics map[string]float64 `json:"metrics"`
+}
+
+// ExperimentStats 实验统计
+type ExperimentStats struct {
+	TestID          uint           `json:"test_id"`
+	TestName        string         `json:"test_name"`
+	Status          string         `json:"status"`
+	GoalMetric      string         `json:"goal_metric"`
+	ConfidenceLevel float64        `json:"confidence_level"`
+	MinSamples      int            `json:"min_samples"`
+	TotalCalls      int            `json:"total_calls"`
+	Variants        []VariantStats `json:"variants"`
+	// Ready 每个变体的样本数都已达到要求
+	Ready bool `json:"ready"`
+	// Winner 统计判定的获胜模板，结果不显著时为空
+	Winner         string `json:"winner"`
+	WinnerTemplate string `json:"winner_template"`
 }
 
 // GetABTestStats 获取A/B测试统计信息
-func (s *ABTestService) GetABTestStats(testID uint) (map[string]interface{}, error) {
+// 结果先按单元求平均再比较，每个变体与对照组做 Welch 检验，显著性水平按比较次数做 Bonferroni 校正
+func (s *ABTestService) GetABTestStats(testID uint) (*ExperimentStats, error) {
 	abTest, err := s.GetABTest(testID)
 	if err != nil {
 		return nil, err
 	}
+	variants, err := parseVariants(abTest)
+	if err != nil {
+		return nil, err
+	}
+
+	var units []struct {
+		TemplateID string
+		Count      int
+	}
+	if err := s.db.Model(&database.PromptExperimentAssignment{}).
+		Select("template_id, COUNT(*) AS count").
+		Where("test_id = ?", testID).
+		Group("template_id").Scan(&units).Error; err != nil {
+		return nil, fmt.Errorf("统计分组失败: %w", err)
+	}
+	var rows []struct {
+		TemplateID string
+		Metric     string
+		UnitID     string
+		Value      float64
+	}
+	if err := s.db.Model(&database.PromptExperimentOutcome{}).
+		Select("template_id, metric, unit_id, AVG(value) AS value").
+		Where("test_id = ?", testID).
+		Group("template_id, metric, unit_id").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("统计实验结果失败: %w", err)
+	}
 
-	// 计算成功率
-	successRateA := 0.0
-	if abTest.CallsA > 0 {
-		successRateA = float64(abTest.SuccessA) / float64(abTest.CallsA) * 100
-	}
-
-	successRateB := 0.0
-	if abTest.CallsB > 0 {
-		successRateB = float64(abTest.SuccessB) / float64(abTest.CallsB) * 100
-	}
-
-	stats := map[string]interface{}{
-		"test_id":       abTest.ID,
-		"test_name":     abTest.TestName,
-		"status":        abTest.Status,
-		"total_calls":   abTest.TotalCalls,
-		"template_a": map[string]interface{}{
-			"template_id":   abTest.TemplateAID,
-			"calls":         abTest.CallsA,
-			"success":       abTest.SuccessA,
-			"success_rate":  successRateA,
-			"avg_duration":  abTest.AvgDurationA,
-			"user_rating":   abTest.UserRatingA,
-		},
-		"template_b": map[string]interface{}{
-			"template_id":   abTest.TemplateBID,
-			"calls":         abTest.CallsB,
-			"success":       abTest.SuccessB,
-			"success_rate":  successRateB,
-			"avg_duration":  abTest.AvgDurationB,
-			"user_rating":   abTest.UserRatingB,
-		},
-		"traffic_split":  abTest.TrafficSplit,
-		"winner_template": abTest.WinnerTemplate,
+	goal := make(map[string][]float64)
+	metricValues := make(map[string]map[string][]float64)
+	for _, row := range rows {
+		if row.Metric == abTest.GoalMetric {
+			goal[row.TemplateID] = append(goal[row.TemplateID], row.Value)
+		}
+		if metricValues[row.TemplateID] == nil {
+			metricValues[row.TemplateID] = make(map[string][]float64)
+		}
+		metricValues[row.TemplateID][row.Metric] = append(metricValues[row.TemplateID][row.Metric], row.Value)
+	}
+
+	stats := &ExperimentStats{
+		TestID:          abTest.ID,
+		TestName:        abTest.TestName,
+		Status:          abTest.Status,
+		GoalMetric:      abTest.GoalMetric,
+		ConfidenceLevel: abTest.ConfidenceLevel,
+		MinSamples:      abTest.MinSamples,
+		TotalCalls:      abTest.TotalCalls,
+		Ready:           true,
+		WinnerTemplate:  abTest.WinnerTemplate,
+	}
+
+	alpha := 1 - abTest.ConfidenceLevel
+	adjusted := alpha / float64(len(variants)-1)
+	control := newSample(goal[variants[0].TemplateID])
+	for i, v := range variants {
+		sample := newSample(goal[v.TemplateID])
+		vs := VariantStats{
+			TemplateID: v.TemplateID,
+			Weight:     v.Weight,
+			Control:    i == 0,
+			Samples:    sample.n,
+			Mean:       sample.mean,
+			PValue:     1,
+			Metrics:    make(map[string]float64),
+		}
+		for _, u := range units {
+			if u.TemplateID == v.TemplateID {
+				vs.Units = u.Count
+			}
+		}
+		for metric, values := range metricValues[v.TemplateID] {
+			vs.Metrics[metric] = newSample(values).mean
+		}
+		if sample.n > 0 {
+			vs.CILow, vs.CIHigh = meanInterval(sample, alpha)
+		}
+		if i > 0 {
+			c := welchTest(control, sample, adjusted)
+			vs.Difference, vs.DiffLow, vs.DiffHigh, vs.PValue = c.diff, c.low, c.high, c.pValue
+			vs.Significant = c.pValue < adjusted
+			if control.mean != 0 {
+				vs.Lift = c.diff / control.mean
+			}
+		}
+		if sample.n < abTest.MinSamples {
+			stats.Ready = false
+		}
+		stats.Variants = append(stats.Variants, vs)
 	}
 
+	if stats.Ready {
+		stats.Winner = pickWinner(stats.Variants)
+	}
 	return stats, nil
 }
+
+// pickWinner 判定获胜变体
+// 显著优于对照组的变体中均值最高者获胜；所有变体都显著差于对照组时对照组获胜；否则不判定
+func pickWinner(variants []VariantStats) string {
+	var better []VariantStats
+	worse := 0
+	for _, v := range variants[1:] {
+		if !v.Significant {
+			continue
+		}
+		if v.Difference > 0 {
+			better = append(better, v)
+		} else {
+			worse++
+		}
+	}
+	if len(better) > 0 {
+		sort.SliceStable(better, func(i, j int) bool { return better[i].Mean > better[j].Mean })
+		return better[0].TemplateID
+	}
+	if worse == len(variants)-1 {
+		return variants[0].TemplateID
+	}
+	return ""
+}
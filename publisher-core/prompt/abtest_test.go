@@ -0,0 +1,261 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"publisher-core/cost"
+	"publisher-core/database"
+)
+
+func createVariantTemplates(t *testing.T, s *Service, ids ...string) {
+	for _, id := range ids {
+		if _, err := s.CreateTemplate(&CreateTemplateRequest{TemplateID: id, Name: id, Type: "title_generation", Content: "标题：{{.topic}}"}); err != nil {
+			t.Fatalf("CreateTemplate %s failed: %v", id, err)
+		}
+	}
+}
+
+func TestExperimentAssignment(t *testing.T) {
+	s := newTestService(t)
+	createVariantTemplates(t, s, "a", "b", "c")
+	ab := NewABTestService(s.db)
+
+	test, err := ab.CreateABTest(&CreateABTestRequest{
+		TestName: "title",
+		Variants: []Variant{{TemplateID: "a", Weight: 2}, {TemplateID: "b", Weight: 1}, {TemplateID: "c", Weight: 1}},
+	})
+	if err != nil {
+		t.Fatalf("CreateABTest failed: %v", err)
+	}
+	if test.TemplateAID != "a" || test.GoalMetric != string(GoalSuccess) || test.MinSamples != 100 {
+		t.Errorf("Unexpected defaults: %+v", test)
+	}
+
+	counts := make(map[string]int)
+	assigned := make(map[string]string)
+	for i := 0; i < 2000; i++ {
+		unit := fmt.Sprintf("user-%d", i)
+		templateID, err := ab.SelectTemplateForTest(test.ID, unit)
+		if err != nil {
+			t.Fatalf("SelectTemplateForTest failed: %v", err)
+		}
+		counts[templateID]++
+		assigned[unit] = templateID
+	}
+	if math.Abs(float64(counts["a"])/2000-0.5) > 0.05 || math.Abs(float64(counts["b"])/2000-0.25) > 0.05 {
+		t.Errorf("Assignment does not follow weights: %v", counts)
+	}
+
+	// 调整权重后已分组的单元不变
+	if _, err := ab.UpdateABTest(test.ID, &UpdateABTestRequest{Weights: map[string]int{"a": 0}}); err != nil {
+		t.Fatalf("UpdateABTest failed: %v", err)
+	}
+	for _, unit := range []string{"user-1", "user-2", "user-3"} {
+		if templateID, _ := ab.SelectTemplateForTest(test.ID, unit); templateID != assigned[unit] {
+			t.Errorf("Assignment for %s changed from %s to %s", unit, assigned[unit], templateID)
+		}
+	}
+	if templateID, _ := ab.SelectTemplateForTest(test.ID, "new-user"); templateID == "a" {
+		t.Error("New units should not be assigned to a variant with zero weight")
+	}
+
+	if _, err := ab.SelectTemplateForTest(test.ID, ""); err == nil {
+		t.Error("Expected empty unit ID to be rejected")
+	}
+	if err := ab.RecordUserRating(test.ID, "unknown", 4); err == nil {
+		t.Error("Expected outcome for unassigned unit to be rejected")
+	}
+	if err := ab.RecordOutcome(test.ID, "user-1", GoalAuditPass, 0.5); err == nil {
+		t.Error("Expected non-binary audit outcome to be rejected")
+	}
+}
+
+func TestExperimentSignificanceAndPromotion(t *testing.T) {
+	s := newTestService(t)
+	createVariantTemplates(t, s, "control", "better", "same")
+	s.db.Model(&database.PromptTemplate{}).Where("template_id = ?", "control").Update("is_default", true)
+	ab := NewABTestService(s.db)
+
+	test, err := ab.CreateABTest(&CreateABTestRequest{
+		TestName:    "audit",
+		Variants:    []Variant{{TemplateID: "control"}, {TemplateID: "better"}, {TemplateID: "same"}},
+		GoalMetric:  GoalAuditPass,
+		MinSamples:  60,
+		AutoPromote: true,
+	})
+	if err != nil {
+		t.Fatalf("CreateABTest failed: %v", err)
+	}
+
+	rates := map[string]int{"control": 5, "better": 8, "same": 5}
+	seen := make(map[string]int)
+	for i := 0; i < 400; i++ {
+		unit := fmt.Sprintf("session-%d", i)
+		templateID, err := ab.SelectTemplateForTest(test.ID, unit)
+		if err != nil {
+			break // 自动完成后不再分组
+		}
+		passed := 0.0
+		if seen[templateID]%10 < rates[templateID] {
+			passed = 1
+		}
+		seen[templateID]++
+		if err := ab.RecordOutcome(test.ID, unit, GoalAuditPass, passed); err != nil {
+			t.Fatalf("RecordOutcome failed: %v", err)
+		}
+		if err := ab.RecordTestCall(test.ID, unit, true); err != nil {
+			t.Fatalf("RecordTestCall failed: %v", err)
+		}
+	}
+
+	stats, err := ab.GetABTestStats(test.ID)
+	if err != nil {
+		t.Fatalf("GetABTestStats failed: %v", err)
+	}
+	if stats.Status != "completed" || stats.WinnerTemplate != "better" || !stats.Ready {
+		t.Fatalf("Expected test to be promoted automatically: %+v", stats)
+	}
+	better, same := stats.Variants[1], stats.Variants[2]
+	if !better.Significant || better.PValue >= 0.025 || better.DiffLow <= 0 || better.Lift < 0.4 {
+		t.Errorf("Expected better variant to be significant: %+v", better)
+	}
+	if same.Significant || same.DiffLow >= 0 || same.DiffHigh <= 0 {
+		t.Errorf("Expected same variant not to differ: %+v", same)
+	}
+	if better.Metrics[string(GoalSuccess)] != 1 || better.CILow >= better.Mean || better.CIHigh <= better.Mean {
+		t.Errorf("Unexpected variant summary: %+v", better)
+	}
+
+	winner, _ := s.GetTemplate("better")
+	control, _ := s.GetTemplate("control")
+	if !winner.IsDefault || control.IsDefault {
+		t.Errorf("Expected winner to become the default template: winner=%v control=%v", winner.IsDefault, control.IsDefault)
+	}
+}
+
+func TestExperimentFeeds(t *testing.T) {
+	s := newTestService(t)
+	createVariantTemplates(t, s, "a", "b")
+	ab := NewABTestService(s.db)
+
+	test, err := ab.CreateABTest(&CreateABTestRequest{
+		TestName:   "engagement",
+		Variants:   []Variant{{TemplateID: "a"}, {TemplateID: "b"}},
+		GoalMetric: GoalEngagement,
+	})
+	if err != nil {
+		t.Fatalf("CreateABTest failed: %v", err)
+	}
+
+	// 按对照组模板生成时为用户选择变体并记录调用
+	ctx := cost.WithAttribution(context.Background(), cost.Attribution{UserID: "u1"})
+	if _, err := s.Generate(ctx, &fakeGenerator{}, "a", map[string]interface{}{"topic": "春游"}, nil); err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	variant, err := ab.SelectTemplateForTest(test.ID, "u1")
+	if err != nil {
+		t.Fatalf("SelectTemplateForTest failed: %v", err)
+	}
+	var usage database.PromptTemplateUsage
+	s.db.First(&usage)
+	if usage.TemplateID != variant {
+		t.Errorf("Expected Generate to use variant %s, got %s", variant, usage.TemplateID)
+	}
+	if stats, _ := ab.GetABTestStats(test.ID); stats.TotalCalls != 1 {
+		t.Errorf("Expected Generate to record the test call, got %d", stats.TotalCalls)
+	}
+
+	if err := ab.RecordAuditResult(ctx, true); err != nil {
+		t.Fatalf("RecordAuditResult failed: %v", err)
+	}
+	if err := ab.RecordPublishedPost(ctx, "douyin", "p1"); err != nil {
+		t.Fatalf("RecordPublishedPost failed: %v", err)
+	}
+	other := cost.WithAttribution(context.Background(), cost.Attribution{UserID: "u2"})
+	if err := ab.RecordPublishedPost(other, "douyin", "p2"); err != nil {
+		t.Fatalf("RecordPublishedPost failed: %v", err)
+	}
+	// 同一内容多次采集只保留最新的互动率，未参与实验的内容忽略
+	for _, engagement := range []float64{0.1, 0.3} {
+		if err := ab.RecordPostEngagement("douyin", "p1", engagement); err != nil {
+			t.Fatalf("RecordPostEngagement failed: %v", err)
+		}
+	}
+	if err := ab.RecordPostEngagement("douyin", "p2", 0.5); err != nil {
+		t.Fatalf("RecordPostEngagement failed: %v", err)
+	}
+
+	var outcomes []database.PromptExperimentOutcome
+	s.db.Where("metric <> ?", string(GoalSuccess)).Order("metric").Find(&outcomes)
+	if len(outcomes) != 2 {
+		t.Fatalf("Expected audit and engagement outcomes, got %+v", outcomes)
+	}
+	if outcomes[0].Metric != string(GoalAuditPass) || outcomes[0].Value != 1 || outcomes[0].TemplateID != variant {
+		t.Errorf("Unexpected audit outcome: %+v", outcomes[0])
+	}
+	if outcomes[1].Metric != string(GoalEngagement) || outcomes[1].Value != 0.3 || outcomes[1].UnitID != "u1" {
+		t.Errorf("Unexpected engagement outcome: %+v", outcomes[1])
+	}
+}
+
+func TestAutoPromoteTestsOnce(t *testing.T) {
+	s := newTestService(t)
+	createVariantTemplates(t, s, "control", "variant")
+	ab := NewABTestService(s.db)
+
+	test, err := ab.CreateABTest(&CreateABTestRequest{
+		TestName:    "audit",
+		Variants:    []Variant{{TemplateID: "control"}, {TemplateID: "variant"}},
+		GoalMetric:  GoalAuditPass,
+		MinSamples:  10,
+		AutoPromote: true,
+	})
+	if err != nil {
+		t.Fatalf("CreateABTest failed: %v", err)
+	}
+
+	// 达到样本量时两组通过率相同，之后变体全部通过也不再判定
+	seen := make(map[string]int)
+	for i := 0; i < 200; i++ {
+		unit := fmt.Sprintf("session-%d", i)
+		templateID, err := ab.SelectTemplateForTest(test.ID, unit)
+		if err != nil {
+			t.Fatalf("SelectTemplateForTest failed: %v", err)
+		}
+		passed := float64(seen[templateID] % 2)
+		if i >= 40 && templateID == "variant" {
+			passed = 1
+		}
+		seen[templateID]++
+		if err := ab.RecordOutcome(test.ID, unit, GoalAuditPass, passed); err != nil {
+			t.Fatalf("RecordOutcome failed: %v", err)
+		}
+	}
+
+	updated, err := ab.GetABTest(test.ID)
+	if err != nil {
+		t.Fatalf("GetABTest failed: %v", err)
+	}
+	if updated.Status != "running" || updated.AutoPromote {
+		t.Errorf("Expected inconclusive test to be left for manual decision: status=%s auto_promote=%v", updated.Status, updated.AutoPromote)
+	}
+}
+
+func TestWelchTest(t *testing.T) {
+	control := newSample([]float64{3, 4, 5, 4, 3, 4, 5, 4})
+	variant := newSample([]float64{4, 5, 5, 4, 5, 5, 4, 5})
+	c := welchTest(control, variant, 0.05)
+	// 差值 0.625，标准误 sqrt(0.5714/8 + 0.2679/8) = 0.3238
+	if math.Abs(c.diff-0.625) > 1e-9 || math.Abs(c.pValue-0.0536) > 0.001 {
+		t.Errorf("Unexpected comparison: %+v", c)
+	}
+	if math.Abs(c.high-c.low-2*1.96*0.3238) > 0.01 {
+		t.Errorf("Unexpected interval: [%.3f, %.3f]", c.low, c.high)
+	}
+	if c := welchTest(newSample([]float64{1}), variant, 0.05); c.pValue != 1 {
+		t.Errorf("Expected insufficient samples to be inconclusive: %+v", c)
+	}
+}
@@ -0,0 +1,630 @@
+package prompt
+
+import "publisher-core/ai/provider"
+
+// 代码中按ID加载的模板，ID 保持稳定，内容可在模板管理中修改并保留版本
+const (
+	TemplatePipelineContentGeneration     = "pipeline-content-generation"
+	TemplatePipelineContentOptimization   = "pipeline-content-optimization"
+	TemplatePipelineQualityScoring        = "pipeline-quality-scoring"
+	TemplatePipelineTranscriptRewrite     = "pipeline-transcript-rewrite"
+	TemplatePipelineTrendAnalysis         = "pipeline-trend-analysis"
+	TemplatePipelineDataReport            = "pipeline-data-report"
+	TemplatePipelineOutlineGeneration     = "pipeline-outline-generation"
+	TemplatePipelineContentClustering     = "pipeline-content-clustering"
+	TemplatePipelineContentClassification = "pipeline-content-classification"
+	TemplatePipelineTitleGeneration       = "pipeline-title-generation"
+	TemplatePipelineDescription           = "pipeline-description-generation"
+	TemplatePipelineContentScoring        = "pipeline-content-scoring"
+	TemplateHotspotAdaptation             = "hotspot-content-adaptation"
+	TemplateVideoTranscriptOptimization   = "video-transcript-optimization"
+	TemplateVideoSummary                  = "video-transcript-summary"
+	TemplateVideoKeyPoints                = "video-key-points"
+	TemplateVideoTopics                   = "video-topic-extraction"
+	TemplateContentAudit                  = "content-audit"
+)
+
+// userMessage 单条用户消息的模板
+func userMessage(content string) []MessageTemplate {
+	return []MessageTemplate{{Role: provider.RoleUser, Content: content}}
+}
+
+// RegistryTemplates 流水线、热点适配和视频优化使用的模板
+// 数据库中没有对应记录时按此处的定义渲染，InitializeDefaultTemplates 会把它们写入数据库
+var RegistryTemplates = []DefaultTemplate{
+	{
+		TemplateID:  TemplatePipelineContentGeneration,
+		Name:        "流水线内容生成",
+		Type:        "pipeline_content_generation",
+		Category:    "pipeline",
+		Description: "流水线 ai_content_generator 步骤按主题生成内容",
+		Messages: userMessage(`请根据以下信息生成一篇高质量的内容：
+
+主题: {{.topic}}
+关键词: {{.keywords | join ", "}}
+目标受众: {{.target_audience}}
+
+要求:
+1. 内容要吸引人，有吸引力
+2. 结构清晰，逻辑连贯
+3. 符合目标受众的阅读习惯
+4. 包含适当的emoji表情
+5. 字数控制在500-1000字
+
+请直接生成内容，不要包含任何解释。`),
+		Parameters: &GenerationParameters{Model: "deepseek-chat", MaxTokens: 2000, Temperature: 0.7},
+		Variables: []VariableDefinition{
+			{Name: "topic", Type: "string", Required: true, Description: "内容主题"},
+			{Name: "keywords", Type: "array", Description: "关键词列表"},
+			{Name: "target_audience", Type: "string", Description: "目标受众"},
+		},
+		IsDefault: true,
+		Tags:      []string{"流水线", "内容生成"},
+	},
+	{
+		TemplateID:  TemplatePipelineContentOptimization,
+		Name:        "流水线内容优化",
+		Type:        "pipeline_content_optimization",
+		Category:    "pipeline",
+		Description: "流水线 content_optimizer 步骤修正和润色内容",
+		Messages: userMessage(`请优化以下内容：
+
+{{.content}}
+
+优化要求:
+{{if .check_spelling}}1. 检查并修正拼写错误
+{{end}}{{if .improve_readability}}2. 改善可读性和流畅度
+{{end}}
+请直接输出优化后的内容，不要包含任何解释。`),
+		Parameters: &GenerationParameters{Model: "deepseek-chat", MaxTokens: 2000, Temperature: 0.3},
+		Variables: []VariableDefinition{
+			{Name: "content", Type: "string", Required: true, Description: "待优化内容"},
+			{Name: "check_spelling", Type: "boolean", Description: "是否检查拼写"},
+			{Name: "improve_readability", Type: "boolean", Description: "是否改善可读性"},
+		},
+		IsDefault: true,
+		Tags:      []string{"流水线", "内容优化"},
+	},
+	{
+		TemplateID:  TemplatePipelineQualityScoring,
+		Name:        "流水线质量评分",
+		Type:        "pipeline_quality_scoring",
+		Category:    "pipeline",
+		Description: "流水线 quality_scorer 步骤对内容打分",
+		Messages: userMessage(`请对以下内容进行质量评分（0-1分）：
+
+{{.content}}
+
+评分维度:
+1. 内容质量（30%）
+2. 吸引力（25%）
+3. 可读性（25%）
+4. 完整性（20%）
+
+请以JSON格式返回评分结果，格式如下:
+{
+  "overall_score": 0.85,
+  "content_quality": 0.9,
+  "attractiveness": 0.85,
+  "readability": 0.8,
+  "completeness": 0.85,
+  "reasoning": "内容质量优秀，逻辑清晰，具有较强的吸引力"
+}`),
+		Parameters: &GenerationParameters{Model: "deepseek-chat", MaxTokens: 500, Temperature: 0.2},
+		Variables: []VariableDefinition{
+			{Name: "content", Type: "string", Required: true, Description: "待评分内容"},
+		},
+		IsDefault: true,
+		Tags:      []string{"流水线", "质量评分"},
+	},
+	{
+		TemplateID:  TemplatePipelineTranscriptRewrite,
+		Name:        "流水线转录改写",
+		Type:        "pipeline_transcript_rewrite",
+		Category:    "pipeline",
+		Description: "流水线 content_rewriter 步骤把转录文本改写为发布内容",
+		Messages: userMessage(`请将以下转录文本改写为适合发布的内容：
+
+{{.transcript}}
+
+改写要求:
+1. 风格: {{.style}}
+2. 字数限制: {{.max_length}}字以内
+3. 语言要生动有趣
+4. 保留核心信息
+5. 适当添加emoji表情
+
+请直接输出改写后的内容，不要包含任何解释。`),
+		Parameters: &GenerationParameters{Model: "deepseek-chat", MaxTokens: 1500, Temperature: 0.8},
+		Variables: []VariableDefinition{
+			{Name: "transcript", Type: "string", Required: true, Description: "转录文本"},
+			{Name: "style", Type: "string", Required: true, Default: "casual", Description: "改写风格"},
+			{Name: "max_length", Type: "number", Required: true, Default: "1000", Description: "字数上限"},
+		},
+		IsDefault: true,
+		Tags:      []string{"流水线", "内容改写"},
+	},
+	{
+		TemplateID:  TemplatePipelineTrendAnalysis,
+		Name:        "流水线趋势分析",
+		Type:        "pipeline_trend_analysis",
+		Category:    "pipeline",
+		Description: "流水线 trend_analyzer 步骤从热点标题中提取趋势",
+		Messages: userMessage(`请分析以下热点标题，提取关键趋势和关键词：
+
+{{.titles | join "\n"}}
+
+分析要求:
+1. 提取主要关键词（最多10个）
+2. 识别热门话题
+3. 分析趋势方向
+4. 给出推荐的主题
+
+请以JSON格式返回分析结果。`),
+		Parameters: &GenerationParameters{Model: "deepseek-chat", MaxTokens: 1000, Temperature: 0.3},
+		Variables: []VariableDefinition{
+			{Name: "titles", Type: "array", Required: true, Description: "热点标题列表"},
+		},
+		IsDefault: true,
+		Tags:      []string{"流水线", "趋势分析"},
+	},
+	{
+		TemplateID:  TemplatePipelineDataReport,
+		Name:        "流水线数据报告",
+		Type:        "pipeline_data_report",
+		Category:    "pipeline",
+		Description: "流水线 data_analyzer 步骤根据数据生成性能报告",
+		Messages: userMessage(`请分析以下数据，生成性能报告：
+
+{{.data | json}}
+
+报告要求:
+1. 总结关键指标
+2. 识别趋势和模式
+3. 提供优化建议
+4. 给出行动建议
+
+请以Markdown格式输出报告。`),
+		Parameters: &GenerationParameters{Model: "deepseek-chat", MaxTokens: 2000, Temperature: 0.4},
+		Variables: []VariableDefinition{
+			{Name: "data", Type: "object", Required: true, Description: "待分析数据"},
+		},
+		IsDefault: true,
+		Tags:      []string{"流水线", "数据分析"},
+	},
+	{
+		TemplateID:  TemplatePipelineOutlineGeneration,
+		Name:        "流水线大纲生成",
+		Type:        "pipeline_outline_generation",
+		Category:    "pipeline",
+		Description: "按主题生成内容大纲",
+		Messages: userMessage(`请为以下主题生成一个详细的内容大纲：
+
+主题: {{.topic}}
+目标字数: {{.target_length}}字
+风格: {{.style}}
+
+要求:
+1. 生成3-5个主要章节
+2. 每个章节包含2-4个子要点
+3. 标注每个章节的预估字数
+4. 确保逻辑连贯、层次分明
+
+请以JSON格式返回大纲，格式如下:
+{
+  "title": "文章标题",
+  "sections": [
+    {
+      "title": "章节标题",
+      "estimated_words": 200,
+      "points": ["要点1", "要点2"]
+    }
+  ],
+  "total_estimated_words": 1000,
+  "keywords": ["关键词1", "关键词2"]
+}`),
+		Parameters: &GenerationParameters{Model: "deepseek-chat", MaxTokens: 1500, Temperature: 0.7},
+		Variables: []VariableDefinition{
+			{Name: "topic", Type: "string", Required: true, Description: "内容主题"},
+			{Name: "target_length", Type: "number", Required: true, Default: "1000", Description: "目标字数"},
+			{Name: "style", Type: "string", Required: true, Default: "professional", Description: "写作风格"},
+		},
+		IsDefault: true,
+		Tags:      []string{"流水线", "大纲"},
+	},
+	{
+		TemplateID:  TemplatePipelineContentClustering,
+		Name:        "流水线内容聚类",
+		Type:        "pipeline_content_clustering",
+		Category:    "pipeline",
+		Description: "把多条内容按主题聚类",
+		Messages: userMessage(`请将以下内容进行聚类分析，分成{{.cluster_count}}个类别：
+
+内容列表:
+{{.contents | join "\n---\n"}}
+
+要求:
+1. 根据内容主题进行分类
+2. 每个类别给出一个描述性名称
+3. 列出每个类别包含的内容索引
+4. 提取每个类别的关键词
+
+请以JSON格式返回聚类结果:
+{
+  "clusters": [
+    {
+      "name": "类别名称",
+      "description": "类别描述",
+      "indices": [0, 2, 5],
+      "keywords": ["关键词1", "关键词2"]
+    }
+  ]
+}`),
+		Parameters: &GenerationParameters{Model: "deepseek-chat", MaxTokens: 2000, Temperature: 0.3},
+		Variables: []VariableDefinition{
+			{Name: "contents", Type: "array", Required: true, Description: "内容列表，索引从 0 开始"},
+			{Name: "cluster_count", Type: "number", Required: true, Default: "5", Description: "类别数"},
+		},
+		IsDefault: true,
+		Tags:      []string{"流水线", "聚类"},
+	},
+	{
+		TemplateID:  TemplatePipelineContentClassification,
+		Name:        "流水线内容分类",
+		Type:        "pipeline_content_classification",
+		Category:    "pipeline",
+		Description: "在给定类别中为内容分类并判断情感",
+		Messages: userMessage(`请对以下内容进行分类：
+
+内容:
+{{.content}}
+
+可选类别: {{.categories | join ", "}}
+
+要求:
+1. 选择最匹配的类别
+2. 给出置信度（0-1）
+3. 提取内容的关键词
+4. 判断内容情感倾向
+
+请以JSON格式返回分类结果:
+{
+  "category": "科技",
+  "confidence": 0.95,
+  "keywords": ["人工智能", "机器学习"],
+  "sentiment": "positive",
+  "sentiment_score": 0.8
+}`),
+		Parameters: &GenerationParameters{Model: "deepseek-chat", MaxTokens: 500, Temperature: 0.2},
+		Variables: []VariableDefinition{
+			{Name: "content", Type: "string", Required: true, Description: "待分类内容"},
+			{Name: "categories", Type: "array", Required: true, Description: "可选类别"},
+		},
+		IsDefault: true,
+		Tags:      []string{"流水线", "分类"},
+	},
+	{
+		TemplateID:  TemplatePipelineTitleGeneration,
+		Name:        "流水线标题生成",
+		Type:        "pipeline_title_generation",
+		Category:    "pipeline",
+		Description: "为内容生成多个候选标题",
+		Messages: userMessage(`请为以下内容生成{{.count}}个吸引人的标题：
+
+内容:
+{{.content}}
+
+要求:
+1. 标题长度不超过{{.max_length}}字
+2. 标题要有吸引力
+3. 适合社交媒体传播
+4. 包含适当的emoji表情
+5. 风格多样化
+
+请以JSON格式返回标题列表:
+{
+  "titles": [
+    {
+      "title": "标题1",
+      "style": "question",
+      "score": 0.95
+    }
+  ]
+}`),
+		Parameters: &GenerationParameters{Model: "deepseek-chat", MaxTokens: 1000, Temperature: 0.8},
+		Variables: []VariableDefinition{
+			{Name: "content", Type: "string", Required: true, Description: "内容"},
+			{Name: "count", Type: "number", Required: true, Default: "5", Description: "标题数量"},
+			{Name: "max_length", Type: "number", Required: true, Default: "30", Description: "标题字数上限"},
+		},
+		IsDefault: true,
+		Tags:      []string{"流水线", "标题"},
+	},
+	{
+		TemplateID:  TemplatePipelineDescription,
+		Name:        "流水线描述生成",
+		Type:        "pipeline_description_generation",
+		Category:    "pipeline",
+		Description: "为内容生成简洁描述",
+		Messages: userMessage(`请为以下内容生成一个简洁的描述：
+
+内容:
+{{.content}}
+
+要求:
+1. 描述长度不超过{{.max_length}}字
+2. 概括内容核心要点
+3. 吸引读者阅读
+{{if .include_keywords}}4. 包含关键词
+{{end}}
+请直接输出描述内容。`),
+		Parameters: &GenerationParameters{Model: "deepseek-chat", MaxTokens: 300, Temperature: 0.6},
+		Variables: []VariableDefinition{
+			{Name: "content", Type: "string", Required: true, Description: "内容"},
+			{Name: "max_length", Type: "number", Required: true, Default: "200", Description: "描述字数上限"},
+			{Name: "include_keywords", Type: "boolean", Description: "是否包含关键词"},
+		},
+		IsDefault: true,
+		Tags:      []string{"流水线", "描述"},
+	},
+	{
+		TemplateID:  TemplatePipelineContentScoring,
+		Name:        "流水线内容筛选评分",
+		Type:        "pipeline_content_scoring",
+		Category:    "pipeline",
+		Description: "按指定维度为单条内容评分，用于筛选",
+		Messages: userMessage(`请对以下内容进行评分：
+
+内容:
+{{.content}}
+
+评分维度: {{.criteria | join ", "}}
+
+请以JSON格式返回评分结果:
+{
+  "overall_score": 0.85,
+  "quality": 0.9,
+  "relevance": 0.85,
+  "originality": 0.8,
+  "reasoning": "评分理由"
+}`),
+		Parameters: &GenerationParameters{Model: "deepseek-chat", MaxTokens: 300, Temperature: 0.2},
+		Variables: []VariableDefinition{
+			{Name: "content", Type: "string", Required: true, Description: "待评分内容"},
+			{Name: "criteria", Type: "array", Required: true, Default: "quality,relevance,originality", Description: "评分维度"},
+		},
+		IsDefault: true,
+		Tags:      []string{"流水线", "筛选"},
+	},
+	{
+		TemplateID:  TemplateHotspotAdaptation,
+		Name:        "热点内容适配",
+		Type:        "hotspot_adaptation",
+		Category:    "hotspot",
+		Description: "基于热点话题为目标平台创作内容，输出结构化 JSON",
+		Messages: userMessage(`请基于以下热点话题，为{{.platform}}平台创作内容：
+
+热点标题：{{.title}}
+{{if .description}}热点描述：{{.description}}
+{{end}}
+平台要求：
+{{if eq .platform "douyin"}}- 标题：30字以内，吸引眼球
+- 内容：轻松幽默，适合短视频
+- 风格：口语化，有互动性
+{{else if eq .platform "xiaohongshu"}}- 标题：20字以内，精致优雅
+- 内容：实用分享，有情感共鸣
+- 风格：图文并茂，有生活气息
+{{else if eq .platform "toutiao"}}- 标题：30字以内，专业深度
+- 内容：信息丰富，有观点
+- 风格：专业严谨，有深度
+{{end}}{{if .style}}
+内容风格：{{.style}}
+{{end}}{{if .keywords}}
+请包含以下关键词：{{.keywords | join ", "}}
+{{end}}{{if .custom_prompt}}
+额外要求：{{.custom_prompt}}
+{{end}}
+请以 JSON 格式输出，包含标题(title)、正文(content)、摘要(summary)、关键词(keywords)和标签(tags)。
+`),
+		Parameters: &GenerationParameters{Temperature: 0.8},
+		Variables: []VariableDefinition{
+			{Name: "platform", Type: "string", Required: true, Description: "目标平台"},
+			{Name: "title", Type: "string", Required: true, Description: "热点标题"},
+			{Name: "description", Type: "string", Description: "热点描述"},
+			{Name: "style", Type: "string", Description: "内容风格"},
+			{Name: "keywords", Type: "array", Description: "需要包含的关键词"},
+			{Name: "custom_prompt", Type: "string", Description: "额外要求"},
+		},
+		IsDefault: true,
+		Tags:      []string{"热点", "内容适配"},
+	},
+	{
+		TemplateID:  TemplateVideoTranscriptOptimization,
+		Name:        "视频转录优化",
+		Type:        "video_transcript_optimization",
+		Category:    "video",
+		Description: "修正转录文本的错别字、断句和标点",
+		Messages: userMessage(`请优化以下{{.language}}转录文本：
+1. 修正错别字和错误词汇
+2. 补全不完整的句子
+3. 按语义合理分段
+{{if .add_punctuation}}4. 添加正确的标点符号
+{{end}}{{if .fix_grammar}}5. 修正语法错误
+{{end}}{{if .remove_filler}}6. 移除口语填充词（如嗯、啊、那个等）
+{{end}}7. 保持原意不变，不要添加新内容
+8. 直接输出优化后的文本，不要添加任何解释
+
+转录文本：
+{{.text}}`),
+		Parameters: &GenerationParameters{Temperature: 0.3},
+		Variables: []VariableDefinition{
+			{Name: "text", Type: "string", Required: true, Description: "转录文本"},
+			{Name: "language", Type: "string", Required: true, Default: "中文", Description: "文本语言"},
+			{Name: "add_punctuation", Type: "boolean", Description: "添加标点"},
+			{Name: "fix_grammar", Type: "boolean", Description: "修正语法"},
+			{Name: "remove_filler", Type: "boolean", Description: "移除填充词"},
+		},
+		IsDefault: true,
+		Tags:      []string{"视频", "转录优化"},
+	},
+	{
+		TemplateID:  TemplateVideoSummary,
+		Name:        "视频转录摘要",
+		Type:        "video_transcript_summary",
+		Category:    "video",
+		Description: "为转录文本生成摘要",
+		Messages: userMessage(`请用{{.language}}为以下内容生成一个简洁的摘要（100-200字）：
+
+{{.text}}
+
+直接输出摘要内容，不要添加任何解释。`),
+		Parameters: &GenerationParameters{Temperature: 0.5},
+		Variables: []VariableDefinition{
+			{Name: "text", Type: "string", Required: true, Description: "转录文本"},
+			{Name: "language", Type: "string", Required: true, Default: "中文", Description: "输出语言"},
+		},
+		IsDefault: true,
+		Tags:      []string{"视频", "摘要"},
+	},
+	{
+		TemplateID:  TemplateVideoKeyPoints,
+		Name:        "视频关键要点",
+		Type:        "video_key_points",
+		Category:    "video",
+		Description: "从转录文本中提取关键要点，每行一个",
+		Messages: userMessage(`请从以下内容中提取5-10个关键要点，用{{.language}}回答：
+
+{{.text}}
+
+请按以下格式输出，每行一个要点：
+- 要点1
+- 要点2
+...`),
+		Parameters: &GenerationParameters{Temperature: 0.3},
+		Variables: []VariableDefinition{
+			{Name: "text", Type: "string", Required: true, Description: "转录文本"},
+			{Name: "language", Type: "string", Required: true, Default: "中文", Description: "输出语言"},
+		},
+		IsDefault: true,
+		Tags:      []string{"视频", "要点"},
+	},
+	{
+		TemplateID:  TemplateVideoTopics,
+		Name:        "视频主题提取",
+		Type:        "video_topic_extraction",
+		Category:    "video",
+		Description: "从转录文本中提取主题标签，逗号分隔",
+		Messages: userMessage(`请从以下内容中提取3-5个主要主题/话题标签，用{{.language}}回答：
+
+{{.text}}
+
+请直接输出主题标签，用逗号分隔，例如：技术,创新,人工智能`),
+		Parameters: &GenerationParameters{Temperature: 0.3},
+		Variables: []VariableDefinition{
+			{Name: "text", Type: "string", Required: true, Description: "转录文本"},
+			{Name: "language", Type: "string", Required: true, Default: "中文", Description: "输出语言"},
+		},
+		IsDefault: true,
+		Tags:      []string{"视频", "主题"},
+	},
+	{
+		TemplateID:  TemplateContentAudit,
+		Name:        "内容合规审核",
+		Type:        "content_audit",
+		Category:    "compliance",
+		Description: "合规检查的 AI 审核，输出带原文片段的问题列表",
+		Messages: []MessageTemplate{
+			{Role: provider.RoleSystem, Content: "You are a content review expert skilled in identifying sensitive information, violations, and potential risks in content."},
+			{Role: provider.RoleUser, Content: `请审核以下准备在国内内容平台公开发布的内容：
+
+{{.content}}
+
+检查以下方面：
+1. 违法违规、涉黄涉赌涉毒、暴力、歧视等敏感内容，包括谐音、拼音、拆字等变体写法
+2. 违反广告法的绝对化用语和虚假宣传
+3. 引导站外交易或导流的表述
+4. 明显的事实错误和不当表达
+
+要求：
+1. findings 中每一项的 text 必须是原文中的原样片段，尽量简短
+2. severity 取值 low、medium、high、critical，critical 表示违法内容
+3. 没有问题时 passed 为 true，findings 为空数组
+`},
+		},
+		Parameters: &GenerationParameters{Temperature: 0.1},
+		Variables: []VariableDefinition{
+			{Name: "content", Type: "string", Required: true, Description: "待审核内容"},
+		},
+		IsDefault: true,
+		Tags:      []string{"合规", "审核"},
+	},
+}
+
+// ContentTemplates 填空式内容模板，原先由 ai.TemplateManager 内置
+var ContentTemplates = []DefaultTemplate{
+	{
+		TemplateID:  "news-hotspot",
+		Name:        "Hotspot News Commentary",
+		Type:        "content_template",
+		Category:    "news",
+		Description: "Generate commentary content for hotspot events",
+		Content:     "[{{.title}}] {{.event}}\n\n{{.comment}}\n\n#hotspot #{{.tags}}",
+		Variables: []VariableDefinition{
+			{Name: "title", Type: "string", Required: true, Description: "Title"},
+			{Name: "event", Type: "string", Required: true, Description: "Event description"},
+			{Name: "comment", Type: "string", Required: true, Description: "Comment content"},
+			{Name: "tags", Type: "string", Description: "Topic tags"},
+		},
+		Tags: []string{"hotspot", "news", "commentary"},
+	},
+	{
+		TemplateID:  "tutorial-guide",
+		Name:        "Tutorial Guide",
+		Type:        "content_template",
+		Category:    "tutorial",
+		Description: "Generate tutorial content",
+		Content:     "[{{.title}}]\n\n{{.intro}}\n\nSteps: {{.steps}}\n\nTips: {{.tips}}\n\n#{{.tags}}",
+		Variables: []VariableDefinition{
+			{Name: "title", Type: "string", Required: true, Description: "Tutorial title"},
+			{Name: "intro", Type: "string", Required: true, Description: "Introduction"},
+			{Name: "steps", Type: "string", Required: true, Description: "Step instructions"},
+			{Name: "tips", Type: "string", Description: "Tips"},
+			{Name: "tags", Type: "string", Description: "Topic tags"},
+		},
+		Tags: []string{"tutorial", "guide", "tips"},
+	},
+	{
+		TemplateID:  "lifestyle-share",
+		Name:        "Lifestyle Sharing",
+		Type:        "content_template",
+		Category:    "lifestyle",
+		Description: "Lifestyle content sharing template",
+		Content:     "[{{.title}}]\n\n{{.content}}\n\nThoughts: {{.thoughts}}\n\nLocation: {{.location}}\n\n#{{.tags}}",
+		Variables: []VariableDefinition{
+			{Name: "title", Type: "string", Required: true, Description: "Title"},
+			{Name: "content", Type: "string", Required: true, Description: "Content"},
+			{Name: "thoughts", Type: "string", Description: "Thoughts"},
+			{Name: "location", Type: "string", Description: "Location"},
+			{Name: "tags", Type: "string", Description: "Topic tags"},
+		},
+		Tags: []string{"lifestyle", "sharing", "daily"},
+	},
+	{
+		TemplateID:  "entertainment-review",
+		Name:        "Entertainment Review",
+		Type:        "content_template",
+		Category:    "entertainment",
+		Description: "Entertainment content review template",
+		Content:     "[{{.title}}]\n\nOverview: {{.overview}}\n\nPros: {{.pros}}\n\nCons: {{.cons}}\n\nPrice: {{.price}}\n\nSummary: {{.summary}}\n\n#{{.tags}}",
+		Variables: []VariableDefinition{
+			{Name: "title", Type: "string", Required: true, Description: "Review title"},
+			{Name: "overview", Type: "string", Required: true, Description: "Overview"},
+			{Name: "pros", Type: "string", Required: true, Description: "Pros"},
+			{Name: "cons", Type: "string", Required: true, Description: "Cons"},
+			{Name: "price", Type: "string", Description: "Price"},
+			{Name: "summary", Type: "string", Required: true, Description: "Summary"},
+			{Name: "tags", Type: "string", Description: "Topic tags"},
+		},
+		Tags: []string{"review", "entertainment", "recommendation"},
+	},
+}
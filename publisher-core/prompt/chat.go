@@ -0,0 +1,244 @@
+package prompt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"publisher-core/ai/provider"
+	"publisher-core/database"
+)
+
+// 模板格式
+const (
+	FormatText = "text" // Content 为单条用户提示词
+	FormatChat = "chat" // Messages 为按顺序排列的多条消息
+)
+
+// MessageTemplate 消息模板，Content 使用与文本模板相同的语法
+// 渲染后内容为空的消息会被跳过，可用 {{if}} 控制是否发送少样本示例
+type MessageTemplate struct {
+	Role    provider.Role `json:"role"`
+	Content string        `json:"content"`
+}
+
+// GenerationParameters 模板的默认生成参数，调用方可在渲染结果上覆盖
+type GenerationParameters struct {
+	Model       string   `json:"model,omitempty"`
+	Temperature float64  `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	TopP        float64  `json:"top_p,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+	// ResponseFormat 结构化输出要求，json_schema 时可附带 schema
+	ResponseFormat *provider.ResponseFormat `json:"response_format,omitempty"`
+}
+
+// validate 检查参数范围
+func (p *GenerationParameters) validate() error {
+	if p.Temperature < 0 || p.Temperature > 2 {
+		return errors.New("temperature 必须在 0-2 之间")
+	}
+	if p.TopP < 0 || p.TopP > 1 {
+		return errors.New("top_p 必须在 0-1 之间")
+	}
+	if p.MaxTokens < 0 {
+		return errors.New("max_tokens 不能为负数")
+	}
+	if f := p.ResponseFormat; f != nil {
+		switch f.Type {
+		case "", provider.ResponseFormatText, provider.ResponseFormatJSONObject:
+		case provider.ResponseFormatJSONSchema:
+			if f.Schema == nil {
+				return errors.New("json_schema 输出格式缺少 schema")
+			}
+		default:
+			return fmt.Errorf("不支持的输出格式: %s", f.Type)
+		}
+	}
+	return nil
+}
+
+// templateDefinition 解析后的模板
+type templateDefinition struct {
+	id         string
+	version    int
+	format     string
+	content    string
+	messages   []MessageTemplate
+	parameters *GenerationParameters
+	variables  []VariableDefinition
+}
+
+// parseDefinition 解析模板记录中 JSON 格式的字段
+func parseDefinition(t *database.PromptTemplate) (*templateDefinition, error) {
+	def := &templateDefinition{
+		id:         t.TemplateID,
+		version:    t.Version,
+		format:     t.Format,
+		content:    t.Content,
+		parameters: &GenerationParameters{},
+	}
+	if def.format == "" {
+		def.format = FormatText
+	}
+	if t.Variables != "" {
+		if err := json.Unmarshal([]byte(t.Variables), &def.variables); err != nil {
+			return nil, fmt.Errorf("解析变量定义失败: %w", err)
+		}
+	}
+	if t.Messages != "" {
+		if err := json.Unmarshal([]byte(t.Messages), &def.messages); err != nil {
+			return nil, fmt.Errorf("解析消息模板失败: %w", err)
+		}
+	}
+	if t.Parameters != "" {
+		if err := json.Unmarshal([]byte(t.Parameters), def.parameters); err != nil {
+			return nil, fmt.Errorf("解析生成参数失败: %w", err)
+		}
+	}
+	return def, nil
+}
+
+// encodeChat 序列化消息模板和生成参数，并确定模板格式
+func encodeChat(messages []MessageTemplate, parameters *GenerationParameters) (format, messagesJSON, parametersJSON string, err error) {
+	format = FormatText
+	if len(messages) > 0 {
+		format = FormatChat
+		data, err := json.Marshal(messages)
+		if err != nil {
+			return "", "", "", fmt.Errorf("序列化消息模板失败: %w", err)
+		}
+		messagesJSON = string(data)
+	}
+	if parameters != nil {
+		data, err := json.Marshal(parameters)
+		if err != nil {
+			return "", "", "", fmt.Errorf("序列化生成参数失败: %w", err)
+		}
+		parametersJSON = string(data)
+	}
+	return format, messagesJSON, parametersJSON, nil
+}
+
+// validateChat 检查消息模板和生成参数，保存前调用
+func (s *Service) validateChat(templateID string, messages []MessageTemplate, parameters *GenerationParameters) error {
+	if err := s.validateMessages(templateID, messages); err != nil {
+		return err
+	}
+	if parameters != nil {
+		if err := parameters.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateMessages 检查消息模板的角色和语法
+func (s *Service) validateMessages(templateID string, messages []MessageTemplate) error {
+	for i, m := range messages {
+		switch m.Role {
+		case provider.RoleSystem, provider.RoleUser, provider.RoleAssistant:
+		default:
+			return fmt.Errorf("第 %d 条消息的角色 %q 不受支持", i+1, m.Role)
+		}
+		if strings.TrimSpace(m.Content) == "" {
+			return fmt.Errorf("第 %d 条消息内容为空", i+1)
+		}
+		if _, err := compileTemplate(messageName(templateID, i), m.Content, s.loadPartial); err != nil {
+			return fmt.Errorf("第 %d 条消息: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// messageName 消息模板在解析时使用的名称
+func messageName(templateID string, index int) string {
+	return fmt.Sprintf("%s#%d", templateID, index+1)
+}
+
+// RenderMessages 渲染模板为消息列表
+// chat 格式按顺序渲染每条消息并跳过内容为空的消息，text 格式渲染为一条用户消息
+func (s *Service) RenderMessages(templateID string, variables map[string]interface{}) ([]provider.Message, error) {
+	def, err := s.resolveDefinition(templateID, "")
+	if err != nil {
+		return nil, err
+	}
+	return s.renderMessages(def, variables)
+}
+
+// renderMessages 渲染已解析的模板
+func (s *Service) renderMessages(def *templateDefinition, variables map[string]interface{}) ([]provider.Message, error) {
+	data, err := prepareVariables(def.variables, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	if def.format != FormatChat {
+		tmpl, err := compileTemplate(def.id, def.content, s.loadPartial)
+		if err != nil {
+			return nil, err
+		}
+		content, err := execute(tmpl, data)
+		if err != nil {
+			return nil, err
+		}
+		return []provider.Message{{Role: provider.RoleUser, Content: content}}, nil
+	}
+
+	messages := make([]provider.Message, 0, len(def.messages))
+	for i, m := range def.messages {
+		tmpl, err := compileTemplate(messageName(def.id, i), m.Content, s.loadPartial)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 条消息: %w", i+1, err)
+		}
+		content, err := execute(tmpl, data)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 条消息: %w", i+1, err)
+		}
+		if strings.TrimSpace(content) == "" {
+			continue
+		}
+		messages = append(messages, provider.Message{Role: m.Role, Content: content})
+	}
+	if len(messages) == 0 {
+		return nil, errors.New("渲染后没有可发送的消息")
+	}
+	return messages, nil
+}
+
+// RenderToGenerateOptions 渲染模板并附带模板的默认生成参数，返回可直接发送的请求
+// 模板正在灰度发布时按灰度比例随机选择版本
+func (s *Service) RenderToGenerateOptions(templateID string, variables map[string]interface{}) (*provider.GenerateOptions, error) {
+	def, err := s.resolveDefinition(templateID, "")
+	if err != nil {
+		return nil, err
+	}
+	return s.generateOptions(def, variables)
+}
+
+// generateOptions 渲染模板定义为生成请求
+func (s *Service) generateOptions(def *templateDefinition, variables map[string]interface{}) (*provider.GenerateOptions, error) {
+	messages, err := s.renderMessages(def, variables)
+	if err != nil {
+		return nil, err
+	}
+
+	params := def.parameters
+	opts := &provider.GenerateOptions{
+		Model:       params.Model,
+		Messages:    messages,
+		Temperature: params.Temperature,
+		MaxTokens:   params.MaxTokens,
+		TopP:        params.TopP,
+		Stop:        params.Stop,
+	}
+	if params.ResponseFormat != nil {
+		format := *params.ResponseFormat
+		if format.Name == "" {
+			format.Name = strings.NewReplacer("-", "_", ".", "_").Replace(def.id)
+		}
+		opts.ResponseFormat = &format
+	}
+	return opts, nil
+}
@@ -0,0 +1,108 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"publisher-core/ai/provider"
+)
+
+func TestRenderToGenerateOptions(t *testing.T) {
+	s := newTestService(t)
+	req := &CreateTemplateRequest{
+		TemplateID: "title-chat",
+		Name:       "标题",
+		Type:       "title_generation",
+		Messages: []MessageTemplate{
+			{Role: provider.RoleSystem, Content: `你是{{.platform}}平台的标题编辑，只输出 JSON。`},
+			{Role: provider.RoleUser, Content: `{{if .examples}}内容：AI 绘画走红{{end}}`},
+			{Role: provider.RoleAssistant, Content: `{{if .examples}}{"titles": ["AI 绘画为何突然火了"]}{{end}}`},
+			{Role: provider.RoleUser, Content: `内容：{{.content}}`},
+		},
+		Variables: []VariableDefinition{
+			{Name: "platform", Type: "string", Default: "抖音"},
+			{Name: "content", Type: "string", Required: true},
+			{Name: "examples", Type: "boolean"},
+		},
+		Parameters: &GenerationParameters{
+			Model:       "deepseek-chat",
+			Temperature: 0.3,
+			MaxTokens:   200,
+			ResponseFormat: &provider.ResponseFormat{
+				Type:   provider.ResponseFormatJSONSchema,
+				Schema: &provider.JSONSchema{Type: "object", Required: []string{"titles"}},
+			},
+		},
+	}
+	template, err := s.CreateTemplate(req)
+	if err != nil {
+		t.Fatalf("CreateTemplate failed: %v", err)
+	}
+	if template.Format != FormatChat {
+		t.Errorf("Expected chat format, got %q", template.Format)
+	}
+
+	opts, err := s.RenderToGenerateOptions("title-chat", map[string]interface{}{"content": "新能源车降价", "examples": true})
+	if err != nil {
+		t.Fatalf("RenderToGenerateOptions failed: %v", err)
+	}
+	if len(opts.Messages) != 4 || opts.Messages[0].Content != "你是抖音平台的标题编辑，只输出 JSON。" ||
+		opts.Messages[2].Role != provider.RoleAssistant || opts.Messages[3].Content != "内容：新能源车降价" {
+		t.Errorf("Unexpected messages: %+v", opts.Messages)
+	}
+	if opts.Model != "deepseek-chat" || opts.Temperature != 0.3 || opts.MaxTokens != 200 ||
+		opts.ResponseFormat == nil || opts.ResponseFormat.Name != "title_chat" {
+		t.Errorf("Unexpected parameters: %+v", opts)
+	}
+
+	// 少样本示例渲染为空时跳过
+	messages, err := s.RenderMessages("title-chat", map[string]interface{}{"content": "新能源车降价"})
+	if err != nil {
+		t.Fatalf("RenderMessages failed: %v", err)
+	}
+	if len(messages) != 2 || messages[1].Role != provider.RoleUser {
+		t.Errorf("Expected few-shot examples to be skipped: %+v", messages)
+	}
+	if _, err := s.RenderTemplate("title-chat", map[string]interface{}{"content": "x"}); err == nil {
+		t.Error("Expected RenderTemplate to reject chat templates")
+	}
+
+	// 文本模板渲染为一条用户消息
+	if _, err := s.CreateTemplate(&CreateTemplateRequest{TemplateID: "plain", Name: "plain", Content: `写{{.topic}}`}); err != nil {
+		t.Fatalf("CreateTemplate plain failed: %v", err)
+	}
+	opts, err = s.RenderToGenerateOptions("plain", map[string]interface{}{"topic": "AI"})
+	if err != nil || len(opts.Messages) != 1 || opts.Messages[0].Content != "写AI" || opts.Model != "" {
+		t.Errorf("Unexpected text rendering: %+v, %v", opts, err)
+	}
+
+	// 更新为文本格式后可以恢复到 chat 版本
+	if _, err := s.UpdateTemplate("title-chat", &UpdateTemplateRequest{Name: "标题", Content: `标题：{{.content}}`, IsActive: true}); err != nil {
+		t.Fatalf("UpdateTemplate failed: %v", err)
+	}
+	restored, err := s.RestoreTemplateVersion("title-chat", 1)
+	if err != nil {
+		t.Fatalf("RestoreTemplateVersion failed: %v", err)
+	}
+	if restored.Format != FormatChat || restored.Version != 3 || !strings.Contains(restored.Parameters, "deepseek-chat") {
+		t.Errorf("Unexpected restored template: %+v", restored)
+	}
+}
+
+func TestChatTemplateValidation(t *testing.T) {
+	s := newTestService(t)
+	cases := map[string]*CreateTemplateRequest{
+		"role":    {Messages: []MessageTemplate{{Role: "tool", Content: "x"}}},
+		"empty":   {Messages: []MessageTemplate{{Role: provider.RoleUser, Content: " "}}},
+		"syntax":  {Messages: []MessageTemplate{{Role: provider.RoleUser, Content: "{{if .x}}"}}},
+		"temp":    {Content: "x", Parameters: &GenerationParameters{Temperature: 3}},
+		"schema":  {Content: "x", Parameters: &GenerationParameters{ResponseFormat: &provider.ResponseFormat{Type: provider.ResponseFormatJSONSchema}}},
+		"unknown": {Content: "x", Parameters: &GenerationParameters{ResponseFormat: &provider.ResponseFormat{Type: "xml"}}},
+	}
+	for id, req := range cases {
+		req.TemplateID, req.Name = id, id
+		if _, err := s.CreateTemplate(req); err == nil {
+			t.Errorf("Expected template %q to be rejected", id)
+		}
+	}
+}
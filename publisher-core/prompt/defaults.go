@@ -9,19 +9,24 @@ import (
 	"gorm.io/gorm"
 )
 
-// DefaultPromptTemplates 默认提示词模板
-var DefaultPromptTemplates = []struct {
+// DefaultTemplate 内置模板定义
+type DefaultTemplate struct {
 	TemplateID  string
 	Name        string
 	Type        string
 	Category    string
 	Description string
 	Content     string
+	Messages    []MessageTemplate
+	Parameters  *GenerationParameters
 	Variables   []VariableDefinition
 	IsDefault   bool
 	IsSystem    bool
 	Tags        []string
-}{
+}
+
+// DefaultPromptTemplates 默认提示词模板
+var DefaultPromptTemplates = []DefaultTemplate{
 	{
 		TemplateID:  "content-generation-v1",
 		Name:        "内容生成模板",
@@ -30,22 +35,22 @@ var DefaultPromptTemplates = []struct {
 		Description: "基于主题和关键词生成高质量内容",
 		Content: `请根据以下信息生成一篇高质量的内容：
 
-主题：{{topic}}
-关键词：{{keywords}}
-目标平台：{{platform}}
-内容风格：{{style}}
+主题：{{.topic}}
+{{if .keywords}}关键词：{{.keywords | join "、"}}
+{{end}}目标平台：{{.platform}}
+内容风格：{{.style}}
 
 要求：
 1. 标题要吸引人，符合平台调性
 2. 内容要有价值，解决用户痛点
 3. 结构清晰，易于阅读
 4. 适当使用emoji增加趣味性
-5. 字数控制在{{word_count}}字左右
+5. 字数控制在{{.word_count}}字左右
 
 请生成完整的内容，包括标题和正文。`,
 		Variables: []VariableDefinition{
 			{Name: "topic", Type: "string", Required: true, Description: "内容主题"},
-			{Name: "keywords", Type: "string", Required: true, Description: "关键词列表"},
+			{Name: "keywords", Type: "array", Required: false, Description: "关键词列表，可传数组或以逗号分隔的字符串"},
 			{Name: "platform", Type: "string", Required: true, Default: "抖音", Description: "目标平台"},
 			{Name: "style", Type: "string", Required: false, Default: "轻松幽默", Description: "内容风格"},
 			{Name: "word_count", Type: "number", Required: false, Default: "500", Description: "字数要求"},
@@ -63,14 +68,14 @@ var DefaultPromptTemplates = []struct {
 		Content: `请改写以下内容，要求：
 
 原始内容：
-{{original_content}}
+{{.original_content}}
 
 改写要求：
 1. 保持原文核心意思不变
 2. 改善语言表达，使其更加流畅
 3. 优化结构，提升可读性
-4. 改写风格：{{rewrite_style}}
-5. 目标平台：{{platform}}
+4. 改写风格：{{.rewrite_style}}
+5. 目标平台：{{.platform}}
 
 请提供改写后的完整内容。`,
 		Variables: []VariableDefinition{
@@ -90,10 +95,10 @@ var DefaultPromptTemplates = []struct {
 		Description: "分析热点话题，提取关键信息和趋势",
 		Content: `请分析以下热点话题：
 
-热点标题：{{hotspot_title}}
-热点描述：{{hotspot_description}}
-热度指数：{{heat_index}}
-来源平台：{{source_platform}}
+热点标题：{{.hotspot_title}}
+热点描述：{{.hotspot_description}}
+热度指数：{{.heat_index}}
+来源平台：{{.source_platform}}
 
 分析要求：
 1. 提取核心关键词（至少5个）
@@ -122,10 +127,10 @@ var DefaultPromptTemplates = []struct {
 		Content: `请优化以下视频转录文本：
 
 原始转录：
-{{transcription}}
+{{.transcription}}
 
-视频标题：{{video_title}}
-视频时长：{{duration}}
+视频标题：{{.video_title}}
+视频时长：{{.duration}}
 
 优化要求：
 1. 修正语音识别错误
@@ -152,16 +157,16 @@ var DefaultPromptTemplates = []struct {
 		Description: "生成吸引人的标题",
 		Content: `请根据以下内容生成吸引人的标题：
 
-内容摘要：{{content_summary}}
-目标平台：{{platform}}
-目标受众：{{target_audience}}
+内容摘要：{{.content_summary}}
+目标平台：{{.platform}}
+目标受众：{{.target_audience}}
 
 标题要求：
-1. 字数控制在{{title_length}}字以内
+1. 字数控制在{{.title_length}}字以内
 2. 使用吸引人的词汇
 3. 符合平台调性
 4. 避免标题党
-5. 生成{{num_titles}}个备选标题
+5. 生成{{.num_titles}}个备选标题
 
 请提供多个标题选项。`,
 		Variables: []VariableDefinition{
@@ -184,8 +189,8 @@ var DefaultPromptTemplates = []struct {
 		Content: `请审核以下内容是否符合平台规范：
 
 待审核内容：
-{{content}}
-目标平台：{{platform}}
+{{.content}}
+目标平台：{{.platform}}
 
 审核要点：
 1. 是否包含敏感词汇
@@ -212,10 +217,10 @@ var DefaultPromptTemplates = []struct {
 		Content: `请从以下内容中提取关键词：
 
 内容：
-{{content}}
+{{.content}}
 
 提取要求：
-1. 提取{{num_keywords}}个核心关键词
+1. 提取{{.num_keywords}}个核心关键词
 2. 按重要性排序
 3. 包含长尾关键词
 4. 适合SEO优化
@@ -239,10 +244,10 @@ var DefaultPromptTemplates = []struct {
 		Content: `请为以下内容生成摘要：
 
 原始内容：
-{{content}}
+{{.content}}
 
 摘要要求：
-1. 字数控制在{{summary_length}}字左右
+1. 字数控制在{{.summary_length}}字左右
 2. 突出核心观点
 3. 保持逻辑清晰
 4. 适合快速阅读
@@ -257,13 +262,98 @@ var DefaultPromptTemplates = []struct {
 		IsSystem:  true,
 		Tags:      []string{"内容摘要", "AI总结", "默认模板"},
 	},
+	{
+		TemplateID:  "content-expansion-v1",
+		Name:        "内容扩写模板",
+		Type:        "content_expansion",
+		Category:    "content",
+		Description: "在保留原意和风格的前提下扩写内容",
+		Content: `请扩写以下内容，使其更加丰富、详实：
+
+原始内容：
+{{.content}}
+
+扩写要求：
+1. 保持核心观点和写作风格
+2. 补充细节、案例或论据
+3. 扩写后字数在{{.length}}字左右
+4. 适合{{.platform}}平台发布
+
+请直接输出扩写后的完整内容。`,
+		Variables: []VariableDefinition{
+			{Name: "content", Type: "string", Required: true, Description: "原始内容"},
+			{Name: "length", Type: "number", Required: false, Default: "800", Description: "目标字数"},
+			{Name: "platform", Type: "string", Required: false, Default: "通用", Description: "目标平台"},
+		},
+		IsDefault: true,
+		IsSystem:  true,
+		Tags:      []string{"内容扩写", "AI创作", "默认模板"},
+	},
+}
+
+// builtinTemplates 全部内置模板，包括默认模板和代码中按ID加载的模板
+func builtinTemplates() []DefaultTemplate {
+	templates := make([]DefaultTemplate, 0, len(DefaultPromptTemplates)+len(RegistryTemplates)+len(ContentTemplates))
+	templates = append(templates, DefaultPromptTemplates...)
+	templates = append(templates, RegistryTemplates...)
+	return append(templates, ContentTemplates...)
+}
+
+// findBuiltin 按ID查找内置模板
+func findBuiltin(templateID string) (*DefaultTemplate, bool) {
+	for _, template := range builtinTemplates() {
+		if template.TemplateID == templateID {
+			return &template, true
+		}
+	}
+	return nil, false
 }
 
-// InitializeDefaultTemplates 初始化默认模板
+// record 转换为数据库记录
+func (t *DefaultTemplate) record() (*database.PromptTemplate, error) {
+	// 序列化变量定义
+	variablesJSON, err := json.Marshal(t.Variables)
+	if err != nil {
+		return nil, err
+	}
+
+	// 序列化标签
+	tagsJSON, err := json.Marshal(t.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	format, messagesJSON, parametersJSON, err := encodeChat(t.Messages, t.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	return &database.PromptTemplate{
+		TemplateID:  t.TemplateID,
+		Name:        t.Name,
+		Type:        t.Type,
+		Category:    t.Category,
+		Description: t.Description,
+		Content:     t.Content,
+		Format:      format,
+		Messages:    messagesJSON,
+		Parameters:  parametersJSON,
+		Variables:   string(variablesJSON),
+		Version:     1,
+		IsActive:    true,
+		IsDefault:   t.IsDefault,
+		IsSystem:    t.IsSystem,
+		Tags:        string(tagsJSON),
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}, nil
+}
+
+// InitializeDefaultTemplates 初始化默认模板，已存在的模板保持不变
 func InitializeDefaultTemplates(db *gorm.DB) error {
 	service := NewService(db)
 
-	for _, template := range DefaultPromptTemplates {
+	for _, template := range builtinTemplates() {
 		// 检查模板是否已存在
 		var count int64
 		if err := db.Model(&database.PromptTemplate{}).Where("template_id = ?", template.TemplateID).Count(&count).Error; err != nil {
@@ -275,36 +365,12 @@ func InitializeDefaultTemplates(db *gorm.DB) error {
 			continue
 		}
 
-		// 序列化变量定义
-		variablesJSON, err := json.Marshal(template.Variables)
-		if err != nil {
-			return err
-		}
-
-		// 序列化标签
-		tagsJSON, err := json.Marshal(template.Tags)
+		// 创建模板
+		promptTemplate, err := template.record()
 		if err != nil {
 			return err
 		}
 
-		// 创建模板
-		promptTemplate := &database.PromptTemplate{
-			TemplateID:  template.TemplateID,
-			Name:        template.Name,
-			Type:        template.Type,
-			Category:    template.Category,
-			Description: template.Description,
-			Content:     template.Content,
-			Variables:   string(variablesJSON),
-			Version:     1,
-			IsActive:    true,
-			IsDefault:   template.IsDefault,
-			IsSystem:    template.IsSystem,
-			Tags:        string(tagsJSON),
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-		}
-
 		if err := db.Create(promptTemplate).Error; err != nil {
 			log.Printf("创建模板 %s 失败: %v", template.TemplateID, err)
 			continue
@@ -0,0 +1,192 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"publisher-core/database"
+)
+
+// 差异行类型
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// DiffLine 行级差异，OldLine 和 NewLine 为行号，从 1 开始，不存在时为 0
+type DiffLine struct {
+	Op      string `json:"op"`
+	Text    string `json:"text"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+// VersionDiff 两个版本之间的差异
+// chat 格式的消息按 "[role]" 标题行加内容展开后比较，变量定义和生成参数按格式化的 JSON 比较
+type VersionDiff struct {
+	TemplateID string     `json:"template_id"`
+	From       int        `json:"from"`
+	To         int        `json:"to"`
+	FromFormat string     `json:"from_format"`
+	ToFormat   string     `json:"to_format"`
+	Content    []DiffLine `json:"content"`
+	Variables  []DiffLine `json:"variables"`
+	Parameters []DiffLine `json:"parameters"`
+	Added      int        `json:"added"`
+	Removed    int        `json:"removed"`
+}
+
+// DiffVersions 比较模板的两个版本，包括草稿
+func (s *Service) DiffVersions(templateID string, from, to int) (*VersionDiff, error) {
+	fromVersion, err := s.GetVersion(templateID, from)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, err := s.GetVersion(templateID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &VersionDiff{
+		TemplateID: templateID,
+		From:       from,
+		To:         to,
+		FromFormat: formatOf(fromVersion),
+		ToFormat:   formatOf(toVersion),
+	}
+	if diff.Content, err = diffField(fromVersion, toVersion, contentText); err != nil {
+		return nil, err
+	}
+	if diff.Variables, err = diffField(fromVersion, toVersion, func(v *database.PromptTemplateVersion) (string, error) {
+		return indentJSON(v.Variables)
+	}); err != nil {
+		return nil, err
+	}
+	if diff.Parameters, err = diffField(fromVersion, toVersion, func(v *database.PromptTemplateVersion) (string, error) {
+		return indentJSON(v.Parameters)
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, lines := range [][]DiffLine{diff.Content, diff.Variables, diff.Parameters} {
+		for _, line := range lines {
+			switch line.Op {
+			case DiffInsert:
+				diff.Added++
+			case DiffDelete:
+				diff.Removed++
+			}
+		}
+	}
+	return diff, nil
+}
+
+// formatOf 版本的模板格式，旧记录为空时按 text 处理
+func formatOf(v *database.PromptTemplateVersion) string {
+	if v.Format == "" {
+		return FormatText
+	}
+	return v.Format
+}
+
+// diffField 取出两个版本的同一部分并比较
+func diffField(from, to *database.PromptTemplateVersion, text func(*database.PromptTemplateVersion) (string, error)) ([]DiffLine, error) {
+	a, err := text(from)
+	if err != nil {
+		return nil, fmt.Errorf("版本 %d: %w", from.Version, err)
+	}
+	b, err := text(to)
+	if err != nil {
+		return nil, fmt.Errorf("版本 %d: %w", to.Version, err)
+	}
+	return diffLines(splitLines(a), splitLines(b)), nil
+}
+
+// contentText 用于比较的模板内容
+func contentText(v *database.PromptTemplateVersion) (string, error) {
+	if formatOf(v) != FormatChat {
+		return v.Content, nil
+	}
+	var messages []MessageTemplate
+	if err := json.Unmarshal([]byte(v.Messages), &messages); err != nil {
+		return "", fmt.Errorf("解析消息模板失败: %w", err)
+	}
+	var b strings.Builder
+	for i, m := range messages {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[%s]\n%s", m.Role, m.Content)
+	}
+	return b.String(), nil
+}
+
+// indentJSON 格式化 JSON 以便逐行比较，空值和 null 视为空
+func indentJSON(data string) (string, error) {
+	if data == "" || data == "null" {
+		return "", nil
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		return "", err
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// splitLines 按行拆分，空文本没有行
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines 基于最长公共子序列的行级比较，同一位置的删除行排在插入行之前
+func diffLines(a, b []string) []DiffLine {
+	// lcs[i][j] 为 a[i:] 和 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Op: DiffEqual, Text: a[i], OldLine: i + 1, NewLine: j + 1})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: DiffDelete, Text: a[i], OldLine: i + 1})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: DiffInsert, Text: b[j], NewLine: j + 1})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{Op: DiffDelete, Text: a[i], OldLine: i + 1})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{Op: DiffInsert, Text: b[j], NewLine: j + 1})
+	}
+	return lines
+}
@@ -0,0 +1,471 @@
+package prompt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"time"
+
+	"publisher-core/ai/provider"
+	"publisher-core/cost"
+	"publisher-core/database"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// 版本状态
+const (
+	VersionDraft     = "draft"     // 草稿，不影响调用方
+	VersionRollout   = "rollout"   // 灰度中，按比例分配流量
+	VersionPublished = "published" // 已发布，曾作为线上版本
+)
+
+// DraftRequest 创建或修改草稿的请求，字段含义与 UpdateTemplateRequest 相同
+type DraftRequest struct {
+	Content    string                `json:"content"`
+	Messages   []MessageTemplate     `json:"messages,omitempty"`
+	Parameters *GenerationParameters `json:"parameters,omitempty"`
+	Variables  []VariableDefinition  `json:"variables"`
+	ChangeNote string                `json:"change_note"`
+	CreatedBy  string                `json:"created_by"`
+}
+
+// draftFields 校验草稿并返回要保存的字段
+func (s *Service) draftFields(templateID string, req *DraftRequest) (map[string]interface{}, error) {
+	if err := s.validateContent(templateID, req.Content, req.Variables); err != nil {
+		return nil, err
+	}
+	if err := s.validateChat(templateID, req.Messages, req.Parameters); err != nil {
+		return nil, err
+	}
+	format, messagesJSON, parametersJSON, err := encodeChat(req.Messages, req.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	variablesJSON, err := json.Marshal(req.Variables)
+	if err != nil {
+		return nil, fmt.Errorf("序列化变量定义失败: %w", err)
+	}
+	return map[string]interface{}{
+		"content":     req.Content,
+		"format":      format,
+		"messages":    messagesJSON,
+		"parameters":  parametersJSON,
+		"variables":   string(variablesJSON),
+		"change_note": req.ChangeNote,
+		"updated_at":  time.Now(),
+	}, nil
+}
+
+// editableTemplate 获取可以修改的模板，系统模板不可修改
+func (s *Service) editableTemplate(templateID string) (*database.PromptTemplate, error) {
+	template, err := s.GetTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template.IsSystem {
+		return nil, errors.New("系统模板不可修改")
+	}
+	return template, nil
+}
+
+// CreateDraft 创建草稿版本，发布前不影响按ID加载模板的调用方
+func (s *Service) CreateDraft(templateID string, req *DraftRequest) (*database.PromptTemplateVersion, error) {
+	template, err := s.editableTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := s.draftFields(templateID, req)
+	if err != nil {
+		return nil, err
+	}
+	number, err := s.nextVersion(template)
+	if err != nil {
+		return nil, err
+	}
+
+	version := &database.PromptTemplateVersion{
+		TemplateID: templateID,
+		Version:    number,
+		Content:    fields["content"].(string),
+		Format:     fields["format"].(string),
+		Messages:   fields["messages"].(string),
+		Parameters: fields["parameters"].(string),
+		Variables:  fields["variables"].(string),
+		ChangeNote: req.ChangeNote,
+		Status:     VersionDraft,
+		CreatedBy:  req.CreatedBy,
+		CreatedAt:  time.Now(),
+	}
+	if version.ChangeNote == "" {
+		version.ChangeNote = fmt.Sprintf("草稿版本 %d", number)
+	}
+	if err := s.db.Create(version).Error; err != nil {
+		return nil, fmt.Errorf("创建草稿失败: %w", err)
+	}
+	return version, nil
+}
+
+// UpdateDraft 修改草稿，灰度中的版本需要先停止灰度
+func (s *Service) UpdateDraft(templateID string, number int, req *DraftRequest) (*database.PromptTemplateVersion, error) {
+	if _, err := s.editableTemplate(templateID); err != nil {
+		return nil, err
+	}
+	version, err := s.GetVersion(templateID, number)
+	if err != nil {
+		return nil, err
+	}
+	if version.Status != VersionDraft {
+		return nil, fmt.Errorf("版本 %d 不是草稿", number)
+	}
+
+	fields, err := s.draftFields(templateID, req)
+	if err != nil {
+		return nil, err
+	}
+	if req.ChangeNote == "" {
+		delete(fields, "change_note")
+	}
+	if err := s.db.Model(version).Updates(fields).Error; err != nil {
+		return nil, fmt.Errorf("更新草稿失败: %w", err)
+	}
+	return version, nil
+}
+
+// DeleteDraft 删除草稿
+func (s *Service) DeleteDraft(templateID string, number int) error {
+	version, err := s.GetVersion(templateID, number)
+	if err != nil {
+		return err
+	}
+	if version.Status != VersionDraft {
+		return fmt.Errorf("版本 %d 不是草稿", number)
+	}
+	return s.db.Delete(version).Error
+}
+
+// GetVersion 获取指定版本
+func (s *Service) GetVersion(templateID string, number int) (*database.PromptTemplateVersion, error) {
+	var version database.PromptTemplateVersion
+	if err := s.db.Where("template_id = ? AND version = ?", templateID, number).First(&version).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("版本 %d 不存在", number)
+		}
+		return nil, fmt.Errorf("查询版本失败: %w", err)
+	}
+	return &version, nil
+}
+
+// nextVersion 下一个版本号，草稿也占用版本号
+func (s *Service) nextVersion(template *database.PromptTemplate) (int, error) {
+	var max int
+	if err := s.db.Model(&database.PromptTemplateVersion{}).Where("template_id = ?", template.TemplateID).
+		Select("COALESCE(MAX(version), 0)").Scan(&max).Error; err != nil {
+		return 0, fmt.Errorf("查询版本号失败: %w", err)
+	}
+	if template.Version > max {
+		max = template.Version
+	}
+	return max + 1, nil
+}
+
+// PublishVersion 把草稿或灰度中的版本发布为线上版本，同时结束灰度
+func (s *Service) PublishVersion(templateID string, number int) (*database.PromptTemplate, error) {
+	template, err := s.editableTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.GetVersion(templateID, number)
+	if err != nil {
+		return nil, err
+	}
+	if version.Status == VersionPublished {
+		return nil, fmt.Errorf("版本 %d 已发布，恢复历史版本请使用恢复接口", number)
+	}
+
+	rollout := template.RolloutVersion
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(template).Updates(map[string]interface{}{
+			"content":         version.Content,
+			"format":          version.Format,
+			"messages":        version.Messages,
+			"parameters":      version.Parameters,
+			"variables":       version.Variables,
+			"version":         version.Version,
+			"rollout_version": 0,
+			"rollout_percent": 0,
+			"updated_at":      time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		if rollout != 0 && rollout != number {
+			// 发布其它版本时正在灰度的版本回到草稿
+			if err := tx.Model(&database.PromptTemplateVersion{}).
+				Where("template_id = ? AND version = ? AND status = ?", templateID, rollout, VersionRollout).
+				Updates(map[string]interface{}{"status": VersionDraft, "updated_at": time.Now()}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(version).Updates(map[string]interface{}{
+			"status":     VersionPublished,
+			"updated_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("发布版本失败: %w", err)
+	}
+
+	logrus.Infof("模板 %s 发布版本 %d", templateID, number)
+	return s.GetTemplate(templateID)
+}
+
+// StartRollout 把草稿按百分比灰度给调用方，已在灰度中时调整比例
+// 同一时间只能灰度一个版本，比例为 100 时仍需调用 PublishVersion 完成发布
+func (s *Service) StartRollout(templateID string, number, percent int) (*database.PromptTemplate, error) {
+	if percent < 1 || percent > 100 {
+		return nil, errors.New("灰度比例必须在 1-100 之间")
+	}
+	template, err := s.editableTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template.RolloutVersion != 0 && template.RolloutVersion != number {
+		return nil, fmt.Errorf("版本 %d 正在灰度，请先停止或发布", template.RolloutVersion)
+	}
+	version, err := s.GetVersion(templateID, number)
+	if err != nil {
+		return nil, err
+	}
+	if version.Status == VersionPublished {
+		return nil, fmt.Errorf("版本 %d 已发布", number)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(template).Updates(map[string]interface{}{
+			"rollout_version": number,
+			"rollout_percent": percent,
+			"updated_at":      time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(version).Updates(map[string]interface{}{
+			"status":     VersionRollout,
+			"updated_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("设置灰度失败: %w", err)
+	}
+
+	logrus.Infof("模板 %s 版本 %d 灰度 %d%%", templateID, number, percent)
+	return template, nil
+}
+
+// StopRollout 停止灰度，灰度版本回到草稿状态，全部流量使用线上版本
+func (s *Service) StopRollout(templateID string) (*database.PromptTemplate, error) {
+	template, err := s.GetTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	if template.RolloutVersion == 0 {
+		return nil, errors.New("模板没有进行中的灰度")
+	}
+
+	number := template.RolloutVersion
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(template).Updates(map[string]interface{}{
+			"rollout_version": 0,
+			"rollout_percent": 0,
+			"updated_at":      time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&database.PromptTemplateVersion{}).
+			Where("template_id = ? AND version = ? AND status = ?", templateID, number, VersionRollout).
+			Updates(map[string]interface{}{"status": VersionDraft, "updated_at": time.Now()}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("停止灰度失败: %w", err)
+	}
+	return template, nil
+}
+
+// resolveDefinition 获取调用方应使用的模板定义
+// 模板正在灰度时，unitID 落在灰度比例内的调用使用灰度版本，同一 unitID 始终得到同一版本；
+// unitID 为空时每次调用按比例随机分配
+func (s *Service) resolveDefinition(templateID, unitID string) (*templateDefinition, error) {
+	template, err := s.lookupTemplate(templateID)
+	if err != nil {
+		return nil, err
+	}
+	if !template.IsActive {
+		return nil, errors.New("模板未激活")
+	}
+
+	if template.RolloutVersion > 0 && rolloutBucket(template, unitID) < template.RolloutPercent {
+		version, err := s.GetVersion(templateID, template.RolloutVersion)
+		if err == nil {
+			return versionDefinition(version)
+		}
+		logrus.Warnf("加载模板 %s 灰度版本失败，使用线上版本: %v", templateID, err)
+	}
+	return parseDefinition(template)
+}
+
+// rolloutBucket 调用所在的灰度分桶，取值 0-99
+func rolloutBucket(template *database.PromptTemplate, unitID string) int {
+	if unitID == "" {
+		return rand.Intn(100)
+	}
+	h := fnv.New32a()
+	fmt.Fprintf(h, "%s:%d:%s", template.TemplateID, template.RolloutVersion, unitID)
+	return int(h.Sum32() % 100)
+}
+
+// rolloutUnit 灰度分桶使用的调用方标识，优先使用用户ID
+func rolloutUnit(ctx context.Context) string {
+	attr := cost.AttributionFromContext(ctx)
+	if attr.UserID != "" {
+		return attr.UserID
+	}
+	return attr.RequestID
+}
+
+// versionDefinition 解析版本记录
+func versionDefinition(v *database.PromptTemplateVersion) (*templateDefinition, error) {
+	return parseDefinition(&database.PromptTemplate{
+		TemplateID: v.TemplateID,
+		Version:    v.Version,
+		Content:    v.Content,
+		Format:     v.Format,
+		Messages:   v.Messages,
+		Parameters: v.Parameters,
+		Variables:  v.Variables,
+	})
+}
+
+// RenderVersion 渲染指定版本，包括草稿，用于发布前预览
+func (s *Service) RenderVersion(templateID string, number int, variables map[string]interface{}) (*provider.GenerateOptions, error) {
+	version, err := s.GetVersion(templateID, number)
+	if err != nil {
+		return nil, err
+	}
+	def, err := versionDefinition(version)
+	if err != nil {
+		return nil, err
+	}
+	return s.generateOptions(def, variables)
+}
+
+// PreviewBackend 预览时调用模型，ai.Service 实现了该接口
+type PreviewBackend interface {
+	Generate(ctx context.Context, opts *provider.GenerateOptions) (*provider.GenerateResult, error)
+	GenerateWithProvider(ctx context.Context, pt provider.ProviderType, opts *provider.GenerateOptions) (*provider.GenerateResult, error)
+}
+
+// PreviewRequest 预览请求
+type PreviewRequest struct {
+	// Provider 为空时使用主提供商，Model 非空时覆盖模板参数中的模型
+	Provider string                   `json:"provider"`
+	Model    string                   `json:"model"`
+	Samples  []map[string]interface{} `json:"samples"`
+	// Compare 同时用线上版本生成，便于对比
+	Compare bool `json:"compare"`
+}
+
+// PreviewOutput 一次生成的结果
+type PreviewOutput struct {
+	Version      int                `json:"version"`
+	Messages     []provider.Message `json:"messages,omitempty"`
+	Content      string             `json:"content"`
+	Model        string             `json:"model"`
+	InputTokens  int                `json:"input_tokens"`
+	OutputTokens int                `json:"output_tokens"`
+	DurationMs   int64              `json:"duration_ms"`
+	Error        string             `json:"error,omitempty"`
+}
+
+// PreviewSample 一组示例变量的预览结果
+type PreviewSample struct {
+	Variables map[string]interface{} `json:"variables"`
+	Draft     PreviewOutput          `json:"draft"`
+	Baseline  *PreviewOutput         `json:"baseline,omitempty"`
+}
+
+// PreviewVersion 用示例变量渲染指定版本并调用模型生成，不记录模板使用
+// 单个示例渲染或生成失败时记录在结果中，不影响其它示例
+func (s *Service) PreviewVersion(ctx context.Context, backend PreviewBackend, templateID string, number int, req *PreviewRequest) ([]PreviewSample, error) {
+	version, err := s.GetVersion(templateID, number)
+	if err != nil {
+		return nil, err
+	}
+	def, err := versionDefinition(version)
+	if err != nil {
+		return nil, err
+	}
+	var baseline *templateDefinition
+	if req.Compare {
+		template, err := s.GetTemplate(templateID)
+		if err != nil {
+			return nil, err
+		}
+		if baseline, err = parseDefinition(template); err != nil {
+			return nil, err
+		}
+	}
+
+	samples := req.Samples
+	if len(samples) == 0 {
+		samples = []map[string]interface{}{{}}
+	}
+
+	// 草稿与线上版本的提示词通常高度相似，预览不能复用缓存，否则两侧可能返回同一份结果
+	ctx = cost.WithoutCache(cost.WithFunction(ctx, "prompt_preview"))
+	results := make([]PreviewSample, 0, len(samples))
+	for _, vars := range samples {
+		sample := PreviewSample{Variables: vars, Draft: s.previewOnce(ctx, backend, def, vars, req)}
+		if baseline != nil {
+			output := s.previewOnce(ctx, backend, baseline, vars, req)
+			sample.Baseline = &output
+		}
+		results = append(results, sample)
+	}
+	return results, nil
+}
+
+// previewOnce 渲染并生成一次
+func (s *Service) previewOnce(ctx context.Context, backend PreviewBackend, def *templateDefinition, vars map[string]interface{}, req *PreviewRequest) PreviewOutput {
+	output := PreviewOutput{Version: def.version}
+	opts, err := s.generateOptions(def, vars)
+	if err != nil {
+		output.Error = err.Error()
+		return output
+	}
+	if req.Model != "" {
+		opts.Model = req.Model
+	}
+	output.Messages = opts.Messages
+
+	start := time.Now()
+	var result *provider.GenerateResult
+	if req.Provider != "" {
+		result, err = backend.GenerateWithProvider(ctx, provider.ProviderType(req.Provider), opts)
+	} else {
+		result, err = backend.Generate(ctx, opts)
+	}
+	output.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		output.Error = err.Error()
+		return output
+	}
+
+	output.Content = result.Content
+	output.Model = result.Model
+	output.InputTokens = result.InputTokens
+	output.OutputTokens = result.OutputTokens
+	return output
+}
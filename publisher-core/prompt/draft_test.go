@@ -0,0 +1,249 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"publisher-core/ai/provider"
+	"publisher-core/cost"
+	"publisher-core/database"
+)
+
+type fakePreviewBackend struct {
+	providers []provider.ProviderType
+}
+
+func (b *fakePreviewBackend) Generate(ctx context.Context, opts *provider.GenerateOptions) (*provider.GenerateResult, error) {
+	return b.GenerateWithProvider(ctx, "", opts)
+}
+
+func (b *fakePreviewBackend) GenerateWithProvider(ctx context.Context, pt provider.ProviderType, opts *provider.GenerateOptions) (*provider.GenerateResult, error) {
+	b.providers = append(b.providers, pt)
+	if strings.Contains(opts.Messages[len(opts.Messages)-1].Content, "失败") {
+		return nil, fmt.Errorf("upstream error")
+	}
+	return &provider.GenerateResult{Content: "生成：" + opts.Messages[len(opts.Messages)-1].Content, Model: opts.Model}, nil
+}
+
+// newDraftTemplate 创建一个线上版本为 1、草稿为 2 的模板
+func newDraftTemplate(t *testing.T, s *Service) {
+	t.Helper()
+	variables := []VariableDefinition{{Name: "topic", Type: VarTypeString, Required: true}}
+	if _, err := s.CreateTemplate(&CreateTemplateRequest{
+		TemplateID: "summary",
+		Name:       "摘要",
+		Content:    "总结：{{.topic}}",
+		Variables:  variables,
+	}); err != nil {
+		t.Fatalf("CreateTemplate failed: %v", err)
+	}
+	draft, err := s.CreateDraft("summary", &DraftRequest{
+		Content:   "请简要总结：{{.topic}}",
+		Variables: variables,
+		CreatedBy: "editor",
+	})
+	if err != nil {
+		t.Fatalf("CreateDraft failed: %v", err)
+	}
+	if draft.Version != 2 || draft.Status != VersionDraft {
+		t.Fatalf("Unexpected draft: %+v", draft)
+	}
+}
+
+func TestDraftDoesNotAffectCallers(t *testing.T) {
+	s := newTestService(t)
+	newDraftTemplate(t, s)
+
+	content, err := s.RenderTemplate("summary", map[string]interface{}{"topic": "新闻"})
+	if err != nil || content != "总结：新闻" {
+		t.Errorf("Expected published content, got %q, %v", content, err)
+	}
+	opts, err := s.RenderVersion("summary", 2, map[string]interface{}{"topic": "新闻"})
+	if err != nil || opts.Messages[0].Content != "请简要总结：新闻" {
+		t.Errorf("Expected draft content, got %+v, %v", opts, err)
+	}
+
+	if _, err := s.UpdateDraft("summary", 2, &DraftRequest{Content: "总结 {{.topic"}); err == nil {
+		t.Error("Expected invalid draft to be rejected")
+	}
+	if _, err := s.UpdateDraft("summary", 1, &DraftRequest{Content: "总结"}); err == nil {
+		t.Error("Expected published version to be read-only")
+	}
+	if _, err := s.RestoreTemplateVersion("summary", 2); err == nil {
+		t.Error("Expected restoring a draft to be rejected")
+	}
+
+	// 草稿占用版本号，之后的修改从 3 开始
+	if _, err := s.UpdateTemplate("summary", &UpdateTemplateRequest{
+		Name:      "摘要",
+		Content:   "总结一下：{{.topic}}",
+		Variables: []VariableDefinition{{Name: "topic", Type: VarTypeString, Required: true}},
+		IsActive:  true,
+	}); err != nil {
+		t.Fatalf("UpdateTemplate failed: %v", err)
+	}
+	if template, _ := s.GetTemplate("summary"); template.Version != 3 {
+		t.Errorf("Expected version 3 after update, got %d", template.Version)
+	}
+	if err := s.DeleteDraft("summary", 2); err != nil {
+		t.Errorf("DeleteDraft failed: %v", err)
+	}
+	if _, err := s.GetVersion("summary", 2); err == nil {
+		t.Error("Expected draft to be deleted")
+	}
+}
+
+func TestDiffVersions(t *testing.T) {
+	s := newTestService(t)
+	newDraftTemplate(t, s)
+
+	diff, err := s.DiffVersions("summary", 1, 2)
+	if err != nil {
+		t.Fatalf("DiffVersions failed: %v", err)
+	}
+	if diff.Added != 1 || diff.Removed != 1 || len(diff.Content) != 2 {
+		t.Errorf("Unexpected diff: %+v", diff)
+	}
+	if diff.Content[0].Op != DiffDelete || diff.Content[0].Text != "总结：{{.topic}}" ||
+		diff.Content[1].Op != DiffInsert || diff.Content[1].Text != "请简要总结：{{.topic}}" {
+		t.Errorf("Unexpected content diff: %+v", diff.Content)
+	}
+	for _, line := range diff.Variables {
+		if line.Op != DiffEqual {
+			t.Errorf("Expected variables to be unchanged: %+v", diff.Variables)
+			break
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	lines := diffLines([]string{"a", "b", "c", "d"}, []string{"a", "c", "x", "d"})
+	var got []string
+	for _, line := range lines {
+		got = append(got, fmt.Sprintf("%s:%s:%d:%d", line.Op, line.Text, line.OldLine, line.NewLine))
+	}
+	want := "equal:a:1:1,delete:b:2:0,equal:c:3:2,insert:x:0:3,equal:d:4:4"
+	if strings.Join(got, ",") != want {
+		t.Errorf("diffLines = %s, want %s", strings.Join(got, ","), want)
+	}
+	if len(diffLines(nil, nil)) != 0 {
+		t.Error("Expected empty diff")
+	}
+}
+
+func TestRolloutAndPublish(t *testing.T) {
+	s := newTestService(t)
+	newDraftTemplate(t, s)
+
+	if _, err := s.StartRollout("summary", 2, 0); err == nil {
+		t.Error("Expected invalid percent to be rejected")
+	}
+	if _, err := s.StartRollout("summary", 1, 10); err == nil {
+		t.Error("Expected published version to be rejected")
+	}
+	if _, err := s.StartRollout("summary", 2, 30); err != nil {
+		t.Fatalf("StartRollout failed: %v", err)
+	}
+	if _, err := s.UpdateDraft("summary", 2, &DraftRequest{Content: "x"}); err == nil {
+		t.Error("Expected rollout version to be read-only")
+	}
+
+	gen := &fakeGenerator{}
+	draftCalls := 0
+	for i := 0; i < 200; i++ {
+		ctx := cost.WithAttribution(context.Background(), cost.Attribution{UserID: fmt.Sprintf("user-%d", i)})
+		if _, err := s.Generate(ctx, gen, "summary", map[string]interface{}{"topic": "新闻"}, nil); err != nil {
+			t.Fatalf("Generate failed: %v", err)
+		}
+		first := gen.last.Messages[0].Content
+		if strings.HasPrefix(first, "请简要总结") {
+			draftCalls++
+		}
+		// 同一用户始终得到同一版本
+		s.Generate(ctx, gen, "summary", map[string]interface{}{"topic": "新闻"}, nil)
+		if gen.last.Messages[0].Content != first {
+			t.Fatalf("Expected sticky rollout for user-%d", i)
+		}
+	}
+	if draftCalls < 40 || draftCalls > 80 {
+		t.Errorf("Expected about 30%% of users on the rollout version, got %d/200", draftCalls)
+	}
+
+	stats, err := s.GetUsageStats("summary", time.Time{})
+	if err != nil {
+		t.Fatalf("GetUsageStats failed: %v", err)
+	}
+	if stats.TotalCalls != 400 || len(stats.Versions) != 2 || stats.Versions[1].Version != 2 ||
+		stats.Versions[1].TotalCalls != int64(draftCalls*2) {
+		t.Errorf("Unexpected usage stats: %+v", stats)
+	}
+
+	// 停止灰度后全部使用线上版本
+	if _, err := s.StopRollout("summary"); err != nil {
+		t.Fatalf("StopRollout failed: %v", err)
+	}
+	if version, _ := s.GetVersion("summary", 2); version.Status != VersionDraft {
+		t.Errorf("Expected stopped rollout to return to draft, got %s", version.Status)
+	}
+	for i := 0; i < 20; i++ {
+		def, err := s.resolveDefinition("summary", fmt.Sprintf("user-%d", i))
+		if err != nil || def.version != 1 {
+			t.Fatalf("Expected published version after stop, got %+v, %v", def, err)
+		}
+	}
+
+	template, err := s.PublishVersion("summary", 2)
+	if err != nil {
+		t.Fatalf("PublishVersion failed: %v", err)
+	}
+	if template.Version != 2 || template.Content != "请简要总结：{{.topic}}" || template.RolloutVersion != 0 {
+		t.Errorf("Unexpected published template: %+v", template)
+	}
+	if version, _ := s.GetVersion("summary", 2); version.Status != VersionPublished {
+		t.Errorf("Expected version to be published, got %s", version.Status)
+	}
+	if _, err := s.PublishVersion("summary", 2); err == nil {
+		t.Error("Expected publishing twice to be rejected")
+	}
+}
+
+func TestPreviewVersion(t *testing.T) {
+	s := newTestService(t)
+	newDraftTemplate(t, s)
+
+	backend := &fakePreviewBackend{}
+	samples, err := s.PreviewVersion(context.Background(), backend, "summary", 2, &PreviewRequest{
+		Provider: "deepseek",
+		Model:    "deepseek-chat",
+		Samples:  []map[string]interface{}{{"topic": "新闻"}, {"topic": "失败"}, {}},
+		Compare:  true,
+	})
+	if err != nil {
+		t.Fatalf("PreviewVersion failed: %v", err)
+	}
+	if len(samples) != 3 {
+		t.Fatalf("Expected 3 samples, got %d", len(samples))
+	}
+	if samples[0].Draft.Content != "生成：请简要总结：新闻" || samples[0].Draft.Model != "deepseek-chat" || samples[0].Draft.Version != 2 {
+		t.Errorf("Unexpected draft output: %+v", samples[0].Draft)
+	}
+	if samples[0].Baseline == nil || samples[0].Baseline.Content != "生成：总结：新闻" || samples[0].Baseline.Version != 1 {
+		t.Errorf("Unexpected baseline output: %+v", samples[0].Baseline)
+	}
+	if samples[1].Draft.Error == "" || samples[2].Draft.Error == "" {
+		t.Errorf("Expected per-sample errors: %+v, %+v", samples[1].Draft, samples[2].Draft)
+	}
+	if len(backend.providers) != 4 || backend.providers[0] != "deepseek" {
+		t.Errorf("Unexpected provider calls: %v", backend.providers)
+	}
+
+	// 预览不计入模板使用记录
+	var count int64
+	s.db.Model(&database.PromptTemplateUsage{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected preview not to record usage, got %d", count)
+	}
+}
@@ -0,0 +1,517 @@
+package prompt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unicode/utf8"
+)
+
+// 模板使用 Go text/template 语法：
+//
+//	{{.topic}}                                 变量
+//	{{if .keywords}}关键词：{{.keywords | join "、"}}{{end}}   条件
+//	{{range .topics}}- {{.}}{{"\n"}}{{end}}    循环
+//	{{.content | truncate 200}}                过滤器
+//	{{template "common-footer-v1" .}}          引用其它模板（按模板ID）
+//
+// 变量值作为数据传入，不会被再次解析，用户输入中的 {{ }} 原样输出。
+// 旧版 {{name}} 写法在渲染时自动转换为 {{.name}}，可通过 MigrateLegacyTemplates 持久化
+
+// maxRenderedSize 渲染结果的最大字节数
+const maxRenderedSize = 1 << 20
+
+// ErrRenderTooLarge 渲染结果超过上限
+var ErrRenderTooLarge = errors.New("渲染结果超过大小上限")
+
+// maxRangeIterations 单次渲染中所有 range 循环体执行次数之和的上限
+const maxRangeIterations = 100000
+
+// ErrRenderTooComplex 渲染中 range 循环次数超过上限
+var ErrRenderTooComplex = errors.New("模板循环次数超过上限")
+
+// legacyPlaceholder 旧版变量占位符
+var legacyPlaceholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// templateKeywords 可以单独出现在 {{ }} 中的模板关键字
+var templateKeywords = map[string]bool{
+	"end": true, "else": true, "break": true, "continue": true,
+	"nil": true, "true": true, "false": true,
+}
+
+// ConvertLegacySyntax 把旧版 {{name}} 占位符转换为 {{.name}}，其它内容不变
+func ConvertLegacySyntax(content string) string {
+	return legacyPlaceholder.ReplaceAllStringFunc(content, func(m string) string {
+		name := legacyPlaceholder.FindStringSubmatch(m)[1]
+		if templateKeywords[name] {
+			return m
+		}
+		return "{{." + name + "}}"
+	})
+}
+
+// templateFuncs 模板可用的过滤器，参数在前、被处理的值在后，以便用于管道
+var templateFuncs = template.FuncMap{
+	"truncate": truncateFilter,
+	"join":     joinFilter,
+	"upper":    func(v interface{}) string { return strings.ToUpper(toString(v)) },
+	"lower":    func(v interface{}) string { return strings.ToLower(toString(v)) },
+	"trim":     func(v interface{}) string { return strings.TrimSpace(toString(v)) },
+	"json":     jsonFilter,
+	"quote":    func(v interface{}) string { return strconv.Quote(toString(v)) },
+	"default":  defaultFilter,
+}
+
+// truncateFilter 按字符数截断，截断时追加省略号
+func truncateFilter(n int, v interface{}) string {
+	s := toString(v)
+	if n < 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
+
+// joinFilter 用分隔符连接数组，字符串原样返回
+func joinFilter(sep string, v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return ""
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return toString(v)
+	}
+	parts := make([]string, rv.Len())
+	for i := range parts {
+		parts[i] = toString(rv.Index(i).Interface())
+	}
+	return strings.Join(parts, sep)
+}
+
+// jsonFilter 序列化为 JSON
+func jsonFilter(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// defaultFilter 值为空时使用默认值
+func defaultFilter(def, v interface{}) interface{} {
+	if isEmpty(v) {
+		return def
+	}
+	return v
+}
+
+// toString 把变量值格式化为字符串，nil 为空字符串，整数值的浮点数不带小数点
+func toString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// isEmpty 判断值是否为空
+func isEmpty(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return true
+	}
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return rv.Len() == 0
+	case reflect.Bool:
+		return !rv.Bool()
+	case reflect.Float64, reflect.Float32:
+		return rv.Float() == 0
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return rv.Int() == 0
+	case reflect.Ptr, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// PartialLoader 按模板ID加载被引用模板的内容
+type PartialLoader func(templateID string) (string, error)
+
+// compileTemplate 解析模板并递归加载通过 {{template "id"}} 引用的模板
+// 循环引用、对数字字面量的 range 等可能导致无限执行的写法在解析时拒绝，
+// 经由变量、with 或函数得到的数字和循环次数在执行时由 guardRanges 拦截
+func compileTemplate(name, content string, load PartialLoader) (*template.Template, error) {
+	root := template.New(name).Funcs(templateFuncs).Option("missingkey=error")
+	if _, err := root.Parse(ConvertLegacySyntax(content)); err != nil {
+		return nil, fmt.Errorf("解析模板失败: %w", err)
+	}
+
+	visiting := map[string]bool{name: true}
+	var resolve func(t *template.Template) error
+	resolve = func(t *template.Template) error {
+		if err := checkTree(t.Tree); err != nil {
+			return fmt.Errorf("模板 %s: %w", t.Name(), err)
+		}
+		for _, ref := range templateRefs(t.Tree.Root) {
+			if visiting[ref] {
+				return fmt.Errorf("模板循环引用: %s", ref)
+			}
+			if root.Lookup(ref) != nil {
+				continue
+			}
+			if load == nil {
+				return fmt.Errorf("引用的模板不存在: %s", ref)
+			}
+			partial, err := load(ref)
+			if err != nil {
+				return fmt.Errorf("加载引用的模板 %s 失败: %w", ref, err)
+			}
+			sub, err := root.New(ref).Parse(ConvertLegacySyntax(partial))
+			if err != nil {
+				return fmt.Errorf("解析引用的模板 %s 失败: %w", ref, err)
+			}
+			visiting[ref] = true
+			if err := resolve(sub); err != nil {
+				return err
+			}
+			delete(visiting, ref)
+		}
+		return nil
+	}
+
+	for _, t := range root.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		if err := resolve(t); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range root.Templates() {
+		if t.Tree != nil {
+			guardRanges(t.Tree.Root)
+		}
+	}
+	return root, nil
+}
+
+// templateRefs 收集 {{template "name"}} 引用的模板名
+func templateRefs(node parse.Node) []string {
+	var refs []string
+	walkTree(node, func(n parse.Node) {
+		if t, ok := n.(*parse.TemplateNode); ok {
+			refs = append(refs, t.Name)
+		}
+	})
+	return refs
+}
+
+// checkTree 拒绝对数字字面量或由数字字面量赋值的变量执行 range
+func checkTree(tree *parse.Tree) error {
+	numeric := make(map[string]bool)
+	var err error
+	walkTree(tree.Root, func(n parse.Node) {
+		if err != nil {
+			return
+		}
+		switch node := n.(type) {
+		case *parse.ActionNode:
+			if pipe := node.Pipe; pipe != nil && len(pipe.Decl) == 1 && len(pipe.Cmds) == 1 && len(pipe.Cmds[0].Args) == 1 {
+				if _, ok := pipe.Cmds[0].Args[0].(*parse.NumberNode); ok {
+					numeric[pipe.Decl[0].Ident[0]] = true
+				}
+			}
+		case *parse.RangeNode:
+			for _, cmd := range node.Pipe.Cmds {
+				for _, arg := range cmd.Args {
+					switch a := arg.(type) {
+					case *parse.NumberNode:
+						err = errors.New("不支持对数字执行 range")
+					case *parse.VariableNode:
+						if numeric[a.Ident[0]] {
+							err = errors.New("不支持对数字执行 range")
+						}
+					}
+				}
+			}
+		}
+	})
+	return err
+}
+
+// guardRanges 在每个 range 的管道末尾追加 rangeable，在循环体开头追加 rangeStep
+// 两个函数由 execute 在每次执行前注入，分别拒绝对数字的 range 和限制循环总次数
+func guardRanges(node parse.Node) {
+	walkTree(node, func(n parse.Node) {
+		r, ok := n.(*parse.RangeNode)
+		if !ok || r.Pipe == nil || r.List == nil {
+			return
+		}
+		r.Pipe.Cmds = append(r.Pipe.Cmds, &parse.CommandNode{
+			NodeType: parse.NodeCommand,
+			Pos:      r.Pos,
+			Args:     []parse.Node{parse.NewIdentifier("rangeable").SetPos(r.Pos)},
+		})
+		step := &parse.ActionNode{
+			NodeType: parse.NodeAction,
+			Pos:      r.Pos,
+			Line:     r.Line,
+			Pipe: &parse.PipeNode{
+				NodeType: parse.NodePipe,
+				Pos:      r.Pos,
+				Line:     r.Line,
+				Cmds: []*parse.CommandNode{{
+					NodeType: parse.NodeCommand,
+					Pos:      r.Pos,
+					Args:     []parse.Node{parse.NewIdentifier("rangeStep").SetPos(r.Pos)},
+				}},
+			},
+		}
+		r.List.Nodes = append([]parse.Node{step}, r.List.Nodes...)
+	})
+}
+
+// rangeGuard 返回 guardRanges 使用的函数，循环计数在单次渲染内累计
+func rangeGuard() template.FuncMap {
+	steps := 0
+	return template.FuncMap{
+		"rangeable": func(v interface{}) (interface{}, error) {
+			switch reflect.ValueOf(v).Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+				reflect.Func, reflect.Chan:
+				return nil, errors.New("不支持对数字执行 range")
+			}
+			return v, nil
+		},
+		"rangeStep": func() (string, error) {
+			steps++
+			if steps > maxRangeIterations {
+				return "", ErrRenderTooComplex
+			}
+			return "", nil
+		},
+	}
+}
+
+// walkTree 深度优先遍历模板语法树
+func walkTree(node parse.Node, fn func(parse.Node)) {
+	if node == nil || reflect.ValueOf(node).IsNil() {
+		return
+	}
+	fn(node)
+	switch n := node.(type) {
+	case *parse.ListNode:
+		for _, child := range n.Nodes {
+			walkTree(child, fn)
+		}
+	case *parse.IfNode:
+		walkTree(n.List, fn)
+		walkTree(n.ElseList, fn)
+	case *parse.RangeNode:
+		walkTree(n.List, fn)
+		walkTree(n.ElseList, fn)
+	case *parse.WithNode:
+		walkTree(n.List, fn)
+		walkTree(n.ElseList, fn)
+	}
+}
+
+// limitedBuffer 超过上限后拒绝写入，用于终止输出过大的渲染
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, ErrRenderTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// execute 执行模板
+func execute(t *template.Template, data map[string]interface{}) (string, error) {
+	buf := &limitedBuffer{limit: maxRenderedSize}
+	t.Funcs(rangeGuard())
+	if err := t.Execute(buf, data); err != nil {
+		if errors.Is(err, ErrRenderTooLarge) {
+			return "", ErrRenderTooLarge
+		}
+		if errors.Is(err, ErrRenderTooComplex) {
+			return "", ErrRenderTooComplex
+		}
+		return "", fmt.Errorf("渲染模板失败: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// 变量类型
+const (
+	VarTypeString  = "string"
+	VarTypeNumber  = "number"
+	VarTypeBoolean = "boolean"
+	VarTypeArray   = "array"
+	VarTypeObject  = "object"
+)
+
+// validVariableType 是否为支持的变量类型，为空时按 string 处理
+func validVariableType(typ string) bool {
+	switch typ {
+	case "", VarTypeString, VarTypeNumber, VarTypeBoolean, VarTypeArray, VarTypeObject:
+		return true
+	}
+	return false
+}
+
+// listSeparators 字符串转换为数组时使用的分隔符
+var listSeparators = regexp.MustCompile(`[,，、;；\n]`)
+
+// prepareVariables 按变量定义校验并转换变量类型，返回新的 map，不修改传入的变量
+// 必需变量缺失时使用默认值，可选变量缺失时填入该类型的空值，以便模板中用 if 判断
+func prepareVariables(defs []VariableDefinition, variables map[string]interface{}) (map[string]interface{}, error) {
+	data := make(map[string]interface{}, len(variables)+len(defs))
+	for k, v := range variables {
+		data[k] = v
+	}
+
+	var errs []string
+	for _, def := range defs {
+		value, ok := data[def.Name]
+		if !ok || value == nil {
+			switch {
+			case def.Default != "":
+				value = def.Default
+			case def.Required:
+				errs = append(errs, fmt.Sprintf("缺少必需变量: %s", def.Name))
+				continue
+			default:
+				data[def.Name] = zeroValue(def.Type)
+				continue
+			}
+		}
+
+		converted, err := convertVariable(def.Type, value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("变量 %s %v", def.Name, err))
+			continue
+		}
+		data[def.Name] = converted
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+	return data, nil
+}
+
+// zeroValue 可选变量缺失时的空值
+func zeroValue(typ string) interface{} {
+	switch typ {
+	case VarTypeBoolean:
+		return false
+	case VarTypeArray:
+		return []interface{}{}
+	case VarTypeObject:
+		return map[string]interface{}{}
+	case VarTypeNumber:
+		return nil
+	}
+	return ""
+}
+
+// convertVariable 把变量值转换为定义的类型
+// 字符串形式的数字、布尔值会被解析，字符串形式的数组可以是 JSON 数组或以逗号、顿号分隔的列表
+func convertVariable(typ string, value interface{}) (interface{}, error) {
+	switch typ {
+	case "", VarTypeString:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64, int, int64, bool:
+			return toString(v), nil
+		}
+		return nil, fmt.Errorf("应为 string，实际为 %T", value)
+
+	case VarTypeNumber:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case json.Number:
+			return v.Float64()
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("应为 number，实际为 %q", v)
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("应为 number，实际为 %T", value)
+
+	case VarTypeBoolean:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("应为 boolean，实际为 %q", v)
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("应为 boolean，实际为 %T", value)
+
+	case VarTypeArray:
+		if s, ok := value.(string); ok {
+			s = strings.TrimSpace(s)
+			if strings.HasPrefix(s, "[") {
+				var list []interface{}
+				if err := json.Unmarshal([]byte(s), &list); err != nil {
+					return nil, fmt.Errorf("不是合法的 JSON 数组: %v", err)
+				}
+				return list, nil
+			}
+			list := []interface{}{}
+			for _, part := range listSeparators.Split(s, -1) {
+				if part = strings.TrimSpace(part); part != "" {
+					list = append(list, part)
+				}
+			}
+			return list, nil
+		}
+		rv := reflect.ValueOf(value)
+		if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+			return nil, fmt.Errorf("应为 array，实际为 %T", value)
+		}
+		return value, nil
+
+	case VarTypeObject:
+		if s, ok := value.(string); ok {
+			var obj map[string]interface{}
+			if err := json.Unmarshal([]byte(s), &obj); err != nil {
+				return nil, fmt.Errorf("不是合法的 JSON 对象: %v", err)
+			}
+			return obj, nil
+		}
+		if reflect.ValueOf(value).Kind() != reflect.Map {
+			return nil, fmt.Errorf("应为 object，实际为 %T", value)
+		}
+		return value, nil
+	}
+	return nil, fmt.Errorf("类型 %s 不受支持", typ)
+}
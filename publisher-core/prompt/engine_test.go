@@ -0,0 +1,189 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"publisher-core/database"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestService(t *testing.T) *Service {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&database.PromptTemplate{}, &database.PromptTemplateVersion{},
+		&database.PromptTemplateUsage{}, &database.PromptTemplateABTest{}, &database.PromptExperimentAssignment{}, &database.PromptExperimentOutcome{}, &database.PromptExperimentPost{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	return NewService(db)
+}
+
+func TestRenderTemplateSyntax(t *testing.T) {
+	s := newTestService(t)
+	if _, err := s.CreateTemplate(&CreateTemplateRequest{
+		TemplateID: "footer",
+		Name:       "结尾",
+		Content:    `请使用{{.platform | upper}}平台的风格。`,
+	}); err != nil {
+		t.Fatalf("CreateTemplate footer failed: %v", err)
+	}
+	if _, err := s.CreateTemplate(&CreateTemplateRequest{
+		TemplateID: "article",
+		Name:       "文章",
+		Content: `主题：{{.topic | truncate 4}}
+{{if .keywords}}关键词：{{.keywords | join "、"}}
+{{end}}{{range $i, $t := .related}}{{$i}}. {{$t}}
+{{end}}字数：{{.words}}，配图：{{.images}}，数据：{{.meta | json}}
+{{template "footer" .}}`,
+		Variables: []VariableDefinition{
+			{Name: "topic", Type: "string", Required: true},
+			{Name: "keywords", Type: "array"},
+			{Name: "related", Type: "array"},
+			{Name: "words", Type: "number", Required: true, Default: "500"},
+			{Name: "images", Type: "boolean", Default: "true"},
+			{Name: "meta", Type: "object"},
+			{Name: "platform", Type: "string", Default: "douyin"},
+		},
+	}); err != nil {
+		t.Fatalf("CreateTemplate article failed: %v", err)
+	}
+
+	variables := map[string]interface{}{
+		"topic":    "人工智能改变生活",
+		"keywords": "AI，大模型、 agent",
+		"related":  []interface{}{"{{.topic}}", "机器人"},
+		"meta":     `{"source": "weibo"}`,
+	}
+	rendered, err := s.RenderTemplate("article", variables)
+	if err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+
+	want := `主题：人工智能…
+关键词：AI、大模型、agent
+0. {{.topic}}
+1. 机器人
+字数：500，配图：true，数据：{"source":"weibo"}
+请使用DOUYIN平台的风格。`
+	if rendered != want {
+		t.Errorf("Unexpected rendering:\n%s\nwant:\n%s", rendered, want)
+	}
+	if _, ok := variables["words"]; ok {
+		t.Error("RenderTemplate should not modify the caller's variables")
+	}
+
+	// 可选变量缺失时条件不成立
+	rendered, err = s.RenderTemplate("article", map[string]interface{}{"topic": "AI", "words": 100})
+	if err != nil {
+		t.Fatalf("RenderTemplate without optional variables failed: %v", err)
+	}
+	if strings.Contains(rendered, "关键词") || !strings.Contains(rendered, "字数：100") {
+		t.Errorf("Unexpected rendering without optional variables:\n%s", rendered)
+	}
+
+	if _, err := s.RenderTemplate("article", map[string]interface{}{"words": "很多"}); err == nil ||
+		!strings.Contains(err.Error(), "缺少必需变量: topic") || !strings.Contains(err.Error(), "变量 words 应为 number") {
+		t.Errorf("Expected typed validation errors, got %v", err)
+	}
+}
+
+func TestTemplateValidation(t *testing.T) {
+	s := newTestService(t)
+
+	cases := map[string]string{
+		"syntax":  `{{if .x}}未闭合`,
+		"partial": `{{template "missing" .}}`,
+		"range":   `{{range 1000000000}}x{{end}}`,
+		"var":     `{{$n := 1000000000}}{{range $n}}x{{end}}`,
+		"self":    `{{template "self" .}}`,
+	}
+	for id, content := range cases {
+		if _, err := s.CreateTemplate(&CreateTemplateRequest{TemplateID: id, Name: id, Content: content}); err == nil {
+			t.Errorf("Expected template %q to be rejected", id)
+		}
+	}
+
+	if _, err := s.CreateTemplate(&CreateTemplateRequest{
+		TemplateID: "typed",
+		Name:       "typed",
+		Content:    `{{.n}}`,
+		Variables:  []VariableDefinition{{Name: "n", Type: "number", Default: "abc"}},
+	}); err == nil {
+		t.Error("Expected invalid default value to be rejected")
+	}
+
+	// 静态检查之外的数字和过多的循环在渲染时拒绝
+	db := s.db
+	runtime := map[string]string{
+		"with":     `{{with $n := 1000000000}}{{range $n}}{{end}}{{end}}`,
+		"len":      `{{range len .items}}{{end}}`,
+		"nested":   `{{range .items}}{{range $.items}}{{range $.items}}{{range $.items}}{{end}}{{end}}{{end}}{{end}}`,
+		"variable": `{{$n := len .items}}{{range $n}}{{end}}`,
+	}
+	for id, content := range runtime {
+		db.Create(&database.PromptTemplate{TemplateID: id, Name: id, Type: "t", Content: content, IsActive: true})
+		items := make([]interface{}, 100)
+		if _, err := s.RenderTemplate(id, map[string]interface{}{"items": items}); err == nil {
+			t.Errorf("Expected template %q to fail at render time", id)
+		}
+	}
+	if rendered, err := s.RenderTemplate("nested", map[string]interface{}{"items": []interface{}{1, 2}}); err != nil || rendered != "" {
+		t.Errorf("Expected small nested ranges to render, got %q %v", rendered, err)
+	}
+
+	// 互相引用的模板在渲染时报告循环
+	db.Create(&database.PromptTemplate{TemplateID: "a", Name: "a", Type: "t", Content: `{{template "b" .}}`, IsActive: true})
+	db.Create(&database.PromptTemplate{TemplateID: "b", Name: "b", Type: "t", Content: `{{template "a" .}}`, IsActive: true})
+	if _, err := s.RenderTemplate("a", nil); err == nil || !strings.Contains(err.Error(), "循环引用") {
+		t.Errorf("Expected cycle error, got %v", err)
+	}
+}
+
+func TestLegacyTemplates(t *testing.T) {
+	s := newTestService(t)
+	legacy := &database.PromptTemplate{
+		TemplateID: "legacy",
+		Name:       "legacy",
+		Type:       "content_generation",
+		Content:    "主题：{{topic}}，平台：{{ platform }}",
+		Variables:  `[{"name":"topic","type":"string","required":true}]`,
+		Version:    1,
+		IsActive:   true,
+	}
+	s.db.Create(legacy)
+
+	rendered, err := s.RenderTemplate("legacy", map[string]interface{}{"topic": "用户输入 {{platform}}", "platform": "抖音"})
+	if err != nil {
+		t.Fatalf("RenderTemplate legacy failed: %v", err)
+	}
+	if rendered != "主题：用户输入 {{platform}}，平台：抖音" {
+		t.Errorf("Unexpected legacy rendering: %s", rendered)
+	}
+
+	if _, err := s.RenderTemplate("legacy", map[string]interface{}{"topic": "AI"}); err == nil {
+		t.Error("Expected missing undeclared variable to fail")
+	}
+
+	migrated, err := s.MigrateLegacyTemplates()
+	if err != nil || migrated != 1 {
+		t.Fatalf("MigrateLegacyTemplates = %d, %v", migrated, err)
+	}
+	template, _ := s.GetTemplate("legacy")
+	if template.Content != "主题：{{.topic}}，平台：{{.platform}}" || template.Version != 2 {
+		t.Errorf("Unexpected migrated template: %q v%d", template.Content, template.Version)
+	}
+	if migrated, _ := s.MigrateLegacyTemplates(); migrated != 0 {
+		t.Errorf("Expected migration to be idempotent, migrated %d", migrated)
+	}
+
+	if got := ConvertLegacySyntax("{{if .x}}{{name}}{{else}}{{end}}"); got != "{{if .x}}{{.name}}{{else}}{{end}}" {
+		t.Errorf("Unexpected conversion: %s", got)
+	}
+}
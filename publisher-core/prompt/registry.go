@@ -0,0 +1,294 @@
+package prompt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"publisher-core/ai/provider"
+	"publisher-core/cost"
+	"publisher-core/database"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// lookupTemplate 查询模板，数据库中没有时使用同ID的内置模板
+// 内置模板在 InitializeDefaultTemplates 之前或未配置数据库时也能按ID渲染
+func (s *Service) lookupTemplate(templateID string) (*database.PromptTemplate, error) {
+	if s.db != nil {
+		template, err := s.GetTemplate(templateID)
+		if !errors.Is(err, ErrTemplateNotFound) {
+			return template, err
+		}
+	}
+	builtin, ok := findBuiltin(templateID)
+	if !ok {
+		return nil, ErrTemplateNotFound
+	}
+	return builtin.record()
+}
+
+// Generate 按ID渲染模板并调用模型生成，调用结果记录到模板使用记录
+// adjust 可在发送前修改请求，如使用调用方配置的模型，为 nil 时使用模板的生成参数
+func (s *Service) Generate(ctx context.Context, gen provider.Generator, templateID string, variables map[string]interface{}, adjust func(*provider.GenerateOptions)) (*provider.GenerateResult, error) {
+	return s.generate(ctx, templateID, variables, adjust, func(opts *provider.GenerateOptions) (*provider.GenerateResult, error) {
+		return gen.Generate(ctx, opts)
+	})
+}
+
+// GenerateStructured 按ID渲染模板并生成结构化输出，解析到 out
+func (s *Service) GenerateStructured(ctx context.Context, gen provider.Generator, templateID string, variables map[string]interface{}, out interface{}, adjust func(*provider.GenerateOptions)) (*provider.GenerateResult, error) {
+	return s.generate(ctx, templateID, variables, adjust, func(opts *provider.GenerateOptions) (*provider.GenerateResult, error) {
+		return provider.GenerateStructured(ctx, gen, opts, out)
+	})
+}
+
+// generate 渲染模板并调用模型
+// templateID 是运行中A/B测试的对照组时，按上下文中的用户或请求ID改用分配到的变体，并记录调用结果
+func (s *Service) generate(ctx context.Context, templateID string, variables map[string]interface{}, adjust func(*provider.GenerateOptions), call func(*provider.GenerateOptions) (*provider.GenerateResult, error)) (*provider.GenerateResult, error) {
+	unitID := rolloutUnit(ctx)
+	testID, variantID := s.experimentVariant(templateID, unitID)
+	if variantID != "" {
+		templateID = variantID
+	}
+
+	def, err := s.resolveDefinition(templateID, unitID)
+	if err != nil {
+		return nil, fmt.Errorf("渲染模板 %s 失败: %w", templateID, err)
+	}
+	opts, err := s.generateOptions(def, variables)
+	if err != nil {
+		return nil, fmt.Errorf("渲染模板 %s 失败: %w", templateID, err)
+	}
+	if adjust != nil {
+		adjust(opts)
+	}
+
+	start := time.Now()
+	result, err := call(opts)
+	s.RecordUsage(ctx, templateID, def.version, variables, result, err, time.Since(start))
+	if testID != 0 {
+		if recordErr := NewABTestService(s.db).RecordTestCall(testID, unitID, err == nil); recordErr != nil {
+			logrus.Warnf("记录测试 %d 调用失败: %v", testID, recordErr)
+		}
+	}
+	return result, err
+}
+
+// experimentVariant 查找以模板为对照组的运行中测试并为单元选择变体，没有测试或无法分组时返回零值
+func (s *Service) experimentVariant(templateID, unitID string) (uint, string) {
+	if s.db == nil || unitID == "" {
+		return 0, ""
+	}
+	var test database.PromptTemplateABTest
+	err := s.db.Where("template_a_id = ? AND status = ?", templateID, "running").Order("id DESC").First(&test).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Warnf("查询模板 %s 的A/B测试失败: %v", templateID, err)
+		}
+		return 0, ""
+	}
+	variantID, err := NewABTestService(s.db).SelectTemplateForTest(test.ID, unitID)
+	if err != nil {
+		logrus.Warnf("测试 %d 分组失败: %v", test.ID, err)
+		return 0, ""
+	}
+	return test.ID, variantID
+}
+
+// RecordUsage 记录一次模板调用，用户和请求ID取自上下文中的成本归属
+// 未配置数据库时不记录，写入失败只记日志，不影响调用方
+func (s *Service) RecordUsage(ctx context.Context, templateID string, version int, variables map[string]interface{}, result *provider.GenerateResult, callErr error, duration time.Duration) {
+	if s.db == nil {
+		return
+	}
+
+	attr := cost.AttributionFromContext(ctx)
+	usage := &database.PromptTemplateUsage{
+		TemplateID: templateID,
+		Version:    version,
+		UserID:     attr.UserID,
+		SessionID:  attr.RequestID,
+		Success:    callErr == nil,
+		DurationMs: int(duration.Milliseconds()),
+		CreatedAt:  time.Now(),
+	}
+	if data, err := json.Marshal(variables); err == nil {
+		usage.InputData = string(data)
+	}
+	if result != nil {
+		usage.OutputData = result.Content
+		usage.TokensUsed = result.InputTokens + result.OutputTokens
+	}
+	if callErr != nil {
+		usage.ErrorMessage = callErr.Error()
+	}
+
+	if err := s.db.Create(usage).Error; err != nil {
+		logrus.Warnf("记录模板 %s 使用失败: %v", templateID, err)
+		return
+	}
+	// success 字段有默认值，创建时零值会被忽略并回填为 true
+	if callErr != nil {
+		s.db.Model(usage).Update("success", false)
+	}
+}
+
+// UsageStats 模板使用统计
+type UsageStats struct {
+	TemplateID    string  `json:"template_id"`
+	TotalCalls    int64   `json:"total_calls"`
+	SuccessCalls  int64   `json:"success_calls"`
+	SuccessRate   float64 `json:"success_rate"`
+	AvgDurationMs float64 `json:"avg_duration_ms"`
+	TotalTokens   int64   `json:"total_tokens"`
+	AvgRating     float64 `json:"avg_rating"`
+	// Versions 按模板版本分组的统计，灰度发布时用于对比新旧版本
+	Versions []VersionUsageStats `json:"versions"`
+}
+
+// VersionUsageStats 单个版本的使用统计
+type VersionUsageStats struct {
+	Version       int     `json:"version"`
+	TotalCalls    int64   `json:"total_calls"`
+	SuccessCalls  int64   `json:"success_calls"`
+	SuccessRate   float64 `json:"success_rate"`
+	AvgDurationMs float64 `json:"avg_duration_ms"`
+	TotalTokens   int64   `json:"total_tokens"`
+}
+
+// GetUsageStats 统计模板在 since 之后的使用情况，since 为零值时统计全部记录
+func (s *Service) GetUsageStats(templateID string, since time.Time) (*UsageStats, error) {
+	query := s.db.Model(&database.PromptTemplateUsage{}).Where("template_id = ?", templateID)
+	if !since.IsZero() {
+		query = query.Where("created_at >= ?", since)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var versions []VersionUsageStats
+	if err := query.Select("version, COUNT(*) AS total_calls, " +
+		"COALESCE(SUM(CASE WHEN success THEN 1 ELSE 0 END), 0) AS success_calls, " +
+		"COALESCE(AVG(duration_ms), 0) AS avg_duration_ms, " +
+		"COALESCE(SUM(tokens_used), 0) AS total_tokens").
+		Group("version").Order("version").Scan(&versions).Error; err != nil {
+		return nil, fmt.Errorf("统计模板使用失败: %w", err)
+	}
+
+	var rating struct{ AvgRating float64 }
+	if err := query.Where("user_rating > 0").
+		Select("COALESCE(AVG(user_rating), 0) AS avg_rating").Scan(&rating).Error; err != nil {
+		return nil, fmt.Errorf("统计模板评分失败: %w", err)
+	}
+
+	stats := &UsageStats{TemplateID: templateID, AvgRating: rating.AvgRating, Versions: versions}
+	var totalDuration float64
+	for i := range versions {
+		v := &versions[i]
+		if v.TotalCalls > 0 {
+			v.SuccessRate = float64(v.SuccessCalls) / float64(v.TotalCalls)
+		}
+		stats.TotalCalls += v.TotalCalls
+		stats.SuccessCalls += v.SuccessCalls
+		stats.TotalTokens += v.TotalTokens
+		totalDuration += v.AvgDurationMs * float64(v.TotalCalls)
+	}
+	if stats.TotalCalls > 0 {
+		stats.SuccessRate = float64(stats.SuccessCalls) / float64(stats.TotalCalls)
+		stats.AvgDurationMs = totalDuration / float64(stats.TotalCalls)
+	}
+	return stats, nil
+}
+
+// legacyContentTemplate 旧版 JSON 模板文件的格式，每个文件一个模板
+type legacyContentTemplate struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Platform    string   `json:"platform"`
+	Category    string   `json:"category"`
+	Template    string   `json:"template"`
+	Examples    []string `json:"examples"`
+	Tags        []string `json:"tags"`
+	UsageCount  int      `json:"usage_count"`
+	Rating      float64  `json:"rating"`
+	Variables   []struct {
+		Name        string   `json:"name"`
+		Description string   `json:"description"`
+		Required    bool     `json:"required"`
+		Default     string   `json:"default"`
+		Options     []string `json:"options,omitempty"`
+	} `json:"variables"`
+}
+
+// ImportLegacyTemplates 导入旧版 JSON 模板目录，模板ID沿用文件中的 id
+// {name} 占位符转换为 {{.name}}，已存在的模板跳过。返回导入的模板数
+func (s *Service) ImportLegacyTemplates(dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return imported, fmt.Errorf("读取 %s 失败: %w", file, err)
+		}
+		var legacy legacyContentTemplate
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return imported, fmt.Errorf("解析 %s 失败: %w", file, err)
+		}
+		if legacy.ID == "" {
+			legacy.ID = strings.TrimSuffix(filepath.Base(file), ".json")
+		}
+
+		if _, err := s.GetTemplate(legacy.ID); err == nil {
+			logrus.Infof("模板 %s 已存在，跳过导入", legacy.ID)
+			continue
+		} else if !errors.Is(err, ErrTemplateNotFound) {
+			return imported, err
+		}
+
+		content := legacy.Template
+		variables := make([]VariableDefinition, 0, len(legacy.Variables))
+		for _, v := range legacy.Variables {
+			content = strings.ReplaceAll(content, "{"+v.Name+"}", "{{."+v.Name+"}}")
+			variables = append(variables, VariableDefinition{
+				Name:        v.Name,
+				Type:        VarTypeString,
+				Required:    v.Required,
+				Default:     v.Default,
+				Description: v.Description,
+			})
+		}
+
+		req := &CreateTemplateRequest{
+			TemplateID:  legacy.ID,
+			Name:        legacy.Name,
+			Type:        "content_template",
+			Category:    legacy.Category,
+			Description: legacy.Description,
+			Content:     content,
+			Variables:   variables,
+			Tags:        legacy.Tags,
+			Metadata: map[string]interface{}{
+				"source":      "legacy_json",
+				"platform":    legacy.Platform,
+				"examples":    legacy.Examples,
+				"usage_count": legacy.UsageCount,
+				"rating":      legacy.Rating,
+			},
+		}
+		if _, err := s.CreateTemplate(req); err != nil {
+			return imported, fmt.Errorf("导入模板 %s 失败: %w", legacy.ID, err)
+		}
+		imported++
+	}
+	return imported, nil
+}
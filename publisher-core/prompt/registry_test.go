@@ -0,0 +1,208 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"publisher-core/ai/provider"
+	"publisher-core/cost"
+	"publisher-core/database"
+)
+
+type fakeGenerator struct {
+	err  error
+	last *provider.GenerateOptions
+}
+
+func (g *fakeGenerator) Generate(ctx context.Context, opts *provider.GenerateOptions) (*provider.GenerateResult, error) {
+	g.last = opts
+	if g.err != nil {
+		return nil, g.err
+	}
+	return &provider.GenerateResult{Content: "要点一\n要点二", InputTokens: 30, OutputTokens: 10}, nil
+}
+
+// sampleVariables 按变量类型构造示例值
+func sampleVariables(defs []VariableDefinition) map[string]interface{} {
+	vars := make(map[string]interface{}, len(defs))
+	for _, def := range defs {
+		switch def.Type {
+		case VarTypeNumber:
+			vars[def.Name] = 3
+		case VarTypeBoolean:
+			vars[def.Name] = true
+		case VarTypeArray:
+			vars[def.Name] = []string{"甲", "乙"}
+		case VarTypeObject:
+			vars[def.Name] = map[string]interface{}{"views": 100}
+		default:
+			vars[def.Name] = "示例"
+		}
+	}
+	return vars
+}
+
+func TestBuiltinTemplatesRender(t *testing.T) {
+	s := NewService(nil)
+	seen := make(map[string]bool)
+	for _, builtin := range builtinTemplates() {
+		if seen[builtin.TemplateID] {
+			t.Errorf("Duplicate builtin template ID %s", builtin.TemplateID)
+		}
+		seen[builtin.TemplateID] = true
+
+		if err := s.validateContent(builtin.TemplateID, builtin.Content, builtin.Variables); err != nil {
+			t.Errorf("Builtin template %s is invalid: %v", builtin.TemplateID, err)
+		}
+		if err := s.validateChat(builtin.TemplateID, builtin.Messages, builtin.Parameters); err != nil {
+			t.Errorf("Builtin template %s is invalid: %v", builtin.TemplateID, err)
+		}
+		opts, err := s.RenderToGenerateOptions(builtin.TemplateID, sampleVariables(builtin.Variables))
+		if err != nil {
+			t.Errorf("Failed to render builtin template %s: %v", builtin.TemplateID, err)
+			continue
+		}
+		if strings.Contains(opts.Messages[len(opts.Messages)-1].Content, "<no value>") {
+			t.Errorf("Builtin template %s rendered a missing variable", builtin.TemplateID)
+		}
+	}
+}
+
+func TestRegistryLoadsByID(t *testing.T) {
+	s := newTestService(t)
+
+	// 数据库中没有记录时使用内置模板
+	opts, err := s.RenderToGenerateOptions(TemplateVideoTopics, map[string]interface{}{"text": "转录内容"})
+	if err != nil {
+		t.Fatalf("RenderToGenerateOptions failed: %v", err)
+	}
+	if !strings.Contains(opts.Messages[0].Content, "用中文回答") || opts.Temperature != 0.3 {
+		t.Errorf("Unexpected builtin rendering: %+v", opts)
+	}
+
+	// 初始化后修改数据库中的模板，按ID加载到新内容
+	if err := InitializeDefaultTemplates(s.db); err != nil {
+		t.Fatalf("InitializeDefaultTemplates failed: %v", err)
+	}
+	var count int64
+	s.db.Model(&database.PromptTemplate{}).Count(&count)
+	if int(count) != len(builtinTemplates()) {
+		t.Errorf("Expected %d templates, got %d", len(builtinTemplates()), count)
+	}
+	if _, err := s.UpdateTemplate(TemplateVideoTopics, &UpdateTemplateRequest{
+		Name:      "视频主题提取",
+		Messages:  []MessageTemplate{{Role: provider.RoleUser, Content: "提取主题：{{.text}}"}},
+		Variables: []VariableDefinition{{Name: "text", Type: VarTypeString, Required: true}},
+		IsActive:  true,
+	}); err != nil {
+		t.Fatalf("UpdateTemplate failed: %v", err)
+	}
+	opts, err = s.RenderToGenerateOptions(TemplateVideoTopics, map[string]interface{}{"text": "转录内容"})
+	if err != nil || opts.Messages[0].Content != "提取主题：转录内容" {
+		t.Errorf("Expected edited template to be used: %+v, %v", opts, err)
+	}
+
+	// 停用的模板不回退到内置定义
+	if _, err := s.UpdateTemplate(TemplateVideoTopics, &UpdateTemplateRequest{Name: "视频主题提取", Content: "x", IsActive: false}); err != nil {
+		t.Fatalf("UpdateTemplate failed: %v", err)
+	}
+	if _, err := s.RenderToGenerateOptions(TemplateVideoTopics, map[string]interface{}{"text": "x"}); err == nil {
+		t.Error("Expected inactive template to be rejected")
+	}
+	if _, err := s.RenderToGenerateOptions("no-such-template", nil); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("Expected ErrTemplateNotFound, got %v", err)
+	}
+}
+
+func TestGenerateRecordsUsage(t *testing.T) {
+	s := newTestService(t)
+	ctx := cost.WithAttribution(context.Background(), cost.Attribution{UserID: "u1", RequestID: "req-1"})
+
+	gen := &fakeGenerator{}
+	result, err := s.Generate(ctx, gen, TemplateVideoKeyPoints, map[string]interface{}{"text": "转录内容"}, func(opts *provider.GenerateOptions) {
+		opts.Model = "gpt-4o-mini"
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if result.Content == "" || gen.last.Model != "gpt-4o-mini" || gen.last.Temperature != 0.3 {
+		t.Errorf("Unexpected request: %+v", gen.last)
+	}
+
+	gen.err = errors.New("upstream error")
+	if _, err := s.Generate(ctx, gen, TemplateVideoKeyPoints, map[string]interface{}{"text": "转录内容"}, nil); err == nil {
+		t.Fatal("Expected generator error to be returned")
+	}
+	if _, err := s.Generate(ctx, gen, TemplateVideoKeyPoints, nil, nil); err == nil {
+		t.Fatal("Expected missing variable to be rejected")
+	}
+
+	var usages []database.PromptTemplateUsage
+	s.db.Order("id").Find(&usages)
+	if len(usages) != 2 {
+		t.Fatalf("Expected 2 usage records, got %d", len(usages))
+	}
+	if !usages[0].Success || usages[0].UserID != "u1" || usages[0].SessionID != "req-1" ||
+		usages[0].TokensUsed != 40 || !strings.Contains(usages[0].InputData, "转录内容") {
+		t.Errorf("Unexpected usage record: %+v", usages[0])
+	}
+	if usages[1].Success || usages[1].ErrorMessage != "upstream error" {
+		t.Errorf("Expected failed usage record: %+v", usages[1])
+	}
+
+	stats, err := s.GetUsageStats(TemplateVideoKeyPoints, time.Time{})
+	if err != nil {
+		t.Fatalf("GetUsageStats failed: %v", err)
+	}
+	if stats.TotalCalls != 2 || stats.SuccessCalls != 1 || stats.SuccessRate != 0.5 || stats.TotalTokens != 40 {
+		t.Errorf("Unexpected usage stats: %+v", stats)
+	}
+}
+
+func TestImportLegacyTemplates(t *testing.T) {
+	s := newTestService(t)
+	dir := t.TempDir()
+	legacy := `{
+  "id": "product-review",
+  "name": "Product Review",
+  "platform": "xiaohongshu",
+  "category": "review",
+  "template": "[{title}]\n\nPros: {pros}\n\n#{tags}",
+  "variables": [
+    {"name": "title", "type": "text", "required": true},
+    {"name": "pros", "type": "text", "required": true},
+    {"name": "tags", "type": "text", "default": "review"}
+  ],
+  "tags": ["review"],
+  "usage_count": 12
+}`
+	if err := os.WriteFile(filepath.Join(dir, "product-review.json"), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := s.ImportLegacyTemplates(dir)
+	if err != nil || imported != 1 {
+		t.Fatalf("ImportLegacyTemplates = %d, %v", imported, err)
+	}
+	content, err := s.RenderTemplate("product-review", map[string]interface{}{"title": "新耳机", "pros": "续航长"})
+	if err != nil {
+		t.Fatalf("RenderTemplate failed: %v", err)
+	}
+	if content != "[新耳机]\n\nPros: 续航长\n\n#review" {
+		t.Errorf("Unexpected rendered content: %q", content)
+	}
+	template, _ := s.GetTemplate("product-review")
+	if !strings.Contains(template.Metadata, `"platform":"xiaohongshu"`) || !strings.Contains(template.Metadata, `"usage_count":12`) {
+		t.Errorf("Expected legacy fields in metadata: %s", template.Metadata)
+	}
+
+	// 再次导入时跳过已存在的模板
+	if imported, err := s.ImportLegacyTemplates(dir); err != nil || imported != 0 {
+		t.Errorf("Expected existing template to be skipped: %d, %v", imported, err)
+	}
+}
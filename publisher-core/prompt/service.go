@@ -4,8 +4,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"regexp"
-	"strings"
 	"time"
 
 	"publisher-core/database"
@@ -18,6 +16,9 @@ type Service struct {
 	db *gorm.DB
 }
 
+// ErrTemplateNotFound 模板不存在
+var ErrTemplateNotFound = errors.New("模板不存在")
+
 // NewService 创建新的提示词模板服务
 func NewService(db *gorm.DB) *Service {
 	return &Service{db: db}
@@ -26,7 +27,7 @@ func NewService(db *gorm.DB) *Service {
 // VariableDefinition 变量定义
 type VariableDefinition struct {
 	Name        string `json:"name"`
-	Type        string `json:"type"`        // string, number, boolean, array
+	Type        string `json:"type"`        // string, number, boolean, array, object
 	Required    bool   `json:"required"`
 	Default     string `json:"default"`
 	Description string `json:"description"`
@@ -50,6 +51,9 @@ type CreateTemplateRequest struct {
 	IsDefault   bool                 `json:"is_default"`
 	Tags        []string             `json:"tags"`
 	Metadata    map[string]interface{} `json:"metadata"`
+	// Messages 多消息模板，非空时模板为 chat 格式，Content 不再使用
+	Messages   []MessageTemplate     `json:"messages,omitempty"`
+	Parameters *GenerationParameters `json:"parameters,omitempty"`
 }
 
 // UpdateTemplateRequest 更新模板请求
@@ -64,6 +68,8 @@ type UpdateTemplateRequest struct {
 	IsDefault   bool                 `json:"is_default"`
 	Tags        []string             `json:"tags"`
 	Metadata    map[string]interface{} `json:"metadata"`
+	Messages    []MessageTemplate      `json:"messages,omitempty"`
+	Parameters  *GenerationParameters  `json:"parameters,omitempty"`
 	ChangeNote  string               `json:"change_note"`
 }
 
@@ -78,6 +84,17 @@ func (s *Service) CreateTemplate(req *CreateTemplateRequest) (*database.PromptTe
 		return nil, errors.New("模板ID已存在")
 	}
 
+	if err := s.validateContent(req.TemplateID, req.Content, req.Variables); err != nil {
+		return nil, err
+	}
+	if err := s.validateChat(req.TemplateID, req.Messages, req.Parameters); err != nil {
+		return nil, err
+	}
+	format, messagesJSON, parametersJSON, err := encodeChat(req.Messages, req.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
 	// 序列化变量定义
 	variablesJSON, err := json.Marshal(req.Variables)
 	if err != nil {
@@ -103,6 +120,9 @@ func (s *Service) CreateTemplate(req *CreateTemplateRequest) (*database.PromptTe
 		Category:    req.Category,
 		Description: req.Description,
 		Content:     req.Content,
+		Format:      format,
+		Messages:    messagesJSON,
+		Parameters:  parametersJSON,
 		Variables:   string(variablesJSON),
 		Version:     1,
 		IsActive:    true,
@@ -131,7 +151,7 @@ func (s *Service) GetTemplate(templateID string) (*database.PromptTemplate, erro
 	var template database.PromptTemplate
 	if err := s.db.Where("template_id = ?", templateID).First(&template).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("模板不存在")
+			return nil, ErrTemplateNotFound
 		}
 		return nil, fmt.Errorf("查询模板失败: %w", err)
 	}
@@ -181,12 +201,28 @@ func (s *Service) UpdateTemplate(templateID string, req *UpdateTemplateRequest)
 		return nil, errors.New("系统模板不可修改")
 	}
 
+	if err := s.validateContent(templateID, req.Content, req.Variables); err != nil {
+		return nil, err
+	}
+	if err := s.validateChat(templateID, req.Messages, req.Parameters); err != nil {
+		return nil, err
+	}
+	format, messagesJSON, parametersJSON, err := encodeChat(req.Messages, req.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
 	// 序列化变量定义
 	variablesJSON, err := json.Marshal(req.Variables)
 	if err != nil {
 		return nil, fmt.Errorf("序列化变量定义失败: %w", err)
 	}
 
+	version, err := s.nextVersion(template)
+	if err != nil {
+		return nil, err
+	}
+
 	// 序列化标签
 	tagsJSON, err := json.Marshal(req.Tags)
 	if err != nil {
@@ -206,12 +242,15 @@ func (s *Service) UpdateTemplate(templateID string, req *UpdateTemplateRequest)
 		"category":    req.Category,
 		"description": req.Description,
 		"content":     req.Content,
+		"format":      format,
+		"messages":    messagesJSON,
+		"parameters":  parametersJSON,
 		"variables":   string(variablesJSON),
 		"is_active":   req.IsActive,
 		"is_default":  req.IsDefault,
 		"tags":        string(tagsJSON),
 		"metadata":    string(metadataJSON),
-		"version":     template.Version + 1,
+		"version":     version,
 		"updated_at":  time.Now(),
 	}
 
@@ -222,7 +261,7 @@ func (s *Service) UpdateTemplate(templateID string, req *UpdateTemplateRequest)
 	// 创建版本记录
 	changeNote := req.ChangeNote
 	if changeNote == "" {
-		changeNote = fmt.Sprintf("更新至版本 %d", template.Version+1)
+		changeNote = fmt.Sprintf("更新至版本 %d", version)
 	}
 	if err := s.createVersionRecord(template, changeNote); err != nil {
 		return nil, fmt.Errorf("创建版本记录失败: %w", err)
@@ -251,58 +290,104 @@ func (s *Service) DeleteTemplate(templateID string) error {
 	return nil
 }
 
-// RenderTemplate 渲染模板（替换变量）
+// RenderTemplate 渲染模板
+// 变量按模板的变量定义校验类型，缺失的必需变量使用默认值，模板语法见 engine.go
 func (s *Service) RenderTemplate(templateID string, variables map[string]interface{}) (string, error) {
-	template, err := s.GetTemplate(templateID)
+	def, err := s.resolveDefinition(templateID, "")
+	if err != nil {
+		return "", err
+	}
+	if def.format == FormatChat {
+		return "", errors.New("chat 格式模板请使用 RenderMessages 或 RenderToGenerateOptions 渲染")
+	}
+
+	return s.render(def.id, def.content, def.variables, variables)
+}
+
+// render 校验变量并渲染模板内容
+func (s *Service) render(name, content string, varDefs []VariableDefinition, variables map[string]interface{}) (string, error) {
+	data, err := prepareVariables(varDefs, variables)
 	if err != nil {
 		return "", err
 	}
 
-	// 检查模板是否激活
+	tmpl, err := compileTemplate(name, content, s.loadPartial)
+	if err != nil {
+		return "", err
+	}
+	return execute(tmpl, data)
+}
+
+// loadPartial 加载被引用的模板，被引用的模板必须处于激活状态
+func (s *Service) loadPartial(templateID string) (string, error) {
+	template, err := s.lookupTemplate(templateID)
+	if err != nil {
+		return "", err
+	}
 	if !template.IsActive {
 		return "", errors.New("模板未激活")
 	}
-
-	// 解析变量定义
-	var varDefs []VariableDefinition
-	if template.Variables != "" {
-		if err := json.Unmarshal([]byte(template.Variables), &varDefs); err != nil {
-			return "", fmt.Errorf("解析变量定义失败: %w", err)
-		}
+	if template.Format == FormatChat {
+		return "", errors.New("chat 格式模板不能被引用")
 	}
+	return template.Content, nil
+}
 
-	// 验证必需变量
-	for _, varDef := range varDefs {
-		if varDef.Required {
-			if _, ok := variables[varDef.Name]; !ok {
-				if varDef.Default == "" {
-					return "", fmt.Errorf("缺少必需变量: %s", varDef.Name)
-				}
-				variables[varDef.Name] = varDef.Default
+// validateContent 检查模板语法和引用的模板，保存前调用
+func (s *Service) validateContent(templateID, content string, varDefs []VariableDefinition) error {
+	for _, def := range varDefs {
+		if def.Name == "" {
+			return errors.New("变量名不能为空")
+		}
+		if !validVariableType(def.Type) {
+			return fmt.Errorf("变量 %s 的类型 %s 不受支持", def.Name, def.Type)
+		}
+		if def.Default != "" {
+			if _, err := convertVariable(def.Type, def.Default); err != nil {
+				return fmt.Errorf("变量 %s 的默认值%v", def.Name, err)
 			}
 		}
 	}
 
-	// 替换变量
-	content := template.Content
-	for varName, varValue := range variables {
-		placeholder := fmt.Sprintf("{{%s}}", varName)
-		valueStr := fmt.Sprintf("%v", varValue)
-		content = strings.ReplaceAll(content, placeholder, valueStr)
+	if _, err := compileTemplate(templateID, content, s.loadPartial); err != nil {
+		return err
 	}
+	return nil
+}
 
-	// 检查是否还有未替换的变量
-	re := regexp.MustCompile(`\{\{(\w+)\}\}`)
-	matches := re.FindAllStringSubmatch(content, -1)
-	if len(matches) > 0 {
-		unreplacedVars := make([]string, 0)
-		for _, match := range matches {
-			unreplacedVars = append(unreplacedVars, match[1])
-		}
-		return "", fmt.Errorf("存在未替换的变量: %s", strings.Join(unreplacedVars, ", "))
+// MigrateLegacyTemplates 把仍使用旧版 {{name}} 占位符的模板转换为新语法并记录新版本
+// 旧语法在渲染时也会自动转换，迁移后模板内容与实际渲染方式一致。返回迁移的模板数
+func (s *Service) MigrateLegacyTemplates() (int, error) {
+	var templates []database.PromptTemplate
+	if err := s.db.Find(&templates).Error; err != nil {
+		return 0, fmt.Errorf("查询模板失败: %w", err)
 	}
 
-	return content, nil
+	migrated := 0
+	for i := range templates {
+		template := &templates[i]
+		converted := ConvertLegacySyntax(template.Content)
+		if converted == template.Content {
+			continue
+		}
+
+		version, err := s.nextVersion(template)
+		if err != nil {
+			return migrated, err
+		}
+		if err := s.db.Model(template).Updates(map[string]interface{}{
+			"content":    converted,
+			"version":    version,
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+			return migrated, fmt.Errorf("迁移模板 %s 失败: %w", template.TemplateID, err)
+		}
+		if err := s.createVersionRecord(template, "迁移到新模板语法"); err != nil {
+			return migrated, fmt.Errorf("创建版本记录失败: %w", err)
+		}
+		migrated++
+	}
+	return migrated, nil
 }
 
 // createVersionRecord 创建版本记录
@@ -311,8 +396,12 @@ func (s *Service) createVersionRecord(template *database.PromptTemplate, changeN
 		TemplateID: template.TemplateID,
 		Version:    template.Version,
 		Content:    template.Content,
+		Format:     template.Format,
+		Messages:   template.Messages,
+		Parameters: template.Parameters,
 		Variables:  template.Variables,
 		ChangeNote: changeNote,
+		Status:     VersionPublished,
 		CreatedAt:  time.Now(),
 	}
 
@@ -360,12 +449,23 @@ func (s *Service) RestoreTemplateVersion(templateID string, version int) (*datab
 		}
 		return nil, fmt.Errorf("查询版本失败: %w", err)
 	}
+	if templateVersion.Status != VersionPublished {
+		return nil, fmt.Errorf("版本 %d 尚未发布，请使用发布接口", version)
+	}
+
+	next, err := s.nextVersion(template)
+	if err != nil {
+		return nil, err
+	}
 
 	// 更新模板
 	updates := map[string]interface{}{
 		"content":    templateVersion.Content,
+		"format":     templateVersion.Format,
+		"messages":   templateVersion.Messages,
+		"parameters": templateVersion.Parameters,
 		"variables":  templateVersion.Variables,
-		"version":    template.Version + 1,
+		"version":    next,
 		"updated_at": time.Now(),
 	}
 
@@ -0,0 +1,81 @@
+package prompt
+
+import "math"
+
+// sample 一个变体在目标指标上的样本统计，样本单位为分组用户或会话
+type sample struct {
+	n        int
+	mean     float64
+	variance float64 // 样本方差
+}
+
+// newSample 计算样本均值和方差
+func newSample(values []float64) sample {
+	s := sample{n: len(values)}
+	if s.n == 0 {
+		return s
+	}
+	for _, v := range values {
+		s.mean += v
+	}
+	s.mean /= float64(s.n)
+	if s.n > 1 {
+		for _, v := range values {
+			s.variance += (v - s.mean) * (v - s.mean)
+		}
+		s.variance /= float64(s.n - 1)
+	}
+	return s
+}
+
+// stdErr 均值的标准误
+func (s sample) stdErr() float64 {
+	if s.n == 0 {
+		return 0
+	}
+	return math.Sqrt(s.variance / float64(s.n))
+}
+
+// zCritical 双侧检验在显著性水平 alpha 下的临界值
+func zCritical(alpha float64) float64 {
+	return math.Sqrt2 * math.Erfinv(1-alpha)
+}
+
+// meanInterval 均值的置信区间
+func meanInterval(s sample, alpha float64) (float64, float64) {
+	margin := zCritical(alpha) * s.stdErr()
+	return s.mean - margin, s.mean + margin
+}
+
+// comparison 变体相对对照组的差异
+type comparison struct {
+	diff   float64
+	low    float64
+	high   float64
+	pValue float64
+}
+
+// welchTest 两组均值差异的 Welch 检验
+// 每组样本数达到判定要求后使用正态近似计算 p 值和差值的置信区间
+func welchTest(control, variant sample, alpha float64) comparison {
+	c := comparison{diff: variant.mean - control.mean, pValue: 1}
+	if control.n < 2 || variant.n < 2 {
+		// 样本不足以估计方差，不给出区间
+		return c
+	}
+
+	se := math.Sqrt(control.variance/float64(control.n) + variant.variance/float64(variant.n))
+	if se == 0 {
+		// 两组都没有波动，均值不同即为确定的差异
+		c.low, c.high = c.diff, c.diff
+		if c.diff != 0 {
+			c.pValue = 0
+		}
+		return c
+	}
+
+	margin := zCritical(alpha) * se
+	c.low, c.high = c.diff-margin, c.diff+margin
+	c.pValue = math.Erfc(math.Abs(c.diff/se) / math.Sqrt2)
+	return c
+}
@@ -0,0 +1,217 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"publisher-core/database"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// newInstanceID 生成当前实例标识
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.New().String()[:8])
+}
+
+// registerRunning 登记本实例运行中任务的取消函数
+func (s *QueueService) registerRunning(taskID string, cancel context.CancelFunc) {
+	s.runningMu.Lock()
+	s.running[taskID] = cancel
+	s.runningMu.Unlock()
+}
+
+// unregisterRunning 移除运行中任务的取消函数
+func (s *QueueService) unregisterRunning(taskID string) {
+	s.runningMu.Lock()
+	delete(s.running, taskID)
+	s.runningMu.Unlock()
+}
+
+// signalCancel 通知本实例运行中的任务退出，任务不在本实例运行时返回 false
+func (s *QueueService) signalCancel(taskID string) bool {
+	s.runningMu.Lock()
+	cancel, exists := s.running[taskID]
+	s.runningMu.Unlock()
+
+	if exists {
+		cancel()
+	}
+	return exists
+}
+
+// requestCancel 请求取消运行中的任务
+// 任务进入取消中状态，本实例运行的任务立即收到取消信号，
+// 其他实例运行的任务由其心跳检查到取消请求后退出
+func (s *QueueService) requestCancel(task *database.AsyncTask) error {
+	now := time.Now()
+	result := s.db.Model(&database.AsyncTask{}).
+		Where("task_id = ? AND status = ?", task.TaskID, database.TaskStatusRunning).
+		Updates(map[string]interface{}{
+			"status":              database.TaskStatusCancelling,
+			"cancel_requested_at": now,
+			"updated_at":          now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		current, err := s.GetTask(task.TaskID)
+		if err == nil && current.Status == database.TaskStatusCancelling {
+			return nil
+		}
+		return fmt.Errorf("任务不存在或无法取消")
+	}
+
+	if s.signalCancel(task.TaskID) {
+		logrus.Infof("任务取消中: %s", task.TaskID)
+	} else {
+		logrus.Infof("任务取消中: %s, 等待工作器 %s 响应", task.TaskID, task.WorkerID)
+	}
+	return nil
+}
+
+// cancelRequestedAt 返回任务的取消请求时间，未请求取消时返回 nil
+func (s *QueueService) cancelRequestedAt(taskID string) *time.Time {
+	task, err := s.GetTask(taskID)
+	if err != nil || task.Status != database.TaskStatusCancelling {
+		return nil
+	}
+	if task.CancelRequestedAt == nil {
+		now := time.Now()
+		return &now
+	}
+	return task.CancelRequestedAt
+}
+
+// startHeartbeat 定期刷新运行中任务的心跳，并检查其他实例发出的取消请求
+// 返回的函数用于停止心跳并等待其退出
+func (s *QueueService) startHeartbeat(ctx context.Context, taskID string, cancel context.CancelFunc) func() {
+	if s.config.HeartbeatInterval <= 0 {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(s.config.HeartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.db.Model(&database.AsyncTask{}).
+					Where("task_id = ? AND worker_id = ?", taskID, s.instanceID).
+					Update("heartbeat_at", time.Now()).Error; err != nil {
+					logrus.Warnf("更新任务心跳失败: %s, 错误: %v", taskID, err)
+					continue
+				}
+
+				current, err := s.GetTask(taskID)
+				if err != nil {
+					continue
+				}
+				if current.Status == database.TaskStatusCancelling {
+					logrus.Infof("心跳检测到取消请求: %s", taskID)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+// handleTaskCancelled 处理器响应取消后将任务置为已取消
+func (s *QueueService) handleTaskCancelled(task *database.AsyncTask, requestedAt *time.Time) {
+	now := time.Now()
+	if err := s.db.Model(&database.AsyncTask{}).
+		Where("task_id = ?", task.TaskID).
+		Updates(map[string]interface{}{
+			"status":       database.TaskStatusCancelled,
+			"error":        "任务已取消",
+			"completed_at": now,
+			"updated_at":   now,
+		}).Error; err != nil {
+		logrus.Errorf("更新任务状态失败: %v", err)
+	}
+
+	task.Status = database.TaskStatusCancelled
+	task.Error = "任务已取消"
+	task.CancelRequestedAt = requestedAt
+	task.CompletedAt = &now
+	task.UpdatedAt = now
+
+	logrus.Infof("任务已取消: %s, 耗时 %s", task.TaskID, now.Sub(*requestedAt).Round(time.Millisecond))
+	s.onTaskFinished(task)
+}
+
+// recoverStaleTasks 回收心跳超时的任务
+// 取消中的任务直接置为已取消，运行中的任务按失败处理并进入重试
+// 未开启心跳时无法区分长时间运行和已退出的任务，不做回收
+func (s *QueueService) recoverStaleTasks() {
+	if s.config.HeartbeatTimeout <= 0 || s.config.HeartbeatInterval <= 0 {
+		return
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-s.config.HeartbeatTimeout)
+	var tasks []database.AsyncTask
+	// 工作器在首次心跳前退出时 heartbeat_at 为空，按开始时间判断；工作流父任务不由工作器执行，不参与回收
+	if err := s.db.Where("status IN ? AND aggregate = ?", []database.TaskStatus{
+		database.TaskStatusRunning,
+		database.TaskStatusCancelling,
+	}, false).
+		Where("heartbeat_at < ? OR (heartbeat_at IS NULL AND started_at < ?)", cutoff, cutoff).
+		Limit(50).
+		Find(&tasks).Error; err != nil {
+		logrus.Errorf("查询心跳超时任务失败: %v", err)
+		return
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+
+		// 以心跳时间作为条件认领，避免多个实例重复回收
+		claim := s.db.Model(&database.AsyncTask{}).Where("task_id = ? AND status = ?", task.TaskID, task.Status)
+		if task.HeartbeatAt == nil {
+			claim = claim.Where("heartbeat_at IS NULL")
+		} else {
+			claim = claim.Where("heartbeat_at = ?", task.HeartbeatAt)
+		}
+		result := claim.Update("heartbeat_at", now)
+		if result.Error != nil || result.RowsAffected == 0 {
+			continue
+		}
+
+		logrus.Warnf("任务心跳超时: %s, 工作器: %s", task.TaskID, task.WorkerID)
+		if task.Status == database.TaskStatusCancelling {
+			requestedAt := task.CancelRequestedAt
+			if requestedAt == nil {
+				requestedAt = &now
+			}
+			s.handleTaskCancelled(task, requestedAt)
+		} else {
+			s.handleTaskError(task, fmt.Errorf("工作器心跳超时: %s", task.WorkerID))
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package task
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"publisher-core/database"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrDuplicateContent 相同内容已提交到同一账号
+	ErrDuplicateContent = errors.New("duplicate publish content")
+	// ErrIdempotencyConflict 幂等键已被内容不同的任务使用
+	ErrIdempotencyConflict = errors.New("idempotency key reused with different request")
+	// ErrInvalidTaskRequest 任务请求不合法，如父任务或依赖任务不存在
+	ErrInvalidTaskRequest = errors.New("invalid task request")
+)
+
+// checkDuplicate 检查幂等键和发布内容是否重复
+// 幂等键命中时返回已有任务，任务内容与已有任务不同时返回 ErrIdempotencyConflict，
+// 内容重复且未强制提交时返回 ErrDuplicateContent
+func (s *QueueService) checkDuplicate(req *TaskRequest) (*database.AsyncTask, error) {
+	if req.IdempotencyKey != "" {
+		existing, err := s.findByIdempotencyKey(req)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			if err := checkIdempotentRequest(existing, req); err != nil {
+				return nil, err
+			}
+			return existing, nil
+		}
+	}
+
+	if req.Force {
+		return nil, nil
+	}
+
+	hash := s.contentHashFor(req)
+	if hash == "" {
+		return nil, nil
+	}
+
+	var dup database.AsyncTask
+	err := s.db.Where("content_hash = ? AND status NOT IN ?", hash, []database.TaskStatus{
+		database.TaskStatusFailed,
+		database.TaskStatusCancelled,
+	}).
+		Where("created_at > ?", time.Now().Add(-s.config.ContentDedupWindow)).
+		Order("created_at DESC").
+		First(&dup).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询重复内容失败: %w", err)
+	}
+
+	return nil, fmt.Errorf("%w: 相同内容已提交到该账号（任务 %s），如需重复发布请设置 force", ErrDuplicateContent, dup.TaskID)
+}
+
+// findByIdempotencyKey 按提交用户和幂等键查找窗口期内的任务
+// 幂等键只在同一用户内唯一；超出窗口期的旧任务会释放幂等键，以便新任务复用
+func (s *QueueService) findByIdempotencyKey(req *TaskRequest) (*database.AsyncTask, error) {
+	key := req.IdempotencyKey
+	var task database.AsyncTask
+	err := s.db.Where("user_id = ? AND idempotency_key = ?", req.UserID, key).First(&task).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询幂等键失败: %w", err)
+	}
+
+	if s.config.IdempotencyWindow > 0 && time.Since(task.CreatedAt) > s.config.IdempotencyWindow {
+		if err := s.db.Model(&database.AsyncTask{}).
+			Where("task_id = ?", task.TaskID).
+			Update("idempotency_key", nil).Error; err != nil {
+			return nil, fmt.Errorf("释放幂等键失败: %w", err)
+		}
+		logrus.Debugf("幂等键已过期: %s, 原任务: %s", key, task.TaskID)
+		return nil, nil
+	}
+
+	return &task, nil
+}
+
+// checkIdempotentRequest 检查重复提交的请求与幂等键对应的已有任务是否一致
+func checkIdempotentRequest(existing *database.AsyncTask, req *TaskRequest) error {
+	payloadBytes, err := json.Marshal(req.Payload)
+	if err != nil {
+		return fmt.Errorf("序列化任务数据失败: %w", err)
+	}
+	if existing.TaskType != req.TaskType || existing.Payload != string(payloadBytes) {
+		return fmt.Errorf("%w: 幂等键 %s 已用于任务 %s", ErrIdempotencyConflict, req.IdempotencyKey, existing.TaskID)
+	}
+	return nil
+}
+
+// contentHashFor 计算发布任务的内容哈希，非去重任务类型返回空
+func (s *QueueService) contentHashFor(req *TaskRequest) string {
+	dedup := false
+	for _, taskType := range s.config.DedupTaskTypes {
+		if taskType == req.TaskType {
+			dedup = true
+			break
+		}
+	}
+	if !dedup || req.Payload == nil {
+		return ""
+	}
+
+	return PublishContentHash(req.Payload)
+}
+
+// PublishContentHash 根据账号、标题、正文和媒体文件计算内容哈希
+func PublishContentHash(payload map[string]interface{}) string {
+	target := payloadString(payload, "account_id")
+	if target == "" {
+		target = payloadString(payload, "platform")
+	}
+
+	body := payloadString(payload, "content")
+	if body == "" {
+		body = payloadString(payload, "body")
+	}
+
+	media := payloadStrings(payload, "images")
+	if video := payloadString(payload, "video"); video != "" {
+		media = append(media, video)
+	}
+	sort.Strings(media)
+
+	h := sha256.New()
+	for _, part := range []string{
+		target,
+		normalizeContent(payloadString(payload, "title")),
+		normalizeContent(body),
+		strings.Join(media, "\n"),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeContent 归一化文本，忽略首尾空白和连续空白的差异
+func normalizeContent(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+func payloadString(payload map[string]interface{}, key string) string {
+	v, _ := payload[key].(string)
+	return strings.TrimSpace(v)
+}
+
+func payloadStrings(payload map[string]interface{}, key string) []string {
+	var result []string
+	switch v := payload[key].(type) {
+	case []string:
+		result = append(result, v...)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+	}
+	return result
+}
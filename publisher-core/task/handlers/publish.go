@@ -2,22 +2,41 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"publisher-core/adapters"
+	"publisher-core/compliance"
 	publisher "publisher-core/interfaces"
 	"publisher-core/task"
 	"github.com/sirupsen/logrus"
 )
 
+// ExperimentRecorder 记录发布内容与提示词实验单元的对应关系，prompt.ABTestService 实现了该接口
+type ExperimentRecorder interface {
+	RecordPublishedPost(ctx context.Context, platform, postID string) error
+}
+
 type PublishHandler struct {
-	factory *adapters.PublisherFactory
+	factory     *adapters.PublisherFactory
+	compliance  *compliance.Checker
+	experiments ExperimentRecorder
 }
 
 func NewPublishHandler(factory *adapters.PublisherFactory) *PublishHandler {
 	return &PublishHandler{factory: factory}
 }
 
+// SetComplianceChecker 发布前执行合规检查，被拦截或待审批的任务直接失败，不再重试
+func (h *PublishHandler) SetComplianceChecker(checker *compliance.Checker) {
+	h.compliance = checker
+}
+
+// SetExperimentRecorder 发布成功后记录内容ID，之后采集到的互动率计入对应实验
+func (h *PublishHandler) SetExperimentRecorder(recorder ExperimentRecorder) {
+	h.experiments = recorder
+}
+
 func (h *PublishHandler) Handle(ctx context.Context, t *task.Task) error {
 	logrus.Infof("Starting publish task: %s, platform: %s", t.ID, t.Platform)
 
@@ -28,6 +47,10 @@ func (h *PublishHandler) Handle(ctx context.Context, t *task.Task) error {
 
 	title, _ := t.Payload["title"].(string)
 	content, _ := t.Payload["content"].(string)
+	if content == "" {
+		// 适配器 PublishAsync 提交的正文字段为 body
+		content, _ = t.Payload["body"].(string)
+	}
 	contentType, _ := t.Payload["type"].(string)
 
 	var images []string
@@ -53,6 +76,39 @@ func (h *PublishHandler) Handle(ctx context.Context, t *task.Task) error {
 	logrus.Infof("Publish content: platform=%s, type=%s, title=%s, content_len=%d, images=%d, video=%s, tags=%d",
 		platform, contentType, title, len(content), len(images), video, len(tags))
 
+	// 启动浏览器前检查任务是否已被取消
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if h.compliance != nil {
+		report, err := h.compliance.Enforce(ctx, &compliance.Content{
+			Platform: platform,
+			Title:    title,
+			Body:     content,
+			Tags:     tags,
+		}, t.ID)
+		var violation *compliance.ViolationError
+		if errors.As(err, &violation) {
+			status := "blocked"
+			if errors.Is(err, compliance.ErrApprovalRequired) {
+				status = "pending_review"
+			}
+			logrus.Warnf("Publish task %s stopped by compliance check: %v", t.ID, err)
+			t.Result = map[string]interface{}{
+				"platform":   platform,
+				"title":      title,
+				"status":     status,
+				"error":      err.Error(),
+				"compliance": report,
+			}
+			return task.NonRetryable(err)
+		}
+		if err != nil {
+			return fmt.Errorf("compliance check failed: %w", err)
+		}
+	}
+
 	pub, err := h.factory.Create(platform, publisher.DefaultOptions())
 	if err != nil {
 		logrus.Errorf("Create publisher failed: %v", err)
@@ -95,6 +151,12 @@ func (h *PublishHandler) Handle(ctx context.Context, t *task.Task) error {
 		t.Result["finished_at"] = result.FinishedAt
 	}
 
+	if h.experiments != nil {
+		if err := h.experiments.RecordPublishedPost(ctx, platform, result.PostID); err != nil {
+			logrus.Warnf("Record experiment post failed: %v", err)
+		}
+	}
+
 	logrus.Infof("Publish task completed: %s, status: %s", t.ID, result.Status)
 	return nil
 }
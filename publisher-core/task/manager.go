@@ -48,6 +48,10 @@ type TaskManager struct {
 	handlers map[string]TaskHandler
 	storage  TaskStorage
 	notifyCh chan *Task
+
+	// queue 非空时任务委托给队列服务持久化和执行
+	queue *QueueService
+	state *StateService
 }
 
 // TaskStorage 任务存储接口
@@ -82,10 +86,18 @@ func (m *TaskManager) RegisterHandler(taskType string, handler TaskHandler) {
 	defer m.mu.Unlock()
 	m.handlers[taskType] = handler
 	logrus.Infof("已注册任务处理器: %s", taskType)
+
+	if m.queue != nil {
+		m.queue.RegisterHandler(taskType, m.queueHandler(handler))
+	}
 }
 
 // CreateTask 创建新任务
 func (m *TaskManager) CreateTask(taskType string, platform string, payload map[string]interface{}) (*Task, error) {
+	if m.queue != nil {
+		return m.queueCreateTask(taskType, platform, payload)
+	}
+
 	task := &Task{
 		ID:        uuid.New().String(),
 		Type:      taskType,
@@ -112,6 +124,10 @@ func (m *TaskManager) CreateTask(taskType string, platform string, payload map[s
 
 // Execute 执行任务
 func (m *TaskManager) Execute(ctx context.Context, taskID string) error {
+	if m.queue != nil {
+		return m.queueExecute(ctx, taskID)
+	}
+
 	m.mu.RLock()
 	task, exists := m.tasks[taskID]
 	handler := m.handlers[task.Type]
@@ -168,6 +184,10 @@ func (m *TaskManager) ExecuteAsync(ctx context.Context, taskID string) <-chan er
 
 // GetTask 获取任务
 func (m *TaskManager) GetTask(taskID string) (*Task, error) {
+	if m.queue != nil {
+		return m.queueGetTask(taskID)
+	}
+
 	m.mu.RLock()
 	task, exists := m.tasks[taskID]
 	m.mu.RUnlock()
@@ -193,6 +213,10 @@ func (m *TaskManager) GetTask(taskID string) (*Task, error) {
 
 // ListTasks 列出任务
 func (m *TaskManager) ListTasks(filter TaskFilter) ([]*Task, error) {
+	if m.queue != nil {
+		return m.queueListTasks(filter)
+	}
+
 	if m.storage != nil {
 		return m.storage.List(filter)
 	}
@@ -222,6 +246,10 @@ func (m *TaskManager) ListTasks(filter TaskFilter) ([]*Task, error) {
 
 // Cancel 取消任务
 func (m *TaskManager) Cancel(taskID string) error {
+	if m.queue != nil {
+		return m.queue.CancelTask(taskID)
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -249,6 +277,12 @@ func (m *TaskManager) Cancel(taskID string) error {
 
 // UpdateProgress 更新任务进度
 func (m *TaskManager) UpdateProgress(taskID string, progress int, result map[string]interface{}) {
+	if m.state != nil {
+		if err := m.state.UpdateProgress(taskID, progress, ""); err != nil {
+			logrus.Warnf("上报任务进度失败: %v", err)
+		}
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
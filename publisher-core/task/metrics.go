@@ -0,0 +1,360 @@
+package task
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"publisher-core/database"
+)
+
+// 执行记录状态
+const (
+	ExecutionStatusSuccess   = "success"
+	ExecutionStatusFailed    = "failed"
+	ExecutionStatusCancelled = "cancelled"
+)
+
+// MetricsFilter 执行指标查询条件
+type MetricsFilter struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	TaskType  string    `json:"task_type"`
+	QueueName string    `json:"queue_name"`
+}
+
+// ExecutionMetrics 时间窗口内的任务执行指标
+type ExecutionMetrics struct {
+	StartTime  time.Time                  `json:"start_time"`
+	EndTime    time.Time                  `json:"end_time"`
+	Overall    *MetricsSummary            `json:"overall"`
+	ByTaskType map[string]*MetricsSummary `json:"by_task_type"`
+	ByQueue    map[string]*MetricsSummary `json:"by_queue"`
+
+	// bySeries 按任务类型和队列组合汇总，用于 Prometheus 输出
+	bySeries map[metricsSeries]*MetricsSummary
+}
+
+// metricsSeries Prometheus 指标的标签组合
+type metricsSeries struct {
+	taskType  string
+	queueName string
+}
+
+// MetricsSummary 一组执行记录的汇总指标
+type MetricsSummary struct {
+	Executions int64 `json:"executions"`
+	Succeeded  int64 `json:"succeeded"`
+	Failed     int64 `json:"failed"`
+	Cancelled  int64 `json:"cancelled"`
+	// SuccessRate 成功率（百分比）
+	SuccessRate float64 `json:"success_rate"`
+	// ThroughputPerMinute 每分钟执行次数
+	ThroughputPerMinute float64 `json:"throughput_per_minute"`
+	// Duration 执行耗时分布
+	Duration DurationStats `json:"duration"`
+	// QueueWait 从可执行到开始执行的等待时间分布
+	QueueWait DurationStats `json:"queue_wait"`
+
+	durations []float64
+	waits     []float64
+}
+
+// DurationStats 耗时分布（毫秒）
+type DurationStats struct {
+	Count int     `json:"count"`
+	SumMs float64 `json:"sum_ms"`
+	AvgMs float64 `json:"avg_ms"`
+	P50Ms float64 `json:"p50_ms"`
+	P95Ms float64 `json:"p95_ms"`
+	P99Ms float64 `json:"p99_ms"`
+	MaxMs float64 `json:"max_ms"`
+}
+
+// executionRow 执行记录与任务的关联行
+type executionRow struct {
+	Status     string
+	DurationMs int
+	TaskType   string
+	QueueName  string
+}
+
+// waitRow 任务等待时间行
+type waitRow struct {
+	TaskType    string
+	QueueName   string
+	CreatedAt   time.Time
+	ScheduledAt *time.Time
+	StartedAt   *time.Time
+}
+
+// GetExecutionMetrics 统计时间窗口内的执行指标
+// 执行结果与耗时取自 TaskExecution，等待时间取自窗口内开始执行的 AsyncTask
+func (s *StateService) GetExecutionMetrics(filter *MetricsFilter) (*ExecutionMetrics, error) {
+	if filter.EndTime.IsZero() {
+		filter.EndTime = time.Now()
+	}
+	if filter.StartTime.IsZero() {
+		filter.StartTime = filter.EndTime.Add(-time.Hour)
+	}
+	if !filter.StartTime.Before(filter.EndTime) {
+		return nil, fmt.Errorf("开始时间必须早于结束时间")
+	}
+
+	metrics := &ExecutionMetrics{
+		StartTime:  filter.StartTime,
+		EndTime:    filter.EndTime,
+		Overall:    &MetricsSummary{},
+		ByTaskType: make(map[string]*MetricsSummary),
+		ByQueue:    make(map[string]*MetricsSummary),
+		bySeries:   make(map[metricsSeries]*MetricsSummary),
+	}
+
+	var executions []executionRow
+	query := s.db.Table("task_executions AS e").
+		Select("e.status, e.duration_ms, t.task_type, t.queue_name").
+		Joins("JOIN async_tasks AS t ON t.task_id = e.task_id").
+		Where("e.completed_at BETWEEN ? AND ?", filter.StartTime, filter.EndTime)
+	if filter.TaskType != "" {
+		query = query.Where("t.task_type = ?", filter.TaskType)
+	}
+	if filter.QueueName != "" {
+		query = query.Where("t.queue_name = ?", filter.QueueName)
+	}
+	if err := query.Scan(&executions).Error; err != nil {
+		return nil, fmt.Errorf("查询执行记录失败: %w", err)
+	}
+
+	for _, row := range executions {
+		for _, summary := range metrics.summariesFor(row.TaskType, row.QueueName) {
+			summary.addExecution(row)
+		}
+	}
+
+	var waits []waitRow
+	waitQuery := s.db.Model(&database.AsyncTask{}).
+		Select("task_type, queue_name, created_at, scheduled_at, started_at").
+		Where("started_at BETWEEN ? AND ?", filter.StartTime, filter.EndTime)
+	if filter.TaskType != "" {
+		waitQuery = waitQuery.Where("task_type = ?", filter.TaskType)
+	}
+	if filter.QueueName != "" {
+		waitQuery = waitQuery.Where("queue_name = ?", filter.QueueName)
+	}
+	if err := waitQuery.Scan(&waits).Error; err != nil {
+		return nil, fmt.Errorf("查询任务等待时间失败: %w", err)
+	}
+
+	for _, row := range waits {
+		wait, ok := queueWait(row)
+		if !ok {
+			continue
+		}
+		for _, summary := range metrics.summariesFor(row.TaskType, row.QueueName) {
+			summary.waits = append(summary.waits, wait)
+		}
+	}
+
+	minutes := filter.EndTime.Sub(filter.StartTime).Minutes()
+	metrics.Overall.finalize(minutes)
+	for _, summary := range metrics.ByTaskType {
+		summary.finalize(minutes)
+	}
+	for _, summary := range metrics.ByQueue {
+		summary.finalize(minutes)
+	}
+	for _, summary := range metrics.bySeries {
+		summary.finalize(minutes)
+	}
+
+	return metrics, nil
+}
+
+// summariesFor 返回一行数据需要计入的汇总
+func (m *ExecutionMetrics) summariesFor(taskType, queueName string) []*MetricsSummary {
+	byType, exists := m.ByTaskType[taskType]
+	if !exists {
+		byType = &MetricsSummary{}
+		m.ByTaskType[taskType] = byType
+	}
+	byQueue, exists := m.ByQueue[queueName]
+	if !exists {
+		byQueue = &MetricsSummary{}
+		m.ByQueue[queueName] = byQueue
+	}
+	series := metricsSeries{taskType: taskType, queueName: queueName}
+	bySeries, exists := m.bySeries[series]
+	if !exists {
+		bySeries = &MetricsSummary{}
+		m.bySeries[series] = bySeries
+	}
+	return []*MetricsSummary{m.Overall, byType, byQueue, bySeries}
+}
+
+// addExecution 计入一条执行记录
+func (s *MetricsSummary) addExecution(row executionRow) {
+	s.Executions++
+	switch row.Status {
+	case ExecutionStatusSuccess:
+		s.Succeeded++
+	case ExecutionStatusCancelled:
+		s.Cancelled++
+	default:
+		s.Failed++
+	}
+	s.durations = append(s.durations, float64(row.DurationMs))
+}
+
+// finalize 计算比率和分位数
+func (s *MetricsSummary) finalize(windowMinutes float64) {
+	if s.Executions > 0 {
+		s.SuccessRate = float64(s.Succeeded) / float64(s.Executions) * 100
+	}
+	if windowMinutes > 0 {
+		s.ThroughputPerMinute = float64(s.Executions) / windowMinutes
+	}
+	s.Duration = computeDurationStats(s.durations)
+	s.QueueWait = computeDurationStats(s.waits)
+}
+
+// queueWait 计算任务从可执行到开始执行的等待时间（毫秒）
+// 计划时间晚于开始时间时说明任务之后又被推迟，此时以创建时间为准
+func queueWait(row waitRow) (float64, bool) {
+	if row.StartedAt == nil {
+		return 0, false
+	}
+
+	readyAt := row.CreatedAt
+	if row.ScheduledAt != nil && row.ScheduledAt.After(readyAt) && !row.ScheduledAt.After(*row.StartedAt) {
+		readyAt = *row.ScheduledAt
+	}
+
+	wait := row.StartedAt.Sub(readyAt)
+	if wait < 0 {
+		wait = 0
+	}
+	return float64(wait.Milliseconds()), true
+}
+
+// computeDurationStats 计算耗时分布
+func computeDurationStats(values []float64) DurationStats {
+	stats := DurationStats{Count: len(values)}
+	if len(values) == 0 {
+		return stats
+	}
+
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	for _, v := range sorted {
+		stats.SumMs += v
+	}
+	stats.AvgMs = stats.SumMs / float64(len(sorted))
+	stats.P50Ms = percentile(sorted, 50)
+	stats.P95Ms = percentile(sorted, 95)
+	stats.P99Ms = percentile(sorted, 99)
+	stats.MaxMs = sorted[len(sorted)-1]
+	return stats
+}
+
+// percentile 最近秩法计算分位数，values 需已排序
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// WritePrometheus 以 Prometheus 文本格式输出执行指标
+func (m *ExecutionMetrics) WritePrometheus(w io.Writer) error {
+	var b strings.Builder
+	window := m.EndTime.Sub(m.StartTime).Seconds()
+
+	fmt.Fprintf(&b, "# HELP publisher_task_metrics_window_seconds Length of the window the task metrics are computed over.\n")
+	fmt.Fprintf(&b, "# TYPE publisher_task_metrics_window_seconds gauge\n")
+	fmt.Fprintf(&b, "publisher_task_metrics_window_seconds %g\n", window)
+
+	// 每个指标族只使用同一组标签，按任务类型或队列汇总由查询端聚合
+	series := make([]metricsSeries, 0, len(m.bySeries))
+	for key := range m.bySeries {
+		series = append(series, key)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		if series[i].taskType != series[j].taskType {
+			return series[i].taskType < series[j].taskType
+		}
+		return series[i].queueName < series[j].queueName
+	})
+
+	writeFamily := func(name, typ, help string, value func(labels string, s *MetricsSummary)) {
+		fmt.Fprintf(&b, "# HELP %s %s\n", name, help)
+		fmt.Fprintf(&b, "# TYPE %s %s\n", name, typ)
+		for _, key := range series {
+			labels := fmt.Sprintf(`task_type="%s",queue="%s"`, escapeLabelValue(key.taskType), escapeLabelValue(key.queueName))
+			value(labels, m.bySeries[key])
+		}
+	}
+
+	writeFamily("publisher_task_executions", "gauge", "Task executions finished in the window.", func(labels string, s *MetricsSummary) {
+		for _, c := range []struct {
+			status string
+			count  int64
+		}{
+			{ExecutionStatusSuccess, s.Succeeded},
+			{ExecutionStatusFailed, s.Failed},
+			{ExecutionStatusCancelled, s.Cancelled},
+		} {
+			fmt.Fprintf(&b, "publisher_task_executions{%s,status=\"%s\"} %d\n", labels, c.status, c.count)
+		}
+	})
+
+	writeFamily("publisher_task_success_ratio", "gauge", "Share of successful task executions in the window.", func(labels string, s *MetricsSummary) {
+		fmt.Fprintf(&b, "publisher_task_success_ratio{%s} %g\n", labels, s.SuccessRate/100)
+	})
+
+	writeFamily("publisher_task_throughput_per_minute", "gauge", "Task executions per minute in the window.", func(labels string, s *MetricsSummary) {
+		fmt.Fprintf(&b, "publisher_task_throughput_per_minute{%s} %g\n", labels, s.ThroughputPerMinute)
+	})
+
+	writeSummary := func(name, help string, stats func(s *MetricsSummary) DurationStats) {
+		writeFamily(name, "summary", help, func(labels string, s *MetricsSummary) {
+			d := stats(s)
+			for _, q := range []struct {
+				quantile string
+				value    float64
+			}{{"0.5", d.P50Ms}, {"0.95", d.P95Ms}, {"0.99", d.P99Ms}} {
+				fmt.Fprintf(&b, "%s{%s,quantile=\"%s\"} %g\n", name, labels, q.quantile, q.value/1000)
+			}
+			fmt.Fprintf(&b, "%s_sum{%s} %g\n", name, labels, d.SumMs/1000)
+			fmt.Fprintf(&b, "%s_count{%s} %d\n", name, labels, d.Count)
+		})
+	}
+
+	writeSummary("publisher_task_duration_seconds", "Task execution duration in the window.", func(s *MetricsSummary) DurationStats {
+		return s.Duration
+	})
+	writeSummary("publisher_task_queue_wait_seconds", "Time tasks waited in the queue before starting in the window.", func(s *MetricsSummary) DurationStats {
+		return s.QueueWait
+	})
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// labelValueEscaper 按 Prometheus 文本格式转义标签值中的反斜杠、双引号和换行
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
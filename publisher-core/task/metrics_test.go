@@ -0,0 +1,98 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"publisher-core/database"
+)
+
+func TestPercentile(t *testing.T) {
+	values := make([]float64, 100)
+	for i := range values {
+		values[i] = float64(i + 1)
+	}
+
+	stats := computeDurationStats(values)
+	if stats.P50Ms != 50 || stats.P95Ms != 95 || stats.P99Ms != 99 || stats.MaxMs != 100 {
+		t.Errorf("Unexpected percentiles: %+v", stats)
+	}
+	if stats.AvgMs != 50.5 {
+		t.Errorf("Expected avg 50.5, got %v", stats.AvgMs)
+	}
+
+	if got := computeDurationStats(nil); got.Count != 0 || got.P99Ms != 0 {
+		t.Errorf("Expected empty stats, got %+v", got)
+	}
+}
+
+func TestExecutionMetrics(t *testing.T) {
+	svc := newTestQueueService(t)
+	state := NewStateService(svc.db)
+	ctx := context.Background()
+
+	svc.RegisterHandler("broken", func(ctx context.Context, task *database.AsyncTask) error {
+		return errors.New("boom")
+	})
+
+	for i := 0; i < 3; i++ {
+		submitted, _, _ := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default"})
+		svc.processTask(ctx, submitted)
+	}
+	failing, _, _ := svc.SubmitTask(ctx, &TaskRequest{TaskType: "broken", QueueName: "media", MaxRetries: 1})
+	svc.processTask(ctx, failing)
+
+	metrics, err := state.GetExecutionMetrics(&MetricsFilter{StartTime: time.Now().Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("GetExecutionMetrics failed: %v", err)
+	}
+
+	if metrics.Overall.Executions != 4 || metrics.Overall.Succeeded != 3 || metrics.Overall.Failed != 1 {
+		t.Errorf("Unexpected overall counts: %+v", metrics.Overall)
+	}
+	if metrics.Overall.SuccessRate != 75 {
+		t.Errorf("Expected success rate 75, got %v", metrics.Overall.SuccessRate)
+	}
+	if got := metrics.ByTaskType["noop"]; got == nil || got.Executions != 3 || got.SuccessRate != 100 {
+		t.Errorf("Unexpected noop metrics: %+v", got)
+	}
+	if got := metrics.ByQueue["media"]; got == nil || got.Failed != 1 {
+		t.Errorf("Unexpected media queue metrics: %+v", got)
+	}
+	if metrics.Overall.QueueWait.Count != 4 {
+		t.Errorf("Expected queue wait for 4 tasks, got %d", metrics.Overall.QueueWait.Count)
+	}
+
+	filtered, err := state.GetExecutionMetrics(&MetricsFilter{StartTime: time.Now().Add(-time.Hour), QueueName: "media"})
+	if err != nil {
+		t.Fatalf("GetExecutionMetrics failed: %v", err)
+	}
+	if filtered.Overall.Executions != 1 {
+		t.Errorf("Expected 1 execution in media queue, got %d", filtered.Overall.Executions)
+	}
+
+	var b strings.Builder
+	if err := metrics.WritePrometheus(&b); err != nil {
+		t.Fatalf("WritePrometheus failed: %v", err)
+	}
+	for _, line := range []string{
+		`publisher_task_executions{task_type="noop",queue="default",status="success"} 3`,
+		`publisher_task_success_ratio{task_type="broken",queue="media"} 0`,
+		`# TYPE publisher_task_duration_seconds summary`,
+		`publisher_task_queue_wait_seconds_count{task_type="broken",queue="media"} 1`,
+	} {
+		if !strings.Contains(b.String(), line) {
+			t.Errorf("Expected exposition to contain %q", line)
+		}
+	}
+	if strings.Contains(b.String(), `{queue=`) {
+		t.Error("Expected every series to carry both task_type and queue labels")
+	}
+
+	if got := escapeLabelValue("a\"b\\c\n"); got != `a\"b\\c\n` {
+		t.Errorf("Unexpected escaped label value %q", got)
+	}
+}
@@ -3,6 +3,7 @@ package task
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -19,8 +20,18 @@ type QueueService struct {
 	db       *gorm.DB
 	queues   map[string]*TaskQueue
 	handlers map[string]QueueTaskHandler
+	limiter  *RateLimiter
 	mu       sync.RWMutex
 	config   *QueueConfig
+
+	// runCtx 服务启动后的上下文，用于为运行期注册的队列启动工作器
+	runCtx context.Context
+
+	// instanceID 当前实例标识，写入运行中任务的 worker_id
+	instanceID string
+	// running 本实例运行中任务的取消函数
+	running   map[string]context.CancelFunc
+	runningMu sync.Mutex
 }
 
 // QueueTaskHandler 队列任务处理函数
@@ -41,6 +52,16 @@ type QueueConfig struct {
 	DefaultTimeout     time.Duration `json:"default_timeout"`
 	DefaultMaxRetries  int           `json:"default_max_retries"`
 	PollInterval       time.Duration `json:"poll_interval"`
+	// IdempotencyWindow 幂等键有效期，窗口期内相同幂等键返回已有任务
+	IdempotencyWindow time.Duration `json:"idempotency_window"`
+	// ContentDedupWindow 发布内容去重窗口
+	ContentDedupWindow time.Duration `json:"content_dedup_window"`
+	// DedupTaskTypes 需要按内容去重的任务类型
+	DedupTaskTypes []string `json:"dedup_task_types"`
+	// HeartbeatInterval 运行中任务的心跳间隔，心跳同时检查跨实例的取消请求
+	HeartbeatInterval time.Duration `json:"heartbeat_interval"`
+	// HeartbeatTimeout 心跳超时时间，超时的运行中任务视为工作器已退出
+	HeartbeatTimeout time.Duration `json:"heartbeat_timeout"`
 }
 
 // DefaultQueueConfig 默认配置
@@ -50,6 +71,11 @@ func DefaultQueueConfig() *QueueConfig {
 		DefaultTimeout:     30 * time.Minute,
 		DefaultMaxRetries:  3,
 		PollInterval:       1 * time.Second,
+		IdempotencyWindow:  24 * time.Hour,
+		ContentDedupWindow: 7 * 24 * time.Hour,
+		DedupTaskTypes:     []string{"publish"},
+		HeartbeatInterval:  5 * time.Second,
+		HeartbeatTimeout:   2 * time.Minute,
 	}
 }
 
@@ -59,10 +85,13 @@ func NewQueueService(db *gorm.DB, config *QueueConfig) *QueueService {
 		config = DefaultQueueConfig()
 	}
 	return &QueueService{
-		db:       db,
-		queues:   make(map[string]*TaskQueue),
-		handlers: make(map[string]QueueTaskHandler),
-		config:   config,
+		db:         db,
+		queues:     make(map[string]*TaskQueue),
+		handlers:   make(map[string]QueueTaskHandler),
+		limiter:    NewRateLimiter(),
+		config:     config,
+		instanceID: newInstanceID(),
+		running:    make(map[string]context.CancelFunc),
 	}
 }
 
@@ -84,6 +113,13 @@ func (s *QueueService) RegisterQueue(name string, concurrency int) error {
 
 	s.queues[name] = queue
 
+	// 服务已启动时立即为新队列启动工作器
+	if s.runCtx != nil {
+		for i := 0; i < concurrency; i++ {
+			go s.worker(s.runCtx, name, i)
+		}
+	}
+
 	// 保存到数据库
 	dbQueue := &database.TaskQueue{
 		Name:        name,
@@ -112,30 +148,103 @@ func (s *QueueService) RegisterHandler(taskType string, handler QueueTaskHandler
 }
 
 // SubmitTask 提交任务
-func (s *QueueService) SubmitTask(ctx context.Context, req *TaskRequest) (*database.AsyncTask, error) {
+// 幂等键命中时返回已有任务，duplicate 为 true；同一幂等键携带不同任务内容时返回 ErrIdempotencyConflict
+func (s *QueueService) SubmitTask(ctx context.Context, req *TaskRequest) (task *database.AsyncTask, duplicate bool, err error) {
+	if req.ParentTaskID != "" {
+		parent, err := s.GetTask(req.ParentTaskID)
+		if err != nil {
+			return nil, false, fmt.Errorf("%w: 父任务不存在: %s", ErrInvalidTaskRequest, req.ParentTaskID)
+		}
+		if !parent.Aggregate {
+			return nil, false, fmt.Errorf("%w: 任务 %s 不是工作流父任务", ErrInvalidTaskRequest, req.ParentTaskID)
+		}
+	}
+
+	existing, err := s.checkDuplicate(req)
+	if err != nil {
+		return nil, false, err
+	}
+	if existing != nil {
+		logrus.Infof("幂等键重复提交，返回已有任务: %s, key: %s", existing.TaskID, req.IdempotencyKey)
+		return existing, true, nil
+	}
+
+	task, err = s.createTask(req, database.TaskStatusPending)
+	if err != nil {
+		// 并发提交相同幂等键时由唯一索引兜底
+		if req.IdempotencyKey != "" {
+			if existing, findErr := s.findByIdempotencyKey(req); findErr == nil && existing != nil {
+				if conflictErr := checkIdempotentRequest(existing, req); conflictErr != nil {
+					return nil, false, conflictErr
+				}
+				return existing, true, nil
+			}
+		}
+		return nil, false, err
+	}
+
+	if task.Status == database.TaskStatusWaiting {
+		// 依赖可能在创建期间已全部完成，这里再检查一次
+		s.releaseIfReady(task.TaskID)
+		logrus.Infof("任务已提交，等待依赖完成: %s, 依赖: %v", task.TaskID, req.DependsOn)
+	} else {
+		s.enqueue(task)
+	}
+
+	if task.ParentTaskID != "" {
+		s.refreshParentStatus(task.ParentTaskID)
+	}
+
+	return task, false, nil
+}
+
+// createTask 创建任务记录及依赖关系
+func (s *QueueService) createTask(req *TaskRequest, status database.TaskStatus) (*database.AsyncTask, error) {
+	// 检查依赖任务，重复的依赖只保留一条
+	depIDs := uniqueTaskIDs(req.DependsOn)
+	if len(depIDs) > 0 {
+		if err := s.validateDependencies(depIDs); err != nil {
+			return nil, err
+		}
+		status = database.TaskStatusWaiting
+	}
+
 	// 生成任务ID
 	taskID := uuid.New().String()
 
 	// 序列化payload
 	payloadBytes, err := json.Marshal(req.Payload)
 	if err != nil {
-		return nil, fmt.Errorf("序列化任务数据失败: %w", err)
+		return nil, fmt.Errorf("%w: 序列化任务数据失败: %v", ErrInvalidTaskRequest, err)
 	}
 
 	// 创建任务记录
 	task := &database.AsyncTask{
-		TaskID:     taskID,
-		TaskType:   req.TaskType,
-		QueueName:  req.QueueName,
-		Status:     database.TaskStatusPending,
-		Priority:   req.Priority,
-		Payload:    string(payloadBytes),
-		MaxRetries: req.MaxRetries,
-		Timeout:    req.Timeout,
-		UserID:     req.UserID,
-		ProjectID:  req.ProjectID,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+		TaskID:       taskID,
+		TaskType:     req.TaskType,
+		QueueName:    req.QueueName,
+		Status:       status,
+		Priority:     req.Priority,
+		Payload:      string(payloadBytes),
+		MaxRetries:   req.MaxRetries,
+		Timeout:      req.Timeout,
+		UserID:       req.UserID,
+		ProjectID:    req.ProjectID,
+		ParentTaskID: req.ParentTaskID,
+		Aggregate:    req.aggregate,
+		ContentHash:  s.contentHashFor(req),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+
+	// 平台和账号单独存列，限流时按列统计近期派发
+	keys := rateLimitKeys(task)
+	task.Platform = keys[RateLimitScopePlatform]
+	task.AccountID = keys[RateLimitScopeAccount]
+
+	if req.IdempotencyKey != "" {
+		key := req.IdempotencyKey
+		task.IdempotencyKey = &key
 	}
 
 	if task.MaxRetries == 0 {
@@ -147,31 +256,54 @@ func (s *QueueService) SubmitTask(ctx context.Context, req *TaskRequest) (*datab
 	}
 
 	// 保存到数据库
-	if err := s.db.Create(task).Error; err != nil {
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(task).Error; err != nil {
+			return err
+		}
+		for _, depID := range depIDs {
+			dep := &database.TaskDependency{
+				TaskID:          taskID,
+				DependsOnTaskID: depID,
+				CreatedAt:       time.Now(),
+			}
+			if err := tx.Create(dep).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, fmt.Errorf("创建任务失败: %w", err)
 	}
 
-	// 添加到内存队列
+	return task, nil
+}
+
+// enqueue 将任务放入内存队列
+func (s *QueueService) enqueue(task *database.AsyncTask) {
 	s.mu.RLock()
-	queue, exists := s.queues[req.QueueName]
+	queue, exists := s.queues[task.QueueName]
 	s.mu.RUnlock()
 
 	if !exists {
 		// 自动创建队列
-		if err := s.RegisterQueue(req.QueueName, s.config.DefaultConcurrency); err != nil {
-			return nil, err
+		if err := s.RegisterQueue(task.QueueName, s.config.DefaultConcurrency); err != nil {
+			logrus.Warnf("创建队列失败: %v", err)
+		}
+		s.mu.RLock()
+		queue, exists = s.queues[task.QueueName]
+		s.mu.RUnlock()
+		if !exists {
+			return
 		}
-		queue = s.queues[req.QueueName]
 	}
 
 	select {
 	case queue.Tasks <- task:
-		logrus.Infof("任务已提交: %s, 类型: %s, 队列: %s", taskID, req.TaskType, req.QueueName)
-		return task, nil
+		logrus.Infof("任务已提交: %s, 类型: %s, 队列: %s", task.TaskID, task.TaskType, task.QueueName)
 	default:
 		// 队列已满，任务仍在数据库中等待
-		logrus.Warnf("队列 %s 已满，任务 %s 将在下次轮询时处理", req.QueueName, taskID)
-		return task, nil
+		logrus.Warnf("队列 %s 已满，任务 %s 将在下次轮询时处理", task.QueueName, task.TaskID)
 	}
 }
 
@@ -185,20 +317,38 @@ type TaskRequest struct {
 	Timeout    int                    `json:"timeout"`
 	UserID     string                 `json:"user_id"`
 	ProjectID  string                 `json:"project_id"`
+
+	// ParentTaskID 父任务ID，父任务状态由子任务汇总得出
+	ParentTaskID string `json:"parent_task_id"`
+	// DependsOn 依赖的任务ID，全部成功完成后本任务才会执行
+	DependsOn []string `json:"depends_on"`
+
+	// IdempotencyKey 幂等键，客户端重试时携带相同的键不会重复创建任务
+	IdempotencyKey string `json:"idempotency_key"`
+	// Force 跳过发布内容去重检查
+	Force bool `json:"force"`
+
+	// aggregate 工作流父任务，只由 SubmitWorkflow 设置
+	aggregate bool
 }
 
 // Start 启动队列服务
 func (s *QueueService) Start(ctx context.Context) {
 	logrus.Info("任务队列服务已启动")
 
+	if err := s.LoadRateLimits(); err != nil {
+		logrus.Warnf("%v", err)
+	}
+
 	// 启动队列工作器
-	s.mu.RLock()
+	s.mu.Lock()
+	s.runCtx = ctx
 	for name, queue := range s.queues {
 		for i := 0; i < queue.Concurrency; i++ {
 			go s.worker(ctx, name, i)
 		}
 	}
-	s.mu.RUnlock()
+	s.mu.Unlock()
 
 	// 启动轮询器
 	go s.poller(ctx)
@@ -208,12 +358,16 @@ func (s *QueueService) Start(ctx context.Context) {
 func (s *QueueService) worker(ctx context.Context, queueName string, workerID int) {
 	logrus.Infof("工作器启动: 队列=%s, ID=%d", queueName, workerID)
 
+	s.mu.RLock()
+	tasks := s.queues[queueName].Tasks
+	s.mu.RUnlock()
+
 	for {
 		select {
 		case <-ctx.Done():
 			logrus.Infof("工作器停止: 队列=%s, ID=%d", queueName, workerID)
 			return
-		case task := <-s.queues[queueName].Tasks:
+		case task := <-tasks:
 			s.processTask(ctx, task)
 		}
 	}
@@ -229,6 +383,7 @@ func (s *QueueService) poller(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
+			s.recoverStaleTasks()
 			s.pollPendingTasks()
 		}
 	}
@@ -247,7 +402,8 @@ func (s *QueueService) pollPendingTasks() {
 
 		// 从数据库获取待处理任务
 		var tasks []database.AsyncTask
-		err := s.db.Where("queue_name = ? AND status = ?", queueName, database.TaskStatusPending).
+		err := s.db.Where("queue_name = ? AND status = ? AND aggregate = ?", queueName, database.TaskStatusPending, false).
+			Where("scheduled_at IS NULL OR scheduled_at <= ?", time.Now()).
 			Order("priority DESC, created_at ASC").
 			Limit(10).
 			Find(&tasks).Error
@@ -271,17 +427,47 @@ func (s *QueueService) pollPendingTasks() {
 
 // processTask 处理任务
 func (s *QueueService) processTask(ctx context.Context, task *database.AsyncTask) {
-	// 更新任务状态为运行中
+	// 更新任务状态为运行中，仅认领仍处于待处理状态且已到计划时间的任务，工作流父任务不执行
 	now := time.Now()
+	result := s.db.Model(&database.AsyncTask{}).
+		Where("task_id = ? AND status = ? AND aggregate = ?", task.TaskID, database.TaskStatusPending, false).
+		Where("scheduled_at IS NULL OR scheduled_at <= ?", now).
+		Updates(map[string]interface{}{
+			"status":       database.TaskStatusRunning,
+			"worker_id":    s.instanceID,
+			"heartbeat_at": now,
+			"started_at":   now,
+			"updated_at":   now,
+		})
+
+	if result.Error != nil {
+		logrus.Errorf("更新任务状态失败: %v", result.Error)
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		// 任务已被取消或被其他工作器认领
+		logrus.Debugf("任务 %s 不再处于待处理状态，跳过", task.TaskID)
+		return
+	}
+
 	task.Status = database.TaskStatusRunning
+	task.WorkerID = s.instanceID
+	task.HeartbeatAt = &now
 	task.StartedAt = &now
 	task.UpdatedAt = now
 
-	if err := s.db.Save(task).Error; err != nil {
-		logrus.Errorf("更新任务状态失败: %v", err)
+	// 限流检查，超限时推迟任务而不是判定失败；结合任务表中的派发记录，重启和多实例下仍然生效
+	keys := rateLimitKeys(task)
+	if wait := s.limiter.reserve(keys, now, s.dispatchUsage(task, keys, now)); wait > 0 {
+		s.deferTask(task, wait)
 		return
 	}
 
+	if task.ParentTaskID != "" {
+		s.refreshParentStatus(task.ParentTaskID)
+	}
+
 	// 获取处理器
 	s.mu.RLock()
 	handler, exists := s.handlers[task.TaskType]
@@ -292,27 +478,47 @@ func (s *QueueService) processTask(ctx context.Context, task *database.AsyncTask
 		return
 	}
 
-	// 执行任务
+	// 执行任务，处理器通过 ctx 感知取消请求
+	taskCtx, cancel := context.WithCancel(ctx)
+	s.registerRunning(task.TaskID, cancel)
+	stopHeartbeat := s.startHeartbeat(taskCtx, task.TaskID, cancel)
+
 	startTime := time.Now()
-	err := handler(ctx, task)
+	err := handler(taskCtx, task)
 	duration := time.Since(startTime)
 
+	stopHeartbeat()
+	s.unregisterRunning(task.TaskID)
+	cancel()
+
 	// 记录执行结果
 	execution := &database.TaskExecution{
 		TaskID:      task.TaskID,
-		WorkerID:    fmt.Sprintf("worker-%d", time.Now().UnixNano()),
-		Status:      "success",
+		WorkerID:    s.instanceID,
+		Status:      ExecutionStatusSuccess,
 		DurationMs:  int(duration.Milliseconds()),
 		StartedAt:   *task.StartedAt,
 		CompletedAt: time.Now(),
 	}
 
 	if err != nil {
-		execution.Status = "failed"
-		execution.Error = err.Error()
-		s.handleTaskError(task, err)
-	} else {
-		s.handleTaskSuccess(task)
+		if requestedAt := s.cancelRequestedAt(task.TaskID); requestedAt != nil {
+			execution.Status = ExecutionStatusCancelled
+			execution.Error = err.Error()
+			execution.CancelRequestedAt = requestedAt
+			s.handleTaskCancelled(task, requestedAt)
+		} else {
+			execution.Status = ExecutionStatusFailed
+			execution.Error = err.Error()
+			s.handleTaskError(task, err)
+		}
+	} else if !s.handleTaskSuccess(task) {
+		// 处理器未响应取消就返回成功时，以取消请求为准
+		if requestedAt := s.cancelRequestedAt(task.TaskID); requestedAt != nil {
+			execution.Status = ExecutionStatusCancelled
+			execution.CancelRequestedAt = requestedAt
+			s.handleTaskCancelled(task, requestedAt)
+		}
 	}
 
 	// 保存执行记录
@@ -321,18 +527,34 @@ func (s *QueueService) processTask(ctx context.Context, task *database.AsyncTask
 	}
 }
 
-// handleTaskSuccess 处理任务成功
-func (s *QueueService) handleTaskSuccess(task *database.AsyncTask) {
+// handleTaskSuccess 处理任务成功，仅在任务仍处于运行中时置为已完成
+// 执行期间被请求取消或被回收的任务不覆盖其状态，返回 false
+func (s *QueueService) handleTaskSuccess(task *database.AsyncTask) bool {
 	now := time.Now()
+	result := s.db.Model(&database.AsyncTask{}).
+		Where("task_id = ? AND status = ?", task.TaskID, database.TaskStatusRunning).
+		Updates(map[string]interface{}{
+			"status":       database.TaskStatusCompleted,
+			"result":       task.Result,
+			"completed_at": now,
+			"updated_at":   now,
+		})
+	if result.Error != nil {
+		logrus.Errorf("更新任务状态失败: %v", result.Error)
+		return false
+	}
+	if result.RowsAffected == 0 {
+		logrus.Warnf("任务 %s 已不处于运行状态，不记录完成", task.TaskID)
+		return false
+	}
+
 	task.Status = database.TaskStatusCompleted
 	task.CompletedAt = &now
 	task.UpdatedAt = now
 
-	if err := s.db.Save(task).Error; err != nil {
-		logrus.Errorf("更新任务状态失败: %v", err)
-	}
-
 	logrus.Infof("任务完成: %s", task.TaskID)
+	s.onTaskFinished(task)
+	return true
 }
 
 // handleTaskError 处理任务错误
@@ -342,7 +564,7 @@ func (s *QueueService) handleTaskError(task *database.AsyncTask, err error) {
 	task.UpdatedAt = time.Now()
 
 	// 检查是否需要重试
-	if task.RetryCount < task.MaxRetries {
+	if task.RetryCount < task.MaxRetries && !IsNonRetryable(err) {
 		task.Status = database.TaskStatusRetrying
 		logrus.Warnf("任务将重试: %s, 重试次数: %d/%d", task.TaskID, task.RetryCount, task.MaxRetries)
 
@@ -359,6 +581,67 @@ func (s *QueueService) handleTaskError(task *database.AsyncTask, err error) {
 	if err := s.db.Save(task).Error; err != nil {
 		logrus.Errorf("更新任务状态失败: %v", err)
 	}
+
+	if task.Status == database.TaskStatusFailed {
+		s.onTaskFinished(task)
+	}
+}
+
+// nonRetryableError 重试也不会成功的错误
+type nonRetryableError struct {
+	err error
+}
+
+func (e *nonRetryableError) Error() string { return e.err.Error() }
+func (e *nonRetryableError) Unwrap() error { return e.err }
+
+// NonRetryable 标记处理器错误不需要重试，任务直接失败
+// 适用于内容被拦截、参数错误等重试也不会成功的情况
+func NonRetryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &nonRetryableError{err: err}
+}
+
+// IsNonRetryable 错误是否被标记为不需要重试
+func IsNonRetryable(err error) bool {
+	var target *nonRetryableError
+	return errors.As(err, &target)
+}
+
+// RequeueTask 重新执行已失败的任务，重试次数清零
+// 用于失败原因已被外部解除的情况，如内容通过人工审批
+func (s *QueueService) RequeueTask(taskID string) error {
+	now := time.Now()
+	result := s.db.Model(&database.AsyncTask{}).
+		Where("task_id = ? AND status = ? AND aggregate = ?", taskID, database.TaskStatusFailed, false).
+		Updates(map[string]interface{}{
+			"status":       database.TaskStatusPending,
+			"retry_count":  0,
+			"error":        "",
+			"scheduled_at": nil,
+			"completed_at": nil,
+			"updated_at":   now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("任务不存在或未失败: %s", taskID)
+	}
+
+	task, err := s.GetTask(taskID)
+	if err != nil {
+		return err
+	}
+	s.enqueue(task)
+	if task.ParentTaskID != "" {
+		s.refreshParentStatus(task.ParentTaskID)
+	}
+
+	logrus.Infof("任务已重新入队: %s", taskID)
+	return nil
 }
 
 // GetTask 获取任务
@@ -370,14 +653,62 @@ func (s *QueueService) GetTask(taskID string) (*database.AsyncTask, error) {
 	return &task, nil
 }
 
-// CancelTask 取消任务
+// WaitTask 等待任务进入终态
+func (s *QueueService) WaitTask(ctx context.Context, taskID string) (*database.AsyncTask, error) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		task, err := s.GetTask(taskID)
+		if err != nil {
+			return nil, err
+		}
+		if isTerminalStatus(task.Status) {
+			return task, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// CancelTask 取消任务，父任务会级联取消其子任务
+// 尚未运行的任务直接取消；运行中的任务进入取消中状态并通知处理器退出，
+// 由执行该任务的工作器在处理器返回后将其置为已取消
 func (s *QueueService) CancelTask(taskID string) error {
+	task, err := s.GetTask(taskID)
+	if err != nil {
+		return fmt.Errorf("任务不存在或无法取消")
+	}
+
+	children, err := s.GetChildTasks(taskID)
+	if err != nil {
+		return err
+	}
+
+	cancellable := []database.TaskStatus{
+		database.TaskStatusPending,
+		database.TaskStatusRetrying,
+		database.TaskStatusWaiting,
+	}
+	if len(children) > 0 {
+		// 父任务的运行状态由子任务汇总而来，同样允许取消
+		cancellable = append(cancellable, database.TaskStatusRunning)
+	} else if task.Status == database.TaskStatusRunning {
+		return s.requestCancel(task)
+	}
+
+	now := time.Now()
 	result := s.db.Model(&database.AsyncTask{}).
-		Where("task_id = ? AND status IN ?", taskID, []database.TaskStatus{
-			database.TaskStatusPending,
-			database.TaskStatusRetrying,
-		}).
-		Update("status", database.TaskStatusCancelled)
+		Where("task_id = ? AND status IN ?", taskID, cancellable).
+		Updates(map[string]interface{}{
+			"status":       database.TaskStatusCancelled,
+			"completed_at": now,
+			"updated_at":   now,
+		})
 
 	if result.Error != nil {
 		return result.Error
@@ -387,6 +718,20 @@ func (s *QueueService) CancelTask(taskID string) error {
 		return fmt.Errorf("任务不存在或无法取消")
 	}
 
+	// 级联取消子任务
+	for _, child := range children {
+		if isTerminalStatus(child.Status) {
+			continue
+		}
+		if err := s.CancelTask(child.TaskID); err != nil {
+			logrus.Warnf("取消子任务失败: %s, 错误: %v", child.TaskID, err)
+		}
+	}
+
+	task.Status = database.TaskStatusCancelled
+	task.CompletedAt = &now
+	s.onTaskFinished(task)
+
 	logrus.Infof("任务已取消: %s", taskID)
 	return nil
 }
@@ -423,9 +768,21 @@ func (s *QueueService) GetQueueStats(queueName string) (*QueueStats, error) {
 			stats.Completed = count
 		case database.TaskStatusFailed:
 			stats.Failed = count
+		case database.TaskStatusWaiting:
+			stats.Waiting = count
+		case database.TaskStatusCancelling:
+			stats.Cancelling = count
 		}
 	}
 
+	// 统计因限流或重试而推迟的任务
+	if err := s.db.Model(&database.AsyncTask{}).
+		Where("queue_name = ? AND status = ? AND scheduled_at > ?", queueName, database.TaskStatusPending, time.Now()).
+		Count(&stats.Deferred).Error; err != nil {
+		return nil, err
+	}
+	stats.RateLimits = s.limiter.Status()
+
 	s.mu.RLock()
 	if queue, exists := s.queues[queueName]; exists {
 		stats.QueueSize = len(queue.Tasks)
@@ -440,10 +797,16 @@ func (s *QueueService) GetQueueStats(queueName string) (*QueueStats, error) {
 // QueueStats 队列统计
 type QueueStats struct {
 	Pending      int64 `json:"pending"`
+	Waiting      int64 `json:"waiting"`
 	Running      int64 `json:"running"`
+	Cancelling   int64 `json:"cancelling"`
 	Completed    int64 `json:"completed"`
 	Failed       int64 `json:"failed"`
 	QueueSize    int   `json:"queue_size"`
 	QueueCapacity int  `json:"queue_capacity"`
 	Concurrency  int   `json:"concurrency"`
+	Deferred     int64 `json:"deferred"`
+
+	// RateLimits 当前限流桶状态
+	RateLimits []RateLimitStatus `json:"rate_limits"`
 }
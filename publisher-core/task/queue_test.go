@@ -0,0 +1,670 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"publisher-core/database"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestQueueService(t *testing.T) *QueueService {
+	t.Helper()
+
+	dbPath := filepath.Join(t.TempDir(), "queue.db")
+	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open db failed: %v", err)
+	}
+
+	if err := db.AutoMigrate(
+		&database.AsyncTask{},
+		&database.TaskQueue{},
+		&database.TaskExecution{},
+		&database.TaskDependency{},
+		&database.TaskRateLimit{},
+		&database.ScheduledTask{},
+		&database.SchedulerLease{},
+	); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	svc := NewQueueService(db, nil)
+	svc.RegisterHandler("noop", func(ctx context.Context, task *database.AsyncTask) error {
+		return nil
+	})
+	return svc
+}
+
+func mustGetTask(t *testing.T, svc *QueueService, taskID string) *database.AsyncTask {
+	t.Helper()
+
+	task, err := svc.GetTask(taskID)
+	if err != nil {
+		t.Fatalf("GetTask(%s) failed: %v", taskID, err)
+	}
+	return task
+}
+
+func TestQueueDependencies(t *testing.T) {
+	svc := newTestQueueService(t)
+	ctx := context.Background()
+
+	first, _, err := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default"})
+	if err != nil {
+		t.Fatalf("SubmitTask failed: %v", err)
+	}
+
+	second, _, err := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default", DependsOn: []string{first.TaskID}})
+	if err != nil {
+		t.Fatalf("SubmitTask with dependency failed: %v", err)
+	}
+	if second.Status != database.TaskStatusWaiting {
+		t.Errorf("Expected status %s, got %s", database.TaskStatusWaiting, second.Status)
+	}
+
+	svc.processTask(ctx, first)
+
+	if got := mustGetTask(t, svc, second.TaskID).Status; got != database.TaskStatusPending {
+		t.Errorf("Expected dependent task to be %s after dependency completed, got %s", database.TaskStatusPending, got)
+	}
+
+	if _, _, err := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default", DependsOn: []string{"missing"}}); !errors.Is(err, ErrInvalidTaskRequest) {
+		t.Errorf("Expected ErrInvalidTaskRequest for missing dependency, got %v", err)
+	}
+}
+
+func TestQueueDuplicateDependencies(t *testing.T) {
+	svc := newTestQueueService(t)
+	ctx := context.Background()
+
+	first, _, err := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default"})
+	if err != nil {
+		t.Fatalf("SubmitTask failed: %v", err)
+	}
+	second, _, err := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default", DependsOn: []string{first.TaskID, first.TaskID}})
+	if err != nil {
+		t.Fatalf("SubmitTask with duplicate dependency failed: %v", err)
+	}
+
+	if deps, _ := svc.GetDependencies(second.TaskID); len(deps) != 1 {
+		t.Errorf("Expected duplicate dependency to be stored once, got %v", deps)
+	}
+
+	svc.processTask(ctx, first)
+
+	if got := mustGetTask(t, svc, second.TaskID).Status; got != database.TaskStatusPending {
+		t.Errorf("Expected dependent task to be %s after dependency completed, got %s", database.TaskStatusPending, got)
+	}
+}
+
+func TestQueueDependencyFailure(t *testing.T) {
+	svc := newTestQueueService(t)
+	ctx := context.Background()
+
+	first, _, _ := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default"})
+	second, _, _ := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default", DependsOn: []string{first.TaskID}})
+
+	if err := svc.CancelTask(first.TaskID); err != nil {
+		t.Fatalf("CancelTask failed: %v", err)
+	}
+
+	if got := mustGetTask(t, svc, second.TaskID).Status; got != database.TaskStatusFailed {
+		t.Errorf("Expected dependent task to be %s after dependency cancelled, got %s", database.TaskStatusFailed, got)
+	}
+}
+
+func TestQueueNonRetryableAndRequeue(t *testing.T) {
+	svc := newTestQueueService(t)
+	ctx := context.Background()
+
+	approved := false
+	svc.RegisterHandler("gated", func(ctx context.Context, task *database.AsyncTask) error {
+		if !approved {
+			return NonRetryable(errors.New("needs approval"))
+		}
+		return nil
+	})
+
+	submitted, _, _ := svc.SubmitTask(ctx, &TaskRequest{TaskType: "gated", QueueName: "default", MaxRetries: 3})
+	svc.processTask(ctx, submitted)
+
+	failed := mustGetTask(t, svc, submitted.TaskID)
+	if failed.Status != database.TaskStatusFailed || failed.RetryCount != 1 {
+		t.Fatalf("Expected non-retryable error to fail immediately, got %s after %d attempts", failed.Status, failed.RetryCount)
+	}
+
+	approved = true
+	if err := svc.RequeueTask(submitted.TaskID); err != nil {
+		t.Fatalf("RequeueTask failed: %v", err)
+	}
+	requeued := mustGetTask(t, svc, submitted.TaskID)
+	if requeued.Status != database.TaskStatusPending || requeued.RetryCount != 0 || requeued.Error != "" {
+		t.Fatalf("Unexpected requeued task: %+v", requeued)
+	}
+
+	svc.processTask(ctx, requeued)
+	if got := mustGetTask(t, svc, submitted.TaskID).Status; got != database.TaskStatusCompleted {
+		t.Errorf("Expected requeued task to complete, got %s", got)
+	}
+	if err := svc.RequeueTask(submitted.TaskID); err == nil {
+		t.Error("Expected requeue of completed task to fail")
+	}
+}
+
+func TestQueueWorkflowAggregation(t *testing.T) {
+	svc := newTestQueueService(t)
+	ctx := context.Background()
+
+	requests := []*TaskRequest{
+		{TaskType: "noop", QueueName: "default"},
+		{TaskType: "noop", QueueName: "default"},
+	}
+	parent, children, err := svc.SubmitWorkflow(ctx, &TaskRequest{TaskType: "noop", QueueName: "default"}, requests)
+	if err != nil {
+		t.Fatalf("SubmitWorkflow failed: %v", err)
+	}
+	if requests[0].ParentTaskID != "" {
+		t.Error("Expected caller's requests not to be modified")
+	}
+	if !parent.Aggregate || children[0].Aggregate {
+		t.Errorf("Expected only the parent to be an aggregate task")
+	}
+
+	// 父任务即使被放入队列也不会执行
+	svc.processTask(ctx, parent)
+	if got := mustGetTask(t, svc, parent.TaskID).Status; got != database.TaskStatusWaiting {
+		t.Errorf("Expected parent to stay %s, got %s", database.TaskStatusWaiting, got)
+	}
+
+	svc.processTask(ctx, children[0])
+
+	got := mustGetTask(t, svc, parent.TaskID)
+	if got.Status != database.TaskStatusRunning {
+		t.Errorf("Expected parent status %s, got %s", database.TaskStatusRunning, got.Status)
+	}
+	if got.Progress != 50 {
+		t.Errorf("Expected parent progress 50, got %d", got.Progress)
+	}
+
+	svc.processTask(ctx, children[1])
+
+	if got := mustGetTask(t, svc, parent.TaskID).Status; got != database.TaskStatusCompleted {
+		t.Errorf("Expected parent status %s, got %s", database.TaskStatusCompleted, got)
+	}
+}
+
+func TestQueueRejectsExecutableParent(t *testing.T) {
+	svc := newTestQueueService(t)
+	ctx := context.Background()
+
+	plain, _, _ := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default"})
+	if _, _, err := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default", ParentTaskID: plain.TaskID}); err == nil {
+		t.Error("Expected a non-workflow task to be rejected as parent")
+	}
+	if _, _, err := svc.SubmitWorkflow(ctx,
+		&TaskRequest{TaskType: "campaign", QueueName: "default", DependsOn: []string{plain.TaskID}},
+		[]*TaskRequest{{TaskType: "noop", QueueName: "default"}}); err == nil {
+		t.Error("Expected workflow parent with dependencies to be rejected")
+	}
+}
+
+func TestQueueWorkflowCascadeCancel(t *testing.T) {
+	svc := newTestQueueService(t)
+	ctx := context.Background()
+
+	parent, children, err := svc.SubmitWorkflow(ctx,
+		&TaskRequest{TaskType: "campaign", QueueName: "default"},
+		[]*TaskRequest{
+			{TaskType: "noop", QueueName: "default"},
+			{TaskType: "noop", QueueName: "default"},
+		})
+	if err != nil {
+		t.Fatalf("SubmitWorkflow failed: %v", err)
+	}
+
+	if err := svc.CancelTask(parent.TaskID); err != nil {
+		t.Fatalf("CancelTask failed: %v", err)
+	}
+
+	for _, child := range children {
+		if got := mustGetTask(t, svc, child.TaskID).Status; got != database.TaskStatusCancelled {
+			t.Errorf("Expected child status %s, got %s", database.TaskStatusCancelled, got)
+		}
+	}
+
+	// 已取消的子任务不应再被执行
+	svc.processTask(ctx, children[0])
+	if got := mustGetTask(t, svc, children[0].TaskID).Status; got != database.TaskStatusCancelled {
+		t.Errorf("Expected cancelled child to stay %s, got %s", database.TaskStatusCancelled, got)
+	}
+}
+
+func TestQueueRateLimitDefersTask(t *testing.T) {
+	svc := newTestQueueService(t)
+	ctx := context.Background()
+
+	if err := svc.SetRateLimit(&database.TaskRateLimit{
+		Scope:       RateLimitScopeAccount,
+		Key:         "douyin-1",
+		MaxCount:    3,
+		Period:      3600,
+		MinInterval: 600,
+		IsActive:    true,
+	}); err != nil {
+		t.Fatalf("SetRateLimit failed: %v", err)
+	}
+
+	payload := map[string]interface{}{"platform": "douyin", "account_id": "douyin-1"}
+	first, _, _ := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default", Payload: payload})
+	second, _, _ := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default", Payload: payload})
+
+	svc.processTask(ctx, first)
+	svc.processTask(ctx, second)
+
+	if got := mustGetTask(t, svc, first.TaskID).Status; got != database.TaskStatusCompleted {
+		t.Errorf("Expected first task %s, got %s", database.TaskStatusCompleted, got)
+	}
+
+	deferred := mustGetTask(t, svc, second.TaskID)
+	if deferred.Status != database.TaskStatusPending {
+		t.Errorf("Expected deferred task %s, got %s", database.TaskStatusPending, deferred.Status)
+	}
+	if deferred.ScheduledAt == nil || deferred.ScheduledAt.Sub(time.Now()) < 9*time.Minute {
+		t.Errorf("Expected task to be deferred by min interval, got %v", deferred.ScheduledAt)
+	}
+
+	// 推迟中的任务不能被再次认领
+	svc.processTask(ctx, deferred)
+	if got := mustGetTask(t, svc, second.TaskID).Status; got != database.TaskStatusPending {
+		t.Errorf("Expected deferred task to stay %s, got %s", database.TaskStatusPending, got)
+	}
+
+	stats, err := svc.GetQueueStats("default")
+	if err != nil {
+		t.Fatalf("GetQueueStats failed: %v", err)
+	}
+	if stats.Deferred != 1 {
+		t.Errorf("Expected 1 deferred task, got %d", stats.Deferred)
+	}
+	if len(stats.RateLimits) != 1 || stats.RateLimits[0].DeferredCount != 1 {
+		t.Errorf("Expected rate limit status with 1 deferral, got %+v", stats.RateLimits)
+	}
+}
+
+func TestQueueRateLimitSharedAcrossInstances(t *testing.T) {
+	svc := newTestQueueService(t)
+	ctx := context.Background()
+
+	rule := &database.TaskRateLimit{
+		Scope:       RateLimitScopeAccount,
+		Key:         "douyin-1",
+		MaxCount:    1,
+		Period:      3600,
+		MinInterval: 600,
+		IsActive:    true,
+	}
+	if err := svc.SetRateLimit(rule); err != nil {
+		t.Fatalf("SetRateLimit failed: %v", err)
+	}
+
+	payload := map[string]interface{}{"platform": "douyin", "account_id": "douyin-1"}
+	first, _, _ := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default", Payload: payload})
+	second, _, _ := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default", Payload: payload})
+	if first.AccountID != "douyin-1" || first.Platform != "douyin" {
+		t.Fatalf("Expected account and platform columns to be set: %+v", first)
+	}
+
+	svc.processTask(ctx, first)
+
+	// 重启后或另一个实例的限流器是空的，仍需根据任务表中的派发记录推迟
+	other := NewQueueService(svc.db, nil)
+	other.RegisterHandler("noop", func(ctx context.Context, task *database.AsyncTask) error {
+		return nil
+	})
+	if err := other.LoadRateLimits(); err != nil {
+		t.Fatalf("LoadRateLimits failed: %v", err)
+	}
+	other.processTask(ctx, second)
+
+	deferred := mustGetTask(t, svc, second.TaskID)
+	if deferred.Status != database.TaskStatusPending || deferred.ScheduledAt == nil {
+		t.Fatalf("Expected task to be deferred by the other instance, got %s", deferred.Status)
+	}
+	if wait := deferred.ScheduledAt.Sub(time.Now()); wait < 9*time.Minute {
+		t.Errorf("Expected deferral to honour min interval since the first dispatch, got %s", wait)
+	}
+}
+
+func TestTokenBucketRefill(t *testing.T) {
+	limiter := NewRateLimiter()
+	limiter.SetRule(database.TaskRateLimit{Scope: RateLimitScopePlatform, Key: "douyin", MaxCount: 2, Period: 60, IsActive: true})
+
+	keys := map[string]string{RateLimitScopePlatform: "douyin"}
+	now := time.Now()
+
+	if wait := limiter.Reserve(keys, now); wait != 0 {
+		t.Errorf("Expected first reserve to pass, wait %v", wait)
+	}
+	if wait := limiter.Reserve(keys, now); wait != 0 {
+		t.Errorf("Expected second reserve to pass, wait %v", wait)
+	}
+	if wait := limiter.Reserve(keys, now); wait <= 0 {
+		t.Error("Expected third reserve to be limited")
+	}
+	if wait := limiter.Reserve(keys, now.Add(30*time.Second)); wait != 0 {
+		t.Errorf("Expected reserve to pass after refill, wait %v", wait)
+	}
+}
+
+func TestQueueIdempotencyKey(t *testing.T) {
+	svc := newTestQueueService(t)
+	ctx := context.Background()
+
+	req := &TaskRequest{
+		TaskType:       "noop",
+		QueueName:      "default",
+		UserID:         "user-1",
+		Payload:        map[string]interface{}{"title": "a"},
+		IdempotencyKey: "client-req-1",
+	}
+	first, duplicate, err := svc.SubmitTask(ctx, req)
+	if err != nil || duplicate {
+		t.Fatalf("SubmitTask failed: %v, duplicate %v", err, duplicate)
+	}
+
+	second, duplicate, err := svc.SubmitTask(ctx, req)
+	if err != nil {
+		t.Fatalf("SubmitTask retry failed: %v", err)
+	}
+	if second.TaskID != first.TaskID || !duplicate {
+		t.Errorf("Expected retry to return task %s as duplicate, got %s, duplicate %v", first.TaskID, second.TaskID, duplicate)
+	}
+
+	// 相同幂等键携带不同内容时拒绝
+	changed := *req
+	changed.Payload = map[string]interface{}{"title": "b"}
+	if _, _, err := svc.SubmitTask(ctx, &changed); !errors.Is(err, ErrIdempotencyConflict) {
+		t.Errorf("Expected ErrIdempotencyConflict, got %v", err)
+	}
+
+	// 幂等键按用户隔离
+	other := *req
+	other.UserID = "user-2"
+	otherTask, duplicate, err := svc.SubmitTask(ctx, &other)
+	if err != nil || duplicate || otherTask.TaskID == first.TaskID {
+		t.Errorf("Expected another user's key to create a new task, got %v, duplicate %v", err, duplicate)
+	}
+
+	// 超出窗口期后相同幂等键创建新任务
+	svc.config.IdempotencyWindow = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	third, _, err := svc.SubmitTask(ctx, req)
+	if err != nil {
+		t.Fatalf("SubmitTask after window failed: %v", err)
+	}
+	if third.TaskID == first.TaskID {
+		t.Error("Expected a new task after idempotency window expired")
+	}
+}
+
+func TestQueueContentDedup(t *testing.T) {
+	svc := newTestQueueService(t)
+	ctx := context.Background()
+
+	payload := map[string]interface{}{
+		"platform":   "douyin",
+		"account_id": "douyin-1",
+		"title":      "Test Title",
+		"content":    "Same body",
+		"images":     []interface{}{"a.jpg", "b.jpg"},
+	}
+
+	if _, _, err := svc.SubmitTask(ctx, &TaskRequest{TaskType: "publish", QueueName: "default", Payload: payload}); err != nil {
+		t.Fatalf("SubmitTask failed: %v", err)
+	}
+
+	repeat := map[string]interface{}{
+		"platform":   "douyin",
+		"account_id": "douyin-1",
+		"title":      " Test  Title ",
+		"content":    "Same body",
+		"images":     []interface{}{"b.jpg", "a.jpg"},
+	}
+	_, _, err := svc.SubmitTask(ctx, &TaskRequest{TaskType: "publish", QueueName: "default", Payload: repeat})
+	if !errors.Is(err, ErrDuplicateContent) {
+		t.Errorf("Expected ErrDuplicateContent, got %v", err)
+	}
+
+	if _, _, err := svc.SubmitTask(ctx, &TaskRequest{TaskType: "publish", QueueName: "default", Payload: repeat, Force: true}); err != nil {
+		t.Errorf("Expected forced submit to succeed, got %v", err)
+	}
+
+	repeat["account_id"] = "douyin-2"
+	if _, _, err := svc.SubmitTask(ctx, &TaskRequest{TaskType: "publish", QueueName: "default", Payload: repeat}); err != nil {
+		t.Errorf("Expected same content to another account to succeed, got %v", err)
+	}
+}
+
+func TestQueueTaskManager(t *testing.T) {
+	svc := newTestQueueService(t)
+	mgr := NewQueueTaskManager(svc, NewStateService(svc.db))
+	ctx := context.Background()
+
+	mgr.RegisterHandler("publish", func(ctx context.Context, task *Task) error {
+		task.Result = map[string]interface{}{"post_id": "123"}
+		return nil
+	})
+
+	created, err := mgr.CreateTask("publish", "douyin", map[string]interface{}{"title": "Hello"})
+	if err != nil {
+		t.Fatalf("CreateTask failed: %v", err)
+	}
+
+	asyncTask := mustGetTask(t, svc, created.ID)
+	if asyncTask.TaskType != "publish" {
+		t.Errorf("Expected task type publish, got %s", asyncTask.TaskType)
+	}
+
+	svc.processTask(ctx, asyncTask)
+
+	got, err := mgr.GetTask(created.ID)
+	if err != nil {
+		t.Fatalf("GetTask failed: %v", err)
+	}
+	if got.Status != TaskStatusCompleted {
+		t.Errorf("Expected status %s, got %s", TaskStatusCompleted, got.Status)
+	}
+	if got.Platform != "douyin" {
+		t.Errorf("Expected platform douyin, got %s", got.Platform)
+	}
+	if got.Result["post_id"] != "123" {
+		t.Errorf("Expected result to be persisted, got %v", got.Result)
+	}
+
+	tasks, err := mgr.ListTasks(TaskFilter{Platform: "douyin"})
+	if err != nil {
+		t.Fatalf("ListTasks failed: %v", err)
+	}
+	if len(tasks) != 1 {
+		t.Errorf("Expected 1 task, got %d", len(tasks))
+	}
+}
+
+func waitForStatus(t *testing.T, svc *QueueService, taskID string, status database.TaskStatus) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if mustGetTask(t, svc, taskID).Status == status {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for task %s to be %s", taskID, status)
+}
+
+func TestQueueCancelRunningTask(t *testing.T) {
+	svc := newTestQueueService(t)
+	svc.config.HeartbeatInterval = 10 * time.Millisecond
+	ctx := context.Background()
+
+	svc.RegisterHandler("blocking", func(ctx context.Context, task *database.AsyncTask) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	submitted, _, err := svc.SubmitTask(ctx, &TaskRequest{TaskType: "blocking", QueueName: "default"})
+	if err != nil {
+		t.Fatalf("SubmitTask failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		svc.processTask(ctx, submitted)
+		close(done)
+	}()
+	waitForStatus(t, svc, submitted.TaskID, database.TaskStatusRunning)
+
+	// 另一实例共享数据库，取消请求通过心跳传递
+	other := NewQueueService(svc.db, nil)
+	if err := other.CancelTask(submitted.TaskID); err != nil {
+		t.Fatalf("CancelTask failed: %v", err)
+	}
+	if got := mustGetTask(t, svc, submitted.TaskID).Status; got != database.TaskStatusCancelling && got != database.TaskStatusCancelled {
+		t.Errorf("Expected status %s, got %s", database.TaskStatusCancelling, got)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Handler did not observe cancellation")
+	}
+
+	got := mustGetTask(t, svc, submitted.TaskID)
+	if got.Status != database.TaskStatusCancelled {
+		t.Errorf("Expected status %s, got %s", database.TaskStatusCancelled, got.Status)
+	}
+
+	var execution database.TaskExecution
+	if err := svc.db.Where("task_id = ?", submitted.TaskID).First(&execution).Error; err != nil {
+		t.Fatalf("Load execution failed: %v", err)
+	}
+	if execution.Status != ExecutionStatusCancelled || execution.CancelRequestedAt == nil {
+		t.Errorf("Expected cancelled execution with request time, got %s %v", execution.Status, execution.CancelRequestedAt)
+	}
+}
+
+func TestQueueRecoverStaleTask(t *testing.T) {
+	svc := newTestQueueService(t)
+	ctx := context.Background()
+
+	submitted, _, _ := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default"})
+
+	stale := time.Now().Add(-time.Hour)
+	svc.db.Model(&database.AsyncTask{}).Where("task_id = ?", submitted.TaskID).Updates(map[string]interface{}{
+		"status":       database.TaskStatusCancelling,
+		"worker_id":    "dead-worker",
+		"heartbeat_at": stale,
+	})
+
+	svc.recoverStaleTasks()
+
+	if got := mustGetTask(t, svc, submitted.TaskID).Status; got != database.TaskStatusCancelled {
+		t.Errorf("Expected stale cancelling task to be %s, got %s", database.TaskStatusCancelled, got)
+	}
+
+	// 工作器在首次心跳前退出的任务按开始时间回收
+	orphan, _, _ := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default", MaxRetries: 1})
+	svc.db.Model(&database.AsyncTask{}).Where("task_id = ?", orphan.TaskID).Updates(map[string]interface{}{
+		"status":     database.TaskStatusRunning,
+		"worker_id":  "dead-worker",
+		"started_at": stale,
+	})
+
+	// 工作流父任务没有心跳，不应被回收
+	parent, _, err := svc.SubmitWorkflow(ctx, &TaskRequest{TaskType: "workflow", QueueName: "default"},
+		[]*TaskRequest{{TaskType: "noop", QueueName: "default"}})
+	if err != nil {
+		t.Fatalf("SubmitWorkflow failed: %v", err)
+	}
+	svc.db.Model(&database.AsyncTask{}).Where("task_id = ?", parent.TaskID).Updates(map[string]interface{}{
+		"status":     database.TaskStatusRunning,
+		"started_at": stale,
+	})
+
+	svc.recoverStaleTasks()
+
+	if got := mustGetTask(t, svc, orphan.TaskID).Status; got != database.TaskStatusFailed {
+		t.Errorf("Expected task without heartbeat to be %s, got %s", database.TaskStatusFailed, got)
+	}
+	if got := mustGetTask(t, svc, parent.TaskID).Status; got != database.TaskStatusRunning {
+		t.Errorf("Expected workflow parent to stay %s, got %s", database.TaskStatusRunning, got)
+	}
+}
+
+func TestQueueSuccessAfterCancelRequest(t *testing.T) {
+	svc := newTestQueueService(t)
+	ctx := context.Background()
+
+	// 处理器忽略 ctx，收到取消请求后仍返回成功
+	release := make(chan struct{})
+	svc.RegisterHandler("ignoring", func(ctx context.Context, task *database.AsyncTask) error {
+		<-release
+		return nil
+	})
+
+	submitted, _, err := svc.SubmitTask(ctx, &TaskRequest{TaskType: "ignoring", QueueName: "default"})
+	if err != nil {
+		t.Fatalf("SubmitTask failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		svc.processTask(ctx, submitted)
+		close(done)
+	}()
+	waitForStatus(t, svc, submitted.TaskID, database.TaskStatusRunning)
+
+	if err := svc.CancelTask(submitted.TaskID); err != nil {
+		t.Fatalf("CancelTask failed: %v", err)
+	}
+	close(release)
+	<-done
+
+	if got := mustGetTask(t, svc, submitted.TaskID).Status; got != database.TaskStatusCancelled {
+		t.Errorf("Expected cancel request to win over late success, got %s", got)
+	}
+}
+
+func TestQueueNoRecoveryWithoutHeartbeat(t *testing.T) {
+	svc := newTestQueueService(t)
+	svc.config.HeartbeatInterval = 0
+	ctx := context.Background()
+
+	submitted, _, _ := svc.SubmitTask(ctx, &TaskRequest{TaskType: "noop", QueueName: "default"})
+	svc.db.Model(&database.AsyncTask{}).Where("task_id = ?", submitted.TaskID).Updates(map[string]interface{}{
+		"status":     database.TaskStatusRunning,
+		"worker_id":  "busy-worker",
+		"started_at": time.Now().Add(-time.Hour),
+	})
+
+	// 未开启心跳时长时间运行的任务不能被当作已退出回收
+	svc.recoverStaleTasks()
+
+	if got := mustGetTask(t, svc, submitted.TaskID).Status; got != database.TaskStatusRunning {
+		t.Errorf("Expected long-running task to stay %s, got %s", database.TaskStatusRunning, got)
+	}
+}
@@ -0,0 +1,441 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"publisher-core/database"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// 限流维度
+const (
+	RateLimitScopeTaskType = "task_type"
+	RateLimitScopePlatform = "platform"
+	RateLimitScopeAccount  = "account"
+)
+
+// RateLimiter 任务限流器，按任务类型、平台、账号维度维护令牌桶
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+// tokenBucket 令牌桶
+type tokenBucket struct {
+	rule       database.TaskRateLimit
+	tokens     float64
+	lastRefill time.Time
+	lastTake   time.Time
+	deferred   int64
+}
+
+// RateLimitStatus 限流状态
+type RateLimitStatus struct {
+	Scope           string     `json:"scope"`
+	Key             string     `json:"key"`
+	MaxCount        int        `json:"max_count"`
+	Period          int        `json:"period"`
+	MinInterval     int        `json:"min_interval"`
+	AvailableTokens float64    `json:"available_tokens"`
+	LastDispatchAt  *time.Time `json:"last_dispatch_at,omitempty"`
+	NextAvailableAt *time.Time `json:"next_available_at,omitempty"`
+	DeferredCount   int64      `json:"deferred_count"`
+}
+
+// NewRateLimiter 创建限流器
+func NewRateLimiter() *RateLimiter {
+	return &RateLimiter{
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// rateLimitKey 生成限流桶键
+func rateLimitKey(scope, key string) string {
+	return scope + ":" + key
+}
+
+// SetRule 设置限流规则，已有桶保留当前令牌数
+func (l *RateLimiter) SetRule(rule database.TaskRateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	k := rateLimitKey(rule.Scope, rule.Key)
+	if bucket, exists := l.buckets[k]; exists {
+		bucket.rule = rule
+		if rule.MaxCount > 0 && bucket.tokens > float64(rule.MaxCount) {
+			bucket.tokens = float64(rule.MaxCount)
+		}
+		return
+	}
+
+	l.buckets[k] = &tokenBucket{
+		rule:       rule,
+		tokens:     float64(rule.MaxCount),
+		lastRefill: time.Now(),
+	}
+}
+
+// RemoveRule 移除限流规则
+func (l *RateLimiter) RemoveRule(scope, key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.buckets, rateLimitKey(scope, key))
+}
+
+// Reserve 检查所有匹配的令牌桶，全部允许时扣减令牌并返回0，否则返回需要等待的时长
+func (l *RateLimiter) Reserve(keys map[string]string, now time.Time) time.Duration {
+	return l.reserve(keys, now, nil)
+}
+
+// dispatchUsage 数据库中记录的某个限流维度的近期派发情况
+type dispatchUsage struct {
+	count int       // 周期内已派发的任务数
+	last  time.Time // 最近一次派发时间
+}
+
+// activeRules 返回与任务限流维度匹配的生效规则
+func (l *RateLimiter) activeRules(keys map[string]string) []database.TaskRateLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var rules []database.TaskRateLimit
+	for scope, key := range keys {
+		if key == "" {
+			continue
+		}
+		if bucket, exists := l.buckets[rateLimitKey(scope, key)]; exists && bucket.rule.IsActive {
+			rules = append(rules, bucket.rule)
+		}
+	}
+	return rules
+}
+
+// reserve 同 Reserve，usage 为数据库中的派发记录，按桶键合并到令牌桶后再判断
+// 进程重启或多个实例共用账号时，令牌数和上次派发时间以更严格的一方为准
+func (l *RateLimiter) reserve(keys map[string]string, now time.Time, usage map[string]dispatchUsage) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var matched []*tokenBucket
+	var wait time.Duration
+	for scope, key := range keys {
+		if key == "" {
+			continue
+		}
+		k := rateLimitKey(scope, key)
+		bucket, exists := l.buckets[k]
+		if !exists || !bucket.rule.IsActive {
+			continue
+		}
+		matched = append(matched, bucket)
+		if u, ok := usage[k]; ok {
+			bucket.observe(u, now)
+		}
+		if w := bucket.waitTime(now); w > wait {
+			wait = w
+		}
+	}
+
+	if wait > 0 {
+		for _, bucket := range matched {
+			bucket.deferred++
+		}
+		return wait
+	}
+
+	for _, bucket := range matched {
+		bucket.take(now)
+	}
+	return 0
+}
+
+// Status 获取所有限流桶状态
+func (l *RateLimiter) Status() []RateLimitStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]RateLimitStatus, 0, len(l.buckets))
+	for _, bucket := range l.buckets {
+		bucket.refill(now)
+		status := RateLimitStatus{
+			Scope:           bucket.rule.Scope,
+			Key:             bucket.rule.Key,
+			MaxCount:        bucket.rule.MaxCount,
+			Period:          bucket.rule.Period,
+			MinInterval:     bucket.rule.MinInterval,
+			AvailableTokens: bucket.tokens,
+			DeferredCount:   bucket.deferred,
+		}
+		if !bucket.lastTake.IsZero() {
+			last := bucket.lastTake
+			status.LastDispatchAt = &last
+		}
+		if w := bucket.waitTime(now); w > 0 {
+			next := now.Add(w)
+			status.NextAvailableAt = &next
+		}
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Scope != statuses[j].Scope {
+			return statuses[i].Scope < statuses[j].Scope
+		}
+		return statuses[i].Key < statuses[j].Key
+	})
+	return statuses
+}
+
+// refill 按周期补充令牌
+func (b *tokenBucket) refill(now time.Time) {
+	if b.rule.MaxCount <= 0 || b.rule.Period <= 0 {
+		return
+	}
+
+	elapsed := now.Sub(b.lastRefill).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	rate := float64(b.rule.MaxCount) / float64(b.rule.Period)
+	b.tokens += elapsed * rate
+	if b.tokens > float64(b.rule.MaxCount) {
+		b.tokens = float64(b.rule.MaxCount)
+	}
+	b.lastRefill = now
+}
+
+// waitTime 计算距离可执行还需等待的时长
+func (b *tokenBucket) waitTime(now time.Time) time.Duration {
+	b.refill(now)
+
+	var wait time.Duration
+	if b.rule.MinInterval > 0 && !b.lastTake.IsZero() {
+		next := b.lastTake.Add(time.Duration(b.rule.MinInterval) * time.Second)
+		if next.After(now) {
+			wait = next.Sub(now)
+		}
+	}
+
+	if b.rule.MaxCount > 0 && b.rule.Period > 0 && b.tokens < 1 {
+		rate := float64(b.rule.MaxCount) / float64(b.rule.Period)
+		tokenWait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		if tokenWait > wait {
+			wait = tokenWait
+		}
+	}
+
+	return wait
+}
+
+// observe 合并数据库中的派发记录
+func (b *tokenBucket) observe(u dispatchUsage, now time.Time) {
+	b.refill(now)
+	if b.rule.MaxCount > 0 && b.rule.Period > 0 {
+		if available := float64(b.rule.MaxCount - u.count); available < b.tokens {
+			b.tokens = available
+		}
+	}
+	if u.last.After(b.lastTake) {
+		b.lastTake = u.last
+	}
+}
+
+// take 扣减一个令牌
+func (b *tokenBucket) take(now time.Time) {
+	if b.rule.MaxCount > 0 {
+		b.tokens--
+	}
+	b.lastTake = now
+}
+
+// rateLimitKeys 提取任务对应的限流维度
+func rateLimitKeys(task *database.AsyncTask) map[string]string {
+	keys := map[string]string{
+		RateLimitScopeTaskType: task.TaskType,
+	}
+
+	if task.Payload == "" {
+		return keys
+	}
+
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(task.Payload), &payload); err != nil {
+		return keys
+	}
+
+	if platform, ok := payload["platform"].(string); ok {
+		keys[RateLimitScopePlatform] = platform
+	}
+	if accountID, ok := payload["account_id"].(string); ok {
+		keys[RateLimitScopeAccount] = accountID
+	}
+
+	return keys
+}
+
+// rateLimitColumns 限流维度对应的任务字段
+var rateLimitColumns = map[string]string{
+	RateLimitScopeTaskType: "task_type",
+	RateLimitScopePlatform: "platform",
+	RateLimitScopeAccount:  "account_id",
+}
+
+// dispatchUsage 从任务表统计匹配规则的近期派发情况，派发以认领时写入的 started_at 为准
+// 只统计认领时间不晚于当前任务的记录，并发认领时由较早的任务占用额度
+func (s *QueueService) dispatchUsage(task *database.AsyncTask, keys map[string]string, now time.Time) map[string]dispatchUsage {
+	rules := s.limiter.activeRules(keys)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	usage := make(map[string]dispatchUsage, len(rules))
+	for _, rule := range rules {
+		column, ok := rateLimitColumns[rule.Scope]
+		if !ok {
+			continue
+		}
+		dispatched := func() *gorm.DB {
+			return s.db.Model(&database.AsyncTask{}).
+				Where(column+" = ? AND task_id <> ?", rule.Key, task.TaskID).
+				Where("started_at IS NOT NULL AND started_at <= ?", now)
+		}
+
+		var u dispatchUsage
+		if rule.MaxCount > 0 && rule.Period > 0 {
+			var count int64
+			since := now.Add(-time.Duration(rule.Period) * time.Second)
+			if err := dispatched().Where("started_at > ?", since).Count(&count).Error; err != nil {
+				logrus.Warnf("统计限流派发记录失败: %v", err)
+				continue
+			}
+			u.count = int(count)
+		}
+		if rule.MinInterval > 0 {
+			var last database.AsyncTask
+			err := dispatched().Select("started_at").Order("started_at DESC").Limit(1).Find(&last).Error
+			if err != nil {
+				logrus.Warnf("查询最近派发记录失败: %v", err)
+				continue
+			}
+			if last.StartedAt != nil {
+				u.last = *last.StartedAt
+			}
+		}
+		usage[rateLimitKey(rule.Scope, rule.Key)] = u
+	}
+	return usage
+}
+
+// LoadRateLimits 从数据库加载限流规则
+func (s *QueueService) LoadRateLimits() error {
+	var rules []database.TaskRateLimit
+	if err := s.db.Where("is_active = ?", true).Find(&rules).Error; err != nil {
+		return fmt.Errorf("加载限流规则失败: %w", err)
+	}
+
+	for _, rule := range rules {
+		s.limiter.SetRule(rule)
+	}
+
+	logrus.Infof("已加载 %d 条限流规则", len(rules))
+	return nil
+}
+
+// SetRateLimit 创建或更新限流规则
+func (s *QueueService) SetRateLimit(rule *database.TaskRateLimit) error {
+	switch rule.Scope {
+	case RateLimitScopeTaskType, RateLimitScopePlatform, RateLimitScopeAccount:
+	default:
+		return fmt.Errorf("无效的限流维度: %s", rule.Scope)
+	}
+	if rule.Key == "" {
+		return fmt.Errorf("限流规则缺少key")
+	}
+	if rule.MaxCount < 0 || rule.Period < 0 || rule.MinInterval < 0 {
+		return fmt.Errorf("限流参数不能为负数")
+	}
+	if rule.MaxCount > 0 && rule.Period == 0 {
+		rule.Period = 3600
+	}
+
+	var existing database.TaskRateLimit
+	err := s.db.Where("scope = ? AND key = ?", rule.Scope, rule.Key).First(&existing).Error
+	if err == nil {
+		rule.ID = existing.ID
+		rule.CreatedAt = existing.CreatedAt
+	}
+
+	if err := s.db.Save(rule).Error; err != nil {
+		return fmt.Errorf("保存限流规则失败: %w", err)
+	}
+	if !rule.IsActive {
+		// is_active 带默认值，新建时零值会被默认值覆盖
+		if err := s.db.Model(rule).Update("is_active", false).Error; err != nil {
+			return fmt.Errorf("保存限流规则失败: %w", err)
+		}
+	}
+
+	if rule.IsActive {
+		s.limiter.SetRule(*rule)
+	} else {
+		s.limiter.RemoveRule(rule.Scope, rule.Key)
+	}
+
+	logrus.Infof("限流规则已更新: %s=%s, %d次/%d秒, 最小间隔%d秒",
+		rule.Scope, rule.Key, rule.MaxCount, rule.Period, rule.MinInterval)
+	return nil
+}
+
+// DeleteRateLimit 删除限流规则
+func (s *QueueService) DeleteRateLimit(id uint) error {
+	var rule database.TaskRateLimit
+	if err := s.db.First(&rule, id).Error; err != nil {
+		return fmt.Errorf("限流规则不存在")
+	}
+
+	if err := s.db.Delete(&rule).Error; err != nil {
+		return err
+	}
+
+	s.limiter.RemoveRule(rule.Scope, rule.Key)
+	return nil
+}
+
+// ListRateLimits 列出限流规则
+func (s *QueueService) ListRateLimits() ([]database.TaskRateLimit, error) {
+	var rules []database.TaskRateLimit
+	err := s.db.Order("scope ASC, key ASC").Find(&rules).Error
+	return rules, err
+}
+
+// deferTask 因限流推迟任务，任务保持待处理状态直到计划时间
+func (s *QueueService) deferTask(task *database.AsyncTask, wait time.Duration) {
+	scheduledAt := time.Now().Add(wait)
+	err := s.db.Model(&database.AsyncTask{}).
+		Where("task_id = ?", task.TaskID).
+		Updates(map[string]interface{}{
+			"status":        database.TaskStatusPending,
+			"scheduled_at":  scheduledAt,
+			"started_at":    nil,
+			"progress_text": fmt.Sprintf("触发限流，推迟至 %s 执行", scheduledAt.Format("2006-01-02 15:04:05")),
+			"updated_at":    time.Now(),
+		}).Error
+	if err != nil {
+		logrus.Errorf("推迟任务失败: %v", err)
+		return
+	}
+
+	task.Status = database.TaskStatusPending
+	task.ScheduledAt = &scheduledAt
+	task.StartedAt = nil
+	logrus.Infof("任务触发限流，已推迟: %s, 等待: %s", task.TaskID, wait.Round(time.Second))
+}
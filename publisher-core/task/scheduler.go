@@ -3,7 +3,9 @@ package task
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -14,24 +16,59 @@ import (
 	"gorm.io/gorm"
 )
 
+// 错过执行的处理策略
+const (
+	// MisfirePolicySkip 跳过错过的执行，等待下一次计划时间
+	MisfirePolicySkip = "skip"
+	// MisfirePolicyRunOnce 启动后补执行一次
+	MisfirePolicyRunOnce = "run_once"
+	// MisfirePolicyRunAll 启动后按顺序补执行所有错过的计划
+	MisfirePolicyRunAll = "run_all"
+)
+
+const (
+	// schedulerLeaseName 调度器租约名称
+	schedulerLeaseName = "scheduler"
+	// defaultLeaseTTL 默认租约有效期
+	defaultLeaseTTL = 30 * time.Second
+	// maxCatchUpRuns 单个任务最多补执行的次数
+	maxCatchUpRuns = 100
+)
+
+// cronParser 支持可选秒字段和 CRON_TZ 前缀的 Cron 解析器
+var cronParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
 // SchedulerService 定时任务调度服务
+// 多个实例共享数据库时通过租约选出一个实例触发定时任务，
+// 每次计划执行以计划时间作为幂等键提交，避免重复入队
 type SchedulerService struct {
-	db          *gorm.DB
+	db           *gorm.DB
 	queueService *QueueService
-	cron        *cron.Cron
-	jobs        map[string]cron.EntryID
-	ctx         context.Context
-	mu          sync.RWMutex
+	cron         *cron.Cron
+	jobs         map[string]cron.EntryID
+	ctx          context.Context
+	mu           sync.RWMutex
+
+	instanceID   string
+	leaseTTL     time.Duration
+	leader       bool
+	leaderMu     sync.RWMutex
+	runningSince time.Time
+	stopOnce     sync.Once
 }
 
 // NewSchedulerService 创建调度服务
 func NewSchedulerService(db *gorm.DB, queueService *QueueService) *SchedulerService {
 	return &SchedulerService{
-		db:          db,
+		db:           db,
 		queueService: queueService,
-		cron:        cron.New(cron.WithSeconds()),
-		jobs:        make(map[string]cron.EntryID),
-		ctx:         context.Background(),
+		cron:         cron.New(cron.WithParser(cronParser)),
+		jobs:         make(map[string]cron.EntryID),
+		ctx:          context.Background(),
+		instanceID:   newInstanceID(),
+		leaseTTL:     defaultLeaseTTL,
 	}
 }
 
@@ -39,7 +76,8 @@ func NewSchedulerService(db *gorm.DB, queueService *QueueService) *SchedulerServ
 func (s *SchedulerService) Start(ctx context.Context) error {
 	// 保存context用于后续操作
 	s.ctx = ctx
-	
+	s.runningSince = time.Now()
+
 	// 加载所有激活的定时任务
 	var scheduledTasks []database.ScheduledTask
 	if err := s.db.Where("is_active = ?", true).Find(&scheduledTasks).Error; err != nil {
@@ -55,6 +93,10 @@ func (s *SchedulerService) Start(ctx context.Context) error {
 	s.cron.Start()
 	logrus.Infof("定时任务调度服务已启动，已加载 %d 个任务", len(scheduledTasks))
 
+	// 竞选调度租约，成为主实例后处理错过的执行
+	s.refreshLeadership()
+	go s.leaseLoop(ctx)
+
 	// 监听上下文取消
 	go func() {
 		<-ctx.Done()
@@ -65,10 +107,115 @@ func (s *SchedulerService) Start(ctx context.Context) error {
 }
 
 // Stop 停止调度服务
+// 可重复调用，上下文取消和显式停止只会生效一次
 func (s *SchedulerService) Stop() {
-	ctx := s.cron.Stop()
-	<-ctx.Done()
-	logrus.Info("定时任务调度服务已停止")
+	s.stopOnce.Do(func() {
+		ctx := s.cron.Stop()
+		<-ctx.Done()
+		s.releaseLease()
+		logrus.Info("定时任务调度服务已停止")
+	})
+}
+
+// leaseLoop 定期续约或竞选租约
+func (s *SchedulerService) leaseLoop(ctx context.Context) {
+	ticker := time.NewTicker(s.leaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.refreshLeadership()
+		}
+	}
+}
+
+// refreshLeadership 续约或竞选租约，新成为主实例时补执行错过的计划
+func (s *SchedulerService) refreshLeadership() {
+	acquired, err := s.acquireLease()
+	if err != nil {
+		logrus.Warnf("调度租约更新失败: %v", err)
+	}
+
+	s.leaderMu.Lock()
+	wasLeader := s.leader
+	s.leader = acquired
+	s.leaderMu.Unlock()
+
+	switch {
+	case acquired && !wasLeader:
+		logrus.Infof("调度器成为主实例: %s", s.instanceID)
+		s.catchUpMissedRuns(time.Now())
+	case !acquired && wasLeader:
+		logrus.Warnf("调度器失去主实例身份: %s", s.instanceID)
+	}
+}
+
+// acquireLease 获取或续约调度租约
+func (s *SchedulerService) acquireLease() (bool, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.leaseTTL)
+
+	result := s.db.Model(&database.SchedulerLease{}).
+		Where("name = ? AND (holder_id = ? OR expires_at < ?)", schedulerLeaseName, s.instanceID, now).
+		Updates(map[string]interface{}{
+			"holder_id":  s.instanceID,
+			"expires_at": expiresAt,
+			"updated_at": now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return true, nil
+	}
+
+	var count int64
+	if err := s.db.Model(&database.SchedulerLease{}).Where("name = ?", schedulerLeaseName).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		// 租约由其他实例持有
+		return false, nil
+	}
+
+	lease := &database.SchedulerLease{
+		Name:      schedulerLeaseName,
+		HolderID:  s.instanceID,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.db.Create(lease).Error; err != nil {
+		// 其他实例同时创建了租约
+		return false, nil
+	}
+	return true, nil
+}
+
+// releaseLease 释放调度租约，便于其他实例尽快接管
+func (s *SchedulerService) releaseLease() {
+	s.leaderMu.Lock()
+	wasLeader := s.leader
+	s.leader = false
+	s.leaderMu.Unlock()
+
+	if !wasLeader {
+		return
+	}
+
+	if err := s.db.Model(&database.SchedulerLease{}).
+		Where("name = ? AND holder_id = ?", schedulerLeaseName, s.instanceID).
+		Update("expires_at", time.Now()).Error; err != nil {
+		logrus.Warnf("释放调度租约失败: %v", err)
+	}
+}
+
+// IsLeader 当前实例是否负责触发定时任务
+func (s *SchedulerService) IsLeader() bool {
+	s.leaderMu.RLock()
+	defer s.leaderMu.RUnlock()
+	return s.leader
 }
 
 // scheduleTask 调度任务
@@ -81,72 +228,215 @@ func (s *SchedulerService) scheduleTask(task *database.ScheduledTask) error {
 		s.cron.Remove(entryID)
 	}
 
-	// 添加新任务
-	entryID, err := s.cron.AddFunc(task.CronExpr, func() {
-		s.executeScheduledTask(task)
-	})
-
+	schedule, err := parseSchedule(task.CronExpr, task.TimeZone)
 	if err != nil {
 		return fmt.Errorf("添加定时任务失败: %w", err)
 	}
 
+	taskID := task.ID
+	jitter := task.JitterSeconds
+
+	// 添加新任务
+	entryID := s.cron.Schedule(schedule, cron.FuncJob(func() {
+		if !s.IsLeader() {
+			return
+		}
+
+		// cron 在计划时间的整秒触发，以此作为本次执行的计划时间
+		scheduledAt := time.Now().Truncate(time.Second)
+		if !s.sleepJitter(jitter) {
+			return
+		}
+		s.executeScheduledTask(taskID, scheduledAt, true)
+	}))
+
 	s.jobs[task.Name] = entryID
-	logrus.Infof("定时任务已调度: %s, Cron: %s", task.Name, task.CronExpr)
+	logrus.Infof("定时任务已调度: %s, Cron: %s, 时区: %s", task.Name, task.CronExpr, scheduleLocation(task.TimeZone))
 
 	return nil
 }
 
+// sleepJitter 随机延迟执行，服务停止时返回 false
+func (s *SchedulerService) sleepJitter(jitterSeconds int) bool {
+	if jitterSeconds <= 0 {
+		return true
+	}
+
+	delay := time.Duration(rand.Int63n(int64(jitterSeconds) * int64(time.Second)))
+	select {
+	case <-time.After(delay):
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
 // executeScheduledTask 执行定时任务
-func (s *SchedulerService) executeScheduledTask(task *database.ScheduledTask) {
-	logrus.Infof("执行定时任务: %s", task.Name)
+// scheduledAt 为本次执行对应的计划时间，同一计划时间只会提交一次；
+// checkOverlap 为 true 且上次提交的任务尚未结束时跳过本次执行
+func (s *SchedulerService) executeScheduledTask(id uint, scheduledAt time.Time, checkOverlap bool) {
+	var task database.ScheduledTask
+	if err := s.db.First(&task, id).Error; err != nil {
+		logrus.Warnf("定时任务不存在: %d", id)
+		return
+	}
 
+	// 以计划时间认领本次执行，避免多实例或补执行重复提交
+	result := s.db.Model(&database.ScheduledTask{}).
+		Where("id = ? AND (last_run_at IS NULL OR last_run_at < ?)", task.ID, scheduledAt).
+		Update("last_run_at", scheduledAt)
+	if result.Error != nil {
+		logrus.Errorf("更新定时任务执行时间失败: %v", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		logrus.Debugf("定时任务 %s 在 %s 的执行已被处理", task.Name, scheduledAt.Format(time.RFC3339))
+		return
+	}
+
+	nextRun, _ := s.getNextRunTime(&task)
+
+	if checkOverlap && !task.AllowOverlap && task.LastTaskID != "" {
+		if last, err := s.queueService.GetTask(task.LastTaskID); err == nil && !isTerminalStatus(last.Status) {
+			logrus.Warnf("定时任务 %s 上次执行尚未结束（任务 %s），跳过本次执行", task.Name, task.LastTaskID)
+			s.db.Model(&database.ScheduledTask{}).
+				Where("id = ?", task.ID).
+				Updates(map[string]interface{}{
+					"last_error":  fmt.Sprintf("上次执行尚未结束（任务 %s），跳过 %s 的执行", task.LastTaskID, scheduledAt.Format(time.RFC3339)),
+					"next_run_at": nextRun,
+				})
+			return
+		}
+	}
+
+	logrus.Infof("执行定时任务: %s, 计划时间: %s", task.Name, scheduledAt.Format(time.RFC3339))
+
+	updates := map[string]interface{}{"next_run_at": nextRun}
+	if _, err := s.submitScheduledTask(&task, fmt.Sprintf("scheduled:%d:%d", task.ID, scheduledAt.Unix()), updates); err != nil {
+		logrus.Errorf("提交定时任务失败: %v", err)
+	}
+}
+
+// submitScheduledTask 把定时任务提交到队列并记录执行结果
+// updates 为需要一并写回定时任务的额外字段
+func (s *SchedulerService) submitScheduledTask(task *database.ScheduledTask, idempotencyKey string, updates map[string]interface{}) (*database.AsyncTask, error) {
 	// 解析payload
 	var payload map[string]interface{}
 	if task.Payload != "" {
 		if err := json.Unmarshal([]byte(task.Payload), &payload); err != nil {
-			logrus.Errorf("解析任务payload失败: %v", err)
-			return
+			return nil, fmt.Errorf("解析任务payload失败: %w", err)
 		}
 	}
 
 	// 提交任务到队列
 	taskReq := &TaskRequest{
-		TaskType:  task.TaskType,
-		QueueName: task.QueueName,
-		Priority:  database.PriorityNormal,
-		Payload:   payload,
+		TaskType:       task.TaskType,
+		QueueName:      task.QueueName,
+		Priority:       database.PriorityNormal,
+		Payload:        payload,
+		IdempotencyKey: idempotencyKey,
 	}
 
-	_, err := s.queueService.SubmitTask(s.ctx, taskReq)
+	submitted, _, err := s.queueService.SubmitTask(s.ctx, taskReq)
 	if err != nil {
-		logrus.Errorf("提交定时任务失败: %v", err)
-
 		// 更新错误信息
-		now := time.Now()
-		s.db.Model(&database.ScheduledTask{}).
-			Where("id = ?", task.ID).
-			Updates(map[string]interface{}{
-				"last_error": err.Error(),
-				"last_run_at": now,
-			})
-		return
+		updates["last_error"] = err.Error()
+		s.db.Model(&database.ScheduledTask{}).Where("id = ?", task.ID).Updates(updates)
+		return nil, err
 	}
 
 	// 更新执行记录
-	now := time.Now()
-	nextRun, _ := s.getNextRunTime(task.CronExpr)
-	s.db.Model(&database.ScheduledTask{}).
-		Where("id = ?", task.ID).
-		Updates(map[string]interface{}{
-			"last_run_at": now,
-			"next_run_at": nextRun,
-			"run_count":   gorm.Expr("run_count + 1"),
-		})
+	updates["last_task_id"] = submitted.TaskID
+	updates["last_error"] = ""
+	updates["run_count"] = gorm.Expr("run_count + 1")
+	s.db.Model(&database.ScheduledTask{}).Where("id = ?", task.ID).Updates(updates)
+	return submitted, nil
+}
+
+// catchUpMissedRuns 按错过执行策略处理停机期间错过的计划
+func (s *SchedulerService) catchUpMissedRuns(now time.Time) {
+	var tasks []database.ScheduledTask
+	if err := s.db.Where("is_active = ?", true).Find(&tasks).Error; err != nil {
+		logrus.Errorf("加载定时任务失败: %v", err)
+		return
+	}
+
+	for _, task := range tasks {
+		schedule, err := parseSchedule(task.CronExpr, task.TimeZone)
+		if err != nil {
+			continue
+		}
+
+		since := task.CreatedAt
+		if task.LastRunAt != nil {
+			since = *task.LastRunAt
+		}
+
+		missed := missedRuns(schedule, since, now, maxCatchUpRuns)
+		if len(missed) == 0 {
+			continue
+		}
+
+		switch task.MisfirePolicy {
+		case MisfirePolicyRunOnce:
+			logrus.Infof("定时任务 %s 错过 %d 次执行，补执行一次", task.Name, len(missed))
+			s.executeScheduledTask(task.ID, missed[len(missed)-1], true)
+		case MisfirePolicyRunAll:
+			logrus.Infof("定时任务 %s 错过 %d 次执行，全部补执行", task.Name, len(missed))
+			for i, scheduledAt := range missed {
+				// 补执行的计划依次入队，仅第一次检查上次执行是否结束
+				s.executeScheduledTask(task.ID, scheduledAt, i == 0)
+			}
+		default:
+			logrus.Infof("定时任务 %s 错过 %d 次执行，已跳过", task.Name, len(missed))
+			nextRun := schedule.Next(now)
+			s.db.Model(&database.ScheduledTask{}).
+				Where("id = ?", task.ID).
+				Update("next_run_at", nextRun)
+		}
+	}
+}
+
+// missedRuns 计算 (since, now] 之间的计划执行时间，最多返回 limit 个最近的计划
+func missedRuns(schedule cron.Schedule, since, now time.Time, limit int) []time.Time {
+	var runs []time.Time
+	for next := schedule.Next(since); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+		runs = append(runs, next)
+		if limit > 0 && len(runs) > limit {
+			runs = runs[1:]
+		}
+	}
+	return runs
+}
+
+// parseSchedule 按时区解析 Cron 表达式
+func parseSchedule(cronExpr, timeZone string) (cron.Schedule, error) {
+	spec := cronExpr
+	if timeZone != "" {
+		if _, err := time.LoadLocation(timeZone); err != nil {
+			return nil, fmt.Errorf("无效的时区: %s", timeZone)
+		}
+		spec = "CRON_TZ=" + timeZone + " " + cronExpr
+	}
+
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("无效的Cron表达式: %w", err)
+	}
+	return schedule, nil
+}
+
+// scheduleLocation 返回用于展示的时区名称
+func scheduleLocation(timeZone string) string {
+	if timeZone == "" {
+		return time.Local.String()
+	}
+	return timeZone
 }
 
 // getNextRunTime 获取下次执行时间
-func (s *SchedulerService) getNextRunTime(cronExpr string) (*time.Time, error) {
-	schedule, err := cron.ParseStandard(cronExpr)
+func (s *SchedulerService) getNextRunTime(task *database.ScheduledTask) (*time.Time, error) {
+	schedule, err := parseSchedule(task.CronExpr, task.TimeZone)
 	if err != nil {
 		return nil, err
 	}
@@ -155,27 +445,50 @@ func (s *SchedulerService) getNextRunTime(cronExpr string) (*time.Time, error) {
 	return &next, nil
 }
 
+// validateScheduledTaskRequest 校验定时任务请求
+func validateScheduledTaskRequest(req *ScheduledTaskRequest) error {
+	if _, err := parseSchedule(req.CronExpr, req.TimeZone); err != nil {
+		return err
+	}
+
+	switch req.MisfirePolicy {
+	case "":
+		req.MisfirePolicy = MisfirePolicySkip
+	case MisfirePolicySkip, MisfirePolicyRunOnce, MisfirePolicyRunAll:
+	default:
+		return fmt.Errorf("无效的错过执行策略: %s", req.MisfirePolicy)
+	}
+
+	if req.JitterSeconds < 0 {
+		return errors.New("随机延迟不能为负数")
+	}
+	return nil
+}
+
 // CreateScheduledTask 创建定时任务
 func (s *SchedulerService) CreateScheduledTask(req *ScheduledTaskRequest) (*database.ScheduledTask, error) {
-	// 验证Cron表达式
-	if _, err := cron.ParseStandard(req.CronExpr); err != nil {
-		return nil, fmt.Errorf("无效的Cron表达式: %w", err)
+	if err := validateScheduledTaskRequest(req); err != nil {
+		return nil, err
 	}
 
 	// 序列化payload
 	payloadBytes, _ := json.Marshal(req.Payload)
 
 	task := &database.ScheduledTask{
-		Name:      req.Name,
-		TaskType:  req.TaskType,
-		CronExpr:  req.CronExpr,
-		Payload:   string(payloadBytes),
-		QueueName: req.QueueName,
-		IsActive:  true,
+		Name:          req.Name,
+		TaskType:      req.TaskType,
+		CronExpr:      req.CronExpr,
+		Payload:       string(payloadBytes),
+		QueueName:     req.QueueName,
+		TimeZone:      req.TimeZone,
+		MisfirePolicy: req.MisfirePolicy,
+		JitterSeconds: req.JitterSeconds,
+		AllowOverlap:  req.AllowOverlap,
+		IsActive:      true,
 	}
 
 	// 计算下次执行时间
-	nextRun, _ := s.getNextRunTime(req.CronExpr)
+	nextRun, _ := s.getNextRunTime(task)
 	task.NextRunAt = nextRun
 
 	if err := s.db.Create(task).Error; err != nil {
@@ -198,6 +511,14 @@ type ScheduledTaskRequest struct {
 	CronExpr  string                 `json:"cron_expr"`
 	Payload   map[string]interface{} `json:"payload"`
 	QueueName string                 `json:"queue_name"`
+	// TimeZone Cron表达式所用时区，如 Asia/Shanghai，为空使用服务器时区
+	TimeZone string `json:"time_zone"`
+	// MisfirePolicy 错过执行的处理策略: skip（默认）, run_once, run_all
+	MisfirePolicy string `json:"misfire_policy"`
+	// JitterSeconds 触发后随机延迟的上限（秒）
+	JitterSeconds int `json:"jitter_seconds"`
+	// AllowOverlap 是否允许上次执行未结束时再次执行
+	AllowOverlap bool `json:"allow_overlap"`
 }
 
 // UpdateScheduledTask 更新定时任务
@@ -207,9 +528,8 @@ func (s *SchedulerService) UpdateScheduledTask(name string, req *ScheduledTaskRe
 		return err
 	}
 
-	// 验证Cron表达式
-	if _, err := cron.ParseStandard(req.CronExpr); err != nil {
-		return fmt.Errorf("无效的Cron表达式: %w", err)
+	if err := validateScheduledTaskRequest(req); err != nil {
+		return err
 	}
 
 	// 更新任务
@@ -218,8 +538,12 @@ func (s *SchedulerService) UpdateScheduledTask(name string, req *ScheduledTaskRe
 	task.CronExpr = req.CronExpr
 	task.Payload = string(payloadBytes)
 	task.QueueName = req.QueueName
+	task.TimeZone = req.TimeZone
+	task.MisfirePolicy = req.MisfirePolicy
+	task.JitterSeconds = req.JitterSeconds
+	task.AllowOverlap = req.AllowOverlap
 
-	nextRun, _ := s.getNextRunTime(req.CronExpr)
+	nextRun, _ := s.getNextRunTime(&task)
 	task.NextRunAt = nextRun
 
 	if err := s.db.Save(&task).Error; err != nil {
@@ -267,14 +591,17 @@ func (s *SchedulerService) PauseScheduledTask(name string) error {
 }
 
 // ResumeScheduledTask 恢复定时任务
+// 暂停期间错过的计划不补执行
 func (s *SchedulerService) ResumeScheduledTask(name string) error {
 	var task database.ScheduledTask
 	if err := s.db.Where("name = ?", name).First(&task).Error; err != nil {
 		return err
 	}
 
+	now := time.Now()
 	task.IsActive = true
-	nextRun, _ := s.getNextRunTime(task.CronExpr)
+	task.LastRunAt = &now
+	nextRun, _ := s.getNextRunTime(&task)
 	task.NextRunAt = nextRun
 
 	if err := s.db.Save(&task).Error; err != nil {
@@ -310,10 +637,12 @@ func (s *SchedulerService) GetSchedulerStats() *SchedulerStats {
 	s.db.Model(&database.ScheduledTask{}).Where("is_active = ?", false).Count(&inactiveCount)
 
 	return &SchedulerStats{
-		TotalJobs:     len(s.jobs),
-		ActiveJobs:    int(activeCount),
-		InactiveJobs:  int(inactiveCount),
-		RunningSince:  time.Now(), // 实际应该记录启动时间
+		TotalJobs:    len(s.jobs),
+		ActiveJobs:   int(activeCount),
+		InactiveJobs: int(inactiveCount),
+		RunningSince: s.runningSince,
+		InstanceID:   s.instanceID,
+		IsLeader:     s.IsLeader(),
 	}
 }
 
@@ -323,6 +652,8 @@ type SchedulerStats struct {
 	ActiveJobs   int       `json:"active_jobs"`
 	InactiveJobs int       `json:"inactive_jobs"`
 	RunningSince time.Time `json:"running_since"`
+	InstanceID   string    `json:"instance_id"`
+	IsLeader     bool      `json:"is_leader"`
 }
 
 // RunScheduledTaskNow 立即执行定时任务
@@ -332,6 +663,15 @@ func (s *SchedulerService) RunScheduledTaskNow(name string) error {
 		return err
 	}
 
-	go s.executeScheduledTask(&task)
-	return nil
+	// 手动执行不占用任何计划时间，不更新 last_run_at，
+	// 以免影响同一时刻的计划执行和停机补执行的判断
+	if !task.AllowOverlap && task.LastTaskID != "" {
+		if last, err := s.queueService.GetTask(task.LastTaskID); err == nil && !isTerminalStatus(last.Status) {
+			return fmt.Errorf("上次执行尚未结束（任务 %s）", task.LastTaskID)
+		}
+	}
+
+	logrus.Infof("手动执行定时任务: %s", task.Name)
+	_, err := s.submitScheduledTask(&task, fmt.Sprintf("manual:%d:%d", task.ID, time.Now().UnixNano()), map[string]interface{}{})
+	return err
 }
@@ -0,0 +1,152 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"publisher-core/database"
+)
+
+func TestMissedRuns(t *testing.T) {
+	schedule, err := parseSchedule("0 9 * * *", "Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("parseSchedule failed: %v", err)
+	}
+
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	since := time.Date(2026, 3, 1, 9, 0, 0, 0, loc)
+	now := time.Date(2026, 3, 4, 10, 0, 0, 0, loc)
+
+	runs := missedRuns(schedule, since, now, 0)
+	if len(runs) != 3 {
+		t.Fatalf("Expected 3 missed runs, got %d", len(runs))
+	}
+	if !runs[0].Equal(time.Date(2026, 3, 2, 9, 0, 0, 0, loc)) {
+		t.Errorf("Unexpected first missed run: %v", runs[0])
+	}
+
+	limited := missedRuns(schedule, since, now, 2)
+	if len(limited) != 2 || !limited[1].Equal(runs[2]) {
+		t.Errorf("Expected the 2 most recent runs, got %v", limited)
+	}
+
+	if _, err := parseSchedule("0 9 * * *", "Mars/Olympus"); err == nil {
+		t.Error("Expected error for invalid time zone")
+	}
+}
+
+func TestSchedulerMisfirePolicies(t *testing.T) {
+	svc := newTestQueueService(t)
+	scheduler := NewSchedulerService(svc.db, svc)
+
+	lastRun := time.Now().Add(-72 * time.Hour).Truncate(time.Hour)
+	for _, policy := range []string{MisfirePolicySkip, MisfirePolicyRunOnce, MisfirePolicyRunAll} {
+		task := &database.ScheduledTask{
+			Name:          "digest-" + policy,
+			TaskType:      "noop",
+			CronExpr:      "0 * * * *",
+			QueueName:     "default",
+			MisfirePolicy: policy,
+			IsActive:      true,
+			LastRunAt:     &lastRun,
+		}
+		if err := svc.db.Create(task).Error; err != nil {
+			t.Fatalf("create scheduled task failed: %v", err)
+		}
+	}
+
+	scheduler.catchUpMissedRuns(time.Now())
+
+	expected := map[string]int{
+		MisfirePolicySkip:    0,
+		MisfirePolicyRunOnce: 1,
+		MisfirePolicyRunAll:  72,
+	}
+	for policy, want := range expected {
+		task, err := scheduler.GetScheduledTask("digest-" + policy)
+		if err != nil {
+			t.Fatalf("GetScheduledTask failed: %v", err)
+		}
+		if task.RunCount != want {
+			t.Errorf("Policy %s: expected %d runs, got %d", policy, want, task.RunCount)
+		}
+	}
+
+	// 再次处理不会重复提交
+	scheduler.catchUpMissedRuns(time.Now())
+	var count int64
+	svc.db.Model(&database.AsyncTask{}).Count(&count)
+	if count != 73 {
+		t.Errorf("Expected 73 queued tasks, got %d", count)
+	}
+}
+
+func TestSchedulerOverlapPrevention(t *testing.T) {
+	svc := newTestQueueService(t)
+	scheduler := NewSchedulerService(svc.db, svc)
+
+	task := &database.ScheduledTask{Name: "report", TaskType: "noop", CronExpr: "*/5 * * * *", QueueName: "default", IsActive: true}
+	if err := svc.db.Create(task).Error; err != nil {
+		t.Fatalf("create scheduled task failed: %v", err)
+	}
+
+	first := time.Now().Add(-10 * time.Minute).Truncate(time.Second)
+	scheduler.executeScheduledTask(task.ID, first, true)
+	scheduler.executeScheduledTask(task.ID, first.Add(5*time.Minute), true)
+
+	got, _ := scheduler.GetScheduledTask("report")
+	if got.RunCount != 1 {
+		t.Errorf("Expected overlapping run to be skipped, got %d runs", got.RunCount)
+	}
+	if got.LastError == "" {
+		t.Error("Expected skipped run to be recorded in last_error")
+	}
+}
+
+func TestSchedulerLeaderElection(t *testing.T) {
+	svc := newTestQueueService(t)
+	first := NewSchedulerService(svc.db, svc)
+	second := NewSchedulerService(svc.db, svc)
+
+	first.refreshLeadership()
+	second.refreshLeadership()
+
+	if !first.IsLeader() || second.IsLeader() {
+		t.Fatalf("Expected only the first instance to lead, got %v/%v", first.IsLeader(), second.IsLeader())
+	}
+
+	first.releaseLease()
+	second.refreshLeadership()
+	if !second.IsLeader() {
+		t.Error("Expected second instance to take over after lease release")
+	}
+}
+
+func TestSchedulerRunNowKeepsSchedule(t *testing.T) {
+	svc := newTestQueueService(t)
+	scheduler := NewSchedulerService(svc.db, svc)
+
+	task := &database.ScheduledTask{Name: "digest", TaskType: "noop", CronExpr: "*/5 * * * *", QueueName: "default", IsActive: true, AllowOverlap: true}
+	if err := svc.db.Create(task).Error; err != nil {
+		t.Fatalf("create scheduled task failed: %v", err)
+	}
+
+	if err := scheduler.RunScheduledTaskNow("digest"); err != nil {
+		t.Fatalf("RunScheduledTaskNow failed: %v", err)
+	}
+	if err := scheduler.RunScheduledTaskNow("digest"); err != nil {
+		t.Fatalf("RunScheduledTaskNow failed: %v", err)
+	}
+
+	// 手动执行不占用计划时间，同一秒的计划执行仍会提交
+	slot := time.Now().Truncate(time.Second)
+	scheduler.executeScheduledTask(task.ID, slot, false)
+
+	got, _ := scheduler.GetScheduledTask("digest")
+	if got.RunCount != 3 {
+		t.Errorf("Expected 2 manual runs and 1 scheduled run, got %d runs", got.RunCount)
+	}
+	if got.LastRunAt == nil || !got.LastRunAt.Equal(slot) {
+		t.Errorf("Expected last_run_at to be the scheduled slot %v, got %v", slot, got.LastRunAt)
+	}
+}
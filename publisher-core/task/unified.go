@@ -0,0 +1,215 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"publisher-core/cost"
+	"publisher-core/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultQueueName TaskManager 提交任务使用的默认队列
+const DefaultQueueName = "default"
+
+// NewQueueTaskManager 创建由 QueueService 支撑的任务管理器
+// 任务持久化到 AsyncTask，由队列工作器执行，进度通过 StateService 上报，
+// 与直接提交到队列的任务出现在同一个任务列表中
+func NewQueueTaskManager(queue *QueueService, state *StateService) *TaskManager {
+	m := NewTaskManager(nil)
+	m.queue = queue
+	m.state = state
+	return m
+}
+
+// QueueBacked 是否由队列服务支撑
+func (m *TaskManager) QueueBacked() bool {
+	return m.queue != nil
+}
+
+// Submit 创建任务并开始执行
+func (m *TaskManager) Submit(ctx context.Context, taskType string, platform string, payload map[string]interface{}) (*Task, error) {
+	task, err := m.CreateTask(taskType, platform, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// 队列模式下任务创建后即由工作器执行
+	if m.queue == nil {
+		go func() {
+			if err := m.Execute(ctx, task.ID); err != nil {
+				logrus.Errorf("任务执行失败: %s, 错误: %v", task.ID, err)
+			}
+		}()
+	}
+
+	return task, nil
+}
+
+// queueHandler 将 TaskHandler 适配为队列任务处理函数
+func (m *TaskManager) queueHandler(handler TaskHandler) QueueTaskHandler {
+	return func(ctx context.Context, asyncTask *database.AsyncTask) error {
+		task := taskFromAsync(asyncTask)
+
+		m.mu.Lock()
+		m.tasks[task.ID] = task
+		m.mu.Unlock()
+
+		defer func() {
+			m.mu.Lock()
+			delete(m.tasks, task.ID)
+			m.mu.Unlock()
+		}()
+
+		// 任务以提交用户的身份执行，AI 成本和实验分组归属到该用户
+		if asyncTask.UserID != "" {
+			ctx = cost.WithAttribution(ctx, cost.Attribution{UserID: asyncTask.UserID})
+		}
+		err := handler(ctx, task)
+
+		if task.Result != nil {
+			if resultBytes, marshalErr := json.Marshal(task.Result); marshalErr == nil {
+				asyncTask.Result = string(resultBytes)
+			}
+		}
+
+		return err
+	}
+}
+
+// queueCreateTask 通过队列服务创建任务
+func (m *TaskManager) queueCreateTask(taskType string, platform string, payload map[string]interface{}) (*Task, error) {
+	if payload == nil {
+		payload = make(map[string]interface{})
+	}
+	if _, exists := payload["platform"]; !exists && platform != "" {
+		payload["platform"] = platform
+	}
+
+	asyncTask, _, err := m.queue.SubmitTask(context.Background(), &TaskRequest{
+		TaskType:  taskType,
+		QueueName: DefaultQueueName,
+		Priority:  database.PriorityNormal,
+		Payload:   payload,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("创建任务: %s, 类型: %s, 平台: %s", asyncTask.TaskID, taskType, platform)
+	return taskFromAsync(asyncTask), nil
+}
+
+// queueExecute 等待队列中的任务执行结束
+func (m *TaskManager) queueExecute(ctx context.Context, taskID string) error {
+	asyncTask, err := m.queue.WaitTask(ctx, taskID)
+	if err != nil {
+		return err
+	}
+
+	switch asyncTask.Status {
+	case database.TaskStatusFailed:
+		return fmt.Errorf("%s", asyncTask.Error)
+	case database.TaskStatusCancelled:
+		return fmt.Errorf("任务已取消: %s", taskID)
+	}
+	return nil
+}
+
+// queueGetTask 从队列服务获取任务
+func (m *TaskManager) queueGetTask(taskID string) (*Task, error) {
+	asyncTask, err := m.queue.GetTask(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("任务不存在: %s", taskID)
+	}
+
+	task := taskFromAsync(asyncTask)
+	if m.state != nil {
+		if p, err := m.state.GetProgress(taskID); err == nil && p.Progress > task.Progress {
+			task.Progress = p.Progress
+		}
+	}
+	return task, nil
+}
+
+// queueListTasks 从队列服务列出任务
+func (m *TaskManager) queueListTasks(filter TaskFilter) ([]*Task, error) {
+	stateFilter := &StateTaskFilter{
+		TaskType: filter.Type,
+		Status:   string(filter.Status),
+		Page:     1,
+		PageSize: filter.Limit,
+	}
+	// 平台信息存储在 payload 中，需要在内存中过滤
+	if filter.Platform != "" || stateFilter.PageSize <= 0 {
+		stateFilter.PageSize = 1000
+	}
+
+	asyncTasks, _, err := m.state.ListTasks(stateFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Task
+	for i := range asyncTasks {
+		task := taskFromAsync(&asyncTasks[i])
+		if filter.Platform != "" && task.Platform != filter.Platform {
+			continue
+		}
+		result = append(result, task)
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// taskFromAsync 将 AsyncTask 转换为 Task
+func taskFromAsync(asyncTask *database.AsyncTask) *Task {
+	task := &Task{
+		ID:         asyncTask.TaskID,
+		Type:       asyncTask.TaskType,
+		Status:     taskStatusFromAsync(asyncTask.Status),
+		Error:      asyncTask.Error,
+		Progress:   asyncTask.Progress,
+		CreatedAt:  asyncTask.CreatedAt,
+		StartedAt:  asyncTask.StartedAt,
+		FinishedAt: asyncTask.CompletedAt,
+	}
+
+	if asyncTask.Payload != "" {
+		if err := json.Unmarshal([]byte(asyncTask.Payload), &task.Payload); err != nil {
+			logrus.Warnf("解析任务payload失败: %s, %v", asyncTask.TaskID, err)
+		}
+	}
+	if asyncTask.Result != "" {
+		if err := json.Unmarshal([]byte(asyncTask.Result), &task.Result); err != nil {
+			logrus.Debugf("任务结果不是JSON对象: %s", asyncTask.TaskID)
+		}
+	}
+	if platform, ok := task.Payload["platform"].(string); ok {
+		task.Platform = platform
+	}
+
+	return task
+}
+
+// taskStatusFromAsync 将队列任务状态映射为 TaskStatus
+func taskStatusFromAsync(status database.TaskStatus) TaskStatus {
+	switch status {
+	case database.TaskStatusRunning, database.TaskStatusCancelling:
+		return TaskStatusRunning
+	case database.TaskStatusCompleted:
+		return TaskStatusCompleted
+	case database.TaskStatusFailed:
+		return TaskStatusFailed
+	case database.TaskStatusCancelled:
+		return TaskStatusCancelled
+	default:
+		// waiting、retrying 对调用方而言仍是待处理
+		return TaskStatusPending
+	}
+}
@@ -0,0 +1,348 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"publisher-core/database"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SubmitWorkflow 提交父子任务工作流
+// 父任务本身不会被执行，其状态与进度由子任务汇总得出。传入的请求不会被修改
+func (s *QueueService) SubmitWorkflow(ctx context.Context, parent *TaskRequest, children []*TaskRequest) (*database.AsyncTask, []*database.AsyncTask, error) {
+	if len(children) == 0 {
+		return nil, nil, fmt.Errorf("%w: 工作流至少需要一个子任务", ErrInvalidTaskRequest)
+	}
+	if len(parent.DependsOn) > 0 || parent.ParentTaskID != "" {
+		return nil, nil, fmt.Errorf("%w: 工作流父任务不能设置依赖或父任务，请在子任务上设置", ErrInvalidTaskRequest)
+	}
+
+	// 父任务幂等键命中时直接返回已有工作流
+	existing, err := s.checkDuplicate(parent)
+	if err != nil {
+		return nil, nil, err
+	}
+	if existing != nil {
+		children, err := s.GetChildTasks(existing.TaskID)
+		if err != nil {
+			return nil, nil, err
+		}
+		childTasks := make([]*database.AsyncTask, 0, len(children))
+		for i := range children {
+			childTasks = append(childTasks, &children[i])
+		}
+		return existing, childTasks, nil
+	}
+
+	for _, child := range children {
+		dup, err := s.checkDuplicate(child)
+		if err != nil {
+			return nil, nil, err
+		}
+		if dup != nil {
+			return nil, nil, fmt.Errorf("%w: 子任务幂等键已被任务 %s 使用", ErrIdempotencyConflict, dup.TaskID)
+		}
+	}
+
+	parentReq := *parent
+	parentReq.aggregate = true
+	parentTask, err := s.createTask(&parentReq, database.TaskStatusWaiting)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 先创建全部子任务再统一入队，避免部分子任务提前完成导致父任务过早结束
+	var childTasks []*database.AsyncTask
+	for _, child := range children {
+		childReq := *child
+		childReq.ParentTaskID = parentTask.TaskID
+		childReq.aggregate = false
+		childTask, err := s.createTask(&childReq, database.TaskStatusPending)
+		if err != nil {
+			if cancelErr := s.CancelTask(parentTask.TaskID); cancelErr != nil {
+				logrus.Warnf("回滚工作流失败: %s, 错误: %v", parentTask.TaskID, cancelErr)
+			}
+			return nil, nil, fmt.Errorf("提交子任务失败: %w", err)
+		}
+		childTasks = append(childTasks, childTask)
+	}
+
+	for _, childTask := range childTasks {
+		if childTask.Status == database.TaskStatusWaiting {
+			s.releaseIfReady(childTask.TaskID)
+		} else {
+			s.enqueue(childTask)
+		}
+	}
+
+	logrus.Infof("工作流已提交: %s, 子任务数: %d", parentTask.TaskID, len(childTasks))
+	return parentTask, childTasks, nil
+}
+
+// GetChildTasks 获取子任务
+func (s *QueueService) GetChildTasks(parentTaskID string) ([]database.AsyncTask, error) {
+	var children []database.AsyncTask
+	err := s.db.Where("parent_task_id = ?", parentTaskID).
+		Order("created_at ASC").
+		Find(&children).Error
+	return children, err
+}
+
+// GetDependencies 获取任务依赖的任务ID
+func (s *QueueService) GetDependencies(taskID string) ([]string, error) {
+	var deps []database.TaskDependency
+	if err := s.db.Where("task_id = ?", taskID).Find(&deps).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(deps))
+	for _, dep := range deps {
+		ids = append(ids, dep.DependsOnTaskID)
+	}
+	return ids, nil
+}
+
+// validateDependencies 检查依赖任务是否存在且仍可能成功
+func (s *QueueService) validateDependencies(depIDs []string) error {
+	var deps []database.AsyncTask
+	if err := s.db.Where("task_id IN ?", depIDs).Find(&deps).Error; err != nil {
+		return fmt.Errorf("查询依赖任务失败: %w", err)
+	}
+
+	found := make(map[string]database.TaskStatus, len(deps))
+	for _, dep := range deps {
+		found[dep.TaskID] = dep.Status
+	}
+
+	for _, id := range depIDs {
+		status, exists := found[id]
+		if !exists {
+			return fmt.Errorf("%w: 依赖任务不存在: %s", ErrInvalidTaskRequest, id)
+		}
+		if status == database.TaskStatusFailed || status == database.TaskStatusCancelled {
+			return fmt.Errorf("%w: 依赖任务 %s 未成功完成（状态: %s）", ErrInvalidTaskRequest, id, status)
+		}
+	}
+
+	return nil
+}
+
+// uniqueTaskIDs 按出现顺序去除重复的任务ID
+func uniqueTaskIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
+
+// onTaskFinished 任务进入终态后处理依赖它的任务和父任务
+func (s *QueueService) onTaskFinished(task *database.AsyncTask) {
+	s.resolveDependents(task)
+
+	if task.ParentTaskID != "" {
+		s.refreshParentStatus(task.ParentTaskID)
+	}
+}
+
+// resolveDependents 唤醒或失败等待该任务的下游任务
+func (s *QueueService) resolveDependents(task *database.AsyncTask) {
+	var deps []database.TaskDependency
+	if err := s.db.Where("depends_on_task_id = ?", task.TaskID).Find(&deps).Error; err != nil {
+		logrus.Errorf("查询下游任务失败: %v", err)
+		return
+	}
+
+	for _, dep := range deps {
+		if task.Status == database.TaskStatusCompleted {
+			s.releaseIfReady(dep.TaskID)
+		} else {
+			s.failDependent(dep.TaskID, fmt.Sprintf("依赖任务 %s 未成功完成（状态: %s）", task.TaskID, task.Status))
+		}
+	}
+}
+
+// releaseIfReady 所有依赖成功完成后将任务置为待处理并入队
+func (s *QueueService) releaseIfReady(taskID string) {
+	depIDs, err := s.GetDependencies(taskID)
+	if err != nil {
+		logrus.Errorf("查询任务依赖失败: %v", err)
+		return
+	}
+
+	var deps []database.AsyncTask
+	if err := s.db.Where("task_id IN ?", depIDs).Find(&deps).Error; err != nil {
+		logrus.Errorf("查询依赖任务失败: %v", err)
+		return
+	}
+
+	// 按去重后的依赖数判断，重复的依赖行只对应一个任务
+	completed := 0
+	for _, dep := range deps {
+		switch dep.Status {
+		case database.TaskStatusCompleted:
+			completed++
+		case database.TaskStatusFailed, database.TaskStatusCancelled:
+			s.failDependent(taskID, fmt.Sprintf("依赖任务 %s 未成功完成（状态: %s）", dep.TaskID, dep.Status))
+			return
+		}
+	}
+
+	if completed < len(uniqueTaskIDs(depIDs)) {
+		return
+	}
+
+	result := s.db.Model(&database.AsyncTask{}).
+		Where("task_id = ? AND status = ?", taskID, database.TaskStatusWaiting).
+		Updates(map[string]interface{}{
+			"status":     database.TaskStatusPending,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		logrus.Errorf("更新任务状态失败: %v", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	task, err := s.GetTask(taskID)
+	if err != nil {
+		logrus.Errorf("加载任务失败: %v", err)
+		return
+	}
+
+	logrus.Infof("任务依赖已全部完成: %s", taskID)
+	s.enqueue(task)
+}
+
+// failDependent 依赖失败时将等待中的任务标记为失败
+func (s *QueueService) failDependent(taskID string, reason string) {
+	now := time.Now()
+	result := s.db.Model(&database.AsyncTask{}).
+		Where("task_id = ? AND status = ?", taskID, database.TaskStatusWaiting).
+		Updates(map[string]interface{}{
+			"status":       database.TaskStatusFailed,
+			"error":        reason,
+			"completed_at": now,
+			"updated_at":   now,
+		})
+	if result.Error != nil {
+		logrus.Errorf("更新任务状态失败: %v", result.Error)
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	logrus.Warnf("任务因依赖失败而终止: %s, 原因: %s", taskID, reason)
+
+	task, err := s.GetTask(taskID)
+	if err != nil {
+		return
+	}
+	s.onTaskFinished(task)
+}
+
+// refreshParentStatus 根据子任务汇总父任务的状态和进度
+func (s *QueueService) refreshParentStatus(parentTaskID string) {
+	parent, err := s.GetTask(parentTaskID)
+	if err != nil {
+		logrus.Warnf("父任务不存在: %s", parentTaskID)
+		return
+	}
+	if !parent.Aggregate {
+		return
+	}
+
+	// 已取消的父任务不再随子任务变化
+	if parent.Status == database.TaskStatusCancelled {
+		return
+	}
+
+	children, err := s.GetChildTasks(parentTaskID)
+	if err != nil || len(children) == 0 {
+		return
+	}
+
+	status, completed := aggregateChildStatus(children)
+	progress := completed * 100 / len(children)
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":        status,
+		"progress":      progress,
+		"progress_text": fmt.Sprintf("子任务 %d/%d 已完成", completed, len(children)),
+		"updated_at":    now,
+	}
+	if status == database.TaskStatusRunning && parent.StartedAt == nil {
+		updates["started_at"] = now
+	}
+	if isTerminalStatus(status) {
+		updates["completed_at"] = now
+	}
+
+	result := s.db.Model(&database.AsyncTask{}).
+		Where("task_id = ? AND status = ?", parentTaskID, parent.Status).
+		Updates(updates)
+	if result.Error != nil {
+		logrus.Errorf("更新父任务状态失败: %v", result.Error)
+		return
+	}
+
+	if result.RowsAffected > 0 && status != parent.Status && isTerminalStatus(status) {
+		parent.Status = status
+		parent.CompletedAt = &now
+		logrus.Infof("父任务已结束: %s, 状态: %s", parentTaskID, status)
+		s.onTaskFinished(parent)
+	}
+}
+
+// aggregateChildStatus 汇总子任务状态，返回父任务状态和已完成子任务数
+func aggregateChildStatus(children []database.AsyncTask) (database.TaskStatus, int) {
+	var completed, failed, cancelled, started int
+	for _, child := range children {
+		switch child.Status {
+		case database.TaskStatusCompleted:
+			completed++
+		case database.TaskStatusFailed:
+			failed++
+		case database.TaskStatusCancelled:
+			cancelled++
+		case database.TaskStatusRunning, database.TaskStatusRetrying, database.TaskStatusCancelling:
+			started++
+		}
+	}
+
+	finished := completed + failed + cancelled
+	switch {
+	case finished < len(children):
+		if started > 0 || finished > 0 {
+			return database.TaskStatusRunning, completed
+		}
+		return database.TaskStatusWaiting, completed
+	case failed > 0:
+		return database.TaskStatusFailed, completed
+	case cancelled > 0:
+		return database.TaskStatusCancelled, completed
+	default:
+		return database.TaskStatusCompleted, completed
+	}
+}
+
+// isTerminalStatus 判断任务是否处于终态
+func isTerminalStatus(status database.TaskStatus) bool {
+	switch status {
+	case database.TaskStatusCompleted, database.TaskStatusFailed, database.TaskStatusCancelled:
+		return true
+	}
+	return false
+}
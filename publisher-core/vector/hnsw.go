@@ -0,0 +1,282 @@
+package vector
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// HNSW 参数
+const (
+	// hnswM 每个节点在上层保留的邻居数，第 0 层保留 2M
+	hnswM = 16
+	// hnswEfConstruction 建图时的候选队列长度
+	hnswEfConstruction = 200
+	// hnswEfSearch 查询时的最小候选队列长度
+	hnswEfSearch = 64
+)
+
+// hnswNode 图中的节点，已删除的节点保留在图中参与路由，但不出现在结果中
+type hnswNode struct {
+	id      string
+	vector  []float32
+	friends [][]int32
+	deleted bool
+}
+
+// hnsw 分层可导航小世界图，向量需预先归一化，距离为 1 - 余弦相似度
+type hnsw struct {
+	nodes     []*hnswNode
+	entry     int32
+	maxLevel  int
+	levelMult float64
+	rng       *rand.Rand
+}
+
+func newHNSW() *hnsw {
+	return &hnsw{
+		entry:     -1,
+		levelMult: 1 / math.Log(hnswM),
+		rng:       rand.New(rand.NewSource(42)),
+	}
+}
+
+// distance 两个归一化向量的余弦距离
+func distance(a, b []float32) float32 {
+	var dot float32
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return 1 - dot
+}
+
+// scored 候选节点及其距离
+type scored struct {
+	node int32
+	dist float32
+}
+
+// minHeap 距离最小的在堆顶
+type minHeap []scored
+
+func (h minHeap) Len() int            { return len(h) }
+func (h minHeap) Less(i, j int) bool  { return h[i].dist < h[j].dist }
+func (h minHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *minHeap) Push(x interface{}) { *h = append(*h, x.(scored)) }
+func (h *minHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// maxHeap 距离最大的在堆顶
+type maxHeap []scored
+
+func (h maxHeap) Len() int            { return len(h) }
+func (h maxHeap) Less(i, j int) bool  { return h[i].dist > h[j].dist }
+func (h maxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *maxHeap) Push(x interface{}) { *h = append(*h, x.(scored)) }
+func (h *maxHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// randomLevel 按指数分布随机生成节点层数
+func (h *hnsw) randomLevel() int {
+	return int(math.Floor(-math.Log(1-h.rng.Float64()) * h.levelMult))
+}
+
+// maxFriends 返回某层的邻居上限
+func maxFriends(level int) int {
+	if level == 0 {
+		return 2 * hnswM
+	}
+	return hnswM
+}
+
+// insert 插入节点，返回节点编号
+func (h *hnsw) insert(id string, vector []float32) int32 {
+	level := h.randomLevel()
+	node := &hnswNode{id: id, vector: vector, friends: make([][]int32, level+1)}
+	idx := int32(len(h.nodes))
+	h.nodes = append(h.nodes, node)
+
+	if h.entry < 0 {
+		h.entry = idx
+		h.maxLevel = level
+		return idx
+	}
+
+	// 从顶层贪心下降到新节点所在的最高层
+	cur := h.entry
+	curDist := distance(vector, h.nodes[cur].vector)
+	for l := h.maxLevel; l > level; l-- {
+		cur, curDist = h.greedy(vector, cur, curDist, l)
+	}
+
+	for l := minInt(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(vector, cur, hnswEfConstruction, l)
+		neighbors := h.selectNeighbors(candidates, maxFriends(l))
+		node.friends[l] = neighbors
+		for _, n := range neighbors {
+			h.link(n, idx, l)
+		}
+		if len(candidates) > 0 {
+			cur = candidates[0].node
+		}
+	}
+
+	if level > h.maxLevel {
+		h.maxLevel = level
+		h.entry = idx
+	}
+	return idx
+}
+
+// link 为节点 from 在第 level 层添加邻居 to，超出上限时只保留最近的邻居
+func (h *hnsw) link(from, to int32, level int) {
+	node := h.nodes[from]
+	if level >= len(node.friends) {
+		return
+	}
+	node.friends[level] = append(node.friends[level], to)
+	if len(node.friends[level]) <= maxFriends(level) {
+		return
+	}
+
+	candidates := make([]scored, len(node.friends[level]))
+	for i, f := range node.friends[level] {
+		candidates[i] = scored{node: f, dist: distance(node.vector, h.nodes[f].vector)}
+	}
+	sortScored(candidates)
+	node.friends[level] = h.selectNeighbors(candidates, maxFriends(level))
+}
+
+// greedy 在单层上贪心移动到离查询最近的节点
+func (h *hnsw) greedy(query []float32, cur int32, curDist float32, level int) (int32, float32) {
+	for changed := true; changed; {
+		changed = false
+		for _, f := range h.nodes[cur].friends[level] {
+			if d := distance(query, h.nodes[f].vector); d < curDist {
+				cur, curDist, changed = f, d, true
+			}
+		}
+	}
+	return cur, curDist
+}
+
+// searchLayer 在单层上做束搜索，返回按距离升序排列的最多 ef 个候选
+func (h *hnsw) searchLayer(query []float32, entry int32, ef, level int) []scored {
+	visited := map[int32]bool{entry: true}
+	d := distance(query, h.nodes[entry].vector)
+	candidates := &minHeap{{node: entry, dist: d}}
+	results := &maxHeap{{node: entry, dist: d}}
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(scored)
+		if c.dist > (*results)[0].dist && results.Len() >= ef {
+			break
+		}
+		friends := h.nodes[c.node].friends
+		if level >= len(friends) {
+			continue
+		}
+		for _, f := range friends[level] {
+			if visited[f] {
+				continue
+			}
+			visited[f] = true
+			fd := distance(query, h.nodes[f].vector)
+			if results.Len() < ef || fd < (*results)[0].dist {
+				heap.Push(candidates, scored{node: f, dist: fd})
+				heap.Push(results, scored{node: f, dist: fd})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	out := make([]scored, results.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(results).(scored)
+	}
+	return out
+}
+
+// selectNeighbors 启发式选择邻居：优先保留彼此不太接近的候选，使图在各方向上都有连接
+// candidates 需按距离升序排列
+func (h *hnsw) selectNeighbors(candidates []scored, m int) []int32 {
+	selected := make([]int32, 0, m)
+	var skipped []int32
+	for _, c := range candidates {
+		if len(selected) >= m {
+			break
+		}
+		good := true
+		for _, s := range selected {
+			if distance(h.nodes[c.node].vector, h.nodes[s].vector) < c.dist {
+				good = false
+				break
+			}
+		}
+		if good {
+			selected = append(selected, c.node)
+		} else {
+			skipped = append(skipped, c.node)
+		}
+	}
+	// 邻居不足时用被跳过的候选补齐
+	for _, s := range skipped {
+		if len(selected) >= m {
+			break
+		}
+		selected = append(selected, s)
+	}
+	return selected
+}
+
+// search 返回离查询最近的 k 个未删除节点
+func (h *hnsw) search(query []float32, k, ef int) []scored {
+	if h.entry < 0 || k <= 0 {
+		return nil
+	}
+	if ef < k {
+		ef = k
+	}
+
+	cur := h.entry
+	curDist := distance(query, h.nodes[cur].vector)
+	for l := h.maxLevel; l > 0; l-- {
+		cur, curDist = h.greedy(query, cur, curDist, l)
+	}
+
+	candidates := h.searchLayer(query, cur, ef, 0)
+	results := make([]scored, 0, k)
+	for _, c := range candidates {
+		if h.nodes[c.node].deleted {
+			continue
+		}
+		results = append(results, c)
+		if len(results) >= k {
+			break
+		}
+	}
+	return results
+}
+
+// sortScored 按距离升序排序
+func sortScored(s []scored) {
+	sort.Slice(s, func(i, j int) bool { return s[i].dist < s[j].dist })
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
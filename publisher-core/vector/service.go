@@ -0,0 +1,261 @@
+package vector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"publisher-core/ai/provider"
+	"publisher-core/cost"
+	"publisher-core/database"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// 内置命名空间
+const (
+	// NamespaceTopics 热点话题标题
+	NamespaceTopics = "topics"
+	// NamespaceAIHistory AI 生成内容
+	NamespaceAIHistory = "ai_history"
+)
+
+const (
+	// defaultBatchSize 每次向量化请求的文本数
+	defaultBatchSize = 64
+	// maxEmbedRunes 单条文本的最大字符数，超出部分截断以免超过向量模型的输入限制
+	maxEmbedRunes = 2000
+)
+
+// Service 向量化数据库内容并提供相似度检索
+type Service struct {
+	store     *Store
+	embedder  provider.Embedder
+	db        *gorm.DB
+	batchSize int
+}
+
+// NewService 创建向量检索服务
+func NewService(store *Store, embedder provider.Embedder, db *gorm.DB) *Service {
+	if db == nil {
+		db = database.GetDB()
+	}
+	return &Service{
+		store:     store,
+		embedder:  embedder,
+		db:        db,
+		batchSize: defaultBatchSize,
+	}
+}
+
+// Store 返回底层向量索引
+func (s *Service) Store() *Store {
+	return s.store
+}
+
+// pendingItem 待向量化的条目
+type pendingItem struct {
+	id       string
+	text     string
+	metadata map[string]string
+}
+
+// embed 批量向量化并写入命名空间
+func (s *Service) embed(ctx context.Context, namespace string, items []pendingItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	model := s.embedder.DefaultEmbeddingModel()
+	ctx = cost.WithFunction(ctx, cost.EmbeddingFunctionType)
+	for start := 0; start < len(items); start += s.batchSize {
+		end := start + s.batchSize
+		if end > len(items) {
+			end = len(items)
+		}
+		batch := items[start:end]
+
+		input := make([]string, len(batch))
+		for i, item := range batch {
+			input[i] = truncateRunes(item.text, maxEmbedRunes)
+		}
+		result, err := s.embedder.Embed(ctx, &provider.EmbedOptions{Model: model, Input: input})
+		if err != nil {
+			return fmt.Errorf("向量化失败: %w", err)
+		}
+
+		records := make([]Record, len(batch))
+		for i, item := range batch {
+			records[i] = Record{ID: item.id, Vector: result.Vectors[i], Metadata: item.metadata}
+		}
+		if err := s.store.Upsert(namespace, records...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareNamespace 确认命名空间使用当前向量模型，模型变更后清空并重建
+func (s *Service) prepareNamespace(namespace string) error {
+	model := s.embedder.DefaultEmbeddingModel()
+	err := s.store.EnsureModel(namespace, model)
+	if !errors.Is(err, ErrModelMismatch) {
+		return err
+	}
+
+	logrus.Warnf("向量模型已变更为 %s，重建命名空间 %s", model, namespace)
+	if err := s.store.Reset(namespace); err != nil {
+		return err
+	}
+	return s.store.EnsureModel(namespace, model)
+}
+
+// IndexTopics 将话题标题写入 topics 命名空间，标题未变化的话题跳过
+func (s *Service) IndexTopics(ctx context.Context) (int, error) {
+	if err := s.prepareNamespace(NamespaceTopics); err != nil {
+		return 0, err
+	}
+
+	var pending []pendingItem
+	var topics []database.Topic
+	err := s.db.Model(&database.Topic{}).
+		Select("id, title, category, source, url").
+		FindInBatches(&topics, 500, func(tx *gorm.DB, batch int) error {
+			for _, topic := range topics {
+				hash := textHash(topic.Title)
+				if r, ok := s.store.Get(NamespaceTopics, topic.ID); ok && r.Metadata["hash"] == hash {
+					continue
+				}
+				pending = append(pending, pendingItem{
+					id:   topic.ID,
+					text: topic.Title,
+					metadata: map[string]string{
+						"title":    topic.Title,
+						"category": topic.Category,
+						"source":   topic.Source,
+						"url":      topic.URL,
+						"hash":     hash,
+					},
+				})
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return 0, fmt.Errorf("查询话题失败: %w", err)
+	}
+
+	if err := s.embed(ctx, NamespaceTopics, pending); err != nil {
+		return 0, err
+	}
+	return len(pending), nil
+}
+
+// IndexAIHistory 将成功的 AI 生成结果写入 ai_history 命名空间，历史记录不会变化，已索引的跳过
+func (s *Service) IndexAIHistory(ctx context.Context) (int, error) {
+	if err := s.prepareNamespace(NamespaceAIHistory); err != nil {
+		return 0, err
+	}
+
+	var pending []pendingItem
+	var histories []database.AIHistory
+	err := s.db.Model(&database.AIHistory{}).
+		Where("success = ? AND response <> ''", true).
+		FindInBatches(&histories, 500, func(tx *gorm.DB, batch int) error {
+			for _, h := range histories {
+				id := strconv.FormatUint(uint64(h.ID), 10)
+				if _, ok := s.store.Get(NamespaceAIHistory, id); ok {
+					continue
+				}
+				pending = append(pending, pendingItem{
+					id:   id,
+					text: h.Response,
+					metadata: map[string]string{
+						"provider":   h.Provider,
+						"model":      h.Model,
+						"prompt":     truncateRunes(h.Prompt, 200),
+						"created_at": h.CreatedAt.Format(time.RFC3339),
+					},
+				})
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return 0, fmt.Errorf("查询AI历史失败: %w", err)
+	}
+
+	if err := s.embed(ctx, NamespaceAIHistory, pending); err != nil {
+		return 0, err
+	}
+	return len(pending), nil
+}
+
+// Sync 增量索引话题和 AI 历史
+func (s *Service) Sync(ctx context.Context) error {
+	topics, err := s.IndexTopics(ctx)
+	if err != nil {
+		return err
+	}
+	histories, err := s.IndexAIHistory(ctx)
+	if err != nil {
+		return err
+	}
+	if topics > 0 || histories > 0 {
+		logrus.Infof("向量索引已更新: 话题 %d 条, AI历史 %d 条", topics, histories)
+	}
+	return nil
+}
+
+// Run 定期增量索引，直到 ctx 取消
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Sync(ctx); err != nil && ctx.Err() == nil {
+			logrus.Warnf("向量索引同步失败: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Search 检索与文本最相似的 k 个条目
+func (s *Service) Search(ctx context.Context, namespace, text string, k int) ([]Match, error) {
+	ctx = cost.WithFunction(ctx, cost.EmbeddingFunctionType)
+	result, err := s.embedder.Embed(ctx, &provider.EmbedOptions{
+		Model: s.embedder.DefaultEmbeddingModel(),
+		Input: []string{truncateRunes(text, maxEmbedRunes)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("向量化失败: %w", err)
+	}
+	return s.store.Search(namespace, result.Vectors[0], k, nil)
+}
+
+// SimilarTopics 检索与标题相似的话题
+func (s *Service) SimilarTopics(ctx context.Context, title string, k int) ([]Match, error) {
+	return s.Search(ctx, NamespaceTopics, title, k)
+}
+
+// textHash 文本摘要，用于判断内容是否变化
+func textHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:8])
+}
+
+// truncateRunes 按字符截断文本
+func truncateRunes(text string, max int) string {
+	if utf8.RuneCountInString(text) <= max {
+		return text
+	}
+	return string([]rune(text)[:max])
+}
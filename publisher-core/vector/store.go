@@ -0,0 +1,510 @@
+// Package vector 提供纯 Go 实现的本地向量索引
+// 每个命名空间对应数据目录下的一个追加写日志文件，启动时回放日志重建索引。
+// 条目较少时精确计算余弦相似度，超过 bruteForceLimit 后使用 HNSW 近似检索
+package vector
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// bruteForceLimit 条目数不超过此值时精确检索
+const bruteForceLimit = 2000
+
+var (
+	// ErrDimensionMismatch 向量维度与命名空间不一致
+	ErrDimensionMismatch = errors.New("向量维度不一致")
+	// ErrModelMismatch 命名空间已使用其他向量模型建立
+	ErrModelMismatch = errors.New("向量模型不一致")
+)
+
+// namespacePattern 命名空间名称同时用作文件名
+var namespacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_\-]{0,63}$`)
+
+// Record 索引条目
+type Record struct {
+	ID       string            `json:"id"`
+	Vector   []float32         `json:"vector"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Match 检索结果，Score 为余弦相似度
+type Match struct {
+	ID       string            `json:"id"`
+	Score    float64           `json:"score"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// NamespaceInfo 命名空间统计
+type NamespaceInfo struct {
+	Name  string `json:"name"`
+	Model string `json:"model"`
+	Dim   int    `json:"dim"`
+	Count int    `json:"count"`
+}
+
+// logEntry 日志中的一条操作
+type logEntry struct {
+	Op       string            `json:"op"` // meta, put, del
+	ID       string            `json:"id,omitempty"`
+	Vector   []float32         `json:"vector,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Model    string            `json:"model,omitempty"`
+	Dim      int               `json:"dim,omitempty"`
+}
+
+// entry 内存中的条目
+type entry struct {
+	record Record
+	node   int32
+}
+
+// namespace 单个命名空间
+type namespace struct {
+	mu      sync.RWMutex
+	name    string
+	path    string
+	model   string
+	dim     int
+	entries map[string]*entry
+	graph   *hnsw
+	deleted int // 图中已删除的节点数
+	ops     int // 日志中的操作数
+	file    *os.File
+}
+
+// Store 按命名空间管理的向量索引
+type Store struct {
+	dir        string
+	mu         sync.Mutex
+	namespaces map[string]*namespace
+}
+
+// Open 打开数据目录下的向量索引，目录不存在时创建
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建向量索引目录失败: %w", err)
+	}
+	return &Store{
+		dir:        dir,
+		namespaces: make(map[string]*namespace),
+	}, nil
+}
+
+// namespace 返回命名空间，首次访问时从日志加载
+func (s *Store) namespace(name string) (*namespace, error) {
+	if !namespacePattern.MatchString(name) {
+		return nil, fmt.Errorf("无效的命名空间: %q", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ns, ok := s.namespaces[name]; ok {
+		return ns, nil
+	}
+
+	ns := &namespace{
+		name:    name,
+		path:    filepath.Join(s.dir, name+".jsonl"),
+		entries: make(map[string]*entry),
+		graph:   newHNSW(),
+	}
+	if err := ns.load(); err != nil {
+		return nil, err
+	}
+	s.namespaces[name] = ns
+	return ns, nil
+}
+
+// load 回放日志，删除操作较多时压缩日志
+func (ns *namespace) load() error {
+	f, err := os.Open(ns.path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("打开向量日志失败: %w", err)
+	}
+	if err == nil {
+		records := make(map[string]Record)
+		var order []string
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+		for scanner.Scan() {
+			var e logEntry
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				// 进程异常退出可能留下半行，忽略即可
+				continue
+			}
+			ns.ops++
+			switch e.Op {
+			case "meta":
+				ns.model, ns.dim = e.Model, e.Dim
+			case "put":
+				if _, exists := records[e.ID]; !exists {
+					order = append(order, e.ID)
+				}
+				records[e.ID] = Record{ID: e.ID, Vector: e.Vector, Metadata: e.Metadata}
+			case "del":
+				delete(records, e.ID)
+			}
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("读取向量日志失败: %w", err)
+		}
+
+		for _, id := range order {
+			if r, ok := records[id]; ok {
+				ns.insert(r)
+			}
+		}
+		if ns.ops > 2*len(ns.entries)+100 {
+			if err := ns.compact(); err != nil {
+				return err
+			}
+		}
+	}
+
+	ns.file, err = os.OpenFile(ns.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开向量日志失败: %w", err)
+	}
+	return nil
+}
+
+// insert 将条目加入内存索引，同名条目会被替换
+func (ns *namespace) insert(r Record) {
+	if old, ok := ns.entries[r.ID]; ok {
+		ns.graph.nodes[old.node].deleted = true
+		ns.deleted++
+	}
+	ns.entries[r.ID] = &entry{record: r, node: ns.graph.insert(r.ID, r.Vector)}
+}
+
+// remove 从内存索引删除条目
+func (ns *namespace) remove(id string) bool {
+	old, ok := ns.entries[id]
+	if !ok {
+		return false
+	}
+	ns.graph.nodes[old.node].deleted = true
+	ns.deleted++
+	delete(ns.entries, id)
+	return true
+}
+
+// append 追加一条日志
+func (ns *namespace) append(e logEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("序列化向量日志失败: %w", err)
+	}
+	if _, err := ns.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入向量日志失败: %w", err)
+	}
+	ns.ops++
+	return nil
+}
+
+// compact 用当前条目重写日志并重建图
+func (ns *namespace) compact() error {
+	tmp := ns.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("创建向量日志失败: %w", err)
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	ops := 0
+	if ns.model != "" || ns.dim > 0 {
+		enc.Encode(logEntry{Op: "meta", Model: ns.model, Dim: ns.dim})
+		ops++
+	}
+
+	ids := make([]string, 0, len(ns.entries))
+	for id := range ns.entries {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ns.entries[ids[i]].node < ns.entries[ids[j]].node })
+
+	graph := newHNSW()
+	for _, id := range ids {
+		e := ns.entries[id]
+		if err := enc.Encode(logEntry{Op: "put", ID: id, Vector: e.record.Vector, Metadata: e.record.Metadata}); err != nil {
+			f.Close()
+			os.Remove(tmp)
+			return fmt.Errorf("写入向量日志失败: %w", err)
+		}
+		e.node = graph.insert(id, e.record.Vector)
+		ops++
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("写入向量日志失败: %w", err)
+	}
+	f.Close()
+
+	if ns.file != nil {
+		ns.file.Close()
+		ns.file = nil
+	}
+	if err := os.Rename(tmp, ns.path); err != nil {
+		return fmt.Errorf("替换向量日志失败: %w", err)
+	}
+
+	ns.graph = graph
+	ns.deleted = 0
+	ns.ops = ops
+	return nil
+}
+
+// normalized 返回归一化后的向量副本
+func normalized(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	out := make([]float32, len(v))
+	if sum == 0 {
+		return out
+	}
+	norm := float32(math.Sqrt(sum))
+	for i, x := range v {
+		out[i] = x / norm
+	}
+	return out
+}
+
+// EnsureModel 检查命名空间使用的向量模型
+// 空命名空间会记录该模型；已有条目且模型不同时返回 ErrModelMismatch，调用方可 Reset 后重建
+func (s *Store) EnsureModel(name, model string) error {
+	ns, err := s.namespace(name)
+	if err != nil {
+		return err
+	}
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	if ns.model == model {
+		return nil
+	}
+	if ns.model != "" && len(ns.entries) > 0 {
+		return fmt.Errorf("命名空间 %s 使用 %s，不能写入 %s 的向量: %w", name, ns.model, model, ErrModelMismatch)
+	}
+	ns.model = model
+	return ns.append(logEntry{Op: "meta", Model: ns.model, Dim: ns.dim})
+}
+
+// Upsert 写入或替换条目，向量会被归一化
+func (s *Store) Upsert(name string, records ...Record) error {
+	ns, err := s.namespace(name)
+	if err != nil {
+		return err
+	}
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	for _, r := range records {
+		if r.ID == "" || len(r.Vector) == 0 {
+			return fmt.Errorf("条目ID和向量不能为空")
+		}
+		if ns.dim == 0 {
+			ns.dim = len(r.Vector)
+			if err := ns.append(logEntry{Op: "meta", Model: ns.model, Dim: ns.dim}); err != nil {
+				return err
+			}
+		}
+		if len(r.Vector) != ns.dim {
+			return fmt.Errorf("命名空间 %s 维度为 %d，条目 %s 为 %d: %w", name, ns.dim, r.ID, len(r.Vector), ErrDimensionMismatch)
+		}
+
+		r.Vector = normalized(r.Vector)
+		if err := ns.append(logEntry{Op: "put", ID: r.ID, Vector: r.Vector, Metadata: r.Metadata}); err != nil {
+			return err
+		}
+		ns.insert(r)
+	}
+
+	if ns.deleted > len(ns.entries) && ns.deleted > bruteForceLimit/2 {
+		if err := ns.compact(); err != nil {
+			return err
+		}
+		return ns.reopen()
+	}
+	return nil
+}
+
+// reopen 压缩后重新打开日志
+func (ns *namespace) reopen() error {
+	f, err := os.OpenFile(ns.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("打开向量日志失败: %w", err)
+	}
+	ns.file = f
+	return nil
+}
+
+// Delete 删除条目，不存在的ID忽略
+func (s *Store) Delete(name string, ids ...string) error {
+	ns, err := s.namespace(name)
+	if err != nil {
+		return err
+	}
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	for _, id := range ids {
+		if !ns.remove(id) {
+			continue
+		}
+		if err := ns.append(logEntry{Op: "del", ID: id}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get 获取条目
+func (s *Store) Get(name, id string) (Record, bool) {
+	ns, err := s.namespace(name)
+	if err != nil {
+		return Record{}, false
+	}
+
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	e, ok := ns.entries[id]
+	if !ok {
+		return Record{}, false
+	}
+	return e.record, true
+}
+
+// Search 返回与查询向量最相似的 k 个条目，按相似度降序排列
+// filter 不为空时只返回满足条件的条目
+func (s *Store) Search(name string, query []float32, k int, filter func(metadata map[string]string) bool) ([]Match, error) {
+	ns, err := s.namespace(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ns.mu.RLock()
+	defer ns.mu.RUnlock()
+
+	if len(ns.entries) == 0 || k <= 0 {
+		return nil, nil
+	}
+	if len(query) != ns.dim {
+		return nil, fmt.Errorf("命名空间 %s 维度为 %d，查询为 %d: %w", name, ns.dim, len(query), ErrDimensionMismatch)
+	}
+	query = normalized(query)
+
+	var candidates []scored
+	if len(ns.entries) <= bruteForceLimit || filter != nil {
+		candidates = make([]scored, 0, len(ns.entries))
+		for _, e := range ns.entries {
+			candidates = append(candidates, scored{node: e.node, dist: distance(query, e.record.Vector)})
+		}
+		sortScored(candidates)
+	} else {
+		ef := hnswEfSearch
+		if ef < 2*k {
+			ef = 2 * k
+		}
+		candidates = ns.graph.search(query, k, ef+ns.deleted)
+	}
+
+	matches := make([]Match, 0, k)
+	for _, c := range candidates {
+		node := ns.graph.nodes[c.node]
+		if node.deleted {
+			continue
+		}
+		e := ns.entries[node.id]
+		if filter != nil && !filter(e.record.Metadata) {
+			continue
+		}
+		matches = append(matches, Match{ID: node.id, Score: float64(1 - c.dist), Metadata: e.record.Metadata})
+		if len(matches) >= k {
+			break
+		}
+	}
+	return matches, nil
+}
+
+// Reset 清空命名空间，包括记录的向量模型
+func (s *Store) Reset(name string) error {
+	ns, err := s.namespace(name)
+	if err != nil {
+		return err
+	}
+
+	ns.mu.Lock()
+	defer ns.mu.Unlock()
+
+	ns.entries = make(map[string]*entry)
+	ns.graph = newHNSW()
+	ns.model, ns.dim, ns.deleted = "", 0, 0
+	if err := ns.compact(); err != nil {
+		return err
+	}
+	return ns.reopen()
+}
+
+// Namespaces 返回已有命名空间的统计，包括尚未加载的命名空间
+func (s *Store) Namespaces() ([]NamespaceInfo, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]NamespaceInfo, 0, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".jsonl")
+		ns, err := s.namespace(name)
+		if err != nil {
+			continue
+		}
+		ns.mu.RLock()
+		result = append(result, NamespaceInfo{Name: name, Model: ns.model, Dim: ns.dim, Count: len(ns.entries)})
+		ns.mu.RUnlock()
+	}
+	return result, nil
+}
+
+// Close 落盘并关闭所有命名空间的日志
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	for _, ns := range s.namespaces {
+		ns.mu.Lock()
+		if ns.file != nil {
+			if err := ns.file.Sync(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			if err := ns.file.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			ns.file = nil
+		}
+		ns.mu.Unlock()
+	}
+	s.namespaces = make(map[string]*namespace)
+	return firstErr
+}
@@ -0,0 +1,175 @@
+package vector
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func randomVector(rng *rand.Rand, dim int) []float32 {
+	v := make([]float32, dim)
+	for i := range v {
+		v[i] = float32(rng.NormFloat64())
+	}
+	return v
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+
+	if err := s.EnsureModel("topics", "m1"); err != nil {
+		t.Fatalf("EnsureModel failed: %v", err)
+	}
+	err = s.Upsert("topics",
+		Record{ID: "a", Vector: []float32{1, 0, 0}, Metadata: map[string]string{"title": "A"}},
+		Record{ID: "b", Vector: []float32{0, 1, 0}},
+		Record{ID: "c", Vector: []float32{0.9, 0.1, 0}},
+	)
+	if err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	matches, err := s.Search("topics", []float32{2, 0, 0}, 2, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(matches) != 2 || matches[0].ID != "a" || matches[1].ID != "c" {
+		t.Fatalf("Unexpected matches: %+v", matches)
+	}
+	if matches[0].Metadata["title"] != "A" || matches[0].Score < 0.999 {
+		t.Errorf("Unexpected top match: %+v", matches[0])
+	}
+
+	if err := s.Delete("topics", "a"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := s.Upsert("topics", Record{ID: "b", Vector: []float32{1, 0, 0.1}}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// 重新打开后回放日志
+	s, err = Open(dir)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer s.Close()
+
+	if _, ok := s.Get("topics", "a"); ok {
+		t.Error("Expected deleted record to stay deleted")
+	}
+	matches, err = s.Search("topics", []float32{1, 0, 0}, 1, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].ID != "b" {
+		t.Errorf("Expected replaced record b on top, got %+v", matches)
+	}
+
+	infos, err := s.Namespaces()
+	if err != nil {
+		t.Fatalf("Namespaces failed: %v", err)
+	}
+	if len(infos) != 1 || infos[0].Model != "m1" || infos[0].Dim != 3 || infos[0].Count != 2 {
+		t.Errorf("Unexpected namespaces: %+v", infos)
+	}
+}
+
+func TestStoreMismatch(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer s.Close()
+
+	if err := s.EnsureModel("docs", "m1"); err != nil {
+		t.Fatalf("EnsureModel failed: %v", err)
+	}
+	if err := s.Upsert("docs", Record{ID: "a", Vector: []float32{1, 0}}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if err := s.Upsert("docs", Record{ID: "b", Vector: []float32{1, 0, 0}}); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Expected ErrDimensionMismatch, got %v", err)
+	}
+	if _, err := s.Search("docs", []float32{1}, 1, nil); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Expected ErrDimensionMismatch on search, got %v", err)
+	}
+	if err := s.EnsureModel("docs", "m2"); !errors.Is(err, ErrModelMismatch) {
+		t.Errorf("Expected ErrModelMismatch, got %v", err)
+	}
+
+	if err := s.Reset("docs"); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if err := s.EnsureModel("docs", "m2"); err != nil {
+		t.Errorf("Expected EnsureModel to succeed after reset, got %v", err)
+	}
+	if err := s.Upsert("Bad/Name", Record{ID: "a", Vector: []float32{1}}); err == nil {
+		t.Error("Expected invalid namespace name to be rejected")
+	}
+}
+
+func TestHNSWRecall(t *testing.T) {
+	const (
+		n       = 3000
+		dim     = 32
+		queries = 50
+		k       = 10
+	)
+	rng := rand.New(rand.NewSource(1))
+
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer s.Close()
+
+	records := make([]Record, n)
+	for i := range records {
+		records[i] = Record{ID: strconv.Itoa(i), Vector: randomVector(rng, dim)}
+	}
+	if err := s.Upsert("bench", records...); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	hits := 0
+	for q := 0; q < queries; q++ {
+		query := randomVector(rng, dim)
+
+		// filter 不为空时走精确检索，作为对照
+		exact, err := s.Search("bench", query, k, func(map[string]string) bool { return true })
+		if err != nil {
+			t.Fatalf("Exact search failed: %v", err)
+		}
+		approx, err := s.Search("bench", query, k, nil)
+		if err != nil {
+			t.Fatalf("Approximate search failed: %v", err)
+		}
+		if !sort.SliceIsSorted(approx, func(i, j int) bool { return approx[i].Score > approx[j].Score }) {
+			t.Fatalf("Expected results sorted by score: %+v", approx)
+		}
+
+		want := make(map[string]bool, k)
+		for _, m := range exact {
+			want[m.ID] = true
+		}
+		for _, m := range approx {
+			if want[m.ID] {
+				hits++
+			}
+		}
+	}
+
+	recall := float64(hits) / float64(queries*k)
+	if recall < 0.9 {
+		t.Errorf("Expected recall >= 0.9, got %.3f", recall)
+	}
+}
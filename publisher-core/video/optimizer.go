@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"publisher-core/ai/provider"
+	"publisher-core/cost"
 	"publisher-core/database"
+	"publisher-core/prompt"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -18,6 +20,7 @@ import (
 type Optimizer struct {
 	db        *gorm.DB
 	aiService AIProvider
+	prompts   *prompt.Service
 	config    *OptimizerConfig
 }
 
@@ -28,19 +31,23 @@ type AIProvider interface {
 
 // OptimizerConfig 优化器配置
 type OptimizerConfig struct {
-	MaxChunkTokens  int     `json:"max_chunk_tokens"`
-	Temperature     float64 `json:"temperature"`
-	EnableParallel  bool    `json:"enable_parallel"`
-	MaxWorkers      int     `json:"max_workers"`
+	MaxChunkTokens int `json:"max_chunk_tokens"`
+	// SummaryMaxTokens 不超过此长度的转录文本整体生成摘要，超出时只保留首尾部分
+	// 默认值能放入常见模型的上下文窗口；所用模型的窗口更大时可以调高
+	SummaryMaxTokens int     `json:"summary_max_tokens"`
+	Temperature      float64 `json:"temperature"`
+	EnableParallel   bool    `json:"enable_parallel"`
+	MaxWorkers       int     `json:"max_workers"`
 }
 
 // DefaultOptimizerConfig 默认配置
 func DefaultOptimizerConfig() *OptimizerConfig {
 	return &OptimizerConfig{
-		MaxChunkTokens:  2000,
-		Temperature:     0.3,
-		EnableParallel:  true,
-		MaxWorkers:      3,
+		MaxChunkTokens:   2000,
+		SummaryMaxTokens: 3000,
+		Temperature:      0.3,
+		EnableParallel:   true,
+		MaxWorkers:       3,
 	}
 }
 
@@ -52,6 +59,7 @@ func NewOptimizer(db *gorm.DB, aiService AIProvider, config *OptimizerConfig) *O
 	return &Optimizer{
 		db:        db,
 		aiService: aiService,
+		prompts:   prompt.NewService(db),
 		config:    config,
 	}
 }
@@ -69,6 +77,8 @@ type OptimizeResult struct {
 
 // Optimize 优化转录文本
 func (o *Optimizer) Optimize(ctx context.Context, transcript string, opts *OptimizeOptions) (*OptimizeResult, error) {
+	ctx = cost.WithFunction(ctx, "transcript_optimization")
+
 	if opts == nil {
 		opts = &OptimizeOptions{}
 	}
@@ -137,13 +147,14 @@ type OptimizeOptions struct {
 
 // optimizeChunk 优化单个文本块
 func (o *Optimizer) optimizeChunk(ctx context.Context, chunk string, opts *OptimizeOptions) (string, error) {
-	prompt := o.buildOptimizePrompt(chunk, opts)
-
-	result, err := o.aiService.Generate(ctx, &provider.GenerateOptions{
-		Messages: []provider.Message{
-			{Role: provider.RoleUser, Content: prompt},
-		},
-		Temperature: o.config.Temperature,
+	result, err := o.prompts.Generate(ctx, o.aiService, prompt.TemplateVideoTranscriptOptimization, map[string]interface{}{
+		"text":            chunk,
+		"language":        outputLanguage(opts),
+		"add_punctuation": opts.AddPunctuation,
+		"fix_grammar":     opts.FixGrammar,
+		"remove_filler":   opts.RemoveFiller,
+	}, func(req *provider.GenerateOptions) {
+		req.Temperature = o.config.Temperature
 	})
 	if err != nil {
 		return "", fmt.Errorf("AI generation failed: %w", err)
@@ -199,63 +210,29 @@ func (o *Optimizer) optimizeChunks(ctx context.Context, chunks []string, opts *O
 	return results, nil
 }
 
-// buildOptimizePrompt 构建优化提示词
-func (o *Optimizer) buildOptimizePrompt(text string, opts *OptimizeOptions) string {
-	language := "中文"
+// outputLanguage 提示词中使用的语言名称
+func outputLanguage(opts *OptimizeOptions) string {
 	if opts.Language == "en" {
-		language = "English"
-	}
-
-	var instructions []string
-	instructions = append(instructions, fmt.Sprintf("请优化以下%s转录文本：", language))
-	instructions = append(instructions, "1. 修正错别字和错误词汇")
-	instructions = append(instructions, "2. 补全不完整的句子")
-	instructions = append(instructions, "3. 按语义合理分段")
-
-	if opts.AddPunctuation {
-		instructions = append(instructions, "4. 添加正确的标点符号")
-	}
-	if opts.FixGrammar {
-		instructions = append(instructions, "5. 修正语法错误")
+		return "English"
 	}
-	if opts.RemoveFiller {
-		instructions = append(instructions, "6. 移除口语填充词（如嗯、啊、那个等）")
-	}
-
-	instructions = append(instructions, "7. 保持原意不变，不要添加新内容")
-	instructions = append(instructions, "8. 直接输出优化后的文本，不要添加任何解释")
-
-	return fmt.Sprintf("%s\n\n转录文本：\n%s", strings.Join(instructions, "\n"), text)
+	return "中文"
 }
 
 // generateSummary 生成摘要
 func (o *Optimizer) generateSummary(ctx context.Context, text string, opts *OptimizeOptions) (string, error) {
-	// 如果文本太长，先截取关键部分
-	if EstimateTokenCount(text) > 3000 {
-		chunks := SplitLongText(text, 2000)
+	// 超出摘要长度上限时只保留首尾部分
+	if o.config.SummaryMaxTokens > 0 && provider.CountTokens("", "", text) > o.config.SummaryMaxTokens {
+		chunks := SplitLongText(text, o.config.MaxChunkTokens)
 		text = chunks[0]
 		if len(chunks) > 1 {
 			text += "\n...\n" + chunks[len(chunks)-1]
 		}
 	}
 
-	language := "中文"
-	if opts.Language == "en" {
-		language = "English"
-	}
-
-	prompt := fmt.Sprintf(`请用%s为以下内容生成一个简洁的摘要（100-200字）：
-
-%s
-
-直接输出摘要内容，不要添加任何解释。`, language, text)
-
-	result, err := o.aiService.Generate(ctx, &provider.GenerateOptions{
-		Messages: []provider.Message{
-			{Role: provider.RoleUser, Content: prompt},
-		},
-		Temperature: 0.5,
-	})
+	result, err := o.prompts.Generate(ctx, o.aiService, prompt.TemplateVideoSummary, map[string]interface{}{
+		"text":     text,
+		"language": outputLanguage(opts),
+	}, nil)
 	if err != nil {
 		return "", err
 	}
@@ -265,26 +242,10 @@ func (o *Optimizer) generateSummary(ctx context.Context, text string, opts *Opti
 
 // extractKeyPoints 提取关键点
 func (o *Optimizer) extractKeyPoints(ctx context.Context, text string, opts *OptimizeOptions) ([]string, error) {
-	language := "中文"
-	if opts.Language == "en" {
-		language = "English"
-	}
-
-	prompt := fmt.Sprintf(`请从以下内容中提取5-10个关键要点，用%s回答：
-
-%s
-
-请按以下格式输出，每行一个要点：
-- 要点1
-- 要点2
-...`, language, text)
-
-	result, err := o.aiService.Generate(ctx, &provider.GenerateOptions{
-		Messages: []provider.Message{
-			{Role: provider.RoleUser, Content: prompt},
-		},
-		Temperature: 0.3,
-	})
+	result, err := o.prompts.Generate(ctx, o.aiService, prompt.TemplateVideoKeyPoints, map[string]interface{}{
+		"text":     text,
+		"language": outputLanguage(opts),
+	}, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -307,23 +268,10 @@ func (o *Optimizer) extractKeyPoints(ctx context.Context, text string, opts *Opt
 
 // extractTopics 提取主题
 func (o *Optimizer) extractTopics(ctx context.Context, text string, opts *OptimizeOptions) ([]string, error) {
-	language := "中文"
-	if opts.Language == "en" {
-		language = "English"
-	}
-
-	prompt := fmt.Sprintf(`请从以下内容中提取3-5个主要主题/话题标签，用%s回答：
-
-%s
-
-请直接输出主题标签，用逗号分隔，例如：技术,创新,人工智能`, language, text)
-
-	result, err := o.aiService.Generate(ctx, &provider.GenerateOptions{
-		Messages: []provider.Message{
-			{Role: provider.RoleUser, Content: prompt},
-		},
-		Temperature: 0.3,
-	})
+	result, err := o.prompts.Generate(ctx, o.aiService, prompt.TemplateVideoTopics, map[string]interface{}{
+		"text":     text,
+		"language": outputLanguage(opts),
+	}, nil)
 	if err != nil {
 		return nil, err
 	}
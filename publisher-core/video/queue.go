@@ -0,0 +1,175 @@
+package video
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"publisher-core/database"
+	taskpkg "publisher-core/task"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// VideoTaskType 视频处理任务类型
+	VideoTaskType = "video_process"
+	// VideoQueueName 视频处理任务队列
+	VideoQueueName = "video"
+)
+
+// videoTaskPayload 队列任务数据
+type videoTaskPayload struct {
+	URL     string          `json:"url"`
+	Options *ProcessOptions `json:"options"`
+}
+
+// submitQueueTask 提交视频任务到统一任务队列
+func (s *Service) submitQueueTask(url string, opts *ProcessOptions) (*VideoTask, error) {
+	asyncTask, _, err := s.queue.SubmitTask(s.ctx, &taskpkg.TaskRequest{
+		TaskType:  VideoTaskType,
+		QueueName: VideoQueueName,
+		Priority:  database.PriorityNormal,
+		Payload: map[string]interface{}{
+			"url":     url,
+			"options": opts,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.db != nil {
+		video := &database.Video{
+			ID:     asyncTask.TaskID,
+			URL:    url,
+			Status: "pending",
+		}
+		if err := s.db.Create(video).Error; err != nil {
+			logrus.Warnf("Failed to save video task: %v", err)
+		}
+	}
+
+	logrus.Infof("Video task submitted to queue: %s", asyncTask.TaskID)
+	return s.videoTaskFromAsync(asyncTask), nil
+}
+
+// handleQueueTask 队列任务处理函数
+func (s *Service) handleQueueTask(ctx context.Context, asyncTask *database.AsyncTask) error {
+	var payload videoTaskPayload
+	if err := json.Unmarshal([]byte(asyncTask.Payload), &payload); err != nil {
+		return fmt.Errorf("invalid video task payload: %w", err)
+	}
+	if payload.Options == nil {
+		payload.Options = DefaultProcessOptions()
+	}
+
+	startTime := time.Now()
+	task := &VideoTask{
+		ID:        asyncTask.TaskID,
+		URL:       payload.URL,
+		Status:    "processing",
+		Options:   payload.Options,
+		CreatedAt: asyncTask.CreatedAt,
+		StartedAt: &startTime,
+	}
+
+	logrus.Infof("Processing video task: %s", task.ID)
+
+	result, err := s.runTask(ctx, task)
+	if err != nil {
+		return err
+	}
+
+	result.ProcessTime = time.Since(startTime).Seconds()
+	resultBytes, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("marshal video result: %w", err)
+	}
+	asyncTask.Result = string(resultBytes)
+
+	if s.db != nil && result.VideoID != "" {
+		s.db.Model(&database.Video{}).Where("id = ?", result.VideoID).Updates(map[string]interface{}{
+			"status":     "completed",
+			"title":      result.Title,
+			"duration":   result.Duration,
+			"updated_at": time.Now(),
+		})
+	}
+
+	logrus.Infof("Video task completed: %s (%.2fs)", task.ID, result.ProcessTime)
+	return nil
+}
+
+// getQueueTask 从统一任务队列获取视频任务
+func (s *Service) getQueueTask(taskID string) (*VideoTask, error) {
+	asyncTask, err := s.queue.GetTask(taskID)
+	if err != nil || asyncTask.TaskType != VideoTaskType {
+		return nil, fmt.Errorf("task not found: %s", taskID)
+	}
+	return s.videoTaskFromAsync(asyncTask), nil
+}
+
+// listQueueTasks 从统一任务队列列出视频任务
+func (s *Service) listQueueTasks(status string) []*VideoTask {
+	asyncTasks, _, err := s.state.ListTasks(&taskpkg.StateTaskFilter{
+		TaskType: VideoTaskType,
+		Page:     1,
+		PageSize: 1000,
+	})
+	if err != nil {
+		logrus.Warnf("Failed to list video tasks: %v", err)
+		return nil
+	}
+
+	var tasks []*VideoTask
+	for i := range asyncTasks {
+		task := s.videoTaskFromAsync(&asyncTasks[i])
+		if status == "" || task.Status == status {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+// videoTaskFromAsync 将队列任务转换为视频任务视图
+func (s *Service) videoTaskFromAsync(asyncTask *database.AsyncTask) *VideoTask {
+	task := &VideoTask{
+		ID:          asyncTask.TaskID,
+		Progress:    asyncTask.Progress,
+		Error:       asyncTask.Error,
+		CreatedAt:   asyncTask.CreatedAt,
+		StartedAt:   asyncTask.StartedAt,
+		CompletedAt: asyncTask.CompletedAt,
+	}
+
+	var payload videoTaskPayload
+	if err := json.Unmarshal([]byte(asyncTask.Payload), &payload); err == nil {
+		task.URL = payload.URL
+		task.Options = payload.Options
+	}
+
+	if asyncTask.Result != "" {
+		var result ProcessResult
+		if err := json.Unmarshal([]byte(asyncTask.Result), &result); err == nil {
+			task.Result = &result
+			task.VideoID = result.VideoID
+		}
+	}
+
+	switch asyncTask.Status {
+	case database.TaskStatusRunning:
+		// 运行中的任务使用上报的阶段名
+		task.Status = "processing"
+		if asyncTask.ProgressText != "" {
+			task.Status = asyncTask.ProgressText
+		}
+	case database.TaskStatusCompleted, database.TaskStatusFailed, database.TaskStatusCancelled, database.TaskStatusCancelling:
+		task.Status = string(asyncTask.Status)
+	default:
+		task.Status = "pending"
+	}
+
+	return task
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"publisher-core/database"
+	taskpkg "publisher-core/task"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -29,6 +30,10 @@ type Service struct {
 	wg        sync.WaitGroup
 	ctx       context.Context
 	cancel    context.CancelFunc
+
+	// queue 非空时任务委托给统一任务队列执行
+	queue *taskpkg.QueueService
+	state *taskpkg.StateService
 }
 
 // ServiceConfig 服务配置
@@ -137,8 +142,24 @@ func NewService(db *gorm.DB, aiProvider AIProvider, config *ServiceConfig) *Serv
 	return s
 }
 
+// SetTaskQueue 将视频任务委托给统一任务队列，任务持久化并出现在统一任务列表中
+func (s *Service) SetTaskQueue(queue *taskpkg.QueueService, state *taskpkg.StateService) {
+	s.queue = queue
+	s.state = state
+
+	if err := queue.RegisterQueue(VideoQueueName, s.workers); err != nil {
+		logrus.Debugf("Video queue already registered: %v", err)
+	}
+	queue.RegisterHandler(VideoTaskType, s.handleQueueTask)
+}
+
 // Start 启动服务
 func (s *Service) Start() {
+	if s.queue != nil {
+		logrus.Info("Video service delegates tasks to the task queue")
+		return
+	}
+
 	logrus.Infof("Starting video service with %d workers", s.workers)
 
 	for i := 0; i < s.workers; i++ {
@@ -178,8 +199,28 @@ func (s *Service) processTask(task *VideoTask) {
 
 	logrus.Infof("Processing video task: %s", task.ID)
 
+	result, err := s.runTask(s.ctx, task)
+	if err != nil {
+		s.failTask(task, err.Error())
+		return
+	}
+
+	// 4. 完成
+	completedAt := time.Now()
+	task.Status = "completed"
+	task.Progress = 100
+	task.CompletedAt = &completedAt
+	task.Result = result
+	s.updateTask(task)
+
+	result.ProcessTime = completedAt.Sub(startTime).Seconds()
+
+	logrus.Infof("Video task completed: %s (%.2fs)", task.ID, result.ProcessTime)
+}
+
+// runTask 执行下载、转录、优化步骤
+func (s *Service) runTask(ctx context.Context, task *VideoTask) (*ProcessResult, error) {
 	var result ProcessResult
-	var err error
 
 	// 1. 下载视频/音频
 	task.Status = "downloading"
@@ -191,10 +232,9 @@ func (s *Service) processTask(task *VideoTask) {
 		DownloadSubtitles: task.Options.DownloadSubtitle,
 	}
 
-	downloadResult, err := s.downloader.Download(s.ctx, task.URL, downloadOpts)
+	downloadResult, err := s.downloader.Download(ctx, task.URL, downloadOpts)
 	if err != nil {
-		s.failTask(task, fmt.Sprintf("Download failed: %v", err))
-		return
+		return nil, fmt.Errorf("Download failed: %v", err)
 	}
 
 	result.VideoID = downloadResult.VideoID
@@ -214,7 +254,7 @@ func (s *Service) processTask(task *VideoTask) {
 		task.Progress = 40
 		s.updateTask(task)
 
-		transcriptResult, err := s.transcriber.TranscribeWithFallback(s.ctx, downloadResult.AudioPath, downloadResult.VideoID)
+		transcriptResult, err := s.transcriber.TranscribeWithFallback(ctx, downloadResult.AudioPath, downloadResult.VideoID)
 		if err != nil {
 			logrus.Warnf("Transcription failed: %v, continuing without transcript", err)
 		} else {
@@ -239,7 +279,7 @@ func (s *Service) processTask(task *VideoTask) {
 			RemoveFiller:   true,
 		}
 
-		optimizeResult, err := s.optimizer.Optimize(s.ctx, result.Transcript, optimizeOpts)
+		optimizeResult, err := s.optimizer.Optimize(ctx, result.Transcript, optimizeOpts)
 		if err != nil {
 			logrus.Warnf("Optimization failed: %v, using original transcript", err)
 		} else {
@@ -253,17 +293,7 @@ func (s *Service) processTask(task *VideoTask) {
 		s.updateTask(task)
 	}
 
-	// 4. 完成
-	completedAt := time.Now()
-	task.Status = "completed"
-	task.Progress = 100
-	task.CompletedAt = &completedAt
-	task.Result = &result
-	s.updateTask(task)
-
-	result.ProcessTime = completedAt.Sub(startTime).Seconds()
-
-	logrus.Infof("Video task completed: %s (%.2fs)", task.ID, result.ProcessTime)
+	return &result, nil
 }
 
 // SubmitTask 提交任务
@@ -272,6 +302,10 @@ func (s *Service) SubmitTask(url string, opts *ProcessOptions) (*VideoTask, erro
 		opts = DefaultProcessOptions()
 	}
 
+	if s.queue != nil {
+		return s.submitQueueTask(url, opts)
+	}
+
 	task := &VideoTask{
 		ID:        fmt.Sprintf("video_%d", time.Now().UnixNano()),
 		URL:       url,
@@ -309,6 +343,10 @@ func (s *Service) SubmitTask(url string, opts *ProcessOptions) (*VideoTask, erro
 
 // GetTask 获取任务状态
 func (s *Service) GetTask(taskID string) (*VideoTask, error) {
+	if s.queue != nil {
+		return s.getQueueTask(taskID)
+	}
+
 	s.taskMu.RLock()
 	defer s.taskMu.RUnlock()
 
@@ -321,6 +359,15 @@ func (s *Service) GetTask(taskID string) (*VideoTask, error) {
 
 // GetTaskByVideoID 通过视频ID获取任务
 func (s *Service) GetTaskByVideoID(videoID string) (*VideoTask, error) {
+	if s.queue != nil {
+		for _, task := range s.listQueueTasks("") {
+			if task.VideoID == videoID {
+				return task, nil
+			}
+		}
+		return nil, fmt.Errorf("task not found for video: %s", videoID)
+	}
+
 	s.taskMu.RLock()
 	defer s.taskMu.RUnlock()
 
@@ -334,6 +381,10 @@ func (s *Service) GetTaskByVideoID(videoID string) (*VideoTask, error) {
 
 // ListTasks 列出所有任务
 func (s *Service) ListTasks(status string) []*VideoTask {
+	if s.queue != nil {
+		return s.listQueueTasks(status)
+	}
+
 	s.taskMu.RLock()
 	defer s.taskMu.RUnlock()
 
@@ -348,6 +399,10 @@ func (s *Service) ListTasks(status string) []*VideoTask {
 
 // CancelTask 取消任务
 func (s *Service) CancelTask(taskID string) error {
+	if s.queue != nil {
+		return s.queue.CancelTask(taskID)
+	}
+
 	task, err := s.GetTask(taskID)
 	if err != nil {
 		return err
@@ -364,10 +419,20 @@ func (s *Service) CancelTask(taskID string) error {
 }
 
 // updateTask 更新任务
+// 使用统一任务队列时任务状态以队列为准，不写入内存表，避免处理过的任务一直留在内存中
 func (s *Service) updateTask(task *VideoTask) {
-	s.taskMu.Lock()
-	s.tasks[task.ID] = task
-	s.taskMu.Unlock()
+	if s.queue == nil {
+		s.taskMu.Lock()
+		s.tasks[task.ID] = task
+		s.taskMu.Unlock()
+	}
+
+	// 通过统一状态服务上报进度，阶段名作为进度描述
+	if s.state != nil && s.queue != nil {
+		if err := s.state.UpdateProgress(task.ID, task.Progress, task.Status); err != nil {
+			logrus.Warnf("Failed to report video task progress: %v", err)
+		}
+	}
 
 	// 更新数据库
 	if s.db != nil && task.VideoID != "" {
@@ -421,7 +486,7 @@ func (s *Service) ProcessVideo(ctx context.Context, url string, opts *ProcessOpt
 				return task.Result, nil
 			}
 			if task.Status == "failed" {
-				return nil, fmt.Errorf(task.Error)
+				return nil, fmt.Errorf("%s", task.Error)
 			}
 			if task.Status == "cancelled" {
 				return nil, fmt.Errorf("task cancelled")
@@ -488,8 +553,8 @@ func (s *Service) GetStats() (*ServiceStats, error) {
 	stats := &ServiceStats{}
 
 	// 任务统计
-	s.taskMu.RLock()
-	for _, task := range s.tasks {
+	tasks := s.ListTasks("")
+	for _, task := range tasks {
 		switch task.Status {
 		case "pending":
 			stats.PendingTasks++
@@ -501,7 +566,6 @@ func (s *Service) GetStats() (*ServiceStats, error) {
 			stats.FailedTasks++
 		}
 	}
-	s.taskMu.RUnlock()
 
 	// 视频统计
 	if s.db != nil {
@@ -12,6 +12,7 @@ import (
 	"strings"
 	"time"
 
+	"publisher-core/ai/provider"
 	"publisher-core/database"
 
 	"github.com/sirupsen/logrus"
@@ -410,26 +411,9 @@ func GetSupportedLanguages() map[string]string {
 	}
 }
 
-// EstimateTokenCount 估算 Token 数量
-func EstimateTokenCount(text string) int {
-	// 简单估算：中文约 1.5 字/token，英文约 4 字符/token
-	chineseCount := 0
-	englishCount := 0
-
-	for _, r := range text {
-		if r >= 0x4E00 && r <= 0x9FFF {
-			chineseCount++
-		} else if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
-			englishCount++
-		}
-	}
-
-	return int(float64(chineseCount)/1.5 + float64(englishCount)/4)
-}
-
-// SplitLongText 分割长文本
+// SplitLongText 分割长文本，token 数按未知模型的保守估计计算
 func SplitLongText(text string, maxTokens int) []string {
-	tokens := EstimateTokenCount(text)
+	tokens := provider.CountTokens("", "", text)
 	if tokens <= maxTokens {
 		return []string{text}
 	}
@@ -441,7 +425,7 @@ func SplitLongText(text string, maxTokens int) []string {
 	currentTokens := 0
 
 	for _, para := range paragraphs {
-		paraTokens := EstimateTokenCount(para)
+		paraTokens := provider.CountTokens("", "", para)
 
 		if currentTokens+paraTokens > maxTokens {
 			if currentChunk.Len() > 0 {
@@ -485,7 +469,7 @@ func splitBySentences(text string, maxTokens int) []string {
 			continue
 		}
 
-		sentTokens := EstimateTokenCount(sent)
+		sentTokens := provider.CountTokens("", "", sent)
 
 		if currentTokens+sentTokens > maxTokens {
 			if currentChunk.Len() > 0 {
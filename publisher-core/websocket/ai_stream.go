@@ -0,0 +1,149 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+
+	"publisher-core/ai/provider"
+	"publisher-core/cost"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MessageTypeAIStream AI 流式生成事件，TaskID 为客户端请求ID，Payload 为 provider.StreamEvent
+const MessageTypeAIStream = "ai_stream"
+
+// AIStreamer 以事件形式流式生成内容，ai.Service 和 ai.UnifiedService 均满足
+type AIStreamer interface {
+	GenerateStreamEvents(ctx context.Context, opts *provider.GenerateOptions) (<-chan provider.StreamEvent, error)
+}
+
+// aiGenerateRequest 客户端发起的流式生成请求
+// {"action":"ai_generate","request_id":"r1","messages":[...]}，取消时发送 {"action":"ai_cancel","request_id":"r1"}
+type aiGenerateRequest struct {
+	RequestID   string             `json:"request_id"`
+	Messages    []provider.Message `json:"messages"`
+	Model       string             `json:"model,omitempty"`
+	MaxTokens   int                `json:"max_tokens,omitempty"`
+	Temperature float64            `json:"temperature,omitempty"`
+}
+
+// SetAIStreamer 设置处理 ai_generate 消息的生成服务
+func (h *Hub) SetAIStreamer(streamer AIStreamer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.streamer = streamer
+}
+
+// aiStreamer 返回当前生成服务
+func (h *Hub) aiStreamer() AIStreamer {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.streamer
+}
+
+// startAIStream 启动流式生成，事件逐条推送给该客户端
+func (c *Client) startAIStream(message []byte) {
+	var req aiGenerateRequest
+	if err := json.Unmarshal(message, &req); err != nil || req.RequestID == "" || len(req.Messages) == 0 {
+		c.sendStreamEvent(context.Background(), req.RequestID, provider.StreamEvent{Type: provider.StreamEventError, Error: "无效的生成请求"})
+		return
+	}
+
+	streamer := c.Hub.aiStreamer()
+	if streamer == nil {
+		c.sendStreamEvent(context.Background(), req.RequestID, provider.StreamEvent{Type: provider.StreamEventError, Error: "AI服务未配置"})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = cost.WithAttribution(ctx, cost.Attribution{UserID: c.UserID, ProjectID: c.ProjectID, RequestID: req.RequestID})
+
+	c.mu.Lock()
+	if c.streams == nil {
+		c.streams = make(map[string]context.CancelFunc)
+	}
+	if _, exists := c.streams[req.RequestID]; exists {
+		c.mu.Unlock()
+		cancel()
+		c.sendStreamEvent(context.Background(), req.RequestID, provider.StreamEvent{Type: provider.StreamEventError, Error: "请求ID重复"})
+		return
+	}
+	c.streams[req.RequestID] = cancel
+	c.streamWG.Add(1)
+	c.mu.Unlock()
+
+	go func() {
+		defer c.streamWG.Done()
+		defer c.finishAIStream(req.RequestID)
+
+		events, err := streamer.GenerateStreamEvents(ctx, &provider.GenerateOptions{
+			Messages:    req.Messages,
+			Model:       req.Model,
+			MaxTokens:   req.MaxTokens,
+			Temperature: req.Temperature,
+		})
+		if err != nil {
+			logrus.Warnf("客户端 %s 流式生成失败: %v", c.ID, err)
+			c.sendStreamEvent(ctx, req.RequestID, provider.StreamEvent{Type: provider.StreamEventError, Error: err.Error()})
+			return
+		}
+
+		for event := range events {
+			c.sendStreamEvent(ctx, req.RequestID, event)
+		}
+	}()
+}
+
+// cancelAIStream 取消进行中的流式生成，取消会传递到提供商请求
+func (c *Client) cancelAIStream(requestID string) {
+	c.mu.Lock()
+	cancel, exists := c.streams[requestID]
+	c.mu.Unlock()
+
+	if exists {
+		cancel()
+		logrus.Infof("客户端 %s 取消流式生成: %s", c.ID, requestID)
+	}
+}
+
+// finishAIStream 释放流式生成占用的资源
+func (c *Client) finishAIStream(requestID string) {
+	c.mu.Lock()
+	cancel, exists := c.streams[requestID]
+	delete(c.streams, requestID)
+	c.mu.Unlock()
+
+	if exists {
+		cancel()
+	}
+}
+
+// stopAIStreams 取消该客户端的全部流式生成并等待结束，需在关闭 Send 通道前调用
+func (c *Client) stopAIStreams() {
+	c.mu.Lock()
+	for _, cancel := range c.streams {
+		cancel()
+	}
+	c.mu.Unlock()
+
+	c.streamWG.Wait()
+}
+
+// sendStreamEvent 推送流式事件，发送缓冲区满时等待直到 ctx 取消
+func (c *Client) sendStreamEvent(ctx context.Context, requestID string, event provider.StreamEvent) {
+	data, err := json.Marshal(&Message{
+		Type:    MessageTypeAIStream,
+		TaskID:  requestID,
+		Payload: event,
+	})
+	if err != nil {
+		logrus.Errorf("序列化消息失败: %v", err)
+		return
+	}
+
+	select {
+	case c.Send <- data:
+	case <-ctx.Done():
+	}
+}
@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"sync"
@@ -20,6 +21,9 @@ type Client struct {
 	Hub        *Hub
 	Subscribed map[string]bool // 订阅的任务ID
 	mu         sync.RWMutex
+
+	streams  map[string]context.CancelFunc // 进行中的 AI 流式生成，键为请求ID
+	streamWG sync.WaitGroup
 }
 
 // Hub WebSocket中心
@@ -29,6 +33,7 @@ type Hub struct {
 	Register   chan *Client
 	Unregister chan *Client
 	mu         sync.RWMutex
+	streamer   AIStreamer
 }
 
 // Message WebSocket消息
@@ -200,11 +205,13 @@ func NewClient(id, userID, projectID string, conn *websocket.Conn, hub *Hub) *Cl
 // ReadPump 读取消息
 func (c *Client) ReadPump() {
 	defer func() {
+		c.stopAIStreams()
 		c.Hub.Unregister <- c
 		c.Conn.Close()
 	}()
 
-	c.Conn.SetReadLimit(512)
+	// ai_generate 消息携带完整对话，需要比订阅消息大得多的上限
+	c.Conn.SetReadLimit(64 << 10)
 	c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	c.Conn.SetPongHandler(func(string) error {
 		c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
@@ -271,8 +278,9 @@ func (c *Client) WritePump() {
 // handleMessage 处理客户端消息
 func (c *Client) handleMessage(message []byte) {
 	var msg struct {
-		Action string `json:"action"` // subscribe, unsubscribe
-		TaskID string `json:"task_id"`
+		Action    string `json:"action"` // subscribe, unsubscribe, ai_generate, ai_cancel
+		TaskID    string `json:"task_id"`
+		RequestID string `json:"request_id"`
 	}
 
 	if err := json.Unmarshal(message, &msg); err != nil {
@@ -280,6 +288,15 @@ func (c *Client) handleMessage(message []byte) {
 		return
 	}
 
+	switch msg.Action {
+	case "ai_generate":
+		c.startAIStream(message)
+		return
+	case "ai_cancel":
+		c.cancelAIStream(msg.RequestID)
+		return
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
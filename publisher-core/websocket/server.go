@@ -62,6 +62,11 @@ func (s *Server) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	go client.readPump()
 }
 
+// SetAIStreamer 设置处理 ai_generate 消息的生成服务
+func (s *Server) SetAIStreamer(streamer AIStreamer) {
+	s.hub.SetAIStreamer(streamer)
+}
+
 // Broadcast 广播消息到所有客户端
 func (s *Server) Broadcast(message *Message) {
 	data, err := json.Marshal(message)
@@ -153,6 +158,7 @@ func (s *Server) GetClientCount() int {
 // readPump 读取协程
 func (c *Client) readPump() {
 	defer func() {
+		c.stopAIStreams()
 		c.Hub.Unregister <- c
 		c.Conn.Close()
 	}()